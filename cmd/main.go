@@ -3,7 +3,11 @@ package main
 import (
 	"context"
 	"fmt"
+	"net"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/labstack/echo-contrib/prometheus"
@@ -12,6 +16,7 @@ import (
 	echoSwagger "github.com/swaggo/echo-swagger" // echo-swagger middleware
 	"zuzanna.com/walletapi/controller"
 	_ "zuzanna.com/walletapi/docs"
+	"zuzanna.com/walletapi/model"
 	"zuzanna.com/walletapi/repository"
 	"zuzanna.com/walletapi/service"
 )
@@ -33,6 +38,9 @@ import (
 // @securityDefinitions.apikey ApiKeyAuth
 // @in header
 // @name Authorization
+// @securityDefinitions.apikey APIKey
+// @in header
+// @name X-API-Key
 func main() {
 	opLogSvc := service.NewJSONOperationalLogService()
 
@@ -43,7 +51,107 @@ func main() {
 	}
 	defer pool.Close()
 
+	var fxRateProvider service.FXRateProvider
+	if path, ok := os.LookupEnv("FX_RATES_FILE"); ok {
+		fileFXRateProvider, err := service.NewFileFXRateProvider(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to load exchange rates: %v\n", err)
+			os.Exit(1)
+		}
+		fxRateProvider = fileFXRateProvider
+	}
+
+	idempotencyKeyTTL, err := time.ParseDuration(EnvWithDefault("IDEMPOTENCY_KEY_TTL", "24h"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid IDEMPOTENCY_KEY_TTL: %v\n", err)
+		os.Exit(1)
+	}
+
+	holdReaperInterval, err := time.ParseDuration(EnvWithDefault("HOLD_REAPER_INTERVAL", "1m"))
+	if err != nil || holdReaperInterval <= 0 {
+		fmt.Fprintf(os.Stderr, "Invalid HOLD_REAPER_INTERVAL: %v\n", err)
+		os.Exit(1)
+	}
+	schedulerInterval, err := time.ParseDuration(EnvWithDefault("SCHEDULER_INTERVAL", "1m"))
+	if err != nil || schedulerInterval <= 0 {
+		fmt.Fprintf(os.Stderr, "Invalid SCHEDULER_INTERVAL: %v\n", err)
+		os.Exit(1)
+	}
+	batchProcessorInterval, err := time.ParseDuration(EnvWithDefault("BATCH_PROCESSOR_INTERVAL", "10s"))
+	if err != nil || batchProcessorInterval <= 0 {
+		fmt.Fprintf(os.Stderr, "Invalid BATCH_PROCESSOR_INTERVAL: %v\n", err)
+		os.Exit(1)
+	}
+	scheduleRetryPolicy, err := scheduleRetryPolicyFromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid schedule retry settings: %v\n", err)
+		os.Exit(1)
+	}
+
+	refreshTokenTTL, err := time.ParseDuration(EnvWithDefault("REFRESH_TOKEN_TTL", service.DefaultRefreshTokenTTL.String()))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid REFRESH_TOKEN_TTL: %v\n", err)
+		os.Exit(1)
+	}
+
+	var jwtKeys *service.JWTKeySet
+	if dir, ok := os.LookupEnv("JWT_KEYS_DIR"); ok {
+		jwtKeys, err = service.NewJWTKeySet(dir)
+	} else {
+		fmt.Fprintln(os.Stderr, "JWT_KEYS_DIR not set, JWT tokens are signed with a generated key and are not valid after restart")
+		jwtKeys, err = service.NewEphemeralJWTKeySet()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to load JWT keys: %v\n", err)
+		os.Exit(1)
+	}
+	jwtKeysReloadInterval, err := time.ParseDuration(EnvWithDefault("JWT_KEYS_RELOAD_INTERVAL", "1m"))
+	if err != nil || jwtKeysReloadInterval <= 0 {
+		fmt.Fprintf(os.Stderr, "Invalid JWT_KEYS_RELOAD_INTERVAL: %v\n", err)
+		os.Exit(1)
+	}
+
+	passwordResetTTL, err := time.ParseDuration(EnvWithDefault("PASSWORD_RESET_TTL", service.DefaultPasswordResetTTL.String()))
+	if err != nil || passwordResetTTL <= 0 {
+		fmt.Fprintf(os.Stderr, "Invalid PASSWORD_RESET_TTL: %v\n", err)
+		os.Exit(1)
+	}
+	// there is no e-mail or SMS delivery yet, notifications are written to a file
+	notifier, err := service.NewFileNotifier(EnvWithDefault("NOTIFICATIONS_FILE", "notifications.log"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to open notifications file: %v\n", err)
+		os.Exit(1)
+	}
+
+	stepUpPolicy, err := stepUpPolicyFromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid step-up authentication settings: %v\n", err)
+		os.Exit(1)
+	}
+
+	loginThrottlePolicy, err := loginThrottlePolicyFromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid login throttling settings: %v\n", err)
+		os.Exit(1)
+	}
+	var loginAttemptRepo repository.LoginAttemptRepo
+	switch store := EnvWithDefault("LOGIN_ATTEMPTS_STORE", "postgres"); store {
+	case "postgres":
+		loginAttemptRepo = repository.NewPostgreLoginAttemptRepo(pool)
+	case "memory":
+		loginAttemptRepo = repository.NewMemoryLoginAttemptRepo()
+	default:
+		fmt.Fprintf(os.Stderr, "Invalid LOGIN_ATTEMPTS_STORE: %s, must be postgres or memory\n", store)
+		os.Exit(1)
+	}
+	loginThrottle := service.NewLoginThrottle(loginAttemptRepo, loginThrottlePolicy)
+
 	e := echo.New()
+	// client IP is used by API key allowlists and login throttling, so it must not be taken from headers set by the client
+	if e.IPExtractor, err = ipExtractorFromEnv(); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid TRUSTED_PROXIES: %v\n", err)
+		os.Exit(1)
+	}
 	e.Use(middleware.BodyDumpWithConfig(middleware.BodyDumpConfig{
 		Skipper: opLogSvc.LogSkipper,
 		Handler: opLogSvc.CreateLog,
@@ -52,37 +160,139 @@ func main() {
 	p := prometheus.NewPrometheus("echo", nil)
 	p.Use(e)
 
+	mfaSvc := service.NewMFAService(repository.NewPostgreMFARepo(pool))
+	credentialsRepo := repository.PostgreCredentialsRepo{DBConn: pool}
+	tokenRepo := repository.NewPostgreTokenRepo(pool)
 	loginSvc := service.AuthServiceImpl{
-		CredentialsRepo: repository.PostgreCredentialsRepo{DBConn: pool},
+		CredentialsRepo: credentialsRepo,
+		TokenRepo:       tokenRepo,
+		Keys:            jwtKeys,
+		RefreshTokenTTL: refreshTokenTTL,
+		MFA:             mfaSvc,
 	}
+	jwtMiddleware := service.NewJWTMiddleware(loginSvc, jwtKeys)
 	postgreBalanceRepo := repository.NewPostgreBalanceRepo(pool)
 	loginController := controller.LoginController{
-		E:   e,
-		Svc: loginSvc,
+		E:        e,
+		Svc:      loginSvc,
+		Throttle: loginThrottle,
+		JWT:      jwtMiddleware,
+	}
+	jwksController := controller.JWKSController{
+		E:    e,
+		Keys: jwtKeys,
 	}
 
+	apiKeyRepo := repository.NewPostgreAPIKeyRepo(pool)
+	userRepo := repository.NewPostgreUserRepo(pool)
+	apiKeySvc := service.NewAPIKeyService(apiKeyRepo, userRepo, credentialsRepo, stepUpPolicy)
 	api := e.Group("/api")
-	api.Use(middleware.JWTWithConfig(middleware.JWTConfig{
-		SigningKey:              service.GetJwtTokenSign(),
-		TokenLookup:             "header:Authorization",
-		ErrorHandlerWithContext: service.JWTErrorHandlerWithContext,
-		Claims:                  &service.JwtCustomClaims{},
-	}))
+	api.Use(service.NewAPIKeyMiddleware(apiKeySvc, jwtMiddleware, controller.APIKeyScopes))
 
+	userSvc := service.NewUserService(userRepo)
+	userController := controller.UserController{
+		E:        e,
+		G:        api,
+		Svc:      userSvc,
+		LoginSvc: loginSvc,
+	}
+
+	mfaController := controller.MFAController{
+		G:        api,
+		Svc:      mfaSvc,
+		LoginSvc: loginSvc,
+	}
+	passwordSvc := service.NewPasswordService(credentialsRepo, repository.NewPostgrePasswordResetRepo(pool), tokenRepo, apiKeyRepo,
+		notifier, passwordResetTTL)
+	passwordController := controller.PasswordController{
+		E:        e,
+		G:        api,
+		Svc:      passwordSvc,
+		LoginSvc: loginSvc,
+		Throttle: loginThrottle,
+	}
+	apiKeyController := controller.APIKeyController{
+		G:        api,
+		Svc:      apiKeySvc,
+		LoginSvc: loginSvc,
+	}
+	balanceSvc := service.NewBalanceService(postgreBalanceRepo)
 	balanceController := controller.BalanceController{
 		G:          api,
-		BalanceSvc: service.NewBalanceService(postgreBalanceRepo),
+		BalanceSvc: balanceSvc,
 		LoginSvc:   loginSvc,
+		UserSvc:    userSvc,
 	}
+	transactionSvc := service.NewTransactionService(postgreBalanceRepo, fxRateProvider, stepUpPolicy)
+	recipientSvc := service.NewRecipientService(userRepo, postgreBalanceRepo)
+	idempotencySvc := service.NewIdempotencyService(repository.NewPostgreIdempotencyRepo(pool), idempotencyKeyTTL)
 	transactionController := controller.TransactionController{
+		G:              api,
+		LoginSvc:       loginSvc,
+		Svc:            transactionSvc,
+		IdempotencySvc: idempotencySvc,
+		RecipientSvc:   recipientSvc,
+		UserSvc:        userSvc,
+	}
+	batchSvc := service.NewBatchService(postgreBalanceRepo, postgreBalanceRepo, fxRateProvider, stepUpPolicy)
+	batchController := controller.BatchController{
+		G:              api,
+		Svc:            batchSvc,
+		LoginSvc:       loginSvc,
+		IdempotencySvc: idempotencySvc,
+	}
+	holdController := controller.HoldController{
+		G:              api,
+		Svc:            service.NewHoldService(postgreBalanceRepo, fxRateProvider, stepUpPolicy),
+		LoginSvc:       loginSvc,
+		IdempotencySvc: idempotencySvc,
+	}
+	scheduleRepo := repository.NewPostgreScheduleRepo(pool)
+	scheduleController := controller.ScheduleController{
+		G:              api,
+		Svc:            service.NewScheduleService(scheduleRepo, postgreBalanceRepo, fxRateProvider, stepUpPolicy),
+		LoginSvc:       loginSvc,
+		IdempotencySvc: idempotencySvc,
+		RecipientSvc:   recipientSvc,
+	}
+	recipientController := controller.RecipientController{
 		G:        api,
+		Svc:      recipientSvc,
 		LoginSvc: loginSvc,
-		Svc:      service.NewTransactionService(postgreBalanceRepo),
 	}
 
 	loginController.Init()
+	jwksController.Init()
+	userController.Init()
+	mfaController.Init()
+	passwordController.Init()
+	apiKeyController.Init()
 	balanceController.Init()
 	transactionController.Init()
+	batchController.Init()
+	holdController.Init()
+	scheduleController.Init()
+	recipientController.Init()
+
+	// every request to admin endpoints is audited, including the ones denied because of insufficient role
+	admin := api.Group("/admin", service.AuditAdminAccess(opLogSvc), service.RequireRole(model.RoleSupport))
+	adminController := controller.AdminController{
+		G:              admin,
+		BalanceSvc:     balanceSvc,
+		TransactionSvc: transactionSvc,
+		LoginThrottle:  loginThrottle,
+		APIKeySvc:      apiKeySvc,
+		LoginSvc:       loginSvc,
+	}
+	adminController.Init()
+
+	holdReaper := service.NewHoldReaper(postgreBalanceRepo, opLogSvc)
+	go holdReaper.Run(context.Background(), holdReaperInterval)
+	transferScheduler := service.NewTransferScheduler(scheduleRepo, transactionSvc, scheduleRetryPolicy)
+	go transferScheduler.Run(context.Background(), schedulerInterval)
+	batchProcessor := service.NewBatchProcessor(postgreBalanceRepo, batchSvc)
+	go batchProcessor.Run(context.Background(), batchProcessorInterval)
+	go jwtKeys.Run(context.Background(), jwtKeysReloadInterval)
 
 	e.Logger.Fatal(e.Start(":8000"))
 }
@@ -94,3 +304,85 @@ func EnvWithDefault(n string, d string) string {
 	}
 	return d
 }
+
+func loginThrottlePolicyFromEnv() (service.LoginThrottlePolicy, error) {
+	policy := service.DefaultLoginThrottlePolicy
+	var err error
+	if v, ok := os.LookupEnv("LOGIN_MAX_FAILURES"); ok {
+		if policy.MaxFailures, err = strconv.Atoi(v); err != nil || policy.MaxFailures <= 0 {
+			return policy, fmt.Errorf("LOGIN_MAX_FAILURES must be a positive number, got: %s", v)
+		}
+	}
+	if v, ok := os.LookupEnv("LOGIN_MAX_FAILURES_PER_IP"); ok {
+		if policy.MaxIPFailures, err = strconv.Atoi(v); err != nil || policy.MaxIPFailures <= 0 {
+			return policy, fmt.Errorf("LOGIN_MAX_FAILURES_PER_IP must be a positive number, got: %s", v)
+		}
+	}
+	if policy.Backoff, err = time.ParseDuration(EnvWithDefault("LOGIN_BACKOFF", policy.Backoff.String())); err != nil {
+		return policy, fmt.Errorf("LOGIN_BACKOFF: %w", err)
+	}
+	if policy.Lockout, err = time.ParseDuration(EnvWithDefault("LOGIN_LOCKOUT", policy.Lockout.String())); err != nil {
+		return policy, fmt.Errorf("LOGIN_LOCKOUT: %w", err)
+	}
+	return policy, nil
+}
+
+func scheduleRetryPolicyFromEnv() (service.ScheduleRetryPolicy, error) {
+	policy := service.DefaultScheduleRetryPolicy
+	var err error
+	if v, ok := os.LookupEnv("SCHEDULE_MAX_ATTEMPTS"); ok {
+		if policy.MaxAttempts, err = strconv.Atoi(v); err != nil || policy.MaxAttempts <= 0 {
+			return policy, fmt.Errorf("SCHEDULE_MAX_ATTEMPTS must be a positive number, got: %s", v)
+		}
+	}
+	if policy.Backoff, err = time.ParseDuration(EnvWithDefault("SCHEDULE_RETRY_BACKOFF", policy.Backoff.String())); err != nil || policy.Backoff <= 0 {
+		return policy, fmt.Errorf("SCHEDULE_RETRY_BACKOFF must be a positive duration, got: %s", EnvWithDefault("SCHEDULE_RETRY_BACKOFF", policy.Backoff.String()))
+	}
+	return policy, nil
+}
+
+// ipExtractorFromEnv returns extractor of the client IP from the connection when TRUSTED_PROXIES is not set. Otherwise
+// X-Forwarded-For header is used, but only addresses added by the listed proxies (IPs or CIDR networks, comma separated) are skipped.
+func ipExtractorFromEnv() (echo.IPExtractor, error) {
+	v, ok := os.LookupEnv("TRUSTED_PROXIES")
+	if !ok || v == "" {
+		return echo.ExtractIPDirect(), nil
+	}
+	options := []echo.TrustOption{echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false)}
+	for _, proxy := range strings.Split(v, ",") {
+		proxy = strings.TrimSpace(proxy)
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("proxy must be an IP address or a network in CIDR notation, got: %s", proxy)
+			}
+			if ip4 := ip.To4(); ip4 != nil {
+				ip = ip4
+			}
+			network = &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)}
+		}
+		options = append(options, echo.TrustIPRange(network))
+	}
+	return echo.ExtractIPFromXFFHeader(options...), nil
+}
+
+// stepUpPolicyFromEnv returns nil when STEP_UP_THRESHOLD is not set, transfers of any amount are then allowed.
+func stepUpPolicyFromEnv() (*service.StepUpPolicy, error) {
+	v, ok := os.LookupEnv("STEP_UP_THRESHOLD")
+	if !ok {
+		return nil, nil
+	}
+	threshold, err := model.ParseAmount(v)
+	if err != nil || threshold < 0 {
+		return nil, fmt.Errorf("STEP_UP_THRESHOLD must be a non-negative amount, got: %s", v)
+	}
+	policy := service.StepUpPolicy{Threshold: threshold, Currency: model.Currency(EnvWithDefault("STEP_UP_CURRENCY", string(model.SGD)))}
+	if !policy.Currency.IsValid() {
+		return nil, fmt.Errorf("STEP_UP_CURRENCY: %w", model.ErrUnknownCurrency)
+	}
+	if policy.MaxAge, err = time.ParseDuration(EnvWithDefault("STEP_UP_MAX_AGE", "5m")); err != nil || policy.MaxAge <= 0 {
+		return nil, fmt.Errorf("STEP_UP_MAX_AGE must be a positive duration, got: %s", EnvWithDefault("STEP_UP_MAX_AGE", "5m"))
+	}
+	return &policy, nil
+}
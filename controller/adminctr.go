@@ -0,0 +1,160 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"zuzanna.com/walletapi/model"
+	"zuzanna.com/walletapi/service"
+)
+
+var ErrInvalidUserIDMsg = "User ID must be a positive number."
+
+// AdminController serves endpoints for support staff. Its group must be protected with service.RequireRole.
+type AdminController struct {
+	G              *echo.Group
+	BalanceSvc     service.BalanceService
+	TransactionSvc service.TransactionService
+	LoginThrottle  service.LoginThrottle
+	APIKeySvc      service.APIKeyService
+	LoginSvc       service.AuthService
+}
+
+func (ctr AdminController) Init() {
+	ctr.G.GET(userBalancesEndpoint, ctr.GetUserBalances)
+	ctr.G.GET(userTransactionsEndpoint, ctr.GetUserTransactions)
+	ctr.G.POST(unlockLoginEndpoint, ctr.UnlockLogin, service.RequireRole(model.RoleAdmin))
+	ctr.G.POST(userAPIKeysEndpoint, ctr.CreateUserAPIKey, service.RequireRole(model.RoleAdmin))
+}
+
+// @Summary Retrieves balances of any user.
+// @Description Retrieves list of balances of the user with given ID. Requires support role.
+// @Security ApiKeyAuth
+// @ID GetUserBalances
+// @Tags admin
+// @Param id path int true "User ID."
+// @Produce  json
+// @Success 200 {array} model.BalanceResponse
+// @Failure 400 {object} model.ErrResponse
+// @Failure 401 {object} model.ErrResponse
+// @Failure 403 {object} model.ErrResponse
+// @Failure 500 {object} model.ErrResponse
+// @Router /api/admin/v1/users/{id}/balances [get]
+func (ctr AdminController) GetUserBalances(c echo.Context) error {
+	log.Infof("GET %s", replaceID(userBalancesEndpoint, c.Param("id")))
+
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || userID <= 0 {
+		return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrInvalidUserIDMsg))
+	}
+
+	balances, err := ctr.BalanceSvc.GetByUserID(userID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, model.NewErrResponse(http.StatusInternalServerError, ErrInternalServerMsg))
+	}
+	return c.JSON(http.StatusOK, model.NewBalanceResponses(balances))
+}
+
+// @Summary Retrieves transactions of any user.
+// @Description Retrieves page of transactions of the user with given ID, the newest first. Requires support role.
+// @Security ApiKeyAuth
+// @ID GetUserTransactions
+// @Tags admin
+// @Param id path int true "User ID."
+// @Param limit query int false "Number of transactions on the page, from 1 to 100, default 50."
+// @Param cursor query string false "Next cursor from the previous page."
+// @Param from query string false "Only transactions made at or after the date, RFC 3339, e.g. 2026-10-01T00:00:00Z."
+// @Param to query string false "Only transactions made before the date, RFC 3339."
+// @Param minAmount query number false "Minimal sent amount."
+// @Param maxAmount query number false "Maximal sent amount."
+// @Param direction query string false "Only sent or received transactions." Enums(sent, received)
+// @Param counterpartyBalanceId query int false "Only transactions with the balance."
+// @Param currency query string false "Only transactions in the sent currency."
+// @Param reference query string false "Only transactions with the reference."
+// @Param memo query string false "Only transactions with memo containing the text."
+// @Param metadata query string false "Only transactions with the metadata pair given as key:value."
+// @Produce  json
+// @Success 200 {object} model.TransactionPageResponse
+// @Failure 400 {object} model.ErrResponse
+// @Failure 401 {object} model.ErrResponse
+// @Failure 403 {object} model.ErrResponse
+// @Failure 500 {object} model.ErrResponse
+// @Router /api/admin/v1/users/{id}/transactions [get]
+func (ctr AdminController) GetUserTransactions(c echo.Context) error {
+	log.Infof("GET %s", replaceID(userTransactionsEndpoint, c.Param("id")))
+
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || userID <= 0 {
+		return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrInvalidUserIDMsg))
+	}
+
+	filter, err := bindTransactionFilter(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, err.Error()))
+	}
+
+	page, err := ctr.TransactionSvc.Retrieve(userID, filter)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, model.NewErrResponse(http.StatusInternalServerError, ErrInternalServerMsg))
+	}
+	return c.JSON(http.StatusOK, model.NewTransactionPageResponse(page))
+}
+
+// @Summary Unlocks login locked after too many failed login attempts.
+// @Description Resets failed login attempts of the login. Requires admin role.
+// @Security ApiKeyAuth
+// @ID UnlockLogin
+// @Tags admin
+// @Param login path string true "User's login."
+// @Success 204
+// @Failure 401 {object} model.ErrResponse
+// @Failure 403 {object} model.ErrResponse
+// @Failure 500 {object} model.ErrResponse
+// @Router /api/admin/v1/logins/{login}/unlock [post]
+func (ctr AdminController) UnlockLogin(c echo.Context) error {
+	login := c.Param("login")
+	log.Infof("POST %s", strings.Replace(unlockLoginEndpoint, ":login", login, 1))
+
+	if err := ctr.LoginThrottle.Unlock(login); err != nil {
+		return c.JSON(http.StatusInternalServerError, model.NewErrResponse(http.StatusInternalServerError, ErrInternalServerMsg))
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// @Summary Creates API key of service account.
+// @Description Creates API key of the service account with given ID - user without credentials, who cannot login with password.
+// @Description Keys of users who can login are refused with 403, they create their own keys. The key is returned only once. Requires admin role and token issued by /login, /login/mfa or /reauth within the last minutes.
+// @Security ApiKeyAuth
+// @ID CreateUserAPIKey
+// @Tags admin
+// @Param id path int true "User ID."
+// @Param key body model.APIKeyRequest true "API key definition."
+// @Accept  json
+// @Produce  json
+// @Success 201 {object} model.APIKeyResponse
+// @Failure 400 {object} model.ErrResponse
+// @Failure 401 {object} model.ErrResponse
+// @Failure 403 {object} model.ErrResponse
+// @Failure 404 {object} model.ErrResponse
+// @Failure 500 {object} model.ErrResponse
+// @Router /api/admin/v1/users/{id}/api-keys [post]
+func (ctr AdminController) CreateUserAPIKey(c echo.Context) error {
+	log.Infof("POST %s", replaceID(userAPIKeysEndpoint, c.Param("id")))
+
+	authTime, err := ctr.LoginSvc.GetAuthTimeFromToken(c)
+	if err != nil {
+		log.Errorf("error while reading auth time from token; error: %v", err)
+		return c.JSON(http.StatusUnauthorized, model.NewErrResponse(http.StatusUnauthorized, ErrInvalidTokenMsg))
+	}
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || userID <= 0 {
+		return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrInvalidUserIDMsg))
+	}
+	return createAPIKey(c, func(name string, scopes []model.Scope, allowedIPs []string, expiresAt time.Time) (string, model.APIKey, error) {
+		return ctr.APIKeySvc.CreateForServiceAccount(userID, authTime, name, scopes, allowedIPs, expiresAt)
+	})
+}
@@ -0,0 +1,157 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"zuzanna.com/walletapi/model"
+	"zuzanna.com/walletapi/service"
+)
+
+var ErrInvalidAPIKeyIDMsg = "API key ID must be a positive number."
+var ErrAPIKeyNotFoundMsg = "API key not found or already revoked."
+var ErrNotServiceAccountMsg = "API keys can be created only for service accounts, users who can login create their own keys."
+var ErrAPIKeyStepUpRequiredMsg = "Creating API key requires recent authentication. Please confirm your password or one-time code at /reauth and retry with the new token."
+
+type APIKeyController struct {
+	G        *echo.Group
+	Svc      service.APIKeyService
+	LoginSvc service.AuthService
+}
+
+func (ctr APIKeyController) Init() {
+	ctr.G.POST(apiKeysEndpoint, ctr.CreateAPIKey)
+	ctr.G.GET(apiKeysEndpoint, ctr.GetAPIKeys)
+	ctr.G.DELETE(apiKeyEndpoint, ctr.RevokeAPIKey)
+}
+
+// @Summary Creates API key of the authenticated user.
+// @Description Creates API key to be sent in X-API-Key header instead of JWT token, e.g. by server to server integrations.
+// @Description The key is returned only once. Key can be used only on endpoints of its scopes, from allowed IPs and before it expires.
+// @Description API keys cannot be used to manage API keys. Requires token issued by /login, /login/mfa or /reauth within the last minutes,
+// @Description otherwise 403 is returned. Transfers above step-up threshold with the key require transactions:large scope.
+// @Security ApiKeyAuth
+// @ID CreateAPIKey
+// @Tags api-keys
+// @Param key body model.APIKeyRequest true "API key definition."
+// @Accept  json
+// @Produce  json
+// @Success 201 {object} model.APIKeyResponse
+// @Failure 400 {object} model.ErrResponse
+// @Failure 401 {object} model.ErrResponse
+// @Failure 403 {object} model.ErrResponse
+// @Failure 500 {object} model.ErrResponse
+// @Router /api/v1/api-keys [post]
+func (ctr APIKeyController) CreateAPIKey(c echo.Context) error {
+	log.Infof("POST %s", apiKeysEndpoint)
+
+	userID, err := ctr.LoginSvc.GetUserIDFromToken(c)
+	if err != nil {
+		log.Errorf("error while reading user ID from token; error: %v", err)
+		return c.JSON(http.StatusUnauthorized, model.NewErrResponse(http.StatusUnauthorized, ErrInvalidTokenMsg))
+	}
+	authTime, err := ctr.LoginSvc.GetAuthTimeFromToken(c)
+	if err != nil {
+		log.Errorf("error while reading auth time from token; error: %v", err)
+		return c.JSON(http.StatusUnauthorized, model.NewErrResponse(http.StatusUnauthorized, ErrInvalidTokenMsg))
+	}
+	return createAPIKey(c, func(name string, scopes []model.Scope, allowedIPs []string, expiresAt time.Time) (string, model.APIKey, error) {
+		return ctr.Svc.Create(userID, authTime, name, scopes, allowedIPs, expiresAt)
+	})
+}
+
+// @Summary Retrieves API keys of the authenticated user.
+// @Description Retrieves all API keys of the authenticated user, including revoked and expired ones, without the keys themselves.
+// @Security ApiKeyAuth
+// @ID GetAPIKeys
+// @Tags api-keys
+// @Produce  json
+// @Success 200 {array} model.APIKeyResponse
+// @Failure 401 {object} model.ErrResponse
+// @Failure 500 {object} model.ErrResponse
+// @Router /api/v1/api-keys [get]
+func (ctr APIKeyController) GetAPIKeys(c echo.Context) error {
+	log.Infof("GET %s", apiKeysEndpoint)
+
+	userID, err := ctr.LoginSvc.GetUserIDFromToken(c)
+	if err != nil {
+		log.Errorf("error while reading user ID from token; error: %v", err)
+		return c.JSON(http.StatusUnauthorized, model.NewErrResponse(http.StatusUnauthorized, ErrInvalidTokenMsg))
+	}
+
+	keys, err := ctr.Svc.GetByUserID(userID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, model.NewErrResponse(http.StatusInternalServerError, ErrInternalServerMsg))
+	}
+	return c.JSON(http.StatusOK, model.NewAPIKeyResponses(keys))
+}
+
+// @Summary Revokes API key of the authenticated user.
+// @Security ApiKeyAuth
+// @ID RevokeAPIKey
+// @Tags api-keys
+// @Param id path int true "API key ID."
+// @Success 204
+// @Failure 400 {object} model.ErrResponse
+// @Failure 401 {object} model.ErrResponse
+// @Failure 404 {object} model.ErrResponse
+// @Failure 500 {object} model.ErrResponse
+// @Router /api/v1/api-keys/{id} [delete]
+func (ctr APIKeyController) RevokeAPIKey(c echo.Context) error {
+	log.Infof("DELETE %s", replaceID(apiKeyEndpoint, c.Param("id")))
+
+	userID, err := ctr.LoginSvc.GetUserIDFromToken(c)
+	if err != nil {
+		log.Errorf("error while reading user ID from token; error: %v", err)
+		return c.JSON(http.StatusUnauthorized, model.NewErrResponse(http.StatusUnauthorized, ErrInvalidTokenMsg))
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrInvalidAPIKeyIDMsg))
+	}
+
+	if err = ctr.Svc.Revoke(userID, id); err != nil {
+		if err == service.ErrAPIKeyNotFound {
+			return c.JSON(http.StatusNotFound, model.NewErrResponse(http.StatusNotFound, ErrAPIKeyNotFoundMsg))
+		}
+		return c.JSON(http.StatusInternalServerError, model.NewErrResponse(http.StatusInternalServerError, ErrInternalServerMsg))
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// createAPIKey creates API key from request body with create, it is shared by user and admin endpoints.
+func createAPIKey(c echo.Context, create func(name string, scopes []model.Scope, allowedIPs []string, expiresAt time.Time) (string, model.APIKey, error)) error {
+	r := new(model.APIKeyRequest)
+	if err := c.Bind(r); err != nil {
+		log.Errorf("cannot bind APIKeyRequest struct with the Request body; error: %v", err)
+		return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrCannotParseBodyMsg))
+	}
+	if ok, err := r.IsValid(); !ok {
+		return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, err.Error()))
+	}
+	var expiresAt time.Time
+	if r.ExpiresAt != nil {
+		expiresAt = *r.ExpiresAt
+	}
+
+	key, apiKey, err := create(r.Name, r.GetScopes(), r.AllowedIPs, expiresAt)
+	if err != nil {
+		switch err {
+		case service.ErrInvalidScope, service.ErrInvalidAllowedIP, service.ErrInvalidAPIKeyExpiry:
+			return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, err.Error()))
+		case service.ErrStepUpRequired:
+			return c.JSON(http.StatusForbidden, model.NewErrResponse(http.StatusForbidden, ErrAPIKeyStepUpRequiredMsg))
+		case service.ErrNotServiceAccount:
+			return c.JSON(http.StatusForbidden, model.NewErrResponse(http.StatusForbidden, ErrNotServiceAccountMsg))
+		case service.ErrUserNotFound:
+			return c.JSON(http.StatusNotFound, model.NewErrResponse(http.StatusNotFound, ErrUserNotFoundMsg))
+		}
+		return c.JSON(http.StatusInternalServerError, model.NewErrResponse(http.StatusInternalServerError, ErrInternalServerMsg))
+	}
+	resp := model.NewAPIKeyResponse(apiKey)
+	resp.Key = key
+	return c.JSON(http.StatusCreated, resp)
+}
@@ -2,6 +2,7 @@ package controller
 
 import (
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/labstack/echo/v4"
@@ -13,22 +14,31 @@ import (
 var ErrInternalServerMsg = "Server error, please try again."
 var ErrWrongLoginMsg = "Login failed. Please double check username and password."
 var ErrInvalidTokenMsg = "Invalid token."
+var ErrBalanceExistsMsg = "Balance in requested currency already exists."
+var ErrInvalidBalanceIDMsg = "Balance ID must be a positive number."
+var ErrBalanceNotFoundMsg = "Balance not found."
 
 type BalanceController struct {
 	G          *echo.Group
 	BalanceSvc service.BalanceService
 	LoginSvc   service.AuthService
+	// UserSvc is used to add owner names to balances, names are not added when it is nil.
+	UserSvc service.UserService
 }
 
 func (ctr BalanceController) Init() {
 	ctr.G.GET(balancesEndpoint, ctr.GetBalances)
+	ctr.G.POST(balancesEndpoint, ctr.CreateBalance)
+	ctr.G.GET(balanceEndpoint, ctr.GetBalance)
 }
 
 // @Summary Retrieves list of balances for authenticated user.
 // @Description Retrieves list of balances for authenticated user.
 // @Security ApiKeyAuth
+// @Security APIKey
 // @ID GetBalances
 // @Tags balances
+// @Param expand query string false "Set to counterparty to add display name of the owner." Enums(counterparty)
 // @Produce  json
 // @Success 200 {array} model.BalanceResponse
 // @Failure 401 {object} model.ErrResponse
@@ -39,7 +49,7 @@ func (ctr BalanceController) GetBalances(c echo.Context) error {
 
 	userID, err := ctr.LoginSvc.GetUserIDFromToken(c)
 	if err != nil {
-		log.Errorf("error while reading user ID from token; error: ", err)
+		log.Errorf("error while reading user ID from token; error: %v", err)
 		return c.JSON(http.StatusUnauthorized, model.NewErrResponse(http.StatusUnauthorized, ErrInvalidTokenMsg))
 	}
 
@@ -47,7 +57,107 @@ func (ctr BalanceController) GetBalances(c echo.Context) error {
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, model.NewErrResponse(http.StatusInternalServerError, ErrInternalServerMsg))
 	}
-	return c.JSON(http.StatusOK, model.NewBalanceResponses(balances))
+	resp := model.NewBalanceResponses(balances)
+	if c.QueryParam(expandParam) == expandCounterparty && ctr.UserSvc != nil && len(resp) > 0 {
+		owner, err := ctr.UserSvc.Get(userID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, model.NewErrResponse(http.StatusInternalServerError, ErrInternalServerMsg))
+		}
+		for i := range resp {
+			resp[i].OwnerName = owner.DisplayName()
+		}
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// @Summary Opens new balance in requested currency.
+// @Description Creates empty balance for authenticated user. User can hold only one balance per currency.
+// @Security ApiKeyAuth
+// @Security APIKey
+// @ID CreateBalance
+// @Tags balances
+// @Param balance body model.BalanceRequest true "Balance definition."
+// @Accept  json
+// @Produce  json
+// @Success 201 {object} model.BalanceResponse
+// @Failure 400 {object} model.ErrResponse
+// @Failure 401 {object} model.ErrResponse
+// @Failure 409 {object} model.ErrResponse
+// @Failure 500 {object} model.ErrResponse
+// @Router /api/v1/balances [post]
+func (ctr BalanceController) CreateBalance(c echo.Context) error {
+	log.Infof("POST %s", replaceID(balancesEndpoint, ""))
+
+	userID, err := ctr.LoginSvc.GetUserIDFromToken(c)
+	if err != nil {
+		log.Errorf("error while reading user ID from token; error: %v", err)
+		return c.JSON(http.StatusUnauthorized, model.NewErrResponse(http.StatusUnauthorized, ErrInvalidTokenMsg))
+	}
+
+	b := new(model.BalanceRequest)
+	if err = c.Bind(b); err != nil {
+		log.Errorf("cannot bind BalanceRequest struct with the Request body; error: %v", err)
+		return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrCannotParseBodyMsg))
+	}
+
+	if ok, err := b.IsValid(); !ok {
+		return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, err.Error()))
+	}
+
+	balance, err := ctr.BalanceSvc.Create(userID, model.Currency(b.Currency))
+	if err != nil {
+		if err == service.ErrBalanceExists {
+			return c.JSON(http.StatusConflict, model.NewErrResponse(http.StatusConflict, ErrBalanceExistsMsg))
+		}
+		return c.JSON(http.StatusInternalServerError, model.NewErrResponse(http.StatusInternalServerError, ErrInternalServerMsg))
+	}
+	return c.JSON(http.StatusCreated, model.NewBalanceResponse(balance))
+}
+
+// @Summary Retrieves balance of authenticated user.
+// @Description Retrieves balance with its lock state and date of the last transaction. Balances of other users are not found.
+// @Security ApiKeyAuth
+// @Security APIKey
+// @ID GetBalance
+// @Tags balances
+// @Param id path int true "Balance ID."
+// @Param expand query string false "Set to counterparty to add display name of the owner." Enums(counterparty)
+// @Produce  json
+// @Success 200 {object} model.BalanceDetailsResponse
+// @Failure 400 {object} model.ErrResponse
+// @Failure 401 {object} model.ErrResponse
+// @Failure 404 {object} model.ErrResponse
+// @Failure 500 {object} model.ErrResponse
+// @Router /api/v1/balances/{id} [get]
+func (ctr BalanceController) GetBalance(c echo.Context) error {
+	log.Infof("GET %s", replaceID(balanceEndpoint, c.Param("id")))
+
+	userID, err := ctr.LoginSvc.GetUserIDFromToken(c)
+	if err != nil {
+		log.Errorf("error while reading user ID from token; error: %v", err)
+		return c.JSON(http.StatusUnauthorized, model.NewErrResponse(http.StatusUnauthorized, ErrInvalidTokenMsg))
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrInvalidBalanceIDMsg))
+	}
+
+	balance, err := ctr.BalanceSvc.Get(userID, id)
+	if err != nil {
+		if err == service.ErrBalanceNotFound {
+			return c.JSON(http.StatusNotFound, model.NewErrResponse(http.StatusNotFound, ErrBalanceNotFoundMsg))
+		}
+		return c.JSON(http.StatusInternalServerError, model.NewErrResponse(http.StatusInternalServerError, ErrInternalServerMsg))
+	}
+	resp := model.NewBalanceDetailsResponse(balance)
+	if c.QueryParam(expandParam) == expandCounterparty && ctr.UserSvc != nil {
+		owner, err := ctr.UserSvc.Get(userID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, model.NewErrResponse(http.StatusInternalServerError, ErrInternalServerMsg))
+		}
+		resp.OwnerName = owner.DisplayName()
+	}
+	return c.JSON(http.StatusOK, resp)
 }
 
 func replaceID(s string, id string) string {
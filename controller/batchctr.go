@@ -0,0 +1,149 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"zuzanna.com/walletapi/model"
+	"zuzanna.com/walletapi/service"
+)
+
+var ErrInvalidBatchIDMsg = "Batch ID must be a positive number."
+var ErrBatchNotFoundMsg = "Batch not found."
+var ErrBatchInsufficientBalanceMsg = "There is not enough money on sender's balance to transfer total amount of the batch."
+var ErrInvalidBatchAmountMsg = "Amount of every batch item must be greater than 0 and at most " + model.MaxAmount.String() + "."
+
+type BatchController struct {
+	G              *echo.Group
+	Svc            service.BatchService
+	LoginSvc       service.AuthService
+	IdempotencySvc service.IdempotencyService
+}
+
+func (ctr *BatchController) Init() {
+	ctr.G.POST(transactionBatchEndpoint, ctr.SubmitBatch)
+	ctr.G.GET(transactionBatchIDEndpoint, ctr.GetBatch)
+}
+
+// @Summary Submits batch of transactions.
+// @Description Transfers money from one sender balance to many receiver balances, e.g. payroll. In all_or_nothing mode either all
+// @Description items are transferred or none of them - the item which failed is failed and the others are skipped. In best_effort mode
+// @Description every item which can be transferred is transferred. Total amount of the batch must be available on the sender balance
+// @Description and it is compared with step-up threshold. Batch of at most 20 items is processed at once and returned with result
+// @Description of every item, larger batch is returned pending with 202 and processed in background - poll it with its ID.
+// @Security ApiKeyAuth
+// @Security APIKey
+// @ID SubmitBatch
+// @Tags transactions
+// @Param batch body model.BatchRequest true "Batch definition."
+// @Param Idempotency-Key header string false "Unique key of the request. Retried request with the same key and body returns the original response."
+// @Accept  json
+// @Produce  json
+// @Success 201 {object} model.BatchResponse
+// @Success 202 {object} model.BatchResponse
+// @Failure 400 {object} model.ErrResponse
+// @Failure 401 {object} model.ErrResponse
+// @Failure 403 {object} model.ErrResponse
+// @Failure 409 {object} model.ErrResponse
+// @Failure 422 {object} model.ErrResponse
+// @Failure 500 {object} model.ErrResponse
+// @Router /api/v1/transactions/batch [post]
+func (ctr *BatchController) SubmitBatch(c echo.Context) error {
+	log.Infof("POST %s", transactionBatchEndpoint)
+
+	userID, err := ctr.LoginSvc.GetUserIDFromToken(c)
+	if err != nil {
+		log.Errorf("error while reading user ID from token; error: %v", err)
+		return c.JSON(http.StatusUnauthorized, model.NewErrResponse(http.StatusUnauthorized, ErrInvalidTokenMsg))
+	}
+	authTime, err := ctr.LoginSvc.GetAuthTimeFromToken(c)
+	if err != nil {
+		log.Errorf("error while reading auth time from token; error: %v", err)
+		return c.JSON(http.StatusUnauthorized, model.NewErrResponse(http.StatusUnauthorized, ErrInvalidTokenMsg))
+	}
+
+	b := new(model.BatchRequest)
+	if err = c.Bind(b); err != nil {
+		log.Errorf("cannot bind BatchRequest struct with the Request body; error: %v", err)
+		if errors.Is(err, model.ErrAmountPrecision) {
+			return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrAmountPrecisionMsg))
+		}
+		return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrCannotParseBodyMsg))
+	}
+	if ok, err := b.IsValid(); !ok {
+		return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, err.Error()))
+	}
+
+	return idempotent(c, ctr.IdempotencySvc, userID, b, func() (int, interface{}) {
+		return ctr.submitBatch(userID, authTime, b.ToBatch())
+	})
+}
+
+// submitBatch submits batch and returns http code with response body.
+func (ctr *BatchController) submitBatch(userID int, authTime time.Time, b model.Batch) (int, interface{}) {
+	batch, err := ctr.Svc.Submit(userID, b, authTime)
+	if err != nil {
+		log.Errorf("cannot submit batch; error: %v", err)
+		switch err {
+		case service.ErrBalanceNotFound:
+			return http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrBalancesNotFoundMsg)
+		case service.ErrInsufficientBalance:
+			return http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrBatchInsufficientBalanceMsg)
+		case service.ErrCurrencyMismatch:
+			return http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrCurrencyMismatchMsg)
+		case service.ErrAmountNotAllowed:
+			return http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrAmountNotAllowedMsg)
+		case service.ErrInvalidBatchAmount:
+			return http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrInvalidBatchAmountMsg)
+		case service.ErrStepUpRequired:
+			return http.StatusForbidden, model.NewErrResponse(http.StatusForbidden, ErrStepUpRequiredMsg)
+		}
+		return http.StatusInternalServerError, model.NewErrResponse(http.StatusInternalServerError, ErrInternalServerMsg)
+	}
+	if batch.Status == model.BatchPending {
+		return http.StatusAccepted, model.NewBatchResponse(batch)
+	}
+	return http.StatusCreated, model.NewBatchResponse(batch)
+}
+
+// @Summary Retrieves batch of transactions.
+// @Description Retrieves batch of the authenticated user with result of every item, status is pending until all items are processed.
+// @Description Batches of other users are not found.
+// @Security ApiKeyAuth
+// @Security APIKey
+// @ID GetBatch
+// @Tags transactions
+// @Param id path int true "Batch ID."
+// @Produce  json
+// @Success 200 {object} model.BatchResponse
+// @Failure 400 {object} model.ErrResponse
+// @Failure 401 {object} model.ErrResponse
+// @Failure 404 {object} model.ErrResponse
+// @Failure 500 {object} model.ErrResponse
+// @Router /api/v1/transactions/batch/{id} [get]
+func (ctr *BatchController) GetBatch(c echo.Context) error {
+	log.Infof("GET %s", replaceID(transactionBatchIDEndpoint, c.Param("id")))
+
+	userID, err := ctr.LoginSvc.GetUserIDFromToken(c)
+	if err != nil {
+		log.Errorf("error while reading user ID from token; error: %v", err)
+		return c.JSON(http.StatusUnauthorized, model.NewErrResponse(http.StatusUnauthorized, ErrInvalidTokenMsg))
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrInvalidBatchIDMsg))
+	}
+
+	batch, err := ctr.Svc.Get(userID, id)
+	if err != nil {
+		if err == service.ErrBatchNotFound {
+			return c.JSON(http.StatusNotFound, model.NewErrResponse(http.StatusNotFound, ErrBatchNotFoundMsg))
+		}
+		return c.JSON(http.StatusInternalServerError, model.NewErrResponse(http.StatusInternalServerError, ErrInternalServerMsg))
+	}
+	return c.JSON(http.StatusOK, model.NewBatchResponse(batch))
+}
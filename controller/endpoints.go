@@ -1,9 +1,99 @@
 package controller
 
+import "zuzanna.com/walletapi/model"
+
 var loginEndpoint = "/login"
 
+var loginMFAEndpoint = loginEndpoint + "/mfa"
+
+var refreshTokenEndpoint = "/token/refresh"
+
+var logoutEndpoint = "/logout"
+
+var reauthEndpoint = "/reauth"
+
+var passwordResetEndpoint = "/password/reset"
+
+var passwordResetConfirmEndpoint = passwordResetEndpoint + "/confirm"
+
+var jwksEndpoint = "/.well-known/jwks.json"
+
 var baseAPIVersion = "/v1"
 
+var usersEndpoint = baseAPIVersion + "/users"
+
+var meEndpoint = baseAPIVersion + "/me"
+
+var mePasswordEndpoint = meEndpoint + "/password"
+
+var totpEndpoint = baseAPIVersion + "/mfa/totp"
+
+var totpConfirmEndpoint = totpEndpoint + "/confirm"
+
+var userBalancesEndpoint = usersEndpoint + "/:id/balances"
+
+var userTransactionsEndpoint = usersEndpoint + "/:id/transactions"
+
+var userAPIKeysEndpoint = usersEndpoint + "/:id/api-keys"
+
+var apiKeysEndpoint = baseAPIVersion + "/api-keys"
+
+var apiKeyEndpoint = apiKeysEndpoint + "/:id"
+
+var unlockLoginEndpoint = baseAPIVersion + "/logins/:login/unlock"
+
 var balancesEndpoint = baseAPIVersion + "/balances"
 
+var balanceEndpoint = balancesEndpoint + "/:id"
+
 var transactionsEndpoint = baseAPIVersion + "/transactions"
+
+var transactionEndpoint = transactionsEndpoint + "/:id"
+
+var transactionRefundEndpoint = transactionEndpoint + "/refund"
+
+var transactionBatchEndpoint = transactionsEndpoint + "/batch"
+
+var transactionBatchIDEndpoint = transactionBatchEndpoint + "/:id"
+
+var holdsEndpoint = baseAPIVersion + "/holds"
+
+var holdEndpoint = holdsEndpoint + "/:id"
+
+var holdCaptureEndpoint = holdEndpoint + "/capture"
+
+var holdVoidEndpoint = holdEndpoint + "/void"
+
+var schedulesEndpoint = baseAPIVersion + "/schedules"
+
+var scheduleEndpoint = schedulesEndpoint + "/:id"
+
+var schedulePauseEndpoint = scheduleEndpoint + "/pause"
+
+var scheduleResumeEndpoint = scheduleEndpoint + "/resume"
+
+var scheduleCancelEndpoint = scheduleEndpoint + "/cancel"
+
+var recipientsEndpoint = baseAPIVersion + "/recipients"
+
+// APIKeyScopes lists endpoints of /api group which accept API keys with the scope required by each of them,
+// API keys are rejected on other endpoints. See service.NewAPIKeyMiddleware.
+var APIKeyScopes = map[string]model.Scope{
+	"GET /api" + balancesEndpoint:           model.ScopeBalancesRead,
+	"POST /api" + balancesEndpoint:          model.ScopeBalancesWrite,
+	"GET /api" + balanceEndpoint:            model.ScopeBalancesRead,
+	"GET /api" + transactionsEndpoint:       model.ScopeTransactionsRead,
+	"POST /api" + transactionsEndpoint:      model.ScopeTransactionsWrite,
+	"GET /api" + transactionEndpoint:        model.ScopeTransactionsRead,
+	"POST /api" + transactionRefundEndpoint: model.ScopeTransactionsWrite,
+	"POST /api" + transactionBatchEndpoint:  model.ScopeTransactionsWrite,
+	"GET /api" + transactionBatchIDEndpoint: model.ScopeTransactionsRead,
+	"POST /api" + holdsEndpoint:             model.ScopeTransactionsWrite,
+	"GET /api" + holdEndpoint:               model.ScopeTransactionsRead,
+	"POST /api" + holdCaptureEndpoint:       model.ScopeTransactionsWrite,
+	"POST /api" + holdVoidEndpoint:          model.ScopeTransactionsWrite,
+	// recipient is looked up before transfer
+	"GET /api" + recipientsEndpoint: model.ScopeTransactionsWrite,
+}
+
+var idempotencyKeyHeader = "Idempotency-Key"
@@ -0,0 +1,266 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"zuzanna.com/walletapi/model"
+	"zuzanna.com/walletapi/service"
+)
+
+var ErrInvalidHoldIDMsg = "Hold ID must be a positive number."
+var ErrHoldNotFoundMsg = "Hold not found."
+var ErrHoldNotAllowedMsg = "Only receiver of the hold can capture or void it."
+var ErrHoldNotActiveMsg = "Hold was already captured or voided, or it expired."
+var ErrCaptureExceedsHoldMsg = "Captured amount exceeds amount of the hold."
+var ErrInvalidHoldExpiryMsg = "Hold must expire in the future, at most in 30 days."
+var ErrHoldCurrencyMismatchMsg = "Currency of sender and receiver balances differ. Holds are supported only between balances in the same currency."
+
+type HoldController struct {
+	G              *echo.Group
+	Svc            service.HoldService
+	LoginSvc       service.AuthService
+	IdempotencySvc service.IdempotencyService
+}
+
+func (ctr *HoldController) Init() {
+	ctr.G.POST(holdsEndpoint, ctr.CreateHold)
+	ctr.G.GET(holdEndpoint, ctr.GetHold)
+	ctr.G.POST(holdCaptureEndpoint, ctr.CaptureHold)
+	ctr.G.POST(holdVoidEndpoint, ctr.VoidHold)
+}
+
+// @Summary Creates hold.
+// @Description Reserves money of the sender balance for the receiver balance, e.g. before an order is confirmed. Held money stays
+// @Description on the sender balance, but it is not available for transfers until the receiver captures it into a transaction
+// @Description or voids it, or until the hold expires. Both balances must be in the same currency.
+// @Description Hold above step-up threshold requires recent authentication, like transfers.
+// @Security ApiKeyAuth
+// @Security APIKey
+// @ID CreateHold
+// @Tags holds
+// @Param hold body model.HoldRequest true "Hold definition."
+// @Param Idempotency-Key header string false "Unique key of the request. Retried request with the same key and body returns the original response."
+// @Accept  json
+// @Produce  json
+// @Success 201 {object} model.HoldResponse
+// @Failure 400 {object} model.ErrResponse
+// @Failure 401 {object} model.ErrResponse
+// @Failure 403 {object} model.ErrResponse
+// @Failure 409 {object} model.ErrResponse
+// @Failure 422 {object} model.ErrResponse
+// @Failure 500 {object} model.ErrResponse
+// @Router /api/v1/holds [post]
+func (ctr *HoldController) CreateHold(c echo.Context) error {
+	log.Infof("POST %s", holdsEndpoint)
+
+	userID, err := ctr.LoginSvc.GetUserIDFromToken(c)
+	if err != nil {
+		log.Errorf("error while reading user ID from token; error: %v", err)
+		return c.JSON(http.StatusUnauthorized, model.NewErrResponse(http.StatusUnauthorized, ErrInvalidTokenMsg))
+	}
+	authTime, err := ctr.LoginSvc.GetAuthTimeFromToken(c)
+	if err != nil {
+		log.Errorf("error while reading auth time from token; error: %v", err)
+		return c.JSON(http.StatusUnauthorized, model.NewErrResponse(http.StatusUnauthorized, ErrInvalidTokenMsg))
+	}
+
+	h := new(model.HoldRequest)
+	if err = c.Bind(h); err != nil {
+		log.Errorf("cannot bind HoldRequest struct with the Request body; error: %v", err)
+		if errors.Is(err, model.ErrAmountPrecision) {
+			return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrAmountPrecisionMsg))
+		}
+		return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrCannotParseBodyMsg))
+	}
+	if ok, err := h.IsValid(); !ok {
+		return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, err.Error()))
+	}
+
+	return idempotent(c, ctr.IdempotencySvc, userID, h, func() (int, interface{}) {
+		return ctr.createHold(userID, authTime, h.ToHold())
+	})
+}
+
+// createHold creates hold and returns http code with response body.
+func (ctr *HoldController) createHold(userID int, authTime time.Time, h model.Hold) (int, interface{}) {
+	hold, err := ctr.Svc.Create(userID, h, authTime)
+	if err != nil {
+		log.Errorf("cannot create hold; error: %v", err)
+		switch err {
+		case service.ErrBalanceNotFound:
+			return http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrBalancesNotFoundMsg)
+		case service.ErrInsufficientBalance:
+			return http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrErrInsufficientBalanceMsg)
+		case service.ErrCurrencyMismatch:
+			return http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrHoldCurrencyMismatchMsg)
+		case service.ErrAmountNotAllowed:
+			return http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrAmountNotAllowedMsg)
+		case service.ErrInvalidHoldExpiry:
+			return http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrInvalidHoldExpiryMsg)
+		case service.ErrUnauthorizedTransaction:
+			return http.StatusUnauthorized, model.NewErrResponse(http.StatusUnauthorized, ErrUnauthorizedTransactionMsg)
+		case service.ErrStepUpRequired:
+			return http.StatusForbidden, model.NewErrResponse(http.StatusForbidden, ErrStepUpRequiredMsg)
+		}
+		return http.StatusInternalServerError, model.NewErrResponse(http.StatusInternalServerError, ErrInternalServerMsg)
+	}
+	return http.StatusCreated, model.NewHoldResponse(hold)
+}
+
+// @Summary Retrieves hold.
+// @Description Retrieves hold from or to a balance of the authenticated user. Holds of other users are not found.
+// @Security ApiKeyAuth
+// @Security APIKey
+// @ID GetHold
+// @Tags holds
+// @Param id path int true "Hold ID."
+// @Produce  json
+// @Success 200 {object} model.HoldResponse
+// @Failure 400 {object} model.ErrResponse
+// @Failure 401 {object} model.ErrResponse
+// @Failure 404 {object} model.ErrResponse
+// @Failure 500 {object} model.ErrResponse
+// @Router /api/v1/holds/{id} [get]
+func (ctr *HoldController) GetHold(c echo.Context) error {
+	log.Infof("GET %s", replaceID(holdEndpoint, c.Param("id")))
+
+	userID, err := ctr.LoginSvc.GetUserIDFromToken(c)
+	if err != nil {
+		log.Errorf("error while reading user ID from token; error: %v", err)
+		return c.JSON(http.StatusUnauthorized, model.NewErrResponse(http.StatusUnauthorized, ErrInvalidTokenMsg))
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrInvalidHoldIDMsg))
+	}
+
+	hold, err := ctr.Svc.Get(userID, id)
+	if err != nil {
+		if err == service.ErrHoldNotFound {
+			return c.JSON(http.StatusNotFound, model.NewErrResponse(http.StatusNotFound, ErrHoldNotFoundMsg))
+		}
+		return c.JSON(http.StatusInternalServerError, model.NewErrResponse(http.StatusInternalServerError, ErrInternalServerMsg))
+	}
+	return c.JSON(http.StatusOK, model.NewHoldResponse(hold))
+}
+
+// @Summary Captures hold.
+// @Description Transfers held money to the receiver balance as a new transaction, linked to the hold with transactionId.
+// @Description Only the receiver of an active hold can capture it, once - in full or partially, the rest of the hold is released.
+// @Description Whole hold is captured when amount is not set.
+// @Security ApiKeyAuth
+// @Security APIKey
+// @ID CaptureHold
+// @Tags holds
+// @Param id path int true "Hold ID."
+// @Param capture body model.CaptureRequest false "Capture definition."
+// @Param Idempotency-Key header string false "Unique key of the request. Retried request with the same key and body returns the original response."
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} model.HoldResponse
+// @Failure 400 {object} model.ErrResponse
+// @Failure 401 {object} model.ErrResponse
+// @Failure 403 {object} model.ErrResponse
+// @Failure 404 {object} model.ErrResponse
+// @Failure 409 {object} model.ErrResponse
+// @Failure 422 {object} model.ErrResponse
+// @Failure 500 {object} model.ErrResponse
+// @Router /api/v1/holds/{id}/capture [post]
+func (ctr *HoldController) CaptureHold(c echo.Context) error {
+	log.Infof("POST %s", replaceID(holdCaptureEndpoint, c.Param("id")))
+
+	userID, err := ctr.LoginSvc.GetUserIDFromToken(c)
+	if err != nil {
+		log.Errorf("error while reading user ID from token; error: %v", err)
+		return c.JSON(http.StatusUnauthorized, model.NewErrResponse(http.StatusUnauthorized, ErrInvalidTokenMsg))
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrInvalidHoldIDMsg))
+	}
+
+	// body is optional, whole hold is captured without it
+	r := new(model.CaptureRequest)
+	if err = c.Bind(r); err != nil {
+		log.Errorf("cannot bind CaptureRequest struct with the Request body; error: %v", err)
+		if errors.Is(err, model.ErrAmountPrecision) {
+			return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrAmountPrecisionMsg))
+		}
+		return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrCannotParseBodyMsg))
+	}
+	if ok, err := r.IsValid(); !ok {
+		return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, err.Error()))
+	}
+
+	body := struct {
+		HoldID int          `json:"holdId"`
+		Amount model.Amount `json:"amount"`
+	}{id, r.Amount}
+	return idempotent(c, ctr.IdempotencySvc, userID, body, func() (int, interface{}) {
+		hold, err := ctr.Svc.Capture(userID, id, r.Amount)
+		return holdUpdateResponse(id, hold, err)
+	})
+}
+
+// @Summary Voids hold.
+// @Description Releases whole held money back to the sender. Only the receiver of an active hold can void it.
+// @Security ApiKeyAuth
+// @Security APIKey
+// @ID VoidHold
+// @Tags holds
+// @Param id path int true "Hold ID."
+// @Produce  json
+// @Success 200 {object} model.HoldResponse
+// @Failure 400 {object} model.ErrResponse
+// @Failure 401 {object} model.ErrResponse
+// @Failure 403 {object} model.ErrResponse
+// @Failure 404 {object} model.ErrResponse
+// @Failure 409 {object} model.ErrResponse
+// @Failure 500 {object} model.ErrResponse
+// @Router /api/v1/holds/{id}/void [post]
+func (ctr *HoldController) VoidHold(c echo.Context) error {
+	log.Infof("POST %s", replaceID(holdVoidEndpoint, c.Param("id")))
+
+	userID, err := ctr.LoginSvc.GetUserIDFromToken(c)
+	if err != nil {
+		log.Errorf("error while reading user ID from token; error: %v", err)
+		return c.JSON(http.StatusUnauthorized, model.NewErrResponse(http.StatusUnauthorized, ErrInvalidTokenMsg))
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrInvalidHoldIDMsg))
+	}
+
+	hold, err := ctr.Svc.Void(userID, id)
+	return c.JSON(holdUpdateResponse(id, hold, err))
+}
+
+// holdUpdateResponse returns http code with response body of captured or voided hold.
+func holdUpdateResponse(id int, hold model.Hold, err error) (int, interface{}) {
+	if err != nil {
+		log.Errorf("cannot update hold %d; error: %v", id, err)
+		switch err {
+		case service.ErrHoldNotFound:
+			return http.StatusNotFound, model.NewErrResponse(http.StatusNotFound, ErrHoldNotFoundMsg)
+		case service.ErrHoldNotAllowed:
+			return http.StatusForbidden, model.NewErrResponse(http.StatusForbidden, ErrHoldNotAllowedMsg)
+		case service.ErrHoldNotActive:
+			return http.StatusConflict, model.NewErrResponse(http.StatusConflict, ErrHoldNotActiveMsg)
+		case service.ErrCaptureExceedsHold:
+			return http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrCaptureExceedsHoldMsg)
+		case service.ErrBalanceNotFound:
+			return http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrBalancesNotFoundMsg)
+		case service.ErrInsufficientBalance:
+			return http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrErrInsufficientBalanceMsg)
+		case service.ErrAmountNotAllowed:
+			return http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrAmountNotAllowedMsg)
+		}
+		return http.StatusInternalServerError, model.NewErrResponse(http.StatusInternalServerError, ErrInternalServerMsg)
+	}
+	return http.StatusOK, model.NewHoldResponse(hold)
+}
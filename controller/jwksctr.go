@@ -0,0 +1,34 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"zuzanna.com/walletapi/model"
+	"zuzanna.com/walletapi/service"
+)
+
+// JWKSController publishes public keys, so other services can verify JWT tokens without any shared secret.
+type JWKSController struct {
+	E    *echo.Echo
+	Keys service.JWTKeyProvider
+}
+
+func (ctr JWKSController) Init() {
+	ctr.E.GET(jwksEndpoint, ctr.GetJWKS)
+}
+
+// @Summary Retrieves public keys used to verify JWT tokens.
+// @Description Retrieves JSON Web Key Set with all keys active for verification, key is selected by kid header of the token.
+// @ID GetJWKS
+// @Tags login
+// @Produce  json
+// @Success 200 {object} model.JWKSResponse
+// @Router /.well-known/jwks.json [get]
+func (ctr JWKSController) GetJWKS(c echo.Context) error {
+	log.Infof("GET %s", jwksEndpoint)
+
+	c.Response().Header().Set("Cache-Control", "public, max-age=300")
+	return c.JSON(http.StatusOK, model.JWKSResponse{Keys: ctr.Keys.PublicKeys()})
+}
@@ -2,7 +2,9 @@ package controller
 
 import (
 	"errors"
+	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/gommon/log"
@@ -10,24 +12,42 @@ import (
 	"zuzanna.com/walletapi/service"
 )
 
+var ErrInvalidRefreshTokenMsg = "Invalid, expired or revoked refresh token."
+var ErrLoginLockedMsg = "Too many failed login attempts. Please try again later."
+var ErrInvalidMFATokenMsg = "Invalid, expired or used MFA token. Please login again."
+var ErrInvalidMFACodeMsg = "Invalid one-time code."
+var ErrReauthFailedMsg = "Re-authentication failed. Please double check password or one-time code."
+
 type LoginController struct {
-	E   *echo.Echo
-	Svc service.AuthService
+	E        *echo.Echo
+	Svc      service.AuthService
+	Throttle service.LoginThrottle
+	// JWT guards logout and re-authentication endpoints, see service.NewJWTMiddleware.
+	JWT echo.MiddlewareFunc
 }
 
 func (ctr LoginController) Init() {
 	ctr.E.POST(loginEndpoint, ctr.Login)
+	ctr.E.POST(loginMFAEndpoint, ctr.LoginMFA)
+	ctr.E.POST(refreshTokenEndpoint, ctr.Refresh)
+	ctr.E.POST(logoutEndpoint, ctr.Logout, ctr.JWT)
+	ctr.E.POST(reauthEndpoint, ctr.Reauthenticate, ctr.JWT)
 }
 
 // @Summary Provide your username and password for authentication.
-// @Description Login endpoint for getting JWT token.
+// @Description Login endpoint for getting JWT token and refresh token.
+// @Description When the user enabled two-factor authentication only MFA token is returned with status 202, it must be
+// @Description exchanged at /login/mfa within 5 minutes.
 // @ID Login
 // @Tags login
 // @Param username formData string true "User's username."
 // @Param password formData string true "User's password."
 // @Produce  json
 // @Success 201 {object} model.TokenResponse
+// @Success 202 {object} model.TokenResponse
 // @Failure 401 {object} model.ErrResponse
+// @Failure 429 {object} model.ErrResponse
+// @Header 429 {integer} Retry-After "Seconds after which login can be retried."
 // @Failure 500 {object} model.ErrResponse
 // @Router /login [post]
 // Login returns http response with JWT token required for other endpoints.
@@ -41,14 +61,219 @@ func (ctr LoginController) Login(c echo.Context) error {
 		return c.JSON(http.StatusUnauthorized, model.NewErrResponse(http.StatusUnauthorized, ErrWrongLoginMsg))
 	}
 
-	token, err := ctr.Svc.Authenticate(username, password)
+	// the attempt is counted as failed before the password is verified, so parallel guesses cannot pass the check at once
+	ip := c.RealIP()
+	retryAfter, err := ctr.Throttle.Attempt(username, ip)
+	if err != nil {
+		if errors.Is(err, service.ErrLoginLocked) {
+			c.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			return c.JSON(http.StatusTooManyRequests, model.NewErrResponse(http.StatusTooManyRequests, ErrLoginLockedMsg))
+		}
+		log.Errorf("error while checking login attempts of user %s; error %v", username, err)
+		return c.JSON(http.StatusInternalServerError, model.NewErrResponse(http.StatusInternalServerError, ErrInternalServerMsg))
+	}
+
+	tokens, err := ctr.Svc.Authenticate(username, password)
 	if err != nil {
 		if errors.Is(err, service.ErrUnauthorized) {
 			return c.JSON(http.StatusUnauthorized, model.NewErrResponse(http.StatusUnauthorized, ErrWrongLoginMsg))
 		}
-		log.Errorf("error while authenticate user %s; error %w", username, err)
+		log.Errorf("error while authenticate user %s; error %v", username, err)
+		cancelLoginAttempt(ctr.Throttle, username, ip)
+		return c.JSON(http.StatusInternalServerError, model.NewErrResponse(http.StatusInternalServerError, ErrInternalServerMsg))
+	}
+	if tokens.MFAToken != "" {
+		// failures are reset only after the one-time code is verified, so codes cannot be guessed with a known password
+		cancelLoginAttempt(ctr.Throttle, username, ip)
+		return c.JSON(http.StatusAccepted, model.NewTokenResponse(tokens))
+	}
+	if err = ctr.Throttle.RegisterSuccess(username, ip); err != nil {
+		log.Errorf("error while resetting failed logins of user %s; error %v", username, err)
+	}
+
+	return c.JSON(http.StatusCreated, model.NewTokenResponse(tokens))
+}
+
+// @Summary Provide one-time code to finish login with two-factor authentication.
+// @Description Exchanges MFA token returned by /login and TOTP code (or unused recovery code) for JWT token and refresh token.
+// @Description Failed attempts are throttled like failed logins.
+// @ID LoginMFA
+// @Tags login
+// @Param mfa_token formData string true "MFA token returned by /login."
+// @Param code formData string true "Code from authenticator app or recovery code."
+// @Produce  json
+// @Success 201 {object} model.TokenResponse
+// @Failure 401 {object} model.ErrResponse
+// @Failure 429 {object} model.ErrResponse
+// @Header 429 {integer} Retry-After "Seconds after which login can be retried."
+// @Failure 500 {object} model.ErrResponse
+// @Router /login/mfa [post]
+// LoginMFA returns http response with JWT token when one-time code is valid.
+func (ctr LoginController) LoginMFA(c echo.Context) error {
+	log.Infof("POST %s", loginMFAEndpoint)
+
+	mfaToken := c.FormValue("mfa_token")
+	code := c.FormValue("code")
+
+	username, err := ctr.Svc.ParseMFAToken(mfaToken)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidMFAToken) {
+			return c.JSON(http.StatusUnauthorized, model.NewErrResponse(http.StatusUnauthorized, ErrInvalidMFATokenMsg))
+		}
+		log.Errorf("error while parsing MFA token; error %v", err)
+		return c.JSON(http.StatusInternalServerError, model.NewErrResponse(http.StatusInternalServerError, ErrInternalServerMsg))
+	}
+
+	ip := c.RealIP()
+	retryAfter, err := ctr.Throttle.Attempt(username, ip)
+	if err != nil {
+		if errors.Is(err, service.ErrLoginLocked) {
+			c.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			return c.JSON(http.StatusTooManyRequests, model.NewErrResponse(http.StatusTooManyRequests, ErrLoginLockedMsg))
+		}
+		log.Errorf("error while checking login attempts of user %s; error %v", username, err)
+		return c.JSON(http.StatusInternalServerError, model.NewErrResponse(http.StatusInternalServerError, ErrInternalServerMsg))
+	}
+
+	tokens, err := ctr.Svc.VerifyMFA(mfaToken, code)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidMFACode) {
+			return c.JSON(http.StatusUnauthorized, model.NewErrResponse(http.StatusUnauthorized, ErrInvalidMFACodeMsg))
+		}
+		if errors.Is(err, service.ErrInvalidMFAToken) {
+			cancelLoginAttempt(ctr.Throttle, username, ip)
+			return c.JSON(http.StatusUnauthorized, model.NewErrResponse(http.StatusUnauthorized, ErrInvalidMFATokenMsg))
+		}
+		log.Errorf("error while verifying one-time code of user %s; error %v", username, err)
+		cancelLoginAttempt(ctr.Throttle, username, ip)
+		return c.JSON(http.StatusInternalServerError, model.NewErrResponse(http.StatusInternalServerError, ErrInternalServerMsg))
+	}
+	if err = ctr.Throttle.RegisterSuccess(username, ip); err != nil {
+		log.Errorf("error while resetting failed logins of user %s; error %v", username, err)
+	}
+
+	return c.JSON(http.StatusCreated, model.NewTokenResponse(tokens))
+}
+
+// @Summary Exchange refresh token for a new JWT token.
+// @Description Returns new JWT token and new refresh token, the refresh token sent in request cannot be used again.
+// @ID RefreshToken
+// @Tags login
+// @Param refresh_token formData string true "Refresh token returned by /login or previous refresh."
+// @Produce  json
+// @Success 201 {object} model.TokenResponse
+// @Failure 401 {object} model.ErrResponse
+// @Failure 500 {object} model.ErrResponse
+// @Router /token/refresh [post]
+// Refresh returns http response with new JWT token and rotated refresh token.
+func (ctr LoginController) Refresh(c echo.Context) error {
+	log.Infof("POST %s", refreshTokenEndpoint)
+
+	refreshToken := c.FormValue("refresh_token")
+	if refreshToken == "" {
+		return c.JSON(http.StatusUnauthorized, model.NewErrResponse(http.StatusUnauthorized, ErrInvalidRefreshTokenMsg))
+	}
+
+	tokens, err := ctr.Svc.Refresh(refreshToken)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidRefreshToken) {
+			return c.JSON(http.StatusUnauthorized, model.NewErrResponse(http.StatusUnauthorized, ErrInvalidRefreshTokenMsg))
+		}
+		log.Errorf("error while refreshing token; error %v", err)
+		return c.JSON(http.StatusInternalServerError, model.NewErrResponse(http.StatusInternalServerError, ErrInternalServerMsg))
+	}
+
+	return c.JSON(http.StatusCreated, model.NewTokenResponse(tokens))
+}
+
+// @Summary Logout authenticated user.
+// @Description Revokes JWT token used for the request and, if given, refresh token of the user.
+// @ID Logout
+// @Tags login
+// @Param refresh_token formData string false "Refresh token to revoke."
+// @Success 204
+// @Failure 400 {object} model.ErrResponse
+// @Failure 401 {object} model.ErrResponse
+// @Failure 500 {object} model.ErrResponse
+// @Router /logout [post]
+// @Security ApiKeyAuth
+// Logout revokes tokens of authenticated user.
+func (ctr LoginController) Logout(c echo.Context) error {
+	log.Infof("POST %s", logoutEndpoint)
+
+	err := ctr.Svc.Logout(c, c.FormValue("refresh_token"))
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidRefreshToken) {
+			return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrInvalidRefreshTokenMsg))
+		}
+		log.Errorf("error while logging out; error %v", err)
+		return c.JSON(http.StatusInternalServerError, model.NewErrResponse(http.StatusInternalServerError, ErrInternalServerMsg))
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// @Summary Confirm identity of authenticated user again.
+// @Description Returns new JWT token with current auth_time claim when password or one-time code (TOTP or recovery code) is valid.
+// @Description Transfers above step-up threshold are allowed only shortly after authentication. Failed attempts are throttled like failed logins.
+// @ID Reauthenticate
+// @Tags login
+// @Param password formData string false "User's password, required when code is not set."
+// @Param code formData string false "Code from authenticator app or recovery code."
+// @Produce  json
+// @Success 201 {object} model.TokenResponse
+// @Failure 401 {object} model.ErrResponse
+// @Failure 429 {object} model.ErrResponse
+// @Header 429 {integer} Retry-After "Seconds after which re-authentication can be retried."
+// @Failure 500 {object} model.ErrResponse
+// @Router /reauth [post]
+// @Security ApiKeyAuth
+// Reauthenticate returns http response with JWT token proving recent authentication.
+func (ctr LoginController) Reauthenticate(c echo.Context) error {
+	log.Infof("POST %s", reauthEndpoint)
+
+	password := c.FormValue("password")
+	code := c.FormValue("code")
+	if password == "" && code == "" {
+		return c.JSON(http.StatusUnauthorized, model.NewErrResponse(http.StatusUnauthorized, ErrReauthFailedMsg))
+	}
+
+	username, err := ctr.Svc.GetLogin(c)
+	if err != nil {
+		log.Errorf("error while reading login of authenticated user; error %v", err)
+		return c.JSON(http.StatusUnauthorized, model.NewErrResponse(http.StatusUnauthorized, ErrInvalidTokenMsg))
+	}
+
+	ip := c.RealIP()
+	retryAfter, err := ctr.Throttle.Attempt(username, ip)
+	if err != nil {
+		if errors.Is(err, service.ErrLoginLocked) {
+			c.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			return c.JSON(http.StatusTooManyRequests, model.NewErrResponse(http.StatusTooManyRequests, ErrLoginLockedMsg))
+		}
+		log.Errorf("error while checking login attempts of user %s; error %v", username, err)
 		return c.JSON(http.StatusInternalServerError, model.NewErrResponse(http.StatusInternalServerError, ErrInternalServerMsg))
 	}
 
-	return c.JSON(http.StatusCreated, model.TokenResponse{Token: token})
+	tokens, err := ctr.Svc.Reauthenticate(c, password, code)
+	if err != nil {
+		if errors.Is(err, service.ErrUnauthorized) || errors.Is(err, service.ErrInvalidMFACode) {
+			return c.JSON(http.StatusUnauthorized, model.NewErrResponse(http.StatusUnauthorized, ErrReauthFailedMsg))
+		}
+		log.Errorf("error while re-authenticating user %s; error %v", username, err)
+		cancelLoginAttempt(ctr.Throttle, username, ip)
+		return c.JSON(http.StatusInternalServerError, model.NewErrResponse(http.StatusInternalServerError, ErrInternalServerMsg))
+	}
+	if err = ctr.Throttle.RegisterSuccess(username, ip); err != nil {
+		log.Errorf("error while resetting failed logins of user %s; error %v", username, err)
+	}
+
+	return c.JSON(http.StatusCreated, model.NewTokenResponse(tokens))
+}
+
+// cancelLoginAttempt does not count the attempt which was not a wrong guess, e.g. it failed because of internal error.
+func cancelLoginAttempt(throttle service.LoginThrottle, username, ip string) {
+	if err := throttle.Cancel(username, ip); err != nil {
+		log.Errorf("error while cancelling login attempt of user %s; error %v", username, err)
+	}
 }
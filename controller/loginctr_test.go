@@ -11,6 +11,7 @@ import (
 
 	"github.com/labstack/echo/v4"
 	"zuzanna.com/walletapi/model"
+	"zuzanna.com/walletapi/repository"
 	"zuzanna.com/walletapi/service"
 )
 
@@ -20,20 +21,93 @@ type AuthServiceFake struct {
 	db map[string]string
 }
 
-func (svc AuthServiceFake) Authenticate(login, password string) (string, error) {
+var exampleRefreshToken = "q3Zk8yV1cXJ0b2tlbi1leGFtcGxl"
+
+var exampleMFAToken = "mfa-pending"
+
+func (svc AuthServiceFake) Authenticate(login, password string) (model.Tokens, error) {
+	if login == "mfa11" && svc.db[login] == password {
+		return model.Tokens{MFAToken: exampleMFAToken}, nil
+	}
 	if svc.db[login] == password {
-		return exampleToken, nil
+		return model.Tokens{AccessToken: exampleToken, RefreshToken: exampleRefreshToken}, nil
 	}
 	if login == "err" {
-		return "", errors.New("some internal error")
+		return model.Tokens{}, errors.New("some internal error")
+	}
+	return model.Tokens{}, service.ErrUnauthorized
+}
+
+func (svc AuthServiceFake) ParseMFAToken(mfaToken string) (string, error) {
+	if mfaToken == exampleMFAToken {
+		return "mfa11", nil
+	}
+	return "", service.ErrInvalidMFAToken
+}
+
+func (svc AuthServiceFake) VerifyMFA(mfaToken, code string) (model.Tokens, error) {
+	if _, err := svc.ParseMFAToken(mfaToken); err != nil {
+		return model.Tokens{}, err
+	}
+	if code == "287082" {
+		return model.Tokens{AccessToken: exampleToken, RefreshToken: exampleRefreshToken}, nil
+	}
+	return model.Tokens{}, service.ErrInvalidMFACode
+}
+
+func (svc AuthServiceFake) Refresh(refreshToken string) (model.Tokens, error) {
+	if refreshToken == exampleRefreshToken {
+		return model.Tokens{AccessToken: exampleToken, RefreshToken: "rotated"}, nil
+	}
+	if refreshToken == "err" {
+		return model.Tokens{}, errors.New("some internal error")
+	}
+	return model.Tokens{}, service.ErrInvalidRefreshToken
+}
+
+func (svc AuthServiceFake) Logout(c echo.Context, refreshToken string) error {
+	if refreshToken == "" || refreshToken == exampleRefreshToken {
+		return nil
+	}
+	if refreshToken == "err" {
+		return errors.New("some internal error")
 	}
-	return "", service.ErrUnauthorized
+	return service.ErrInvalidRefreshToken
+}
+
+func (svc AuthServiceFake) IsTokenRevoked(claims *service.JwtCustomClaims) (bool, error) {
+	return false, nil
 }
 
 func (svc AuthServiceFake) GetUserIDFromToken(echo.Context) (int, error) {
 	return 1, nil
 }
 
+func (svc AuthServiceFake) GetAuthTimeFromToken(echo.Context) (time.Time, error) {
+	return time.Time{}, nil
+}
+
+func (svc AuthServiceFake) GetRoleFromToken(echo.Context) (model.Role, error) {
+	return model.RoleUser, nil
+}
+
+func (svc AuthServiceFake) GetLogin(echo.Context) (string, error) {
+	return "ala11", nil
+}
+
+func (svc AuthServiceFake) Reauthenticate(c echo.Context, password, code string) (model.Tokens, error) {
+	if password == "haslo" || code == "287082" {
+		return model.Tokens{AccessToken: exampleToken}, nil
+	}
+	if password == "err" {
+		return model.Tokens{}, errors.New("some internal error")
+	}
+	if code != "" {
+		return model.Tokens{}, service.ErrInvalidMFACode
+	}
+	return model.Tokens{}, service.ErrUnauthorized
+}
+
 func prepareLoginRequest(username, password string) *http.Request {
 	req := httptest.NewRequest(http.MethodPost, "/login", nil)
 	req.Form = url.Values{}
@@ -72,6 +146,7 @@ func TestLogin(t *testing.T) {
 			Svc: AuthServiceFake{
 				db: map[string]string{"ala11": "haslo"},
 			},
+			Throttle: service.NewLoginThrottle(repository.NewMemoryLoginAttemptRepo(), service.DefaultLoginThrottlePolicy),
 		}
 		err := controller.Login(c)
 		if err != nil {
@@ -110,6 +185,257 @@ func TestLogin(t *testing.T) {
 			if respBody.Token != exampleToken {
 				t.Errorf("JWT token got: %s; want: %s", respBody.Token, exampleToken)
 			}
+			if respBody.RefreshToken != exampleRefreshToken {
+				t.Errorf("refresh token got: %s; want: %s", respBody.RefreshToken, exampleRefreshToken)
+			}
+		}
+	}
+}
+
+func TestLoginLocked(t *testing.T) {
+	e := echo.New()
+	controller := LoginController{
+		E:   e,
+		Svc: AuthServiceFake{db: map[string]string{"ala11": "haslo"}},
+		Throttle: service.NewLoginThrottle(repository.NewMemoryLoginAttemptRepo(), service.LoginThrottlePolicy{
+			MaxFailures:   2,
+			MaxIPFailures: 10,
+			Lockout:       time.Minute,
+		}),
+	}
+
+	for i, want := range []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests} {
+		rec := httptest.NewRecorder()
+		if err := controller.Login(e.NewContext(prepareLoginRequest("ala11", "wrongPass"), rec)); err != nil {
+			t.Errorf("error was not expected while making a login request: %s", err)
+		}
+		if rec.Code != want {
+			t.Errorf("http code of attempt %d got: %d; want: %d", i+1, rec.Code, want)
+		}
+	}
+
+	// correct password does not help while login is locked
+	rec := httptest.NewRecorder()
+	if err := controller.Login(e.NewContext(prepareLoginRequest("ala11", "haslo"), rec)); err != nil {
+		t.Errorf("error was not expected while making a login request: %s", err)
+	}
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("http code got: %d; want: %d", rec.Code, http.StatusTooManyRequests)
+	}
+	if retryAfter := rec.Header().Get("Retry-After"); retryAfter != "60" {
+		t.Errorf("Retry-After header got: %s; want: 60", retryAfter)
+	}
+	respBody := model.ErrResponse{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &respBody); err != nil {
+		t.Errorf("error was not expected while making a unmarshal body request: %s", err)
+	}
+	if respBody.Error != ErrLoginLockedMsg {
+		t.Errorf("ErrResponse error got: %s; want: %s", respBody.Error, ErrLoginLockedMsg)
+	}
+}
+
+func prepareRefreshTokenRequest(endpoint, refreshToken string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, endpoint, nil)
+	req.Form = url.Values{}
+	req.Form.Add("refresh_token", refreshToken)
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	return req
+}
+
+func TestRefresh(t *testing.T) {
+	cases := []struct {
+		refreshToken string
+		httpCode     int
+		errMsg       string
+	}{
+		{refreshToken: exampleRefreshToken, httpCode: http.StatusCreated},
+		{refreshToken: "revoked", httpCode: http.StatusUnauthorized, errMsg: ErrInvalidRefreshTokenMsg},
+		{refreshToken: "", httpCode: http.StatusUnauthorized, errMsg: ErrInvalidRefreshTokenMsg},
+		{refreshToken: "err", httpCode: http.StatusInternalServerError, errMsg: ErrInternalServerMsg},
+	}
+
+	for _, testCase := range cases {
+		e := echo.New()
+		rec := httptest.NewRecorder()
+		c := e.NewContext(prepareRefreshTokenRequest("/token/refresh", testCase.refreshToken), rec)
+		controller := LoginController{E: e, Svc: AuthServiceFake{}}
+		if err := controller.Refresh(c); err != nil {
+			t.Errorf("error was not expected while making a refresh request: %s", err)
+		}
+		if rec.Code != testCase.httpCode {
+			t.Errorf("http code got: %d; want: %d", rec.Code, testCase.httpCode)
+		}
+		if testCase.httpCode == http.StatusCreated {
+			respBody := model.TokenResponse{}
+			if err := json.Unmarshal(rec.Body.Bytes(), &respBody); err != nil {
+				t.Errorf("error was not expected while making a unmarshal body request: %s", err)
+			}
+			if respBody.Token != exampleToken || respBody.RefreshToken != "rotated" {
+				t.Errorf("tokens got: %+v; want: %s and rotated refresh token", respBody, exampleToken)
+			}
+			continue
+		}
+		respBody := model.ErrResponse{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &respBody); err != nil {
+			t.Errorf("error was not expected while making a unmarshal body request: %s", err)
+		}
+		if respBody.Error != testCase.errMsg {
+			t.Errorf("ErrResponse error got: %s; want: %s", respBody.Error, testCase.errMsg)
+		}
+	}
+}
+
+func TestLogout(t *testing.T) {
+	cases := []struct {
+		refreshToken string
+		httpCode     int
+	}{
+		{refreshToken: exampleRefreshToken, httpCode: http.StatusNoContent},
+		{refreshToken: "", httpCode: http.StatusNoContent},
+		{refreshToken: "other", httpCode: http.StatusBadRequest},
+		{refreshToken: "err", httpCode: http.StatusInternalServerError},
+	}
+
+	for _, testCase := range cases {
+		e := echo.New()
+		rec := httptest.NewRecorder()
+		c := e.NewContext(prepareRefreshTokenRequest("/logout", testCase.refreshToken), rec)
+		controller := LoginController{E: e, Svc: AuthServiceFake{}}
+		if err := controller.Logout(c); err != nil {
+			t.Errorf("error was not expected while making a logout request: %s", err)
+		}
+		if rec.Code != testCase.httpCode {
+			t.Errorf("http code for refresh token %q got: %d; want: %d", testCase.refreshToken, rec.Code, testCase.httpCode)
+		}
+	}
+}
+
+func prepareLoginMFARequest(mfaToken, code string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/login/mfa", nil)
+	req.Form = url.Values{}
+	req.Form.Add("mfa_token", mfaToken)
+	req.Form.Add("code", code)
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	return req
+}
+
+func TestLoginMFA(t *testing.T) {
+	e := echo.New()
+	controller := LoginController{
+		E:   e,
+		Svc: AuthServiceFake{db: map[string]string{"mfa11": "haslo"}},
+		Throttle: service.NewLoginThrottle(repository.NewMemoryLoginAttemptRepo(), service.LoginThrottlePolicy{
+			MaxFailures:   2,
+			MaxIPFailures: 10,
+			Lockout:       time.Minute,
+		}),
+	}
+
+	// password is correct, but one-time code is still required
+	rec := httptest.NewRecorder()
+	if err := controller.Login(e.NewContext(prepareLoginRequest("mfa11", "haslo"), rec)); err != nil {
+		t.Errorf("error was not expected while making a login request: %s", err)
+	}
+	if rec.Code != http.StatusAccepted {
+		t.Errorf("http code got: %d; want: %d", rec.Code, http.StatusAccepted)
+	}
+	tokenResp := model.TokenResponse{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &tokenResp); err != nil {
+		t.Errorf("error was not expected while making a unmarshal body request: %s", err)
+	}
+	if tokenResp.MFAToken != exampleMFAToken || tokenResp.Token != "" {
+		t.Errorf("tokens got: %+v; want only MFA token %s", tokenResp, exampleMFAToken)
+	}
+
+	cases := []struct {
+		mfaToken string
+		code     string
+		httpCode int
+		errMsg   string
+	}{
+		{mfaToken: "other", code: "287082", httpCode: http.StatusUnauthorized, errMsg: ErrInvalidMFATokenMsg},
+		{mfaToken: exampleMFAToken, code: "000000", httpCode: http.StatusUnauthorized, errMsg: ErrInvalidMFACodeMsg},
+		{mfaToken: exampleMFAToken, code: "287082", httpCode: http.StatusCreated},
+		// failed codes are throttled like failed passwords
+		{mfaToken: exampleMFAToken, code: "000000", httpCode: http.StatusUnauthorized, errMsg: ErrInvalidMFACodeMsg},
+		{mfaToken: exampleMFAToken, code: "000001", httpCode: http.StatusUnauthorized, errMsg: ErrInvalidMFACodeMsg},
+		{mfaToken: exampleMFAToken, code: "287082", httpCode: http.StatusTooManyRequests, errMsg: ErrLoginLockedMsg},
+	}
+	for i, testCase := range cases {
+		rec := httptest.NewRecorder()
+		if err := controller.LoginMFA(e.NewContext(prepareLoginMFARequest(testCase.mfaToken, testCase.code), rec)); err != nil {
+			t.Errorf("error was not expected while making a login request: %s", err)
+		}
+		if rec.Code != testCase.httpCode {
+			t.Errorf("http code of attempt %d got: %d; want: %d", i+1, rec.Code, testCase.httpCode)
+		}
+		if testCase.httpCode == http.StatusCreated {
+			respBody := model.TokenResponse{}
+			if err := json.Unmarshal(rec.Body.Bytes(), &respBody); err != nil {
+				t.Errorf("error was not expected while making a unmarshal body request: %s", err)
+			}
+			if respBody.Token != exampleToken || respBody.RefreshToken != exampleRefreshToken {
+				t.Errorf("tokens got: %+v; want: %s and %s", respBody, exampleToken, exampleRefreshToken)
+			}
+			continue
+		}
+		respBody := model.ErrResponse{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &respBody); err != nil {
+			t.Errorf("error was not expected while making a unmarshal body request: %s", err)
+		}
+		if respBody.Error != testCase.errMsg {
+			t.Errorf("ErrResponse error of attempt %d got: %s; want: %s", i+1, respBody.Error, testCase.errMsg)
+		}
+	}
+}
+
+func TestReauthenticate(t *testing.T) {
+	e := echo.New()
+	controller := LoginController{
+		E:   e,
+		Svc: AuthServiceFake{},
+		Throttle: service.NewLoginThrottle(repository.NewMemoryLoginAttemptRepo(), service.LoginThrottlePolicy{
+			MaxFailures:   3,
+			MaxIPFailures: 10,
+			Lockout:       time.Minute,
+		}),
+	}
+
+	cases := []struct {
+		password string
+		code     string
+		httpCode int
+	}{
+		{password: "haslo", httpCode: http.StatusCreated},
+		{code: "287082", httpCode: http.StatusCreated},
+		{password: "", code: "", httpCode: http.StatusUnauthorized},
+		{password: "err", httpCode: http.StatusInternalServerError},
+		{password: "wrongPass", httpCode: http.StatusUnauthorized},
+		{code: "000000", httpCode: http.StatusUnauthorized},
+		{password: "wrongPass", httpCode: http.StatusUnauthorized},
+		{password: "haslo", httpCode: http.StatusTooManyRequests},
+	}
+	for i, testCase := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/reauth", nil)
+		req.Form = url.Values{}
+		req.Form.Add("password", testCase.password)
+		req.Form.Add("code", testCase.code)
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		rec := httptest.NewRecorder()
+		if err := controller.Reauthenticate(e.NewContext(req, rec)); err != nil {
+			t.Errorf("error was not expected while making a re-authentication request: %s", err)
+		}
+		if rec.Code != testCase.httpCode {
+			t.Errorf("http code of attempt %d got: %d; want: %d", i+1, rec.Code, testCase.httpCode)
+		}
+		if rec.Code == http.StatusCreated {
+			respBody := model.TokenResponse{}
+			if err := json.Unmarshal(rec.Body.Bytes(), &respBody); err != nil {
+				t.Errorf("error was not expected while making a unmarshal body request: %s", err)
+			}
+			if respBody.Token != exampleToken || respBody.RefreshToken != "" {
+				t.Errorf("tokens got: %+v; want only JWT token %s", respBody, exampleToken)
+			}
 		}
 	}
 }
@@ -0,0 +1,100 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"zuzanna.com/walletapi/model"
+	"zuzanna.com/walletapi/service"
+)
+
+var ErrMFAAlreadyEnabledMsg = "Two-factor authentication is already enabled."
+var ErrMFANotEnrolledMsg = "TOTP secret was not enrolled. Please enroll it first."
+
+type MFAController struct {
+	G        *echo.Group
+	Svc      service.MFAService
+	LoginSvc service.AuthService
+}
+
+func (ctr MFAController) Init() {
+	ctr.G.POST(totpEndpoint, ctr.EnrollTOTP)
+	ctr.G.POST(totpConfirmEndpoint, ctr.ConfirmTOTP)
+}
+
+// @Summary Enrolls TOTP secret for two-factor authentication.
+// @Description Generates new TOTP secret and provisioning URI to be shown as QR code in authenticator app.
+// @Description The secret is not used for login until it is confirmed. Enrolling again replaces unconfirmed secret.
+// @Security ApiKeyAuth
+// @ID EnrollTOTP
+// @Tags mfa
+// @Produce  json
+// @Success 201 {object} model.TOTPEnrollmentResponse
+// @Failure 401 {object} model.ErrResponse
+// @Failure 409 {object} model.ErrResponse
+// @Failure 500 {object} model.ErrResponse
+// @Router /api/v1/mfa/totp [post]
+func (ctr MFAController) EnrollTOTP(c echo.Context) error {
+	log.Infof("POST %s", totpEndpoint)
+
+	userID, err := ctr.LoginSvc.GetUserIDFromToken(c)
+	if err != nil {
+		log.Errorf("error while reading user ID from token; error: %v", err)
+		return c.JSON(http.StatusUnauthorized, model.NewErrResponse(http.StatusUnauthorized, ErrInvalidTokenMsg))
+	}
+
+	enrollment, err := ctr.Svc.Enroll(userID)
+	if err != nil {
+		if err == service.ErrMFAAlreadyEnabled {
+			return c.JSON(http.StatusConflict, model.NewErrResponse(http.StatusConflict, ErrMFAAlreadyEnabledMsg))
+		}
+		return c.JSON(http.StatusInternalServerError, model.NewErrResponse(http.StatusInternalServerError, ErrInternalServerMsg))
+	}
+	return c.JSON(http.StatusCreated, model.TOTPEnrollmentResponse{Secret: enrollment.Secret, URI: enrollment.URI})
+}
+
+// @Summary Confirms enrolled TOTP secret with the first code.
+// @Description Enables two-factor authentication and returns recovery codes. Recovery codes are shown only once,
+// @Description each of them can be used instead of TOTP code for one login.
+// @Security ApiKeyAuth
+// @ID ConfirmTOTP
+// @Tags mfa
+// @Param code body model.MFACodeRequest true "Code from authenticator app."
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} model.RecoveryCodesResponse
+// @Failure 400 {object} model.ErrResponse
+// @Failure 401 {object} model.ErrResponse
+// @Failure 409 {object} model.ErrResponse
+// @Failure 500 {object} model.ErrResponse
+// @Router /api/v1/mfa/totp/confirm [post]
+func (ctr MFAController) ConfirmTOTP(c echo.Context) error {
+	log.Infof("POST %s", totpConfirmEndpoint)
+
+	userID, err := ctr.LoginSvc.GetUserIDFromToken(c)
+	if err != nil {
+		log.Errorf("error while reading user ID from token; error: %v", err)
+		return c.JSON(http.StatusUnauthorized, model.NewErrResponse(http.StatusUnauthorized, ErrInvalidTokenMsg))
+	}
+
+	r := new(model.MFACodeRequest)
+	if err = c.Bind(r); err != nil {
+		log.Errorf("cannot bind MFACodeRequest struct with the Request body; error: %v", err)
+		return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrCannotParseBodyMsg))
+	}
+
+	codes, err := ctr.Svc.Confirm(userID, r.Code)
+	if err != nil {
+		switch err {
+		case service.ErrInvalidMFACode:
+			return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrInvalidMFACodeMsg))
+		case service.ErrMFANotEnrolled:
+			return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrMFANotEnrolledMsg))
+		case service.ErrMFAAlreadyEnabled:
+			return c.JSON(http.StatusConflict, model.NewErrResponse(http.StatusConflict, ErrMFAAlreadyEnabledMsg))
+		}
+		return c.JSON(http.StatusInternalServerError, model.NewErrResponse(http.StatusInternalServerError, ErrInternalServerMsg))
+	}
+	return c.JSON(http.StatusOK, model.RecoveryCodesResponse{RecoveryCodes: codes})
+}
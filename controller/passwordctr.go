@@ -0,0 +1,158 @@
+package controller
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"zuzanna.com/walletapi/model"
+	"zuzanna.com/walletapi/service"
+)
+
+var ErrWrongPasswordMsg = "Current password does not match."
+var ErrInvalidResetTokenMsg = "Invalid, expired or used password reset token. Please request a new one."
+
+type PasswordController struct {
+	E *echo.Echo
+	// G is a group of endpoints guarded with JWT token.
+	G        *echo.Group
+	Svc      service.PasswordService
+	LoginSvc service.AuthService
+	Throttle service.LoginThrottle
+}
+
+func (ctr PasswordController) Init() {
+	ctr.G.PUT(mePasswordEndpoint, ctr.ChangePassword)
+	ctr.E.POST(passwordResetEndpoint, ctr.RequestReset)
+	ctr.E.POST(passwordResetConfirmEndpoint, ctr.ConfirmReset)
+}
+
+// @Summary Changes password of the logged user.
+// @Description Requires the current password. Password must have from 8 to 72 characters and contain at least one lowercase letter,
+// @Description one uppercase letter and one digit. All sessions of the user are ended, also the one used for this request,
+// @Description so the user has to login again with the new password.
+// @Security ApiKeyAuth
+// @ID ChangePassword
+// @Tags users
+// @Param password body model.PasswordChangeRequest true "Current and new password."
+// @Accept  json
+// @Produce  json
+// @Success 204
+// @Failure 400 {object} model.ErrResponse
+// @Failure 401 {object} model.ErrResponse
+// @Failure 403 {object} model.ErrResponse
+// @Failure 429 {object} model.ErrResponse
+// @Header 429 {integer} Retry-After "Seconds after which password change can be retried."
+// @Failure 500 {object} model.ErrResponse
+// @Router /api/v1/me/password [put]
+func (ctr PasswordController) ChangePassword(c echo.Context) error {
+	log.Infof("PUT %s", mePasswordEndpoint)
+
+	userID, err := ctr.LoginSvc.GetUserIDFromToken(c)
+	if err != nil {
+		log.Errorf("error while reading user ID from token; error: %v", err)
+		return c.JSON(http.StatusUnauthorized, model.NewErrResponse(http.StatusUnauthorized, ErrInvalidTokenMsg))
+	}
+	username, err := ctr.LoginSvc.GetLogin(c)
+	if err != nil {
+		log.Errorf("error while reading login of authenticated user; error %v", err)
+		return c.JSON(http.StatusUnauthorized, model.NewErrResponse(http.StatusUnauthorized, ErrInvalidTokenMsg))
+	}
+
+	r := new(model.PasswordChangeRequest)
+	if err = c.Bind(r); err != nil {
+		log.Errorf("cannot bind PasswordChangeRequest struct with the Request body; error: %v", err)
+		return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrCannotParseBodyMsg))
+	}
+
+	// guessing the current password with stolen token is throttled the same way as login
+	ip := c.RealIP()
+	retryAfter, err := ctr.Throttle.Attempt(username, ip)
+	if err != nil {
+		if errors.Is(err, service.ErrLoginLocked) {
+			c.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			return c.JSON(http.StatusTooManyRequests, model.NewErrResponse(http.StatusTooManyRequests, ErrLoginLockedMsg))
+		}
+		log.Errorf("error while checking login attempts of user %s; error %v", username, err)
+		return c.JSON(http.StatusInternalServerError, model.NewErrResponse(http.StatusInternalServerError, ErrInternalServerMsg))
+	}
+
+	err = ctr.Svc.Change(userID, r.CurrentPassword, r.NewPassword)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrWrongPassword):
+			return c.JSON(http.StatusForbidden, model.NewErrResponse(http.StatusForbidden, ErrWrongPasswordMsg))
+		case errors.Is(err, service.ErrWeakPassword):
+			cancelLoginAttempt(ctr.Throttle, username, ip)
+			return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, err.Error()))
+		}
+		log.Errorf("error while changing password of user %s; error %v", username, err)
+		cancelLoginAttempt(ctr.Throttle, username, ip)
+		return c.JSON(http.StatusInternalServerError, model.NewErrResponse(http.StatusInternalServerError, ErrInternalServerMsg))
+	}
+	if err = ctr.Throttle.RegisterSuccess(username, ip); err != nil {
+		log.Errorf("error while resetting failed logins of user %s; error %v", username, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// @Summary Requests password reset.
+// @Description Sends single-use password reset token to the user. The response is the same whether the login exists or not.
+// @ID RequestPasswordReset
+// @Tags users
+// @Param login body model.PasswordResetRequest true "Login of the user."
+// @Accept  json
+// @Produce  json
+// @Success 202
+// @Failure 400 {object} model.ErrResponse
+// @Failure 500 {object} model.ErrResponse
+// @Router /password/reset [post]
+func (ctr PasswordController) RequestReset(c echo.Context) error {
+	log.Infof("POST %s", passwordResetEndpoint)
+
+	r := new(model.PasswordResetRequest)
+	if err := c.Bind(r); err != nil || r.Login == "" {
+		log.Errorf("cannot bind PasswordResetRequest struct with the Request body; error: %v", err)
+		return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrCannotParseBodyMsg))
+	}
+
+	if err := ctr.Svc.RequestReset(r.Login); err != nil {
+		return c.JSON(http.StatusInternalServerError, model.NewErrResponse(http.StatusInternalServerError, ErrInternalServerMsg))
+	}
+	return c.NoContent(http.StatusAccepted)
+}
+
+// @Summary Sets new password with password reset token.
+// @Description Token can be used only once and before it expires. All sessions of the user are ended.
+// @ID ConfirmPasswordReset
+// @Tags users
+// @Param reset body model.PasswordResetConfirmRequest true "Password reset token and new password."
+// @Accept  json
+// @Produce  json
+// @Success 204
+// @Failure 400 {object} model.ErrResponse
+// @Failure 500 {object} model.ErrResponse
+// @Router /password/reset/confirm [post]
+func (ctr PasswordController) ConfirmReset(c echo.Context) error {
+	log.Infof("POST %s", passwordResetConfirmEndpoint)
+
+	r := new(model.PasswordResetConfirmRequest)
+	if err := c.Bind(r); err != nil {
+		log.Errorf("cannot bind PasswordResetConfirmRequest struct with the Request body; error: %v", err)
+		return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrCannotParseBodyMsg))
+	}
+
+	if err := ctr.Svc.Reset(r.Token, r.NewPassword); err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidResetToken):
+			return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrInvalidResetTokenMsg))
+		case errors.Is(err, service.ErrWeakPassword):
+			return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, err.Error()))
+		}
+		return c.JSON(http.StatusInternalServerError, model.NewErrResponse(http.StatusInternalServerError, ErrInternalServerMsg))
+	}
+	return c.NoContent(http.StatusNoContent)
+}
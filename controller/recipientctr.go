@@ -0,0 +1,69 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"zuzanna.com/walletapi/model"
+	"zuzanna.com/walletapi/service"
+)
+
+var ErrRecipientNotFoundMsg = "There is no user with such login, e-mail or phone."
+var ErrRecipientNoBalanceMsg = "Recipient has no balance in the transfer currency."
+var ErrReceiverRequiredMsg = "Receiver query param is required."
+
+type RecipientController struct {
+	G        *echo.Group
+	Svc      service.RecipientService
+	LoginSvc service.AuthService
+}
+
+func (ctr RecipientController) Init() {
+	ctr.G.GET(recipientsEndpoint, ctr.LookupRecipient)
+}
+
+// @Summary Looks up receiver of a transfer.
+// @Description Finds user by login, e-mail or phone alias (with + and country code), to confirm receiver's masked name before transfer.
+// @Description When currency is set the receiver must have balance in this currency, otherwise 400 is returned.
+// @Security ApiKeyAuth
+// @Security APIKey
+// @ID LookupRecipient
+// @Tags transactions
+// @Param receiver query string true "Login, e-mail or phone of the receiver."
+// @Param currency query string false "Currency of the transfer."
+// @Produce  json
+// @Success 200 {object} model.RecipientResponse
+// @Failure 400 {object} model.ErrResponse
+// @Failure 401 {object} model.ErrResponse
+// @Failure 404 {object} model.ErrResponse
+// @Failure 500 {object} model.ErrResponse
+// @Router /api/v1/recipients [get]
+func (ctr RecipientController) LookupRecipient(c echo.Context) error {
+	log.Infof("GET %s", recipientsEndpoint)
+
+	if _, err := ctr.LoginSvc.GetUserIDFromToken(c); err != nil {
+		log.Errorf("error while reading user ID from token; error: %v", err)
+		return c.JSON(http.StatusUnauthorized, model.NewErrResponse(http.StatusUnauthorized, ErrInvalidTokenMsg))
+	}
+	receiver := c.QueryParam("receiver")
+	if receiver == "" {
+		return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrReceiverRequiredMsg))
+	}
+	currency := model.Currency(c.QueryParam("currency"))
+	if currency != "" && !currency.IsValid() {
+		return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, model.ErrUnknownCurrency.Error()))
+	}
+
+	user, err := ctr.Svc.Lookup(receiver, currency)
+	if err != nil {
+		if err == service.ErrRecipientNotFound {
+			return c.JSON(http.StatusNotFound, model.NewErrResponse(http.StatusNotFound, ErrRecipientNotFoundMsg))
+		}
+		if err == service.ErrRecipientNoBalance {
+			return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrRecipientNoBalanceMsg))
+		}
+		return c.JSON(http.StatusInternalServerError, model.NewErrResponse(http.StatusInternalServerError, ErrInternalServerMsg))
+	}
+	return c.JSON(http.StatusOK, model.RecipientResponse{Receiver: receiver, Name: user.MaskedName()})
+}
@@ -0,0 +1,267 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"zuzanna.com/walletapi/model"
+	"zuzanna.com/walletapi/service"
+)
+
+var ErrInvalidScheduleIDMsg = "Schedule ID must be a positive number."
+var ErrScheduleNotFoundMsg = "Schedule not found."
+var ErrScheduleStatusMsg = "Only active schedule can be paused, only paused schedule can be resumed and only active or paused schedule can be cancelled."
+var ErrInvalidScheduleStartMsg = "Schedule must start in the future, at most in a year."
+
+type ScheduleController struct {
+	G              *echo.Group
+	Svc            service.ScheduleService
+	LoginSvc       service.AuthService
+	IdempotencySvc service.IdempotencyService
+	RecipientSvc   service.RecipientService
+}
+
+func (ctr *ScheduleController) Init() {
+	ctr.G.POST(schedulesEndpoint, ctr.CreateSchedule)
+	ctr.G.GET(schedulesEndpoint, ctr.ListSchedules)
+	ctr.G.GET(scheduleEndpoint, ctr.GetSchedule)
+	ctr.G.POST(schedulePauseEndpoint, ctr.PauseSchedule)
+	ctr.G.POST(scheduleResumeEndpoint, ctr.ResumeSchedule)
+	ctr.G.POST(scheduleCancelEndpoint, ctr.CancelSchedule)
+}
+
+// @Summary Schedules transfer.
+// @Description Schedules transfer at startAt, repeated daily, weekly or monthly on dayOfMonth when recurrence is set.
+// @Description The last day of the month is used in months shorter than dayOfMonth. Receiver given by alias is resolved to its balance now.
+// @Description Transfer failed because of insufficient balance or internal error is retried with backoff, other failures fail the schedule, see lastError.
+// @Description Schedule above step-up threshold requires recent authentication, scheduled transfers are then executed without it.
+// @Security ApiKeyAuth
+// @ID CreateSchedule
+// @Tags schedules
+// @Param schedule body model.ScheduleRequest true "Schedule definition."
+// @Param Idempotency-Key header string false "Unique key of the request. Retried request with the same key and body returns the original response."
+// @Accept  json
+// @Produce  json
+// @Success 201 {object} model.ScheduleResponse
+// @Failure 400 {object} model.ErrResponse
+// @Failure 401 {object} model.ErrResponse
+// @Failure 403 {object} model.ErrResponse
+// @Failure 409 {object} model.ErrResponse
+// @Failure 422 {object} model.ErrResponse
+// @Failure 500 {object} model.ErrResponse
+// @Router /api/v1/schedules [post]
+func (ctr *ScheduleController) CreateSchedule(c echo.Context) error {
+	log.Infof("POST %s", schedulesEndpoint)
+
+	userID, err := ctr.LoginSvc.GetUserIDFromToken(c)
+	if err != nil {
+		log.Errorf("error while reading user ID from token; error: %v", err)
+		return c.JSON(http.StatusUnauthorized, model.NewErrResponse(http.StatusUnauthorized, ErrInvalidTokenMsg))
+	}
+	authTime, err := ctr.LoginSvc.GetAuthTimeFromToken(c)
+	if err != nil {
+		log.Errorf("error while reading auth time from token; error: %v", err)
+		return c.JSON(http.StatusUnauthorized, model.NewErrResponse(http.StatusUnauthorized, ErrInvalidTokenMsg))
+	}
+
+	s := new(model.ScheduleRequest)
+	if err = c.Bind(s); err != nil {
+		log.Errorf("cannot bind ScheduleRequest struct with the Request body; error: %v", err)
+		if errors.Is(err, model.ErrAmountPrecision) {
+			return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrAmountPrecisionMsg))
+		}
+		return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrCannotParseBodyMsg))
+	}
+	if ok, err := s.IsValid(); !ok {
+		return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, err.Error()))
+	}
+
+	return idempotent(c, ctr.IdempotencySvc, userID, s, func() (int, interface{}) {
+		return ctr.createSchedule(userID, authTime, *s)
+	})
+}
+
+// createSchedule creates schedule and returns http code with response body.
+func (ctr *ScheduleController) createSchedule(userID int, authTime time.Time, s model.ScheduleRequest) (int, interface{}) {
+	if code, resp, ok := resolveReceiver(ctr.RecipientSvc, userID, &s.TransactionRequest); !ok {
+		return code, resp
+	}
+
+	schedule, err := ctr.Svc.Create(userID, s.ToSchedule(), authTime)
+	if err != nil {
+		log.Errorf("cannot create schedule; error: %v", err)
+		switch err {
+		case service.ErrBalanceNotFound:
+			return http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrBalancesNotFoundMsg)
+		case service.ErrInvalidScheduleStart:
+			return http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrInvalidScheduleStartMsg)
+		case service.ErrCurrencyMismatch:
+			return http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrCurrencyMismatchMsg)
+		case service.ErrAmountNotAllowed:
+			return http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrAmountNotAllowedMsg)
+		case service.ErrStepUpRequired:
+			return http.StatusForbidden, model.NewErrResponse(http.StatusForbidden, ErrStepUpRequiredMsg)
+		}
+		return http.StatusInternalServerError, model.NewErrResponse(http.StatusInternalServerError, ErrInternalServerMsg)
+	}
+	return http.StatusCreated, model.NewScheduleResponse(schedule)
+}
+
+// @Summary Lists schedules.
+// @Description Lists all schedules of the authenticated user, the newest first.
+// @Security ApiKeyAuth
+// @ID ListSchedules
+// @Tags schedules
+// @Produce  json
+// @Success 200 {array} model.ScheduleResponse
+// @Failure 401 {object} model.ErrResponse
+// @Failure 500 {object} model.ErrResponse
+// @Router /api/v1/schedules [get]
+func (ctr *ScheduleController) ListSchedules(c echo.Context) error {
+	log.Infof("GET %s", schedulesEndpoint)
+
+	userID, err := ctr.LoginSvc.GetUserIDFromToken(c)
+	if err != nil {
+		log.Errorf("error while reading user ID from token; error: %v", err)
+		return c.JSON(http.StatusUnauthorized, model.NewErrResponse(http.StatusUnauthorized, ErrInvalidTokenMsg))
+	}
+
+	schedules, err := ctr.Svc.List(userID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, model.NewErrResponse(http.StatusInternalServerError, ErrInternalServerMsg))
+	}
+	return c.JSON(http.StatusOK, model.NewScheduleResponses(schedules))
+}
+
+// @Summary Retrieves schedule.
+// @Description Retrieves schedule of the authenticated user with the result of its last run. Schedules of other users are not found.
+// @Security ApiKeyAuth
+// @ID GetSchedule
+// @Tags schedules
+// @Param id path int true "Schedule ID."
+// @Produce  json
+// @Success 200 {object} model.ScheduleResponse
+// @Failure 400 {object} model.ErrResponse
+// @Failure 401 {object} model.ErrResponse
+// @Failure 404 {object} model.ErrResponse
+// @Failure 500 {object} model.ErrResponse
+// @Router /api/v1/schedules/{id} [get]
+func (ctr *ScheduleController) GetSchedule(c echo.Context) error {
+	log.Infof("GET %s", replaceID(scheduleEndpoint, c.Param("id")))
+
+	userID, id, errResp := ctr.bindScheduleID(c)
+	if errResp != nil {
+		return errResp
+	}
+	schedule, err := ctr.Svc.Get(userID, id)
+	return c.JSON(scheduleUpdateResponse(id, schedule, err))
+}
+
+// @Summary Pauses schedule.
+// @Description Stops executing active schedule until it is resumed.
+// @Security ApiKeyAuth
+// @ID PauseSchedule
+// @Tags schedules
+// @Param id path int true "Schedule ID."
+// @Produce  json
+// @Success 200 {object} model.ScheduleResponse
+// @Failure 400 {object} model.ErrResponse
+// @Failure 401 {object} model.ErrResponse
+// @Failure 404 {object} model.ErrResponse
+// @Failure 409 {object} model.ErrResponse
+// @Failure 500 {object} model.ErrResponse
+// @Router /api/v1/schedules/{id}/pause [post]
+func (ctr *ScheduleController) PauseSchedule(c echo.Context) error {
+	log.Infof("POST %s", replaceID(schedulePauseEndpoint, c.Param("id")))
+
+	userID, id, errResp := ctr.bindScheduleID(c)
+	if errResp != nil {
+		return errResp
+	}
+	schedule, err := ctr.Svc.Pause(userID, id)
+	return c.JSON(scheduleUpdateResponse(id, schedule, err))
+}
+
+// @Summary Resumes schedule.
+// @Description Continues executing paused schedule. Transfer missed while the schedule was paused is executed at once,
+// @Description the other missed transfers are skipped.
+// @Security ApiKeyAuth
+// @ID ResumeSchedule
+// @Tags schedules
+// @Param id path int true "Schedule ID."
+// @Produce  json
+// @Success 200 {object} model.ScheduleResponse
+// @Failure 400 {object} model.ErrResponse
+// @Failure 401 {object} model.ErrResponse
+// @Failure 404 {object} model.ErrResponse
+// @Failure 409 {object} model.ErrResponse
+// @Failure 500 {object} model.ErrResponse
+// @Router /api/v1/schedules/{id}/resume [post]
+func (ctr *ScheduleController) ResumeSchedule(c echo.Context) error {
+	log.Infof("POST %s", replaceID(scheduleResumeEndpoint, c.Param("id")))
+
+	userID, id, errResp := ctr.bindScheduleID(c)
+	if errResp != nil {
+		return errResp
+	}
+	schedule, err := ctr.Svc.Resume(userID, id)
+	return c.JSON(scheduleUpdateResponse(id, schedule, err))
+}
+
+// @Summary Cancels schedule.
+// @Description Stops executing active or paused schedule for good.
+// @Security ApiKeyAuth
+// @ID CancelSchedule
+// @Tags schedules
+// @Param id path int true "Schedule ID."
+// @Produce  json
+// @Success 200 {object} model.ScheduleResponse
+// @Failure 400 {object} model.ErrResponse
+// @Failure 401 {object} model.ErrResponse
+// @Failure 404 {object} model.ErrResponse
+// @Failure 409 {object} model.ErrResponse
+// @Failure 500 {object} model.ErrResponse
+// @Router /api/v1/schedules/{id}/cancel [post]
+func (ctr *ScheduleController) CancelSchedule(c echo.Context) error {
+	log.Infof("POST %s", replaceID(scheduleCancelEndpoint, c.Param("id")))
+
+	userID, id, errResp := ctr.bindScheduleID(c)
+	if errResp != nil {
+		return errResp
+	}
+	schedule, err := ctr.Svc.Cancel(userID, id)
+	return c.JSON(scheduleUpdateResponse(id, schedule, err))
+}
+
+// bindScheduleID reads user ID from token and schedule ID from path, it returns written error response when they are invalid.
+func (ctr *ScheduleController) bindScheduleID(c echo.Context) (int, int, error) {
+	userID, err := ctr.LoginSvc.GetUserIDFromToken(c)
+	if err != nil {
+		log.Errorf("error while reading user ID from token; error: %v", err)
+		return 0, 0, c.JSON(http.StatusUnauthorized, model.NewErrResponse(http.StatusUnauthorized, ErrInvalidTokenMsg))
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		return 0, 0, c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrInvalidScheduleIDMsg))
+	}
+	return userID, id, nil
+}
+
+// scheduleUpdateResponse returns http code with response body of retrieved or updated schedule.
+func scheduleUpdateResponse(id int, schedule model.Schedule, err error) (int, interface{}) {
+	if err != nil {
+		log.Errorf("cannot retrieve or update schedule %d; error: %v", id, err)
+		switch err {
+		case service.ErrScheduleNotFound:
+			return http.StatusNotFound, model.NewErrResponse(http.StatusNotFound, ErrScheduleNotFoundMsg)
+		case service.ErrScheduleStatus:
+			return http.StatusConflict, model.NewErrResponse(http.StatusConflict, ErrScheduleStatusMsg)
+		}
+		return http.StatusInternalServerError, model.NewErrResponse(http.StatusInternalServerError, ErrInternalServerMsg)
+	}
+	return http.StatusOK, model.NewScheduleResponse(schedule)
+}
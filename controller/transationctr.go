@@ -1,8 +1,11 @@
 package controller
 
 import (
-	"math"
+	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/gommon/log"
@@ -11,33 +14,62 @@ import (
 )
 
 var ErrCannotParseBodyMsg = "Could not parse body request. Please doble check the JSON."
+var ErrAmountPrecisionMsg = "Amount cannot have more than 2 decimal places."
 var ErrUnauthorizedTransactionMsg = "User has no privilages to make requested transaction."
 var ErrErrInsufficientBalanceMsg = "There is not enough money on sender's balance to make requested transaction."
-var ErrBalancesLockedMsg = "Sender or receiver balance is locked - no money transfer allowed right now."
 var ErrBalancesNotFoundMsg = "Sender or receiver balance not found."
+var ErrCurrencyMismatchMsg = "Currency of sender and receiver balances differ. Set convert flag to transfer money between different currencies."
+var ErrConversionNotSupportedMsg = "Currency conversion is not supported."
+var ErrConversionRateNotFoundMsg = "There is no exchange rate between currencies of sender and receiver balances."
+var ErrAmountNotAllowedMsg = "Amount has more decimal places than the currency of sender balance allows."
+var ErrInvalidIdempotencyKeyMsg = "Idempotency-Key header cannot be longer than 255 characters."
+var ErrIdempotencyKeyMismatchMsg = "Idempotency-Key was already used for a different request."
+var ErrIdempotencyKeyInProgressMsg = "Request with the same Idempotency-Key is still in progress."
+var ErrInvalidTransactionFilterMsg = "Limit and counterpartyBalanceId query params must be numbers."
+var ErrInvalidTransactionIDMsg = "Transaction ID must be a positive number."
+var ErrTransactionNotFoundMsg = "Transaction not found."
+var ErrSameBalanceMsg = "Sender and receiver balances cannot be the same."
+var ErrRefundNotAllowedMsg = "Only receiver of the transaction or admin can refund it."
+var ErrRefundExceedsAmountMsg = "Refund amount exceeds not refunded amount of the transaction."
+var ErrRefundOfRefundMsg = "Refund cannot be refunded."
+var ErrStepUpRequiredMsg = "Transfer amount requires recent authentication. Please confirm your password or one-time code at /reauth and retry with the new token."
 
 type TransactionController struct {
-	G        *echo.Group
-	Svc      service.TransactionService
-	LoginSvc service.AuthService
+	G              *echo.Group
+	Svc            service.TransactionService
+	LoginSvc       service.AuthService
+	IdempotencySvc service.IdempotencyService
+	RecipientSvc   service.RecipientService
+	// UserSvc is used to add counterparty names to transactions, names are not added when it is nil.
+	UserSvc service.UserService
 }
 
 func (ctr *TransactionController) Init() {
 	ctr.G.GET(transactionsEndpoint, ctr.RetriveTransactions)
 	ctr.G.POST(transactionsEndpoint, ctr.ExecuteTransaction)
+	ctr.G.GET(transactionEndpoint, ctr.GetTransaction)
+	ctr.G.POST(transactionRefundEndpoint, ctr.RefundTransaction)
 }
 
 // @Summary Executes transaction between two balances.
 // @Description Triggers transfer of money from sender balance to receiver balance.
+// @Description Receiver can be given by receiverBalanceId or by receiver - login, e-mail or phone alias (with + and country code),
+// @Description money is then sent to the receiver's balance in currency of the sender balance. See /api/v1/recipients.
+// @Description Transfer above step-up threshold requires token issued by /login, /login/mfa or /reauth within the last minutes, otherwise 403 is returned.
 // @Security ApiKeyAuth
+// @Security APIKey
 // @ID ExecuteTransaction
 // @Tags transactions
 // @Param user body model.TransactionRequest true "Transaction definifion."
+// @Param Idempotency-Key header string false "Unique key of the request. Retried request with the same key and body returns the original response."
 // @Accept  json
 // @Produce  json
 // @Success 201 {object} model.TransactionResponse
 // @Failure 400 {object} model.ErrResponse
 // @Failure 401 {object} model.ErrResponse
+// @Failure 403 {object} model.ErrResponse
+// @Failure 409 {object} model.ErrResponse
+// @Failure 422 {object} model.ErrResponse
 // @Failure 500 {object} model.ErrResponse
 // @Router /api/v1/transactions [post]
 func (ctr *TransactionController) ExecuteTransaction(c echo.Context) error {
@@ -45,7 +77,12 @@ func (ctr *TransactionController) ExecuteTransaction(c echo.Context) error {
 
 	userID, err := ctr.LoginSvc.GetUserIDFromToken(c)
 	if err != nil {
-		log.Errorf("error while reading user ID from token; error: ", err)
+		log.Errorf("error while reading user ID from token; error: %v", err)
+		return c.JSON(http.StatusUnauthorized, model.NewErrResponse(http.StatusUnauthorized, ErrInvalidTokenMsg))
+	}
+	authTime, err := ctr.LoginSvc.GetAuthTimeFromToken(c)
+	if err != nil {
+		log.Errorf("error while reading auth time from token; error: %v", err)
 		return c.JSON(http.StatusUnauthorized, model.NewErrResponse(http.StatusUnauthorized, ErrInvalidTokenMsg))
 	}
 
@@ -53,7 +90,10 @@ func (ctr *TransactionController) ExecuteTransaction(c echo.Context) error {
 	err = c.Bind(t)
 
 	if err != nil {
-		log.Errorf("cannot bind TransactionRequest struct with the Request body; error: %w", err)
+		log.Errorf("cannot bind TransactionRequest struct with the Request body; error: %v", err)
+		if errors.Is(err, model.ErrAmountPrecision) {
+			return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrAmountPrecisionMsg))
+		}
 		return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrCannotParseBodyMsg))
 	}
 
@@ -61,41 +101,162 @@ func (ctr *TransactionController) ExecuteTransaction(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, err.Error()))
 	}
 
+	return idempotent(c, ctr.IdempotencySvc, userID, t, func() (int, interface{}) {
+		return ctr.executeTransaction(userID, authTime, *t)
+	})
+}
+
+// idempotent executes request with execute once per Idempotency-Key header of the user, retried request with the same key
+// and body gets the saved response. Without the header or svc the request is always executed.
+func idempotent(c echo.Context, svc service.IdempotencyService, userID int, body interface{}, execute func() (int, interface{})) error {
+	key := c.Request().Header.Get(idempotencyKeyHeader)
+	if key == "" || svc == nil {
+		return c.JSON(execute())
+	}
+
+	request, err := json.Marshal(body)
+	if err != nil {
+		log.Errorf("cannot marshal %T for idempotency key fingerprint; error: %v", body, err)
+		return c.JSON(http.StatusInternalServerError, model.NewErrResponse(http.StatusInternalServerError, ErrInternalServerMsg))
+	}
+	saved, err := svc.Start(userID, key, request)
+	if err != nil {
+		if err == service.ErrInvalidIdempotencyKey {
+			return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrInvalidIdempotencyKeyMsg))
+		}
+		if err == service.ErrIdempotencyKeyMismatch {
+			return c.JSON(http.StatusUnprocessableEntity, model.NewErrResponse(http.StatusUnprocessableEntity, ErrIdempotencyKeyMismatchMsg))
+		}
+		if err == service.ErrIdempotencyKeyInProgress {
+			return c.JSON(http.StatusConflict, model.NewErrResponse(http.StatusConflict, ErrIdempotencyKeyInProgressMsg))
+		}
+		log.Errorf("cannot start request with idempotency key %s; error: %v", key, err)
+		return c.JSON(http.StatusInternalServerError, model.NewErrResponse(http.StatusInternalServerError, ErrInternalServerMsg))
+	}
+	if saved.IsCompleted() {
+		log.Infof("replaying response for idempotency key %s", key)
+		return c.JSONBlob(saved.ResponseCode, saved.ResponseBody)
+	}
+
+	code, resp := execute()
+	finishIdempotentRequest(svc, userID, key, code, resp)
+	return c.JSON(code, resp)
+}
+
+// finishIdempotentRequest saves response for the idempotency key. Server errors and step-up rejections are not saved
+// so the request can be retried, the latter after re-authentication.
+func finishIdempotentRequest(svc service.IdempotencyService, userID int, key string, code int, resp interface{}) {
+	if code >= http.StatusInternalServerError || code == http.StatusForbidden {
+		if err := svc.Release(userID, key); err != nil {
+			log.Errorf("cannot release idempotency key %s; error: %v", key, err)
+		}
+		return
+	}
+	b, err := json.Marshal(resp)
+	if err == nil {
+		err = svc.Finish(userID, key, code, b)
+	}
+	if err != nil {
+		log.Errorf("cannot save response for idempotency key %s; error: %v", key, err)
+	}
+}
+
+// executeTransaction executes transaction and returns http code with response body.
+func (ctr *TransactionController) executeTransaction(userID int, authTime time.Time, t model.TransactionRequest) (int, interface{}) {
+	if code, resp, ok := resolveReceiver(ctr.RecipientSvc, userID, &t); !ok {
+		return code, resp
+	}
+
 	transaction := model.Transaction{
-		SenderBalanceID:   t.SenderBalanceID,
-		ReceiverBalanceID: t.ReceiverBalanceID,
-		Amount:            math.Floor(t.Amount*100) / 100,
-		Currency:          model.SGD,
+		SenderBalanceID:    t.SenderBalanceID,
+		ReceiverBalanceID:  t.ReceiverBalanceID,
+		Amount:             t.Amount,
+		Currency:           model.Currency(t.Currency),
+		Convert:            t.Convert,
+		TransactionDetails: t.Details(),
 	}
 
-	transaction, err = ctr.Svc.Execute(userID, transaction)
+	transaction, err := ctr.Svc.Execute(userID, transaction, authTime)
 	if err != nil {
 		log.Errorf("cannot execute transaction; error: %v", err)
 		if err == service.ErrBalanceNotFound {
-			return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrBalancesNotFoundMsg))
-		}
-		if err == service.ErrBalancesLocked {
-			return c.JSON(http.StatusConflict, model.NewErrResponse(http.StatusConflict, ErrBalancesLockedMsg))
+			return http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrBalancesNotFoundMsg)
 		}
 		if err == service.ErrInsufficientBalance {
-			return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrErrInsufficientBalanceMsg))
+			return http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrErrInsufficientBalanceMsg)
+		}
+		if err == service.ErrCurrencyMismatch {
+			return http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrCurrencyMismatchMsg)
+		}
+		if err == service.ErrConversionNotSupported {
+			return http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrConversionNotSupportedMsg)
+		}
+		if err == service.ErrConversionRateNotFound {
+			return http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrConversionRateNotFoundMsg)
+		}
+		if err == service.ErrAmountNotAllowed {
+			return http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrAmountNotAllowedMsg)
 		}
 		if err == service.ErrUnauthorizedTransaction {
-			return c.JSON(http.StatusUnauthorized, model.NewErrResponse(http.StatusUnauthorized, ErrUnauthorizedTransactionMsg))
+			return http.StatusUnauthorized, model.NewErrResponse(http.StatusUnauthorized, ErrUnauthorizedTransactionMsg)
 		}
-		return c.JSON(http.StatusInternalServerError, model.NewErrResponse(http.StatusInternalServerError, ErrInternalServerMsg))
+		if err == service.ErrStepUpRequired {
+			return http.StatusForbidden, model.NewErrResponse(http.StatusForbidden, ErrStepUpRequiredMsg)
+		}
+		return http.StatusInternalServerError, model.NewErrResponse(http.StatusInternalServerError, ErrInternalServerMsg)
 	}
 
-	return c.JSON(http.StatusCreated, model.NewTransactionResponse(transaction))
+	return http.StatusCreated, model.NewTransactionResponse(transaction)
+}
+
+// resolveReceiver sets ReceiverBalanceID of the request with receiver alias. It returns http code with response body
+// and false when the receiver cannot be resolved.
+func resolveReceiver(svc service.RecipientService, userID int, t *model.TransactionRequest) (int, interface{}, bool) {
+	if t.Receiver == "" {
+		return 0, nil, true
+	}
+	balanceID, err := svc.ResolveBalance(userID, t.SenderBalanceID, t.Receiver)
+	if err != nil {
+		log.Errorf("cannot resolve receiver balance; error: %v", err)
+		switch err {
+		case service.ErrBalanceNotFound:
+			return http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrBalancesNotFoundMsg), false
+		case service.ErrRecipientNotFound:
+			return http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrRecipientNotFoundMsg), false
+		case service.ErrRecipientNoBalance:
+			return http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrRecipientNoBalanceMsg), false
+		case service.ErrSameBalance:
+			return http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrSameBalanceMsg), false
+		}
+		return http.StatusInternalServerError, model.NewErrResponse(http.StatusInternalServerError, ErrInternalServerMsg), false
+	}
+	t.ReceiverBalanceID = balanceID
+	return 0, nil, true
 }
 
 // @Summary Retrives list of transactions.
-// @Description Retrives list of transactions for the authenticated user.
+// @Description Retrives page of transactions for the authenticated user, the newest first. Send next cursor of the page
+// @Description with the same filters to get the next page, there are no more transactions when next cursor is not returned.
 // @Security ApiKeyAuth
+// @Security APIKey
 // @ID RetriveTransactions
 // @Tags transactions
+// @Param limit query int false "Number of transactions on the page, from 1 to 100, default 50."
+// @Param cursor query string false "Next cursor from the previous page."
+// @Param from query string false "Only transactions made at or after the date, RFC 3339, e.g. 2026-10-01T00:00:00Z."
+// @Param to query string false "Only transactions made before the date, RFC 3339."
+// @Param minAmount query number false "Minimal sent amount."
+// @Param maxAmount query number false "Maximal sent amount."
+// @Param direction query string false "Only sent or received transactions." Enums(sent, received)
+// @Param counterpartyBalanceId query int false "Only transactions with the balance."
+// @Param currency query string false "Only transactions in the sent currency."
+// @Param reference query string false "Only transactions with the reference."
+// @Param memo query string false "Only transactions with memo containing the text."
+// @Param metadata query string false "Only transactions with the metadata pair given as key:value."
+// @Param expand query string false "Set to counterparty to add display name of the other party of each transaction." Enums(counterparty)
 // @Produce  json
-// @Success 200 {array} model.TransactionResponse
+// @Success 200 {object} model.TransactionPageResponse
+// @Failure 400 {object} model.ErrResponse
 // @Failure 401 {object} model.ErrResponse
 // @Failure 500 {object} model.ErrResponse
 // @Router /api/v1/transactions [get]
@@ -104,14 +265,194 @@ func (ctr *TransactionController) RetriveTransactions(c echo.Context) error {
 
 	userID, err := ctr.LoginSvc.GetUserIDFromToken(c)
 	if err != nil {
-		log.Errorf("error while reading user ID from token; error: ", err)
+		log.Errorf("error while reading user ID from token; error: %v", err)
 		return c.JSON(http.StatusUnauthorized, model.NewErrResponse(http.StatusUnauthorized, ErrInvalidTokenMsg))
 	}
 
-	transactions, err := ctr.Svc.Retrieve(userID)
+	filter, err := bindTransactionFilter(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, err.Error()))
+	}
+
+	page, err := ctr.Svc.Retrieve(userID, filter)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, model.NewErrResponse(http.StatusInternalServerError, ErrInternalServerMsg))
 	}
 
-	return c.JSON(http.StatusOK, model.NewTransactionResponses(transactions))
+	resp := model.NewTransactionPageResponse(page)
+	if c.QueryParam(expandParam) == expandCounterparty && ctr.UserSvc != nil {
+		if err = ctr.addCounterpartyNames(userID, resp.Transactions); err != nil {
+			return c.JSON(http.StatusInternalServerError, model.NewErrResponse(http.StatusInternalServerError, ErrInternalServerMsg))
+		}
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// @Summary Retrieves transaction.
+// @Description Retrieves transaction from or to a balance of the authenticated user. Transactions of other users are not found.
+// @Security ApiKeyAuth
+// @Security APIKey
+// @ID GetTransaction
+// @Tags transactions
+// @Param id path int true "Transaction ID."
+// @Param expand query string false "Set to counterparty to add display name of the other party of the transaction." Enums(counterparty)
+// @Produce  json
+// @Success 200 {object} model.TransactionResponse
+// @Failure 400 {object} model.ErrResponse
+// @Failure 401 {object} model.ErrResponse
+// @Failure 404 {object} model.ErrResponse
+// @Failure 500 {object} model.ErrResponse
+// @Router /api/v1/transactions/{id} [get]
+func (ctr *TransactionController) GetTransaction(c echo.Context) error {
+	log.Infof("GET %s", replaceID(transactionEndpoint, c.Param("id")))
+
+	userID, err := ctr.LoginSvc.GetUserIDFromToken(c)
+	if err != nil {
+		log.Errorf("error while reading user ID from token; error: %v", err)
+		return c.JSON(http.StatusUnauthorized, model.NewErrResponse(http.StatusUnauthorized, ErrInvalidTokenMsg))
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrInvalidTransactionIDMsg))
+	}
+
+	transaction, err := ctr.Svc.Get(userID, id)
+	if err != nil {
+		if err == service.ErrTransactionNotFound {
+			return c.JSON(http.StatusNotFound, model.NewErrResponse(http.StatusNotFound, ErrTransactionNotFoundMsg))
+		}
+		return c.JSON(http.StatusInternalServerError, model.NewErrResponse(http.StatusInternalServerError, ErrInternalServerMsg))
+	}
+	resp := []model.TransactionResponse{model.NewTransactionResponse(transaction)}
+	if c.QueryParam(expandParam) == expandCounterparty && ctr.UserSvc != nil {
+		if err = ctr.addCounterpartyNames(userID, resp); err != nil {
+			return c.JSON(http.StatusInternalServerError, model.NewErrResponse(http.StatusInternalServerError, ErrInternalServerMsg))
+		}
+	}
+	return c.JSON(http.StatusOK, resp[0])
+}
+
+// @Summary Refunds transaction.
+// @Description Returns money of the transaction to its sender as a new transaction from the receiver balance, linked to it with refundOf.
+// @Description Only the receiver of the transaction or an admin can refund it, fully or partially - refunds cannot exceed
+// @Description the transferred amount. Amount is in currency of the receiver balance, when it is not set whole not refunded amount is returned.
+// @Description Converted transfers are refunded with the same part of the sent amount, without current exchange rate.
+// @Description Refund above step-up threshold requires recent authentication, like transfers.
+// @Security ApiKeyAuth
+// @Security APIKey
+// @ID RefundTransaction
+// @Tags transactions
+// @Param id path int true "Transaction ID."
+// @Param refund body model.RefundRequest false "Refund definition."
+// @Param Idempotency-Key header string false "Unique key of the request. Retried request with the same key and body returns the original response."
+// @Accept  json
+// @Produce  json
+// @Success 201 {object} model.TransactionResponse
+// @Failure 400 {object} model.ErrResponse
+// @Failure 401 {object} model.ErrResponse
+// @Failure 403 {object} model.ErrResponse
+// @Failure 404 {object} model.ErrResponse
+// @Failure 409 {object} model.ErrResponse
+// @Failure 422 {object} model.ErrResponse
+// @Failure 500 {object} model.ErrResponse
+// @Router /api/v1/transactions/{id}/refund [post]
+func (ctr *TransactionController) RefundTransaction(c echo.Context) error {
+	log.Infof("POST %s", replaceID(transactionRefundEndpoint, c.Param("id")))
+
+	userID, err := ctr.LoginSvc.GetUserIDFromToken(c)
+	if err != nil {
+		log.Errorf("error while reading user ID from token; error: %v", err)
+		return c.JSON(http.StatusUnauthorized, model.NewErrResponse(http.StatusUnauthorized, ErrInvalidTokenMsg))
+	}
+	role, err := ctr.LoginSvc.GetRoleFromToken(c)
+	if err != nil {
+		log.Errorf("error while reading role from token; error: %v", err)
+		return c.JSON(http.StatusUnauthorized, model.NewErrResponse(http.StatusUnauthorized, ErrInvalidTokenMsg))
+	}
+	authTime, err := ctr.LoginSvc.GetAuthTimeFromToken(c)
+	if err != nil {
+		log.Errorf("error while reading auth time from token; error: %v", err)
+		return c.JSON(http.StatusUnauthorized, model.NewErrResponse(http.StatusUnauthorized, ErrInvalidTokenMsg))
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrInvalidTransactionIDMsg))
+	}
+
+	// body is optional, whole not refunded amount is returned without it
+	r := new(model.RefundRequest)
+	if err = c.Bind(r); err != nil {
+		log.Errorf("cannot bind RefundRequest struct with the Request body; error: %v", err)
+		if errors.Is(err, model.ErrAmountPrecision) {
+			return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrAmountPrecisionMsg))
+		}
+		return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrCannotParseBodyMsg))
+	}
+	if ok, err := r.IsValid(); !ok {
+		return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, err.Error()))
+	}
+
+	refund := r.ToRefund(id)
+	return idempotent(c, ctr.IdempotencySvc, userID, refund, func() (int, interface{}) {
+		return ctr.refundTransaction(userID, role, authTime, refund)
+	})
+}
+
+// refundTransaction refunds transaction and returns http code with response body.
+func (ctr *TransactionController) refundTransaction(userID int, role model.Role, authTime time.Time, r model.Refund) (int, interface{}) {
+	refund, err := ctr.Svc.Refund(userID, role, r, authTime)
+	if err != nil {
+		log.Errorf("cannot refund transaction %d; error: %v", r.TransactionID, err)
+		switch err {
+		case service.ErrTransactionNotFound:
+			return http.StatusNotFound, model.NewErrResponse(http.StatusNotFound, ErrTransactionNotFoundMsg)
+		case service.ErrRefundNotAllowed:
+			return http.StatusForbidden, model.NewErrResponse(http.StatusForbidden, ErrRefundNotAllowedMsg)
+		case service.ErrRefundExceedsAmount:
+			return http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrRefundExceedsAmountMsg)
+		case service.ErrRefundOfRefund:
+			return http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrRefundOfRefundMsg)
+		case service.ErrBalanceNotFound:
+			return http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrBalancesNotFoundMsg)
+		case service.ErrInsufficientBalance:
+			return http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrErrInsufficientBalanceMsg)
+		case service.ErrAmountNotAllowed:
+			return http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrAmountNotAllowedMsg)
+		case service.ErrStepUpRequired:
+			return http.StatusForbidden, model.NewErrResponse(http.StatusForbidden, ErrStepUpRequiredMsg)
+		}
+		return http.StatusInternalServerError, model.NewErrResponse(http.StatusInternalServerError, ErrInternalServerMsg)
+	}
+	return http.StatusCreated, model.NewTransactionResponse(refund)
+}
+
+// addCounterpartyNames sets display name of the owner of the other balance of each transaction. For transfers between
+// balances of the user it is the user's own name.
+func (ctr *TransactionController) addCounterpartyNames(userID int, transactions []model.TransactionResponse) error {
+	balanceIDs := make([]int, 0, 2*len(transactions))
+	for _, t := range transactions {
+		balanceIDs = append(balanceIDs, t.SenderBalanceID, t.ReceiverBalanceID)
+	}
+	owners, err := ctr.UserSvc.GetBalanceOwners(balanceIDs)
+	if err != nil {
+		return err
+	}
+	for i, t := range transactions {
+		counterparty, ok := owners[t.SenderBalanceID]
+		if !ok || counterparty.ID == userID {
+			counterparty = owners[t.ReceiverBalanceID]
+		}
+		transactions[i].CounterpartyName = counterparty.DisplayName()
+	}
+	return nil
+}
+
+// bindTransactionFilter reads filter of transaction history from query params.
+func bindTransactionFilter(c echo.Context) (model.TransactionFilter, error) {
+	r := new(model.TransactionFilterRequest)
+	if err := (&echo.DefaultBinder{}).BindQueryParams(c, r); err != nil {
+		log.Errorf("cannot bind TransactionFilterRequest struct with the query params; error: %v", err)
+		return model.TransactionFilter{}, errors.New(ErrInvalidTransactionFilterMsg)
+	}
+	return r.ToFilter()
 }
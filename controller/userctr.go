@@ -0,0 +1,141 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"zuzanna.com/walletapi/model"
+	"zuzanna.com/walletapi/service"
+)
+
+var ErrLoginExistsMsg = "Login is already taken."
+var ErrUserNotFoundMsg = "User not found."
+
+// expandParam is the query param of list endpoints, expandCounterparty adds display names of other users to the response.
+const expandParam = "expand"
+const expandCounterparty = "counterparty"
+
+type UserController struct {
+	E *echo.Echo
+	// G is a group of endpoints guarded with JWT token.
+	G        *echo.Group
+	Svc      service.UserService
+	LoginSvc service.AuthService
+}
+
+func (ctr UserController) Init() {
+	ctr.E.POST(usersEndpoint, ctr.Register)
+	ctr.G.GET(meEndpoint, ctr.GetProfile)
+	ctr.G.PATCH(meEndpoint, ctr.UpdateProfile)
+}
+
+// @Summary Registers new user.
+// @Description Creates new user with credentials and initial empty balance (SGD when currency is not set).
+// @Description Password must have from 8 to 72 characters and contain at least one lowercase letter, one uppercase letter and one digit.
+// @ID Register
+// @Tags users
+// @Param user body model.UserRequest true "User definition."
+// @Accept  json
+// @Produce  json
+// @Success 201 {object} model.UserResponse
+// @Failure 400 {object} model.ErrResponse
+// @Failure 409 {object} model.ErrResponse
+// @Failure 500 {object} model.ErrResponse
+// @Router /v1/users [post]
+func (ctr UserController) Register(c echo.Context) error {
+	log.Infof("POST %s", usersEndpoint)
+
+	u := new(model.UserRequest)
+	if err := c.Bind(u); err != nil {
+		log.Errorf("cannot bind UserRequest struct with the Request body; error: %v", err)
+		return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrCannotParseBodyMsg))
+	}
+
+	if ok, err := u.IsValid(); !ok {
+		return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, err.Error()))
+	}
+
+	user, err := ctr.Svc.Register(model.User{FirstName: u.FirstName, LastName: u.LastName, Age: u.Age}, u.Login, u.Password, model.Currency(u.Currency))
+	if err != nil {
+		if err == service.ErrWeakPassword {
+			return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, err.Error()))
+		}
+		if err == service.ErrLoginExists {
+			return c.JSON(http.StatusConflict, model.NewErrResponse(http.StatusConflict, ErrLoginExistsMsg))
+		}
+		return c.JSON(http.StatusInternalServerError, model.NewErrResponse(http.StatusInternalServerError, ErrInternalServerMsg))
+	}
+	return c.JSON(http.StatusCreated, model.UserResponse{ID: user.ID})
+}
+
+// @Summary Retrieves profile of the authenticated user.
+// @Security ApiKeyAuth
+// @ID GetProfile
+// @Tags users
+// @Produce  json
+// @Success 200 {object} model.ProfileResponse
+// @Failure 401 {object} model.ErrResponse
+// @Failure 404 {object} model.ErrResponse
+// @Failure 500 {object} model.ErrResponse
+// @Router /api/v1/me [get]
+func (ctr UserController) GetProfile(c echo.Context) error {
+	log.Infof("GET %s", meEndpoint)
+
+	userID, err := ctr.LoginSvc.GetUserIDFromToken(c)
+	if err != nil {
+		log.Errorf("error while reading user ID from token; error: %v", err)
+		return c.JSON(http.StatusUnauthorized, model.NewErrResponse(http.StatusUnauthorized, ErrInvalidTokenMsg))
+	}
+
+	user, err := ctr.Svc.Get(userID)
+	if err != nil {
+		if err == service.ErrUserNotFound {
+			return c.JSON(http.StatusNotFound, model.NewErrResponse(http.StatusNotFound, ErrUserNotFoundMsg))
+		}
+		return c.JSON(http.StatusInternalServerError, model.NewErrResponse(http.StatusInternalServerError, ErrInternalServerMsg))
+	}
+	return c.JSON(http.StatusOK, model.NewProfileResponse(user))
+}
+
+// @Summary Updates profile of the authenticated user.
+// @Description Changes only fields present in the request. First and last name can have at most 50 characters.
+// @Security ApiKeyAuth
+// @ID UpdateProfile
+// @Tags users
+// @Param profile body model.ProfileRequest true "Profile fields to change."
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} model.ProfileResponse
+// @Failure 400 {object} model.ErrResponse
+// @Failure 401 {object} model.ErrResponse
+// @Failure 404 {object} model.ErrResponse
+// @Failure 500 {object} model.ErrResponse
+// @Router /api/v1/me [patch]
+func (ctr UserController) UpdateProfile(c echo.Context) error {
+	log.Infof("PATCH %s", meEndpoint)
+
+	userID, err := ctr.LoginSvc.GetUserIDFromToken(c)
+	if err != nil {
+		log.Errorf("error while reading user ID from token; error: %v", err)
+		return c.JSON(http.StatusUnauthorized, model.NewErrResponse(http.StatusUnauthorized, ErrInvalidTokenMsg))
+	}
+
+	r := new(model.ProfileRequest)
+	if err = c.Bind(r); err != nil {
+		log.Errorf("cannot bind ProfileRequest struct with the Request body; error: %v", err)
+		return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, ErrCannotParseBodyMsg))
+	}
+	if ok, err := r.IsValid(); !ok {
+		return c.JSON(http.StatusBadRequest, model.NewErrResponse(http.StatusBadRequest, err.Error()))
+	}
+
+	user, err := ctr.Svc.Update(userID, r.ToPatch())
+	if err != nil {
+		if err == service.ErrUserNotFound {
+			return c.JSON(http.StatusNotFound, model.NewErrResponse(http.StatusNotFound, ErrUserNotFoundMsg))
+		}
+		return c.JSON(http.StatusInternalServerError, model.NewErrResponse(http.StatusInternalServerError, ErrInternalServerMsg))
+	}
+	return c.JSON(http.StatusOK, model.NewProfileResponse(user))
+}
@@ -32,22 +32,176 @@ var doc = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/v1/balances": {
+        "/.well-known/jwks.json": {
             "get": {
+                "description": "Retrieves JSON Web Key Set with all keys active for verification, key is selected by kid header of the token.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "login"
+                ],
+                "summary": "Retrieves public keys used to verify JWT tokens.",
+                "operationId": "GetJWKS",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.JWKSResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/admin/v1/logins/{login}/unlock": {
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Retrieves list of balances for authenticated user.",
+                "description": "Resets failed login attempts of the login. Requires admin role.",
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Unlocks login locked after too many failed login attempts.",
+                "operationId": "UnlockLogin",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User's login.",
+                        "name": "login",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": ""
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/admin/v1/users/{id}/api-keys": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Creates API key of the service account with given ID - user without credentials, who cannot login with password.\nKeys of users who can login are refused with 403, they create their own keys. The key is returned only once. Requires admin role and token issued by /login, /login/mfa or /reauth within the last minutes.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "balances"
+                    "admin"
+                ],
+                "summary": "Creates API key of service account.",
+                "operationId": "CreateUserAPIKey",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID.",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "API key definition.",
+                        "name": "key",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.APIKeyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIKeyResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/admin/v1/users/{id}/balances": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Retrieves list of balances of the user with given ID. Requires support role.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Retrieves balances of any user.",
+                "operationId": "GetUserBalances",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID.",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Retrieves list of balances for authenticated user.",
-                "operationId": "GetBalances",
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -58,12 +212,159 @@ var doc = `{
                             }
                         }
                     },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/admin/v1/users/{id}/transactions": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Retrieves page of transactions of the user with given ID, the newest first. Requires support role.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Retrieves transactions of any user.",
+                "operationId": "GetUserTransactions",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID.",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of transactions on the page, from 1 to 100, default 50.",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Next cursor from the previous page.",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only transactions made at or after the date, RFC 3339, e.g. 2026-10-01T00:00:00Z.",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only transactions made before the date, RFC 3339.",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Minimal sent amount.",
+                        "name": "minAmount",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Maximal sent amount.",
+                        "name": "maxAmount",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "sent",
+                            "received"
+                        ],
+                        "type": "string",
+                        "description": "Only sent or received transactions.",
+                        "name": "direction",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Only transactions with the balance.",
+                        "name": "counterpartyBalanceId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only transactions in the sent currency.",
+                        "name": "currency",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only transactions with the reference.",
+                        "name": "reference",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only transactions with memo containing the text.",
+                        "name": "memo",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only transactions with the metadata pair given as key:value.",
+                        "name": "metadata",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.TransactionPageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/model.ErrResponse"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -73,29 +374,29 @@ var doc = `{
                 }
             }
         },
-        "/api/v1/transactions": {
+        "/api/v1/api-keys": {
             "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Retrives list of transactions for the authenticated user.",
+                "description": "Retrieves all API keys of the authenticated user, including revoked and expired ones, without the keys themselves.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "transactions"
+                    "api-keys"
                 ],
-                "summary": "Retrives list of transactions.",
-                "operationId": "RetriveTransactions",
+                "summary": "Retrieves API keys of the authenticated user.",
+                "operationId": "GetAPIKeys",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/model.TransactionResponse"
+                                "$ref": "#/definitions/model.APIKeyResponse"
                             }
                         }
                     },
@@ -119,7 +420,7 @@ var doc = `{
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Triggers transfer of money from sender balance to receiver balance.",
+                "description": "Creates API key to be sent in X-API-Key header instead of JWT token, e.g. by server to server integrations.\nThe key is returned only once. Key can be used only on endpoints of its scopes, from allowed IPs and before it expires.\nAPI keys cannot be used to manage API keys. Requires token issued by /login, /login/mfa or /reauth within the last minutes,\notherwise 403 is returned. Transfers above step-up threshold with the key require transactions:large scope.",
                 "consumes": [
                     "application/json"
                 ],
@@ -127,18 +428,18 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "transactions"
+                    "api-keys"
                 ],
-                "summary": "Executes transaction between two balances.",
-                "operationId": "ExecuteTransaction",
+                "summary": "Creates API key of the authenticated user.",
+                "operationId": "CreateAPIKey",
                 "parameters": [
                     {
-                        "description": "Transaction definifion.",
-                        "name": "user",
+                        "description": "API key definition.",
+                        "name": "key",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/model.TransactionRequest"
+                            "$ref": "#/definitions/model.APIKeyRequest"
                         }
                     }
                 ],
@@ -146,7 +447,7 @@ var doc = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/model.TransactionResponse"
+                            "$ref": "#/definitions/model.APIKeyResponse"
                         }
                     },
                     "400": {
@@ -161,6 +462,12 @@ var doc = `{
                             "$ref": "#/definitions/model.ErrResponse"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -170,38 +477,35 @@ var doc = `{
                 }
             }
         },
-        "/login": {
-            "post": {
-                "description": "Login endpoint for getting JWT token.",
-                "produces": [
-                    "application/json"
+        "/api/v1/api-keys/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
                 ],
                 "tags": [
-                    "login"
+                    "api-keys"
                 ],
-                "summary": "Provide your username and password for authentication.",
-                "operationId": "Login",
+                "summary": "Revokes API key of the authenticated user.",
+                "operationId": "RevokeAPIKey",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "User's username.",
-                        "name": "username",
-                        "in": "formData",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "User's password.",
-                        "name": "password",
-                        "in": "formData",
+                        "type": "integer",
+                        "description": "API key ID.",
+                        "name": "id",
+                        "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "204": {
+                        "description": ""
+                    },
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/model.TokenResponse"
+                            "$ref": "#/definitions/model.ErrResponse"
                         }
                     },
                     "401": {
@@ -210,75 +514,3095 @@ var doc = `{
                             "$ref": "#/definitions/model.ErrResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/model.ErrResponse"
                         }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/balances": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "APIKey": []
+                    }
+                ],
+                "description": "Retrieves list of balances for authenticated user.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "balances"
+                ],
+                "summary": "Retrieves list of balances for authenticated user.",
+                "operationId": "GetBalances",
+                "parameters": [
+                    {
+                        "enum": [
+                            "counterparty"
+                        ],
+                        "type": "string",
+                        "description": "Set to counterparty to add display name of the owner.",
+                        "name": "expand",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.BalanceResponse"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "APIKey": []
+                    }
+                ],
+                "description": "Creates empty balance for authenticated user. User can hold only one balance per currency.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "balances"
+                ],
+                "summary": "Opens new balance in requested currency.",
+                "operationId": "CreateBalance",
+                "parameters": [
+                    {
+                        "description": "Balance definition.",
+                        "name": "balance",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.BalanceRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/model.BalanceResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/balances/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "APIKey": []
+                    }
+                ],
+                "description": "Retrieves balance with its lock state and date of the last transaction. Balances of other users are not found.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "balances"
+                ],
+                "summary": "Retrieves balance of authenticated user.",
+                "operationId": "GetBalance",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Balance ID.",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "counterparty"
+                        ],
+                        "type": "string",
+                        "description": "Set to counterparty to add display name of the owner.",
+                        "name": "expand",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.BalanceDetailsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/holds": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "APIKey": []
+                    }
+                ],
+                "description": "Reserves money of the sender balance for the receiver balance, e.g. before an order is confirmed. Held money stays\non the sender balance, but it is not available for transfers until the receiver captures it into a transaction\nor voids it, or until the hold expires. Both balances must be in the same currency.\nHold above step-up threshold requires recent authentication, like transfers.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "holds"
+                ],
+                "summary": "Creates hold.",
+                "operationId": "CreateHold",
+                "parameters": [
+                    {
+                        "description": "Hold definition.",
+                        "name": "hold",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.HoldRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Unique key of the request. Retried request with the same key and body returns the original response.",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/model.HoldResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/holds/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "APIKey": []
+                    }
+                ],
+                "description": "Retrieves hold from or to a balance of the authenticated user. Holds of other users are not found.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "holds"
+                ],
+                "summary": "Retrieves hold.",
+                "operationId": "GetHold",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Hold ID.",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.HoldResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/holds/{id}/capture": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "APIKey": []
+                    }
+                ],
+                "description": "Transfers held money to the receiver balance as a new transaction, linked to the hold with transactionId.\nOnly the receiver of an active hold can capture it, once - in full or partially, the rest of the hold is released.\nWhole hold is captured when amount is not set.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "holds"
+                ],
+                "summary": "Captures hold.",
+                "operationId": "CaptureHold",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Hold ID.",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Capture definition.",
+                        "name": "capture",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/model.CaptureRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Unique key of the request. Retried request with the same key and body returns the original response.",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.HoldResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/holds/{id}/void": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "APIKey": []
+                    }
+                ],
+                "description": "Releases whole held money back to the sender. Only the receiver of an active hold can void it.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "holds"
+                ],
+                "summary": "Voids hold.",
+                "operationId": "VoidHold",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Hold ID.",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.HoldResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/me": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Retrieves profile of the authenticated user.",
+                "operationId": "GetProfile",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.ProfileResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Changes only fields present in the request. First and last name can have at most 50 characters.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Updates profile of the authenticated user.",
+                "operationId": "UpdateProfile",
+                "parameters": [
+                    {
+                        "description": "Profile fields to change.",
+                        "name": "profile",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.ProfileRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.ProfileResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/me/password": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Requires the current password. Password must have from 8 to 72 characters and contain at least one lowercase letter,\none uppercase letter and one digit. All sessions of the user are ended, also the one used for this request,\nso the user has to login again with the new password.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Changes password of the logged user.",
+                "operationId": "ChangePassword",
+                "parameters": [
+                    {
+                        "description": "Current and new password.",
+                        "name": "password",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.PasswordChangeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": ""
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "integer",
+                                "description": "Seconds after which password change can be retried."
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/mfa/totp": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Generates new TOTP secret and provisioning URI to be shown as QR code in authenticator app.\nThe secret is not used for login until it is confirmed. Enrolling again replaces unconfirmed secret.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "mfa"
+                ],
+                "summary": "Enrolls TOTP secret for two-factor authentication.",
+                "operationId": "EnrollTOTP",
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/model.TOTPEnrollmentResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/mfa/totp/confirm": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Enables two-factor authentication and returns recovery codes. Recovery codes are shown only once,\neach of them can be used instead of TOTP code for one login.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "mfa"
+                ],
+                "summary": "Confirms enrolled TOTP secret with the first code.",
+                "operationId": "ConfirmTOTP",
+                "parameters": [
+                    {
+                        "description": "Code from authenticator app.",
+                        "name": "code",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.MFACodeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.RecoveryCodesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/recipients": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "APIKey": []
+                    }
+                ],
+                "description": "Finds user by login, e-mail or phone alias (with + and country code), to confirm receiver's masked name before transfer.\nWhen currency is set the receiver must have balance in this currency, otherwise 400 is returned.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Looks up receiver of a transfer.",
+                "operationId": "LookupRecipient",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Login, e-mail or phone of the receiver.",
+                        "name": "receiver",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Currency of the transfer.",
+                        "name": "currency",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.RecipientResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/schedules": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Lists all schedules of the authenticated user, the newest first.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "schedules"
+                ],
+                "summary": "Lists schedules.",
+                "operationId": "ListSchedules",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.ScheduleResponse"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Schedules transfer at startAt, repeated daily, weekly or monthly on dayOfMonth when recurrence is set.\nThe last day of the month is used in months shorter than dayOfMonth. Receiver given by alias is resolved to its balance now.\nTransfer failed because of insufficient balance or internal error is retried with backoff, other failures fail the schedule, see lastError.\nSchedule above step-up threshold requires recent authentication, scheduled transfers are then executed without it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "schedules"
+                ],
+                "summary": "Schedules transfer.",
+                "operationId": "CreateSchedule",
+                "parameters": [
+                    {
+                        "description": "Schedule definition.",
+                        "name": "schedule",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.ScheduleRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Unique key of the request. Retried request with the same key and body returns the original response.",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/model.ScheduleResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/schedules/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Retrieves schedule of the authenticated user with the result of its last run. Schedules of other users are not found.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "schedules"
+                ],
+                "summary": "Retrieves schedule.",
+                "operationId": "GetSchedule",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Schedule ID.",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.ScheduleResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/schedules/{id}/cancel": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Stops executing active or paused schedule for good.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "schedules"
+                ],
+                "summary": "Cancels schedule.",
+                "operationId": "CancelSchedule",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Schedule ID.",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.ScheduleResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/schedules/{id}/pause": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Stops executing active schedule until it is resumed.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "schedules"
+                ],
+                "summary": "Pauses schedule.",
+                "operationId": "PauseSchedule",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Schedule ID.",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.ScheduleResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/schedules/{id}/resume": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Continues executing paused schedule. Transfer missed while the schedule was paused is executed at once,\nthe other missed transfers are skipped.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "schedules"
+                ],
+                "summary": "Resumes schedule.",
+                "operationId": "ResumeSchedule",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Schedule ID.",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.ScheduleResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/transactions": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "APIKey": []
+                    }
+                ],
+                "description": "Retrives page of transactions for the authenticated user, the newest first. Send next cursor of the page\nwith the same filters to get the next page, there are no more transactions when next cursor is not returned.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Retrives list of transactions.",
+                "operationId": "RetriveTransactions",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Number of transactions on the page, from 1 to 100, default 50.",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Next cursor from the previous page.",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only transactions made at or after the date, RFC 3339, e.g. 2026-10-01T00:00:00Z.",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only transactions made before the date, RFC 3339.",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Minimal sent amount.",
+                        "name": "minAmount",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Maximal sent amount.",
+                        "name": "maxAmount",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "sent",
+                            "received"
+                        ],
+                        "type": "string",
+                        "description": "Only sent or received transactions.",
+                        "name": "direction",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Only transactions with the balance.",
+                        "name": "counterpartyBalanceId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only transactions in the sent currency.",
+                        "name": "currency",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only transactions with the reference.",
+                        "name": "reference",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only transactions with memo containing the text.",
+                        "name": "memo",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only transactions with the metadata pair given as key:value.",
+                        "name": "metadata",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "counterparty"
+                        ],
+                        "type": "string",
+                        "description": "Set to counterparty to add display name of the other party of each transaction.",
+                        "name": "expand",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.TransactionPageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "APIKey": []
+                    }
+                ],
+                "description": "Triggers transfer of money from sender balance to receiver balance.\nReceiver can be given by receiverBalanceId or by receiver - login, e-mail or phone alias (with + and country code),\nmoney is then sent to the receiver's balance in currency of the sender balance. See /api/v1/recipients.\nTransfer above step-up threshold requires token issued by /login, /login/mfa or /reauth within the last minutes, otherwise 403 is returned.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Executes transaction between two balances.",
+                "operationId": "ExecuteTransaction",
+                "parameters": [
+                    {
+                        "description": "Transaction definifion.",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.TransactionRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Unique key of the request. Retried request with the same key and body returns the original response.",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/model.TransactionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/transactions/batch": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "APIKey": []
+                    }
+                ],
+                "description": "Transfers money from one sender balance to many receiver balances, e.g. payroll. In all_or_nothing mode either all\nitems are transferred or none of them - the item which failed is failed and the others are skipped. In best_effort mode\nevery item which can be transferred is transferred. Total amount of the batch must be available on the sender balance\nand it is compared with step-up threshold. Batch of at most 20 items is processed at once and returned with result\nof every item, larger batch is returned pending with 202 and processed in background - poll it with its ID.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Submits batch of transactions.",
+                "operationId": "SubmitBatch",
+                "parameters": [
+                    {
+                        "description": "Batch definition.",
+                        "name": "batch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.BatchRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Unique key of the request. Retried request with the same key and body returns the original response.",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/model.BatchResponse"
+                        }
+                    },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/model.BatchResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/transactions/batch/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "APIKey": []
+                    }
+                ],
+                "description": "Retrieves batch of the authenticated user with result of every item, status is pending until all items are processed.\nBatches of other users are not found.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Retrieves batch of transactions.",
+                "operationId": "GetBatch",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Batch ID.",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.BatchResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/transactions/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "APIKey": []
+                    }
+                ],
+                "description": "Retrieves transaction from or to a balance of the authenticated user. Transactions of other users are not found.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Retrieves transaction.",
+                "operationId": "GetTransaction",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Transaction ID.",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "counterparty"
+                        ],
+                        "type": "string",
+                        "description": "Set to counterparty to add display name of the other party of the transaction.",
+                        "name": "expand",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.TransactionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/transactions/{id}/refund": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "APIKey": []
+                    }
+                ],
+                "description": "Returns money of the transaction to its sender as a new transaction from the receiver balance, linked to it with refundOf.\nOnly the receiver of the transaction or an admin can refund it, fully or partially - refunds cannot exceed\nthe transferred amount. Amount is in currency of the receiver balance, when it is not set whole not refunded amount is returned.\nConverted transfers are refunded with the same part of the sent amount, without current exchange rate.\nRefund above step-up threshold requires recent authentication, like transfers.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Refunds transaction.",
+                "operationId": "RefundTransaction",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Transaction ID.",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Refund definition.",
+                        "name": "refund",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/model.RefundRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Unique key of the request. Retried request with the same key and body returns the original response.",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/model.TransactionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/login": {
+            "post": {
+                "description": "Login endpoint for getting JWT token and refresh token.\nWhen the user enabled two-factor authentication only MFA token is returned with status 202, it must be\nexchanged at /login/mfa within 5 minutes.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "login"
+                ],
+                "summary": "Provide your username and password for authentication.",
+                "operationId": "Login",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User's username.",
+                        "name": "username",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "User's password.",
+                        "name": "password",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/model.TokenResponse"
+                        }
+                    },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/model.TokenResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "integer",
+                                "description": "Seconds after which login can be retried."
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/login/mfa": {
+            "post": {
+                "description": "Exchanges MFA token returned by /login and TOTP code (or unused recovery code) for JWT token and refresh token.\nFailed attempts are throttled like failed logins.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "login"
+                ],
+                "summary": "Provide one-time code to finish login with two-factor authentication.",
+                "operationId": "LoginMFA",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "MFA token returned by /login.",
+                        "name": "mfa_token",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Code from authenticator app or recovery code.",
+                        "name": "code",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/model.TokenResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "integer",
+                                "description": "Seconds after which login can be retried."
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/logout": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Revokes JWT token used for the request and, if given, refresh token of the user.",
+                "tags": [
+                    "login"
+                ],
+                "summary": "Logout authenticated user.",
+                "operationId": "Logout",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Refresh token to revoke.",
+                        "name": "refresh_token",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": ""
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/password/reset": {
+            "post": {
+                "description": "Sends single-use password reset token to the user. The response is the same whether the login exists or not.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Requests password reset.",
+                "operationId": "RequestPasswordReset",
+                "parameters": [
+                    {
+                        "description": "Login of the user.",
+                        "name": "login",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.PasswordResetRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": ""
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/password/reset/confirm": {
+            "post": {
+                "description": "Token can be used only once and before it expires. All sessions of the user are ended.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Sets new password with password reset token.",
+                "operationId": "ConfirmPasswordReset",
+                "parameters": [
+                    {
+                        "description": "Password reset token and new password.",
+                        "name": "reset",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.PasswordResetConfirmRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": ""
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/reauth": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns new JWT token with current auth_time claim when password or one-time code (TOTP or recovery code) is valid.\nTransfers above step-up threshold are allowed only shortly after authentication. Failed attempts are throttled like failed logins.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "login"
+                ],
+                "summary": "Confirm identity of authenticated user again.",
+                "operationId": "Reauthenticate",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User's password, required when code is not set.",
+                        "name": "password",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Code from authenticator app or recovery code.",
+                        "name": "code",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/model.TokenResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "integer",
+                                "description": "Seconds after which re-authentication can be retried."
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/token/refresh": {
+            "post": {
+                "description": "Returns new JWT token and new refresh token, the refresh token sent in request cannot be used again.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "login"
+                ],
+                "summary": "Exchange refresh token for a new JWT token.",
+                "operationId": "RefreshToken",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Refresh token returned by /login or previous refresh.",
+                        "name": "refresh_token",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/model.TokenResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/users": {
+            "post": {
+                "description": "Creates new user with credentials and initial empty balance (SGD when currency is not set).\nPassword must have from 8 to 72 characters and contain at least one lowercase letter, one uppercase letter and one digit.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Registers new user.",
+                "operationId": "Register",
+                "parameters": [
+                    {
+                        "description": "User definition.",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.UserRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/model.UserResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "model.APIKeyRequest": {
+            "type": "object",
+            "properties": {
+                "allowedIps": {
+                    "description": "AllowedIPs are IP addresses or networks in CIDR notation the key can be used from, any IP when empty.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "10.0.0.0/24"
+                    ]
+                },
+                "expiresAt": {
+                    "description": "ExpiresAt is in 90 days when not set, at most in a year.",
+                    "type": "string",
+                    "example": "2027-01-01T00:00:00Z"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "batch payouts"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "transactions:write"
+                    ]
+                }
+            }
+        },
+        "model.APIKeyResponse": {
+            "type": "object",
+            "properties": {
+                "allowedIps": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "10.0.0.0/24"
+                    ]
+                },
+                "createdAt": {
+                    "type": "string",
+                    "example": "2026-10-18T12:00:00Z"
+                },
+                "expiresAt": {
+                    "type": "string",
+                    "example": "2027-01-01T00:00:00Z"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "key": {
+                    "description": "Key is returned only when the key is created, it cannot be retrieved later.",
+                    "type": "string",
+                    "example": "wak_Jg0k6XJ2oYw8hVj5oSeXG1mBv8dL2ZqN5cTnF0rU9eA"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "batch payouts"
+                },
+                "prefix": {
+                    "description": "Prefix is the beginning of the key, so the key can be recognized.",
+                    "type": "string",
+                    "example": "wak_Jg0k6X"
+                },
+                "revokedAt": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "transactions:write"
+                    ]
+                }
+            }
+        },
+        "model.BalanceDetailsResponse": {
+            "type": "object",
+            "properties": {
+                "available": {
+                    "type": "number",
+                    "example": 9979.5
+                },
+                "balance": {
+                    "type": "number",
+                    "example": 10000
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "SGD"
+                },
+                "held": {
+                    "description": "Held is reserved by active holds, Available is the rest of the balance which can be transferred.",
+                    "type": "number",
+                    "example": 20.5
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "lastTransactionAt": {
+                    "description": "LastTransactionAt is not set when there was no transaction from or to the balance.",
+                    "type": "string"
+                },
+                "ownerName": {
+                    "description": "OwnerName is display name of the balance owner, set only when requested.",
+                    "type": "string",
+                    "example": "Alice C."
+                }
+            }
+        },
+        "model.BalanceRequest": {
+            "type": "object",
+            "properties": {
+                "currency": {
+                    "type": "string",
+                    "example": "USD"
+                }
+            }
+        },
+        "model.BalanceResponse": {
+            "type": "object",
+            "properties": {
+                "available": {
+                    "type": "number",
+                    "example": 9979.5
+                },
+                "balance": {
+                    "type": "number",
+                    "example": 10000
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "SGD"
+                },
+                "held": {
+                    "description": "Held is reserved by active holds, Available is the rest of the balance which can be transferred.",
+                    "type": "number",
+                    "example": 20.5
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "ownerName": {
+                    "description": "OwnerName is display name of the balance owner, set only when requested.",
+                    "type": "string",
+                    "example": "Alice C."
+                }
+            }
+        },
+        "model.BatchItemRequest": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "example": 3200
+                },
+                "memo": {
+                    "type": "string",
+                    "example": "Salary 10/2026"
+                },
+                "metadata": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "receiverBalanceId": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "reference": {
+                    "type": "string",
+                    "example": "PAYROLL-2026-10"
+                }
+            }
+        },
+        "model.BatchItemResponse": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "example": 3200
+                },
+                "error": {
+                    "description": "Error is reason of failed or skipped item.",
+                    "type": "string",
+                    "example": "insufficient balance of a sender"
+                },
+                "memo": {
+                    "type": "string",
+                    "example": "Salary 10/2026"
+                },
+                "metadata": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "receiverBalanceId": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "reference": {
+                    "type": "string",
+                    "example": "PAYROLL-2026-10"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "pending",
+                        "completed",
+                        "failed",
+                        "skipped"
+                    ]
+                },
+                "transactionId": {
+                    "description": "TransactionID is set for completed item.",
+                    "type": "integer",
+                    "example": 42
+                }
+            }
+        },
+        "model.BatchRequest": {
+            "type": "object",
+            "properties": {
+                "convert": {
+                    "description": "Convert must be set to transfer money to balances in different currencies.",
+                    "type": "boolean",
+                    "example": false
+                },
+                "currency": {
+                    "description": "Currency is optional, when set it must match currency of sender balance.",
+                    "type": "string",
+                    "example": "SGD"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.BatchItemRequest"
+                    }
+                },
+                "mode": {
+                    "description": "Mode all_or_nothing transfers all items or none of them, best_effort transfers every item which can be transferred.",
+                    "type": "string",
+                    "enum": [
+                        "all_or_nothing",
+                        "best_effort"
+                    ]
+                },
+                "senderBalanceId": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "model.BatchResponse": {
+            "type": "object",
+            "properties": {
+                "completedAt": {
+                    "type": "string"
+                },
+                "convert": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "SGD"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 12
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.BatchItemResponse"
+                    }
+                },
+                "mode": {
+                    "type": "string",
+                    "enum": [
+                        "all_or_nothing",
+                        "best_effort"
+                    ]
+                },
+                "senderBalanceId": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "pending",
+                        "completed",
+                        "partially_completed",
+                        "failed"
+                    ]
+                },
+                "total": {
+                    "description": "Total is sum of amounts of all items in currency of sender balance.",
+                    "type": "number",
+                    "example": 6400
+                }
+            }
+        },
+        "model.CaptureRequest": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "example": 18
+                }
+            }
+        },
+        "model.ErrResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer",
+                    "example": 401
+                },
+                "date": {
+                    "type": "string",
+                    "example": "2021-12-19T15:25:58.907966Z"
+                },
+                "error": {
+                    "type": "string",
+                    "example": "Login failed. Please double check username and password."
+                },
+                "message": {
+                    "type": "string",
+                    "example": "Unauthorized"
+                }
+            }
+        },
+        "model.HoldRequest": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "example": 20.5
+                },
+                "expiresAt": {
+                    "description": "ExpiresAt is in 7 days when not set, at most in 30 days.",
+                    "type": "string",
+                    "example": "2026-10-25T12:00:00Z"
+                },
+                "memo": {
+                    "type": "string",
+                    "example": "Order 42"
+                },
+                "metadata": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "receiverBalanceId": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "reference": {
+                    "type": "string",
+                    "example": "ORDER-42"
+                },
+                "senderBalanceId": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "model.HoldResponse": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "example": 20.5
+                },
+                "capturedAmount": {
+                    "description": "CapturedAmount and TransactionID are set when the hold was captured.",
+                    "type": "number",
+                    "example": 18
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "SGD"
+                },
+                "expiresAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 7
+                },
+                "memo": {
+                    "type": "string",
+                    "example": "Order 42"
+                },
+                "metadata": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "receiverBalanceId": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "reference": {
+                    "type": "string",
+                    "example": "ORDER-42"
+                },
+                "senderBalanceId": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "active",
+                        "captured",
+                        "voided",
+                        "expired"
+                    ]
+                },
+                "transactionId": {
+                    "type": "integer",
+                    "example": 43
+                }
+            }
+        },
+        "model.JWK": {
+            "type": "object",
+            "properties": {
+                "alg": {
+                    "type": "string",
+                    "example": "EdDSA"
+                },
+                "crv": {
+                    "description": "Crv and X are set for Ed25519 keys",
+                    "type": "string",
+                    "example": "Ed25519"
+                },
+                "e": {
+                    "type": "string"
+                },
+                "kid": {
+                    "type": "string",
+                    "example": "2026-10-18"
+                },
+                "kty": {
+                    "type": "string",
+                    "example": "OKP"
+                },
+                "n": {
+                    "description": "N and E are set for RSA keys",
+                    "type": "string"
+                },
+                "use": {
+                    "type": "string",
+                    "example": "sig"
+                },
+                "x": {
+                    "type": "string",
+                    "example": "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"
+                }
+            }
+        },
+        "model.JWKSResponse": {
+            "type": "object",
+            "properties": {
+                "keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.JWK"
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "model.BalanceResponse": {
+        },
+        "model.MFACodeRequest": {
             "type": "object",
             "properties": {
-                "balance": {
+                "code": {
+                    "type": "string",
+                    "example": "123456"
+                }
+            }
+        },
+        "model.PasswordChangeRequest": {
+            "type": "object",
+            "properties": {
+                "currentPassword": {
+                    "type": "string",
+                    "example": "haslo"
+                },
+                "newPassword": {
+                    "type": "string",
+                    "example": "Secret123"
+                }
+            }
+        },
+        "model.PasswordResetConfirmRequest": {
+            "type": "object",
+            "properties": {
+                "newPassword": {
+                    "type": "string",
+                    "example": "Secret123"
+                },
+                "token": {
+                    "description": "Token was sent to the user after password reset was requested.",
+                    "type": "string",
+                    "example": "Jg0k6XJ2oYw8hVj5oSeXG1mBv8dL2ZqN5cTnF0rU9eA"
+                }
+            }
+        },
+        "model.PasswordResetRequest": {
+            "type": "object",
+            "properties": {
+                "login": {
+                    "type": "string",
+                    "example": "test11"
+                }
+            }
+        },
+        "model.ProfileRequest": {
+            "type": "object",
+            "properties": {
+                "age": {
+                    "type": "integer",
+                    "example": 26
+                },
+                "firstName": {
+                    "type": "string",
+                    "example": "Alice"
+                },
+                "lastName": {
+                    "type": "string",
+                    "example": "Cruz"
+                }
+            }
+        },
+        "model.ProfileResponse": {
+            "type": "object",
+            "properties": {
+                "age": {
+                    "type": "integer",
+                    "example": 25
+                },
+                "displayName": {
+                    "description": "DisplayName is the name other users see, e.g. in their transactions.",
+                    "type": "string",
+                    "example": "Alice C."
+                },
+                "firstName": {
+                    "type": "string",
+                    "example": "Alice"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 5
+                },
+                "lastName": {
+                    "type": "string",
+                    "example": "Cruz"
+                }
+            }
+        },
+        "model.RecipientResponse": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "description": "Name is masked display name of the receiver, e.g. \"Z*** Z.\".",
+                    "type": "string",
+                    "example": "Z*** Z."
+                },
+                "receiver": {
+                    "type": "string",
+                    "example": "zazu18"
+                }
+            }
+        },
+        "model.RecoveryCodesResponse": {
+            "type": "object",
+            "properties": {
+                "recoveryCodes": {
+                    "description": "RecoveryCodes can be used instead of TOTP code, every code only once. They are shown only once.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "ABCD-EFGH-IJKL-MNOP"
+                    ]
+                }
+            }
+        },
+        "model.RefundRequest": {
+            "type": "object",
+            "properties": {
+                "amount": {
                     "type": "number",
-                    "example": 10000
+                    "example": 5.25
+                },
+                "memo": {
+                    "type": "string",
+                    "example": "Returned item"
+                },
+                "reference": {
+                    "type": "string",
+                    "example": "RMA-2026-0007"
+                }
+            }
+        },
+        "model.ScheduleRequest": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "example": 20.5
+                },
+                "convert": {
+                    "description": "Convert must be set to transfer money between balances in different currencies.",
+                    "type": "boolean",
+                    "example": false
                 },
                 "currency": {
+                    "description": "Currency is optional, when set it must match currency of sender balance.",
                     "type": "string",
                     "example": "SGD"
                 },
-                "id": {
+                "dayOfMonth": {
+                    "description": "DayOfMonth is day of monthly transfers, day of StartAt when not set. Shorter months use their last day.",
+                    "type": "integer",
+                    "example": 1
+                },
+                "memo": {
+                    "type": "string",
+                    "example": "Dinner on Friday"
+                },
+                "metadata": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "receiver": {
+                    "description": "Receiver is login, e-mail or phone alias of the receiver, it can be set instead of ReceiverBalanceID.\nMoney is sent to the receiver's balance in currency of the sender balance.",
+                    "type": "string",
+                    "example": "zazu18"
+                },
+                "receiverBalanceId": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "recurrence": {
+                    "description": "Recurrence is not set for transfer executed once.",
+                    "type": "string",
+                    "enum": [
+                        "daily",
+                        "weekly",
+                        "monthly"
+                    ]
+                },
+                "reference": {
+                    "type": "string",
+                    "example": "INV-2026-0042"
+                },
+                "senderBalanceId": {
                     "type": "integer",
                     "example": 1
+                },
+                "startAt": {
+                    "description": "StartAt is time of the first transfer, it must be in the future, at most in a year.",
+                    "type": "string",
+                    "example": "2026-11-01T09:00:00Z"
                 }
             }
         },
-        "model.ErrResponse": {
+        "model.ScheduleResponse": {
             "type": "object",
             "properties": {
-                "code": {
+                "amount": {
+                    "type": "number",
+                    "example": 1200
+                },
+                "attempts": {
                     "type": "integer",
-                    "example": 401
+                    "example": 1
                 },
-                "date": {
+                "convert": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "currency": {
                     "type": "string",
-                    "example": "2021-12-19T15:25:58.907966Z"
+                    "example": "SGD"
                 },
-                "error": {
+                "dayOfMonth": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "lastError": {
+                    "description": "LastError is reason of the last failed transfer.",
                     "type": "string",
-                    "example": "Login failed. Please double check username and password."
+                    "example": "insufficient balance of a sender"
                 },
-                "message": {
+                "lastRunAt": {
+                    "type": "string"
+                },
+                "lastTransactionId": {
+                    "type": "integer",
+                    "example": 42
+                },
+                "memo": {
                     "type": "string",
-                    "example": "Unauthorized"
+                    "example": "Rent"
+                },
+                "metadata": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "nextRunAt": {
+                    "description": "NextRunAt is set for active and paused schedules.",
+                    "type": "string"
+                },
+                "receiverBalanceId": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "recurrence": {
+                    "type": "string",
+                    "enum": [
+                        "daily",
+                        "weekly",
+                        "monthly"
+                    ]
+                },
+                "reference": {
+                    "type": "string",
+                    "example": "FLAT-12"
+                },
+                "retryAt": {
+                    "description": "RetryAt and Attempts are set when the transfer failed and it is retried.",
+                    "type": "string"
+                },
+                "senderBalanceId": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "active",
+                        "paused",
+                        "completed",
+                        "cancelled",
+                        "failed"
+                    ]
+                }
+            }
+        },
+        "model.TOTPEnrollmentResponse": {
+            "type": "object",
+            "properties": {
+                "secret": {
+                    "type": "string",
+                    "example": "JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"
+                },
+                "uri": {
+                    "type": "string",
+                    "example": "otpauth://totp/walletApi:test11?algorithm=SHA1\u0026digits=6\u0026issuer=walletApi\u0026period=30\u0026secret=JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"
                 }
             }
         },
         "model.TokenResponse": {
             "type": "object",
             "properties": {
+                "mfaToken": {
+                    "description": "MFAToken is returned instead of other tokens when user has two-factor authentication enabled, see /login/mfa.",
+                    "type": "string",
+                    "example": "eyJhbGciOiJFZERTQSIsImtpZCI6IjIwMjYtMTAtMTgiLCJ0eXAiOiJKV1QifQ...."
+                },
+                "refreshToken": {
+                    "type": "string",
+                    "example": "q3Zk8yV1cXJ0b2tlbi1leGFtcGxlLXZhbHVlLTQzLWNoYXJz"
+                },
                 "token": {
                     "type": "string",
                     "example": "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9.eyJ1c2VyX2lkIjoxLC....jUxOTd9.1vqDegq6YpbXuI5qrfKDG_-AloRajTBuE1eZCMhU1no"
                 }
             }
         },
+        "model.TransactionPageResponse": {
+            "type": "object",
+            "properties": {
+                "next": {
+                    "description": "Next is cursor of the next page, to be sent in cursor query param.",
+                    "type": "string",
+                    "example": "MTc2MDc2NjQwMDAwMDAwMDAwMC40Mg"
+                },
+                "transactions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.TransactionResponse"
+                    }
+                }
+            }
+        },
         "model.TransactionRequest": {
             "type": "object",
             "properties": {
                 "amount": {
                     "type": "number",
-                    "example": 20
+                    "example": 20.5
+                },
+                "convert": {
+                    "description": "Convert must be set to transfer money between balances in different currencies.",
+                    "type": "boolean",
+                    "example": false
+                },
+                "currency": {
+                    "description": "Currency is optional, when set it must match currency of sender balance.",
+                    "type": "string",
+                    "example": "SGD"
+                },
+                "memo": {
+                    "type": "string",
+                    "example": "Dinner on Friday"
+                },
+                "metadata": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "receiver": {
+                    "description": "Receiver is login, e-mail or phone alias of the receiver, it can be set instead of ReceiverBalanceID.\nMoney is sent to the receiver's balance in currency of the sender balance.",
+                    "type": "string",
+                    "example": "zazu18"
                 },
                 "receiverBalanceId": {
                     "type": "integer",
                     "example": 2
                 },
+                "reference": {
+                    "type": "string",
+                    "example": "INV-2026-0042"
+                },
                 "senderBalanceId": {
                     "type": "integer",
                     "example": 1
@@ -291,6 +3615,11 @@ var doc = `{
                 "amount": {
                     "type": "number"
                 },
+                "counterpartyName": {
+                    "description": "CounterpartyName is display name of the other party of the transaction, set only when requested.",
+                    "type": "string",
+                    "example": "Alice C."
+                },
                 "currency": {
                     "type": "string"
                 },
@@ -300,16 +3629,104 @@ var doc = `{
                 "id": {
                     "type": "integer"
                 },
+                "memo": {
+                    "type": "string",
+                    "example": "Dinner on Friday"
+                },
+                "metadata": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "rate": {
+                    "type": "number"
+                },
+                "rateDate": {
+                    "type": "string"
+                },
+                "receiverAmount": {
+                    "description": "fields below are set only for transfers converted between currencies",
+                    "type": "number"
+                },
                 "receiverBalanceId": {
                     "type": "integer"
                 },
+                "receiverCurrency": {
+                    "type": "string"
+                },
+                "reference": {
+                    "type": "string",
+                    "example": "INV-2026-0042"
+                },
+                "refundOf": {
+                    "description": "RefundOf is ID of the transaction refunded by this one.",
+                    "type": "integer",
+                    "example": 42
+                },
+                "refundedAmount": {
+                    "description": "RefundedAmount is total of refunds of the transaction in currency of the receiver balance.",
+                    "type": "number"
+                },
                 "senderBalanceId": {
                     "type": "integer"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "completed",
+                        "partially_refunded",
+                        "refunded"
+                    ]
+                }
+            }
+        },
+        "model.UserRequest": {
+            "type": "object",
+            "properties": {
+                "age": {
+                    "type": "integer",
+                    "example": 25
+                },
+                "currency": {
+                    "description": "Currency of initial balance, SGD when not set.",
+                    "type": "string",
+                    "example": "SGD"
+                },
+                "firstName": {
+                    "type": "string",
+                    "example": "Alice"
+                },
+                "lastName": {
+                    "type": "string",
+                    "example": "Cruz"
+                },
+                "login": {
+                    "type": "string",
+                    "example": "ala11"
+                },
+                "password": {
+                    "type": "string",
+                    "example": "1qazXSW@"
+                }
+            }
+        },
+        "model.UserResponse": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer",
+                    "example": 5
                 }
             }
         }
     },
     "securityDefinitions": {
+        "APIKey": {
+            "type": "apiKey",
+            "name": "X-API-Key",
+            "in": "header"
+        },
         "ApiKeyAuth": {
             "type": "apiKey",
             "name": "Authorization",
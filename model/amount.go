@@ -0,0 +1,164 @@
+package model
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgtype"
+)
+
+var ErrInvalidAmount = errors.New("amount must be a decimal number, e.g. 10.25")
+var ErrAmountPrecision = errors.New("amount cannot have more than 2 decimal places")
+
+// amountScale is the number of decimal places kept by Amount. It matches NUMERIC(12, 2) columns in the database.
+const amountScale = 2
+
+// MaxAmount is the largest amount which fits NUMERIC(12, 2) columns, larger amounts could not be stored anyway.
+const MaxAmount Amount = 999999999999
+
+var ErrAmountTooLarge = fmt.Errorf("amount cannot be greater than %s", MaxAmount)
+
+// Amount is an exact money value stored as a whole number of hundredths (e.g. cents), so adding and subtracting never drifts.
+type Amount int64
+
+// ParseAmount parses decimal string such as "10", "-3.5" or "0.01". More than 2 decimal places is an error - the value is never rounded.
+func ParseAmount(s string) (Amount, error) {
+	units, err := parseDecimal(s, amountScale)
+	if err == errDecimalPrecision {
+		return 0, ErrAmountPrecision
+	}
+	if err != nil {
+		return 0, ErrInvalidAmount
+	}
+	return Amount(units), nil
+}
+
+// MustParseAmount is like ParseAmount but panics if the string cannot be parsed. It simplifies initialization of fixed amounts.
+func MustParseAmount(s string) Amount {
+	a, err := ParseAmount(s)
+	if err != nil {
+		panic(fmt.Sprintf("model: ParseAmount(%q): %v", s, err))
+	}
+	return a
+}
+
+var errDecimalPrecision = errors.New("too many decimal places")
+var errDecimalSyntax = errors.New("invalid decimal number")
+
+// parseDecimal parses decimal string into whole number of 10^-scale units, e.g. "1.5" with scale 2 gives 150.
+func parseDecimal(s string, scale int) (int64, error) {
+	s = strings.TrimSpace(s)
+	neg := false
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		neg = s[0] == '-'
+		s = s[1:]
+	}
+	whole, frac := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		whole, frac = s[:i], s[i+1:]
+	}
+	if whole == "" && frac == "" || !isDigits(whole) || !isDigits(frac) {
+		return 0, errDecimalSyntax
+	}
+	// trailing zeros do not change the value, e.g. "10.500" is exactly 10.50
+	trimmed := strings.TrimRight(frac, "0")
+	if len(trimmed) > scale {
+		return 0, errDecimalPrecision
+	}
+	frac = trimmed + strings.Repeat("0", scale-len(trimmed))
+
+	if whole == "" {
+		whole = "0"
+	}
+	units, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return 0, errDecimalSyntax
+	}
+	if neg {
+		units = -units
+	}
+	return units, nil
+}
+
+// formatDecimal is the reverse of parseDecimal, it always prints scale decimal places.
+func formatDecimal(units int64, scale int) string {
+	sign := ""
+	if units < 0 {
+		sign = "-"
+		units = -units
+	}
+	s := strconv.FormatInt(units, 10)
+	if len(s) <= scale {
+		s = strings.Repeat("0", scale-len(s)+1) + s
+	}
+	if scale == 0 {
+		return sign + s
+	}
+	return sign + s[:len(s)-scale] + "." + s[len(s)-scale:]
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns amount with exactly 2 decimal places, e.g. "10.50".
+func (a Amount) String() string {
+	return formatDecimal(int64(a), amountScale)
+}
+
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalJSON accepts both JSON number (10.25) and JSON string ("10.25").
+func (a *Amount) UnmarshalJSON(b []byte) error {
+	s := string(b)
+	if s == "null" {
+		return nil
+	}
+	s = strings.Trim(s, `"`)
+	parsed, err := ParseAmount(s)
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+// Scan implements sql.Scanner so NUMERIC columns can be read directly into Amount.
+func (a *Amount) Scan(src interface{}) error {
+	var err error
+	switch v := src.(type) {
+	case nil:
+		*a = 0
+	case string:
+		*a, err = ParseAmount(v)
+	case []byte:
+		*a, err = ParseAmount(string(v))
+	case int64:
+		*a = Amount(v * 100)
+	case float64:
+		*a, err = ParseAmount(strconv.FormatFloat(v, 'f', -1, 64))
+	default:
+		err = fmt.Errorf("cannot scan %T into Amount", src)
+	}
+	return err
+}
+
+// Value implements driver.Valuer.
+func (a Amount) Value() (driver.Value, error) {
+	return a.String(), nil
+}
+
+// EncodeText implements pgtype.TextEncoder. Without it pgx would treat Amount as plain integer number of hundredths.
+func (a Amount) EncodeText(ci *pgtype.ConnInfo, buf []byte) ([]byte, error) {
+	return append(buf, a.String()...), nil
+}
@@ -0,0 +1,117 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseAmount(t *testing.T) {
+	cases := []struct {
+		in          string
+		want        Amount
+		expectedErr error
+	}{
+		{in: "10", want: 1000},
+		{in: "10.5", want: 1050},
+		{in: "10.50", want: 1050},
+		{in: "10.500", want: 1050},
+		{in: "0.01", want: 1},
+		{in: ".99", want: 99},
+		{in: "-3.25", want: -325},
+		{in: "0.009", expectedErr: ErrAmountPrecision},
+		{in: "10.999", expectedErr: ErrAmountPrecision},
+		{in: "", expectedErr: ErrInvalidAmount},
+		{in: "1e2", expectedErr: ErrInvalidAmount},
+		{in: "ten", expectedErr: ErrInvalidAmount},
+	}
+	for _, testCase := range cases {
+		got, err := ParseAmount(testCase.in)
+		if err != testCase.expectedErr {
+			t.Errorf("ParseAmount(%q) error got: %v; want: %v", testCase.in, err, testCase.expectedErr)
+		}
+		if got != testCase.want {
+			t.Errorf("ParseAmount(%q) got: %d; want: %d", testCase.in, got, testCase.want)
+		}
+	}
+}
+
+func TestAmountString(t *testing.T) {
+	cases := map[Amount]string{
+		0:       "0.00",
+		1:       "0.01",
+		1050:    "10.50",
+		-325:    "-3.25",
+		1000000: "10000.00",
+	}
+	for in, want := range cases {
+		if got := in.String(); got != want {
+			t.Errorf("Amount(%d).String() got: %s; want: %s", in, got, want)
+		}
+	}
+}
+
+func TestAmountJSON(t *testing.T) {
+	req := TransactionRequest{}
+	err := json.Unmarshal([]byte(`{"amount": 20.5}`), &req)
+	if err != nil {
+		t.Errorf("error was not expected while unmarshal amount: %s", err)
+	}
+	if req.Amount != 2050 {
+		t.Errorf("unmarshal amount got: %s; want: 20.50", req.Amount)
+	}
+
+	err = json.Unmarshal([]byte(`{"amount": "7.01"}`), &req)
+	if err != nil {
+		t.Errorf("error was not expected while unmarshal amount: %s", err)
+	}
+	if req.Amount != 701 {
+		t.Errorf("unmarshal amount got: %s; want: 7.01", req.Amount)
+	}
+
+	err = json.Unmarshal([]byte(`{"amount": 20.505}`), &req)
+	if err != ErrAmountPrecision {
+		t.Errorf("unmarshal amount error got: %v; want: %v", err, ErrAmountPrecision)
+	}
+
+	b, err := json.Marshal(BalanceResponse{ID: 1, Currency: "SGD", Balance: 1000, Held: 250, Available: 750})
+	if err != nil {
+		t.Errorf("error was not expected while marshal balance: %s", err)
+	}
+	if string(b) != `{"id":1,"currency":"SGD","balance":10.00,"held":2.50,"available":7.50}` {
+		t.Errorf("marshal balance got: %s; want: %s", b, `{"id":1,"currency":"SGD","balance":10.00,"held":2.50,"available":7.50}`)
+	}
+}
+
+func TestAmountScan(t *testing.T) {
+	cases := []struct {
+		src  interface{}
+		want Amount
+	}{
+		{src: "1000.00", want: 100000},
+		{src: []byte("25.25"), want: 2525},
+		{src: int64(7), want: 700},
+		{src: 0.1, want: 10},
+	}
+	for _, testCase := range cases {
+		var got Amount
+		if err := got.Scan(testCase.src); err != nil {
+			t.Errorf("error was not expected while scanning %v: %s", testCase.src, err)
+		}
+		if got != testCase.want {
+			t.Errorf("Scan(%v) got: %s; want: %s", testCase.src, got, testCase.want)
+		}
+	}
+}
+
+func TestAmountNoDrift(t *testing.T) {
+	b := Balance{}
+	for i := 0; i < 1000; i++ {
+		b.Increase(MustParseAmount("0.10"))
+	}
+	for i := 0; i < 300; i++ {
+		b.Decrease(MustParseAmount("0.10"))
+	}
+	if b.Balance != MustParseAmount("70.00") {
+		t.Errorf("balance after 1000 increases and 300 decreases by 0.10 got: %s; want: 70.00", b.Balance)
+	}
+}
@@ -0,0 +1,107 @@
+package model
+
+import "errors"
+
+var ErrUnknownCurrency = errors.New("unknown currency, expected ISO 4217 code, e.g. SGD")
+
+// Currency is ISO 4217 alphabetic code, e.g. "SGD".
+type Currency string
+
+const (
+	AUD Currency = "AUD"
+	CAD Currency = "CAD"
+	CHF Currency = "CHF"
+	CLP Currency = "CLP"
+	CNY Currency = "CNY"
+	CZK Currency = "CZK"
+	DKK Currency = "DKK"
+	EUR Currency = "EUR"
+	GBP Currency = "GBP"
+	HKD Currency = "HKD"
+	IDR Currency = "IDR"
+	INR Currency = "INR"
+	ISK Currency = "ISK"
+	JPY Currency = "JPY"
+	KRW Currency = "KRW"
+	MYR Currency = "MYR"
+	NOK Currency = "NOK"
+	NZD Currency = "NZD"
+	PHP Currency = "PHP"
+	PLN Currency = "PLN"
+	SEK Currency = "SEK"
+	SGD Currency = "SGD"
+	THB Currency = "THB"
+	USD Currency = "USD"
+	VND Currency = "VND"
+)
+
+// CurrencyInfo describes currency as defined by ISO 4217.
+type CurrencyInfo struct {
+	Code Currency
+	// Number is ISO 4217 numeric code.
+	Number string
+	// MinorUnits is number of decimal places used by the currency, e.g. 2 for SGD (cents), 0 for JPY.
+	MinorUnits int
+}
+
+// currencies is the registry of supported currencies.
+// Balances are stored as NUMERIC(12, 2) so currencies with 3 minor units (e.g. BHD, KWD) are not supported.
+var currencies = map[Currency]CurrencyInfo{
+	AUD: {Code: AUD, Number: "036", MinorUnits: 2},
+	CAD: {Code: CAD, Number: "124", MinorUnits: 2},
+	CHF: {Code: CHF, Number: "756", MinorUnits: 2},
+	CLP: {Code: CLP, Number: "152", MinorUnits: 0},
+	CNY: {Code: CNY, Number: "156", MinorUnits: 2},
+	CZK: {Code: CZK, Number: "203", MinorUnits: 2},
+	DKK: {Code: DKK, Number: "208", MinorUnits: 2},
+	EUR: {Code: EUR, Number: "978", MinorUnits: 2},
+	GBP: {Code: GBP, Number: "826", MinorUnits: 2},
+	HKD: {Code: HKD, Number: "344", MinorUnits: 2},
+	IDR: {Code: IDR, Number: "360", MinorUnits: 2},
+	INR: {Code: INR, Number: "356", MinorUnits: 2},
+	ISK: {Code: ISK, Number: "352", MinorUnits: 0},
+	JPY: {Code: JPY, Number: "392", MinorUnits: 0},
+	KRW: {Code: KRW, Number: "410", MinorUnits: 0},
+	MYR: {Code: MYR, Number: "458", MinorUnits: 2},
+	NOK: {Code: NOK, Number: "578", MinorUnits: 2},
+	NZD: {Code: NZD, Number: "554", MinorUnits: 2},
+	PHP: {Code: PHP, Number: "608", MinorUnits: 2},
+	PLN: {Code: PLN, Number: "985", MinorUnits: 2},
+	SEK: {Code: SEK, Number: "752", MinorUnits: 2},
+	SGD: {Code: SGD, Number: "702", MinorUnits: 2},
+	THB: {Code: THB, Number: "764", MinorUnits: 2},
+	USD: {Code: USD, Number: "840", MinorUnits: 2},
+	VND: {Code: VND, Number: "704", MinorUnits: 0},
+}
+
+func (c Currency) IsValid() bool {
+	_, ok := currencies[c]
+	return ok
+}
+
+// Info returns ISO 4217 details of the currency. Second value is false for unknown currency.
+func (c Currency) Info() (CurrencyInfo, bool) {
+	info, ok := currencies[c]
+	return info, ok
+}
+
+// MinorUnits returns number of decimal places of the currency.
+func (c Currency) MinorUnits() int {
+	return currencies[c].MinorUnits
+}
+
+// AllowsAmount checks if amount can be expressed in the currency, e.g. 10.50 is not a valid JPY amount.
+func (c Currency) AllowsAmount(a Amount) bool {
+	info, ok := currencies[c]
+	if !ok {
+		return false
+	}
+	if info.MinorUnits >= amountScale {
+		return true
+	}
+	step := Amount(1)
+	for i := info.MinorUnits; i < amountScale; i++ {
+		step *= 10
+	}
+	return a%step == 0
+}
@@ -0,0 +1,57 @@
+package model
+
+import "testing"
+
+func TestCurrencyIsValid(t *testing.T) {
+	cases := map[Currency]bool{
+		SGD:   true,
+		USD:   true,
+		JPY:   true,
+		"XXX": false,
+		"KWD": false,
+		"sgd": false,
+		"":    false,
+	}
+	for c, want := range cases {
+		if got := c.IsValid(); got != want {
+			t.Errorf("Currency(%q).IsValid() got: %t; want: %t", c, got, want)
+		}
+	}
+}
+
+func TestCurrencyMinorUnits(t *testing.T) {
+	cases := map[Currency]int{
+		SGD: 2,
+		JPY: 0,
+	}
+	for c, want := range cases {
+		if got := c.MinorUnits(); got != want {
+			t.Errorf("Currency(%q).MinorUnits() got: %d; want: %d", c, got, want)
+		}
+	}
+	// amounts of every currency must be stored exactly
+	for c, info := range currencies {
+		if info.MinorUnits > amountScale {
+			t.Errorf("Currency(%q) has %d minor units, amounts are stored with %d", c, info.MinorUnits, amountScale)
+		}
+	}
+}
+
+func TestCurrencyAllowsAmount(t *testing.T) {
+	cases := []struct {
+		currency Currency
+		amount   Amount
+		want     bool
+	}{
+		{currency: SGD, amount: MustParseAmount("10.99"), want: true},
+		{currency: JPY, amount: MustParseAmount("1000"), want: true},
+		{currency: JPY, amount: MustParseAmount("10.50"), want: false},
+		{currency: USD, amount: MustParseAmount("0.01"), want: true},
+		{currency: "XXX", amount: MustParseAmount("1"), want: false},
+	}
+	for _, testCase := range cases {
+		if got := testCase.currency.AllowsAmount(testCase.amount); got != testCase.want {
+			t.Errorf("Currency(%q).AllowsAmount(%s) got: %t; want: %t", testCase.currency, testCase.amount, got, testCase.want)
+		}
+	}
+}
@@ -5,9 +5,10 @@ import "time"
 type BalanceDB struct {
 	ID       int
 	Currency Currency
-	Balance  float64
-	Locked   bool
-	UserID   int
+	Balance  Amount
+	// Held is reserved by active holds of the balance, it cannot be transferred - see Balance.Available.
+	Held   Amount
+	UserID int
 }
 
 func ConvertListBalance(from []Balance) []BalanceDB {
@@ -22,16 +23,174 @@ type TransactionDB struct {
 	ID                int
 	SenderBalanceID   int
 	ReceiverBalanceID int
-	Amount            float64
+	Amount            Amount
 	Currency          Currency
 	Date              time.Time
+	ReceiverAmount    Amount
+	ReceiverCurrency  Currency
+	Rate              Rate
+	RateDate          time.Time
+	TransactionDetails
+	RefundOf       int
+	RefundedAmount Amount
+}
+
+func ConvertTransaction(from Transaction) TransactionDB {
+	return TransactionDB{
+		ID:                 from.ID,
+		SenderBalanceID:    from.SenderBalanceID,
+		ReceiverBalanceID:  from.ReceiverBalanceID,
+		Amount:             from.Amount,
+		Currency:           from.Currency,
+		Date:               from.Date,
+		ReceiverAmount:     from.ReceiverAmount,
+		ReceiverCurrency:   from.ReceiverCurrency,
+		Rate:               from.Rate,
+		RateDate:           from.RateDate,
+		TransactionDetails: from.TransactionDetails,
+		RefundOf:           from.RefundOf,
+		RefundedAmount:     from.RefundedAmount,
+	}
 }
 
 type TransactionDBFull struct {
-	ID              int
+	ID               int
+	SenderBalance    BalanceDB
+	ReceiverBalance  BalanceDB
+	Amount           Amount
+	Currency         Currency
+	Date             time.Time
+	ReceiverAmount   Amount
+	ReceiverCurrency Currency
+	Rate             Rate
+	RateDate         time.Time
+	TransactionDetails
+	RefundOf int
+}
+
+type HoldDB struct {
+	ID                int
+	SenderBalanceID   int
+	ReceiverBalanceID int
+	Amount            Amount
+	Currency          Currency
+	Status            HoldStatus
+	CapturedAmount    Amount
+	TransactionID     int
+	CreatedAt         time.Time
+	ExpiresAt         time.Time
+	TransactionDetails
+}
+
+// HoldDBFull is hold with its sender and receiver balances.
+type HoldDBFull struct {
+	HoldDB
 	SenderBalance   BalanceDB
 	ReceiverBalance BalanceDB
-	Amount          float64
+}
+
+type ScheduleDB struct {
+	ID                int
+	UserID            int
+	SenderBalanceID   int
+	ReceiverBalanceID int
+	Amount            Amount
+	Currency          Currency
+	Convert           bool
+	Recurrence        Recurrence
+	DayOfMonth        int
+	Status            ScheduleStatus
+	NextRunAt         time.Time
+	RetryAt           time.Time
+	Attempts          int
+	LastError         string
+	LastRunAt         time.Time
+	LastTransactionID int
+	CreatedAt         time.Time
+	TransactionDetails
+}
+
+type BatchDB struct {
+	ID              int
+	UserID          int
+	SenderBalanceID int
 	Currency        Currency
-	Date            time.Time
+	Convert         bool
+	Mode            BatchMode
+	Status          BatchStatus
+	Items           []BatchItemDB
+	CreatedAt       time.Time
+	CompletedAt     time.Time
+}
+
+type BatchItemDB struct {
+	Index             int
+	ReceiverBalanceID int
+	Amount            Amount
+	Status            BatchItemStatus
+	TransactionID     int
+	Error             string
+	TransactionDetails
+}
+
+type IdempotencyKeyDB struct {
+	UserID       int
+	Key          string
+	Fingerprint  string
+	ResponseCode int
+	ResponseBody []byte
+	CreatedAt    time.Time
+}
+
+type UserDB struct {
+	ID        int
+	FirstName string
+	LastName  string
+	Age       int
+}
+
+type RefreshTokenDB struct {
+	ID        int
+	TokenHash string
+	Family    string
+	UserID    int
+	ExpiresAt time.Time
+	RevokedAt time.Time
+	CreatedAt time.Time
+}
+
+type LoginAttemptDB struct {
+	Key           string
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   time.Time
+}
+
+type MFADB struct {
+	UserID       int
+	Secret       string
+	ConfirmedAt  time.Time
+	LastUsedStep int64
+}
+
+type PasswordResetDB struct {
+	TokenHash string
+	UserID    int
+	ExpiresAt time.Time
+	UsedAt    time.Time
+	CreatedAt time.Time
+}
+
+type APIKeyDB struct {
+	ID      int
+	KeyHash string
+	// Prefix is the beginning of the key shown to the user, so the key can be recognized without revealing it.
+	Prefix     string
+	UserID     int
+	Name       string
+	Scopes     []string
+	AllowedIPs []string
+	ExpiresAt  time.Time
+	RevokedAt  time.Time
+	CreatedAt  time.Time
 }
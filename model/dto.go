@@ -2,41 +2,181 @@ package model
 
 import (
 	"errors"
-	"math"
+	"fmt"
 	"net/http"
+	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
+// maxReceiverLength matches alias column of user_alias table.
+const maxReceiverLength = 255
+
+// maxMemoLength and maxReferenceLength match VARCHAR columns of transaction table, metadata is limited
+// to maxMetadataKeys keys of at most maxMetadataKeyLength letters, digits, '_', '.' or '-'.
+const maxMemoLength = 140
+const maxReferenceLength = 64
+const maxMetadataKeys = 10
+const maxMetadataKeyLength = 40
+const maxMetadataValueLength = 200
+
+// MaxBatchItems limits number of transfers in one batch.
+const MaxBatchItems = 1000
+
 type TransactionRequest struct {
-	SenderBalanceID   int     `json:"senderBalanceId,omitempty" example:"1"`
-	ReceiverBalanceID int     `json:"receiverBalanceId,omitempty" example:"2"`
-	Amount            float64 `json:"amount,omitempty" example:"20"`
+	SenderBalanceID   int `json:"senderBalanceId,omitempty" example:"1"`
+	ReceiverBalanceID int `json:"receiverBalanceId,omitempty" example:"2"`
+	// Receiver is login, e-mail or phone alias of the receiver, it can be set instead of ReceiverBalanceID.
+	// Money is sent to the receiver's balance in currency of the sender balance.
+	Receiver string `json:"receiver,omitempty" example:"zazu18"`
+	Amount   Amount `json:"amount,omitempty" swaggertype:"number" example:"20.50"`
+	// Currency is optional, when set it must match currency of sender balance.
+	Currency string `json:"currency,omitempty" example:"SGD"`
+	// Convert must be set to transfer money between balances in different currencies.
+	Convert   bool              `json:"convert,omitempty" example:"false"`
+	Memo      string            `json:"memo,omitempty" example:"Dinner on Friday"`
+	Reference string            `json:"reference,omitempty" example:"INV-2026-0042"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
 }
 
 func (tr TransactionRequest) IsValid() (bool, error) {
-	if tr.SenderBalanceID <= 0 || tr.ReceiverBalanceID <= 0 {
+	if tr.ReceiverBalanceID != 0 && tr.Receiver != "" {
+		return false, errors.New("only one of receiverBalanceId and receiver can be set")
+	}
+	if tr.Receiver != "" {
+		if tr.SenderBalanceID <= 0 {
+			return false, errors.New("sender balance not found")
+		}
+		if utf8.RuneCountInString(tr.Receiver) > maxReceiverLength {
+			return false, fmt.Errorf("receiver can have at most %d characters", maxReceiverLength)
+		}
+	} else if tr.SenderBalanceID <= 0 || tr.ReceiverBalanceID <= 0 {
 		return false, errors.New("sender or receiver balance not found")
 	}
 	if tr.SenderBalanceID == tr.ReceiverBalanceID {
 		return false, errors.New("sender and receiver balances cannot be the same")
 	}
-	if math.Floor(tr.Amount*100)/100 <= 0 {
-		return false, errors.New("amount (rounded down to 2 decimal places) field must be greater then 0")
+	if tr.Amount <= 0 {
+		return false, errors.New("amount field must be greater then 0")
+	}
+	if tr.Amount > MaxAmount {
+		return false, ErrAmountTooLarge
+	}
+	if tr.Currency != "" && !Currency(tr.Currency).IsValid() {
+		return false, ErrUnknownCurrency
+	}
+	if err := validateDetails(tr.Details()); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (tr TransactionRequest) Details() TransactionDetails {
+	return TransactionDetails{Memo: tr.Memo, Reference: tr.Reference, Metadata: tr.Metadata}
+}
+
+// RefundRequest returns money of a transaction to its sender. Amount is in currency of the receiver balance of the
+// transaction, when it is not set whole not refunded amount is returned.
+type RefundRequest struct {
+	Amount    Amount `json:"amount,omitempty" swaggertype:"number" example:"5.25"`
+	Memo      string `json:"memo,omitempty" example:"Returned item"`
+	Reference string `json:"reference,omitempty" example:"RMA-2026-0007"`
+}
+
+func (rr RefundRequest) IsValid() (bool, error) {
+	if rr.Amount < 0 {
+		return false, errors.New("amount field must be greater then 0")
+	}
+	if rr.Amount > MaxAmount {
+		return false, ErrAmountTooLarge
+	}
+	if err := validateDetails(TransactionDetails{Memo: rr.Memo, Reference: rr.Reference}); err != nil {
+		return false, err
 	}
 	return true, nil
 }
 
+func (rr RefundRequest) ToRefund(transactionID int) Refund {
+	return Refund{TransactionID: transactionID, Amount: rr.Amount, TransactionDetails: TransactionDetails{Memo: rr.Memo, Reference: rr.Reference}}
+}
+
+// validateDetails checks memo, reference and metadata given by the client.
+func validateDetails(d TransactionDetails) error {
+	if utf8.RuneCountInString(d.Memo) > maxMemoLength || hasControlCharacters(d.Memo) {
+		return fmt.Errorf("memo can have at most %d characters and cannot contain control characters", maxMemoLength)
+	}
+	if utf8.RuneCountInString(d.Reference) > maxReferenceLength || hasControlCharacters(d.Reference) {
+		return fmt.Errorf("reference can have at most %d characters and cannot contain control characters", maxReferenceLength)
+	}
+	if len(d.Metadata) > maxMetadataKeys {
+		return fmt.Errorf("metadata can have at most %d keys", maxMetadataKeys)
+	}
+	for k, v := range d.Metadata {
+		if !isValidMetadataKey(k) {
+			return fmt.Errorf("metadata key can have from 1 to %d letters, digits, '_', '.' or '-'", maxMetadataKeyLength)
+		}
+		if utf8.RuneCountInString(v) > maxMetadataValueLength || hasControlCharacters(v) {
+			return fmt.Errorf("metadata value can have at most %d characters and cannot contain control characters", maxMetadataValueLength)
+		}
+	}
+	return nil
+}
+
+func hasControlCharacters(s string) bool {
+	return strings.IndexFunc(s, unicode.IsControl) >= 0
+}
+
+func isValidMetadataKey(k string) bool {
+	if k == "" || len(k) > maxMetadataKeyLength {
+		return false
+	}
+	for _, r := range k {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '.' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
+
 type TransactionResponse struct {
 	ID                int       `json:"id,omitempty"`
 	SenderBalanceID   int       `json:"senderBalanceId,omitempty"`
 	ReceiverBalanceID int       `json:"receiverBalanceId,omitempty"`
-	Amount            float64   `json:"amount,omitempty"`
+	Amount            Amount    `json:"amount,omitempty" swaggertype:"number"`
 	Currency          string    `json:"currency,omitempty"`
 	Date              time.Time `json:"date,omitempty"`
+	// CounterpartyName is display name of the other party of the transaction, set only when requested.
+	CounterpartyName string `json:"counterpartyName,omitempty" example:"Alice C."`
+	// fields below are set only for transfers converted between currencies
+	ReceiverAmount   Amount            `json:"receiverAmount,omitempty" swaggertype:"number"`
+	ReceiverCurrency string            `json:"receiverCurrency,omitempty"`
+	Rate             Rate              `json:"rate,omitempty" swaggertype:"number"`
+	RateDate         *time.Time        `json:"rateDate,omitempty"`
+	Memo             string            `json:"memo,omitempty" example:"Dinner on Friday"`
+	Reference        string            `json:"reference,omitempty" example:"INV-2026-0042"`
+	Metadata         map[string]string `json:"metadata,omitempty"`
+	// RefundOf is ID of the transaction refunded by this one.
+	RefundOf int `json:"refundOf,omitempty" example:"42"`
+	// RefundedAmount is total of refunds of the transaction in currency of the receiver balance.
+	RefundedAmount Amount            `json:"refundedAmount,omitempty" swaggertype:"number"`
+	Status         TransactionStatus `json:"status,omitempty" enums:"completed,partially_refunded,refunded"`
 }
 
 func NewTransactionResponse(t Transaction) TransactionResponse {
-	return TransactionResponse{ID: t.ID, SenderBalanceID: t.SenderBalanceID, ReceiverBalanceID: t.ReceiverBalanceID, Amount: t.Amount, Currency: string(t.Currency), Date: t.Date}
+	resp := TransactionResponse{ID: t.ID, SenderBalanceID: t.SenderBalanceID, ReceiverBalanceID: t.ReceiverBalanceID, Amount: t.Amount, Currency: string(t.Currency), Date: t.Date,
+		Memo: t.Memo, Reference: t.Reference, Metadata: t.Metadata, RefundOf: t.RefundOf, RefundedAmount: t.RefundedAmount, Status: t.Status()}
+	if t.ReceiverCurrency != "" && t.ReceiverCurrency != t.Currency {
+		resp.ReceiverAmount = t.ReceiverAmount
+		resp.ReceiverCurrency = string(t.ReceiverCurrency)
+	}
+	// refunds of converted transfers have no rate, see Transaction.SenderRefund
+	if t.Rate != 0 {
+		rateDate := t.RateDate
+		resp.Rate = t.Rate
+		resp.RateDate = &rateDate
+	}
+	return resp
 }
 
 func NewTransactionResponses(ts []Transaction) []TransactionResponse {
@@ -47,8 +187,437 @@ func NewTransactionResponses(ts []Transaction) []TransactionResponse {
 	return ret
 }
 
+// HoldRequest reserves money of the sender balance for the receiver, see Hold.
+type HoldRequest struct {
+	SenderBalanceID   int    `json:"senderBalanceId,omitempty" example:"1"`
+	ReceiverBalanceID int    `json:"receiverBalanceId,omitempty" example:"2"`
+	Amount            Amount `json:"amount,omitempty" swaggertype:"number" example:"20.50"`
+	// ExpiresAt is in 7 days when not set, at most in 30 days.
+	ExpiresAt *time.Time        `json:"expiresAt,omitempty" example:"2026-10-25T12:00:00Z"`
+	Memo      string            `json:"memo,omitempty" example:"Order 42"`
+	Reference string            `json:"reference,omitempty" example:"ORDER-42"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+}
+
+func (hr HoldRequest) IsValid() (bool, error) {
+	if hr.SenderBalanceID <= 0 || hr.ReceiverBalanceID <= 0 {
+		return false, errors.New("sender or receiver balance not found")
+	}
+	if hr.SenderBalanceID == hr.ReceiverBalanceID {
+		return false, errors.New("sender and receiver balances cannot be the same")
+	}
+	if hr.Amount <= 0 {
+		return false, errors.New("amount field must be greater then 0")
+	}
+	if hr.Amount > MaxAmount {
+		return false, ErrAmountTooLarge
+	}
+	if err := validateDetails(TransactionDetails{Memo: hr.Memo, Reference: hr.Reference, Metadata: hr.Metadata}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (hr HoldRequest) ToHold() Hold {
+	h := Hold{SenderBalanceID: hr.SenderBalanceID, ReceiverBalanceID: hr.ReceiverBalanceID, Amount: hr.Amount,
+		TransactionDetails: TransactionDetails{Memo: hr.Memo, Reference: hr.Reference, Metadata: hr.Metadata}}
+	if hr.ExpiresAt != nil {
+		h.ExpiresAt = *hr.ExpiresAt
+	}
+	return h
+}
+
+// CaptureRequest makes transaction of the hold, whole held amount is captured when Amount is not set.
+type CaptureRequest struct {
+	Amount Amount `json:"amount,omitempty" swaggertype:"number" example:"18.00"`
+}
+
+func (cr CaptureRequest) IsValid() (bool, error) {
+	if cr.Amount < 0 {
+		return false, errors.New("amount field must be greater then 0")
+	}
+	if cr.Amount > MaxAmount {
+		return false, ErrAmountTooLarge
+	}
+	return true, nil
+}
+
+type HoldResponse struct {
+	ID                int        `json:"id" example:"7"`
+	SenderBalanceID   int        `json:"senderBalanceId" example:"1"`
+	ReceiverBalanceID int        `json:"receiverBalanceId" example:"2"`
+	Amount            Amount     `json:"amount" swaggertype:"number" example:"20.50"`
+	Currency          string     `json:"currency" example:"SGD"`
+	Status            HoldStatus `json:"status" enums:"active,captured,voided,expired"`
+	// CapturedAmount and TransactionID are set when the hold was captured.
+	CapturedAmount Amount            `json:"capturedAmount,omitempty" swaggertype:"number" example:"18.00"`
+	TransactionID  int               `json:"transactionId,omitempty" example:"43"`
+	CreatedAt      time.Time         `json:"createdAt"`
+	ExpiresAt      time.Time         `json:"expiresAt"`
+	Memo           string            `json:"memo,omitempty" example:"Order 42"`
+	Reference      string            `json:"reference,omitempty" example:"ORDER-42"`
+	Metadata       map[string]string `json:"metadata,omitempty"`
+}
+
+func NewHoldResponse(h Hold) HoldResponse {
+	return HoldResponse{ID: h.ID, SenderBalanceID: h.SenderBalanceID, ReceiverBalanceID: h.ReceiverBalanceID, Amount: h.Amount, Currency: string(h.Currency),
+		Status: h.Status, CapturedAmount: h.CapturedAmount, TransactionID: h.TransactionID, CreatedAt: h.CreatedAt, ExpiresAt: h.ExpiresAt,
+		Memo: h.Memo, Reference: h.Reference, Metadata: h.Metadata}
+}
+
+// maxDayOfMonth is the last day of the longest months.
+const maxDayOfMonth = 31
+
+// ScheduleRequest schedules transfer defined like in TransactionRequest.
+type ScheduleRequest struct {
+	TransactionRequest
+	// StartAt is time of the first transfer, it must be in the future, at most in a year.
+	StartAt time.Time `json:"startAt" example:"2026-11-01T09:00:00Z"`
+	// Recurrence is not set for transfer executed once.
+	Recurrence Recurrence `json:"recurrence,omitempty" enums:"daily,weekly,monthly"`
+	// DayOfMonth is day of monthly transfers, day of StartAt when not set. Shorter months use their last day.
+	DayOfMonth int `json:"dayOfMonth,omitempty" example:"1"`
+}
+
+func (sr ScheduleRequest) IsValid() (bool, error) {
+	if ok, err := sr.TransactionRequest.IsValid(); !ok {
+		return false, err
+	}
+	if sr.StartAt.IsZero() {
+		return false, errors.New("startAt is required")
+	}
+	if !sr.Recurrence.IsValid() {
+		return false, errors.New("recurrence must be daily, weekly or monthly")
+	}
+	if sr.DayOfMonth != 0 && (sr.Recurrence != RecurrenceMonthly || sr.DayOfMonth < 1 || sr.DayOfMonth > maxDayOfMonth) {
+		return false, fmt.Errorf("dayOfMonth from 1 to %d can be set only for monthly recurrence", maxDayOfMonth)
+	}
+	return true, nil
+}
+
+func (sr ScheduleRequest) ToSchedule() Schedule {
+	return Schedule{
+		SenderBalanceID:    sr.SenderBalanceID,
+		ReceiverBalanceID:  sr.ReceiverBalanceID,
+		Amount:             sr.Amount,
+		Currency:           Currency(sr.Currency),
+		Convert:            sr.Convert,
+		Recurrence:         sr.Recurrence,
+		DayOfMonth:         sr.DayOfMonth,
+		NextRunAt:          sr.StartAt,
+		TransactionDetails: sr.Details(),
+	}
+}
+
+type ScheduleResponse struct {
+	ID                int            `json:"id" example:"3"`
+	SenderBalanceID   int            `json:"senderBalanceId" example:"1"`
+	ReceiverBalanceID int            `json:"receiverBalanceId" example:"2"`
+	Amount            Amount         `json:"amount" swaggertype:"number" example:"1200.00"`
+	Currency          string         `json:"currency,omitempty" example:"SGD"`
+	Convert           bool           `json:"convert,omitempty" example:"false"`
+	Recurrence        Recurrence     `json:"recurrence,omitempty" enums:"daily,weekly,monthly"`
+	DayOfMonth        int            `json:"dayOfMonth,omitempty" example:"1"`
+	Status            ScheduleStatus `json:"status" enums:"active,paused,completed,cancelled,failed"`
+	// NextRunAt is set for active and paused schedules.
+	NextRunAt *time.Time `json:"nextRunAt,omitempty"`
+	// RetryAt and Attempts are set when the transfer failed and it is retried.
+	RetryAt  *time.Time `json:"retryAt,omitempty"`
+	Attempts int        `json:"attempts,omitempty" example:"1"`
+	// LastError is reason of the last failed transfer.
+	LastError         string            `json:"lastError,omitempty" example:"insufficient balance of a sender"`
+	LastRunAt         *time.Time        `json:"lastRunAt,omitempty"`
+	LastTransactionID int               `json:"lastTransactionId,omitempty" example:"42"`
+	CreatedAt         time.Time         `json:"createdAt"`
+	Memo              string            `json:"memo,omitempty" example:"Rent"`
+	Reference         string            `json:"reference,omitempty" example:"FLAT-12"`
+	Metadata          map[string]string `json:"metadata,omitempty"`
+}
+
+func NewScheduleResponse(s Schedule) ScheduleResponse {
+	resp := ScheduleResponse{ID: s.ID, SenderBalanceID: s.SenderBalanceID, ReceiverBalanceID: s.ReceiverBalanceID, Amount: s.Amount,
+		Currency: string(s.Currency), Convert: s.Convert, Recurrence: s.Recurrence, DayOfMonth: s.DayOfMonth, Status: s.Status,
+		Attempts: s.Attempts, LastError: s.LastError, LastTransactionID: s.LastTransactionID, CreatedAt: s.CreatedAt,
+		Memo: s.Memo, Reference: s.Reference, Metadata: s.Metadata}
+	if s.Status == ScheduleActive || s.Status == SchedulePaused {
+		nextRunAt := s.NextRunAt
+		resp.NextRunAt = &nextRunAt
+	}
+	if !s.RetryAt.IsZero() {
+		retryAt := s.RetryAt
+		resp.RetryAt = &retryAt
+	}
+	if !s.LastRunAt.IsZero() {
+		lastRunAt := s.LastRunAt
+		resp.LastRunAt = &lastRunAt
+	}
+	return resp
+}
+
+func NewScheduleResponses(ss []Schedule) []ScheduleResponse {
+	schedules := []ScheduleResponse{}
+	for _, s := range ss {
+		schedules = append(schedules, NewScheduleResponse(s))
+	}
+	return schedules
+}
+
+// BatchRequest is list of transfers from one sender balance, receivers are given only by balance IDs.
+type BatchRequest struct {
+	SenderBalanceID int `json:"senderBalanceId" example:"1"`
+	// Currency is optional, when set it must match currency of sender balance.
+	Currency string `json:"currency,omitempty" example:"SGD"`
+	// Convert must be set to transfer money to balances in different currencies.
+	Convert bool `json:"convert,omitempty" example:"false"`
+	// Mode all_or_nothing transfers all items or none of them, best_effort transfers every item which can be transferred.
+	Mode  BatchMode          `json:"mode" enums:"all_or_nothing,best_effort"`
+	Items []BatchItemRequest `json:"items"`
+}
+
+type BatchItemRequest struct {
+	ReceiverBalanceID int               `json:"receiverBalanceId" example:"2"`
+	Amount            Amount            `json:"amount" swaggertype:"number" example:"3200.00"`
+	Memo              string            `json:"memo,omitempty" example:"Salary 10/2026"`
+	Reference         string            `json:"reference,omitempty" example:"PAYROLL-2026-10"`
+	Metadata          map[string]string `json:"metadata,omitempty"`
+}
+
+func (br BatchRequest) IsValid() (bool, error) {
+	if br.SenderBalanceID <= 0 {
+		return false, errors.New("sender balance not found")
+	}
+	if br.Currency != "" && !Currency(br.Currency).IsValid() {
+		return false, ErrUnknownCurrency
+	}
+	if !br.Mode.IsValid() {
+		return false, errors.New("mode must be all_or_nothing or best_effort")
+	}
+	if len(br.Items) == 0 || len(br.Items) > MaxBatchItems {
+		return false, fmt.Errorf("batch must have from 1 to %d items", MaxBatchItems)
+	}
+	for i, item := range br.Items {
+		if item.ReceiverBalanceID <= 0 {
+			return false, fmt.Errorf("items[%d]: receiver balance not found", i)
+		}
+		if item.ReceiverBalanceID == br.SenderBalanceID {
+			return false, fmt.Errorf("items[%d]: sender and receiver balances cannot be the same", i)
+		}
+		if item.Amount <= 0 {
+			return false, fmt.Errorf("items[%d]: amount field must be greater then 0", i)
+		}
+		// caps total of the batch too, so it cannot overflow
+		if item.Amount > MaxAmount {
+			return false, fmt.Errorf("items[%d]: %w", i, ErrAmountTooLarge)
+		}
+		if err := validateDetails(item.details()); err != nil {
+			return false, fmt.Errorf("items[%d]: %w", i, err)
+		}
+	}
+	return true, nil
+}
+
+func (ir BatchItemRequest) details() TransactionDetails {
+	return TransactionDetails{Memo: ir.Memo, Reference: ir.Reference, Metadata: ir.Metadata}
+}
+
+func (br BatchRequest) ToBatch() Batch {
+	items := []BatchItem{}
+	for i, item := range br.Items {
+		items = append(items, BatchItem{Index: i, ReceiverBalanceID: item.ReceiverBalanceID, Amount: item.Amount, Status: BatchItemPending,
+			TransactionDetails: item.details()})
+	}
+	return Batch{SenderBalanceID: br.SenderBalanceID, Currency: Currency(br.Currency), Convert: br.Convert, Mode: br.Mode, Items: items}
+}
+
+type BatchResponse struct {
+	ID              int         `json:"id" example:"12"`
+	SenderBalanceID int         `json:"senderBalanceId" example:"1"`
+	Currency        string      `json:"currency" example:"SGD"`
+	Convert         bool        `json:"convert,omitempty" example:"false"`
+	Mode            BatchMode   `json:"mode" enums:"all_or_nothing,best_effort"`
+	Status          BatchStatus `json:"status" enums:"pending,completed,partially_completed,failed"`
+	// Total is sum of amounts of all items in currency of sender balance.
+	Total       Amount              `json:"total" swaggertype:"number" example:"6400.00"`
+	Items       []BatchItemResponse `json:"items"`
+	CreatedAt   time.Time           `json:"createdAt"`
+	CompletedAt *time.Time          `json:"completedAt,omitempty"`
+}
+
+// BatchItemResponse is result of transfer of the item, items are in the order of the request.
+type BatchItemResponse struct {
+	ReceiverBalanceID int             `json:"receiverBalanceId" example:"2"`
+	Amount            Amount          `json:"amount" swaggertype:"number" example:"3200.00"`
+	Status            BatchItemStatus `json:"status" enums:"pending,completed,failed,skipped"`
+	// TransactionID is set for completed item.
+	TransactionID int `json:"transactionId,omitempty" example:"42"`
+	// Error is reason of failed or skipped item.
+	Error     string            `json:"error,omitempty" example:"insufficient balance of a sender"`
+	Memo      string            `json:"memo,omitempty" example:"Salary 10/2026"`
+	Reference string            `json:"reference,omitempty" example:"PAYROLL-2026-10"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+}
+
+func NewBatchResponse(b Batch) BatchResponse {
+	resp := BatchResponse{ID: b.ID, SenderBalanceID: b.SenderBalanceID, Currency: string(b.Currency), Convert: b.Convert, Mode: b.Mode,
+		Status: b.Status, Total: b.Total(), Items: []BatchItemResponse{}, CreatedAt: b.CreatedAt}
+	for _, item := range b.Items {
+		resp.Items = append(resp.Items, BatchItemResponse{ReceiverBalanceID: item.ReceiverBalanceID, Amount: item.Amount, Status: item.Status,
+			TransactionID: item.TransactionID, Error: item.Error, Memo: item.Memo, Reference: item.Reference, Metadata: item.Metadata})
+	}
+	if !b.CompletedAt.IsZero() {
+		completedAt := b.CompletedAt
+		resp.CompletedAt = &completedAt
+	}
+	return resp
+}
+
+// TransactionPageResponse is one page of transaction history, Next is empty on the last page.
+type TransactionPageResponse struct {
+	Transactions []TransactionResponse `json:"transactions"`
+	// Next is cursor of the next page, to be sent in cursor query param.
+	Next string `json:"next,omitempty" example:"MTc2MDc2NjQwMDAwMDAwMDAwMC40Mg"`
+}
+
+func NewTransactionPageResponse(p TransactionPage) TransactionPageResponse {
+	resp := TransactionPageResponse{Transactions: NewTransactionResponses(p.Transactions)}
+	if p.Next != nil {
+		resp.Next = p.Next.String()
+	}
+	return resp
+}
+
+// TransactionFilterRequest holds query params of transaction history, see TransactionFilter.
+type TransactionFilterRequest struct {
+	Limit                 int    `query:"limit"`
+	Cursor                string `query:"cursor"`
+	From                  string `query:"from"`
+	To                    string `query:"to"`
+	MinAmount             string `query:"minAmount"`
+	MaxAmount             string `query:"maxAmount"`
+	Direction             string `query:"direction"`
+	CounterpartyBalanceID int    `query:"counterpartyBalanceId"`
+	Currency              string `query:"currency"`
+	Reference             string `query:"reference"`
+	Memo                  string `query:"memo"`
+	// Metadata is key:value pair of metadata.
+	Metadata string `query:"metadata"`
+}
+
+// ToFilter parses and validates the params.
+func (r TransactionFilterRequest) ToFilter() (TransactionFilter, error) {
+	f := TransactionFilter{
+		Limit:                 r.Limit,
+		Direction:             Direction(r.Direction),
+		CounterpartyBalanceID: r.CounterpartyBalanceID,
+		Currency:              Currency(r.Currency),
+		Reference:             r.Reference,
+		Memo:                  r.Memo,
+	}
+	if r.Metadata != "" {
+		i := strings.Index(r.Metadata, ":")
+		if i < 0 || !isValidMetadataKey(r.Metadata[:i]) {
+			return TransactionFilter{}, errors.New("metadata must be key:value pair, e.g. orderId:42")
+		}
+		f.MetadataKey, f.MetadataValue = r.Metadata[:i], r.Metadata[i+1:]
+	}
+	if f.Limit == 0 {
+		f.Limit = DefaultTransactionsLimit
+	}
+	if r.Cursor != "" {
+		cursor, err := ParseTransactionCursor(r.Cursor)
+		if err != nil {
+			return TransactionFilter{}, err
+		}
+		f.After = &cursor
+	}
+	var err error
+	for _, d := range []struct {
+		param string
+		value string
+		to    *time.Time
+	}{{"from", r.From, &f.From}, {"to", r.To, &f.To}} {
+		if d.value == "" {
+			continue
+		}
+		if *d.to, err = time.Parse(time.RFC3339, d.value); err != nil {
+			return TransactionFilter{}, fmt.Errorf("%s must be a date in RFC 3339 format, e.g. 2026-10-18T00:00:00Z", d.param)
+		}
+		*d.to = d.to.UTC()
+	}
+	for _, a := range []struct {
+		param string
+		value string
+		to    *Amount
+	}{{"minAmount", r.MinAmount, &f.MinAmount}, {"maxAmount", r.MaxAmount, &f.MaxAmount}} {
+		if a.value == "" {
+			continue
+		}
+		if *a.to, err = ParseAmount(a.value); err != nil {
+			return TransactionFilter{}, fmt.Errorf("%s must be an amount with at most 2 decimal places", a.param)
+		}
+	}
+	if ok, err := f.IsValid(); !ok {
+		return TransactionFilter{}, err
+	}
+	return f, nil
+}
+
 type TokenResponse struct {
-	Token string `json:"token,omitempty" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9.eyJ1c2VyX2lkIjoxLC....jUxOTd9.1vqDegq6YpbXuI5qrfKDG_-AloRajTBuE1eZCMhU1no"`
+	Token        string `json:"token,omitempty" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9.eyJ1c2VyX2lkIjoxLC....jUxOTd9.1vqDegq6YpbXuI5qrfKDG_-AloRajTBuE1eZCMhU1no"`
+	RefreshToken string `json:"refreshToken,omitempty" example:"q3Zk8yV1cXJ0b2tlbi1leGFtcGxlLXZhbHVlLTQzLWNoYXJz"`
+	// MFAToken is returned instead of other tokens when user has two-factor authentication enabled, see /login/mfa.
+	MFAToken string `json:"mfaToken,omitempty" example:"eyJhbGciOiJFZERTQSIsImtpZCI6IjIwMjYtMTAtMTgiLCJ0eXAiOiJKV1QifQ...."`
+}
+
+func NewTokenResponse(t Tokens) TokenResponse {
+	return TokenResponse{Token: t.AccessToken, RefreshToken: t.RefreshToken, MFAToken: t.MFAToken}
+}
+
+type TOTPEnrollmentResponse struct {
+	Secret string `json:"secret" example:"JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"`
+	URI    string `json:"uri" example:"otpauth://totp/walletApi:test11?algorithm=SHA1&digits=6&issuer=walletApi&period=30&secret=JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"`
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code" example:"123456"`
+}
+
+type PasswordChangeRequest struct {
+	CurrentPassword string `json:"currentPassword" example:"haslo"`
+	NewPassword     string `json:"newPassword" example:"Secret123"`
+}
+
+type PasswordResetRequest struct {
+	Login string `json:"login" example:"test11"`
+}
+
+type PasswordResetConfirmRequest struct {
+	// Token was sent to the user after password reset was requested.
+	Token       string `json:"token" example:"Jg0k6XJ2oYw8hVj5oSeXG1mBv8dL2ZqN5cTnF0rU9eA"`
+	NewPassword string `json:"newPassword" example:"Secret123"`
+}
+
+type RecoveryCodesResponse struct {
+	// RecoveryCodes can be used instead of TOTP code, every code only once. They are shown only once.
+	RecoveryCodes []string `json:"recoveryCodes" example:"ABCD-EFGH-IJKL-MNOP"`
+}
+
+// JWK is a public key used to verify JWT tokens, see RFC 7517 and RFC 8037.
+type JWK struct {
+	Kty string `json:"kty" example:"OKP"`
+	Kid string `json:"kid" example:"2026-10-18"`
+	Use string `json:"use" example:"sig"`
+	Alg string `json:"alg" example:"EdDSA"`
+	// N and E are set for RSA keys
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Crv and X are set for Ed25519 keys
+	Crv string `json:"crv,omitempty" example:"Ed25519"`
+	X   string `json:"x,omitempty" example:"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"`
+}
+
+type JWKSResponse struct {
+	Keys []JWK `json:"keys"`
 }
 
 type ErrResponse struct {
@@ -67,18 +636,134 @@ func NewErrResponse(code int, message string) ErrResponse {
 	}
 }
 
+// maxLoginLength and maxNameLength match VARCHAR columns of credentials and user tables,
+// see scripts/migrations/0012_widen_user_names.sql.
+const maxLoginLength = 20
+const maxNameLength = 50
+
+type UserRequest struct {
+	Login     string `json:"login,omitempty" example:"ala11"`
+	Password  string `json:"password,omitempty" example:"1qazXSW@"`
+	FirstName string `json:"firstName,omitempty" example:"Alice"`
+	LastName  string `json:"lastName,omitempty" example:"Cruz"`
+	Age       int    `json:"age,omitempty" example:"25"`
+	// Currency of initial balance, SGD when not set.
+	Currency string `json:"currency,omitempty" example:"SGD"`
+}
+
+func (ur UserRequest) IsValid() (bool, error) {
+	if ur.Login == "" || utf8.RuneCountInString(ur.Login) > maxLoginLength || strings.TrimSpace(ur.Login) != ur.Login {
+		return false, fmt.Errorf("login is required and can have at most %d characters without leading or trailing spaces", maxLoginLength)
+	}
+	if ur.Password == "" {
+		return false, errors.New("password is required")
+	}
+	if ur.FirstName == "" || utf8.RuneCountInString(ur.FirstName) > maxNameLength || ur.LastName == "" || utf8.RuneCountInString(ur.LastName) > maxNameLength {
+		return false, fmt.Errorf("first and last name are required and can have at most %d characters", maxNameLength)
+	}
+	if ur.Age <= 0 {
+		return false, errors.New("age must be greater then 0")
+	}
+	if ur.Currency != "" && !Currency(ur.Currency).IsValid() {
+		return false, ErrUnknownCurrency
+	}
+	return true, nil
+}
+
+type RecipientResponse struct {
+	Receiver string `json:"receiver" example:"zazu18"`
+	// Name is masked display name of the receiver, e.g. "Z*** Z.".
+	Name string `json:"name" example:"Z*** Z."`
+}
+
+type UserResponse struct {
+	ID int `json:"id,omitempty" example:"5"`
+}
+
+type ProfileResponse struct {
+	ID        int    `json:"id" example:"5"`
+	FirstName string `json:"firstName" example:"Alice"`
+	LastName  string `json:"lastName" example:"Cruz"`
+	Age       int    `json:"age" example:"25"`
+	// DisplayName is the name other users see, e.g. in their transactions.
+	DisplayName string `json:"displayName" example:"Alice C."`
+}
+
+func NewProfileResponse(u User) ProfileResponse {
+	return ProfileResponse{ID: u.ID, FirstName: u.FirstName, LastName: u.LastName, Age: u.Age, DisplayName: u.DisplayName()}
+}
+
+// ProfileRequest changes only fields which are set.
+type ProfileRequest struct {
+	FirstName *string `json:"firstName,omitempty" example:"Alice"`
+	LastName  *string `json:"lastName,omitempty" example:"Cruz"`
+	Age       *int    `json:"age,omitempty" example:"26"`
+}
+
+func (pr ProfileRequest) IsValid() (bool, error) {
+	if pr.FirstName == nil && pr.LastName == nil && pr.Age == nil {
+		return false, errors.New("at least one of firstName, lastName and age is required")
+	}
+	for _, name := range []*string{pr.FirstName, pr.LastName} {
+		if name != nil && (*name == "" || utf8.RuneCountInString(*name) > maxNameLength) {
+			return false, fmt.Errorf("first and last name cannot be empty and can have at most %d characters", maxNameLength)
+		}
+	}
+	if pr.Age != nil && *pr.Age <= 0 {
+		return false, errors.New("age must be greater then 0")
+	}
+	return true, nil
+}
+
+func (pr ProfileRequest) ToPatch() UserPatch {
+	return UserPatch{FirstName: pr.FirstName, LastName: pr.LastName, Age: pr.Age}
+}
+
+type BalanceRequest struct {
+	Currency string `json:"currency,omitempty" example:"USD"`
+}
+
+func (br BalanceRequest) IsValid() (bool, error) {
+	if !Currency(br.Currency).IsValid() {
+		return false, ErrUnknownCurrency
+	}
+	return true, nil
+}
+
 type BalanceResponse struct {
-	ID       int     `json:"id,omitempty" example:"1"`
-	Currency string  `json:"currency,omitempty" example:"SGD"`
-	Balance  float64 `json:"balance" example:"10000.00"`
+	ID       int    `json:"id,omitempty" example:"1"`
+	Currency string `json:"currency,omitempty" example:"SGD"`
+	Balance  Amount `json:"balance" swaggertype:"number" example:"10000.00"`
+	// Held is reserved by active holds, Available is the rest of the balance which can be transferred.
+	Held      Amount `json:"held" swaggertype:"number" example:"20.50"`
+	Available Amount `json:"available" swaggertype:"number" example:"9979.50"`
+	// OwnerName is display name of the balance owner, set only when requested.
+	OwnerName string `json:"ownerName,omitempty" example:"Alice C."`
 }
 
 func NewBalanceResponse(b Balance) BalanceResponse {
 	return BalanceResponse{
-		ID:       b.ID,
-		Currency: string(b.Currency),
-		Balance:  b.Balance,
+		ID:        b.ID,
+		Currency:  string(b.Currency),
+		Balance:   b.Balance,
+		Held:      b.Held,
+		Available: b.Available(),
+	}
+}
+
+// BalanceDetailsResponse describes single balance of the user.
+type BalanceDetailsResponse struct {
+	BalanceResponse
+	// LastTransactionAt is not set when there was no transaction from or to the balance.
+	LastTransactionAt *time.Time `json:"lastTransactionAt,omitempty"`
+}
+
+func NewBalanceDetailsResponse(b BalanceDetails) BalanceDetailsResponse {
+	resp := BalanceDetailsResponse{BalanceResponse: NewBalanceResponse(b.Balance)}
+	if !b.LastTransactionAt.IsZero() {
+		resp.LastTransactionAt = &b.LastTransactionAt
 	}
+	return resp
 }
 
 func NewBalanceResponses(bs []Balance) []BalanceResponse {
@@ -88,3 +773,67 @@ func NewBalanceResponses(bs []Balance) []BalanceResponse {
 	}
 	return balances
 }
+
+// maxAPIKeyNameLength matches VARCHAR column of api_key table.
+const maxAPIKeyNameLength = 64
+
+type APIKeyRequest struct {
+	Name   string   `json:"name" example:"batch payouts"`
+	Scopes []string `json:"scopes" example:"transactions:write"`
+	// AllowedIPs are IP addresses or networks in CIDR notation the key can be used from, any IP when empty.
+	AllowedIPs []string `json:"allowedIps,omitempty" example:"10.0.0.0/24"`
+	// ExpiresAt is in 90 days when not set, at most in a year.
+	ExpiresAt *time.Time `json:"expiresAt,omitempty" example:"2027-01-01T00:00:00Z"`
+}
+
+func (r APIKeyRequest) IsValid() (bool, error) {
+	if r.Name == "" || utf8.RuneCountInString(r.Name) > maxAPIKeyNameLength {
+		return false, fmt.Errorf("name is required and can have at most %d characters", maxAPIKeyNameLength)
+	}
+	return true, nil
+}
+
+func (r APIKeyRequest) GetScopes() []Scope {
+	scopes := make([]Scope, len(r.Scopes))
+	for i, s := range r.Scopes {
+		scopes[i] = Scope(s)
+	}
+	return scopes
+}
+
+type APIKeyResponse struct {
+	ID int `json:"id" example:"3"`
+	// Prefix is the beginning of the key, so the key can be recognized.
+	Prefix     string     `json:"prefix" example:"wak_Jg0k6X"`
+	Name       string     `json:"name" example:"batch payouts"`
+	Scopes     []string   `json:"scopes" example:"transactions:write"`
+	AllowedIPs []string   `json:"allowedIps,omitempty" example:"10.0.0.0/24"`
+	ExpiresAt  time.Time  `json:"expiresAt" example:"2027-01-01T00:00:00Z"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt" example:"2026-10-18T12:00:00Z"`
+	// Key is returned only when the key is created, it cannot be retrieved later.
+	Key string `json:"key,omitempty" example:"wak_Jg0k6XJ2oYw8hVj5oSeXG1mBv8dL2ZqN5cTnF0rU9eA"`
+}
+
+func NewAPIKeyResponse(k APIKey) APIKeyResponse {
+	resp := APIKeyResponse{ID: k.ID, Prefix: k.Prefix, Name: k.Name, ExpiresAt: k.ExpiresAt, CreatedAt: k.CreatedAt, Scopes: make([]string, len(k.Scopes))}
+	for i, s := range k.Scopes {
+		resp.Scopes[i] = string(s)
+	}
+	for _, network := range k.AllowedIPs {
+		resp.AllowedIPs = append(resp.AllowedIPs, network.String())
+	}
+	if !k.RevokedAt.IsZero() {
+		revokedAt := k.RevokedAt
+		resp.RevokedAt = &revokedAt
+	}
+	return resp
+}
+
+func NewAPIKeyResponses(ks []APIKey) []APIKeyResponse {
+	ret := make([]APIKeyResponse, len(ks))
+	for i, k := range ks {
+		ret[i] = NewAPIKeyResponse(k)
+	}
+	return ret
+}
@@ -1,6 +1,10 @@
 package model
 
-import "testing"
+import (
+	"strings"
+	"testing"
+	"time"
+)
 
 type testCase struct {
 	transactionRequest TransactionRequest
@@ -13,38 +17,147 @@ func TestIsValid(t *testing.T) {
 		{transactionRequest: TransactionRequest{
 			SenderBalanceID:   1,
 			ReceiverBalanceID: 2,
-			Amount:            10.99,
+			Amount:            MustParseAmount("10.99"),
 		},
 			isValid: true,
 			errMsg:  ""},
 		{transactionRequest: TransactionRequest{
 			SenderBalanceID:   1,
 			ReceiverBalanceID: 2,
-			Amount:            0.009,
+			Amount:            0,
 		},
 			isValid: false,
-			errMsg:  "amount (rounded down to 2 decimal places) field must be greater then 0"},
+			errMsg:  "amount field must be greater then 0"},
 		{transactionRequest: TransactionRequest{
 			SenderBalanceID:   1,
 			ReceiverBalanceID: 2,
-			Amount:            -10.99,
+			Amount:            MustParseAmount("-10.99"),
 		},
 			isValid: false,
-			errMsg:  "amount (rounded down to 2 decimal places) field must be greater then 0"},
+			errMsg:  "amount field must be greater then 0"},
 		{transactionRequest: TransactionRequest{
 			SenderBalanceID:   0,
 			ReceiverBalanceID: -3,
-			Amount:            0.99,
+			Amount:            MustParseAmount("0.99"),
 		},
 			isValid: false,
 			errMsg:  "sender or receiver balance not found"},
+		{transactionRequest: TransactionRequest{
+			SenderBalanceID: 1,
+			Receiver:        "zazu18",
+			Amount:          MustParseAmount("0.99"),
+		},
+			isValid: true,
+			errMsg:  ""},
+		{transactionRequest: TransactionRequest{
+			SenderBalanceID:   1,
+			ReceiverBalanceID: 2,
+			Receiver:          "zazu18",
+			Amount:            MustParseAmount("0.99"),
+		},
+			isValid: false,
+			errMsg:  "only one of receiverBalanceId and receiver can be set"},
+		{transactionRequest: TransactionRequest{
+			Receiver: "zazu18",
+			Amount:   MustParseAmount("0.99"),
+		},
+			isValid: false,
+			errMsg:  "sender balance not found"},
 		{transactionRequest: TransactionRequest{
 			SenderBalanceID:   3,
 			ReceiverBalanceID: 3,
-			Amount:            0.99,
+			Amount:            MustParseAmount("0.99"),
 		},
 			isValid: false,
 			errMsg:  "sender and receiver balances cannot be the same"},
+		{transactionRequest: TransactionRequest{
+			SenderBalanceID:   1,
+			ReceiverBalanceID: 2,
+			Amount:            MustParseAmount("0.99"),
+			Currency:          "USD",
+		},
+			isValid: true,
+			errMsg:  ""},
+		{transactionRequest: TransactionRequest{
+			SenderBalanceID:   1,
+			ReceiverBalanceID: 2,
+			Amount:            MustParseAmount("0.99"),
+			Currency:          "ABC",
+		},
+			isValid: false,
+			errMsg:  ErrUnknownCurrency.Error()},
+		{transactionRequest: TransactionRequest{
+			SenderBalanceID:   1,
+			ReceiverBalanceID: 2,
+			Amount:            MustParseAmount("0.99"),
+			Memo:              "Dinner on Friday",
+			Reference:         "INV-2026-10",
+			Metadata:          map[string]string{"orderId": "42", "shop.name": "Żabka"},
+		},
+			isValid: true,
+			errMsg:  ""},
+		{transactionRequest: TransactionRequest{
+			SenderBalanceID:   1,
+			ReceiverBalanceID: 2,
+			Amount:            MustParseAmount("0.99"),
+			Memo:              "Dinner\non Friday",
+		},
+			isValid: false,
+			errMsg:  "memo can have at most 140 characters and cannot contain control characters"},
+		{transactionRequest: TransactionRequest{
+			SenderBalanceID:   1,
+			ReceiverBalanceID: 2,
+			Amount:            MustParseAmount("0.99"),
+			Memo:              strings.Repeat("ż", 141),
+		},
+			isValid: false,
+			errMsg:  "memo can have at most 140 characters and cannot contain control characters"},
+		{transactionRequest: TransactionRequest{
+			SenderBalanceID:   1,
+			ReceiverBalanceID: 2,
+			Amount:            MustParseAmount("0.99"),
+			Reference:         strings.Repeat("a", 65),
+		},
+			isValid: false},
+		{transactionRequest: TransactionRequest{
+			SenderBalanceID:   1,
+			ReceiverBalanceID: 2,
+			Amount:            MustParseAmount("0.99"),
+			Metadata:          map[string]string{"order id": "42"},
+		},
+			isValid: false,
+			errMsg:  "metadata key can have from 1 to 40 letters, digits, '_', '.' or '-'"},
+		{transactionRequest: TransactionRequest{
+			SenderBalanceID:   1,
+			ReceiverBalanceID: 2,
+			Amount:            MustParseAmount("0.99"),
+			Metadata:          map[string]string{"orderId": "42\x00"},
+		},
+			isValid: false,
+			errMsg:  "metadata value can have at most 200 characters and cannot contain control characters"},
+		{transactionRequest: TransactionRequest{
+			SenderBalanceID:   1,
+			ReceiverBalanceID: 2,
+			Amount:            MustParseAmount("0.99"),
+			Metadata: map[string]string{"a": "1", "b": "2", "c": "3", "d": "4", "e": "5", "f": "6",
+				"g": "7", "h": "8", "i": "9", "j": "10", "k": "11"},
+		},
+			isValid: false,
+			errMsg:  "metadata can have at most 10 keys"},
+		{transactionRequest: TransactionRequest{
+			SenderBalanceID:   1,
+			ReceiverBalanceID: 2,
+			Amount:            MaxAmount,
+		},
+			isValid: true,
+			errMsg:  ""},
+		{transactionRequest: TransactionRequest{
+			SenderBalanceID:   1,
+			ReceiverBalanceID: 2,
+			Amount:            MaxAmount + 1,
+		},
+			isValid: false,
+			errMsg:  "amount cannot be greater than 9999999999.99"},
 	}
 	for _, testCase := range cases {
 		ok, err := testCase.transactionRequest.IsValid()
@@ -56,3 +169,169 @@ func TestIsValid(t *testing.T) {
 		}
 	}
 }
+
+func TestUserRequestIsValid(t *testing.T) {
+	valid := UserRequest{Login: "ala11", Password: "1qazXSW@", FirstName: "Alice", LastName: "Cruz", Age: 25}
+	cases := []struct {
+		modify  func(ur *UserRequest)
+		isValid bool
+	}{
+		{modify: func(ur *UserRequest) {}, isValid: true},
+		{modify: func(ur *UserRequest) { ur.Currency = "USD" }, isValid: true},
+		{modify: func(ur *UserRequest) { ur.Login = "aaaaaaaaaabbbbbbbbbb" }, isValid: true},
+		{modify: func(ur *UserRequest) { ur.Login = "żółwżółwżółwżółwżółw" }, isValid: true},
+		{modify: func(ur *UserRequest) { ur.Login = "aaaaaaaaaabbbbbbbbbbc" }, isValid: false},
+		{modify: func(ur *UserRequest) { ur.Login = "" }, isValid: false},
+		{modify: func(ur *UserRequest) { ur.Login = " ala11" }, isValid: false},
+		{modify: func(ur *UserRequest) { ur.Password = "" }, isValid: false},
+		{modify: func(ur *UserRequest) { ur.FirstName = "" }, isValid: false},
+		{modify: func(ur *UserRequest) { ur.LastName = "Abcdefghijk" }, isValid: true},
+		{modify: func(ur *UserRequest) { ur.LastName = strings.Repeat("ż", 50) }, isValid: true},
+		{modify: func(ur *UserRequest) { ur.LastName = strings.Repeat("a", 51) }, isValid: false},
+		{modify: func(ur *UserRequest) { ur.Age = 0 }, isValid: false},
+		{modify: func(ur *UserRequest) { ur.Currency = "ABC" }, isValid: false},
+	}
+	for _, testCase := range cases {
+		ur := valid
+		testCase.modify(&ur)
+		ok, err := ur.IsValid()
+		if ok != testCase.isValid {
+			t.Errorf("UserRequest.IsValid() for %+v got: %t (%v); want: %t", ur, ok, err, testCase.isValid)
+		}
+		if !ok && err == nil {
+			t.Errorf("UserRequest.IsValid() for %+v must return error", ur)
+		}
+	}
+}
+
+func TestProfileRequestIsValid(t *testing.T) {
+	empty, name, long, age, noAge := "", "Alicja", strings.Repeat("a", 51), 26, 0
+	cases := []struct {
+		pr      ProfileRequest
+		isValid bool
+	}{
+		{pr: ProfileRequest{FirstName: &name}, isValid: true},
+		{pr: ProfileRequest{LastName: &name, Age: &age}, isValid: true},
+		{pr: ProfileRequest{}, isValid: false},
+		{pr: ProfileRequest{FirstName: &empty}, isValid: false},
+		{pr: ProfileRequest{LastName: &long}, isValid: false},
+		{pr: ProfileRequest{Age: &noAge}, isValid: false},
+	}
+	for _, testCase := range cases {
+		ok, err := testCase.pr.IsValid()
+		if ok != testCase.isValid || (!ok && err == nil) {
+			t.Errorf("ProfileRequest.IsValid() for %+v got: %t (%v); want: %t", testCase.pr, ok, err, testCase.isValid)
+		}
+	}
+}
+
+func TestRefundRequestIsValid(t *testing.T) {
+	cases := []struct {
+		rr      RefundRequest
+		isValid bool
+	}{
+		{rr: RefundRequest{}, isValid: true},
+		{rr: RefundRequest{Amount: MustParseAmount("5.25"), Memo: "Returned item", Reference: "RMA-7"}, isValid: true},
+		{rr: RefundRequest{Amount: MustParseAmount("-1")}, isValid: false},
+		{rr: RefundRequest{Amount: MaxAmount}, isValid: true},
+		{rr: RefundRequest{Amount: MaxAmount + 1}, isValid: false},
+		{rr: RefundRequest{Memo: "Returned\titem"}, isValid: false},
+		{rr: RefundRequest{Reference: strings.Repeat("a", 65)}, isValid: false},
+	}
+	for _, testCase := range cases {
+		ok, err := testCase.rr.IsValid()
+		if ok != testCase.isValid || (!ok && err == nil) {
+			t.Errorf("RefundRequest.IsValid() for %+v got: %t (%v); want: %t", testCase.rr, ok, err, testCase.isValid)
+		}
+	}
+}
+
+func TestHoldRequestIsValid(t *testing.T) {
+	cases := []struct {
+		hr      HoldRequest
+		isValid bool
+	}{
+		{hr: HoldRequest{SenderBalanceID: 1, ReceiverBalanceID: 2, Amount: MustParseAmount("20.50"), Memo: "Order 42"}, isValid: true},
+		{hr: HoldRequest{SenderBalanceID: 1, ReceiverBalanceID: 1, Amount: MustParseAmount("20.50")}, isValid: false},
+		{hr: HoldRequest{SenderBalanceID: 1, Amount: MustParseAmount("20.50")}, isValid: false},
+		{hr: HoldRequest{SenderBalanceID: 1, ReceiverBalanceID: 2}, isValid: false},
+		{hr: HoldRequest{SenderBalanceID: 1, ReceiverBalanceID: 2, Amount: MaxAmount}, isValid: true},
+		{hr: HoldRequest{SenderBalanceID: 1, ReceiverBalanceID: 2, Amount: MaxAmount + 1}, isValid: false},
+		{hr: HoldRequest{SenderBalanceID: 1, ReceiverBalanceID: 2, Amount: MustParseAmount("1"), Reference: strings.Repeat("a", 65)}, isValid: false},
+	}
+	for _, testCase := range cases {
+		ok, err := testCase.hr.IsValid()
+		if ok != testCase.isValid || (!ok && err == nil) {
+			t.Errorf("HoldRequest.IsValid() for %+v got: %t (%v); want: %t", testCase.hr, ok, err, testCase.isValid)
+		}
+	}
+
+	if ok, err := (CaptureRequest{}).IsValid(); !ok {
+		t.Errorf("CaptureRequest.IsValid() without amount got: %t (%v); want: true", ok, err)
+	}
+	if ok, _ := (CaptureRequest{Amount: MustParseAmount("-1")}).IsValid(); ok {
+		t.Errorf("CaptureRequest.IsValid() with negative amount got: %t; want: false", ok)
+	}
+	if ok, err := (CaptureRequest{Amount: MaxAmount}).IsValid(); !ok {
+		t.Errorf("CaptureRequest.IsValid() with max amount got: %t (%v); want: true", ok, err)
+	}
+	if ok, _ := (CaptureRequest{Amount: MaxAmount + 1}).IsValid(); ok {
+		t.Errorf("CaptureRequest.IsValid() with amount above max got: %t; want: false", ok)
+	}
+}
+
+func TestScheduleRequestIsValid(t *testing.T) {
+	transfer := TransactionRequest{SenderBalanceID: 1, ReceiverBalanceID: 2, Amount: MustParseAmount("1200"), Memo: "Rent"}
+	startAt := time.Date(2026, 11, 1, 9, 0, 0, 0, time.UTC)
+	cases := []struct {
+		sr      ScheduleRequest
+		isValid bool
+	}{
+		{sr: ScheduleRequest{TransactionRequest: transfer, StartAt: startAt}, isValid: true},
+		{sr: ScheduleRequest{TransactionRequest: transfer, StartAt: startAt, Recurrence: RecurrenceMonthly, DayOfMonth: 31}, isValid: true},
+		{sr: ScheduleRequest{TransactionRequest: transfer, StartAt: startAt, Recurrence: RecurrenceWeekly}, isValid: true},
+		{sr: ScheduleRequest{TransactionRequest: transfer}, isValid: false},
+		{sr: ScheduleRequest{TransactionRequest: TransactionRequest{SenderBalanceID: 1, ReceiverBalanceID: 2}, StartAt: startAt}, isValid: false},
+		{sr: ScheduleRequest{TransactionRequest: TransactionRequest{SenderBalanceID: 1, ReceiverBalanceID: 2, Amount: MaxAmount + 1}, StartAt: startAt}, isValid: false},
+		{sr: ScheduleRequest{TransactionRequest: transfer, StartAt: startAt, Recurrence: "yearly"}, isValid: false},
+		{sr: ScheduleRequest{TransactionRequest: transfer, StartAt: startAt, Recurrence: RecurrenceMonthly, DayOfMonth: 32}, isValid: false},
+		{sr: ScheduleRequest{TransactionRequest: transfer, StartAt: startAt, Recurrence: RecurrenceDaily, DayOfMonth: 1}, isValid: false},
+	}
+	for _, testCase := range cases {
+		ok, err := testCase.sr.IsValid()
+		if ok != testCase.isValid || (!ok && err == nil) {
+			t.Errorf("ScheduleRequest.IsValid() for %+v got: %t (%v); want: %t", testCase.sr, ok, err, testCase.isValid)
+		}
+	}
+}
+
+func TestBatchRequestIsValid(t *testing.T) {
+	item := BatchItemRequest{ReceiverBalanceID: 2, Amount: MustParseAmount("3200"), Memo: "Salary 10/2026"}
+	tooMany := make([]BatchItemRequest, MaxBatchItems+1)
+	for i := range tooMany {
+		tooMany[i] = item
+	}
+	cases := []struct {
+		br      BatchRequest
+		isValid bool
+	}{
+		{br: BatchRequest{SenderBalanceID: 1, Mode: BatchAllOrNothing, Items: []BatchItemRequest{item, item}}, isValid: true},
+		{br: BatchRequest{SenderBalanceID: 1, Mode: BatchBestEffort, Currency: "SGD", Items: []BatchItemRequest{item}}, isValid: true},
+		{br: BatchRequest{SenderBalanceID: 1, Items: []BatchItemRequest{item}}, isValid: false},
+		{br: BatchRequest{Mode: BatchBestEffort, Items: []BatchItemRequest{item}}, isValid: false},
+		{br: BatchRequest{SenderBalanceID: 1, Mode: BatchBestEffort}, isValid: false},
+		{br: BatchRequest{SenderBalanceID: 1, Mode: BatchBestEffort, Items: tooMany}, isValid: false},
+		{br: BatchRequest{SenderBalanceID: 2, Mode: BatchBestEffort, Items: []BatchItemRequest{item}}, isValid: false},
+		{br: BatchRequest{SenderBalanceID: 1, Mode: BatchBestEffort, Items: []BatchItemRequest{item, {ReceiverBalanceID: 3}}}, isValid: false},
+		{br: BatchRequest{SenderBalanceID: 1, Mode: BatchBestEffort, Items: []BatchItemRequest{{ReceiverBalanceID: 3, Amount: 1, Memo: strings.Repeat("a", 141)}}},
+			isValid: false},
+		{br: BatchRequest{SenderBalanceID: 1, Mode: BatchBestEffort, Items: []BatchItemRequest{{ReceiverBalanceID: 3, Amount: MaxAmount}}}, isValid: true},
+		{br: BatchRequest{SenderBalanceID: 1, Mode: BatchBestEffort, Items: []BatchItemRequest{{ReceiverBalanceID: 3, Amount: MaxAmount + 1}}}, isValid: false},
+	}
+	for _, testCase := range cases {
+		ok, err := testCase.br.IsValid()
+		if ok != testCase.isValid || (!ok && err == nil) {
+			t.Errorf("BatchRequest.IsValid() for %+v got: %t (%v); want: %t", testCase.br, ok, err, testCase.isValid)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package model
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgtype"
+)
+
+var ErrInvalidRate = errors.New("exchange rate must be a positive decimal number with at most 6 decimal places")
+
+// rateScale is the number of decimal places kept by Rate. It matches NUMERIC(18, 6) column in the database.
+const rateScale = 6
+
+// Rate is an exact exchange rate stored as a whole number of millionths, e.g. 1.352 is 1352000.
+type Rate int64
+
+// ParseRate parses decimal string such as "1.3521". Rate must be positive and have at most 6 decimal places.
+func ParseRate(s string) (Rate, error) {
+	units, err := parseDecimal(s, rateScale)
+	if err != nil || units <= 0 {
+		return 0, ErrInvalidRate
+	}
+	return Rate(units), nil
+}
+
+// MustParseRate is like ParseRate but panics if the string cannot be parsed. It simplifies initialization of fixed rates.
+func MustParseRate(s string) Rate {
+	r, err := ParseRate(s)
+	if err != nil {
+		panic(fmt.Sprintf("model: ParseRate(%q): %v", s, err))
+	}
+	return r
+}
+
+// String returns rate with exactly 6 decimal places, e.g. "1.352000".
+func (r Rate) String() string {
+	return formatDecimal(int64(r), rateScale)
+}
+
+// Convert multiplies amount by the rate. Result is rounded half away from zero to minor units of the target currency.
+func (r Rate) Convert(a Amount, to Currency) Amount {
+	return scale(a, int64(r), 1_000_000, to)
+}
+
+// scale multiplies amount by num/den. Result is rounded half away from zero to minor units of the target currency.
+func scale(a Amount, num, den int64, to Currency) Amount {
+	step := int64(1)
+	for i := to.MinorUnits(); i < amountScale; i++ {
+		step *= 10
+	}
+	n := new(big.Int).Mul(big.NewInt(int64(a)), big.NewInt(num))
+	d := new(big.Int).Mul(big.NewInt(den), big.NewInt(step))
+
+	q, m := new(big.Int).QuoRem(n, d, new(big.Int))
+	// round half away from zero
+	if new(big.Int).Mul(new(big.Int).Abs(m), big.NewInt(2)).Cmp(d) >= 0 {
+		if n.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return Amount(q.Int64() * step)
+}
+
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalJSON accepts both JSON number (1.35) and JSON string ("1.35").
+func (r *Rate) UnmarshalJSON(b []byte) error {
+	s := string(b)
+	if s == "null" {
+		return nil
+	}
+	parsed, err := ParseRate(strings.Trim(s, `"`))
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+// Scan implements sql.Scanner so NUMERIC columns can be read directly into Rate. NULL is scanned as 0 (no conversion).
+func (r *Rate) Scan(src interface{}) error {
+	var err error
+	switch v := src.(type) {
+	case nil:
+		*r = 0
+	case string:
+		*r, err = ParseRate(v)
+	case []byte:
+		*r, err = ParseRate(string(v))
+	case float64:
+		*r, err = ParseRate(strconv.FormatFloat(v, 'f', -1, 64))
+	default:
+		err = fmt.Errorf("cannot scan %T into Rate", src)
+	}
+	return err
+}
+
+// Value implements driver.Valuer. Zero rate is stored as NULL.
+func (r Rate) Value() (driver.Value, error) {
+	if r == 0 {
+		return nil, nil
+	}
+	return r.String(), nil
+}
+
+// EncodeText implements pgtype.TextEncoder. Zero rate is stored as NULL.
+func (r Rate) EncodeText(ci *pgtype.ConnInfo, buf []byte) ([]byte, error) {
+	if r == 0 {
+		return nil, nil
+	}
+	return append(buf, r.String()...), nil
+}
+
+// FXRate is the price of one unit of From currency expressed in To currency, valid at Date.
+type FXRate struct {
+	From Currency
+	To   Currency
+	Rate Rate
+	Date time.Time
+}
@@ -0,0 +1,80 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRate(t *testing.T) {
+	cases := []struct {
+		in          string
+		want        Rate
+		expectedErr error
+	}{
+		{in: "1", want: 1000000},
+		{in: "1.3654", want: 1365400},
+		{in: "0.000001", want: 1},
+		{in: "0.0000001", expectedErr: ErrInvalidRate},
+		{in: "0", expectedErr: ErrInvalidRate},
+		{in: "-1.2", expectedErr: ErrInvalidRate},
+		{in: "abc", expectedErr: ErrInvalidRate},
+	}
+	for _, testCase := range cases {
+		got, err := ParseRate(testCase.in)
+		if err != testCase.expectedErr {
+			t.Errorf("ParseRate(%q) error got: %v; want: %v", testCase.in, err, testCase.expectedErr)
+		}
+		if got != testCase.want {
+			t.Errorf("ParseRate(%q) got: %d; want: %d", testCase.in, got, testCase.want)
+		}
+	}
+}
+
+func TestRateConvert(t *testing.T) {
+	cases := []struct {
+		rate   Rate
+		amount Amount
+		to     Currency
+		want   Amount
+	}{
+		{rate: MustParseRate("1.3654"), amount: MustParseAmount("100"), to: SGD, want: MustParseAmount("136.54")},
+		// 10.00 * 0.7324 = 7.324 -> 7.32
+		{rate: MustParseRate("0.7324"), amount: MustParseAmount("10"), to: USD, want: MustParseAmount("7.32")},
+		// 0.05 * 1.5 = 0.075 -> 0.08 (half away from zero)
+		{rate: MustParseRate("1.5"), amount: MustParseAmount("0.05"), to: EUR, want: MustParseAmount("0.08")},
+		// JPY has no minor units, 10.00 * 113.456 = 1134.56 -> 1135
+		{rate: MustParseRate("113.456"), amount: MustParseAmount("10"), to: JPY, want: MustParseAmount("1135")},
+		// large values must not overflow, 10^10 * 25000
+		{rate: MustParseRate("25000"), amount: MustParseAmount("10000000000"), to: VND, want: MustParseAmount("250000000000000")},
+	}
+	for _, testCase := range cases {
+		if got := testCase.rate.Convert(testCase.amount, testCase.to); got != testCase.want {
+			t.Errorf("Rate(%s).Convert(%s, %s) got: %s; want: %s", testCase.rate, testCase.amount, testCase.to, got, testCase.want)
+		}
+	}
+}
+
+func TestTransactionFullApplyRate(t *testing.T) {
+	b1 := Balance{Balance: MustParseAmount("100.00"), Currency: USD}
+	b2 := Balance{Balance: MustParseAmount("0.00"), Currency: SGD}
+	rateDate := time.Now()
+
+	transaction := TransactionFull{
+		SenderBalance:   &b1,
+		ReceiverBalance: &b2,
+		Amount:          MustParseAmount("10.00"),
+		Currency:        USD,
+	}
+	transaction.ApplyRate(FXRate{From: USD, To: SGD, Rate: MustParseRate("1.3654"), Date: rateDate})
+	transaction.Make()
+
+	if transaction.SenderBalance.Balance != MustParseAmount("90.00") {
+		t.Errorf("transaction.Make(); SenderBalance.Balance = %s; want 90.00", transaction.SenderBalance.Balance)
+	}
+	if transaction.ReceiverBalance.Balance != MustParseAmount("13.65") {
+		t.Errorf("transaction.Make(); ReceiverBalance.Balance = %s; want 13.65", transaction.ReceiverBalance.Balance)
+	}
+	if transaction.ReceiverCurrency != SGD || transaction.Rate != MustParseRate("1.3654") || !transaction.RateDate.Equal(rateDate) {
+		t.Errorf("transaction.ApplyRate(); got: %s at %s (%s); want: 1.365400 at %s (SGD)", transaction.Rate, transaction.RateDate, transaction.ReceiverCurrency, rateDate)
+	}
+}
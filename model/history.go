@@ -0,0 +1,113 @@
+package model
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor, use next cursor from the previous page")
+
+// DefaultTransactionsLimit and MaxTransactionsLimit bound number of transactions on one page of history.
+const DefaultTransactionsLimit = 50
+const MaxTransactionsLimit = 100
+
+// Direction of transaction from the point of view of the user whose history is read.
+type Direction string
+
+const (
+	DirectionSent     Direction = "sent"
+	DirectionReceived Direction = "received"
+)
+
+func (d Direction) IsValid() bool {
+	return d == "" || d == DirectionSent || d == DirectionReceived
+}
+
+// TransactionCursor points at the last transaction of a page, history is ordered by date and ID, the newest first.
+type TransactionCursor struct {
+	Date time.Time
+	ID   int
+}
+
+// String encodes the cursor to an opaque value, which is sent to clients.
+func (c TransactionCursor) String() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(c.Date.UnixNano(), 10) + "." + strconv.Itoa(c.ID)))
+}
+
+func ParseTransactionCursor(s string) (TransactionCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return TransactionCursor{}, ErrInvalidCursor
+	}
+	parts := strings.Split(string(b), ".")
+	if len(parts) != 2 {
+		return TransactionCursor{}, ErrInvalidCursor
+	}
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return TransactionCursor{}, ErrInvalidCursor
+	}
+	id, err := strconv.Atoi(parts[1])
+	if err != nil || id <= 0 {
+		return TransactionCursor{}, ErrInvalidCursor
+	}
+	return TransactionCursor{Date: time.Unix(0, nanos).UTC(), ID: id}, nil
+}
+
+// TransactionFilter selects transactions of the user's history, zero fields do not filter.
+type TransactionFilter struct {
+	// After continues history after the cursor, nil for the first page.
+	After *TransactionCursor
+	Limit int
+	// From is inclusive and To is exclusive.
+	From      time.Time
+	To        time.Time
+	MinAmount Amount
+	MaxAmount Amount
+	Direction Direction
+	// CounterpartyBalanceID is the other balance of the transaction.
+	CounterpartyBalanceID int
+	// Currency is currency of the sent amount.
+	Currency Currency
+	// Reference must be equal to the transaction reference and Memo must be a part of the transaction memo, case-insensitive.
+	Reference string
+	Memo      string
+	// MetadataKey with MetadataValue must be in the transaction metadata.
+	MetadataKey   string
+	MetadataValue string
+}
+
+func (f TransactionFilter) IsValid() (bool, error) {
+	if f.Limit < 0 || f.Limit > MaxTransactionsLimit {
+		return false, fmt.Errorf("limit must be between 1 and %d", MaxTransactionsLimit)
+	}
+	if !f.From.IsZero() && !f.To.IsZero() && !f.From.Before(f.To) {
+		return false, errors.New("from must be before to")
+	}
+	if f.MinAmount < 0 || f.MaxAmount < 0 || (f.MaxAmount != 0 && f.MinAmount > f.MaxAmount) {
+		return false, errors.New("minAmount and maxAmount cannot be negative and minAmount cannot be greater than maxAmount")
+	}
+	if f.MinAmount > MaxAmount || f.MaxAmount > MaxAmount {
+		return false, fmt.Errorf("minAmount and maxAmount cannot be greater than %s", MaxAmount)
+	}
+	if !f.Direction.IsValid() {
+		return false, errors.New("direction must be sent or received")
+	}
+	if f.CounterpartyBalanceID < 0 {
+		return false, errors.New("counterpartyBalanceId must be a positive number")
+	}
+	if f.Currency != "" && !f.Currency.IsValid() {
+		return false, ErrUnknownCurrency
+	}
+	return true, nil
+}
+
+// TransactionPage is one page of history, Next is nil on the last page.
+type TransactionPage struct {
+	Transactions []Transaction
+	Next         *TransactionCursor
+}
@@ -0,0 +1,60 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTransactionCursor(t *testing.T) {
+	cursor := TransactionCursor{Date: time.Date(2026, 10, 18, 12, 30, 0, 123456000, time.UTC), ID: 42}
+	got, err := ParseTransactionCursor(cursor.String())
+	if err != nil || got != cursor {
+		t.Errorf("cursor got: %+v (%v); want: %+v", got, err, cursor)
+	}
+
+	for _, s := range []string{"", "abc", "MTIzNDU", cursor.String() + "x"} {
+		if _, err = ParseTransactionCursor(s); err != ErrInvalidCursor {
+			t.Errorf("error for cursor %q got: %v; want: %v", s, err, ErrInvalidCursor)
+		}
+	}
+}
+
+func TestTransactionFilterRequestToFilter(t *testing.T) {
+	cursor := TransactionCursor{Date: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC), ID: 7}
+	f, err := TransactionFilterRequest{
+		Cursor:    cursor.String(),
+		From:      "2026-10-01T02:00:00+02:00",
+		MinAmount: "10.5",
+		Direction: "received",
+		Currency:  "USD",
+		Metadata:  "orderId:42:1",
+	}.ToFilter()
+	if err != nil {
+		t.Fatalf("error was not expected while parsing filter: %s", err)
+	}
+	if f.Limit != DefaultTransactionsLimit || f.After == nil || *f.After != cursor || !f.From.Equal(time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)) ||
+		f.MinAmount != MustParseAmount("10.50") || f.Direction != DirectionReceived || f.Currency != USD ||
+		f.MetadataKey != "orderId" || f.MetadataValue != "42:1" {
+		t.Errorf("filter got: %+v", f)
+	}
+
+	for _, r := range []TransactionFilterRequest{
+		{Limit: MaxTransactionsLimit + 1},
+		{Limit: -1},
+		{Cursor: "abc"},
+		{From: "2026-10-01"},
+		{From: "2026-10-02T00:00:00Z", To: "2026-10-01T00:00:00Z"},
+		{MinAmount: "20", MaxAmount: "10"},
+		{MaxAmount: "1.001"},
+		{MaxAmount: "10000000000"},
+		{Direction: "both"},
+		{CounterpartyBalanceID: -1},
+		{Currency: "ABC"},
+		{Metadata: "orderId"},
+		{Metadata: ":42"},
+	} {
+		if _, err = r.ToFilter(); err == nil {
+			t.Errorf("filter request %+v must be invalid", r)
+		}
+	}
+}
@@ -1,39 +1,48 @@
 package model
 
-import "time"
-
-type Currency string
-
-const (
-	SGD Currency = "SGD"
+import (
+	"net"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 type Balance struct {
 	ID       int
 	Currency Currency
-	Balance  float64
-	Locked   bool
-	UserID   int
+	Balance  Amount
+	// Held is reserved by active holds of the balance, it cannot be transferred - see Balance.Available.
+	Held   Amount
+	UserID int
+}
+
+// BalanceDetails is balance with date of its last transaction, zero when there was none.
+type BalanceDetails struct {
+	Balance
+	LastTransactionAt time.Time
 }
 
-func (b *Balance) IsLocked() bool {
-	return b.Locked
+func (b *Balance) Increase(amount Amount) {
+	b.Balance += amount
 }
 
-func (b *Balance) Lock() {
-	b.Locked = true
+func (b *Balance) Decrease(amount Amount) {
+	b.Balance -= amount
 }
 
-func (b *Balance) Unlock() {
-	b.Locked = false
+// Available is the part of the balance which is not held.
+func (b *Balance) Available() Amount {
+	return b.Balance - b.Held
 }
 
-func (b *Balance) Increase(amount float64) {
-	b.Balance += amount
+// Hold reserves amount of the balance, it stays on the balance until it is released.
+func (b *Balance) Hold(amount Amount) {
+	b.Held += amount
 }
 
-func (b *Balance) Decrease(amount float64) {
-	b.Balance -= amount
+func (b *Balance) Release(amount Amount) {
+	b.Held -= amount
 }
 
 func ConvertListBalanceDB(from []BalanceDB) []Balance {
@@ -48,41 +57,622 @@ type Transaction struct {
 	ID                int
 	SenderBalanceID   int
 	ReceiverBalanceID int
-	Amount            float64
+	Amount            Amount
 	Currency          Currency
 	Date              time.Time
+	// ReceiverAmount and ReceiverCurrency is what receiver balance got, it differs from Amount/Currency only for converted transfers.
+	ReceiverAmount   Amount
+	ReceiverCurrency Currency
+	// Rate and RateDate describe exchange rate used for conversion, both are zero when no conversion was made.
+	Rate     Rate
+	RateDate time.Time
+	// Convert allows transfer between balances in different currencies. It is not stored.
+	Convert bool
+	TransactionDetails
+	// RefundOf is ID of the transaction refunded by this one, zero for other transactions.
+	RefundOf int
+	// RefundedAmount is total of refunds of the transaction in ReceiverCurrency.
+	RefundedAmount Amount
+}
+
+// TransactionStatus tells if money of the transaction was returned to the sender.
+type TransactionStatus string
+
+const (
+	TransactionCompleted         TransactionStatus = "completed"
+	TransactionPartiallyRefunded TransactionStatus = "partially_refunded"
+	TransactionRefunded          TransactionStatus = "refunded"
+)
+
+func (t Transaction) Status() TransactionStatus {
+	switch {
+	case t.RefundedAmount <= 0:
+		return TransactionCompleted
+	case t.RefundedAmount < t.ReceiverAmount:
+		return TransactionPartiallyRefunded
+	}
+	return TransactionRefunded
+}
+
+// RefundableAmount is the part of ReceiverAmount which was not refunded yet.
+func (t Transaction) RefundableAmount() Amount {
+	return t.ReceiverAmount - t.RefundedAmount
+}
+
+// SenderRefund returns how much of Amount goes back to the sender when amount of ReceiverAmount is refunded. For converted
+// transfers it is the same part of Amount, rounded half away from zero to minor units of Currency, so the sender gets back
+// what was sent regardless of the current exchange rate.
+func (t Transaction) SenderRefund(amount Amount) Amount {
+	if t.ReceiverAmount == 0 || t.ReceiverCurrency == t.Currency {
+		return amount
+	}
+	return scale(amount, int64(t.Amount), int64(t.ReceiverAmount), t.Currency)
+}
+
+// Refund returns money of the transaction with TransactionID to its sender, see Transaction.SenderRefund.
+type Refund struct {
+	TransactionID int
+	// Amount is in ReceiverCurrency of the refunded transaction, zero refunds whole RefundableAmount.
+	Amount Amount
+	TransactionDetails
+}
+
+// TransactionDetails are set by the sender to describe the transfer, they are visible to both sender and receiver.
+type TransactionDetails struct {
+	Memo string
+	// Reference is an identifier of the transfer in client's system, e.g. invoice number.
+	Reference string
+	Metadata  map[string]string
 }
 
 type TransactionFull struct {
-	ID              int
-	SenderBalance   *Balance
-	ReceiverBalance *Balance
-	Amount          float64
-	Currency        Currency
-	Date            time.Time
+	ID               int
+	SenderBalance    *Balance
+	ReceiverBalance  *Balance
+	Amount           Amount
+	Currency         Currency
+	Date             time.Time
+	ReceiverAmount   Amount
+	ReceiverCurrency Currency
+	Rate             Rate
+	RateDate         time.Time
 }
 
 func (t *TransactionFull) IsValid() bool {
-	return t.SenderBalance.IsLocked() && t.ReceiverBalance.IsLocked() && t.SenderBalance.Balance > t.Amount
+	return t.SenderBalance.Available() > t.Amount
+}
+
+// SameCurrency checks if sender and receiver balances hold the same currency.
+func (t *TransactionFull) SameCurrency() bool {
+	return t.SenderBalance.Currency == t.ReceiverBalance.Currency
+}
+
+// ApplyRate converts Amount to the currency of the rate. Receiver balance is increased by the converted amount in Make().
+func (t *TransactionFull) ApplyRate(r FXRate) {
+	t.ReceiverAmount = r.Rate.Convert(t.Amount, r.To)
+	t.ReceiverCurrency = r.To
+	t.Rate = r.Rate
+	t.RateDate = r.Date
 }
 
 func (t *TransactionFull) Make() {
+	if t.ReceiverCurrency == "" {
+		// no conversion - receiver gets exactly what sender sends
+		t.ReceiverAmount = t.Amount
+		t.ReceiverCurrency = t.Currency
+	}
 	t.SenderBalance.Decrease(t.Amount)
-	t.ReceiverBalance.Increase(t.Amount)
+	t.ReceiverBalance.Increase(t.ReceiverAmount)
 	t.Date = time.Now()
 }
 
+func ConvertTransactionDB(from TransactionDB) Transaction {
+	return Transaction{
+		ID:                 from.ID,
+		SenderBalanceID:    from.SenderBalanceID,
+		ReceiverBalanceID:  from.ReceiverBalanceID,
+		Amount:             from.Amount,
+		Currency:           from.Currency,
+		Date:               from.Date,
+		ReceiverAmount:     from.ReceiverAmount,
+		ReceiverCurrency:   from.ReceiverCurrency,
+		Rate:               from.Rate,
+		RateDate:           from.RateDate,
+		TransactionDetails: from.TransactionDetails,
+		RefundOf:           from.RefundOf,
+		RefundedAmount:     from.RefundedAmount,
+	}
+}
+
 func ConvertListTransactionDB(from []TransactionDB) []Transaction {
 	arr := []Transaction{}
 	for _, b := range from {
-		arr = append(arr, Transaction(b))
+		arr = append(arr, ConvertTransactionDB(b))
+	}
+	return arr
+}
+
+// HoldStatus tells what happened with the hold, only active hold can be captured or voided.
+type HoldStatus string
+
+const (
+	HoldActive   HoldStatus = "active"
+	HoldCaptured HoldStatus = "captured"
+	HoldVoided   HoldStatus = "voided"
+	HoldExpired  HoldStatus = "expired"
+)
+
+// Hold reserves Amount of the sender balance for the receiver until it is captured into a transaction, voided or it expires.
+// Held money stays on the sender balance, but it is not available for transfers - see Balance.Available.
+type Hold struct {
+	ID                int
+	SenderBalanceID   int
+	ReceiverBalanceID int
+	Amount            Amount
+	Currency          Currency
+	Status            HoldStatus
+	// CapturedAmount is amount of the transaction made by capture, the rest of the hold is released.
+	CapturedAmount Amount
+	TransactionID  int
+	CreatedAt      time.Time
+	ExpiresAt      time.Time
+	TransactionDetails
+}
+
+// IsActive checks if the hold can be captured or voided at now.
+func (h Hold) IsActive(now time.Time) bool {
+	return h.Status == HoldActive && now.Before(h.ExpiresAt)
+}
+
+// Recurrence tells how often scheduled transfer is repeated, empty recurrence is transfer executed once.
+type Recurrence string
+
+const (
+	RecurrenceNone    Recurrence = ""
+	RecurrenceDaily   Recurrence = "daily"
+	RecurrenceWeekly  Recurrence = "weekly"
+	RecurrenceMonthly Recurrence = "monthly"
+)
+
+func (r Recurrence) IsValid() bool {
+	switch r {
+	case RecurrenceNone, RecurrenceDaily, RecurrenceWeekly, RecurrenceMonthly:
+		return true
+	}
+	return false
+}
+
+// ScheduleStatus tells if scheduled transfer is still executed, only active schedule is executed.
+type ScheduleStatus string
+
+const (
+	ScheduleActive    ScheduleStatus = "active"
+	SchedulePaused    ScheduleStatus = "paused"
+	ScheduleCompleted ScheduleStatus = "completed"
+	ScheduleCancelled ScheduleStatus = "cancelled"
+	ScheduleFailed    ScheduleStatus = "failed"
+)
+
+// Schedule is transfer of the user executed at NextRunAt, once or repeatedly by Recurrence.
+type Schedule struct {
+	ID                int
+	UserID            int
+	SenderBalanceID   int
+	ReceiverBalanceID int
+	Amount            Amount
+	Currency          Currency
+	Convert           bool
+	Recurrence        Recurrence
+	// DayOfMonth is day of monthly transfers, they are executed on the last day of shorter months.
+	DayOfMonth int
+	Status     ScheduleStatus
+	NextRunAt  time.Time
+	// RetryAt is set when the run at NextRunAt failed and is retried, Attempts counts failed attempts of the run.
+	RetryAt           time.Time
+	Attempts          int
+	LastError         string
+	LastRunAt         time.Time
+	LastTransactionID int
+	CreatedAt         time.Time
+	TransactionDetails
+}
+
+// Transaction returns transfer to be executed by the schedule.
+func (s Schedule) Transaction() Transaction {
+	return Transaction{SenderBalanceID: s.SenderBalanceID, ReceiverBalanceID: s.ReceiverBalanceID, Amount: s.Amount, Currency: s.Currency,
+		Convert: s.Convert, TransactionDetails: s.TransactionDetails}
+}
+
+// NextRun returns the first run after NextRunAt which is after now, missed runs are skipped. It is zero for schedule without recurrence.
+func (s Schedule) NextRun(now time.Time) time.Time {
+	if s.Recurrence == RecurrenceNone {
+		return time.Time{}
+	}
+	next := s.NextRunAt
+	for {
+		switch s.Recurrence {
+		case RecurrenceDaily:
+			next = next.AddDate(0, 0, 1)
+		case RecurrenceWeekly:
+			next = next.AddDate(0, 0, 7)
+		case RecurrenceMonthly:
+			next = nextMonthDay(next, s.DayOfMonth)
+		default:
+			return time.Time{}
+		}
+		if next.After(now) {
+			return next
+		}
+	}
+}
+
+// nextMonthDay returns day of the month after t at the same time, the last day of the month when it has less days.
+func nextMonthDay(t time.Time, day int) time.Time {
+	y, m, _ := t.Date()
+	first := time.Date(y, m+1, 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	if last := first.AddDate(0, 1, -1).Day(); day > last {
+		day = last
+	}
+	return first.AddDate(0, 0, day-1)
+}
+
+func ConvertListScheduleDB(from []ScheduleDB) []Schedule {
+	arr := []Schedule{}
+	for _, s := range from {
+		arr = append(arr, Schedule(s))
 	}
 	return arr
 }
 
+// BatchMode tells how failed transfer of batch item affects the other items.
+type BatchMode string
+
+const (
+	// BatchAllOrNothing transfers all items together, nothing is transferred when any of them fails.
+	BatchAllOrNothing BatchMode = "all_or_nothing"
+	// BatchBestEffort transfers every item on its own, failed items do not stop the others.
+	BatchBestEffort BatchMode = "best_effort"
+)
+
+func (m BatchMode) IsValid() bool {
+	return m == BatchAllOrNothing || m == BatchBestEffort
+}
+
+// BatchStatus is pending until all items of the batch are processed.
+type BatchStatus string
+
+const (
+	BatchPending            BatchStatus = "pending"
+	BatchCompleted          BatchStatus = "completed"
+	BatchPartiallyCompleted BatchStatus = "partially_completed"
+	BatchFailed             BatchStatus = "failed"
+)
+
+// BatchItemStatus is skipped for items of all-or-nothing batch which were not transferred because other item failed.
+type BatchItemStatus string
+
+const (
+	BatchItemPending   BatchItemStatus = "pending"
+	BatchItemCompleted BatchItemStatus = "completed"
+	BatchItemFailed    BatchItemStatus = "failed"
+	BatchItemSkipped   BatchItemStatus = "skipped"
+)
+
+// Batch is list of transfers from one sender balance, e.g. payroll.
+type Batch struct {
+	ID              int
+	UserID          int
+	SenderBalanceID int
+	Currency        Currency
+	Convert         bool
+	Mode            BatchMode
+	Status          BatchStatus
+	Items           []BatchItem
+	CreatedAt       time.Time
+	CompletedAt     time.Time
+}
+
+// BatchItem is transfer of the batch, Index is its position in the batch. Error is reason of failed or skipped transfer.
+type BatchItem struct {
+	Index             int
+	ReceiverBalanceID int
+	Amount            Amount
+	Status            BatchItemStatus
+	TransactionID     int
+	Error             string
+	TransactionDetails
+}
+
+// Total returns sum of amounts of all items.
+func (b Batch) Total() Amount {
+	var total Amount
+	for _, item := range b.Items {
+		total += item.Amount
+	}
+	return total
+}
+
+// Transaction returns transfer of the item.
+func (b Batch) Transaction(item BatchItem) Transaction {
+	return Transaction{SenderBalanceID: b.SenderBalanceID, ReceiverBalanceID: item.ReceiverBalanceID, Amount: item.Amount, Currency: b.Currency,
+		Convert: b.Convert, TransactionDetails: item.TransactionDetails}
+}
+
+// Finish sets status of the batch by statuses of its processed items.
+func (b *Batch) Finish(now time.Time) {
+	completed := 0
+	for _, item := range b.Items {
+		if item.Status == BatchItemCompleted {
+			completed++
+		}
+	}
+	switch completed {
+	case len(b.Items):
+		b.Status = BatchCompleted
+	case 0:
+		b.Status = BatchFailed
+	default:
+		b.Status = BatchPartiallyCompleted
+	}
+	b.CompletedAt = now
+}
+
+func ConvertBatchDB(from BatchDB) Batch {
+	items := []BatchItem{}
+	for _, item := range from.Items {
+		items = append(items, BatchItem(item))
+	}
+	return Batch{ID: from.ID, UserID: from.UserID, SenderBalanceID: from.SenderBalanceID, Currency: from.Currency, Convert: from.Convert,
+		Mode: from.Mode, Status: from.Status, Items: items, CreatedAt: from.CreatedAt, CompletedAt: from.CompletedAt}
+}
+
+func ConvertBatch(from Batch) BatchDB {
+	items := []BatchItemDB{}
+	for _, item := range from.Items {
+		items = append(items, BatchItemDB(item))
+	}
+	return BatchDB{ID: from.ID, UserID: from.UserID, SenderBalanceID: from.SenderBalanceID, Currency: from.Currency, Convert: from.Convert,
+		Mode: from.Mode, Status: from.Status, Items: items, CreatedAt: from.CreatedAt, CompletedAt: from.CompletedAt}
+}
+
+// IdempotencyKey remembers response of a request sent with Idempotency-Key header, so retried request is not executed twice.
+type IdempotencyKey struct {
+	UserID int
+	Key    string
+	// Fingerprint is a hash of the request body, the same key cannot be reused for different request.
+	Fingerprint  string
+	ResponseCode int
+	ResponseBody []byte
+	CreatedAt    time.Time
+}
+
+// IsCompleted checks if response of the request was already saved.
+func (k IdempotencyKey) IsCompleted() bool {
+	return k.ResponseCode != 0
+}
+
+type User struct {
+	ID        int
+	FirstName string
+	LastName  string
+	Age       int
+}
+
+// DisplayName is the name shown to other users, e.g. to the counterparty of a transaction - first name and initial
+// of last name, like "Alice C.".
+func (u User) DisplayName() string {
+	for _, r := range u.LastName {
+		return u.FirstName + " " + string(r) + "."
+	}
+	return u.FirstName
+}
+
+// MaskedName is the name shown to users who look up a recipient before transfer, first name is masked
+// to avoid disclosing who is behind login or alias, e.g. "A*** C.".
+func (u User) MaskedName() string {
+	first, _ := utf8.DecodeRuneInString(u.FirstName)
+	if first == utf8.RuneError {
+		return ""
+	}
+	masked := string(first) + "***"
+	for _, r := range u.LastName {
+		return masked + " " + string(r) + "."
+	}
+	return masked
+}
+
+// NormalizeAlias returns receiver in the form aliases are stored in - e-mails in lower case and phone numbers
+// (starting with +) as + followed by digits only. Other receivers are logins and are returned trimmed.
+func NormalizeAlias(receiver string) string {
+	receiver = strings.TrimSpace(receiver)
+	if strings.Contains(receiver, "@") {
+		return strings.ToLower(receiver)
+	}
+	if strings.HasPrefix(receiver, "+") {
+		return "+" + strings.Map(func(r rune) rune {
+			if unicode.IsDigit(r) {
+				return r
+			}
+			return -1
+		}, receiver)
+	}
+	return receiver
+}
+
+// UserPatch holds profile fields to be changed, nil fields are left unchanged.
+type UserPatch struct {
+	FirstName *string
+	LastName  *string
+	Age       *int
+}
+
+// Role defines what user is allowed to do, every role has all privileges of the roles below it.
+type Role string
+
+const (
+	RoleUser    Role = "user"
+	RoleSupport Role = "support"
+	RoleAdmin   Role = "admin"
+)
+
+var roleRanks = map[Role]int{
+	RoleUser:    1,
+	RoleSupport: 2,
+	RoleAdmin:   3,
+}
+
+func (r Role) IsValid() bool {
+	_, ok := roleRanks[r]
+	return ok
+}
+
+// Includes checks if the role has privileges of required role. Unknown role has no privileges.
+func (r Role) Includes(required Role) bool {
+	return r.IsValid() && roleRanks[r] >= roleRanks[required]
+}
+
 type Credentials struct {
 	ID       int
 	Login    string
 	Password string
 	UserID   int
+	// Role is stored with the user, it is read together with credentials on login.
+	Role Role
+}
+
+// Tokens are issued after successful login or refresh. When user has two-factor authentication enabled, login returns
+// only MFAToken, which has to be exchanged for access and refresh tokens together with one-time code.
+type Tokens struct {
+	AccessToken  string
+	RefreshToken string
+	MFAToken     string
+}
+
+// RefreshToken is a long-lived token used to get a new access token. Only hash of the token is stored.
+type RefreshToken struct {
+	ID        int
+	TokenHash string
+	// Family groups tokens rotated from the same login, reuse of a rotated token revokes whole family.
+	Family    string
+	UserID    int
+	ExpiresAt time.Time
+	RevokedAt time.Time
+	CreatedAt time.Time
+}
+
+func (t RefreshToken) IsRevoked() bool {
+	return !t.RevokedAt.IsZero()
+}
+
+func (t RefreshToken) IsExpired(now time.Time) bool {
+	return !now.Before(t.ExpiresAt)
+}
+
+// LoginAttempt counts failed logins for a key, e.g. login or client IP.
+type LoginAttempt struct {
+	Key           string
+	Failures      int
+	LastFailureAt time.Time
+	// LockedUntil is set after every failure, no login attempt for the key is allowed before it.
+	LockedUntil time.Time
+}
+
+// RetryAfter returns how long the key is still locked, zero when it is not locked.
+func (a LoginAttempt) RetryAfter(now time.Time) time.Duration {
+	if now.Before(a.LockedUntil) {
+		return a.LockedUntil.Sub(now)
+	}
+	return 0
+}
+
+// MFA holds TOTP secret of the user. Secret is used for login only after it was confirmed with the first code.
+type MFA struct {
+	UserID      int
+	Secret      string
+	ConfirmedAt time.Time
+	// LastUsedStep is TOTP time step of the last accepted code, the same code cannot be used twice.
+	LastUsedStep int64
+}
+
+func (m MFA) IsConfirmed() bool {
+	return !m.ConfirmedAt.IsZero()
+}
+
+// TOTPEnrollment is returned when user enrolls TOTP secret, URI can be shown as QR code and scanned by authenticator app.
+type TOTPEnrollment struct {
+	Secret string
+	URI    string
+}
+
+// Notification is a message sent to the user outside of the API, e.g. with password reset token.
+type Notification struct {
+	UserID  int       `json:"userId"`
+	Login   string    `json:"login"`
+	Subject string    `json:"subject"`
+	Message string    `json:"message"`
+	Time    time.Time `json:"time"`
+}
+
+// Scope is a permission granted to API key, JWT tokens of the user have all scopes.
+type Scope string
+
+const (
+	ScopeBalancesRead      Scope = "balances:read"
+	ScopeBalancesWrite     Scope = "balances:write"
+	ScopeTransactionsRead  Scope = "transactions:read"
+	ScopeTransactionsWrite Scope = "transactions:write"
+	// ScopeTransactionsLarge allows transfers above step-up threshold with the key, which cannot re-authenticate.
+	ScopeTransactionsLarge Scope = "transactions:large"
+)
+
+var scopes = map[Scope]bool{
+	ScopeBalancesRead:      true,
+	ScopeBalancesWrite:     true,
+	ScopeTransactionsRead:  true,
+	ScopeTransactionsWrite: true,
+	ScopeTransactionsLarge: true,
+}
+
+func (s Scope) IsValid() bool {
+	return scopes[s]
+}
+
+// APIKey authenticates a user or a service account without password, e.g. for server to server calls.
+type APIKey struct {
+	ID     int
+	Prefix string
+	UserID int
+	Name   string
+	Scopes []Scope
+	// AllowedIPs are networks in CIDR notation the key can be used from, any IP is allowed when it is empty.
+	AllowedIPs []*net.IPNet
+	ExpiresAt  time.Time
+	RevokedAt  time.Time
+	CreatedAt  time.Time
+}
+
+func (k APIKey) HasScope(scope Scope) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func (k APIKey) AllowsIP(ip net.IP) bool {
+	if len(k.AllowedIPs) == 0 {
+		return true
+	}
+	for _, network := range k.AllowedIPs {
+		if ip != nil && network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsActive checks if the key is neither revoked nor expired.
+func (k APIKey) IsActive(now time.Time) bool {
+	return k.RevokedAt.IsZero() && now.Before(k.ExpiresAt)
 }
@@ -1,116 +1,288 @@
 package model
 
 import (
-	"math"
+	"net"
 	"testing"
 	"time"
 )
 
-func TestBalanceLocking(t *testing.T) {
-	b := Balance{
-		Locked: true,
-	}
-	got := b.IsLocked()
-	if !got {
-		t.Errorf("IsLocked() init = %t; want true", got)
-	}
-
-	b.Unlock()
-	got = b.IsLocked()
-	if got {
-		t.Errorf("IsLocked() after unlocking = %t; want false", got)
-	}
-
-	b.Lock()
-	got = b.IsLocked()
-	if !got {
-		t.Errorf("IsLocked() after locking = %t; want true", got)
-	}
-}
-
 func TestBalanceIncrease(t *testing.T) {
-	b := Balance{Balance: 1000.00}
-	b.Increase(10)
+	b := Balance{Balance: MustParseAmount("1000.00")}
+	b.Increase(MustParseAmount("10"))
 	got := b.Balance
-	if got != 1010.00 {
-		t.Errorf("Increase(10) = %f; want 1010.00", got)
+	if got != MustParseAmount("1010.00") {
+		t.Errorf("Increase(10) = %s; want 1010.00", got)
 	}
-	b.Increase(999.99)
+	b.Increase(MustParseAmount("999.99"))
 	got = b.Balance
-	if got != 2009.99 {
-		t.Errorf("Increase(999.99) = %f; want 2009.99", got)
+	if got != MustParseAmount("2009.99") {
+		t.Errorf("Increase(999.99) = %s; want 2009.99", got)
 	}
-	b.Increase(0.01)
+	b.Increase(MustParseAmount("0.01"))
 	got = b.Balance
-	if got != 2010.00 {
-		t.Errorf("Increase(0.01) = %f; want 2010.00", got)
+	if got != MustParseAmount("2010.00") {
+		t.Errorf("Increase(0.01) = %s; want 2010.00", got)
 	}
 }
 
 func TestBalanceDecrease(t *testing.T) {
-	b := Balance{Balance: 2000.00}
-	b.Decrease(10)
+	b := Balance{Balance: MustParseAmount("2000.00")}
+	b.Decrease(MustParseAmount("10"))
 	got := b.Balance
-	if got != 1990 {
-		t.Errorf("Increase(10) = %f; want 1990", got)
+	if got != MustParseAmount("1990") {
+		t.Errorf("Increase(10) = %s; want 1990", got)
 	}
-	b.Decrease(999.99)
+	b.Decrease(MustParseAmount("999.99"))
 	got = b.Balance
-	if got != 990.01 {
-		t.Errorf("Increase(999.99) = %f; want 990.01", got)
+	if got != MustParseAmount("990.01") {
+		t.Errorf("Increase(999.99) = %s; want 990.01", got)
 	}
-	b.Decrease(0.01)
+	b.Decrease(MustParseAmount("0.01"))
 	got = b.Balance
-	if got != 990.00 {
-		t.Errorf("Increase(0.01) = %f; want 990.00", got)
+	if got != MustParseAmount("990.00") {
+		t.Errorf("Increase(0.01) = %s; want 990.00", got)
 	}
 }
 
 func TestTransactionFullIsValid(t *testing.T) {
-	b1 := Balance{Balance: 2000.00, Locked: true}
-	b2 := Balance{Balance: 2000.00, Locked: true}
+	b1 := Balance{Balance: MustParseAmount("2000.00")}
+	b2 := Balance{Balance: MustParseAmount("2000.00")}
 
 	transaction := TransactionFull{
 		SenderBalance:   &b1,
 		ReceiverBalance: &b2,
-		Amount:          250.86,
+		Amount:          MustParseAmount("250.86"),
 	}
 
 	isValid := transaction.IsValid()
 	if !isValid {
 		t.Errorf("IsValid() = %t; want true", isValid)
 	}
+
+	// held money cannot be transferred
+	b1.Hold(MustParseAmount("1749.14"))
+	isValid = transaction.IsValid()
+	if isValid {
+		t.Errorf("IsValid() with %s available = %t; want false", b1.Available(), isValid)
+	}
+	b1.Release(MustParseAmount("0.01"))
+	isValid = transaction.IsValid()
+	if !isValid {
+		t.Errorf("IsValid() with %s available = %t; want true", b1.Available(), isValid)
+	}
 }
 
 func TestTransactionFullMake(t *testing.T) {
-	b1 := Balance{Balance: 2000.00, Locked: false}
-	b2 := Balance{Balance: 2000.00, Locked: false}
+	b1 := Balance{Balance: MustParseAmount("2000.00")}
+	b2 := Balance{Balance: MustParseAmount("2000.00")}
 
 	transaction := TransactionFull{
 		SenderBalance:   &b1,
 		ReceiverBalance: &b2,
-		Amount:          250.86,
+		Amount:          MustParseAmount("250.86"),
 	}
 
 	beforeTransaction := transaction.Date
 
 	transaction.Make()
-	if !withTolerane(transaction.SenderBalance.Balance, 1749.14) {
-		t.Errorf("transaction.Make(); SenderBalance.Balance = %f; want 1749.14", transaction.SenderBalance.Balance)
+	if transaction.SenderBalance.Balance != MustParseAmount("1749.14") {
+		t.Errorf("transaction.Make(); SenderBalance.Balance = %s; want 1749.14", transaction.SenderBalance.Balance)
 	}
-	if !withTolerane(transaction.ReceiverBalance.Balance, 2250.86) {
-		t.Errorf("transaction.Make(); ReceiverBalance.Balance = %f; want 2250.86", transaction.ReceiverBalance.Balance)
+	if transaction.ReceiverBalance.Balance != MustParseAmount("2250.86") {
+		t.Errorf("transaction.Make(); ReceiverBalance.Balance = %s; want 2250.86", transaction.ReceiverBalance.Balance)
 	}
 	if !transaction.Date.After(beforeTransaction) {
 		t.Errorf("transaction.Make(); Date = %s; want after %s", transaction.Date.Format(time.RFC3339Nano), beforeTransaction.Format(time.RFC3339Nano))
 	}
 }
 
-func withTolerane(a, b float64) bool {
-	tolerance := 0.01
-	if diff := math.Abs(a - b); diff < tolerance {
-		return true
-	} else {
-		return false
+func TestRoleIncludes(t *testing.T) {
+	cases := []struct {
+		role     Role
+		required Role
+		want     bool
+	}{
+		{role: RoleUser, required: RoleUser, want: true},
+		{role: RoleUser, required: RoleSupport, want: false},
+		{role: RoleSupport, required: RoleSupport, want: true},
+		{role: RoleSupport, required: RoleAdmin, want: false},
+		{role: RoleAdmin, required: RoleSupport, want: true},
+		{role: Role("root"), required: RoleUser, want: false},
+		{role: Role(""), required: RoleUser, want: false},
+	}
+	for _, testCase := range cases {
+		if got := testCase.role.Includes(testCase.required); got != testCase.want {
+			t.Errorf("Role(%q).Includes(%q) got: %t; want: %t", testCase.role, testCase.required, got, testCase.want)
+		}
+	}
+}
+
+func TestAPIKeyAllowsIP(t *testing.T) {
+	_, network, _ := net.ParseCIDR("10.0.0.0/24")
+	_, single, _ := net.ParseCIDR("2001:db8::1/128")
+	key := APIKey{AllowedIPs: []*net.IPNet{network, single}}
+	cases := []struct {
+		ip   string
+		want bool
+	}{
+		{ip: "10.0.0.17", want: true},
+		{ip: "10.0.1.17", want: false},
+		{ip: "2001:db8::1", want: true},
+		{ip: "2001:db8::2", want: false},
+		{ip: "not an IP", want: false},
+	}
+	for _, testCase := range cases {
+		if got := key.AllowsIP(net.ParseIP(testCase.ip)); got != testCase.want {
+			t.Errorf("AllowsIP(%s) got: %t; want: %t", testCase.ip, got, testCase.want)
+		}
+	}
+	if !(APIKey{}).AllowsIP(net.ParseIP("192.168.1.1")) {
+		t.Errorf("key without allowlist should allow any IP")
+	}
+}
+
+func TestAPIKeyIsActive(t *testing.T) {
+	now := time.Now()
+	cases := []struct {
+		key  APIKey
+		want bool
+	}{
+		{key: APIKey{ExpiresAt: now.Add(time.Hour)}, want: true},
+		{key: APIKey{ExpiresAt: now}, want: false},
+		{key: APIKey{ExpiresAt: now.Add(time.Hour), RevokedAt: now.Add(-time.Minute)}, want: false},
+	}
+	for _, testCase := range cases {
+		if got := testCase.key.IsActive(now); got != testCase.want {
+			t.Errorf("%+v.IsActive() got: %t; want: %t", testCase.key, got, testCase.want)
+		}
+	}
+}
+
+func TestUserDisplayName(t *testing.T) {
+	cases := []struct {
+		user User
+		want string
+	}{
+		{user: User{FirstName: "Alice", LastName: "Cruz"}, want: "Alice C."},
+		{user: User{FirstName: "Łucja", LastName: "Żak"}, want: "Łucja Ż."},
+		{user: User{FirstName: "Payouts", LastName: ""}, want: "Payouts"},
+	}
+	for _, testCase := range cases {
+		if got := testCase.user.DisplayName(); got != testCase.want {
+			t.Errorf("display name of %+v got: %s; want: %s", testCase.user, got, testCase.want)
+		}
+	}
+}
+
+func TestUserMaskedName(t *testing.T) {
+	cases := []struct {
+		user User
+		want string
+	}{
+		{user: User{FirstName: "Alice", LastName: "Cruz"}, want: "A*** C."},
+		{user: User{FirstName: "Łucja", LastName: "Żak"}, want: "Ł*** Ż."},
+		{user: User{FirstName: "Payouts"}, want: "P***"},
+	}
+	for _, testCase := range cases {
+		if got := testCase.user.MaskedName(); got != testCase.want {
+			t.Errorf("masked name of %+v got: %s; want: %s", testCase.user, got, testCase.want)
+		}
+	}
+}
+
+func TestNormalizeAlias(t *testing.T) {
+	cases := []struct {
+		receiver string
+		want     string
+	}{
+		{receiver: " Alice.Cruz@Example.com", want: "alice.cruz@example.com"},
+		{receiver: "+65 9123-4567", want: "+6591234567"},
+		{receiver: "+(65) 91234567 ", want: "+6591234567"},
+		{receiver: "Test11 ", want: "Test11"},
+	}
+	for _, testCase := range cases {
+		if got := NormalizeAlias(testCase.receiver); got != testCase.want {
+			t.Errorf("alias %q got: %q; want: %q", testCase.receiver, got, testCase.want)
+		}
+	}
+}
+
+func TestTransactionRefund(t *testing.T) {
+	transaction := Transaction{Currency: SGD, Amount: MustParseAmount("20"), ReceiverCurrency: SGD, ReceiverAmount: MustParseAmount("20")}
+	cases := []struct {
+		refunded   Amount
+		status     TransactionStatus
+		refundable Amount
+	}{
+		{refunded: 0, status: TransactionCompleted, refundable: MustParseAmount("20")},
+		{refunded: MustParseAmount("5"), status: TransactionPartiallyRefunded, refundable: MustParseAmount("15")},
+		{refunded: MustParseAmount("20"), status: TransactionRefunded, refundable: 0},
+	}
+	for _, testCase := range cases {
+		transaction.RefundedAmount = testCase.refunded
+		if transaction.Status() != testCase.status || transaction.RefundableAmount() != testCase.refundable {
+			t.Errorf("transaction with %s refunded got: %s, %s refundable; want: %s, %s refundable", testCase.refunded,
+				transaction.Status(), transaction.RefundableAmount(), testCase.status, testCase.refundable)
+		}
+	}
+
+	if got := transaction.SenderRefund(MustParseAmount("7.50")); got != MustParseAmount("7.50") {
+		t.Errorf("sender refund got: %s; want: 7.50", got)
+	}
+	// 1.50 USD was converted to 225 JPY
+	converted := Transaction{Currency: USD, Amount: MustParseAmount("1.50"), ReceiverCurrency: JPY, ReceiverAmount: MustParseAmount("225")}
+	for refund, want := range map[string]string{"225": "1.50", "75": "0.50", "1": "0.01", "2": "0.01", "3": "0.02"} {
+		if got := converted.SenderRefund(MustParseAmount(refund)); got != MustParseAmount(want) {
+			t.Errorf("sender refund of %s JPY got: %s; want: %s", refund, got, want)
+		}
+	}
+}
+
+func TestScheduleNextRun(t *testing.T) {
+	at := func(day string) time.Time {
+		d, _ := time.Parse("2006-01-02 15:04", day)
+		return d
+	}
+	cases := []struct {
+		schedule Schedule
+		now      time.Time
+		want     time.Time
+	}{
+		{schedule: Schedule{NextRunAt: at("2026-01-31 09:00")}, now: at("2026-01-31 09:00"), want: time.Time{}},
+		{schedule: Schedule{Recurrence: RecurrenceDaily, NextRunAt: at("2026-01-31 09:00")}, now: at("2026-01-31 09:00"), want: at("2026-02-01 09:00")},
+		{schedule: Schedule{Recurrence: RecurrenceWeekly, NextRunAt: at("2026-01-31 09:00")}, now: at("2026-01-31 09:00"), want: at("2026-02-07 09:00")},
+		{schedule: Schedule{Recurrence: RecurrenceMonthly, DayOfMonth: 31, NextRunAt: at("2026-01-31 09:00")}, now: at("2026-01-31 09:00"), want: at("2026-02-28 09:00")},
+		{schedule: Schedule{Recurrence: RecurrenceMonthly, DayOfMonth: 31, NextRunAt: at("2026-02-28 09:00")}, now: at("2026-02-28 09:00"), want: at("2026-03-31 09:00")},
+		// runs missed while the schedule was paused are skipped
+		{schedule: Schedule{Recurrence: RecurrenceDaily, NextRunAt: at("2026-01-01 09:00")}, now: at("2026-01-10 12:00"), want: at("2026-01-11 09:00")},
+		{schedule: Schedule{Recurrence: RecurrenceMonthly, DayOfMonth: 1, NextRunAt: at("2026-01-01 09:00")}, now: at("2026-04-15 09:00"), want: at("2026-05-01 09:00")},
+	}
+	for _, testCase := range cases {
+		if got := testCase.schedule.NextRun(testCase.now); !got.Equal(testCase.want) {
+			t.Errorf("next run of %s schedule from %s at %s got: %s; want: %s", testCase.schedule.Recurrence, testCase.schedule.NextRunAt, testCase.now,
+				got, testCase.want)
+		}
+	}
+}
+
+func TestBatchFinish(t *testing.T) {
+	cases := []struct {
+		items []BatchItemStatus
+		want  BatchStatus
+	}{
+		{items: []BatchItemStatus{BatchItemCompleted, BatchItemCompleted}, want: BatchCompleted},
+		{items: []BatchItemStatus{BatchItemCompleted, BatchItemFailed}, want: BatchPartiallyCompleted},
+		{items: []BatchItemStatus{BatchItemSkipped, BatchItemFailed}, want: BatchFailed},
+	}
+	now := time.Now()
+	for _, testCase := range cases {
+		b := Batch{Status: BatchPending}
+		for i, status := range testCase.items {
+			b.Items = append(b.Items, BatchItem{Index: i, Amount: MustParseAmount("10"), Status: status})
+		}
+		b.Finish(now)
+		if b.Status != testCase.want || !b.CompletedAt.Equal(now) || b.Total() != MustParseAmount("20") {
+			t.Errorf("batch with items %v got: %s, total %s; want: %s, total 20", testCase.items, b.Status, b.Total(), testCase.want)
+		}
 	}
 }
@@ -15,6 +15,7 @@ type LogLevel string
 
 const (
 	Info LogLevel = "INFO"
+	Warn LogLevel = "WARN"
 )
 
 type Protocol string
@@ -36,6 +37,9 @@ type OperationalLog struct {
 	Request  Request   `json:"request"`
 	Response Response  `json:"response"`
 	Err      string    `json:"err"`
+	// Event and Details are set only for event logs, see OperationalLogService.CreateEventLog.
+	Event   string          `json:"event,omitempty"`
+	Details json.RawMessage `json:"details,omitempty"`
 }
 
 type Request struct {
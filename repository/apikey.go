@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/labstack/gommon/log"
+	"zuzanna.com/walletapi/model"
+)
+
+type APIKeyRepo interface {
+	// Create saves the key and returns its ID.
+	Create(k model.APIKeyDB) (int, error)
+	GetByHash(hash string) (model.APIKeyDB, error)
+	// GetByUserID returns all keys of the user, including revoked and expired ones, the newest first.
+	GetByUserID(userID int) ([]model.APIKeyDB, error)
+	// Revoke revokes active key of the user. Returns ErrRecordNotFound when there is no such key.
+	Revoke(id, userID int, revokedAt time.Time) error
+	// RevokeUserKeys revokes all active keys of the user.
+	RevokeUserKeys(userID int, revokedAt time.Time) error
+}
+
+type PostgreAPIKeyRepo struct {
+	DBConn pgxConn
+}
+
+func NewPostgreAPIKeyRepo(pool *pgxpool.Pool) *PostgreAPIKeyRepo {
+	return &PostgreAPIKeyRepo{DBConn: pool}
+}
+
+func (r PostgreAPIKeyRepo) Create(k model.APIKeyDB) (int, error) {
+	var id int
+	err := r.DBConn.QueryRow(context.Background(),
+		`INSERT INTO api_key (key_hash, prefix, user_id, name, scopes, allowed_ips, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`,
+		k.KeyHash, k.Prefix, k.UserID, k.Name, k.Scopes, k.AllowedIPs, k.ExpiresAt, k.CreatedAt).Scan(&id)
+	if err != nil {
+		log.Errorf("#Create(...) error while saving API key for user with ID %d; error %v", k.UserID, err)
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r PostgreAPIKeyRepo) GetByHash(hash string) (model.APIKeyDB, error) {
+	k, err := scanAPIKey(r.DBConn.QueryRow(context.Background(),
+		`SELECT id, key_hash, prefix, user_id, name, scopes, allowed_ips, expires_at, revoked_at, created_at
+		FROM api_key WHERE key_hash=$1`, hash))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return model.APIKeyDB{}, ErrRecordNotFound
+		}
+		log.Errorf("#GetByHash(...) error while reading API key; error %v", err)
+		return model.APIKeyDB{}, err
+	}
+	return k, nil
+}
+
+func (r PostgreAPIKeyRepo) GetByUserID(userID int) ([]model.APIKeyDB, error) {
+	rows, err := r.DBConn.Query(context.Background(),
+		`SELECT id, key_hash, prefix, user_id, name, scopes, allowed_ips, expires_at, revoked_at, created_at
+		FROM api_key WHERE user_id=$1 ORDER BY id DESC`, userID)
+	if err != nil {
+		log.Errorf("#GetByUserID(...) error while reading API keys of user with ID %d; error %v", userID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []model.APIKeyDB{}
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			log.Errorf("#GetByUserID(...) error while scanning API key of user with ID %d; error %v", userID, err)
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	if err = rows.Err(); err != nil {
+		log.Errorf("#GetByUserID(...) error while reading API keys of user with ID %d; error %v", userID, err)
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (r PostgreAPIKeyRepo) Revoke(id, userID int, revokedAt time.Time) error {
+	tag, err := r.DBConn.Exec(context.Background(),
+		"UPDATE api_key SET revoked_at=$1 WHERE id=$2 AND user_id=$3 AND revoked_at IS NULL", revokedAt, id, userID)
+	if err != nil {
+		log.Errorf("#Revoke(...) error while revoking API key %d of user with ID %d; error %v", id, userID, err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r PostgreAPIKeyRepo) RevokeUserKeys(userID int, revokedAt time.Time) error {
+	_, err := r.DBConn.Exec(context.Background(),
+		"UPDATE api_key SET revoked_at=$1 WHERE user_id=$2 AND revoked_at IS NULL", revokedAt, userID)
+	if err != nil {
+		log.Errorf("#RevokeUserKeys(...) error while revoking API keys of user with ID %d; error %v", userID, err)
+		return err
+	}
+	return nil
+}
+
+func scanAPIKey(row pgx.Row) (model.APIKeyDB, error) {
+	k := model.APIKeyDB{}
+	var revokedAt *time.Time
+	err := row.Scan(&k.ID, &k.KeyHash, &k.Prefix, &k.UserID, &k.Name, &k.Scopes, &k.AllowedIPs, &k.ExpiresAt, &revokedAt, &k.CreatedAt)
+	if err != nil {
+		return model.APIKeyDB{}, err
+	}
+	if revokedAt != nil {
+		k.RevokedAt = *revokedAt
+	}
+	return k, nil
+}
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pashagolub/pgxmock"
+	"zuzanna.com/walletapi/model"
+)
+
+func TestGetAPIKeyByHash(t *testing.T) {
+	mockPool, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockPool.Close()
+
+	mockRepo := PostgreAPIKeyRepo{
+		DBConn: dbMockPool{mockPool},
+	}
+	now := time.Now()
+	var notRevoked *time.Time
+
+	query := `SELECT id, key_hash, prefix, user_id, name, scopes, allowed_ips, expires_at, revoked_at, created_at
+		FROM api_key WHERE key_hash=$1`
+	mockPool.ExpectQuery(query).WithArgs("hash1").
+		WillReturnRows(pgxmock.NewRows([]string{"id", "key_hash", "prefix", "user_id", "name", "scopes", "allowed_ips", "expires_at", "revoked_at", "created_at"}).
+			AddRow(1, "hash1", "wak_abcd", 7, "payouts", []string{"transactions:write"}, []string{"10.0.0.0/24"}, now.Add(time.Hour), notRevoked, now))
+	mockPool.ExpectQuery(query).WithArgs("hash2").
+		WillReturnError(pgx.ErrNoRows)
+
+	want := model.APIKeyDB{
+		ID:         1,
+		KeyHash:    "hash1",
+		Prefix:     "wak_abcd",
+		UserID:     7,
+		Name:       "payouts",
+		Scopes:     []string{"transactions:write"},
+		AllowedIPs: []string{"10.0.0.0/24"},
+		ExpiresAt:  now.Add(time.Hour),
+		CreatedAt:  now,
+	}
+	key, err := mockRepo.GetByHash("hash1")
+	if err != nil {
+		t.Errorf("error was not expected while reading API key: %s", err)
+	}
+	if !reflect.DeepEqual(key, want) {
+		t.Errorf("API key got: %+v; want: %+v", key, want)
+	}
+	if _, err = mockRepo.GetByHash("hash2"); err != ErrRecordNotFound {
+		t.Errorf("error got: %v; want: %v", err, ErrRecordNotFound)
+	}
+
+	if err := mockPool.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	mockPool, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockPool.Close()
+
+	mockRepo := PostgreAPIKeyRepo{
+		DBConn: dbMockPool{mockPool},
+	}
+	now := time.Now()
+
+	query := "UPDATE api_key SET revoked_at=$1 WHERE id=$2 AND user_id=$3 AND revoked_at IS NULL"
+	mockPool.ExpectExec(query).WithArgs(now, 1, 7).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mockPool.ExpectExec(query).WithArgs(now, 1, 8).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+	if err = mockRepo.Revoke(1, 7, now); err != nil {
+		t.Errorf("error was not expected while revoking API key: %s", err)
+	}
+	// key of other user or already revoked
+	if err = mockRepo.Revoke(1, 8, now); err != ErrRecordNotFound {
+		t.Errorf("error got: %v; want: %v", err, ErrRecordNotFound)
+	}
+
+	if err := mockPool.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRevokeUserAPIKeys(t *testing.T) {
+	mockPool, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockPool.Close()
+
+	mockRepo := PostgreAPIKeyRepo{
+		DBConn: dbMockPool{mockPool},
+	}
+	now := time.Now()
+
+	mockPool.ExpectExec("UPDATE api_key SET revoked_at=$1 WHERE user_id=$2 AND revoked_at IS NULL").WithArgs(now, 7).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 2))
+
+	if err = mockRepo.RevokeUserKeys(7, now); err != nil {
+		t.Errorf("error was not expected while revoking API keys: %s", err)
+	}
+	if err := mockPool.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
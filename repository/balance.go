@@ -4,7 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
@@ -13,13 +16,24 @@ import (
 )
 
 var ErrBalancesNotFound = errors.New("missing required balances")
+var ErrBalanceExists = errors.New("balance in given currency already exists")
 
 type BalanceRepo interface {
 	GetList(userID int) ([]model.BalanceDB, error)
+	CreateBalance(userID int, currency model.Currency) (model.BalanceDB, error)
 	UpdateBalances(balanceIDs []int, updateFn func(b []model.BalanceDB) ([]model.BalanceDB, error)) error
 
 	MakeTransaction(t model.TransactionDB, fn func(t model.TransactionDBFull) (model.TransactionDBFull, error)) (model.TransactionDB, error)
-	GetTransactions(userID int) ([]model.TransactionDB, error)
+	GetTransactions(userID int, filter model.TransactionFilter) ([]model.TransactionDB, error)
+	// GetBalance returns balance of the user with date of its last transaction, zero when there was none.
+	// Returns ErrRecordNotFound when there is no such balance of the user.
+	GetBalance(id, userID int) (model.BalanceDB, time.Time, error)
+	// GetTransaction returns transaction from or to a balance of the user. Returns ErrRecordNotFound when
+	// there is no such transaction of the user.
+	GetTransaction(id, userID int) (model.TransactionDB, error)
+	// RefundTransaction makes refund of the transaction with ID - a new transaction from its receiver back to its sender balance,
+	// see PostgreBalanceRepo.RefundTransaction. Returns ErrRecordNotFound when there is no such transaction.
+	RefundTransaction(id int, fn func(refunded model.TransactionDB, refund model.TransactionDBFull) (model.TransactionDBFull, error)) (model.TransactionDB, error)
 }
 
 type PostgreBalanceRepo struct {
@@ -33,7 +47,7 @@ func NewPostgreBalanceRepo(pool *pgxpool.Pool) *PostgreBalanceRepo {
 // Get retrieves all balances assigned to particular user.
 func (r PostgreBalanceRepo) GetList(userID int) ([]model.BalanceDB, error) {
 	balances := []model.BalanceDB{}
-	rows, err := r.DBConn.Query(context.Background(), "SELECT id, currency, balance, user_id FROM balance WHERE user_id=$1", userID)
+	rows, err := r.DBConn.Query(context.Background(), "SELECT id, currency, balance, held, user_id FROM balance WHERE user_id=$1", userID)
 	if err != nil {
 		log.Errorf("error while retrieving balances for user with ID %d; error %v", userID, err)
 		return nil, err
@@ -42,7 +56,7 @@ func (r PostgreBalanceRepo) GetList(userID int) ([]model.BalanceDB, error) {
 
 	for rows.Next() {
 		tmp := model.BalanceDB{}
-		err = rows.Scan(&tmp.ID, &tmp.Currency, &tmp.Balance, &tmp.UserID)
+		err = rows.Scan(&tmp.ID, &tmp.Currency, &tmp.Balance, &tmp.Held, &tmp.UserID)
 		if err != nil {
 			log.Errorf("error while reading balances for user with ID %d; error %v", userID, err)
 			return nil, err
@@ -52,70 +66,174 @@ func (r PostgreBalanceRepo) GetList(userID int) ([]model.BalanceDB, error) {
 	return balances, nil
 }
 
-func (r PostgreBalanceRepo) GetTransactions(userID int) (transactions []model.TransactionDB, err error) {
-	tx, err := r.DBConn.BeginTx(context.Background(), pgx.TxOptions{})
+// CreateBalance inserts new empty balance. There can be only one balance per user and currency.
+func (r PostgreBalanceRepo) CreateBalance(userID int, currency model.Currency) (model.BalanceDB, error) {
+	balance := model.BalanceDB{Currency: currency, UserID: userID}
+	err := r.DBConn.QueryRow(context.Background(),
+		"INSERT INTO balance (currency, balance, user_id) VALUES ($1, $2, $3) ON CONFLICT (user_id, currency) DO NOTHING RETURNING id",
+		string(currency), balance.Balance, userID).Scan(&balance.ID)
 	if err != nil {
-		log.Errorf("#GetTransactions(...) failed, error: %v", err)
-		return nil, fmt.Errorf("unable to start a transaction; error: %w", err)
+		if err == pgx.ErrNoRows {
+			return model.BalanceDB{}, ErrBalanceExists
+		}
+		log.Errorf("error while creating %s balance for user with ID %d; error %v", currency, userID, err)
+		return model.BalanceDB{}, err
 	}
-	defer func() {
-		err = r.finishTx(err, tx)
-	}()
+	return balance, nil
+}
 
-	existingBalances, err := r.getBalances(tx, userID)
+// GetTransactions returns transactions of balances of the user matching the filter, ordered by date and ID, the newest first.
+// Indexes on (sender_id, date, id) and (receiver_id, date, id) of transaction table serve the query.
+func (r PostgreBalanceRepo) GetTransactions(userID int, filter model.TransactionFilter) ([]model.TransactionDB, error) {
+	query, args := transactionsQuery(userID, filter)
+	rows, err := r.DBConn.Query(context.Background(), query, args...)
 	if err != nil {
+		log.Errorf("#GetTransactions(...) error while retrieving transactions of user with ID %d; error %v", userID, err)
 		return nil, err
 	}
-	balanceIDs := []int{}
-	for _, b := range existingBalances {
-		balanceIDs = append(balanceIDs, b.ID)
-	}
+	defer rows.Close()
 
-	transactions, err = r.getTransactionsByBalanceIDs(tx, balanceIDs)
-	if err != nil {
+	transactions := []model.TransactionDB{}
+	for rows.Next() {
+		tmp, err := scanTransaction(rows)
+		if err != nil {
+			log.Errorf("#GetTransactions(...) error while scanning transactions of user with ID %d; error %v", userID, err)
+			return nil, err
+		}
+		transactions = append(transactions, tmp)
+	}
+	if err = rows.Err(); err != nil {
+		log.Errorf("#GetTransactions(...) error while retrieving transactions of user with ID %d; error %v", userID, err)
 		return nil, err
 	}
 	return transactions, nil
-
 }
 
-func (r PostgreBalanceRepo) getTransactionsByBalanceIDs(tx pgx.Tx, balanceIDs []int) ([]model.TransactionDB, error) {
-	transactions := []model.TransactionDB{}
-	query :=
-		`select bt.transaction_id, t.sender_id, t.receiver_id, t.currency, t.amount, t."date" 
-		from balance_transaction bt
-			left join "transaction" t ON bt.transaction_id = t.id
-			where bt.balance_id IN (`
-	for i := range balanceIDs {
-		query += " $" + strconv.Itoa(i+1)
-		if i < len(balanceIDs)-1 {
-			query += ","
+func (r PostgreBalanceRepo) GetBalance(id, userID int) (model.BalanceDB, time.Time, error) {
+	b := model.BalanceDB{}
+	var lastTransactionAt *time.Time
+	err := r.DBConn.QueryRow(context.Background(),
+		`SELECT b.id, b.currency, b.balance, b.held, b.user_id,
+		(SELECT max(t."date") FROM "transaction" t WHERE t.sender_id = b.id OR t.receiver_id = b.id)
+		FROM balance b WHERE b.id=$1 AND b.user_id=$2`, id, userID).
+		Scan(&b.ID, &b.Currency, &b.Balance, &b.Held, &b.UserID, &lastTransactionAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return model.BalanceDB{}, time.Time{}, ErrRecordNotFound
 		}
+		log.Errorf("#GetBalance(...) error while retrieving balance %d of user with ID %d; error %v", id, userID, err)
+		return model.BalanceDB{}, time.Time{}, err
 	}
-	query += ")"
-	rows, err := tx.Query(context.Background(), query, toArgs(balanceIDs)...)
+	if lastTransactionAt == nil {
+		return b, time.Time{}, nil
+	}
+	return b, *lastTransactionAt, nil
+}
 
+func (r PostgreBalanceRepo) GetTransaction(id, userID int) (model.TransactionDB, error) {
+	t, err := scanTransaction(r.DBConn.QueryRow(context.Background(),
+		`SELECT `+transactionColumns+`
+		FROM "transaction" t WHERE t.id=$1
+		AND (t.sender_id IN (SELECT id FROM balance WHERE user_id=$2) OR t.receiver_id IN (SELECT id FROM balance WHERE user_id=$2))`,
+		id, userID))
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			return make([]model.TransactionDB, 0), nil
+			return model.TransactionDB{}, ErrRecordNotFound
 		}
-		log.Errorf("#getTransactionsByBalanceIDs(...) error while retrieving transactions for balances with IDs %s; error %v", balanceIDs, err)
-		return nil, err
+		log.Errorf("#GetTransaction(...) error while retrieving transaction %d of user with ID %d; error %v", id, userID, err)
+		return model.TransactionDB{}, err
 	}
+	return t, nil
+}
 
-	for rows.Next() {
-		tmp := model.TransactionDB{}
-		err = rows.Scan(&tmp.ID, &tmp.SenderBalanceID, &tmp.ReceiverBalanceID, &tmp.Currency, &tmp.Amount, &tmp.Date)
-		if err != nil {
-			log.Errorf("#getTransactionsByBalanceIDs(...) error while scanning transactions for balances with IDs %s; error %v", balanceIDs, err)
-			return nil, err
-		}
-		transactions = append(transactions, tmp)
+// transactionColumns are read by scanTransaction.
+const transactionColumns = `t.id, t.sender_id, t.receiver_id, t.currency, t.amount, t."date", t.receiver_currency, t.receiver_amount, t.rate, t.rate_date,
+		t.memo, t.reference, t.metadata, t.refund_of, t.refunded_amount`
+
+// scanTransaction reads row with transactionColumns.
+func scanTransaction(row pgx.Row) (model.TransactionDB, error) {
+	t := model.TransactionDB{}
+	var rateDate *time.Time
+	var refundOf *int
+	err := row.Scan(&t.ID, &t.SenderBalanceID, &t.ReceiverBalanceID, &t.Currency, &t.Amount, &t.Date, &t.ReceiverCurrency, &t.ReceiverAmount, &t.Rate, &rateDate,
+		&t.Memo, &t.Reference, &t.Metadata, &refundOf, &t.RefundedAmount)
+	if err != nil {
+		return model.TransactionDB{}, err
 	}
-	return transactions, nil
+	if rateDate != nil {
+		t.RateDate = *rateDate
+	}
+	if refundOf != nil {
+		t.RefundOf = *refundOf
+	}
+	return t, nil
+}
+
+// likeEscaper escapes wildcards of LIKE patterns, backslash is the default escape character of PostgreSQL.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// transactionsQuery builds query of GetTransactions, conditions are added only for set fields of the filter.
+func transactionsQuery(userID int, filter model.TransactionFilter) (string, []interface{}) {
+	args := []interface{}{userID}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	sent := "t.sender_id IN (SELECT id FROM balance WHERE user_id=$1)"
+	received := "t.receiver_id IN (SELECT id FROM balance WHERE user_id=$1)"
+	if filter.CounterpartyBalanceID != 0 {
+		counterparty := arg(filter.CounterpartyBalanceID)
+		sent += " AND t.receiver_id=" + counterparty
+		received += " AND t.sender_id=" + counterparty
+	}
+	var conditions []string
+	switch filter.Direction {
+	case model.DirectionSent:
+		conditions = append(conditions, sent)
+	case model.DirectionReceived:
+		conditions = append(conditions, received)
+	default:
+		conditions = append(conditions, "(("+sent+") OR ("+received+"))")
+	}
+	if filter.After != nil {
+		conditions = append(conditions, `(t."date", t.id) < (`+arg(filter.After.Date)+", "+arg(filter.After.ID)+")")
+	}
+	if !filter.From.IsZero() {
+		conditions = append(conditions, `t."date" >= `+arg(filter.From))
+	}
+	if !filter.To.IsZero() {
+		conditions = append(conditions, `t."date" < `+arg(filter.To))
+	}
+	if filter.MinAmount != 0 {
+		conditions = append(conditions, "t.amount >= "+arg(filter.MinAmount))
+	}
+	if filter.MaxAmount != 0 {
+		conditions = append(conditions, "t.amount <= "+arg(filter.MaxAmount))
+	}
+	if filter.Currency != "" {
+		conditions = append(conditions, "t.currency = "+arg(string(filter.Currency)))
+	}
+	if filter.Reference != "" {
+		conditions = append(conditions, "t.reference = "+arg(filter.Reference))
+	}
+	if filter.Memo != "" {
+		conditions = append(conditions, "t.memo ILIKE "+arg("%"+likeEscaper.Replace(filter.Memo)+"%"))
+	}
+	if filter.MetadataKey != "" {
+		conditions = append(conditions, "t.metadata @> "+arg(map[string]string{filter.MetadataKey: filter.MetadataValue}))
+	}
+
+	query := `SELECT ` + transactionColumns + `
+		FROM "transaction" t WHERE ` + strings.Join(conditions, " AND ") + ` ORDER BY t."date" DESC, t.id DESC`
+	if filter.Limit > 0 {
+		query += " LIMIT " + arg(filter.Limit)
+	}
+	return query, args
 }
 
 // UpdateBalance update couple of balances by applying updateFn. All actions than happen here are included in one transaction.
+// Balances are locked (SELECT ... FOR UPDATE) in ascending ID order and passed to updateFn in that order.
 func (r PostgreBalanceRepo) UpdateBalances(IDs []int, updateFn func(bs []model.BalanceDB) ([]model.BalanceDB, error)) (err error) {
 	tx, err := r.DBConn.BeginTx(context.Background(), pgx.TxOptions{})
 	if err != nil {
@@ -123,10 +241,10 @@ func (r PostgreBalanceRepo) UpdateBalances(IDs []int, updateFn func(bs []model.B
 		return fmt.Errorf("unable to start a transaction; error: %w", err)
 	}
 	defer func() {
-		err = r.finishTx(err, tx)
+		err = finishTx(err, tx)
 	}()
 
-	existingBalances, err := r.getBalances(tx, IDs...)
+	existingBalances, err := r.getBalancesForUpdate(tx, IDs...)
 	if err != nil {
 		return err
 	}
@@ -148,6 +266,10 @@ func (r PostgreBalanceRepo) UpdateBalances(IDs []int, updateFn func(bs []model.B
 	return nil
 }
 
+// MakeTransaction transfers money between balances as one unit - balances are locked, updated by fn and saved together with
+// the new transaction in a single DB transaction, so either everything is committed or nothing is.
+// Rows are locked with SELECT ... FOR UPDATE in ascending ID order, concurrent transfers on the same balances wait for each other
+// instead of failing or deadlocking.
 func (r PostgreBalanceRepo) MakeTransaction(t model.TransactionDB, fn func(t model.TransactionDBFull) (model.TransactionDBFull, error)) (madeTransaction model.TransactionDB, err error) {
 	tx, err := r.DBConn.BeginTx(context.Background(), pgx.TxOptions{})
 	if err != nil {
@@ -155,28 +277,39 @@ func (r PostgreBalanceRepo) MakeTransaction(t model.TransactionDB, fn func(t mod
 		return model.TransactionDB{}, fmt.Errorf("unable to start a transaction; error: %w", err)
 	}
 	defer func() {
-		err = r.finishTx(err, tx)
+		err = finishTx(err, tx)
 	}()
 
-	existingBalances, err := r.getBalances(tx, t.SenderBalanceID, t.ReceiverBalanceID)
+	return r.makeTransaction(tx, t, fn)
+}
+
+// makeTransaction makes transfer of MakeTransaction in tx, so the caller can save other changes together with it.
+func (r PostgreBalanceRepo) makeTransaction(tx pgx.Tx, t model.TransactionDB, fn func(t model.TransactionDBFull) (model.TransactionDBFull, error)) (model.TransactionDB, error) {
+	existingBalances, err := r.getBalancesForUpdate(tx, t.SenderBalanceID, t.ReceiverBalanceID)
 	if err != nil {
 		return model.TransactionDB{}, err
 	}
+	if len(existingBalances) != 2 {
+		log.Errorf("#MakeTransaction(...) failed, found %d balance(s) instead of 2", len(existingBalances))
+		return model.TransactionDB{}, ErrBalancesNotFound
+	}
 
 	var transaction model.TransactionDBFull
 	if existingBalances[0].ID == t.SenderBalanceID {
 		transaction = model.TransactionDBFull{
-			SenderBalance:   existingBalances[0],
-			ReceiverBalance: existingBalances[1],
-			Amount:          t.Amount,
-			Currency:        t.Currency,
+			SenderBalance:      existingBalances[0],
+			ReceiverBalance:    existingBalances[1],
+			Amount:             t.Amount,
+			Currency:           t.Currency,
+			TransactionDetails: t.TransactionDetails,
 		}
 	} else {
 		transaction = model.TransactionDBFull{
-			SenderBalance:   existingBalances[1],
-			ReceiverBalance: existingBalances[0],
-			Amount:          t.Amount,
-			Currency:        t.Currency,
+			SenderBalance:      existingBalances[1],
+			ReceiverBalance:    existingBalances[0],
+			Amount:             t.Amount,
+			Currency:           t.Currency,
+			TransactionDetails: t.TransactionDetails,
 		}
 	}
 
@@ -185,7 +318,7 @@ func (r PostgreBalanceRepo) MakeTransaction(t model.TransactionDB, fn func(t mod
 		return model.TransactionDB{}, err
 	}
 
-	madeTransaction, err = r.createTransaction(tx, transaction)
+	madeTransaction, err := r.createTransaction(tx, transaction)
 	if err != nil {
 		return model.TransactionDB{}, err
 	}
@@ -198,36 +331,119 @@ func (r PostgreBalanceRepo) MakeTransaction(t model.TransactionDB, fn func(t mod
 	return madeTransaction, nil
 }
 
+// RefundTransaction returns money of the transaction with ID to its sender as a new transaction linked to it. The refunded transaction
+// is locked (SELECT ... FOR UPDATE) before both balances, so concurrent refunds of the same transaction are executed one after another
+// and cannot return more than it transferred. fn gets the refunded transaction and the refund from its receiver to its sender balance
+// and returns the refund to be saved, Amount of the refund is added to refunded amount of the refunded transaction.
+func (r PostgreBalanceRepo) RefundTransaction(id int, fn func(refunded model.TransactionDB, refund model.TransactionDBFull) (model.TransactionDBFull, error)) (refund model.TransactionDB, err error) {
+	tx, err := r.DBConn.BeginTx(context.Background(), pgx.TxOptions{})
+	if err != nil {
+		log.Errorf("#RefundTransaction(...) failed, error: %v", err)
+		return model.TransactionDB{}, fmt.Errorf("unable to start a transaction; error: %w", err)
+	}
+	defer func() {
+		err = finishTx(err, tx)
+	}()
+
+	refunded, err := scanTransaction(tx.QueryRow(context.Background(),
+		`SELECT `+transactionColumns+` FROM "transaction" t WHERE t.id=$1 FOR UPDATE`, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return model.TransactionDB{}, ErrRecordNotFound
+		}
+		log.Errorf("#RefundTransaction(...) error while retrieving transaction %d; error %v", id, err)
+		return model.TransactionDB{}, err
+	}
+
+	existingBalances, err := r.getBalancesForUpdate(tx, refunded.ReceiverBalanceID, refunded.SenderBalanceID)
+	if err != nil {
+		return model.TransactionDB{}, err
+	}
+	if len(existingBalances) != 2 {
+		log.Errorf("#RefundTransaction(...) failed, found %d balance(s) instead of 2", len(existingBalances))
+		return model.TransactionDB{}, ErrBalancesNotFound
+	}
+
+	transaction := model.TransactionDBFull{SenderBalance: existingBalances[0], ReceiverBalance: existingBalances[1]}
+	if existingBalances[0].ID != refunded.ReceiverBalanceID {
+		transaction = model.TransactionDBFull{SenderBalance: existingBalances[1], ReceiverBalance: existingBalances[0]}
+	}
+
+	transaction, err = fn(refunded, transaction)
+	if err != nil {
+		return model.TransactionDB{}, err
+	}
+	transaction.RefundOf = refunded.ID
+
+	refund, err = r.createTransaction(tx, transaction)
+	if err != nil {
+		return model.TransactionDB{}, err
+	}
+
+	_, err = tx.Exec(context.Background(), `UPDATE "transaction" SET refunded_amount = refunded_amount + $1 WHERE id = $2`, transaction.Amount, refunded.ID)
+	if err != nil {
+		log.Errorf("#RefundTransaction(...) error while updating refunded amount of transaction %d; error %v", refunded.ID, err)
+		return model.TransactionDB{}, err
+	}
+
+	err = r.saveBalances(tx, []model.BalanceDB{transaction.SenderBalance, transaction.ReceiverBalance})
+	if err != nil {
+		return model.TransactionDB{}, err
+	}
+
+	return refund, nil
+}
+
 func (r PostgreBalanceRepo) createTransaction(tx pgx.Tx, t model.TransactionDBFull) (model.TransactionDB, error) {
 	var tID int
+	var rateDate *time.Time
+	if !t.RateDate.IsZero() {
+		rateDate = &t.RateDate
+	}
+	metadata := t.Metadata
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	var refundOf *int
+	if t.RefundOf != 0 {
+		refundOf = &t.RefundOf
+	}
 	err := tx.QueryRow(context.Background(),
-		"INSERT INTO transaction (sender_id, receiver_id, currency, amount, date) VALUES ($1, $2, $3, $4, $5) RETURNING id",
-		t.SenderBalance.ID, t.ReceiverBalance.ID, string(t.Currency), t.Amount, t.Date).Scan(&tID)
+		`INSERT INTO transaction (sender_id, receiver_id, currency, amount, date, receiver_currency, receiver_amount, rate, rate_date, memo, reference, metadata, refund_of)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) RETURNING id`,
+		t.SenderBalance.ID, t.ReceiverBalance.ID, string(t.Currency), t.Amount, t.Date, string(t.ReceiverCurrency), t.ReceiverAmount, t.Rate, rateDate,
+		t.Memo, t.Reference, metadata, refundOf).Scan(&tID)
 	if err != nil {
 		log.Errorf("#createTransaction(...) error while inserting into transaction table: %v", err)
 		return model.TransactionDB{}, err
 	}
 	transaction := model.TransactionDB{
-		ID:                tID,
-		SenderBalanceID:   t.SenderBalance.ID,
-		ReceiverBalanceID: t.ReceiverBalance.ID,
-		Amount:            t.Amount,
-		Currency:          t.Currency,
-		Date:              t.Date,
+		ID:                 tID,
+		SenderBalanceID:    t.SenderBalance.ID,
+		ReceiverBalanceID:  t.ReceiverBalance.ID,
+		Amount:             t.Amount,
+		Currency:           t.Currency,
+		Date:               t.Date,
+		ReceiverAmount:     t.ReceiverAmount,
+		ReceiverCurrency:   t.ReceiverCurrency,
+		Rate:               t.Rate,
+		RateDate:           t.RateDate,
+		TransactionDetails: t.TransactionDetails,
+		RefundOf:           t.RefundOf,
 	}
 
 	_, err = tx.Exec(context.Background(),
 		"INSERT INTO balance_transaction (balance_id, transaction_id) VALUES ($1, $2)",
 		t.SenderBalance.ID, tID)
 	if err != nil {
-		log.Errorf("#createTransaction(...) error while inserting into balance_transaction table for balance_i %s: %v", t.SenderBalance.ID, err)
+		log.Errorf("#createTransaction(...) error while inserting into balance_transaction table for balance_id %d: %v", t.SenderBalance.ID, err)
 		return model.TransactionDB{}, err
 	}
 	_, err = tx.Exec(context.Background(),
 		"INSERT INTO balance_transaction (balance_id, transaction_id) VALUES ($1, $2)",
 		t.ReceiverBalance.ID, tID)
 	if err != nil {
-		log.Errorf("#createTransaction(...) error while inserting into balance_transaction table for balance_i %s: %v", t.ReceiverBalance.ID, err)
+		log.Errorf("#createTransaction(...) error while inserting into balance_transaction table for balance_id %d: %v", t.ReceiverBalance.ID, err)
 		return model.TransactionDB{}, err
 	}
 
@@ -242,33 +458,42 @@ func toArgs(IDs []int) []interface{} {
 	return ret
 }
 
-func (r PostgreBalanceRepo) getBalances(tx pgx.Tx, IDs ...int) ([]model.BalanceDB, error) {
-	balances := []model.BalanceDB{}
-	query := "SELECT id, currency, balance, locked, user_id FROM balance WHERE id IN ("
+// getBalancesForUpdate locks balances until the end of tx. IDs are sorted, so concurrent transactions always lock rows in the same order.
+func (r PostgreBalanceRepo) getBalancesForUpdate(tx pgx.Tx, IDs ...int) ([]model.BalanceDB, error) {
+	sortedIDs := append([]int{}, IDs...)
+	sort.Ints(sortedIDs)
+	return r.queryBalances(tx, balancesQuery(sortedIDs)+" ORDER BY id FOR UPDATE", sortedIDs)
+}
+
+func balancesQuery(IDs []int) string {
+	query := "SELECT id, currency, balance, held, user_id FROM balance WHERE id IN ("
 	for i := range IDs {
 		query += " $" + strconv.Itoa(i+1)
 		if i < len(IDs)-1 {
 			query += ","
 		}
 	}
-	query += ")"
+	return query + ")"
+}
 
+func (r PostgreBalanceRepo) queryBalances(tx pgx.Tx, query string, IDs []int) ([]model.BalanceDB, error) {
+	balances := []model.BalanceDB{}
 	rows, err := tx.Query(context.Background(), query, toArgs(IDs)...)
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			log.Infof("no records for balances with IDs %s", IDs)
+			log.Infof("no records for balances with IDs %v", IDs)
 			return make([]model.BalanceDB, 0), nil
 		}
-		log.Errorf("#getBalances(...) error while retrieving balances with IDs %s; error %v", IDs, err)
+		log.Errorf("#getBalances(...) error while retrieving balances with IDs %v; error %v", IDs, err)
 		return nil, err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
 		tmp := model.BalanceDB{}
-		err = rows.Scan(&tmp.ID, &tmp.Currency, &tmp.Balance, &tmp.Locked, &tmp.UserID)
+		err = rows.Scan(&tmp.ID, &tmp.Currency, &tmp.Balance, &tmp.Held, &tmp.UserID)
 		if err != nil {
-			log.Errorf("#getBalances(...) error while scanning balances with IDs %s; error %v", IDs, err)
+			log.Errorf("#getBalances(...) error while scanning balances with IDs %v; error %v", IDs, err)
 			return nil, err
 		}
 		balances = append(balances, tmp)
@@ -277,9 +502,10 @@ func (r PostgreBalanceRepo) getBalances(tx pgx.Tx, IDs ...int) ([]model.BalanceD
 }
 
 func (r PostgreBalanceRepo) saveBalance(tx pgx.Tx, balance model.BalanceDB) error {
-	_, err := tx.Exec(context.Background(), "UPDATE balance SET balance=$1, locked=$2 WHERE id=$3", balance.Balance, balance.Locked, balance.ID)
+	_, err := tx.Exec(context.Background(), "UPDATE balance SET balance=$1, held=$2 WHERE id=$3",
+		balance.Balance, balance.Held, balance.ID)
 	if err != nil {
-		log.Errorf("#saveBalance(...) error: %w", err)
+		log.Errorf("#saveBalance(...) error: %v", err)
 		return err
 	}
 	return nil
@@ -294,19 +520,3 @@ func (r PostgreBalanceRepo) saveBalances(tx pgx.Tx, balance []model.BalanceDB) e
 	}
 	return nil
 }
-
-func (r PostgreBalanceRepo) finishTx(err error, tx pgx.Tx) error {
-	if err != nil {
-		if rollbackErr := tx.Rollback(context.Background()); rollbackErr != nil {
-			log.Errorf("#finishTx(...) failed when rollback, error: %v", err)
-			return fmt.Errorf("unable to rollback a transaction; error: %v", err)
-		}
-
-		return err
-	}
-	if commitErr := tx.Commit(context.Background()); commitErr != nil {
-		log.Errorf("finishTx(...) failed when commiting, error: %v", err)
-		return fmt.Errorf("unable to commit a transaction; error: %v", err)
-	}
-	return nil
-}
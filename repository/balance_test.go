@@ -23,15 +23,15 @@ func TestGetList(t *testing.T) {
 	}
 
 	want := []model.BalanceDB{
-		{ID: 1, Currency: "SGD", Balance: 1000, UserID: 1},
-		{ID: 2, Currency: "SGD", Balance: 25.25, UserID: 1},
+		{ID: 1, Currency: "SGD", Balance: model.MustParseAmount("1000"), UserID: 1},
+		{ID: 2, Currency: "SGD", Balance: model.MustParseAmount("25.25"), UserID: 1},
 	}
 
-	mockPool.ExpectQuery("SELECT id, currency, balance, user_id FROM balance WHERE user_id=$1").
+	mockPool.ExpectQuery("SELECT id, currency, balance, held, user_id FROM balance WHERE user_id=$1").
 		WithArgs(1).
-		WillReturnRows(pgxmock.NewRows([]string{"id", "currency", "balance", "user_id"}).
-			AddRow(want[0].ID, want[0].Currency, want[0].Balance, want[0].UserID).
-			AddRow(want[1].ID, want[1].Currency, want[1].Balance, want[1].UserID))
+		WillReturnRows(pgxmock.NewRows([]string{"id", "currency", "balance", "held", "user_id"}).
+			AddRow(want[0].ID, want[0].Currency, want[0].Balance.String(), want[0].Held, want[0].UserID).
+			AddRow(want[1].ID, want[1].Currency, want[1].Balance.String(), want[1].Held, want[1].UserID))
 
 	got, err := mockRepo.GetList(1)
 	if err != nil {
@@ -49,7 +49,7 @@ func TestGetList(t *testing.T) {
 	}
 }
 
-func TestGetTransactions(t *testing.T) {
+func TestCreateBalance(t *testing.T) {
 	mockPool, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherEqual))
 	if err != nil {
 		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
@@ -60,38 +60,197 @@ func TestGetTransactions(t *testing.T) {
 		DBConn: dbMockPool{mockPool},
 	}
 
-	foundBalance := model.Balance{
-		ID: 1, Currency: "SGD", Balance: 1000, UserID: 1, Locked: false,
+	query := "INSERT INTO balance (currency, balance, user_id) VALUES ($1, $2, $3) ON CONFLICT (user_id, currency) DO NOTHING RETURNING id"
+	mockPool.ExpectQuery(query).
+		WithArgs("USD", model.Amount(0), 1).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(5))
+	mockPool.ExpectQuery(query).
+		WithArgs("SGD", model.Amount(0), 1).
+		WillReturnError(pgx.ErrNoRows)
+
+	got, err := mockRepo.CreateBalance(1, model.USD)
+	if err != nil {
+		t.Errorf("error was not expected while creating balance: %s", err)
 	}
-	want := []model.TransactionDB{
-		{ID: 1, SenderBalanceID: 1, ReceiverBalanceID: 2, Currency: "SGD", Amount: 3.99, Date: time.Now()},
-		{ID: 2, SenderBalanceID: 1, ReceiverBalanceID: 4, Currency: "SGD", Amount: 56.85, Date: time.Now()},
+	want := model.BalanceDB{ID: 5, Currency: model.USD, UserID: 1}
+	if got != want {
+		t.Errorf("error got: %+v want: %+v", got, want)
 	}
 
-	mockPool.ExpectBeginTx(pgx.TxOptions{})
-	mockPool.ExpectQuery("SELECT id, currency, balance, locked, user_id FROM balance WHERE id IN ( $1)").
-		WithArgs(1).
-		WillReturnRows(pgxmock.NewRows([]string{"id", "currency", "balance", "locked", "user_id"}).
-			AddRow(foundBalance.ID, foundBalance.Currency, foundBalance.Balance, foundBalance.Locked, foundBalance.UserID))
-	mockPool.ExpectQuery(`select bt.transaction_id, t.sender_id, t.receiver_id, t.currency, t.amount, t."date" 
-			from balance_transaction bt
-				left join "transaction" t ON bt.transaction_id = t.id
-				where bt.balance_id IN ( $1)`).
-		WithArgs(1).
-		WillReturnRows(pgxmock.NewRows([]string{"bt.transaction_id", "t.sender_id", "t.receiver_id", "t.currency", "t.amount", `t."date"`}).
-			AddRow(want[0].ID, want[0].SenderBalanceID, want[0].ReceiverBalanceID, want[0].Currency, want[0].Amount, want[0].Date).
-			AddRow(want[1].ID, want[1].SenderBalanceID, want[1].ReceiverBalanceID, want[1].Currency, want[1].Amount, want[1].Date))
-	mockPool.ExpectCommit()
+	_, err = mockRepo.CreateBalance(1, model.SGD)
+	if err != ErrBalanceExists {
+		t.Errorf("error got: %v want: %v", err, ErrBalanceExists)
+	}
 
-	got, err := mockRepo.GetTransactions(1)
+	if err := mockPool.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetTransactions(t *testing.T) {
+	mockPool, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockPool.Close()
+
+	mockRepo := PostgreBalanceRepo{
+		DBConn: dbMockPool{mockPool},
+	}
+
+	rateDate := time.Now()
+	want := []model.TransactionDB{
+		{ID: 2, SenderBalanceID: 1, ReceiverBalanceID: 4, Currency: "SGD", Amount: model.MustParseAmount("56.85"), Date: time.Now(),
+			ReceiverCurrency: "USD", ReceiverAmount: model.MustParseAmount("42.11"), Rate: model.MustParseRate("0.7407"), RateDate: rateDate,
+			TransactionDetails: model.TransactionDetails{Memo: "Dinner", Reference: "INV-1", Metadata: map[string]string{"orderId": "42"}},
+			RefundedAmount:     model.MustParseAmount("10")},
+		{ID: 1, SenderBalanceID: 1, ReceiverBalanceID: 2, Currency: "SGD", Amount: model.MustParseAmount("3.99"), Date: time.Now(),
+			ReceiverCurrency: "SGD", ReceiverAmount: model.MustParseAmount("3.99"), TransactionDetails: model.TransactionDetails{Metadata: map[string]string{}}},
+	}
+
+	mockPool.ExpectQuery(`SELECT t.id, t.sender_id, t.receiver_id, t.currency, t.amount, t."date", t.receiver_currency, t.receiver_amount, t.rate, t.rate_date,
+		t.memo, t.reference, t.metadata, t.refund_of, t.refunded_amount
+		FROM "transaction" t WHERE ((t.sender_id IN (SELECT id FROM balance WHERE user_id=$1)) OR (t.receiver_id IN (SELECT id FROM balance WHERE user_id=$1)))
+		ORDER BY t."date" DESC, t.id DESC LIMIT $2`).
+		WithArgs(1, 50).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "sender_id", "receiver_id", "currency", "amount", "date", "receiver_currency", "receiver_amount", "rate", "rate_date", "memo", "reference", "metadata", "refund_of", "refunded_amount"}).
+			AddRow(want[0].ID, want[0].SenderBalanceID, want[0].ReceiverBalanceID, want[0].Currency, want[0].Amount, want[0].Date, want[0].ReceiverCurrency, want[0].ReceiverAmount, "0.740700", &rateDate,
+				want[0].Memo, want[0].Reference, want[0].Metadata, nil, want[0].RefundedAmount).
+			AddRow(want[1].ID, want[1].SenderBalanceID, want[1].ReceiverBalanceID, want[1].Currency, want[1].Amount, want[1].Date, want[1].ReceiverCurrency, want[1].ReceiverAmount, nil, nil,
+				"", "", map[string]string{}, nil, model.Amount(0)))
+
+	got, err := mockRepo.GetTransactions(1, model.TransactionFilter{Limit: 50})
 	if err != nil {
 		t.Errorf("error was not expected while retrieving transactions: %s", err)
 	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("transactions got: %+v want: %+v", got, want)
+	}
 
-	for i := range got {
-		if !reflect.DeepEqual(got[i], want[i]) {
-			t.Errorf("error got: %+v want: %+v", got[i], want[i])
-		}
+	if err := mockPool.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetTransactionsWithFilter(t *testing.T) {
+	mockPool, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockPool.Close()
+
+	mockRepo := PostgreBalanceRepo{
+		DBConn: dbMockPool{mockPool},
+	}
+
+	cursor := model.TransactionCursor{Date: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC), ID: 42}
+	from := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	filter := model.TransactionFilter{
+		After:                 &cursor,
+		Limit:                 11,
+		From:                  from,
+		MinAmount:             model.MustParseAmount("10"),
+		Direction:             model.DirectionSent,
+		CounterpartyBalanceID: 2,
+		Currency:              model.SGD,
+		Reference:             "INV-1",
+		Memo:                  "50%_off",
+		MetadataKey:           "orderId",
+		MetadataValue:         "42",
+	}
+
+	mockPool.ExpectQuery(`SELECT t.id, t.sender_id, t.receiver_id, t.currency, t.amount, t."date", t.receiver_currency, t.receiver_amount, t.rate, t.rate_date,
+		t.memo, t.reference, t.metadata, t.refund_of, t.refunded_amount
+		FROM "transaction" t WHERE t.sender_id IN (SELECT id FROM balance WHERE user_id=$1) AND t.receiver_id=$2
+		AND (t."date", t.id) < ($3, $4) AND t."date" >= $5 AND t.amount >= $6 AND t.currency = $7
+		AND t.reference = $8 AND t.memo ILIKE $9 AND t.metadata @> $10
+		ORDER BY t."date" DESC, t.id DESC LIMIT $11`).
+		WithArgs(1, 2, cursor.Date, 42, from, model.MustParseAmount("10"), "SGD", "INV-1", `%50\%\_off%`, map[string]string{"orderId": "42"}, 11).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "sender_id", "receiver_id", "currency", "amount", "date", "receiver_currency", "receiver_amount", "rate", "rate_date", "memo", "reference", "metadata", "refund_of", "refunded_amount"}))
+
+	got, err := mockRepo.GetTransactions(1, filter)
+	if err != nil || len(got) != 0 {
+		t.Errorf("transactions got: %+v (%v); want none", got, err)
+	}
+
+	if err := mockPool.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetBalance(t *testing.T) {
+	mockPool, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockPool.Close()
+
+	mockRepo := PostgreBalanceRepo{
+		DBConn: dbMockPool{mockPool},
+	}
+	lastTransactionAt := time.Now()
+	var noTransaction *time.Time
+
+	query := `SELECT b.id, b.currency, b.balance, b.held, b.user_id,
+		(SELECT max(t."date") FROM "transaction" t WHERE t.sender_id = b.id OR t.receiver_id = b.id)
+		FROM balance b WHERE b.id=$1 AND b.user_id=$2`
+	columns := []string{"id", "currency", "balance", "held", "user_id", "max"}
+	mockPool.ExpectQuery(query).WithArgs(1, 1).
+		WillReturnRows(pgxmock.NewRows(columns).AddRow(1, model.SGD, model.MustParseAmount("1000"), model.MustParseAmount("150"), 1, &lastTransactionAt))
+	mockPool.ExpectQuery(query).WithArgs(3, 1).
+		WillReturnRows(pgxmock.NewRows(columns).AddRow(3, model.USD, model.Amount(0), model.Amount(0), 1, noTransaction))
+	// balance of other user
+	mockPool.ExpectQuery(query).WithArgs(2, 1).
+		WillReturnError(pgx.ErrNoRows)
+
+	b, last, err := mockRepo.GetBalance(1, 1)
+	want := model.BalanceDB{ID: 1, Currency: model.SGD, Balance: model.MustParseAmount("1000"), Held: model.MustParseAmount("150"), UserID: 1}
+	if err != nil || b != want || !last.Equal(lastTransactionAt) {
+		t.Errorf("balance got: %+v, last transaction at %s (%v); want: %+v, last transaction at %s", b, last, err, want, lastTransactionAt)
+	}
+	if _, last, err = mockRepo.GetBalance(3, 1); err != nil || !last.IsZero() {
+		t.Errorf("last transaction of new balance got: %s (%v); want zero time", last, err)
+	}
+	if _, _, err = mockRepo.GetBalance(2, 1); err != ErrRecordNotFound {
+		t.Errorf("error got: %v; want: %v", err, ErrRecordNotFound)
+	}
+
+	if err := mockPool.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetTransaction(t *testing.T) {
+	mockPool, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockPool.Close()
+
+	mockRepo := PostgreBalanceRepo{
+		DBConn: dbMockPool{mockPool},
+	}
+	want := model.TransactionDB{ID: 5, SenderBalanceID: 2, ReceiverBalanceID: 1, Currency: model.SGD, Amount: model.MustParseAmount("3.99"),
+		Date: time.Now(), ReceiverCurrency: model.SGD, ReceiverAmount: model.MustParseAmount("3.99"), TransactionDetails: model.TransactionDetails{Metadata: map[string]string{}},
+		RefundOf: 4}
+
+	query := `SELECT t.id, t.sender_id, t.receiver_id, t.currency, t.amount, t."date", t.receiver_currency, t.receiver_amount, t.rate, t.rate_date,
+		t.memo, t.reference, t.metadata, t.refund_of, t.refunded_amount
+		FROM "transaction" t WHERE t.id=$1
+		AND (t.sender_id IN (SELECT id FROM balance WHERE user_id=$2) OR t.receiver_id IN (SELECT id FROM balance WHERE user_id=$2))`
+	mockPool.ExpectQuery(query).WithArgs(5, 1).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "sender_id", "receiver_id", "currency", "amount", "date", "receiver_currency", "receiver_amount", "rate", "rate_date", "memo", "reference", "metadata", "refund_of", "refunded_amount"}).
+			AddRow(want.ID, want.SenderBalanceID, want.ReceiverBalanceID, want.Currency, want.Amount, want.Date, want.ReceiverCurrency, want.ReceiverAmount, nil, nil,
+				"", "", map[string]string{}, &want.RefundOf, model.Amount(0)))
+	mockPool.ExpectQuery(query).WithArgs(5, 3).
+		WillReturnError(pgx.ErrNoRows)
+
+	got, err := mockRepo.GetTransaction(5, 1)
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("transaction got: %+v (%v); want: %+v", got, err, want)
+	}
+	if _, err = mockRepo.GetTransaction(5, 3); err != ErrRecordNotFound {
+		t.Errorf("error got: %v; want: %v", err, ErrRecordNotFound)
 	}
 
 	if err := mockPool.ExpectationsWereMet(); err != nil {
@@ -111,27 +270,27 @@ func TestUpdateBalances(t *testing.T) {
 	}
 
 	found := []model.Balance{
-		{ID: 1, Currency: "SGD", Balance: 1000, UserID: 1, Locked: false},
-		{ID: 2, Currency: "SGD", Balance: 25.25, UserID: 1, Locked: false},
+		{ID: 1, Currency: "SGD", Balance: model.MustParseAmount("1000"), UserID: 1},
+		{ID: 2, Currency: "SGD", Balance: model.MustParseAmount("25.25"), UserID: 1},
 	}
 
 	mockPool.ExpectBeginTx(pgx.TxOptions{})
-	mockPool.ExpectQuery("SELECT id, currency, balance, locked, user_id FROM balance WHERE id IN ( $1, $2)").
+	mockPool.ExpectQuery("SELECT id, currency, balance, held, user_id FROM balance WHERE id IN ( $1, $2) ORDER BY id FOR UPDATE").
 		WithArgs(1, 2).
-		WillReturnRows(pgxmock.NewRows([]string{"id", "currency", "balance", "locked", "user_id"}).
-			AddRow(found[0].ID, found[0].Currency, found[0].Balance, found[0].Locked, found[0].UserID).
-			AddRow(found[1].ID, found[1].Currency, found[1].Balance, found[1].Locked, found[1].UserID))
-	mockPool.ExpectExec("UPDATE balance SET balance=$1, locked=$2 WHERE id=$3").
-		WithArgs(found[0].Balance, true, found[0].ID).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "currency", "balance", "held", "user_id"}).
+			AddRow(found[0].ID, found[0].Currency, found[0].Balance, found[0].Held, found[0].UserID).
+			AddRow(found[1].ID, found[1].Currency, found[1].Balance, found[1].Held, found[1].UserID))
+	mockPool.ExpectExec("UPDATE balance SET balance=$1, held=$2 WHERE id=$3").
+		WithArgs(found[0].Balance, model.MustParseAmount("10"), found[0].ID).
 		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
-	mockPool.ExpectExec("UPDATE balance SET balance=$1, locked=$2 WHERE id=$3").
-		WithArgs(found[1].Balance, true, found[1].ID).
+	mockPool.ExpectExec("UPDATE balance SET balance=$1, held=$2 WHERE id=$3").
+		WithArgs(found[1].Balance, model.MustParseAmount("20"), found[1].ID).
 		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
 	mockPool.ExpectCommit()
 
 	err = mockRepo.UpdateBalances([]int{1, 2}, func(bs []model.BalanceDB) ([]model.BalanceDB, error) {
-		bs[0].Locked = true
-		bs[1].Locked = true
+		bs[0].Held = model.MustParseAmount("10")
+		bs[1].Held = model.MustParseAmount("20")
 		return bs, nil
 	})
 	if err != nil {
@@ -155,27 +314,30 @@ func TestMakeTransaction(t *testing.T) {
 	}
 
 	transaction := model.TransactionDB{
-		SenderBalanceID:   1,
-		ReceiverBalanceID: 2,
-		Currency:          "SGD",
-		Amount:            11.49,
+		SenderBalanceID:    1,
+		ReceiverBalanceID:  2,
+		Currency:           "SGD",
+		Amount:             model.MustParseAmount("11.49"),
+		TransactionDetails: model.TransactionDetails{Memo: "Dinner"},
 	}
 	beforeTransaction := transaction.Date
 
 	found := []model.Balance{
-		{ID: 1, Currency: "SGD", Balance: 1000, UserID: 1, Locked: true},
-		{ID: 2, Currency: "SGD", Balance: 25.25, UserID: 1, Locked: true},
+		{ID: 1, Currency: "SGD", Balance: model.MustParseAmount("1000"), UserID: 1},
+		{ID: 2, Currency: "SGD", Balance: model.MustParseAmount("25.25"), UserID: 1},
 	}
 
 	mockPool.ExpectBeginTx(pgx.TxOptions{})
-	mockPool.ExpectQuery("SELECT id, currency, balance, locked, user_id FROM balance WHERE id IN ( $1, $2)").
+	mockPool.ExpectQuery("SELECT id, currency, balance, held, user_id FROM balance WHERE id IN ( $1, $2) ORDER BY id FOR UPDATE").
 		WithArgs(1, 2).
-		WillReturnRows(pgxmock.NewRows([]string{"id", "currency", "balance", "locked", "user_id"}).
-			AddRow(found[0].ID, found[0].Currency, found[0].Balance, found[0].Locked, found[0].UserID).
-			AddRow(found[1].ID, found[1].Currency, found[1].Balance, found[1].Locked, found[1].UserID))
-
-	mockPool.ExpectQuery("INSERT INTO transaction (sender_id, receiver_id, currency, amount, date) VALUES ($1, $2, $3, $4, $5) RETURNING id").
-		WithArgs(transaction.SenderBalanceID, transaction.ReceiverBalanceID, string(transaction.Currency), transaction.Amount, AnyTime{}).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "currency", "balance", "held", "user_id"}).
+			AddRow(found[0].ID, found[0].Currency, found[0].Balance, found[0].Held, found[0].UserID).
+			AddRow(found[1].ID, found[1].Currency, found[1].Balance, found[1].Held, found[1].UserID))
+
+	mockPool.ExpectQuery(`INSERT INTO transaction (sender_id, receiver_id, currency, amount, date, receiver_currency, receiver_amount, rate, rate_date, memo, reference, metadata, refund_of)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) RETURNING id`).
+		WithArgs(transaction.SenderBalanceID, transaction.ReceiverBalanceID, string(transaction.Currency), transaction.Amount, AnyTime{}, string(transaction.Currency), transaction.Amount, model.Rate(0), (*time.Time)(nil),
+			"Dinner", "", map[string]string{}, (*int)(nil)).
 		WillReturnRows(pgxmock.NewRows([]string{"id"}).
 			AddRow(1))
 	mockPool.ExpectExec("INSERT INTO balance_transaction (balance_id, transaction_id) VALUES ($1, $2)").
@@ -185,11 +347,11 @@ func TestMakeTransaction(t *testing.T) {
 		WithArgs(found[1].ID, 1).
 		WillReturnResult(pgxmock.NewResult("INSERT", 1))
 
-	mockPool.ExpectExec("UPDATE balance SET balance=$1, locked=$2 WHERE id=$3").
-		WithArgs(found[0].Balance-transaction.Amount, true, found[0].ID).
+	mockPool.ExpectExec("UPDATE balance SET balance=$1, held=$2 WHERE id=$3").
+		WithArgs(found[0].Balance-transaction.Amount, model.Amount(0), found[0].ID).
 		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
-	mockPool.ExpectExec("UPDATE balance SET balance=$1, locked=$2 WHERE id=$3").
-		WithArgs(found[1].Balance+transaction.Amount, true, found[1].ID).
+	mockPool.ExpectExec("UPDATE balance SET balance=$1, held=$2 WHERE id=$3").
+		WithArgs(found[1].Balance+transaction.Amount, model.Amount(0), found[1].ID).
 		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
 	mockPool.ExpectCommit()
 
@@ -209,12 +371,15 @@ func TestMakeTransaction(t *testing.T) {
 		}
 		transactionFull.Make()
 		return model.TransactionDBFull{
-			ID:              transactionFull.ID,
-			SenderBalance:   model.BalanceDB(*transactionFull.SenderBalance),
-			ReceiverBalance: model.BalanceDB(*transactionFull.ReceiverBalance),
-			Amount:          transactionFull.Amount,
-			Currency:        transactionFull.Currency,
-			Date:            transactionFull.Date,
+			ID:                 transactionFull.ID,
+			SenderBalance:      model.BalanceDB(*transactionFull.SenderBalance),
+			ReceiverBalance:    model.BalanceDB(*transactionFull.ReceiverBalance),
+			Amount:             transactionFull.Amount,
+			Currency:           transactionFull.Currency,
+			Date:               transactionFull.Date,
+			ReceiverAmount:     transactionFull.ReceiverAmount,
+			ReceiverCurrency:   transactionFull.ReceiverCurrency,
+			TransactionDetails: tFull.TransactionDetails,
 		}, nil
 	})
 	if err != nil {
@@ -230,6 +395,132 @@ func TestMakeTransaction(t *testing.T) {
 	}
 }
 
+func TestMakeTransactionLockOrder(t *testing.T) {
+	mockPool, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockPool.Close()
+
+	mockRepo := PostgreBalanceRepo{
+		DBConn: dbMockPool{mockPool},
+	}
+
+	// sender has higher ID than receiver - rows must be still locked in ascending ID order
+	transaction := model.TransactionDB{
+		SenderBalanceID:   7,
+		ReceiverBalanceID: 3,
+		Currency:          "SGD",
+		Amount:            model.MustParseAmount("11.49"),
+	}
+
+	mockPool.ExpectBeginTx(pgx.TxOptions{})
+	mockPool.ExpectQuery("SELECT id, currency, balance, held, user_id FROM balance WHERE id IN ( $1, $2) ORDER BY id FOR UPDATE").
+		WithArgs(3, 7).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "currency", "balance", "held", "user_id"}).
+			AddRow(3, model.SGD, model.MustParseAmount("25.25"), model.Amount(0), 2))
+	mockPool.ExpectRollback()
+
+	called := false
+	_, err = mockRepo.MakeTransaction(transaction, func(tFull model.TransactionDBFull) (model.TransactionDBFull, error) {
+		called = true
+		return tFull, nil
+	})
+	if err != ErrBalancesNotFound {
+		t.Errorf("error got: %v; want: %v", err, ErrBalancesNotFound)
+	}
+	if called {
+		t.Errorf("transaction function must not be called when balance is missing")
+	}
+
+	if err := mockPool.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRefundTransaction(t *testing.T) {
+	mockPool, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockPool.Close()
+
+	mockRepo := PostgreBalanceRepo{
+		DBConn: dbMockPool{mockPool},
+	}
+
+	refunded := model.TransactionDB{ID: 9, SenderBalanceID: 1, ReceiverBalanceID: 2, Currency: model.SGD, Amount: model.MustParseAmount("20"), Date: time.Now(),
+		ReceiverCurrency: model.SGD, ReceiverAmount: model.MustParseAmount("20"), TransactionDetails: model.TransactionDetails{Metadata: map[string]string{}},
+		RefundedAmount: model.MustParseAmount("5")}
+	query := `SELECT t.id, t.sender_id, t.receiver_id, t.currency, t.amount, t."date", t.receiver_currency, t.receiver_amount, t.rate, t.rate_date,
+		t.memo, t.reference, t.metadata, t.refund_of, t.refunded_amount FROM "transaction" t WHERE t.id=$1 FOR UPDATE`
+
+	mockPool.ExpectBeginTx(pgx.TxOptions{})
+	mockPool.ExpectQuery(query).WithArgs(9).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "sender_id", "receiver_id", "currency", "amount", "date", "receiver_currency", "receiver_amount", "rate", "rate_date", "memo", "reference", "metadata", "refund_of", "refunded_amount"}).
+			AddRow(refunded.ID, refunded.SenderBalanceID, refunded.ReceiverBalanceID, refunded.Currency, refunded.Amount, refunded.Date, refunded.ReceiverCurrency, refunded.ReceiverAmount, nil, nil,
+				"", "", map[string]string{}, nil, refunded.RefundedAmount))
+	mockPool.ExpectQuery("SELECT id, currency, balance, held, user_id FROM balance WHERE id IN ( $1, $2) ORDER BY id FOR UPDATE").
+		WithArgs(1, 2).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "currency", "balance", "held", "user_id"}).
+			AddRow(1, model.SGD, model.MustParseAmount("100"), model.Amount(0), 1).
+			AddRow(2, model.SGD, model.MustParseAmount("50"), model.Amount(0), 2))
+	mockPool.ExpectQuery(`INSERT INTO transaction (sender_id, receiver_id, currency, amount, date, receiver_currency, receiver_amount, rate, rate_date, memo, reference, metadata, refund_of)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) RETURNING id`).
+		WithArgs(2, 1, "SGD", model.MustParseAmount("15"), AnyTime{}, "SGD", model.MustParseAmount("15"), model.Rate(0), (*time.Time)(nil),
+			"", "", map[string]string{}, &refunded.ID).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(10))
+	mockPool.ExpectExec("INSERT INTO balance_transaction (balance_id, transaction_id) VALUES ($1, $2)").
+		WithArgs(2, 10).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mockPool.ExpectExec("INSERT INTO balance_transaction (balance_id, transaction_id) VALUES ($1, $2)").
+		WithArgs(1, 10).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mockPool.ExpectExec(`UPDATE "transaction" SET refunded_amount = refunded_amount + $1 WHERE id = $2`).
+		WithArgs(model.MustParseAmount("15"), 9).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mockPool.ExpectExec("UPDATE balance SET balance=$1, held=$2 WHERE id=$3").
+		WithArgs(model.MustParseAmount("35"), model.Amount(0), 2).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mockPool.ExpectExec("UPDATE balance SET balance=$1, held=$2 WHERE id=$3").
+		WithArgs(model.MustParseAmount("115"), model.Amount(0), 1).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mockPool.ExpectCommit()
+
+	got, err := mockRepo.RefundTransaction(9, func(original model.TransactionDB, refund model.TransactionDBFull) (model.TransactionDBFull, error) {
+		if !reflect.DeepEqual(original, refunded) {
+			t.Errorf("refunded transaction got: %+v; want: %+v", original, refunded)
+		}
+		if refund.SenderBalance.ID != 2 || refund.ReceiverBalance.ID != 1 {
+			t.Errorf("refund must be from balance 2 to balance 1, got: %+v", refund)
+		}
+		amount := original.ReceiverAmount - original.RefundedAmount
+		refund.Amount, refund.Currency = amount, refund.SenderBalance.Currency
+		refund.ReceiverAmount, refund.ReceiverCurrency = amount, refund.ReceiverBalance.Currency
+		refund.SenderBalance.Balance -= amount
+		refund.ReceiverBalance.Balance += amount
+		refund.Date = time.Now()
+		return refund, nil
+	})
+	if err != nil {
+		t.Errorf("error was not expected while refunding a transaction: %s", err)
+	}
+	if got.ID != 10 || got.RefundOf != 9 || got.Amount != model.MustParseAmount("15") {
+		t.Errorf("refund got: %+v", got)
+	}
+
+	mockPool.ExpectBeginTx(pgx.TxOptions{})
+	mockPool.ExpectQuery(query).WithArgs(11).WillReturnError(pgx.ErrNoRows)
+	mockPool.ExpectRollback()
+	if _, err = mockRepo.RefundTransaction(11, nil); err != ErrRecordNotFound {
+		t.Errorf("error got: %v; want: %v", err, ErrRecordNotFound)
+	}
+
+	if err := mockPool.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
 type AnyTime struct{}
 
 // Match satisfies sqlmock.Argument interface
@@ -249,7 +540,7 @@ func validateTransactions(got, want model.TransactionDB, beforeTransaction time.
 		return false, fmt.Sprintf("ReceiverBalanceID got: %d; want: %d", got.ReceiverBalanceID, want.ReceiverBalanceID)
 	}
 	if got.Amount != want.Amount {
-		return false, fmt.Sprintf("Amount got: %f; want: %f", got.Amount, want.Amount)
+		return false, fmt.Sprintf("Amount got: %s; want: %s", got.Amount, want.Amount)
 	}
 	if got.Currency != want.Currency {
 		return false, fmt.Sprintf("Currency got: %s; want: %s", got.Currency, want.Currency)
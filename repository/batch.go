@@ -0,0 +1,312 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/labstack/gommon/log"
+	"zuzanna.com/walletapi/model"
+)
+
+var ErrBatchItemProcessed = errors.New("batch item was already processed")
+
+// BatchRepo keeps batches of transfers, it is implemented by PostgreBalanceRepo as items are completed in the same
+// DB transaction as their transfers.
+type BatchRepo interface {
+	// CreateBatch saves the batch with its items and returns its ID. The batch is claimed until claimedUntil unless it is zero,
+	// see ClaimPendingBatches.
+	CreateBatch(b model.BatchDB, claimedUntil time.Time) (int, error)
+	// GetBatch returns batch of the user with its items. Returns ErrRecordNotFound when there is no such batch of the user.
+	GetBatch(id, userID int) (model.BatchDB, error)
+	// ClaimPendingBatches returns at most limit pending batches with their items, which are not claimed by others,
+	// and claims them until claimedUntil.
+	ClaimPendingBatches(now, claimedUntil time.Time, limit int) ([]model.BatchDB, error)
+	// MakeBatchTransactions transfers items of the batch together, see PostgreBalanceRepo.MakeBatchTransactions.
+	MakeBatchTransactions(b model.BatchDB, items []model.BatchItemDB,
+		fn func(item model.BatchItemDB, t model.TransactionDBFull) (model.TransactionDBFull, error)) ([]model.BatchItemDB, error)
+	// SaveBatch saves status of the processed batch with its failed and skipped items and releases the claim.
+	SaveBatch(b model.BatchDB) error
+}
+
+// BatchItemError is returned by MakeBatchTransactions when the item with Index cannot be transferred.
+type BatchItemError struct {
+	Index int
+	Err   error
+}
+
+func (e BatchItemError) Error() string {
+	return fmt.Sprintf("transfer of batch item %d failed; error: %v", e.Index, e.Err)
+}
+
+func (e BatchItemError) Unwrap() error {
+	return e.Err
+}
+
+func (r PostgreBalanceRepo) CreateBatch(b model.BatchDB, claimedUntil time.Time) (id int, err error) {
+	tx, err := r.DBConn.BeginTx(context.Background(), pgx.TxOptions{})
+	if err != nil {
+		log.Errorf("#CreateBatch(...) failed, error: %v", err)
+		return 0, fmt.Errorf("unable to start a transaction; error: %w", err)
+	}
+	defer func() {
+		err = finishTx(err, tx)
+	}()
+
+	var claimed *time.Time
+	if !claimedUntil.IsZero() {
+		claimed = &claimedUntil
+	}
+	err = tx.QueryRow(context.Background(),
+		`INSERT INTO batch (user_id, sender_id, currency, convert_currency, mode, status, created_at, claimed_until)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`,
+		b.UserID, b.SenderBalanceID, string(b.Currency), b.Convert, string(b.Mode), string(b.Status), b.CreatedAt, claimed).Scan(&id)
+	if err != nil {
+		log.Errorf("#CreateBatch(...) error while saving batch for user with ID %d; error %v", b.UserID, err)
+		return 0, err
+	}
+
+	for _, item := range b.Items {
+		metadata := item.Metadata
+		if metadata == nil {
+			metadata = map[string]string{}
+		}
+		_, err = tx.Exec(context.Background(),
+			`INSERT INTO batch_item (batch_id, idx, receiver_id, amount, status, memo, reference, metadata) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+			id, item.Index, item.ReceiverBalanceID, item.Amount, string(item.Status), item.Memo, item.Reference, metadata)
+		if err != nil {
+			log.Errorf("#CreateBatch(...) error while saving item %d of batch %d; error %v", item.Index, id, err)
+			return 0, err
+		}
+	}
+	return id, nil
+}
+
+func (r PostgreBalanceRepo) GetBatch(id, userID int) (model.BatchDB, error) {
+	b, err := scanBatch(r.DBConn.QueryRow(context.Background(),
+		`SELECT `+batchColumns+` FROM batch b WHERE b.id=$1 AND b.user_id=$2`, id, userID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return model.BatchDB{}, ErrRecordNotFound
+		}
+		log.Errorf("#GetBatch(...) error while retrieving batch %d of user with ID %d; error %v", id, userID, err)
+		return model.BatchDB{}, err
+	}
+	if b.Items, err = r.getBatchItems(b.ID); err != nil {
+		return model.BatchDB{}, err
+	}
+	return b, nil
+}
+
+// ClaimPendingBatches claims batches with SELECT ... FOR UPDATE SKIP LOCKED, so concurrent instances claim different batches.
+// Claim of instance which did not save the batch expires at claimedUntil and its pending items are processed again.
+func (r PostgreBalanceRepo) ClaimPendingBatches(now, claimedUntil time.Time, limit int) ([]model.BatchDB, error) {
+	rows, err := r.DBConn.Query(context.Background(),
+		`UPDATE batch b SET claimed_until=$1 WHERE b.id IN (
+			SELECT id FROM batch WHERE status=$2 AND (claimed_until IS NULL OR claimed_until <= $3) ORDER BY id LIMIT $4 FOR UPDATE SKIP LOCKED)
+		RETURNING `+batchColumns,
+		claimedUntil, string(model.BatchPending), now, limit)
+	if err != nil {
+		log.Errorf("#ClaimPendingBatches(...) error while claiming pending batches; error %v", err)
+		return nil, err
+	}
+
+	batches := []model.BatchDB{}
+	for rows.Next() {
+		b, err := scanBatch(rows)
+		if err != nil {
+			rows.Close()
+			log.Errorf("#ClaimPendingBatches(...) error while scanning claimed batches; error %v", err)
+			return nil, err
+		}
+		batches = append(batches, b)
+	}
+	rows.Close()
+
+	for i := range batches {
+		if batches[i].Items, err = r.getBatchItems(batches[i].ID); err != nil {
+			return nil, err
+		}
+	}
+	return batches, nil
+}
+
+// MakeBatchTransactions transfers items of the batch as one unit - sender and all receiver balances are locked
+// (SELECT ... FOR UPDATE) in ascending ID order, fn gets transfer of every item from balances updated by the previous items
+// and returns it to be saved. The item is completed in the same DB transaction, so it is never transferred twice.
+// When any item fails nothing is saved and BatchItemError is returned.
+func (r PostgreBalanceRepo) MakeBatchTransactions(b model.BatchDB, items []model.BatchItemDB,
+	fn func(item model.BatchItemDB, t model.TransactionDBFull) (model.TransactionDBFull, error)) (made []model.BatchItemDB, err error) {
+	tx, err := r.DBConn.BeginTx(context.Background(), pgx.TxOptions{})
+	if err != nil {
+		log.Errorf("#MakeBatchTransactions(...) failed, error: %v", err)
+		return nil, fmt.Errorf("unable to start a transaction; error: %w", err)
+	}
+	defer func() {
+		err = finishTx(err, tx)
+	}()
+
+	IDs := []int{b.SenderBalanceID}
+	for _, item := range items {
+		IDs = append(IDs, item.ReceiverBalanceID)
+	}
+	existingBalances, err := r.getBalancesForUpdate(tx, uniqueIDs(IDs)...)
+	if err != nil {
+		return nil, err
+	}
+	balances := map[int]model.BalanceDB{}
+	for _, balance := range existingBalances {
+		balances[balance.ID] = balance
+	}
+	if _, ok := balances[b.SenderBalanceID]; !ok {
+		log.Errorf("#MakeBatchTransactions(...) failed, sender balance %d of batch %d not found", b.SenderBalanceID, b.ID)
+		return nil, ErrBalancesNotFound
+	}
+
+	for _, item := range items {
+		receiver, ok := balances[item.ReceiverBalanceID]
+		if !ok {
+			log.Errorf("#MakeBatchTransactions(...) failed, receiver balance %d of batch %d not found", item.ReceiverBalanceID, b.ID)
+			return nil, BatchItemError{Index: item.Index, Err: ErrBalancesNotFound}
+		}
+		transaction, err := fn(item, model.TransactionDBFull{
+			SenderBalance:      balances[b.SenderBalanceID],
+			ReceiverBalance:    receiver,
+			Amount:             item.Amount,
+			Currency:           b.Currency,
+			TransactionDetails: item.TransactionDetails,
+		})
+		if err != nil {
+			return nil, BatchItemError{Index: item.Index, Err: err}
+		}
+
+		madeTransaction, err := r.createTransaction(tx, transaction)
+		if err != nil {
+			return nil, err
+		}
+		balances[transaction.SenderBalance.ID] = transaction.SenderBalance
+		balances[transaction.ReceiverBalance.ID] = transaction.ReceiverBalance
+
+		tag, err := tx.Exec(context.Background(),
+			`UPDATE batch_item SET status=$1, transaction_id=$2, error='' WHERE batch_id=$3 AND idx=$4 AND status=$5`,
+			string(model.BatchItemCompleted), madeTransaction.ID, b.ID, item.Index, string(model.BatchItemPending))
+		if err != nil {
+			log.Errorf("#MakeBatchTransactions(...) error while completing item %d of batch %d; error %v", item.Index, b.ID, err)
+			return nil, err
+		}
+		if tag.RowsAffected() == 0 {
+			log.Errorf("#MakeBatchTransactions(...) failed, item %d of batch %d was already processed", item.Index, b.ID)
+			return nil, BatchItemError{Index: item.Index, Err: ErrBatchItemProcessed}
+		}
+		item.Status = model.BatchItemCompleted
+		item.TransactionID = madeTransaction.ID
+		item.Error = ""
+		made = append(made, item)
+	}
+
+	for i := range existingBalances {
+		existingBalances[i] = balances[existingBalances[i].ID]
+	}
+	err = r.saveBalances(tx, existingBalances)
+	if err != nil {
+		return nil, err
+	}
+
+	return made, nil
+}
+
+func (r PostgreBalanceRepo) SaveBatch(b model.BatchDB) (err error) {
+	tx, err := r.DBConn.BeginTx(context.Background(), pgx.TxOptions{})
+	if err != nil {
+		log.Errorf("#SaveBatch(...) failed, error: %v", err)
+		return fmt.Errorf("unable to start a transaction; error: %w", err)
+	}
+	defer func() {
+		err = finishTx(err, tx)
+	}()
+
+	var completedAt *time.Time
+	if !b.CompletedAt.IsZero() {
+		completedAt = &b.CompletedAt
+	}
+	_, err = tx.Exec(context.Background(), "UPDATE batch SET status=$1, completed_at=$2, claimed_until=NULL WHERE id=$3",
+		string(b.Status), completedAt, b.ID)
+	if err != nil {
+		log.Errorf("#SaveBatch(...) error while saving batch %d; error %v", b.ID, err)
+		return err
+	}
+
+	// completed items were saved with their transfers
+	for _, item := range b.Items {
+		if item.Status != model.BatchItemFailed && item.Status != model.BatchItemSkipped {
+			continue
+		}
+		_, err = tx.Exec(context.Background(), "UPDATE batch_item SET status=$1, error=$2 WHERE batch_id=$3 AND idx=$4 AND status=$5",
+			string(item.Status), item.Error, b.ID, item.Index, string(model.BatchItemPending))
+		if err != nil {
+			log.Errorf("#SaveBatch(...) error while saving item %d of batch %d; error %v", item.Index, b.ID, err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (r PostgreBalanceRepo) getBatchItems(batchID int) ([]model.BatchItemDB, error) {
+	rows, err := r.DBConn.Query(context.Background(),
+		`SELECT i.idx, i.receiver_id, i.amount, i.status, i.transaction_id, i.error, i.memo, i.reference, i.metadata
+		FROM batch_item i WHERE i.batch_id=$1 ORDER BY i.idx`, batchID)
+	if err != nil {
+		log.Errorf("#getBatchItems(...) error while retrieving items of batch %d; error %v", batchID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []model.BatchItemDB{}
+	for rows.Next() {
+		item := model.BatchItemDB{}
+		var transactionID *int
+		err = rows.Scan(&item.Index, &item.ReceiverBalanceID, &item.Amount, &item.Status, &transactionID, &item.Error, &item.Memo, &item.Reference,
+			&item.Metadata)
+		if err != nil {
+			log.Errorf("#getBatchItems(...) error while scanning items of batch %d; error %v", batchID, err)
+			return nil, err
+		}
+		if transactionID != nil {
+			item.TransactionID = *transactionID
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// batchColumns are read by scanBatch.
+const batchColumns = `b.id, b.user_id, b.sender_id, b.currency, b.convert_currency, b.mode, b.status, b.created_at, b.completed_at`
+
+// scanBatch reads row with batchColumns, items are not read.
+func scanBatch(row pgx.Row) (model.BatchDB, error) {
+	b := model.BatchDB{}
+	var completedAt *time.Time
+	err := row.Scan(&b.ID, &b.UserID, &b.SenderBalanceID, &b.Currency, &b.Convert, &b.Mode, &b.Status, &b.CreatedAt, &completedAt)
+	if err != nil {
+		return model.BatchDB{}, err
+	}
+	if completedAt != nil {
+		b.CompletedAt = *completedAt
+	}
+	return b, nil
+}
+
+// uniqueIDs returns IDs without duplicates in their original order.
+func uniqueIDs(IDs []int) []int {
+	seen := map[int]bool{}
+	unique := []int{}
+	for _, ID := range IDs {
+		if !seen[ID] {
+			seen[ID] = true
+			unique = append(unique, ID)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pashagolub/pgxmock"
+	"zuzanna.com/walletapi/model"
+)
+
+func TestMakeBatchTransactions(t *testing.T) {
+	mockPool, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockPool.Close()
+
+	mockRepo := PostgreBalanceRepo{
+		DBConn: dbMockPool{mockPool},
+	}
+	b := model.BatchDB{ID: 12, SenderBalanceID: 1, Currency: model.SGD}
+	items := []model.BatchItemDB{
+		{Index: 0, ReceiverBalanceID: 3, Amount: model.MustParseAmount("30"), Status: model.BatchItemPending},
+		{Index: 1, ReceiverBalanceID: 2, Amount: model.MustParseAmount("20"), Status: model.BatchItemPending},
+	}
+	balanceColumns := []string{"id", "currency", "balance", "held", "user_id"}
+	transfer := func(item model.BatchItemDB, t model.TransactionDBFull) (model.TransactionDBFull, error) {
+		t.SenderBalance.Balance -= t.Amount
+		t.ReceiverBalance.Balance += t.Amount
+		t.ReceiverAmount, t.ReceiverCurrency = t.Amount, t.Currency
+		return t, nil
+	}
+
+	mockPool.ExpectBeginTx(pgx.TxOptions{})
+	mockPool.ExpectQuery("SELECT id, currency, balance, held, user_id FROM balance WHERE id IN ( $1, $2, $3) ORDER BY id FOR UPDATE").
+		WithArgs(1, 2, 3).
+		WillReturnRows(pgxmock.NewRows(balanceColumns).
+			AddRow(1, model.SGD, model.MustParseAmount("100"), model.Amount(0), 1).
+			AddRow(2, model.SGD, model.MustParseAmount("50"), model.Amount(0), 2).
+			AddRow(3, model.SGD, model.MustParseAmount("10"), model.Amount(0), 2))
+	for i, item := range items {
+		transactionID := 40 + i
+		mockPool.ExpectQuery(`INSERT INTO transaction (sender_id, receiver_id, currency, amount, date, receiver_currency, receiver_amount, rate, rate_date, memo, reference, metadata, refund_of)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) RETURNING id`).
+			WithArgs(1, item.ReceiverBalanceID, "SGD", item.Amount, AnyTime{}, "SGD", item.Amount, model.Rate(0), (*time.Time)(nil),
+				"", "", map[string]string{}, (*int)(nil)).
+			WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(transactionID))
+		mockPool.ExpectExec("INSERT INTO balance_transaction (balance_id, transaction_id) VALUES ($1, $2)").
+			WithArgs(1, transactionID).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mockPool.ExpectExec("INSERT INTO balance_transaction (balance_id, transaction_id) VALUES ($1, $2)").
+			WithArgs(item.ReceiverBalanceID, transactionID).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mockPool.ExpectExec("UPDATE batch_item SET status=$1, transaction_id=$2, error='' WHERE batch_id=$3 AND idx=$4 AND status=$5").
+			WithArgs("completed", transactionID, 12, item.Index, "pending").
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	}
+	// balances are saved in ascending ID order
+	for i, balance := range []string{"50", "70", "40"} {
+		mockPool.ExpectExec("UPDATE balance SET balance=$1, held=$2 WHERE id=$3").
+			WithArgs(model.MustParseAmount(balance), model.Amount(0), i+1).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	}
+	mockPool.ExpectCommit()
+
+	made, err := mockRepo.MakeBatchTransactions(b, items, transfer)
+	if err != nil {
+		t.Errorf("error was not expected while making batch transactions: %s", err)
+	}
+	if len(made) != 2 || made[0].TransactionID != 40 || made[1].TransactionID != 41 || made[1].Status != model.BatchItemCompleted {
+		t.Errorf("made items got: %+v", made)
+	}
+	if err := mockPool.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+
+	// receiver of the second item does not exist, nothing is saved
+	items[1].ReceiverBalanceID = 5
+	mockPool.ExpectBeginTx(pgx.TxOptions{})
+	mockPool.ExpectQuery("SELECT id, currency, balance, held, user_id FROM balance WHERE id IN ( $1, $2, $3) ORDER BY id FOR UPDATE").
+		WithArgs(1, 3, 5).
+		WillReturnRows(pgxmock.NewRows(balanceColumns).
+			AddRow(1, model.SGD, model.MustParseAmount("100"), model.Amount(0), 1).
+			AddRow(3, model.SGD, model.MustParseAmount("10"), model.Amount(0), 2))
+	mockPool.ExpectQuery(`INSERT INTO transaction (sender_id, receiver_id, currency, amount, date, receiver_currency, receiver_amount, rate, rate_date, memo, reference, metadata, refund_of)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) RETURNING id`).
+		WithArgs(1, 3, "SGD", model.MustParseAmount("30"), AnyTime{}, "SGD", model.MustParseAmount("30"), model.Rate(0), (*time.Time)(nil),
+			"", "", map[string]string{}, (*int)(nil)).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(42))
+	mockPool.ExpectExec("INSERT INTO balance_transaction (balance_id, transaction_id) VALUES ($1, $2)").
+		WithArgs(1, 42).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mockPool.ExpectExec("INSERT INTO balance_transaction (balance_id, transaction_id) VALUES ($1, $2)").
+		WithArgs(3, 42).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mockPool.ExpectExec("UPDATE batch_item SET status=$1, transaction_id=$2, error='' WHERE batch_id=$3 AND idx=$4 AND status=$5").
+		WithArgs("completed", 42, 12, 0, "pending").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mockPool.ExpectRollback()
+
+	_, err = mockRepo.MakeBatchTransactions(b, items, transfer)
+	var itemErr BatchItemError
+	if !errors.As(err, &itemErr) || itemErr.Index != 1 || itemErr.Err != ErrBalancesNotFound {
+		t.Errorf("error got: %v; want: error of item 1 %v", err, ErrBalancesNotFound)
+	}
+	if err := mockPool.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestSaveBatch(t *testing.T) {
+	mockPool, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockPool.Close()
+
+	mockRepo := PostgreBalanceRepo{
+		DBConn: dbMockPool{mockPool},
+	}
+	now := time.Now()
+	b := model.BatchDB{ID: 12, Status: model.BatchPartiallyCompleted, CompletedAt: now, Items: []model.BatchItemDB{
+		{Index: 0, Status: model.BatchItemCompleted, TransactionID: 40},
+		{Index: 1, Status: model.BatchItemFailed, Error: "insufficient balance of a sender"},
+	}}
+
+	mockPool.ExpectBeginTx(pgx.TxOptions{})
+	mockPool.ExpectExec("UPDATE batch SET status=$1, completed_at=$2, claimed_until=NULL WHERE id=$3").
+		WithArgs("partially_completed", &now, 12).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mockPool.ExpectExec("UPDATE batch_item SET status=$1, error=$2 WHERE batch_id=$3 AND idx=$4 AND status=$5").
+		WithArgs("failed", "insufficient balance of a sender", 12, 1, "pending").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mockPool.ExpectCommit()
+
+	if err = mockRepo.SaveBatch(b); err != nil {
+		t.Errorf("error was not expected while saving batch: %s", err)
+	}
+	if err := mockPool.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
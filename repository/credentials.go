@@ -13,6 +13,10 @@ var ErrRecordNotFound = errors.New("record not found")
 
 type CredentialsRepo interface {
 	Get(login string) (model.Credentials, error)
+	GetByUserID(userID int) (model.Credentials, error)
+	// UpdatePassword replaces password hash only if it was not changed in the meantime.
+	UpdatePassword(login, oldHash, newHash string) error
+	GetRole(userID int) (model.Role, error)
 }
 
 type PostgreCredentialsRepo struct {
@@ -21,8 +25,10 @@ type PostgreCredentialsRepo struct {
 
 func (cred PostgreCredentialsRepo) Get(login string) (model.Credentials, error) {
 	credentials := model.Credentials{}
+	var role string
 	err := cred.DBConn.QueryRow(context.Background(),
-		"SELECT login, password, user_id FROM credentials WHERE login=$1", login).Scan(&credentials.Login, &credentials.Password, &credentials.UserID)
+		`SELECT c.login, c.password, c.user_id, u.role FROM credentials c JOIN "user" u ON u.id = c.user_id WHERE c.login=$1`, login).
+		Scan(&credentials.Login, &credentials.Password, &credentials.UserID, &role)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return model.Credentials{}, ErrRecordNotFound
@@ -30,5 +36,49 @@ func (cred PostgreCredentialsRepo) Get(login string) (model.Credentials, error)
 		log.Errorf("error while reading credentials for user with login %s; error %v", login, err)
 		return model.Credentials{}, err
 	}
+	credentials.Role = model.Role(role)
 	return credentials, nil
 }
+
+func (cred PostgreCredentialsRepo) GetByUserID(userID int) (model.Credentials, error) {
+	credentials := model.Credentials{}
+	var role string
+	err := cred.DBConn.QueryRow(context.Background(),
+		`SELECT c.login, c.password, c.user_id, u.role FROM credentials c JOIN "user" u ON u.id = c.user_id WHERE c.user_id=$1`, userID).
+		Scan(&credentials.Login, &credentials.Password, &credentials.UserID, &role)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return model.Credentials{}, ErrRecordNotFound
+		}
+		log.Errorf("error while reading credentials for user with ID %d; error %v", userID, err)
+		return model.Credentials{}, err
+	}
+	credentials.Role = model.Role(role)
+	return credentials, nil
+}
+
+func (cred PostgreCredentialsRepo) UpdatePassword(login, oldHash, newHash string) error {
+	tag, err := cred.DBConn.Exec(context.Background(),
+		"UPDATE credentials SET password=$1 WHERE login=$2 AND password=$3", newHash, login, oldHash)
+	if err != nil {
+		log.Errorf("error while updating password for user with login %s; error %v", login, err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+func (cred PostgreCredentialsRepo) GetRole(userID int) (model.Role, error) {
+	var role string
+	err := cred.DBConn.QueryRow(context.Background(), `SELECT role FROM "user" WHERE id=$1`, userID).Scan(&role)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return "", ErrRecordNotFound
+		}
+		log.Errorf("error while reading role of user with ID %d; error %v", userID, err)
+		return "", err
+	}
+	return model.Role(role), nil
+}
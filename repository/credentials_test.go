@@ -32,11 +32,12 @@ func TestGet(t *testing.T) {
 		Login:    "test11",
 		Password: "aGFzbG8=",
 		UserID:   1,
+		Role:     model.RoleSupport,
 	}
 
-	rows := pgxmock.NewRows([]string{"login", "password", "user_id"}).
-		AddRow(want.Login, want.Password, want.UserID)
-	mockPool.ExpectQuery("SELECT login, password, user_id FROM credentials WHERE login=$1").WithArgs(want.Login).WillReturnRows(rows)
+	rows := pgxmock.NewRows([]string{"login", "password", "user_id", "role"}).
+		AddRow(want.Login, want.Password, want.UserID, "support")
+	mockPool.ExpectQuery(`SELECT c.login, c.password, c.user_id, u.role FROM credentials c JOIN "user" u ON u.id = c.user_id WHERE c.login=$1`).WithArgs(want.Login).WillReturnRows(rows)
 
 	got, err := mockRepo.Get(want.Login)
 	if err != nil {
@@ -51,3 +52,103 @@ func TestGet(t *testing.T) {
 		t.Errorf("there were unfulfilled expectations: %s", err)
 	}
 }
+
+func TestGetByUserID(t *testing.T) {
+	mockPool, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockPool.Close()
+
+	mockRepo := PostgreCredentialsRepo{
+		DBConn: dbMockPool{mockPool},
+	}
+	want := model.Credentials{
+		Login:    "test11",
+		Password: "aGFzbG8=",
+		UserID:   1,
+		Role:     model.RoleUser,
+	}
+
+	query := `SELECT c.login, c.password, c.user_id, u.role FROM credentials c JOIN "user" u ON u.id = c.user_id WHERE c.user_id=$1`
+	mockPool.ExpectQuery(query).WithArgs(1).
+		WillReturnRows(pgxmock.NewRows([]string{"login", "password", "user_id", "role"}).AddRow(want.Login, want.Password, want.UserID, "user"))
+	mockPool.ExpectQuery(query).WithArgs(2).WillReturnError(pgx.ErrNoRows)
+
+	got, err := mockRepo.GetByUserID(1)
+	if err != nil {
+		t.Errorf("error was not expected while retrieving credentials: %s", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("error got: %+v want: %+v", got, want)
+	}
+	if _, err = mockRepo.GetByUserID(2); err != ErrRecordNotFound {
+		t.Errorf("error got: %v; want: %v", err, ErrRecordNotFound)
+	}
+
+	if err := mockPool.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestUpdatePassword(t *testing.T) {
+	mockPool, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockPool.Close()
+
+	mockRepo := PostgreCredentialsRepo{
+		DBConn: dbMockPool{mockPool},
+	}
+
+	mockPool.ExpectExec("UPDATE credentials SET password=$1 WHERE login=$2 AND password=$3").
+		WithArgs("bcrypt$new", "test11", "aGFzbG8=").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mockPool.ExpectExec("UPDATE credentials SET password=$1 WHERE login=$2 AND password=$3").
+		WithArgs("bcrypt$new", "test11", "changed").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+	if err = mockRepo.UpdatePassword("test11", "aGFzbG8=", "bcrypt$new"); err != nil {
+		t.Errorf("error was not expected while updating password: %s", err)
+	}
+	if err = mockRepo.UpdatePassword("test11", "changed", "bcrypt$new"); err != ErrRecordNotFound {
+		t.Errorf("error got: %v; want: %v", err, ErrRecordNotFound)
+	}
+
+	if err := mockPool.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetRole(t *testing.T) {
+	mockPool, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockPool.Close()
+
+	mockRepo := PostgreCredentialsRepo{
+		DBConn: dbMockPool{mockPool},
+	}
+
+	mockPool.ExpectQuery(`SELECT role FROM "user" WHERE id=$1`).WithArgs(1).
+		WillReturnRows(pgxmock.NewRows([]string{"role"}).AddRow("admin"))
+	mockPool.ExpectQuery(`SELECT role FROM "user" WHERE id=$1`).WithArgs(2).
+		WillReturnError(pgx.ErrNoRows)
+
+	role, err := mockRepo.GetRole(1)
+	if err != nil {
+		t.Errorf("error was not expected while retrieving role: %s", err)
+	}
+	if role != model.RoleAdmin {
+		t.Errorf("role got: %s; want: %s", role, model.RoleAdmin)
+	}
+	if _, err = mockRepo.GetRole(2); err != ErrRecordNotFound {
+		t.Errorf("error got: %v; want: %v", err, ErrRecordNotFound)
+	}
+
+	if err := mockPool.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -2,12 +2,33 @@ package repository
 
 import (
 	"context"
+	"fmt"
 
+	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v4"
+	"github.com/labstack/gommon/log"
 )
 
 type pgxConn interface {
 	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
 	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
 	BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error)
 }
+
+// finishTx commits tx when err is nil, otherwise rolls it back.
+func finishTx(err error, tx pgx.Tx) error {
+	if err != nil {
+		if rollbackErr := tx.Rollback(context.Background()); rollbackErr != nil {
+			log.Errorf("#finishTx(...) failed when rollback, error: %v", err)
+			return fmt.Errorf("unable to rollback a transaction; error: %v", err)
+		}
+
+		return err
+	}
+	if commitErr := tx.Commit(context.Background()); commitErr != nil {
+		log.Errorf("finishTx(...) failed when commiting, error: %v", err)
+		return fmt.Errorf("unable to commit a transaction; error: %v", err)
+	}
+	return nil
+}
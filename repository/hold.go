@@ -0,0 +1,197 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/labstack/gommon/log"
+	"zuzanna.com/walletapi/model"
+)
+
+// HoldRepo keeps holds of balances, it is implemented by PostgreBalanceRepo as holds change balances they are made from.
+type HoldRepo interface {
+	// CreateHold saves hold made by fn from locked sender and receiver balances of h, see PostgreBalanceRepo.CreateHold.
+	CreateHold(h model.HoldDB, fn func(h model.HoldDBFull) (model.HoldDBFull, error)) (model.HoldDB, error)
+	// GetHold returns hold from or to a balance of the user. Returns ErrRecordNotFound when there is no such hold of the user.
+	GetHold(id, userID int) (model.HoldDB, error)
+	// UpdateHold changes status of the hold with ID by fn, see PostgreBalanceRepo.UpdateHold. Returns ErrRecordNotFound
+	// when there is no such hold.
+	UpdateHold(id int, fn func(h model.HoldDBFull) (model.HoldDBFull, *model.TransactionDBFull, error)) (model.HoldDB, error)
+	// GetExpiredHolds returns active holds which expired before now.
+	GetExpiredHolds(now time.Time) ([]model.HoldDB, error)
+}
+
+// CreateHold reserves money of the sender balance - both balances are locked (SELECT ... FOR UPDATE) like in MakeTransaction,
+// fn gets them with h and returns the hold to be saved together with its balances.
+func (r PostgreBalanceRepo) CreateHold(h model.HoldDB, fn func(h model.HoldDBFull) (model.HoldDBFull, error)) (created model.HoldDB, err error) {
+	tx, err := r.DBConn.BeginTx(context.Background(), pgx.TxOptions{})
+	if err != nil {
+		log.Errorf("#CreateHold(...) failed, error: %v", err)
+		return model.HoldDB{}, fmt.Errorf("unable to start a transaction; error: %w", err)
+	}
+	defer func() {
+		err = finishTx(err, tx)
+	}()
+
+	hold, err := r.getHoldBalancesForUpdate(tx, h)
+	if err != nil {
+		return model.HoldDB{}, err
+	}
+
+	hold, err = fn(hold)
+	if err != nil {
+		return model.HoldDB{}, err
+	}
+
+	metadata := hold.Metadata
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	err = tx.QueryRow(context.Background(),
+		`INSERT INTO hold (sender_id, receiver_id, currency, amount, status, created_at, expires_at, memo, reference, metadata)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id`,
+		hold.SenderBalance.ID, hold.ReceiverBalance.ID, string(hold.Currency), hold.Amount, string(hold.Status), hold.CreatedAt, hold.ExpiresAt,
+		hold.Memo, hold.Reference, metadata).Scan(&hold.ID)
+	if err != nil {
+		log.Errorf("#CreateHold(...) error while inserting into hold table: %v", err)
+		return model.HoldDB{}, err
+	}
+
+	err = r.saveBalances(tx, []model.BalanceDB{hold.SenderBalance, hold.ReceiverBalance})
+	if err != nil {
+		return model.HoldDB{}, err
+	}
+
+	return hold.HoldDB, nil
+}
+
+func (r PostgreBalanceRepo) GetHold(id, userID int) (model.HoldDB, error) {
+	h, err := scanHold(r.DBConn.QueryRow(context.Background(),
+		`SELECT `+holdColumns+`
+		FROM hold h WHERE h.id=$1
+		AND (h.sender_id IN (SELECT id FROM balance WHERE user_id=$2) OR h.receiver_id IN (SELECT id FROM balance WHERE user_id=$2))`,
+		id, userID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return model.HoldDB{}, ErrRecordNotFound
+		}
+		log.Errorf("#GetHold(...) error while retrieving hold %d of user with ID %d; error %v", id, userID, err)
+		return model.HoldDB{}, err
+	}
+	return h, nil
+}
+
+// UpdateHold locks the hold with ID (SELECT ... FOR UPDATE) and then its balances, so the hold cannot be captured, voided
+// or expired twice. fn returns the hold with its new status and balances, and for capture also the transaction to be made.
+// Balances of the returned hold are saved, the transaction gets its ID linked to the hold.
+func (r PostgreBalanceRepo) UpdateHold(id int, fn func(h model.HoldDBFull) (model.HoldDBFull, *model.TransactionDBFull, error)) (updated model.HoldDB, err error) {
+	tx, err := r.DBConn.BeginTx(context.Background(), pgx.TxOptions{})
+	if err != nil {
+		log.Errorf("#UpdateHold(...) failed, error: %v", err)
+		return model.HoldDB{}, fmt.Errorf("unable to start a transaction; error: %w", err)
+	}
+	defer func() {
+		err = finishTx(err, tx)
+	}()
+
+	h, err := scanHold(tx.QueryRow(context.Background(), `SELECT `+holdColumns+` FROM hold h WHERE h.id=$1 FOR UPDATE`, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return model.HoldDB{}, ErrRecordNotFound
+		}
+		log.Errorf("#UpdateHold(...) error while retrieving hold %d; error %v", id, err)
+		return model.HoldDB{}, err
+	}
+
+	hold, err := r.getHoldBalancesForUpdate(tx, h)
+	if err != nil {
+		return model.HoldDB{}, err
+	}
+
+	hold, transaction, err := fn(hold)
+	if err != nil {
+		return model.HoldDB{}, err
+	}
+
+	var transactionID *int
+	if transaction != nil {
+		made, err := r.createTransaction(tx, *transaction)
+		if err != nil {
+			return model.HoldDB{}, err
+		}
+		hold.TransactionID = made.ID
+		transactionID = &made.ID
+	}
+
+	_, err = tx.Exec(context.Background(), "UPDATE hold SET status=$1, captured_amount=$2, transaction_id=$3 WHERE id=$4",
+		string(hold.Status), hold.CapturedAmount, transactionID, hold.ID)
+	if err != nil {
+		log.Errorf("#UpdateHold(...) error while updating hold %d; error %v", hold.ID, err)
+		return model.HoldDB{}, err
+	}
+
+	err = r.saveBalances(tx, []model.BalanceDB{hold.SenderBalance, hold.ReceiverBalance})
+	if err != nil {
+		return model.HoldDB{}, err
+	}
+
+	return hold.HoldDB, nil
+}
+
+func (r PostgreBalanceRepo) GetExpiredHolds(now time.Time) ([]model.HoldDB, error) {
+	rows, err := r.DBConn.Query(context.Background(),
+		`SELECT `+holdColumns+` FROM hold h WHERE h.status=$1 AND h.expires_at <= $2 ORDER BY h.id`, string(model.HoldActive), now)
+	if err != nil {
+		log.Errorf("#GetExpiredHolds(...) error while retrieving holds expired before %s; error %v", now, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	holds := []model.HoldDB{}
+	for rows.Next() {
+		h, err := scanHold(rows)
+		if err != nil {
+			log.Errorf("#GetExpiredHolds(...) error while scanning holds; error %v", err)
+			return nil, err
+		}
+		holds = append(holds, h)
+	}
+	return holds, nil
+}
+
+// getHoldBalancesForUpdate locks sender and receiver balances of h.
+func (r PostgreBalanceRepo) getHoldBalancesForUpdate(tx pgx.Tx, h model.HoldDB) (model.HoldDBFull, error) {
+	existingBalances, err := r.getBalancesForUpdate(tx, h.SenderBalanceID, h.ReceiverBalanceID)
+	if err != nil {
+		return model.HoldDBFull{}, err
+	}
+	if len(existingBalances) != 2 {
+		log.Errorf("#getHoldBalancesForUpdate(...) failed, found %d balance(s) instead of 2", len(existingBalances))
+		return model.HoldDBFull{}, ErrBalancesNotFound
+	}
+	if existingBalances[0].ID == h.SenderBalanceID {
+		return model.HoldDBFull{HoldDB: h, SenderBalance: existingBalances[0], ReceiverBalance: existingBalances[1]}, nil
+	}
+	return model.HoldDBFull{HoldDB: h, SenderBalance: existingBalances[1], ReceiverBalance: existingBalances[0]}, nil
+}
+
+// holdColumns are read by scanHold.
+const holdColumns = `h.id, h.sender_id, h.receiver_id, h.currency, h.amount, h.status, h.captured_amount, h.transaction_id, h.created_at, h.expires_at,
+		h.memo, h.reference, h.metadata`
+
+// scanHold reads row with holdColumns.
+func scanHold(row pgx.Row) (model.HoldDB, error) {
+	h := model.HoldDB{}
+	var transactionID *int
+	err := row.Scan(&h.ID, &h.SenderBalanceID, &h.ReceiverBalanceID, &h.Currency, &h.Amount, &h.Status, &h.CapturedAmount, &transactionID,
+		&h.CreatedAt, &h.ExpiresAt, &h.Memo, &h.Reference, &h.Metadata)
+	if err != nil {
+		return model.HoldDB{}, err
+	}
+	if transactionID != nil {
+		h.TransactionID = *transactionID
+	}
+	return h, nil
+}
@@ -0,0 +1,186 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pashagolub/pgxmock"
+	"zuzanna.com/walletapi/model"
+)
+
+const holdQuery = `SELECT h.id, h.sender_id, h.receiver_id, h.currency, h.amount, h.status, h.captured_amount, h.transaction_id, h.created_at, h.expires_at,
+		h.memo, h.reference, h.metadata FROM hold h`
+
+var holdRowColumns = []string{"id", "sender_id", "receiver_id", "currency", "amount", "status", "captured_amount", "transaction_id", "created_at", "expires_at",
+	"memo", "reference", "metadata"}
+
+func TestCreateHold(t *testing.T) {
+	mockPool, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockPool.Close()
+
+	mockRepo := PostgreBalanceRepo{
+		DBConn: dbMockPool{mockPool},
+	}
+	now := time.Now()
+	expiresAt := now.Add(time.Hour)
+
+	mockPool.ExpectBeginTx(pgx.TxOptions{})
+	mockPool.ExpectQuery("SELECT id, currency, balance, held, user_id FROM balance WHERE id IN ( $1, $2) ORDER BY id FOR UPDATE").
+		WithArgs(1, 2).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "currency", "balance", "held", "user_id"}).
+			AddRow(1, model.SGD, model.MustParseAmount("100"), model.MustParseAmount("10"), 1).
+			AddRow(2, model.SGD, model.MustParseAmount("50"), model.Amount(0), 2))
+	mockPool.ExpectQuery(`INSERT INTO hold (sender_id, receiver_id, currency, amount, status, created_at, expires_at, memo, reference, metadata)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id`).
+		WithArgs(1, 2, "SGD", model.MustParseAmount("20"), "active", now, expiresAt, "Order 42", "", map[string]string{}).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(7))
+	mockPool.ExpectExec("UPDATE balance SET balance=$1, held=$2 WHERE id=$3").
+		WithArgs(model.MustParseAmount("100"), model.MustParseAmount("30"), 1).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mockPool.ExpectExec("UPDATE balance SET balance=$1, held=$2 WHERE id=$3").
+		WithArgs(model.MustParseAmount("50"), model.Amount(0), 2).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mockPool.ExpectCommit()
+
+	h := model.HoldDB{SenderBalanceID: 1, ReceiverBalanceID: 2, Amount: model.MustParseAmount("20"), TransactionDetails: model.TransactionDetails{Memo: "Order 42"}}
+	got, err := mockRepo.CreateHold(h, func(h model.HoldDBFull) (model.HoldDBFull, error) {
+		if h.SenderBalance.ID != 1 || h.ReceiverBalance.ID != 2 {
+			t.Errorf("hold must be from balance 1 to balance 2, got: %+v", h)
+		}
+		h.Currency, h.Status, h.CreatedAt, h.ExpiresAt = h.SenderBalance.Currency, model.HoldActive, now, expiresAt
+		h.SenderBalance.Held += h.Amount
+		return h, nil
+	})
+	if err != nil {
+		t.Errorf("error was not expected while creating a hold: %s", err)
+	}
+	if got.ID != 7 || got.Status != model.HoldActive || got.SenderBalanceID != 1 || got.ReceiverBalanceID != 2 {
+		t.Errorf("hold got: %+v", got)
+	}
+
+	mockPool.ExpectBeginTx(pgx.TxOptions{})
+	mockPool.ExpectQuery("SELECT id, currency, balance, held, user_id FROM balance WHERE id IN ( $1, $2) ORDER BY id FOR UPDATE").
+		WithArgs(1, 3).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "currency", "balance", "held", "user_id"}).
+			AddRow(1, model.SGD, model.MustParseAmount("100"), model.Amount(0), 1))
+	mockPool.ExpectRollback()
+	if _, err = mockRepo.CreateHold(model.HoldDB{SenderBalanceID: 1, ReceiverBalanceID: 3}, nil); err != ErrBalancesNotFound {
+		t.Errorf("error got: %v; want: %v", err, ErrBalancesNotFound)
+	}
+
+	if err := mockPool.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestUpdateHold(t *testing.T) {
+	mockPool, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockPool.Close()
+
+	mockRepo := PostgreBalanceRepo{
+		DBConn: dbMockPool{mockPool},
+	}
+	createdAt := time.Now()
+	expiresAt := createdAt.Add(time.Hour)
+
+	// capture of 15 from hold of 20
+	mockPool.ExpectBeginTx(pgx.TxOptions{})
+	mockPool.ExpectQuery(holdQuery + " WHERE h.id=$1 FOR UPDATE").WithArgs(7).
+		WillReturnRows(pgxmock.NewRows(holdRowColumns).
+			AddRow(7, 1, 2, model.SGD, model.MustParseAmount("20"), model.HoldActive, model.Amount(0), nil, createdAt, expiresAt, "", "", map[string]string{}))
+	mockPool.ExpectQuery("SELECT id, currency, balance, held, user_id FROM balance WHERE id IN ( $1, $2) ORDER BY id FOR UPDATE").
+		WithArgs(1, 2).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "currency", "balance", "held", "user_id"}).
+			AddRow(1, model.SGD, model.MustParseAmount("100"), model.MustParseAmount("20"), 1).
+			AddRow(2, model.SGD, model.MustParseAmount("50"), model.Amount(0), 2))
+	mockPool.ExpectQuery(`INSERT INTO transaction (sender_id, receiver_id, currency, amount, date, receiver_currency, receiver_amount, rate, rate_date, memo, reference, metadata, refund_of)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) RETURNING id`).
+		WithArgs(1, 2, "SGD", model.MustParseAmount("15"), AnyTime{}, "SGD", model.MustParseAmount("15"), model.Rate(0), (*time.Time)(nil),
+			"", "", map[string]string{}, (*int)(nil)).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(10))
+	mockPool.ExpectExec("INSERT INTO balance_transaction (balance_id, transaction_id) VALUES ($1, $2)").
+		WithArgs(1, 10).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mockPool.ExpectExec("INSERT INTO balance_transaction (balance_id, transaction_id) VALUES ($1, $2)").
+		WithArgs(2, 10).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	transactionID := 10
+	mockPool.ExpectExec("UPDATE hold SET status=$1, captured_amount=$2, transaction_id=$3 WHERE id=$4").
+		WithArgs("captured", model.MustParseAmount("15"), &transactionID, 7).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mockPool.ExpectExec("UPDATE balance SET balance=$1, held=$2 WHERE id=$3").
+		WithArgs(model.MustParseAmount("85"), model.Amount(0), 1).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mockPool.ExpectExec("UPDATE balance SET balance=$1, held=$2 WHERE id=$3").
+		WithArgs(model.MustParseAmount("65"), model.Amount(0), 2).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mockPool.ExpectCommit()
+
+	got, err := mockRepo.UpdateHold(7, func(h model.HoldDBFull) (model.HoldDBFull, *model.TransactionDBFull, error) {
+		if h.Status != model.HoldActive || h.Amount != model.MustParseAmount("20") || !h.ExpiresAt.Equal(expiresAt) {
+			t.Errorf("hold got: %+v", h)
+		}
+		amount := model.MustParseAmount("15")
+		h.SenderBalance.Held -= h.Amount
+		h.SenderBalance.Balance -= amount
+		h.ReceiverBalance.Balance += amount
+		h.Status, h.CapturedAmount = model.HoldCaptured, amount
+		return h, &model.TransactionDBFull{SenderBalance: h.SenderBalance, ReceiverBalance: h.ReceiverBalance, Amount: amount, Currency: model.SGD,
+			ReceiverAmount: amount, ReceiverCurrency: model.SGD, Date: time.Now()}, nil
+	})
+	if err != nil {
+		t.Errorf("error was not expected while capturing a hold: %s", err)
+	}
+	if got.ID != 7 || got.Status != model.HoldCaptured || got.TransactionID != 10 {
+		t.Errorf("captured hold got: %+v", got)
+	}
+
+	mockPool.ExpectBeginTx(pgx.TxOptions{})
+	mockPool.ExpectQuery(holdQuery + " WHERE h.id=$1 FOR UPDATE").WithArgs(8).WillReturnError(pgx.ErrNoRows)
+	mockPool.ExpectRollback()
+	if _, err = mockRepo.UpdateHold(8, nil); err != ErrRecordNotFound {
+		t.Errorf("error got: %v; want: %v", err, ErrRecordNotFound)
+	}
+
+	if err := mockPool.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetExpiredHolds(t *testing.T) {
+	mockPool, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockPool.Close()
+
+	mockRepo := PostgreBalanceRepo{
+		DBConn: dbMockPool{mockPool},
+	}
+	now := time.Now()
+	transactionID := 3
+
+	mockPool.ExpectQuery(holdQuery+" WHERE h.status=$1 AND h.expires_at <= $2 ORDER BY h.id").WithArgs("active", now).
+		WillReturnRows(pgxmock.NewRows(holdRowColumns).
+			AddRow(7, 1, 2, model.SGD, model.MustParseAmount("20"), model.HoldActive, model.Amount(0), nil, now.Add(-time.Hour), now.Add(-time.Minute), "", "", map[string]string{}).
+			AddRow(8, 1, 2, model.SGD, model.MustParseAmount("5"), model.HoldActive, model.Amount(0), &transactionID, now.Add(-time.Hour), now, "", "", map[string]string{}))
+
+	holds, err := mockRepo.GetExpiredHolds(now)
+	if err != nil {
+		t.Errorf("error was not expected while retrieving expired holds: %s", err)
+	}
+	if len(holds) != 2 || holds[0].ID != 7 || holds[0].TransactionID != 0 || holds[1].ID != 8 || holds[1].TransactionID != 3 {
+		t.Errorf("expired holds got: %+v", holds)
+	}
+
+	if err := mockPool.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/labstack/gommon/log"
+	"zuzanna.com/walletapi/model"
+)
+
+type IdempotencyRepo interface {
+	Reserve(k model.IdempotencyKeyDB, expiredBefore time.Time) (bool, error)
+	Get(userID int, key string) (model.IdempotencyKeyDB, error)
+	SaveResponse(userID int, key string, code int, body []byte) error
+	Delete(userID int, key string) error
+}
+
+type PostgreIdempotencyRepo struct {
+	DBConn pgxConn
+}
+
+func NewPostgreIdempotencyRepo(pool *pgxpool.Pool) *PostgreIdempotencyRepo {
+	return &PostgreIdempotencyRepo{DBConn: pool}
+}
+
+// Reserve inserts new key without response. Key that was created before expiredBefore is overwritten.
+// Returns false when the key already exists and has not expired yet.
+func (r PostgreIdempotencyRepo) Reserve(k model.IdempotencyKeyDB, expiredBefore time.Time) (bool, error) {
+	var userID int
+	err := r.DBConn.QueryRow(context.Background(),
+		`INSERT INTO idempotency_key (user_id, key, fingerprint, created_at) VALUES ($1, $2, $3, $4) 
+		ON CONFLICT (user_id, key) DO UPDATE SET fingerprint=EXCLUDED.fingerprint, created_at=EXCLUDED.created_at, response_code=0, response_body='' 
+		WHERE idempotency_key.created_at < $5 RETURNING user_id`,
+		k.UserID, k.Key, k.Fingerprint, k.CreatedAt, expiredBefore).Scan(&userID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return false, nil
+		}
+		log.Errorf("#Reserve(...) error while reserving idempotency key %s for user with ID %d; error %v", k.Key, k.UserID, err)
+		return false, err
+	}
+	return true, nil
+}
+
+func (r PostgreIdempotencyRepo) Get(userID int, key string) (model.IdempotencyKeyDB, error) {
+	k := model.IdempotencyKeyDB{}
+	var body string
+	err := r.DBConn.QueryRow(context.Background(),
+		"SELECT user_id, key, fingerprint, response_code, response_body, created_at FROM idempotency_key WHERE user_id=$1 AND key=$2",
+		userID, key).Scan(&k.UserID, &k.Key, &k.Fingerprint, &k.ResponseCode, &body, &k.CreatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return model.IdempotencyKeyDB{}, ErrRecordNotFound
+		}
+		log.Errorf("#Get(...) error while reading idempotency key %s for user with ID %d; error %v", key, userID, err)
+		return model.IdempotencyKeyDB{}, err
+	}
+	k.ResponseBody = []byte(body)
+	return k, nil
+}
+
+func (r PostgreIdempotencyRepo) SaveResponse(userID int, key string, code int, body []byte) error {
+	_, err := r.DBConn.Exec(context.Background(),
+		"UPDATE idempotency_key SET response_code=$1, response_body=$2 WHERE user_id=$3 AND key=$4",
+		code, string(body), userID, key)
+	if err != nil {
+		log.Errorf("#SaveResponse(...) error while saving response for idempotency key %s for user with ID %d; error %v", key, userID, err)
+		return err
+	}
+	return nil
+}
+
+func (r PostgreIdempotencyRepo) Delete(userID int, key string) error {
+	_, err := r.DBConn.Exec(context.Background(), "DELETE FROM idempotency_key WHERE user_id=$1 AND key=$2", userID, key)
+	if err != nil {
+		log.Errorf("#Delete(...) error while deleting idempotency key %s for user with ID %d; error %v", key, userID, err)
+		return err
+	}
+	return nil
+}
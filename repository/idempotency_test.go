@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pashagolub/pgxmock"
+	"zuzanna.com/walletapi/model"
+)
+
+var reserveIdempotencyKeyQuery = `INSERT INTO idempotency_key (user_id, key, fingerprint, created_at) VALUES ($1, $2, $3, $4) 
+		ON CONFLICT (user_id, key) DO UPDATE SET fingerprint=EXCLUDED.fingerprint, created_at=EXCLUDED.created_at, response_code=0, response_body='' 
+		WHERE idempotency_key.created_at < $5 RETURNING user_id`
+
+func TestReserve(t *testing.T) {
+	mockPool, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockPool.Close()
+
+	mockRepo := PostgreIdempotencyRepo{
+		DBConn: dbMockPool{mockPool},
+	}
+
+	now := time.Now()
+	key := model.IdempotencyKeyDB{UserID: 1, Key: "abc", Fingerprint: "f1", CreatedAt: now}
+	mockPool.ExpectQuery(reserveIdempotencyKeyQuery).
+		WithArgs(1, "abc", "f1", now, now.Add(-time.Hour)).
+		WillReturnRows(pgxmock.NewRows([]string{"user_id"}).AddRow(1))
+	mockPool.ExpectQuery(reserveIdempotencyKeyQuery).
+		WithArgs(1, "abc", "f1", now, now.Add(-time.Hour)).
+		WillReturnError(pgx.ErrNoRows)
+
+	reserved, err := mockRepo.Reserve(key, now.Add(-time.Hour))
+	if err != nil {
+		t.Errorf("error was not expected while reserving idempotency key: %s", err)
+	}
+	if !reserved {
+		t.Errorf("reserved got: %t; want: true", reserved)
+	}
+
+	reserved, err = mockRepo.Reserve(key, now.Add(-time.Hour))
+	if err != nil {
+		t.Errorf("error was not expected while reserving idempotency key: %s", err)
+	}
+	if reserved {
+		t.Errorf("reserved got: %t; want: false", reserved)
+	}
+
+	if err := mockPool.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetIdempotencyKey(t *testing.T) {
+	mockPool, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockPool.Close()
+
+	mockRepo := PostgreIdempotencyRepo{
+		DBConn: dbMockPool{mockPool},
+	}
+
+	want := model.IdempotencyKeyDB{UserID: 1, Key: "abc", Fingerprint: "f1", ResponseCode: 201, ResponseBody: []byte(`{"id":1}`), CreatedAt: time.Now()}
+	query := "SELECT user_id, key, fingerprint, response_code, response_body, created_at FROM idempotency_key WHERE user_id=$1 AND key=$2"
+	mockPool.ExpectQuery(query).
+		WithArgs(1, "abc").
+		WillReturnRows(pgxmock.NewRows([]string{"user_id", "key", "fingerprint", "response_code", "response_body", "created_at"}).
+			AddRow(want.UserID, want.Key, want.Fingerprint, want.ResponseCode, string(want.ResponseBody), want.CreatedAt))
+	mockPool.ExpectQuery(query).
+		WithArgs(1, "xyz").
+		WillReturnError(pgx.ErrNoRows)
+
+	got, err := mockRepo.Get(1, "abc")
+	if err != nil {
+		t.Errorf("error was not expected while reading idempotency key: %s", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("error got: %+v want: %+v", got, want)
+	}
+
+	_, err = mockRepo.Get(1, "xyz")
+	if err != ErrRecordNotFound {
+		t.Errorf("error got: %v want: %v", err, ErrRecordNotFound)
+	}
+
+	if err := mockPool.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestSaveResponseAndDelete(t *testing.T) {
+	mockPool, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockPool.Close()
+
+	mockRepo := PostgreIdempotencyRepo{
+		DBConn: dbMockPool{mockPool},
+	}
+
+	mockPool.ExpectExec("UPDATE idempotency_key SET response_code=$1, response_body=$2 WHERE user_id=$3 AND key=$4").
+		WithArgs(201, `{"id":1}`, 1, "abc").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mockPool.ExpectExec("DELETE FROM idempotency_key WHERE user_id=$1 AND key=$2").
+		WithArgs(1, "abc").
+		WillReturnResult(pgxmock.NewResult("DELETE", 1))
+
+	if err := mockRepo.SaveResponse(1, "abc", 201, []byte(`{"id":1}`)); err != nil {
+		t.Errorf("error was not expected while saving response: %s", err)
+	}
+	if err := mockRepo.Delete(1, "abc"); err != nil {
+		t.Errorf("error was not expected while deleting idempotency key: %s", err)
+	}
+
+	if err := mockPool.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/labstack/gommon/log"
+	"zuzanna.com/walletapi/model"
+)
+
+type LoginAttemptRepo interface {
+	Get(key string) (model.LoginAttemptDB, error)
+	// Update calls fn with current attempts of the key and saves the result. Concurrent updates of the same key
+	// are made one after another.
+	Update(key string, fn func(a *model.LoginAttemptDB)) (model.LoginAttemptDB, error)
+	Reset(key string) error
+}
+
+type PostgreLoginAttemptRepo struct {
+	DBConn pgxConn
+}
+
+func NewPostgreLoginAttemptRepo(pool *pgxpool.Pool) *PostgreLoginAttemptRepo {
+	return &PostgreLoginAttemptRepo{DBConn: pool}
+}
+
+func (r PostgreLoginAttemptRepo) Get(key string) (model.LoginAttemptDB, error) {
+	a, err := getLoginAttempt(r.DBConn, key, "")
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return model.LoginAttemptDB{}, ErrRecordNotFound
+		}
+		log.Errorf("#Get(...) error while reading login attempts of %s; error %v", key, err)
+		return model.LoginAttemptDB{}, err
+	}
+	return a, nil
+}
+
+func (r PostgreLoginAttemptRepo) Update(key string, fn func(a *model.LoginAttemptDB)) (a model.LoginAttemptDB, err error) {
+	tx, err := r.DBConn.BeginTx(context.Background(), pgx.TxOptions{})
+	if err != nil {
+		log.Errorf("#Update(...) failed, error: %v", err)
+		return model.LoginAttemptDB{}, fmt.Errorf("unable to start a transaction; error: %w", err)
+	}
+	defer func() {
+		err = finishTx(err, tx)
+	}()
+
+	_, err = tx.Exec(context.Background(), "INSERT INTO login_attempt (key, failures) VALUES ($1, 0) ON CONFLICT (key) DO NOTHING", key)
+	if err != nil {
+		log.Errorf("#Update(...) error while inserting login attempts of %s; error %v", key, err)
+		return model.LoginAttemptDB{}, err
+	}
+	a, err = getLoginAttempt(tx, key, " FOR UPDATE")
+	if err != nil {
+		log.Errorf("#Update(...) error while reading login attempts of %s; error %v", key, err)
+		return model.LoginAttemptDB{}, err
+	}
+
+	fn(&a)
+
+	_, err = tx.Exec(context.Background(),
+		"UPDATE login_attempt SET failures=$1, last_failure_at=$2, locked_until=$3 WHERE key=$4",
+		a.Failures, a.LastFailureAt, a.LockedUntil, key)
+	if err != nil {
+		log.Errorf("#Update(...) error while saving login attempts of %s; error %v", key, err)
+		return model.LoginAttemptDB{}, err
+	}
+	return a, nil
+}
+
+func (r PostgreLoginAttemptRepo) Reset(key string) error {
+	_, err := r.DBConn.Exec(context.Background(), "DELETE FROM login_attempt WHERE key=$1", key)
+	if err != nil {
+		log.Errorf("#Reset(...) error while deleting login attempts of %s; error %v", key, err)
+		return err
+	}
+	return nil
+}
+
+type queryRower interface {
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+func getLoginAttempt(conn queryRower, key string, suffix string) (model.LoginAttemptDB, error) {
+	a := model.LoginAttemptDB{}
+	var lastFailureAt, lockedUntil *time.Time
+	err := conn.QueryRow(context.Background(),
+		"SELECT key, failures, last_failure_at, locked_until FROM login_attempt WHERE key=$1"+suffix, key).
+		Scan(&a.Key, &a.Failures, &lastFailureAt, &lockedUntil)
+	if err != nil {
+		return model.LoginAttemptDB{}, err
+	}
+	if lastFailureAt != nil {
+		a.LastFailureAt = *lastFailureAt
+	}
+	if lockedUntil != nil {
+		a.LockedUntil = *lockedUntil
+	}
+	return a, nil
+}
+
+// MemoryLoginAttemptRepo keeps login attempts in memory, they are lost on restart and not shared between instances.
+type MemoryLoginAttemptRepo struct {
+	mu       sync.Mutex
+	attempts map[string]model.LoginAttemptDB
+}
+
+func NewMemoryLoginAttemptRepo() *MemoryLoginAttemptRepo {
+	return &MemoryLoginAttemptRepo{attempts: map[string]model.LoginAttemptDB{}}
+}
+
+func (r *MemoryLoginAttemptRepo) Get(key string) (model.LoginAttemptDB, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	a, ok := r.attempts[key]
+	if !ok {
+		return model.LoginAttemptDB{}, ErrRecordNotFound
+	}
+	return a, nil
+}
+
+func (r *MemoryLoginAttemptRepo) Update(key string, fn func(a *model.LoginAttemptDB)) (model.LoginAttemptDB, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	a, ok := r.attempts[key]
+	if !ok {
+		a = model.LoginAttemptDB{Key: key}
+	}
+	fn(&a)
+	r.attempts[key] = a
+	return a, nil
+}
+
+func (r *MemoryLoginAttemptRepo) Reset(key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.attempts, key)
+	return nil
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pashagolub/pgxmock"
+	"zuzanna.com/walletapi/model"
+)
+
+func TestUpdateLoginAttempt(t *testing.T) {
+	mockPool, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockPool.Close()
+
+	mockRepo := PostgreLoginAttemptRepo{
+		DBConn: dbMockPool{mockPool},
+	}
+	now := time.Now()
+	lastFailureAt := now.Add(-time.Second)
+
+	mockPool.ExpectBeginTx(pgx.TxOptions{})
+	mockPool.ExpectExec("INSERT INTO login_attempt (key, failures) VALUES ($1, 0) ON CONFLICT (key) DO NOTHING").
+		WithArgs("login:ala11").
+		WillReturnResult(pgxmock.NewResult("INSERT", 0))
+	mockPool.ExpectQuery("SELECT key, failures, last_failure_at, locked_until FROM login_attempt WHERE key=$1 FOR UPDATE").
+		WithArgs("login:ala11").
+		WillReturnRows(pgxmock.NewRows([]string{"key", "failures", "last_failure_at", "locked_until"}).AddRow("login:ala11", 1, &lastFailureAt, &lastFailureAt))
+	mockPool.ExpectExec("UPDATE login_attempt SET failures=$1, last_failure_at=$2, locked_until=$3 WHERE key=$4").
+		WithArgs(2, now, now.Add(time.Minute), "login:ala11").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mockPool.ExpectCommit()
+
+	got, err := mockRepo.Update("login:ala11", func(a *model.LoginAttemptDB) {
+		if a.Failures != 1 || !a.LastFailureAt.Equal(lastFailureAt) {
+			t.Errorf("current attempts got: %+v; want: 1 failure at %s", a, lastFailureAt)
+		}
+		a.Failures++
+		a.LastFailureAt = now
+		a.LockedUntil = now.Add(time.Minute)
+	})
+	if err != nil {
+		t.Errorf("error was not expected while registering failure: %s", err)
+	}
+	if got.Failures != 2 {
+		t.Errorf("failures got: %d; want: 2", got.Failures)
+	}
+
+	if err := mockPool.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetLoginAttempt(t *testing.T) {
+	mockPool, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockPool.Close()
+
+	mockRepo := PostgreLoginAttemptRepo{
+		DBConn: dbMockPool{mockPool},
+	}
+
+	mockPool.ExpectQuery("SELECT key, failures, last_failure_at, locked_until FROM login_attempt WHERE key=$1").
+		WithArgs("ip:10.0.0.1").
+		WillReturnError(pgx.ErrNoRows)
+	mockPool.ExpectExec("DELETE FROM login_attempt WHERE key=$1").
+		WithArgs("ip:10.0.0.1").
+		WillReturnResult(pgxmock.NewResult("DELETE", 0))
+
+	if _, err = mockRepo.Get("ip:10.0.0.1"); err != ErrRecordNotFound {
+		t.Errorf("error got: %v; want: %v", err, ErrRecordNotFound)
+	}
+	if err = mockRepo.Reset("ip:10.0.0.1"); err != nil {
+		t.Errorf("error was not expected while resetting attempts: %s", err)
+	}
+
+	if err := mockPool.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestMemoryLoginAttemptRepo(t *testing.T) {
+	repo := NewMemoryLoginAttemptRepo()
+	if _, err := repo.Get("login:ala11"); err != ErrRecordNotFound {
+		t.Errorf("error got: %v; want: %v", err, ErrRecordNotFound)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := repo.Update("login:ala11", func(a *model.LoginAttemptDB) { a.Failures++ }); err != nil {
+			t.Errorf("error was not expected while registering failure: %s", err)
+		}
+	}
+	a, err := repo.Get("login:ala11")
+	if err != nil || a.Failures != 2 || a.Key != "login:ala11" {
+		t.Errorf("attempts got: %+v (%v); want: 2 failures of login:ala11", a, err)
+	}
+	if err = repo.Reset("login:ala11"); err != nil {
+		t.Errorf("error was not expected while resetting attempts: %s", err)
+	}
+	if _, err := repo.Get("login:ala11"); err != ErrRecordNotFound {
+		t.Errorf("error got: %v; want: %v", err, ErrRecordNotFound)
+	}
+}
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/labstack/gommon/log"
+	"zuzanna.com/walletapi/model"
+)
+
+var ErrMFAConfirmed = errors.New("two-factor authentication already confirmed")
+
+type MFARepo interface {
+	// SaveSecret saves new unconfirmed TOTP secret replacing previous unconfirmed one and returns login of the user.
+	// Returns ErrMFAConfirmed when user already confirmed the secret.
+	SaveSecret(userID int, secret string) (string, error)
+	Get(userID int) (model.MFADB, error)
+	// Confirm marks secret as confirmed and replaces recovery codes of the user in one DB transaction.
+	Confirm(userID int, step int64, confirmedAt time.Time, codeHashes []string) error
+	// UseStep saves TOTP time step of accepted code. Returns false when the same or later step was already used.
+	UseStep(userID int, step int64) (bool, error)
+	// UseRecoveryCode marks recovery code as used. Returns false when there is no such unused code.
+	UseRecoveryCode(userID int, codeHash string, usedAt time.Time) (bool, error)
+}
+
+type PostgreMFARepo struct {
+	DBConn pgxConn
+}
+
+func NewPostgreMFARepo(pool *pgxpool.Pool) *PostgreMFARepo {
+	return &PostgreMFARepo{DBConn: pool}
+}
+
+func (r PostgreMFARepo) SaveSecret(userID int, secret string) (string, error) {
+	var login string
+	err := r.DBConn.QueryRow(context.Background(),
+		`INSERT INTO mfa (user_id, secret) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET secret=EXCLUDED.secret, last_used_step=0 WHERE mfa.confirmed_at IS NULL
+		RETURNING (SELECT login FROM credentials WHERE user_id=$1)`,
+		userID, secret).Scan(&login)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return "", ErrMFAConfirmed
+		}
+		log.Errorf("#SaveSecret(...) error while saving TOTP secret of user with ID %d; error %v", userID, err)
+		return "", err
+	}
+	return login, nil
+}
+
+func (r PostgreMFARepo) Get(userID int) (model.MFADB, error) {
+	m := model.MFADB{}
+	var confirmedAt *time.Time
+	err := r.DBConn.QueryRow(context.Background(),
+		"SELECT user_id, secret, confirmed_at, last_used_step FROM mfa WHERE user_id=$1", userID).
+		Scan(&m.UserID, &m.Secret, &confirmedAt, &m.LastUsedStep)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return model.MFADB{}, ErrRecordNotFound
+		}
+		log.Errorf("#Get(...) error while reading TOTP secret of user with ID %d; error %v", userID, err)
+		return model.MFADB{}, err
+	}
+	if confirmedAt != nil {
+		m.ConfirmedAt = *confirmedAt
+	}
+	return m, nil
+}
+
+func (r PostgreMFARepo) Confirm(userID int, step int64, confirmedAt time.Time, codeHashes []string) (err error) {
+	tx, err := r.DBConn.BeginTx(context.Background(), pgx.TxOptions{})
+	if err != nil {
+		log.Errorf("#Confirm(...) failed, error: %v", err)
+		return fmt.Errorf("unable to start a transaction; error: %w", err)
+	}
+	defer func() {
+		err = finishTx(err, tx)
+	}()
+
+	tag, err := tx.Exec(context.Background(),
+		"UPDATE mfa SET confirmed_at=$1, last_used_step=$2 WHERE user_id=$3 AND confirmed_at IS NULL",
+		confirmedAt, step, userID)
+	if err != nil {
+		log.Errorf("#Confirm(...) error while confirming TOTP secret of user with ID %d; error %v", userID, err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrMFAConfirmed
+	}
+
+	_, err = tx.Exec(context.Background(), "DELETE FROM recovery_code WHERE user_id=$1", userID)
+	if err != nil {
+		log.Errorf("#Confirm(...) error while deleting recovery codes of user with ID %d; error %v", userID, err)
+		return err
+	}
+	for _, hash := range codeHashes {
+		_, err = tx.Exec(context.Background(), "INSERT INTO recovery_code (user_id, code_hash) VALUES ($1, $2)", userID, hash)
+		if err != nil {
+			log.Errorf("#Confirm(...) error while saving recovery code of user with ID %d; error %v", userID, err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (r PostgreMFARepo) UseStep(userID int, step int64) (bool, error) {
+	tag, err := r.DBConn.Exec(context.Background(),
+		"UPDATE mfa SET last_used_step=$1 WHERE user_id=$2 AND confirmed_at IS NOT NULL AND last_used_step < $1", step, userID)
+	if err != nil {
+		log.Errorf("#UseStep(...) error while saving TOTP step of user with ID %d; error %v", userID, err)
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r PostgreMFARepo) UseRecoveryCode(userID int, codeHash string, usedAt time.Time) (bool, error) {
+	tag, err := r.DBConn.Exec(context.Background(),
+		"UPDATE recovery_code SET used_at=$1 WHERE user_id=$2 AND code_hash=$3 AND used_at IS NULL", usedAt, userID, codeHash)
+	if err != nil {
+		log.Errorf("#UseRecoveryCode(...) error while using recovery code of user with ID %d; error %v", userID, err)
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
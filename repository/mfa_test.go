@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pashagolub/pgxmock"
+)
+
+const saveSecretQuery = `INSERT INTO mfa (user_id, secret) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET secret=EXCLUDED.secret, last_used_step=0 WHERE mfa.confirmed_at IS NULL
+		RETURNING (SELECT login FROM credentials WHERE user_id=$1)`
+
+func TestSaveSecret(t *testing.T) {
+	mockPool, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockPool.Close()
+
+	mockRepo := PostgreMFARepo{
+		DBConn: dbMockPool{mockPool},
+	}
+
+	mockPool.ExpectQuery(saveSecretQuery).WithArgs(1, "SECRET").
+		WillReturnRows(pgxmock.NewRows([]string{"login"}).AddRow("test11"))
+	mockPool.ExpectQuery(saveSecretQuery).WithArgs(2, "SECRET").
+		WillReturnError(pgx.ErrNoRows)
+
+	login, err := mockRepo.SaveSecret(1, "SECRET")
+	if err != nil {
+		t.Errorf("error was not expected while saving secret: %s", err)
+	}
+	if login != "test11" {
+		t.Errorf("login got: %s; want: test11", login)
+	}
+	if _, err = mockRepo.SaveSecret(2, "SECRET"); err != ErrMFAConfirmed {
+		t.Errorf("error got: %v; want: %v", err, ErrMFAConfirmed)
+	}
+
+	if err := mockPool.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestConfirmMFA(t *testing.T) {
+	mockPool, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockPool.Close()
+
+	mockRepo := PostgreMFARepo{
+		DBConn: dbMockPool{mockPool},
+	}
+	now := time.Now()
+
+	mockPool.ExpectBeginTx(pgx.TxOptions{})
+	mockPool.ExpectExec("UPDATE mfa SET confirmed_at=$1, last_used_step=$2 WHERE user_id=$3 AND confirmed_at IS NULL").
+		WithArgs(now, int64(100), 1).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mockPool.ExpectExec("DELETE FROM recovery_code WHERE user_id=$1").WithArgs(1).
+		WillReturnResult(pgxmock.NewResult("DELETE", 0))
+	mockPool.ExpectExec("INSERT INTO recovery_code (user_id, code_hash) VALUES ($1, $2)").WithArgs(1, "hash1").
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mockPool.ExpectExec("INSERT INTO recovery_code (user_id, code_hash) VALUES ($1, $2)").WithArgs(1, "hash2").
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mockPool.ExpectCommit()
+
+	if err = mockRepo.Confirm(1, 100, now, []string{"hash1", "hash2"}); err != nil {
+		t.Errorf("error was not expected while confirming secret: %s", err)
+	}
+
+	// already confirmed - recovery codes are kept
+	mockPool.ExpectBeginTx(pgx.TxOptions{})
+	mockPool.ExpectExec("UPDATE mfa SET confirmed_at=$1, last_used_step=$2 WHERE user_id=$3 AND confirmed_at IS NULL").
+		WithArgs(now, int64(100), 1).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+	mockPool.ExpectRollback()
+
+	if err = mockRepo.Confirm(1, 100, now, []string{"hash1"}); err != ErrMFAConfirmed {
+		t.Errorf("error got: %v; want: %v", err, ErrMFAConfirmed)
+	}
+
+	if err := mockPool.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestUseStep(t *testing.T) {
+	mockPool, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockPool.Close()
+
+	mockRepo := PostgreMFARepo{
+		DBConn: dbMockPool{mockPool},
+	}
+
+	mockPool.ExpectExec("UPDATE mfa SET last_used_step=$1 WHERE user_id=$2 AND confirmed_at IS NOT NULL AND last_used_step < $1").
+		WithArgs(int64(101), 1).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mockPool.ExpectExec("UPDATE mfa SET last_used_step=$1 WHERE user_id=$2 AND confirmed_at IS NOT NULL AND last_used_step < $1").
+		WithArgs(int64(101), 1).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+	if ok, err := mockRepo.UseStep(1, 101); err != nil || !ok {
+		t.Errorf("first use of step got: %t (%v); want: true", ok, err)
+	}
+	if ok, err := mockRepo.UseStep(1, 101); err != nil || ok {
+		t.Errorf("second use of step got: %t (%v); want: false", ok, err)
+	}
+
+	if err := mockPool.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/labstack/gommon/log"
+	"zuzanna.com/walletapi/model"
+)
+
+type PasswordResetRepo interface {
+	Create(r model.PasswordResetDB) error
+	// Use marks unused and not expired reset token as used and returns ID of its user.
+	// Returns ErrRecordNotFound when there is no such token.
+	Use(hash string, usedAt time.Time) (int, error)
+	// InvalidateAll marks all unused reset tokens of the user as used.
+	InvalidateAll(userID int, usedAt time.Time) error
+}
+
+type PostgrePasswordResetRepo struct {
+	DBConn pgxConn
+}
+
+func NewPostgrePasswordResetRepo(pool *pgxpool.Pool) *PostgrePasswordResetRepo {
+	return &PostgrePasswordResetRepo{DBConn: pool}
+}
+
+func (r PostgrePasswordResetRepo) Create(reset model.PasswordResetDB) error {
+	_, err := r.DBConn.Exec(context.Background(),
+		"INSERT INTO password_reset (token_hash, user_id, expires_at, created_at) VALUES ($1, $2, $3, $4)",
+		reset.TokenHash, reset.UserID, reset.ExpiresAt, reset.CreatedAt)
+	if err != nil {
+		log.Errorf("#Create(...) error while saving password reset token for user with ID %d; error %v", reset.UserID, err)
+		return err
+	}
+	return nil
+}
+
+func (r PostgrePasswordResetRepo) Use(hash string, usedAt time.Time) (int, error) {
+	var userID int
+	err := r.DBConn.QueryRow(context.Background(),
+		"UPDATE password_reset SET used_at=$1 WHERE token_hash=$2 AND used_at IS NULL AND expires_at > $1 RETURNING user_id",
+		usedAt, hash).Scan(&userID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, ErrRecordNotFound
+		}
+		log.Errorf("#Use(...) error while using password reset token; error %v", err)
+		return 0, err
+	}
+	return userID, nil
+}
+
+func (r PostgrePasswordResetRepo) InvalidateAll(userID int, usedAt time.Time) error {
+	_, err := r.DBConn.Exec(context.Background(),
+		"UPDATE password_reset SET used_at=$1 WHERE user_id=$2 AND used_at IS NULL", usedAt, userID)
+	if err != nil {
+		log.Errorf("#InvalidateAll(...) error while invalidating password reset tokens of user with ID %d; error %v", userID, err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pashagolub/pgxmock"
+)
+
+func TestUsePasswordReset(t *testing.T) {
+	mockPool, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockPool.Close()
+
+	mockRepo := PostgrePasswordResetRepo{
+		DBConn: dbMockPool{mockPool},
+	}
+	now := time.Now()
+
+	query := "UPDATE password_reset SET used_at=$1 WHERE token_hash=$2 AND used_at IS NULL AND expires_at > $1 RETURNING user_id"
+	mockPool.ExpectQuery(query).WithArgs(now, "hash1").
+		WillReturnRows(pgxmock.NewRows([]string{"user_id"}).AddRow(1))
+	mockPool.ExpectQuery(query).WithArgs(now, "hash1").
+		WillReturnError(pgx.ErrNoRows)
+
+	userID, err := mockRepo.Use("hash1", now)
+	if err != nil || userID != 1 {
+		t.Errorf("user ID got: %d (%v); want: 1", userID, err)
+	}
+	// used, expired or unknown token
+	if _, err = mockRepo.Use("hash1", now); err != ErrRecordNotFound {
+		t.Errorf("error got: %v; want: %v", err, ErrRecordNotFound)
+	}
+
+	if err := mockPool.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
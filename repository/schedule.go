@@ -0,0 +1,236 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/labstack/gommon/log"
+	"zuzanna.com/walletapi/model"
+)
+
+var ErrScheduleRunSaved = errors.New("run of the schedule was already saved")
+
+type ScheduleRepo interface {
+	// Create saves the schedule and returns its ID.
+	Create(s model.ScheduleDB) (int, error)
+	// Get returns schedule of the user. Returns ErrRecordNotFound when there is no such schedule of the user.
+	Get(id, userID int) (model.ScheduleDB, error)
+	// GetByUserID returns all schedules of the user, the newest first.
+	GetByUserID(userID int) ([]model.ScheduleDB, error)
+	// UpdateStatus changes status of the user's schedule which has one of from statuses. Returns ErrRecordNotFound when
+	// there is no such schedule of the user or it has other status.
+	UpdateStatus(id, userID int, from []model.ScheduleStatus, to model.ScheduleStatus) (model.ScheduleDB, error)
+	// ClaimDue returns at most limit active schedules due at now, which are not claimed by others, and claims them until claimedUntil.
+	ClaimDue(now, claimedUntil time.Time, limit int) ([]model.ScheduleDB, error)
+	// SaveRun saves result of the run of claimed schedule and releases the claim, see PostgreScheduleRepo.SaveRun.
+	SaveRun(s model.ScheduleDB) error
+	// MakeScheduledTransaction makes transfer of claimed schedule and saves its successful run together with it,
+	// see PostgreScheduleRepo.MakeScheduledTransaction.
+	MakeScheduledTransaction(claimed, run model.ScheduleDB, fn func(t model.TransactionDBFull) (model.TransactionDBFull, error)) (model.ScheduleDB, error)
+}
+
+type PostgreScheduleRepo struct {
+	DBConn pgxConn
+}
+
+func NewPostgreScheduleRepo(pool *pgxpool.Pool) *PostgreScheduleRepo {
+	return &PostgreScheduleRepo{DBConn: pool}
+}
+
+func (r PostgreScheduleRepo) Create(s model.ScheduleDB) (int, error) {
+	metadata := s.Metadata
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	var id int
+	err := r.DBConn.QueryRow(context.Background(),
+		`INSERT INTO schedule (user_id, sender_id, receiver_id, currency, amount, convert_currency, recurrence, day_of_month, status, next_run_at, created_at,
+		memo, reference, metadata)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14) RETURNING id`,
+		s.UserID, s.SenderBalanceID, s.ReceiverBalanceID, string(s.Currency), s.Amount, s.Convert, string(s.Recurrence), s.DayOfMonth, string(s.Status),
+		s.NextRunAt, s.CreatedAt, s.Memo, s.Reference, metadata).Scan(&id)
+	if err != nil {
+		log.Errorf("#Create(...) error while saving schedule for user with ID %d; error %v", s.UserID, err)
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r PostgreScheduleRepo) Get(id, userID int) (model.ScheduleDB, error) {
+	s, err := scanSchedule(r.DBConn.QueryRow(context.Background(),
+		`SELECT `+scheduleColumns+` FROM schedule s WHERE s.id=$1 AND s.user_id=$2`, id, userID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return model.ScheduleDB{}, ErrRecordNotFound
+		}
+		log.Errorf("#Get(...) error while retrieving schedule %d of user with ID %d; error %v", id, userID, err)
+		return model.ScheduleDB{}, err
+	}
+	return s, nil
+}
+
+func (r PostgreScheduleRepo) GetByUserID(userID int) ([]model.ScheduleDB, error) {
+	rows, err := r.DBConn.Query(context.Background(),
+		`SELECT `+scheduleColumns+` FROM schedule s WHERE s.user_id=$1 ORDER BY s.id DESC`, userID)
+	if err != nil {
+		log.Errorf("#GetByUserID(...) error while retrieving schedules of user with ID %d; error %v", userID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	schedules, err := scanSchedules(rows)
+	if err != nil {
+		log.Errorf("#GetByUserID(...) error while scanning schedules of user with ID %d; error %v", userID, err)
+		return nil, err
+	}
+	return schedules, nil
+}
+
+func (r PostgreScheduleRepo) UpdateStatus(id, userID int, from []model.ScheduleStatus, to model.ScheduleStatus) (model.ScheduleDB, error) {
+	statuses := make([]string, len(from))
+	for i, status := range from {
+		statuses[i] = string(status)
+	}
+	s, err := scanSchedule(r.DBConn.QueryRow(context.Background(),
+		`UPDATE schedule s SET status=$1 WHERE s.id=$2 AND s.user_id=$3 AND s.status = ANY($4) RETURNING `+scheduleColumns,
+		string(to), id, userID, statuses))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return model.ScheduleDB{}, ErrRecordNotFound
+		}
+		log.Errorf("#UpdateStatus(...) error while updating schedule %d of user with ID %d; error %v", id, userID, err)
+		return model.ScheduleDB{}, err
+	}
+	return s, nil
+}
+
+// ClaimDue claims schedules with SELECT ... FOR UPDATE SKIP LOCKED, so concurrent instances claim different schedules.
+// Claim of instance which did not save the run expires at claimedUntil and the schedule is claimed again.
+func (r PostgreScheduleRepo) ClaimDue(now, claimedUntil time.Time, limit int) ([]model.ScheduleDB, error) {
+	rows, err := r.DBConn.Query(context.Background(),
+		`UPDATE schedule s SET claimed_until=$1 WHERE s.id IN (
+			SELECT id FROM schedule WHERE status=$2 AND COALESCE(retry_at, next_run_at) <= $3 AND (claimed_until IS NULL OR claimed_until <= $3)
+			ORDER BY COALESCE(retry_at, next_run_at) LIMIT $4 FOR UPDATE SKIP LOCKED)
+		RETURNING `+scheduleColumns,
+		claimedUntil, string(model.ScheduleActive), now, limit)
+	if err != nil {
+		log.Errorf("#ClaimDue(...) error while claiming schedules due at %s; error %v", now, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	schedules, err := scanSchedules(rows)
+	if err != nil {
+		log.Errorf("#ClaimDue(...) error while scanning claimed schedules; error %v", err)
+		return nil, err
+	}
+	return schedules, nil
+}
+
+// SaveRun saves result of the run. Status is changed only when the schedule is still active, it is not overwritten when
+// the user paused or cancelled the schedule during the run.
+func (r PostgreScheduleRepo) SaveRun(s model.ScheduleDB) error {
+	_, err := r.DBConn.Exec(context.Background(), saveRunQuery, runArgs(s)...)
+	if err != nil {
+		log.Errorf("#SaveRun(...) error while saving run of schedule %d; error %v", s.ID, err)
+		return err
+	}
+	return nil
+}
+
+// MakeScheduledTransaction makes transfer of claimed schedule with fn like PostgreBalanceRepo.MakeTransaction and saves run
+// with the new transaction in the same DB transaction, so the transfer cannot be made again when its run is not saved.
+// The run is saved like by SaveRun, but only when the schedule is still at the claimed run - when other instance executed
+// it in the meantime (e.g. after the claim expired), nothing is saved and ErrScheduleRunSaved is returned.
+func (r PostgreScheduleRepo) MakeScheduledTransaction(claimed, run model.ScheduleDB,
+	fn func(t model.TransactionDBFull) (model.TransactionDBFull, error)) (saved model.ScheduleDB, err error) {
+	tx, err := r.DBConn.BeginTx(context.Background(), pgx.TxOptions{})
+	if err != nil {
+		log.Errorf("#MakeScheduledTransaction(...) failed, error: %v", err)
+		return model.ScheduleDB{}, fmt.Errorf("unable to start a transaction; error: %w", err)
+	}
+	defer func() {
+		err = finishTx(err, tx)
+	}()
+
+	t := model.TransactionDB{SenderBalanceID: claimed.SenderBalanceID, ReceiverBalanceID: claimed.ReceiverBalanceID, Amount: claimed.Amount,
+		Currency: claimed.Currency, TransactionDetails: claimed.TransactionDetails}
+	madeTransaction, err := PostgreBalanceRepo{DBConn: r.DBConn}.makeTransaction(tx, t, fn)
+	if err != nil {
+		return model.ScheduleDB{}, err
+	}
+
+	run.LastTransactionID = madeTransaction.ID
+	tag, err := tx.Exec(context.Background(), saveRunQuery+" AND next_run_at=$10 AND attempts=$11",
+		append(runArgs(run), claimed.NextRunAt, claimed.Attempts)...)
+	if err != nil {
+		log.Errorf("#MakeScheduledTransaction(...) error while saving run of schedule %d; error %v", run.ID, err)
+		return model.ScheduleDB{}, err
+	}
+	if tag.RowsAffected() == 0 {
+		log.Errorf("#MakeScheduledTransaction(...) failed, run of schedule %d was already saved", run.ID)
+		return model.ScheduleDB{}, ErrScheduleRunSaved
+	}
+	return run, nil
+}
+
+// saveRunQuery saves run of the schedule with runArgs.
+const saveRunQuery = `UPDATE schedule SET status = CASE WHEN status=$1 THEN $2 ELSE status END, next_run_at=$3, retry_at=$4, attempts=$5, last_error=$6,
+		last_run_at=$7, last_transaction_id=$8, claimed_until=NULL WHERE id=$9`
+
+func runArgs(s model.ScheduleDB) []interface{} {
+	var retryAt, lastRunAt *time.Time
+	if !s.RetryAt.IsZero() {
+		retryAt = &s.RetryAt
+	}
+	if !s.LastRunAt.IsZero() {
+		lastRunAt = &s.LastRunAt
+	}
+	var lastTransactionID *int
+	if s.LastTransactionID != 0 {
+		lastTransactionID = &s.LastTransactionID
+	}
+	return []interface{}{string(model.ScheduleActive), string(s.Status), s.NextRunAt, retryAt, s.Attempts, s.LastError, lastRunAt, lastTransactionID, s.ID}
+}
+
+// scheduleColumns are read by scanSchedule.
+const scheduleColumns = `s.id, s.user_id, s.sender_id, s.receiver_id, s.currency, s.amount, s.convert_currency, s.recurrence, s.day_of_month, s.status,
+		s.next_run_at, s.retry_at, s.attempts, s.last_error, s.last_run_at, s.last_transaction_id, s.created_at, s.memo, s.reference, s.metadata`
+
+// scanSchedule reads row with scheduleColumns.
+func scanSchedule(row pgx.Row) (model.ScheduleDB, error) {
+	s := model.ScheduleDB{}
+	var retryAt, lastRunAt *time.Time
+	var lastTransactionID *int
+	err := row.Scan(&s.ID, &s.UserID, &s.SenderBalanceID, &s.ReceiverBalanceID, &s.Currency, &s.Amount, &s.Convert, &s.Recurrence, &s.DayOfMonth, &s.Status,
+		&s.NextRunAt, &retryAt, &s.Attempts, &s.LastError, &lastRunAt, &lastTransactionID, &s.CreatedAt, &s.Memo, &s.Reference, &s.Metadata)
+	if err != nil {
+		return model.ScheduleDB{}, err
+	}
+	if retryAt != nil {
+		s.RetryAt = *retryAt
+	}
+	if lastRunAt != nil {
+		s.LastRunAt = *lastRunAt
+	}
+	if lastTransactionID != nil {
+		s.LastTransactionID = *lastTransactionID
+	}
+	return s, nil
+}
+
+func scanSchedules(rows pgx.Rows) ([]model.ScheduleDB, error) {
+	schedules := []model.ScheduleDB{}
+	for rows.Next() {
+		s, err := scanSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, s)
+	}
+	return schedules, nil
+}
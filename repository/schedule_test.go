@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pashagolub/pgxmock"
+	"zuzanna.com/walletapi/model"
+)
+
+var scheduleRowColumns = []string{"id", "user_id", "sender_id", "receiver_id", "currency", "amount", "convert_currency", "recurrence", "day_of_month", "status",
+	"next_run_at", "retry_at", "attempts", "last_error", "last_run_at", "last_transaction_id", "created_at", "memo", "reference", "metadata"}
+
+func TestUpdateScheduleStatus(t *testing.T) {
+	mockPool, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockPool.Close()
+
+	mockRepo := PostgreScheduleRepo{
+		DBConn: dbMockPool{mockPool},
+	}
+	now := time.Now()
+	lastRunAt := now.Add(-time.Hour)
+	lastTransactionID := 42
+
+	query := `UPDATE schedule s SET status=$1 WHERE s.id=$2 AND s.user_id=$3 AND s.status = ANY($4) RETURNING s.id, s.user_id, s.sender_id, s.receiver_id,
+		s.currency, s.amount, s.convert_currency, s.recurrence, s.day_of_month, s.status, s.next_run_at, s.retry_at, s.attempts, s.last_error, s.last_run_at,
+		s.last_transaction_id, s.created_at, s.memo, s.reference, s.metadata`
+	mockPool.ExpectQuery(query).WithArgs("paused", 3, 1, []string{"active"}).
+		WillReturnRows(pgxmock.NewRows(scheduleRowColumns).
+			AddRow(3, 1, 1, 2, model.Currency(""), model.MustParseAmount("1200"), false, model.RecurrenceMonthly, 1, model.SchedulePaused,
+				now.Add(time.Hour), nil, 0, "", &lastRunAt, &lastTransactionID, now.Add(-48*time.Hour), "Rent", "", map[string]string{}))
+	mockPool.ExpectQuery(query).WithArgs("paused", 4, 1, []string{"active"}).
+		WillReturnError(pgx.ErrNoRows)
+
+	want := model.ScheduleDB{ID: 3, UserID: 1, SenderBalanceID: 1, ReceiverBalanceID: 2, Amount: model.MustParseAmount("1200"), Recurrence: model.RecurrenceMonthly,
+		DayOfMonth: 1, Status: model.SchedulePaused, NextRunAt: now.Add(time.Hour), LastRunAt: now.Add(-time.Hour), LastTransactionID: 42, CreatedAt: now.Add(-48 * time.Hour),
+		TransactionDetails: model.TransactionDetails{Memo: "Rent", Metadata: map[string]string{}}}
+	s, err := mockRepo.UpdateStatus(3, 1, []model.ScheduleStatus{model.ScheduleActive}, model.SchedulePaused)
+	if err != nil {
+		t.Errorf("error was not expected while pausing schedule: %s", err)
+	}
+	if !reflect.DeepEqual(s, want) {
+		t.Errorf("schedule got: %+v; want: %+v", s, want)
+	}
+	if _, err = mockRepo.UpdateStatus(4, 1, []model.ScheduleStatus{model.ScheduleActive}, model.SchedulePaused); err != ErrRecordNotFound {
+		t.Errorf("error got: %v; want: %v", err, ErrRecordNotFound)
+	}
+
+	if err := mockPool.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestClaimDueSchedules(t *testing.T) {
+	mockPool, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockPool.Close()
+
+	mockRepo := PostgreScheduleRepo{
+		DBConn: dbMockPool{mockPool},
+	}
+	now := time.Now()
+	retryAt := now.Add(-time.Minute)
+	lastRunAt := now.Add(-time.Hour)
+
+	mockPool.ExpectQuery(`UPDATE schedule s SET claimed_until=$1 WHERE s.id IN (
+			SELECT id FROM schedule WHERE status=$2 AND COALESCE(retry_at, next_run_at) <= $3 AND (claimed_until IS NULL OR claimed_until <= $3)
+			ORDER BY COALESCE(retry_at, next_run_at) LIMIT $4 FOR UPDATE SKIP LOCKED)
+		RETURNING s.id, s.user_id, s.sender_id, s.receiver_id, s.currency, s.amount, s.convert_currency, s.recurrence, s.day_of_month, s.status,
+		s.next_run_at, s.retry_at, s.attempts, s.last_error, s.last_run_at, s.last_transaction_id, s.created_at, s.memo, s.reference, s.metadata`).
+		WithArgs(now.Add(5*time.Minute), "active", now, 100).
+		WillReturnRows(pgxmock.NewRows(scheduleRowColumns).
+			AddRow(3, 1, 1, 2, model.SGD, model.MustParseAmount("1200"), false, model.RecurrenceNone, 0, model.ScheduleActive,
+				now.Add(-time.Hour), &retryAt, 1, "insufficient balance of a sender", &lastRunAt, nil,
+				now.Add(-48*time.Hour), "", "", map[string]string{}))
+
+	schedules, err := mockRepo.ClaimDue(now, now.Add(5*time.Minute), 100)
+	if err != nil {
+		t.Errorf("error was not expected while claiming schedules: %s", err)
+	}
+	if len(schedules) != 1 || schedules[0].ID != 3 || !schedules[0].RetryAt.Equal(retryAt) || schedules[0].Attempts != 1 || schedules[0].LastTransactionID != 0 {
+		t.Errorf("claimed schedules got: %+v", schedules)
+	}
+
+	if err := mockPool.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestSaveScheduleRun(t *testing.T) {
+	mockPool, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockPool.Close()
+
+	mockRepo := PostgreScheduleRepo{
+		DBConn: dbMockPool{mockPool},
+	}
+	now := time.Now()
+	s := model.ScheduleDB{ID: 3, Status: model.ScheduleActive, NextRunAt: now.Add(24 * time.Hour), LastRunAt: now, LastTransactionID: 42}
+
+	mockPool.ExpectExec(`UPDATE schedule SET status = CASE WHEN status=$1 THEN $2 ELSE status END, next_run_at=$3, retry_at=$4, attempts=$5, last_error=$6,
+		last_run_at=$7, last_transaction_id=$8, claimed_until=NULL WHERE id=$9`).
+		WithArgs("active", "active", s.NextRunAt, (*time.Time)(nil), 0, "", &now, &s.LastTransactionID, 3).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	if err = mockRepo.SaveRun(s); err != nil {
+		t.Errorf("error was not expected while saving run of schedule: %s", err)
+	}
+
+	if err := mockPool.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestMakeScheduledTransaction(t *testing.T) {
+	mockPool, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockPool.Close()
+
+	mockRepo := PostgreScheduleRepo{
+		DBConn: dbMockPool{mockPool},
+	}
+	now := time.Now()
+	claimed := model.ScheduleDB{ID: 3, UserID: 1, SenderBalanceID: 1, ReceiverBalanceID: 2, Amount: model.MustParseAmount("10"), Currency: model.SGD,
+		Recurrence: model.RecurrenceDaily, Status: model.ScheduleActive, NextRunAt: now.Add(-time.Minute), Attempts: 1, RetryAt: now.Add(-time.Second)}
+	run := claimed
+	run.NextRunAt, run.RetryAt, run.Attempts, run.LastRunAt = claimed.NextRunAt.AddDate(0, 0, 1), time.Time{}, 0, now
+	transfer := func(t model.TransactionDBFull) (model.TransactionDBFull, error) {
+		t.SenderBalance.Balance -= t.Amount
+		t.ReceiverBalance.Balance += t.Amount
+		t.ReceiverAmount, t.ReceiverCurrency = t.Amount, t.Currency
+		return t, nil
+	}
+	expectTransfer := func() {
+		mockPool.ExpectBeginTx(pgx.TxOptions{})
+		mockPool.ExpectQuery("SELECT id, currency, balance, held, user_id FROM balance WHERE id IN ( $1, $2) ORDER BY id FOR UPDATE").
+			WithArgs(1, 2).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "currency", "balance", "held", "user_id"}).
+				AddRow(1, model.SGD, model.MustParseAmount("100"), model.Amount(0), 1).
+				AddRow(2, model.SGD, model.MustParseAmount("50"), model.Amount(0), 2))
+		mockPool.ExpectQuery(`INSERT INTO transaction (sender_id, receiver_id, currency, amount, date, receiver_currency, receiver_amount, rate, rate_date, memo, reference, metadata, refund_of)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) RETURNING id`).
+			WithArgs(1, 2, "SGD", claimed.Amount, AnyTime{}, "SGD", claimed.Amount, model.Rate(0), (*time.Time)(nil), "", "", map[string]string{}, (*int)(nil)).
+			WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(42))
+		mockPool.ExpectExec("INSERT INTO balance_transaction (balance_id, transaction_id) VALUES ($1, $2)").
+			WithArgs(1, 42).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mockPool.ExpectExec("INSERT INTO balance_transaction (balance_id, transaction_id) VALUES ($1, $2)").
+			WithArgs(2, 42).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		for _, balance := range []model.BalanceDB{{ID: 1, Balance: model.MustParseAmount("90")}, {ID: 2, Balance: model.MustParseAmount("60")}} {
+			mockPool.ExpectExec("UPDATE balance SET balance=$1, held=$2 WHERE id=$3").
+				WithArgs(balance.Balance, model.Amount(0), balance.ID).
+				WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		}
+	}
+	transactionID := 42
+	saveRun := `UPDATE schedule SET status = CASE WHEN status=$1 THEN $2 ELSE status END, next_run_at=$3, retry_at=$4, attempts=$5, last_error=$6,
+		last_run_at=$7, last_transaction_id=$8, claimed_until=NULL WHERE id=$9 AND next_run_at=$10 AND attempts=$11`
+
+	expectTransfer()
+	mockPool.ExpectExec(saveRun).
+		WithArgs("active", "active", run.NextRunAt, (*time.Time)(nil), 0, "", &now, &transactionID, 3, claimed.NextRunAt, 1).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mockPool.ExpectCommit()
+
+	saved, err := mockRepo.MakeScheduledTransaction(claimed, run, transfer)
+	if err != nil {
+		t.Errorf("error was not expected while making scheduled transaction: %s", err)
+	}
+	if saved.LastTransactionID != 42 {
+		t.Errorf("last transaction ID got: %d; want: 42", saved.LastTransactionID)
+	}
+
+	// run was saved by other instance, the transfer is rolled back
+	expectTransfer()
+	mockPool.ExpectExec(saveRun).
+		WithArgs("active", "active", run.NextRunAt, (*time.Time)(nil), 0, "", &now, &transactionID, 3, claimed.NextRunAt, 1).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+	mockPool.ExpectRollback()
+
+	if _, err = mockRepo.MakeScheduledTransaction(claimed, run, transfer); err != ErrScheduleRunSaved {
+		t.Errorf("error got: %v; want: %v", err, ErrScheduleRunSaved)
+	}
+	if err := mockPool.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/labstack/gommon/log"
+	"zuzanna.com/walletapi/model"
+)
+
+var ErrRefreshTokenRevoked = errors.New("refresh token already revoked")
+
+type TokenRepo interface {
+	CreateRefreshToken(t model.RefreshTokenDB) error
+	GetRefreshToken(hash string) (model.RefreshTokenDB, error)
+	// RotateRefreshToken revokes token with given hash and saves its successor in one DB transaction.
+	// Returns ErrRefreshTokenRevoked when the token was already revoked, e.g. rotated by concurrent request.
+	RotateRefreshToken(hash string, next model.RefreshTokenDB) error
+	RevokeRefreshTokenFamily(family string, revokedAt time.Time) error
+	// RevokeAccessToken remembers ID (jti) of access token until the token expires.
+	RevokeAccessToken(jti string, expiresAt time.Time) error
+	// RevokeUserTokens revokes all refresh tokens of the user and all access tokens issued before revokedAt.
+	RevokeUserTokens(userID int, revokedAt time.Time) error
+	// IsAccessTokenRevoked checks if the token was revoked by its ID or by RevokeUserTokens.
+	IsAccessTokenRevoked(jti string, userID int, issuedAt time.Time) (bool, error)
+}
+
+type PostgreTokenRepo struct {
+	DBConn pgxConn
+}
+
+func NewPostgreTokenRepo(pool *pgxpool.Pool) *PostgreTokenRepo {
+	return &PostgreTokenRepo{DBConn: pool}
+}
+
+func (r PostgreTokenRepo) CreateRefreshToken(t model.RefreshTokenDB) error {
+	_, err := r.DBConn.Exec(context.Background(),
+		"INSERT INTO refresh_token (token_hash, family, user_id, expires_at, created_at) VALUES ($1, $2, $3, $4, $5)",
+		t.TokenHash, t.Family, t.UserID, t.ExpiresAt, t.CreatedAt)
+	if err != nil {
+		log.Errorf("#CreateRefreshToken(...) error while saving refresh token for user with ID %d; error %v", t.UserID, err)
+		return err
+	}
+	return nil
+}
+
+func (r PostgreTokenRepo) GetRefreshToken(hash string) (model.RefreshTokenDB, error) {
+	t := model.RefreshTokenDB{}
+	var revokedAt *time.Time
+	err := r.DBConn.QueryRow(context.Background(),
+		"SELECT id, token_hash, family, user_id, expires_at, revoked_at, created_at FROM refresh_token WHERE token_hash=$1",
+		hash).Scan(&t.ID, &t.TokenHash, &t.Family, &t.UserID, &t.ExpiresAt, &revokedAt, &t.CreatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return model.RefreshTokenDB{}, ErrRecordNotFound
+		}
+		log.Errorf("#GetRefreshToken(...) error while reading refresh token; error %v", err)
+		return model.RefreshTokenDB{}, err
+	}
+	if revokedAt != nil {
+		t.RevokedAt = *revokedAt
+	}
+	return t, nil
+}
+
+func (r PostgreTokenRepo) RotateRefreshToken(hash string, next model.RefreshTokenDB) (err error) {
+	tx, err := r.DBConn.BeginTx(context.Background(), pgx.TxOptions{})
+	if err != nil {
+		log.Errorf("#RotateRefreshToken(...) failed, error: %v", err)
+		return fmt.Errorf("unable to start a transaction; error: %w", err)
+	}
+	defer func() {
+		err = finishTx(err, tx)
+	}()
+
+	tag, err := tx.Exec(context.Background(),
+		"UPDATE refresh_token SET revoked_at=$1 WHERE token_hash=$2 AND revoked_at IS NULL",
+		next.CreatedAt, hash)
+	if err != nil {
+		log.Errorf("#RotateRefreshToken(...) error while revoking refresh token; error %v", err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrRefreshTokenRevoked
+	}
+
+	_, err = tx.Exec(context.Background(),
+		"INSERT INTO refresh_token (token_hash, family, user_id, expires_at, created_at) VALUES ($1, $2, $3, $4, $5)",
+		next.TokenHash, next.Family, next.UserID, next.ExpiresAt, next.CreatedAt)
+	if err != nil {
+		log.Errorf("#RotateRefreshToken(...) error while saving refresh token for user with ID %d; error %v", next.UserID, err)
+		return err
+	}
+	return nil
+}
+
+func (r PostgreTokenRepo) RevokeRefreshTokenFamily(family string, revokedAt time.Time) error {
+	_, err := r.DBConn.Exec(context.Background(),
+		"UPDATE refresh_token SET revoked_at=$1 WHERE family=$2 AND revoked_at IS NULL", revokedAt, family)
+	if err != nil {
+		log.Errorf("#RevokeRefreshTokenFamily(...) error while revoking refresh tokens of family %s; error %v", family, err)
+		return err
+	}
+	return nil
+}
+
+func (r PostgreTokenRepo) RevokeAccessToken(jti string, expiresAt time.Time) error {
+	_, err := r.DBConn.Exec(context.Background(),
+		"INSERT INTO revoked_token (jti, expires_at) VALUES ($1, $2) ON CONFLICT (jti) DO NOTHING", jti, expiresAt)
+	if err != nil {
+		log.Errorf("#RevokeAccessToken(...) error while revoking access token %s; error %v", jti, err)
+		return err
+	}
+	return nil
+}
+
+func (r PostgreTokenRepo) RevokeUserTokens(userID int, revokedAt time.Time) (err error) {
+	tx, err := r.DBConn.BeginTx(context.Background(), pgx.TxOptions{})
+	if err != nil {
+		log.Errorf("#RevokeUserTokens(...) failed, error: %v", err)
+		return fmt.Errorf("unable to start a transaction; error: %w", err)
+	}
+	defer func() {
+		err = finishTx(err, tx)
+	}()
+
+	_, err = tx.Exec(context.Background(),
+		"UPDATE refresh_token SET revoked_at=$1 WHERE user_id=$2 AND revoked_at IS NULL", revokedAt, userID)
+	if err != nil {
+		log.Errorf("#RevokeUserTokens(...) error while revoking refresh tokens of user with ID %d; error %v", userID, err)
+		return err
+	}
+	_, err = tx.Exec(context.Background(),
+		"INSERT INTO session_revocation (user_id, revoked_at) VALUES ($1, $2) ON CONFLICT (user_id) DO UPDATE SET revoked_at=EXCLUDED.revoked_at",
+		userID, revokedAt)
+	if err != nil {
+		log.Errorf("#RevokeUserTokens(...) error while revoking access tokens of user with ID %d; error %v", userID, err)
+		return err
+	}
+	return nil
+}
+
+func (r PostgreTokenRepo) IsAccessTokenRevoked(jti string, userID int, issuedAt time.Time) (bool, error) {
+	var revoked bool
+	err := r.DBConn.QueryRow(context.Background(),
+		`SELECT EXISTS (SELECT 1 FROM revoked_token WHERE jti=$1)
+		OR EXISTS (SELECT 1 FROM session_revocation WHERE user_id=$2 AND revoked_at > $3)`, jti, userID, issuedAt).Scan(&revoked)
+	if err != nil {
+		log.Errorf("#IsAccessTokenRevoked(...) error while checking access token %s; error %v", jti, err)
+		return false, err
+	}
+	return revoked, nil
+}
@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pashagolub/pgxmock"
+	"zuzanna.com/walletapi/model"
+)
+
+func TestGetRefreshToken(t *testing.T) {
+	mockPool, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockPool.Close()
+
+	mockRepo := PostgreTokenRepo{
+		DBConn: dbMockPool{mockPool},
+	}
+	now := time.Now()
+	want := model.RefreshTokenDB{ID: 1, TokenHash: "hash", Family: "family", UserID: 2, ExpiresAt: now.Add(time.Hour), CreatedAt: now}
+
+	rows := pgxmock.NewRows([]string{"id", "token_hash", "family", "user_id", "expires_at", "revoked_at", "created_at"}).
+		AddRow(want.ID, want.TokenHash, want.Family, want.UserID, want.ExpiresAt, nil, want.CreatedAt)
+	mockPool.ExpectQuery("SELECT id, token_hash, family, user_id, expires_at, revoked_at, created_at FROM refresh_token WHERE token_hash=$1").
+		WithArgs("hash").WillReturnRows(rows)
+	mockPool.ExpectQuery("SELECT id, token_hash, family, user_id, expires_at, revoked_at, created_at FROM refresh_token WHERE token_hash=$1").
+		WithArgs("unknown").WillReturnError(pgx.ErrNoRows)
+
+	got, err := mockRepo.GetRefreshToken("hash")
+	if err != nil {
+		t.Errorf("error was not expected while reading refresh token: %s", err)
+	}
+	if got != want {
+		t.Errorf("refresh token got: %+v; want: %+v", got, want)
+	}
+	_, err = mockRepo.GetRefreshToken("unknown")
+	if err != ErrRecordNotFound {
+		t.Errorf("error got: %v; want: %v", err, ErrRecordNotFound)
+	}
+
+	if err := mockPool.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRotateRefreshToken(t *testing.T) {
+	mockPool, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockPool.Close()
+
+	mockRepo := PostgreTokenRepo{
+		DBConn: dbMockPool{mockPool},
+	}
+	now := time.Now()
+	next := model.RefreshTokenDB{TokenHash: "next", Family: "family", UserID: 2, ExpiresAt: now.Add(time.Hour), CreatedAt: now}
+
+	mockPool.ExpectBeginTx(pgx.TxOptions{})
+	mockPool.ExpectExec("UPDATE refresh_token SET revoked_at=$1 WHERE token_hash=$2 AND revoked_at IS NULL").
+		WithArgs(now, "old").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mockPool.ExpectExec("INSERT INTO refresh_token (token_hash, family, user_id, expires_at, created_at) VALUES ($1, $2, $3, $4, $5)").
+		WithArgs(next.TokenHash, next.Family, next.UserID, next.ExpiresAt, next.CreatedAt).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mockPool.ExpectCommit()
+
+	if err := mockRepo.RotateRefreshToken("old", next); err != nil {
+		t.Errorf("error was not expected while rotating refresh token: %s", err)
+	}
+
+	// token already rotated - successor is not saved
+	mockPool.ExpectBeginTx(pgx.TxOptions{})
+	mockPool.ExpectExec("UPDATE refresh_token SET revoked_at=$1 WHERE token_hash=$2 AND revoked_at IS NULL").
+		WithArgs(now, "old").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+	mockPool.ExpectRollback()
+
+	if err := mockRepo.RotateRefreshToken("old", next); err != ErrRefreshTokenRevoked {
+		t.Errorf("error got: %v; want: %v", err, ErrRefreshTokenRevoked)
+	}
+
+	if err := mockPool.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRevokeAccessToken(t *testing.T) {
+	mockPool, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockPool.Close()
+
+	mockRepo := PostgreTokenRepo{
+		DBConn: dbMockPool{mockPool},
+	}
+	expiresAt := time.Now().Add(time.Hour)
+
+	mockPool.ExpectExec("INSERT INTO revoked_token (jti, expires_at) VALUES ($1, $2) ON CONFLICT (jti) DO NOTHING").
+		WithArgs("jti1", expiresAt).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	issuedAt := time.Now().Add(-time.Minute)
+	mockPool.ExpectQuery(`SELECT EXISTS (SELECT 1 FROM revoked_token WHERE jti=$1)
+		OR EXISTS (SELECT 1 FROM session_revocation WHERE user_id=$2 AND revoked_at > $3)`).
+		WithArgs("jti1", 1, issuedAt).
+		WillReturnRows(pgxmock.NewRows([]string{"exists"}).AddRow(true))
+
+	if err := mockRepo.RevokeAccessToken("jti1", expiresAt); err != nil {
+		t.Errorf("error was not expected while revoking access token: %s", err)
+	}
+	revoked, err := mockRepo.IsAccessTokenRevoked("jti1", 1, issuedAt)
+	if err != nil {
+		t.Errorf("error was not expected while checking access token: %s", err)
+	}
+	if !revoked {
+		t.Errorf("access token revoked got: %t; want: true", revoked)
+	}
+
+	if err := mockPool.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRevokeUserTokens(t *testing.T) {
+	mockPool, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockPool.Close()
+
+	mockRepo := PostgreTokenRepo{
+		DBConn: dbMockPool{mockPool},
+	}
+	revokedAt := time.Now()
+
+	mockPool.ExpectBeginTx(pgx.TxOptions{})
+	mockPool.ExpectExec("UPDATE refresh_token SET revoked_at=$1 WHERE user_id=$2 AND revoked_at IS NULL").
+		WithArgs(revokedAt, 1).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 2))
+	mockPool.ExpectExec("INSERT INTO session_revocation (user_id, revoked_at) VALUES ($1, $2) ON CONFLICT (user_id) DO UPDATE SET revoked_at=EXCLUDED.revoked_at").
+		WithArgs(1, revokedAt).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mockPool.ExpectCommit()
+
+	if err = mockRepo.RevokeUserTokens(1, revokedAt); err != nil {
+		t.Errorf("error was not expected while revoking tokens of user: %s", err)
+	}
+
+	if err := mockPool.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
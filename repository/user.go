@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/labstack/gommon/log"
+	"zuzanna.com/walletapi/model"
+)
+
+var ErrLoginExists = errors.New("login already exists")
+
+type UserRepo interface {
+	// Create inserts user, its credentials and initial empty balance in one DB transaction.
+	Create(u model.UserDB, c model.Credentials, currency model.Currency) (model.UserDB, error)
+	Get(userID int) (model.UserDB, error)
+	// Update changes only fields set in the patch and returns the updated user.
+	Update(userID int, patch model.UserPatch) (model.UserDB, error)
+	// GetByBalanceIDs returns owners of the balances by balance ID, unknown balances are skipped.
+	GetByBalanceIDs(balanceIDs []int) (map[int]model.UserDB, error)
+	// GetIDByAlias returns ID of the user with the login or, when there is no such login, with the alias
+	// from user_alias table. Returns ErrRecordNotFound when there is no such user.
+	GetIDByAlias(login, alias string) (int, error)
+}
+
+type PostgreUserRepo struct {
+	DBConn pgxConn
+}
+
+func NewPostgreUserRepo(pool *pgxpool.Pool) *PostgreUserRepo {
+	return &PostgreUserRepo{DBConn: pool}
+}
+
+func (r PostgreUserRepo) Create(u model.UserDB, c model.Credentials, currency model.Currency) (user model.UserDB, err error) {
+	tx, err := r.DBConn.BeginTx(context.Background(), pgx.TxOptions{})
+	if err != nil {
+		log.Errorf("#Create(...) failed, error: %v", err)
+		return model.UserDB{}, fmt.Errorf("unable to start a transaction; error: %w", err)
+	}
+	defer func() {
+		err = finishTx(err, tx)
+	}()
+
+	err = tx.QueryRow(context.Background(),
+		`INSERT INTO "user" (first_name, last_name, age) VALUES ($1, $2, $3) RETURNING id`,
+		u.FirstName, u.LastName, u.Age).Scan(&u.ID)
+	if err != nil {
+		log.Errorf("#Create(...) error while inserting into user table: %v", err)
+		return model.UserDB{}, err
+	}
+
+	var credentialsID int
+	err = tx.QueryRow(context.Background(),
+		"INSERT INTO credentials (login, password, user_id) VALUES ($1, $2, $3) ON CONFLICT (login) DO NOTHING RETURNING id",
+		c.Login, c.Password, u.ID).Scan(&credentialsID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return model.UserDB{}, ErrLoginExists
+		}
+		log.Errorf("#Create(...) error while inserting into credentials table: %v", err)
+		return model.UserDB{}, err
+	}
+
+	_, err = tx.Exec(context.Background(),
+		"INSERT INTO balance (currency, balance, user_id) VALUES ($1, $2, $3)",
+		string(currency), model.Amount(0), u.ID)
+	if err != nil {
+		log.Errorf("#Create(...) error while inserting into balance table: %v", err)
+		return model.UserDB{}, err
+	}
+
+	return u, nil
+}
+
+func (r PostgreUserRepo) Get(userID int) (model.UserDB, error) {
+	u := model.UserDB{}
+	err := r.DBConn.QueryRow(context.Background(),
+		`SELECT id, first_name, last_name, age FROM "user" WHERE id=$1`, userID).Scan(&u.ID, &u.FirstName, &u.LastName, &u.Age)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return model.UserDB{}, ErrRecordNotFound
+		}
+		log.Errorf("#Get(...) error while reading user with ID %d; error %v", userID, err)
+		return model.UserDB{}, err
+	}
+	return u, nil
+}
+
+func (r PostgreUserRepo) Update(userID int, patch model.UserPatch) (model.UserDB, error) {
+	u := model.UserDB{}
+	err := r.DBConn.QueryRow(context.Background(),
+		`UPDATE "user" SET first_name=COALESCE($1, first_name), last_name=COALESCE($2, last_name), age=COALESCE($3, age)
+		WHERE id=$4 RETURNING id, first_name, last_name, age`,
+		patch.FirstName, patch.LastName, patch.Age, userID).Scan(&u.ID, &u.FirstName, &u.LastName, &u.Age)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return model.UserDB{}, ErrRecordNotFound
+		}
+		log.Errorf("#Update(...) error while updating user with ID %d; error %v", userID, err)
+		return model.UserDB{}, err
+	}
+	return u, nil
+}
+
+func (r PostgreUserRepo) GetByBalanceIDs(balanceIDs []int) (map[int]model.UserDB, error) {
+	rows, err := r.DBConn.Query(context.Background(),
+		`SELECT b.id, u.id, u.first_name, u.last_name, u.age FROM balance b JOIN "user" u ON u.id = b.user_id WHERE b.id = ANY($1)`,
+		balanceIDs)
+	if err != nil {
+		log.Errorf("#GetByBalanceIDs(...) error while reading owners of balances %v; error %v", balanceIDs, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	owners := map[int]model.UserDB{}
+	for rows.Next() {
+		var balanceID int
+		u := model.UserDB{}
+		if err = rows.Scan(&balanceID, &u.ID, &u.FirstName, &u.LastName, &u.Age); err != nil {
+			log.Errorf("#GetByBalanceIDs(...) error while scanning owner of balance; error %v", err)
+			return nil, err
+		}
+		owners[balanceID] = u
+	}
+	if err = rows.Err(); err != nil {
+		log.Errorf("#GetByBalanceIDs(...) error while reading owners of balances %v; error %v", balanceIDs, err)
+		return nil, err
+	}
+	return owners, nil
+}
+
+func (r PostgreUserRepo) GetIDByAlias(login, alias string) (int, error) {
+	var userID, priority int
+	err := r.DBConn.QueryRow(context.Background(),
+		`SELECT user_id, 1 FROM credentials WHERE login=$1
+		UNION ALL SELECT user_id, 2 FROM user_alias WHERE alias=$2 ORDER BY 2 LIMIT 1`,
+		login, alias).Scan(&userID, &priority)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, ErrRecordNotFound
+		}
+		log.Errorf("#GetIDByAlias(...) error while reading user with alias %s; error %v", alias, err)
+		return 0, err
+	}
+	return userID, nil
+}
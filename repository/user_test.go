@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pashagolub/pgxmock"
+	"zuzanna.com/walletapi/model"
+)
+
+func TestCreateUser(t *testing.T) {
+	mockPool, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockPool.Close()
+
+	mockRepo := PostgreUserRepo{
+		DBConn: dbMockPool{mockPool},
+	}
+	user := model.UserDB{FirstName: "Alice", LastName: "Cruz", Age: 25}
+	credentials := model.Credentials{Login: "ala11", Password: "bcrypt$hash"}
+
+	mockPool.ExpectBeginTx(pgx.TxOptions{})
+	mockPool.ExpectQuery(`INSERT INTO "user" (first_name, last_name, age) VALUES ($1, $2, $3) RETURNING id`).
+		WithArgs(user.FirstName, user.LastName, user.Age).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(5))
+	mockPool.ExpectQuery("INSERT INTO credentials (login, password, user_id) VALUES ($1, $2, $3) ON CONFLICT (login) DO NOTHING RETURNING id").
+		WithArgs(credentials.Login, credentials.Password, 5).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(7))
+	mockPool.ExpectExec("INSERT INTO balance (currency, balance, user_id) VALUES ($1, $2, $3)").
+		WithArgs("SGD", model.Amount(0), 5).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mockPool.ExpectCommit()
+
+	got, err := mockRepo.Create(user, credentials, model.SGD)
+	if err != nil {
+		t.Errorf("error was not expected while creating user: %s", err)
+	}
+	user.ID = 5
+	if got != user {
+		t.Errorf("user got: %+v; want: %+v", got, user)
+	}
+
+	// login already exists - whole transaction is rolled back
+	mockPool.ExpectBeginTx(pgx.TxOptions{})
+	mockPool.ExpectQuery(`INSERT INTO "user" (first_name, last_name, age) VALUES ($1, $2, $3) RETURNING id`).
+		WithArgs(user.FirstName, user.LastName, user.Age).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(6))
+	mockPool.ExpectQuery("INSERT INTO credentials (login, password, user_id) VALUES ($1, $2, $3) ON CONFLICT (login) DO NOTHING RETURNING id").
+		WithArgs(credentials.Login, credentials.Password, 6).
+		WillReturnError(pgx.ErrNoRows)
+	mockPool.ExpectRollback()
+
+	_, err = mockRepo.Create(model.UserDB{FirstName: "Alice", LastName: "Cruz", Age: 25}, credentials, model.SGD)
+	if err != ErrLoginExists {
+		t.Errorf("error got: %v; want: %v", err, ErrLoginExists)
+	}
+
+	if err := mockPool.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestUpdateUser(t *testing.T) {
+	mockPool, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockPool.Close()
+
+	mockRepo := PostgreUserRepo{
+		DBConn: dbMockPool{mockPool},
+	}
+	lastName := "Kowalska"
+	var noName *string
+	var noAge *int
+	patch := model.UserPatch{LastName: &lastName}
+
+	query := `UPDATE "user" SET first_name=COALESCE($1, first_name), last_name=COALESCE($2, last_name), age=COALESCE($3, age)
+		WHERE id=$4 RETURNING id, first_name, last_name, age`
+	mockPool.ExpectQuery(query).WithArgs(noName, &lastName, noAge, 1).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "first_name", "last_name", "age"}).AddRow(1, "Alice", "Kowalska", 25))
+	mockPool.ExpectQuery(query).WithArgs(noName, &lastName, noAge, 9).
+		WillReturnError(pgx.ErrNoRows)
+
+	got, err := mockRepo.Update(1, patch)
+	want := model.UserDB{ID: 1, FirstName: "Alice", LastName: "Kowalska", Age: 25}
+	if err != nil || got != want {
+		t.Errorf("user got: %+v (%v); want: %+v", got, err, want)
+	}
+	if _, err = mockRepo.Update(9, patch); err != ErrRecordNotFound {
+		t.Errorf("error got: %v; want: %v", err, ErrRecordNotFound)
+	}
+
+	if err := mockPool.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetUsersByBalanceIDs(t *testing.T) {
+	mockPool, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockPool.Close()
+
+	mockRepo := PostgreUserRepo{
+		DBConn: dbMockPool{mockPool},
+	}
+
+	mockPool.ExpectQuery(`SELECT b.id, u.id, u.first_name, u.last_name, u.age FROM balance b JOIN "user" u ON u.id = b.user_id WHERE b.id = ANY($1)`).
+		WithArgs([]int{1, 2, 5}).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "id", "first_name", "last_name", "age"}).
+			AddRow(1, 1, "Alice", "Cruz", 25).
+			AddRow(5, 1, "Alice", "Cruz", 25).
+			AddRow(2, 2, "Zuzanna", "Zazu", 18))
+
+	got, err := mockRepo.GetByBalanceIDs([]int{1, 2, 5})
+	if err != nil {
+		t.Errorf("error was not expected while reading owners of balances: %s", err)
+	}
+	alice := model.UserDB{ID: 1, FirstName: "Alice", LastName: "Cruz", Age: 25}
+	if len(got) != 3 || got[1] != alice || got[5] != alice || got[2].FirstName != "Zuzanna" {
+		t.Errorf("owners got: %+v; want owners of balances 1, 2 and 5", got)
+	}
+
+	if err := mockPool.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetUserIDByAlias(t *testing.T) {
+	mockPool, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockPool.Close()
+
+	mockRepo := PostgreUserRepo{
+		DBConn: dbMockPool{mockPool},
+	}
+
+	query := `SELECT user_id, 1 FROM credentials WHERE login=$1
+		UNION ALL SELECT user_id, 2 FROM user_alias WHERE alias=$2 ORDER BY 2 LIMIT 1`
+	mockPool.ExpectQuery(query).WithArgs("Alice@Example.com", "alice@example.com").
+		WillReturnRows(pgxmock.NewRows([]string{"user_id", "priority"}).AddRow(1, 2))
+	mockPool.ExpectQuery(query).WithArgs("nobody", "nobody").
+		WillReturnError(pgx.ErrNoRows)
+
+	if userID, err := mockRepo.GetIDByAlias("Alice@Example.com", "alice@example.com"); err != nil || userID != 1 {
+		t.Errorf("user ID got: %d (%v); want: 1", userID, err)
+	}
+	if _, err = mockRepo.GetIDByAlias("nobody", "nobody"); err != ErrRecordNotFound {
+		t.Errorf("error got: %v; want: %v", err, ErrRecordNotFound)
+	}
+
+	if err := mockPool.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
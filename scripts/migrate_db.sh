@@ -0,0 +1,22 @@
+#!/usr/bin/env bash
+
+# Applies scripts/migrations/*.sql, which were not applied yet, in the order of their names.
+# Applied migrations are recorded in schema_migration table, each migration runs in a single transaction.
+
+MIGRATIONS_DIR="$(dirname "$0")/migrations"
+
+psql -h db -U postgres -d wallets -c 'CREATE TABLE IF NOT EXISTS "schema_migration"(name VARCHAR(255) PRIMARY KEY NOT NULL, applied_at TIMESTAMP NOT NULL);'
+
+for migration in "$MIGRATIONS_DIR"/*.sql; do
+    name=$(basename "$migration")
+    applied=$(psql -h db -U postgres -d wallets -tA -c "SELECT 1 FROM schema_migration WHERE name='$name';")
+    if [ "$applied" = "1" ]; then
+        continue
+    fi
+    echo "applying migration $name"
+    if ! psql -h db -U postgres -d wallets -v ON_ERROR_STOP=1 --single-transaction \
+        -f "$migration" -c "INSERT INTO schema_migration(name, applied_at) VALUES('$name', now());"; then
+        echo "migration $name failed" >&2
+        exit 1
+    fi
+done
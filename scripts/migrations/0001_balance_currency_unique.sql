@@ -0,0 +1,3 @@
+-- user has at most one balance per currency, see PostgreBalanceRepo.CreateBalance. Balances of a user in the same currency
+-- have to be merged before the migration, otherwise it fails.
+ALTER TABLE "balance" ADD UNIQUE (user_ID, currency);
@@ -0,0 +1,9 @@
+-- receiver_amount in receiver_currency is credited to the receiver balance, rate and rate_date are set only for converted
+-- transfers, see TransactionServiceImpl.convert. Transfers made before were in one currency, so the receiver got the amount.
+ALTER TABLE "transaction"
+    ADD COLUMN receiver_currency VARCHAR(3),
+    ADD COLUMN receiver_amount NUMERIC(12, 2),
+    ADD COLUMN rate NUMERIC(18, 6),
+    ADD COLUMN rate_date TIMESTAMP;
+UPDATE "transaction" SET receiver_currency=currency, receiver_amount=amount;
+ALTER TABLE "transaction" ALTER COLUMN receiver_currency SET NOT NULL, ALTER COLUMN receiver_amount SET NOT NULL;
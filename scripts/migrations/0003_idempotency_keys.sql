@@ -0,0 +1,4 @@
+-- response of request with Idempotency-Key header is stored for IDEMPOTENCY_KEY_TTL, see PostgreIdempotencyRepo.
+-- response_code is 0 while the request is in progress.
+CREATE TABLE "idempotency_key"(user_ID INT references "user"(ID) NOT NULL, key VARCHAR(255) NOT NULL, fingerprint VARCHAR(64) NOT NULL,
+    response_code INT NOT NULL DEFAULT 0, response_body TEXT NOT NULL DEFAULT '', created_at TIMESTAMP NOT NULL, PRIMARY KEY (user_ID, key));
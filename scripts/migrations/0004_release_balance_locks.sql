@@ -0,0 +1,3 @@
+-- transfers lock balances with SELECT ... FOR UPDATE, see PostgreBalanceRepo.MakeTransaction, and do not set locked flag any more.
+-- Flags set by older versions are left only by requests which crashed between locking and unlocking the balances.
+UPDATE "balance" SET locked=false WHERE locked;
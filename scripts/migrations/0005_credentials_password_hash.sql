@@ -0,0 +1,2 @@
+-- passwords are stored as bcrypt hashes (60 characters), legacy base64 passwords are re-hashed on login, see AuthServiceImpl.Authenticate.
+ALTER TABLE "credentials" ALTER COLUMN password TYPE VARCHAR(255);
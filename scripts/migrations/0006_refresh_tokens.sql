@@ -0,0 +1,6 @@
+-- refresh tokens are stored hashed, all tokens issued by rotation of one login share family, see PostgreTokenRepo.
+CREATE TABLE "refresh_token"(ID SERIAL PRIMARY KEY NOT NULL, token_hash VARCHAR(64) NOT NULL UNIQUE, family VARCHAR(32) NOT NULL,
+    user_ID INT references "user"(ID) NOT NULL, expires_at TIMESTAMP NOT NULL, revoked_at TIMESTAMP, created_at TIMESTAMP NOT NULL);
+CREATE INDEX ON "refresh_token"(family);
+-- jti of revoked JWT tokens, kept until the token expires.
+CREATE TABLE "revoked_token"(jti VARCHAR(32) PRIMARY KEY NOT NULL, expires_at TIMESTAMP NOT NULL);
@@ -0,0 +1,5 @@
+-- role is put to JWT claims and checked by RequireRole, users created before are regular users.
+ALTER TABLE "user" ADD COLUMN role VARCHAR(16) NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'support', 'admin'));
+
+UPDATE "user" SET role='support' WHERE ID IN (SELECT user_ID FROM "credentials" WHERE login='support01');
+UPDATE "user" SET role='admin' WHERE ID IN (SELECT user_ID FROM "credentials" WHERE login='admin01');
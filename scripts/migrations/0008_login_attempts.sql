@@ -0,0 +1,2 @@
+-- failed login attempts per login ("login:" prefix) and per client IP ("ip:" prefix), see LoginThrottleImpl.
+CREATE TABLE "login_attempt"(key VARCHAR(255) PRIMARY KEY NOT NULL, failures INT NOT NULL DEFAULT 0, last_failure_at TIMESTAMP, locked_until TIMESTAMP);
@@ -0,0 +1,6 @@
+-- TOTP secret of the user is used for login once it is confirmed, last_used_step prevents reuse of a code, see MFAServiceImpl.
+CREATE TABLE "mfa"(user_ID INT PRIMARY KEY references "user"(ID) NOT NULL, secret VARCHAR(64) NOT NULL, confirmed_at TIMESTAMP,
+    last_used_step BIGINT NOT NULL DEFAULT 0);
+-- recovery codes are stored hashed, every code can be used once.
+CREATE TABLE "recovery_code"(ID SERIAL PRIMARY KEY NOT NULL, user_ID INT references "user"(ID) NOT NULL, code_hash VARCHAR(64) NOT NULL, used_at TIMESTAMP);
+CREATE INDEX ON "recovery_code"(user_ID, code_hash);
@@ -0,0 +1,8 @@
+-- refresh tokens of the user are revoked when the password is changed or reset.
+CREATE INDEX ON "refresh_token"(user_ID);
+-- JWT tokens of the user issued before revoked_at are rejected, see PostgreTokenRepo.RevokeUserTokens.
+CREATE TABLE "session_revocation"(user_ID INT PRIMARY KEY references "user"(ID) NOT NULL, revoked_at TIMESTAMP NOT NULL);
+-- password reset tokens are stored hashed, every token can be used once.
+CREATE TABLE "password_reset"(token_hash VARCHAR(64) PRIMARY KEY NOT NULL, user_ID INT references "user"(ID) NOT NULL, expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP, created_at TIMESTAMP NOT NULL);
+CREATE INDEX ON "password_reset"(user_ID);
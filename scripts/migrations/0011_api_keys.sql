@@ -0,0 +1,5 @@
+-- API keys are stored hashed, prefix identifies the key in listings, see PostgreAPIKeyRepo.
+CREATE TABLE "api_key"(ID SERIAL PRIMARY KEY NOT NULL, key_hash VARCHAR(64) NOT NULL UNIQUE, prefix VARCHAR(16) NOT NULL,
+    user_ID INT references "user"(ID) NOT NULL, name VARCHAR(64) NOT NULL, scopes TEXT[] NOT NULL, allowed_ips TEXT[] NOT NULL DEFAULT '{}',
+    expires_at TIMESTAMP NOT NULL, revoked_at TIMESTAMP, created_at TIMESTAMP NOT NULL);
+CREATE INDEX ON "api_key"(user_ID);
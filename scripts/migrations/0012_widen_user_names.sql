@@ -0,0 +1,2 @@
+-- first_name and last_name were too short for many real names, the API accepts names up to 50 characters.
+ALTER TABLE "user" ALTER COLUMN first_name TYPE VARCHAR(50), ALTER COLUMN last_name TYPE VARCHAR(50);
@@ -0,0 +1,6 @@
+-- e-mail and phone aliases of users, to send money by alias instead of balance ID. Aliases are stored normalized,
+-- e-mails in lower case and phones as + followed by digits, see model.NormalizeAlias.
+CREATE TABLE "user_alias"(alias VARCHAR(255) PRIMARY KEY NOT NULL, user_ID INT references "user"(ID) NOT NULL);
+CREATE INDEX ON "user_alias"(user_ID);
+
+INSERT INTO "user_alias"(alias, user_ID) VALUES('alice.cruz@example.com', 1), ('+6591234567', 1), ('zuzanna@example.com', 2), ('+6598765432', 3);
@@ -0,0 +1,3 @@
+-- transaction history is read by sender or receiver balance, ordered by date and ID, see PostgreBalanceRepo.GetTransactions.
+CREATE INDEX ON "transaction"(sender_ID, "date" DESC, ID DESC);
+CREATE INDEX ON "transaction"(receiver_ID, "date" DESC, ID DESC);
@@ -0,0 +1,7 @@
+-- memo, reference and metadata are given by the sender, see TransactionRequest.IsValid for the limits.
+ALTER TABLE "transaction"
+    ADD COLUMN memo VARCHAR(140) NOT NULL DEFAULT '',
+    ADD COLUMN reference VARCHAR(64) NOT NULL DEFAULT '',
+    ADD COLUMN metadata JSONB NOT NULL DEFAULT '{}';
+CREATE INDEX ON "transaction"(reference) WHERE reference <> '';
+CREATE INDEX ON "transaction" USING GIN (metadata);
@@ -0,0 +1,6 @@
+-- refund is a transaction from receiver back to sender balance of the refunded transaction, see PostgreBalanceRepo.RefundTransaction.
+-- refunded_amount is total of refunds in receiver currency of the refunded transaction.
+ALTER TABLE "transaction"
+    ADD COLUMN refund_of INT REFERENCES "transaction"(ID),
+    ADD COLUMN refunded_amount NUMERIC(12, 2) NOT NULL DEFAULT 0;
+CREATE INDEX ON "transaction"(refund_of) WHERE refund_of IS NOT NULL;
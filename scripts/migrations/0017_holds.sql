@@ -0,0 +1,11 @@
+-- held is total of active holds of the balance, it is not available for transfers, see PostgreBalanceRepo.CreateHold.
+ALTER TABLE "balance" ADD COLUMN held NUMERIC(12, 2) NOT NULL DEFAULT 0;
+CREATE TABLE "hold"(ID SERIAL PRIMARY KEY NOT NULL, sender_ID INT references "balance"(ID) NOT NULL, receiver_ID INT references "balance"(ID) NOT NULL,
+    currency VARCHAR(3) NOT NULL, amount NUMERIC(12, 2) NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'captured', 'voided', 'expired')),
+    captured_amount NUMERIC(12, 2) NOT NULL DEFAULT 0, transaction_ID INT references "transaction"(ID),
+    created_at TIMESTAMP NOT NULL, expires_at TIMESTAMP NOT NULL,
+    memo VARCHAR(140) NOT NULL DEFAULT '', reference VARCHAR(64) NOT NULL DEFAULT '', metadata JSONB NOT NULL DEFAULT '{}');
+CREATE INDEX ON "hold"(sender_ID);
+CREATE INDEX ON "hold"(receiver_ID);
+CREATE INDEX ON "hold"(expires_at) WHERE status = 'active';
@@ -0,0 +1,11 @@
+-- scheduled transfers are executed by TransferScheduler, claimed_until is the lease of the instance executing the transfer.
+CREATE TABLE "schedule"(ID SERIAL PRIMARY KEY NOT NULL, user_ID INT references "user"(ID) NOT NULL,
+    sender_ID INT references "balance"(ID) NOT NULL, receiver_ID INT references "balance"(ID) NOT NULL,
+    currency VARCHAR(3) NOT NULL DEFAULT '', amount NUMERIC(12, 2) NOT NULL, convert_currency BOOLEAN NOT NULL DEFAULT false,
+    recurrence VARCHAR(16) NOT NULL DEFAULT '' CHECK (recurrence IN ('', 'daily', 'weekly', 'monthly')), day_of_month INT NOT NULL DEFAULT 0,
+    status VARCHAR(16) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'paused', 'completed', 'cancelled', 'failed')),
+    next_run_at TIMESTAMP NOT NULL, retry_at TIMESTAMP, attempts INT NOT NULL DEFAULT 0, last_error VARCHAR(255) NOT NULL DEFAULT '',
+    last_run_at TIMESTAMP, last_transaction_ID INT references "transaction"(ID), claimed_until TIMESTAMP, created_at TIMESTAMP NOT NULL,
+    memo VARCHAR(140) NOT NULL DEFAULT '', reference VARCHAR(64) NOT NULL DEFAULT '', metadata JSONB NOT NULL DEFAULT '{}');
+CREATE INDEX ON "schedule"(user_ID);
+CREATE INDEX ON "schedule"((COALESCE(retry_at, next_run_at))) WHERE status = 'active';
@@ -0,0 +1,13 @@
+-- batches are processed by BatchProcessor, claimed_until is the lease of the instance processing the batch.
+CREATE TABLE "batch"(ID SERIAL PRIMARY KEY NOT NULL, user_ID INT references "user"(ID) NOT NULL, sender_ID INT references "balance"(ID) NOT NULL,
+    currency VARCHAR(3) NOT NULL, convert_currency BOOLEAN NOT NULL DEFAULT false,
+    mode VARCHAR(16) NOT NULL CHECK (mode IN ('all_or_nothing', 'best_effort')),
+    status VARCHAR(24) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'completed', 'partially_completed', 'failed')),
+    created_at TIMESTAMP NOT NULL, completed_at TIMESTAMP, claimed_until TIMESTAMP);
+CREATE INDEX ON "batch"(created_at) WHERE status = 'pending';
+-- item is completed in the same DB transaction as its transfer, so it is never transferred twice.
+CREATE TABLE "batch_item"(batch_ID INT references "batch"(ID) NOT NULL, idx INT NOT NULL, receiver_ID INT NOT NULL, amount NUMERIC(12, 2) NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'completed', 'failed', 'skipped')),
+    transaction_ID INT references "transaction"(ID), error VARCHAR(255) NOT NULL DEFAULT '',
+    memo VARCHAR(140) NOT NULL DEFAULT '', reference VARCHAR(64) NOT NULL DEFAULT '', metadata JSONB NOT NULL DEFAULT '{}',
+    PRIMARY KEY (batch_ID, idx));
@@ -0,0 +1,2 @@
+-- locked flag of balances is not used any more, transfers lock balances with SELECT ... FOR UPDATE, see 0004_release_balance_locks.sql.
+ALTER TABLE "balance" DROP COLUMN locked;
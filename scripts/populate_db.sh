@@ -27,6 +27,17 @@ psql -h db -U postgres -d wallets -c 'INSERT INTO "balance"(currency, balance, u
 psql -h db -U postgres -d wallets -c 'INSERT INTO "user"(first_name, last_name, age) VALUES('"'"'Jim'"'"', '"'"'Smith'"'"', 50);'
 psql -h db -U postgres -d wallets -c 'INSERT INTO "credentials"(login, password, user_ID) VALUES('"'"'jimsmith44'"'"', '"'"'aGFzbG8='"'"', 4);'
 psql -h db -U postgres -d wallets -c 'INSERT INTO "balance"(currency, balance, user_ID) VALUES('"'"'SGD'"'"', 10, 4);'
+psql -h db -U postgres -d wallets -c 'INSERT INTO "balance"(currency, balance, user_ID) VALUES('"'"'USD'"'"', 500, 1);'
+
+psql -h db -U postgres -d wallets -c 'INSERT INTO "user"(first_name, last_name, age) VALUES('"'"'Sam'"'"', '"'"'Support'"'"', 30);'
+psql -h db -U postgres -d wallets -c 'INSERT INTO "credentials"(login, password, user_ID) VALUES('"'"'support01'"'"', '"'"'aGFzbG8='"'"', 5);'
+
+psql -h db -U postgres -d wallets -c 'INSERT INTO "user"(first_name, last_name, age) VALUES('"'"'Ada'"'"', '"'"'Admin'"'"', 35);'
+psql -h db -U postgres -d wallets -c 'INSERT INTO "credentials"(login, password, user_ID) VALUES('"'"'admin01'"'"', '"'"'aGFzbG8='"'"', 6);'
+
+# service account has no credentials, it authenticates only with API keys created by admin
+psql -h db -U postgres -d wallets -c 'INSERT INTO "user"(first_name, last_name, age) VALUES('"'"'Payouts'"'"', '"'"'Service'"'"', 0);'
+psql -h db -U postgres -d wallets -c 'INSERT INTO "balance"(currency, balance, user_ID) VALUES('"'"'SGD'"'"', 50000, 7);'
 
 psql -h db -U postgres -d wallets -c 'INSERT INTO "transaction"(sender_ID, receiver_ID, currency, amount, date) VALUES(1, 2, '"'"'SGD'"'"', 100, now());'
 psql -h db -U postgres -d wallets -c 'INSERT INTO "balance_transaction"(balance_ID, transaction_ID) VALUES(1, 1);'
@@ -0,0 +1,266 @@
+package service
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"zuzanna.com/walletapi/model"
+	"zuzanna.com/walletapi/repository"
+)
+
+var ErrInvalidAPIKey = errors.New("invalid, expired or revoked API key")
+var ErrAPIKeyIPNotAllowed = errors.New("API key is not allowed from this IP address")
+var ErrAPIKeyNotFound = errors.New("API key not found")
+var ErrInvalidScope = errors.New("at least one scope is required, allowed scopes: balances:read, balances:write, transactions:read, transactions:write, transactions:large")
+var ErrInvalidAllowedIP = errors.New("allowed IP must be an IP address or a network in CIDR notation")
+var ErrInvalidAPIKeyExpiry = errors.New("API key must expire in the future, at most in a year")
+var ErrNotServiceAccount = errors.New("user can login with password, only the user can create own API keys")
+
+// apiKeyPrefix starts every API key, so leaked keys are easy to recognize, e.g. by secret scanners.
+const apiKeyPrefix = "wak_"
+
+// apiKeyShownPrefixLength is number of characters of the key stored in plain text to recognize the key in the list.
+const apiKeyShownPrefixLength = len(apiKeyPrefix) + 6
+
+// DefaultAPIKeyTTL is used when expiry of new key is not set, keys cannot be valid longer than maxAPIKeyTTL.
+const DefaultAPIKeyTTL = 90 * 24 * time.Hour
+const maxAPIKeyTTL = 365 * 24 * time.Hour
+
+// apiKeyHeader is the request header with API key, requests without it are authenticated with JWT token.
+const apiKeyHeader = "X-API-Key"
+
+// apiKeyContextKey is the echo context key of authenticated model.APIKey.
+const apiKeyContextKey = "apiKey"
+
+type APIKeyService interface {
+	// Create returns the new key, which is not stored and cannot be shown again, and its details. The caller authenticated
+	// at authTime must pass step-up authentication, otherwise a stolen access token could be exchanged for a long-lived key.
+	Create(userID int, authTime time.Time, name string, scopes []model.Scope, allowedIPs []string, expiresAt time.Time) (string, model.APIKey, error)
+	// CreateForServiceAccount creates key of the service account - user without credentials, who cannot create keys
+	// by itself - on behalf of the admin authenticated at authTime. Keys of other users could be used to act as them,
+	// so they are refused with ErrNotServiceAccount.
+	CreateForServiceAccount(userID int, authTime time.Time, name string, scopes []model.Scope, allowedIPs []string, expiresAt time.Time) (string, model.APIKey, error)
+	GetByUserID(userID int) ([]model.APIKey, error)
+	Revoke(userID, id int) error
+	// Authenticate returns active key used from allowed IP. The key is returned also with ErrAPIKeyIPNotAllowed.
+	Authenticate(key string, ip net.IP) (model.APIKey, error)
+}
+
+type APIKeyServiceImpl struct {
+	repo        repository.APIKeyRepo
+	users       repository.UserRepo
+	credentials repository.CredentialsRepo
+	// stepUp is nil when step-up authentication is disabled, keys can be then created with any token.
+	stepUp *StepUpPolicy
+}
+
+func NewAPIKeyService(r repository.APIKeyRepo, users repository.UserRepo, credentials repository.CredentialsRepo, stepUp *StepUpPolicy) APIKeyServiceImpl {
+	if r == nil || users == nil || credentials == nil {
+		panic("repo cannot be nil!")
+	}
+	return APIKeyServiceImpl{repo: r, users: users, credentials: credentials, stepUp: stepUp}
+}
+
+func (svc APIKeyServiceImpl) Create(userID int, authTime time.Time, name string, scopes []model.Scope, allowedIPs []string, expiresAt time.Time) (string, model.APIKey, error) {
+	now := time.Now()
+	if svc.stepUp != nil && !svc.stepUp.IsFresh(authTime, now) {
+		log.Warnf("#Create(...) failed while creating API key for user with ID %d, error: %v", userID, ErrStepUpRequired)
+		return "", model.APIKey{}, ErrStepUpRequired
+	}
+	if len(scopes) == 0 {
+		return "", model.APIKey{}, ErrInvalidScope
+	}
+	for _, s := range scopes {
+		if !s.IsValid() {
+			return "", model.APIKey{}, ErrInvalidScope
+		}
+	}
+	networks := make([]string, 0, len(allowedIPs))
+	for _, ip := range allowedIPs {
+		network, err := parseAllowedIP(ip)
+		if err != nil {
+			return "", model.APIKey{}, err
+		}
+		networks = append(networks, network.String())
+	}
+	if expiresAt.IsZero() {
+		expiresAt = now.Add(DefaultAPIKeyTTL)
+	}
+	if !expiresAt.After(now) || expiresAt.After(now.Add(maxAPIKeyTTL)) {
+		return "", model.APIKey{}, ErrInvalidAPIKeyExpiry
+	}
+
+	token, _, err := newSecretToken()
+	if err != nil {
+		log.Errorf("#Create(...) error while generating API key; error: %v", err)
+		return "", model.APIKey{}, err
+	}
+	key := apiKeyPrefix + token
+	k := model.APIKeyDB{
+		KeyHash:    hashSecret(key),
+		Prefix:     key[:apiKeyShownPrefixLength],
+		UserID:     userID,
+		Name:       name,
+		Scopes:     make([]string, 0, len(scopes)),
+		AllowedIPs: networks,
+		ExpiresAt:  expiresAt,
+		CreatedAt:  now,
+	}
+	for _, s := range scopes {
+		k.Scopes = append(k.Scopes, string(s))
+	}
+	if k.ID, err = svc.repo.Create(k); err != nil {
+		return "", model.APIKey{}, err
+	}
+	return key, toAPIKey(k), nil
+}
+
+func (svc APIKeyServiceImpl) CreateForServiceAccount(userID int, authTime time.Time, name string, scopes []model.Scope, allowedIPs []string,
+	expiresAt time.Time) (string, model.APIKey, error) {
+	if _, err := svc.users.Get(userID); err != nil {
+		if err == repository.ErrRecordNotFound {
+			return "", model.APIKey{}, ErrUserNotFound
+		}
+		return "", model.APIKey{}, err
+	}
+	_, err := svc.credentials.GetByUserID(userID)
+	if err == nil {
+		log.Warnf("#CreateForServiceAccount(...) failed while creating API key for user with ID %d, error: %v", userID, ErrNotServiceAccount)
+		return "", model.APIKey{}, ErrNotServiceAccount
+	}
+	if err != repository.ErrRecordNotFound {
+		return "", model.APIKey{}, err
+	}
+	return svc.Create(userID, authTime, name, scopes, allowedIPs, expiresAt)
+}
+
+func (svc APIKeyServiceImpl) GetByUserID(userID int) ([]model.APIKey, error) {
+	keys, err := svc.repo.GetByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]model.APIKey, 0, len(keys))
+	for _, k := range keys {
+		result = append(result, toAPIKey(k))
+	}
+	return result, nil
+}
+
+func (svc APIKeyServiceImpl) Revoke(userID, id int) error {
+	err := svc.repo.Revoke(id, userID, time.Now())
+	if err == repository.ErrRecordNotFound {
+		return ErrAPIKeyNotFound
+	}
+	return err
+}
+
+func (svc APIKeyServiceImpl) Authenticate(key string, ip net.IP) (model.APIKey, error) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return model.APIKey{}, ErrInvalidAPIKey
+	}
+	k, err := svc.repo.GetByHash(hashSecret(key))
+	if err != nil {
+		if err == repository.ErrRecordNotFound {
+			return model.APIKey{}, ErrInvalidAPIKey
+		}
+		return model.APIKey{}, err
+	}
+	apiKey := toAPIKey(k)
+	if !apiKey.IsActive(time.Now()) {
+		return model.APIKey{}, ErrInvalidAPIKey
+	}
+	if !apiKey.AllowsIP(ip) {
+		return apiKey, ErrAPIKeyIPNotAllowed
+	}
+	return apiKey, nil
+}
+
+// parseAllowedIP accepts network in CIDR notation or single IP address.
+func parseAllowedIP(s string) (*net.IPNet, error) {
+	if _, network, err := net.ParseCIDR(s); err == nil {
+		return network, nil
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, ErrInvalidAllowedIP
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}, nil
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+}
+
+func toAPIKey(k model.APIKeyDB) model.APIKey {
+	apiKey := model.APIKey{
+		ID:        k.ID,
+		Prefix:    k.Prefix,
+		UserID:    k.UserID,
+		Name:      k.Name,
+		Scopes:    make([]model.Scope, 0, len(k.Scopes)),
+		ExpiresAt: k.ExpiresAt,
+		RevokedAt: k.RevokedAt,
+		CreatedAt: k.CreatedAt,
+	}
+	for _, s := range k.Scopes {
+		apiKey.Scopes = append(apiKey.Scopes, model.Scope(s))
+	}
+	for _, ip := range k.AllowedIPs {
+		network, err := parseAllowedIP(ip)
+		if err != nil {
+			// the key must not become usable from any IP because of a corrupted allowlist
+			log.Errorf("invalid allowed IP %s of API key %d; error %v", ip, k.ID, err)
+			network = &net.IPNet{IP: net.IPv6zero, Mask: net.CIDRMask(128, 128)}
+		}
+		apiKey.AllowedIPs = append(apiKey.AllowedIPs, network)
+	}
+	return apiKey
+}
+
+// NewAPIKeyMiddleware authenticates requests with X-API-Key header and passes the other requests to jwt middleware.
+// API key is accepted only on routes listed in routeScopes ("METHOD /path" as registered in echo) and only when
+// the key has the scope required by the route.
+func NewAPIKeyMiddleware(svc APIKeyService, jwt echo.MiddlewareFunc, routeScopes map[string]model.Scope) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		jwtNext := jwt(next)
+		return func(c echo.Context) error {
+			key := c.Request().Header.Get(apiKeyHeader)
+			if key == "" {
+				return jwtNext(c)
+			}
+
+			apiKey, err := svc.Authenticate(key, net.ParseIP(c.RealIP()))
+			if err != nil {
+				switch err {
+				case ErrInvalidAPIKey:
+					return c.JSON(http.StatusUnauthorized, model.NewErrResponse(http.StatusUnauthorized, "Invalid, expired or revoked API key."))
+				case ErrAPIKeyIPNotAllowed:
+					log.Warnf("API key %d used from not allowed IP %s", apiKey.ID, c.RealIP())
+					return c.JSON(http.StatusForbidden, model.NewErrResponse(http.StatusForbidden, "API key is not allowed from this IP address."))
+				}
+				log.Errorf("error while authenticating API key; error %v", err)
+				return c.JSON(http.StatusInternalServerError, model.NewErrResponse(http.StatusInternalServerError, "Internal server error."))
+			}
+
+			scope, ok := routeScopes[c.Request().Method+" "+c.Path()]
+			if !ok {
+				return c.JSON(http.StatusForbidden, model.NewErrResponse(http.StatusForbidden, "API keys are not accepted on this endpoint."))
+			}
+			if !apiKey.HasScope(scope) {
+				return c.JSON(http.StatusForbidden, model.NewErrResponse(http.StatusForbidden, "API key has no "+string(scope)+" scope."))
+			}
+			c.Set(apiKeyContextKey, apiKey)
+			return next(c)
+		}
+	}
+}
+
+// getAPIKey returns API key the request was authenticated with, false for requests authenticated with JWT token.
+func getAPIKey(c echo.Context) (model.APIKey, bool) {
+	apiKey, ok := c.Get(apiKeyContextKey).(model.APIKey)
+	return apiKey, ok
+}
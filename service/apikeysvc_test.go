@@ -0,0 +1,278 @@
+package service
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"zuzanna.com/walletapi/model"
+	"zuzanna.com/walletapi/repository"
+)
+
+type APIKeyRepoFake struct {
+	db map[string]model.APIKeyDB
+}
+
+func newAPIKeyRepoFake() APIKeyRepoFake {
+	return APIKeyRepoFake{db: map[string]model.APIKeyDB{}}
+}
+
+func (r APIKeyRepoFake) Create(k model.APIKeyDB) (int, error) {
+	k.ID = len(r.db) + 1
+	r.db[k.KeyHash] = k
+	return k.ID, nil
+}
+
+func (r APIKeyRepoFake) GetByHash(hash string) (model.APIKeyDB, error) {
+	k, ok := r.db[hash]
+	if !ok {
+		return model.APIKeyDB{}, repository.ErrRecordNotFound
+	}
+	return k, nil
+}
+
+func (r APIKeyRepoFake) GetByUserID(userID int) ([]model.APIKeyDB, error) {
+	keys := []model.APIKeyDB{}
+	for _, k := range r.db {
+		if k.UserID == userID {
+			keys = append(keys, k)
+		}
+	}
+	return keys, nil
+}
+
+func (r APIKeyRepoFake) Revoke(id, userID int, revokedAt time.Time) error {
+	for hash, k := range r.db {
+		if k.ID == id && k.UserID == userID && k.RevokedAt.IsZero() {
+			k.RevokedAt = revokedAt
+			r.db[hash] = k
+			return nil
+		}
+	}
+	return repository.ErrRecordNotFound
+}
+
+func (r APIKeyRepoFake) RevokeUserKeys(userID int, revokedAt time.Time) error {
+	for hash, k := range r.db {
+		if k.UserID == userID && k.RevokedAt.IsZero() {
+			k.RevokedAt = revokedAt
+			r.db[hash] = k
+		}
+	}
+	return nil
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	repo := newAPIKeyRepoFake()
+	svc := NewAPIKeyService(repo, newUserRepoFake(), newCredentialsRepoFake(), nil)
+	read := []model.Scope{model.ScopeBalancesRead}
+
+	cases := []struct {
+		scopes     []model.Scope
+		allowedIPs []string
+		expiresAt  time.Time
+		err        error
+	}{
+		{scopes: nil, err: ErrInvalidScope},
+		{scopes: []model.Scope{"balances:delete"}, err: ErrInvalidScope},
+		{scopes: read, allowedIPs: []string{"10.0.0.300"}, err: ErrInvalidAllowedIP},
+		{scopes: read, expiresAt: time.Now().Add(-time.Minute), err: ErrInvalidAPIKeyExpiry},
+		{scopes: read, expiresAt: time.Now().Add(2 * maxAPIKeyTTL), err: ErrInvalidAPIKeyExpiry},
+	}
+	for _, testCase := range cases {
+		if _, _, err := svc.Create(1, time.Time{}, "test", testCase.scopes, testCase.allowedIPs, testCase.expiresAt); err != testCase.err {
+			t.Errorf("error for scopes %v, allowed IPs %v and expiry %s got: %v; want: %v", testCase.scopes, testCase.allowedIPs, testCase.expiresAt, err, testCase.err)
+		}
+	}
+
+	key, apiKey, err := svc.Create(1, time.Time{}, "payouts", []model.Scope{model.ScopeTransactionsWrite}, []string{"10.0.0.1", "192.168.0.0/16"}, time.Time{})
+	if err != nil {
+		t.Fatalf("error was not expected while creating API key: %s", err)
+	}
+	if !strings.HasPrefix(key, apiKeyPrefix) || !strings.HasPrefix(key, apiKey.Prefix) {
+		t.Errorf("key %s should start with %s and %s", key, apiKeyPrefix, apiKey.Prefix)
+	}
+	if time.Until(apiKey.ExpiresAt) < DefaultAPIKeyTTL-time.Minute {
+		t.Errorf("default expiry got: %s; want: in %s", apiKey.ExpiresAt, DefaultAPIKeyTTL)
+	}
+	stored := repo.db[hashSecret(key)]
+	if stored.UserID != 1 || strings.Join(stored.AllowedIPs, ",") != "10.0.0.1/32,192.168.0.0/16" {
+		t.Errorf("stored key got: %+v; want key of user 1 with normalized allowed IPs", stored)
+	}
+	for hash := range repo.db {
+		if hash == key {
+			t.Errorf("API key stored in plain text")
+		}
+	}
+}
+
+func TestCreateAPIKeyStepUp(t *testing.T) {
+	svc := NewAPIKeyService(newAPIKeyRepoFake(), newUserRepoFake(), newCredentialsRepoFake(), &StepUpPolicy{Threshold: model.MustParseAmount("500"), Currency: model.SGD, MaxAge: 5 * time.Minute})
+	read := []model.Scope{model.ScopeBalancesRead}
+
+	// token issued by refresh has no auth time
+	for _, authTime := range []time.Time{{}, time.Now().Add(-10 * time.Minute)} {
+		if _, _, err := svc.Create(1, authTime, "test", read, nil, time.Time{}); err != ErrStepUpRequired {
+			t.Errorf("error for auth time %s got: %v; want: %v", authTime, err, ErrStepUpRequired)
+		}
+	}
+	if _, _, err := svc.Create(1, time.Now().Add(-time.Minute), "test", read, nil, time.Time{}); err != nil {
+		t.Errorf("error was not expected while creating API key after step-up: %s", err)
+	}
+}
+
+func TestCreateAPIKeyForServiceAccount(t *testing.T) {
+	users := newUserRepoFake()
+	users.users[7] = model.UserDB{ID: 7, FirstName: "Payouts", LastName: "Service"}
+	svc := NewAPIKeyService(newAPIKeyRepoFake(), users, newCredentialsRepoFake(), nil)
+	read := []model.Scope{model.ScopeBalancesRead}
+
+	if _, _, err := svc.CreateForServiceAccount(1, time.Now(), "test", read, nil, time.Time{}); err != ErrNotServiceAccount {
+		t.Errorf("error for user with credentials got: %v; want: %v", err, ErrNotServiceAccount)
+	}
+	if _, _, err := svc.CreateForServiceAccount(8, time.Now(), "test", read, nil, time.Time{}); err != ErrUserNotFound {
+		t.Errorf("error for unknown user got: %v; want: %v", err, ErrUserNotFound)
+	}
+	if _, apiKey, err := svc.CreateForServiceAccount(7, time.Now(), "payouts", read, nil, time.Time{}); err != nil || apiKey.UserID != 7 {
+		t.Errorf("API key got: %+v (%v); want key of service account 7", apiKey, err)
+	}
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	repo := newAPIKeyRepoFake()
+	svc := NewAPIKeyService(repo, newUserRepoFake(), newCredentialsRepoFake(), nil)
+
+	key, created, err := svc.Create(7, time.Time{}, "payouts", []model.Scope{model.ScopeTransactionsWrite}, []string{"10.0.0.0/24"}, time.Time{})
+	if err != nil {
+		t.Fatalf("error was not expected while creating API key: %s", err)
+	}
+
+	apiKey, err := svc.Authenticate(key, net.ParseIP("10.0.0.5"))
+	if err != nil || apiKey.ID != created.ID || apiKey.UserID != 7 {
+		t.Errorf("API key got: %+v (%v); want key %d of user 7", apiKey, err, created.ID)
+	}
+	if _, err = svc.Authenticate(key, net.ParseIP("10.0.1.5")); err != ErrAPIKeyIPNotAllowed {
+		t.Errorf("error for not allowed IP got: %v; want: %v", err, ErrAPIKeyIPNotAllowed)
+	}
+	if _, err = svc.Authenticate(key+"x", net.ParseIP("10.0.0.5")); err != ErrInvalidAPIKey {
+		t.Errorf("error for unknown key got: %v; want: %v", err, ErrInvalidAPIKey)
+	}
+
+	if err = svc.Revoke(8, created.ID); err != ErrAPIKeyNotFound {
+		t.Errorf("error for key of other user got: %v; want: %v", err, ErrAPIKeyNotFound)
+	}
+	if err = svc.Revoke(7, created.ID); err != nil {
+		t.Fatalf("error was not expected while revoking API key: %s", err)
+	}
+	if _, err = svc.Authenticate(key, net.ParseIP("10.0.0.5")); err != ErrInvalidAPIKey {
+		t.Errorf("error for revoked key got: %v; want: %v", err, ErrInvalidAPIKey)
+	}
+
+	repo.Create(model.APIKeyDB{KeyHash: hashSecret("wak_expired"), UserID: 7, ExpiresAt: time.Now().Add(-time.Second)})
+	if _, err = svc.Authenticate("wak_expired", nil); err != ErrInvalidAPIKey {
+		t.Errorf("error for expired key got: %v; want: %v", err, ErrInvalidAPIKey)
+	}
+}
+
+func TestAPIKeyMiddleware(t *testing.T) {
+	svc := NewAPIKeyService(newAPIKeyRepoFake(), newUserRepoFake(), newCredentialsRepoFake(), nil)
+	authSvc := AuthServiceImpl{}
+	key, _, err := svc.Create(7, time.Time{}, "payouts", []model.Scope{model.ScopeTransactionsWrite}, nil, time.Time{})
+	if err != nil {
+		t.Fatalf("error was not expected while creating API key: %s", err)
+	}
+
+	jwtCalled := false
+	jwtFake := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			jwtCalled = true
+			return c.NoContent(http.StatusUnauthorized)
+		}
+	}
+	e := echo.New()
+	api := e.Group("/api", NewAPIKeyMiddleware(svc, jwtFake, map[string]model.Scope{
+		"POST /api/v1/transactions": model.ScopeTransactionsWrite,
+		"GET /api/v1/transactions":  model.ScopeTransactionsRead,
+	}))
+	handler := func(c echo.Context) error {
+		userID, err := authSvc.GetUserIDFromToken(c)
+		if err != nil {
+			return c.NoContent(http.StatusInternalServerError)
+		}
+		if role, _ := authSvc.GetRoleFromToken(c); role != model.RoleUser {
+			return c.NoContent(http.StatusInternalServerError)
+		}
+		return c.JSON(http.StatusOK, userID)
+	}
+	api.POST("/v1/transactions", handler)
+	api.GET("/v1/transactions", handler)
+	api.PUT("/v1/me/password", handler)
+
+	cases := []struct {
+		method       string
+		path         string
+		key          string
+		expectedCode int
+	}{
+		{method: http.MethodPost, path: "/api/v1/transactions", key: key, expectedCode: http.StatusOK},
+		// key without required scope
+		{method: http.MethodGet, path: "/api/v1/transactions", key: key, expectedCode: http.StatusForbidden},
+		// endpoint not accepting API keys
+		{method: http.MethodPut, path: "/api/v1/me/password", key: key, expectedCode: http.StatusForbidden},
+		{method: http.MethodPost, path: "/api/v1/transactions", key: "wak_unknown", expectedCode: http.StatusUnauthorized},
+	}
+	for _, testCase := range cases {
+		req := httptest.NewRequest(testCase.method, testCase.path, nil)
+		req.Header.Set(apiKeyHeader, testCase.key)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		if rec.Code != testCase.expectedCode {
+			t.Errorf("http code of %s %s got: %d; want: %d", testCase.method, testCase.path, rec.Code, testCase.expectedCode)
+		}
+		if testCase.expectedCode == http.StatusOK && strings.TrimSpace(rec.Body.String()) != "7" {
+			t.Errorf("user ID got: %s; want: 7", rec.Body.String())
+		}
+	}
+	if jwtCalled {
+		t.Errorf("JWT middleware called for request with API key")
+	}
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/transactions", nil))
+	if !jwtCalled || rec.Code != http.StatusUnauthorized {
+		t.Errorf("request without API key should be passed to JWT middleware")
+	}
+}
+
+func TestAPIKeyStepUp(t *testing.T) {
+	policy := StepUpPolicy{Threshold: model.MustParseAmount("10.00"), Currency: model.SGD, MaxAge: 5 * time.Minute}
+	svc := NewTransactionService(newBalanceRepoFake(), nil, &policy)
+	authSvc := AuthServiceImpl{}
+	// index 0 - 10.34 SGD is above the threshold
+	test := transactionTestCases[0]
+	// the keys are older than MaxAge of the policy
+	createdAt := time.Now().Add(-24 * time.Hour)
+
+	cases := []struct {
+		scopes      []model.Scope
+		expectedErr error
+	}{
+		{scopes: []model.Scope{model.ScopeTransactionsWrite}, expectedErr: ErrStepUpRequired},
+		{scopes: []model.Scope{model.ScopeTransactionsWrite, model.ScopeTransactionsLarge}, expectedErr: nil},
+	}
+	for _, testCase := range cases {
+		c := echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/api/v1/transactions", nil), httptest.NewRecorder())
+		c.Set(apiKeyContextKey, model.APIKey{ID: 1, UserID: test.userID, Scopes: testCase.scopes, CreatedAt: createdAt, ExpiresAt: time.Now().Add(time.Hour)})
+		authTime, err := authSvc.GetAuthTimeFromToken(c)
+		if err != nil {
+			t.Fatalf("error was not expected while getting auth time of API key: %s", err)
+		}
+		if _, err = svc.Execute(test.userID, test.transaction, authTime); err != testCase.expectedErr {
+			t.Errorf("error for key with scopes %v got: %v; want: %v", testCase.scopes, err, testCase.expectedErr)
+		}
+	}
+}
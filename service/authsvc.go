@@ -1,10 +1,8 @@
 package service
 
 import (
-	"encoding/base64"
 	"errors"
 	"net/http"
-	"os"
 	"time"
 
 	"github.com/golang-jwt/jwt"
@@ -16,34 +14,73 @@ import (
 )
 
 var ErrUnauthorized = errors.New("login failed")
+var ErrInvalidRefreshToken = errors.New("invalid, expired or revoked refresh token")
+var ErrInvalidMFAToken = errors.New("invalid, expired or used MFA token")
+
+// accessTokenTTL is lifetime of JWT access token, longer sessions are kept with refresh token.
+const accessTokenTTL = time.Hour
+
+// DefaultRefreshTokenTTL is used when AuthServiceImpl.RefreshTokenTTL is not set.
+const DefaultRefreshTokenTTL = 30 * 24 * time.Hour
+
+// mfaTokenTTL is time given to the user to enter one-time code after password was verified.
+const mfaTokenTTL = 5 * time.Minute
+
+// mfaPendingAudience marks tokens which only can be exchanged at /login/mfa, they are rejected by JWT middleware.
+const mfaPendingAudience = "mfa-pending"
 
 type AuthService interface {
-	Authenticate(login, password string) (string, error)
+	// Authenticate returns only model.Tokens.MFAToken when the user enabled two-factor authentication.
+	Authenticate(login, password string) (model.Tokens, error)
+	// ParseMFAToken returns login of the user who got MFA token.
+	ParseMFAToken(mfaToken string) (string, error)
+	// VerifyMFA exchanges MFA token and one-time code for access and refresh tokens, MFA token cannot be used again.
+	VerifyMFA(mfaToken, code string) (model.Tokens, error)
+	// Reauthenticate verifies password or one-time code of the user from context again and returns new access token
+	// with current auth_time claim, see StepUpPolicy.
+	Reauthenticate(c echo.Context, password, code string) (model.Tokens, error)
+	// Refresh exchanges refresh token for a new access token and a new refresh token, the old refresh token cannot be used again.
+	Refresh(refreshToken string) (model.Tokens, error)
+	// Logout revokes access token from context and, if given, refresh token of the same user.
+	Logout(c echo.Context, refreshToken string) error
+	// IsTokenRevoked checks if the token was revoked by logout or by the end of all sessions of the user, e.g. after password change.
+	IsTokenRevoked(claims *JwtCustomClaims) (bool, error)
+	// GetUserIDFromToken returns ID of the principal authenticated with JWT token or API key, see NewAPIKeyMiddleware.
 	GetUserIDFromToken(echo.Context) (int, error)
+	// GetAuthTimeFromToken returns time of the last authentication of the user with password or one-time code,
+	// zero time when the token was issued by refresh. Requests authenticated with API key get current time when the key has
+	// model.ScopeTransactionsLarge and zero time otherwise, so they pass step-up authentication only with the scope.
+	GetAuthTimeFromToken(echo.Context) (time.Time, error)
+	// GetRoleFromToken returns role of the user from JWT token, model.RoleUser for requests authenticated with API key.
+	GetRoleFromToken(echo.Context) (model.Role, error)
+	// GetLogin returns login of the user from context.
+	GetLogin(echo.Context) (string, error)
 }
 
 type AuthServiceImpl struct {
 	repository.CredentialsRepo
+	TokenRepo       repository.TokenRepo
+	Keys            JWTKeyProvider
+	RefreshTokenTTL time.Duration
+	// MFA is optional, when it is nil two-factor authentication is disabled.
+	MFA MFAService
 }
 
-var jwtTokenSign []byte
-
 type JwtCustomClaims struct {
 	UserID int `json:"user_id"`
+	// Role is empty in tokens issued before roles were introduced, such tokens have privileges of model.RoleUser.
+	Role model.Role `json:"role,omitempty"`
+	// AuthTime is Unix time when the user entered password or one-time code, it is not set in refreshed tokens.
+	AuthTime int64 `json:"auth_time,omitempty"`
 	jwt.StandardClaims
 }
 
-func init() {
-	env, ok := os.LookupEnv("JWT_SIGN")
-	if ok {
-		jwtTokenSign = []byte(env)
-	} else {
-		jwtTokenSign = []byte("mySecret")
+// GetRole returns role of the token owner.
+func (claims JwtCustomClaims) GetRole() model.Role {
+	if claims.Role == "" {
+		return model.RoleUser
 	}
-}
-
-func GetJwtTokenSign() []byte {
-	return jwtTokenSign
+	return claims.Role
 }
 
 func JWTErrorHandlerWithContext(err error, c echo.Context) error {
@@ -56,34 +93,289 @@ func JWTErrorHandlerWithContext(err error, c echo.Context) error {
 	return c.JSON(http.StatusUnauthorized, model.NewErrResponse(http.StatusUnauthorized, "Invalid JWT token."))
 }
 
-func (svc AuthServiceImpl) Authenticate(login, password string) (string, error) {
+// NewJWTMiddleware validates JWT token like middleware.JWTWithConfig and additionally rejects tokens revoked by logout
+// and MFA tokens.
+func NewJWTMiddleware(svc AuthService, keys JWTKeyProvider) echo.MiddlewareFunc {
+	jwtMiddleware := middleware.JWTWithConfig(middleware.JWTConfig{
+		KeyFunc:                 keys.Keyfunc,
+		TokenLookup:             "header:Authorization",
+		ErrorHandlerWithContext: JWTErrorHandlerWithContext,
+		Claims:                  &JwtCustomClaims{},
+	})
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return jwtMiddleware(rejectRevokedToken(svc, next))
+	}
+}
+
+func rejectRevokedToken(svc AuthService, next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		claims, err := getClaims(c)
+		if err != nil {
+			return c.JSON(http.StatusUnauthorized, model.NewErrResponse(http.StatusUnauthorized, "Invalid JWT token."))
+		}
+		if claims.Audience == mfaPendingAudience {
+			return c.JSON(http.StatusUnauthorized, model.NewErrResponse(http.StatusUnauthorized, "Two-factor authentication required."))
+		}
+		revoked, err := svc.IsTokenRevoked(claims)
+		if err != nil {
+			log.Errorf("error while checking if token of user with ID %d is revoked; error %v", claims.UserID, err)
+			return c.JSON(http.StatusInternalServerError, model.NewErrResponse(http.StatusInternalServerError, "Internal server error."))
+		}
+		if revoked {
+			return c.JSON(http.StatusUnauthorized, model.NewErrResponse(http.StatusUnauthorized, "Revoked JWT token."))
+		}
+		return next(c)
+	}
+}
+
+func (svc AuthServiceImpl) Authenticate(login, password string) (model.Tokens, error) {
 	credentials, err := svc.CredentialsRepo.Get(login)
 	if err != nil {
 		if err == repository.ErrRecordNotFound {
 			log.Infof("no record found for login %s", login)
-			return "", ErrUnauthorized
+			VerifyPassword(dummyPasswordHash, password)
+			return model.Tokens{}, ErrUnauthorized
 		}
 		log.Errorf("error while retrieving credentials for login %s; error %v", login, err)
-		return "", err
+		return model.Tokens{}, err
+	}
+
+	ok, rehash, err := VerifyPassword(credentials.Password, password)
+	if err != nil {
+		log.Errorf("error while verifying password for login %s; error %v", login, err)
+		return model.Tokens{}, err
+	}
+	if login != credentials.Login || !ok {
+		return model.Tokens{}, ErrUnauthorized
+	}
+	if rehash {
+		svc.rehashPassword(credentials, password)
+	}
+
+	if svc.MFA != nil {
+		enabled, err := svc.MFA.IsEnabled(credentials.UserID)
+		if err != nil {
+			log.Errorf("error while checking two-factor authentication for login %s; error %v", login, err)
+			return model.Tokens{}, err
+		}
+		if enabled {
+			mfaToken, err := svc.newMFAToken(credentials)
+			if err != nil {
+				return model.Tokens{}, err
+			}
+			return model.Tokens{MFAToken: mfaToken}, nil
+		}
 	}
+	return svc.issueTokens(credentials.UserID, credentials.Role, time.Now())
+}
 
-	encodedPass, err := base64.StdEncoding.DecodeString(credentials.Password)
+func (svc AuthServiceImpl) ParseMFAToken(mfaToken string) (string, error) {
+	claims, err := svc.parseMFAToken(mfaToken)
 	if err != nil {
-		log.Errorf("error while encoding password for login %s; error %v", login, err)
 		return "", err
 	}
-	if login != credentials.Login || password != string(encodedPass) {
-		return "", ErrUnauthorized
+	return claims.Subject, nil
+}
+
+func (svc AuthServiceImpl) VerifyMFA(mfaToken, code string) (model.Tokens, error) {
+	if svc.MFA == nil {
+		return model.Tokens{}, ErrInvalidMFAToken
+	}
+	claims, err := svc.parseMFAToken(mfaToken)
+	if err != nil {
+		return model.Tokens{}, err
+	}
+	if err = svc.MFA.Verify(claims.UserID, code); err != nil {
+		return model.Tokens{}, err
+	}
+	if err = svc.TokenRepo.RevokeAccessToken(claims.Id, time.Unix(claims.ExpiresAt, 0)); err != nil {
+		return model.Tokens{}, err
+	}
+	return svc.issueTokens(claims.UserID, claims.GetRole(), time.Now())
+}
+
+func (svc AuthServiceImpl) Reauthenticate(c echo.Context, password, code string) (model.Tokens, error) {
+	claims, err := getClaims(c)
+	if err != nil {
+		return model.Tokens{}, err
+	}
+	credentials, err := svc.CredentialsRepo.GetByUserID(claims.UserID)
+	if err != nil {
+		if err == repository.ErrRecordNotFound {
+			return model.Tokens{}, ErrUnauthorized
+		}
+		log.Errorf("error while retrieving credentials of user with ID %d; error %v", claims.UserID, err)
+		return model.Tokens{}, err
+	}
+
+	if code != "" {
+		if svc.MFA == nil {
+			return model.Tokens{}, ErrInvalidMFACode
+		}
+		if err = svc.MFA.Verify(credentials.UserID, code); err != nil {
+			return model.Tokens{}, err
+		}
+	} else {
+		ok, _, err := VerifyPassword(credentials.Password, password)
+		if err != nil {
+			log.Errorf("error while verifying password for login %s; error %v", credentials.Login, err)
+			return model.Tokens{}, err
+		}
+		if !ok {
+			return model.Tokens{}, ErrUnauthorized
+		}
+	}
+
+	accessToken, err := svc.newAccessToken(credentials.UserID, credentials.Role, time.Now())
+	if err != nil {
+		return model.Tokens{}, err
+	}
+	return model.Tokens{AccessToken: accessToken}, nil
+}
+
+// parseMFAToken returns claims of valid, not used MFA token.
+func (svc AuthServiceImpl) parseMFAToken(mfaToken string) (*JwtCustomClaims, error) {
+	claims := &JwtCustomClaims{}
+	token, err := jwt.ParseWithClaims(mfaToken, claims, svc.Keys.Keyfunc)
+	if err != nil || !token.Valid || !claims.VerifyAudience(mfaPendingAudience, true) || claims.Id == "" {
+		return nil, ErrInvalidMFAToken
+	}
+	used, err := svc.TokenRepo.IsAccessTokenRevoked(claims.Id, claims.UserID, time.Unix(claims.IssuedAt, 0))
+	if err != nil {
+		return nil, err
+	}
+	if used {
+		return nil, ErrInvalidMFAToken
+	}
+	return claims, nil
+}
+
+// issueTokens starts new login session.
+func (svc AuthServiceImpl) issueTokens(userID int, role model.Role, authTime time.Time) (model.Tokens, error) {
+	family, err := newTokenID()
+	if err != nil {
+		log.Errorf("error while generating refresh token family; error %v", err)
+		return model.Tokens{}, err
+	}
+	refreshToken, next, err := svc.newRefreshToken(userID, family)
+	if err != nil {
+		return model.Tokens{}, err
+	}
+	if err = svc.TokenRepo.CreateRefreshToken(next); err != nil {
+		return model.Tokens{}, err
+	}
+	accessToken, err := svc.newAccessToken(userID, role, authTime)
+	if err != nil {
+		return model.Tokens{}, err
+	}
+	return model.Tokens{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+func (svc AuthServiceImpl) Refresh(refreshToken string) (model.Tokens, error) {
+	hash := hashSecret(refreshToken)
+	current, err := svc.TokenRepo.GetRefreshToken(hash)
+	if err != nil {
+		if err == repository.ErrRecordNotFound {
+			return model.Tokens{}, ErrInvalidRefreshToken
+		}
+		return model.Tokens{}, err
+	}
+	token := model.RefreshToken(current)
+	if token.IsRevoked() {
+		// rotated token was used again - it could be stolen, so the whole login session is ended
+		log.Warnf("revoked refresh token of user with ID %d reused, revoking token family %s", token.UserID, token.Family)
+		if err = svc.TokenRepo.RevokeRefreshTokenFamily(token.Family, time.Now()); err != nil {
+			return model.Tokens{}, err
+		}
+		return model.Tokens{}, ErrInvalidRefreshToken
+	}
+	if token.IsExpired(time.Now()) {
+		return model.Tokens{}, ErrInvalidRefreshToken
+	}
+	// role could be changed since login, new access token gets the current one
+	role, err := svc.CredentialsRepo.GetRole(token.UserID)
+	if err != nil {
+		log.Errorf("error while retrieving role of user with ID %d; error %v", token.UserID, err)
+		return model.Tokens{}, err
 	}
+
+	newRefreshToken, next, err := svc.newRefreshToken(token.UserID, token.Family)
+	if err != nil {
+		return model.Tokens{}, err
+	}
+	err = svc.TokenRepo.RotateRefreshToken(hash, next)
+	if err != nil {
+		if err == repository.ErrRefreshTokenRevoked {
+			log.Warnf("refresh token of user with ID %d rotated concurrently, revoking token family %s", token.UserID, token.Family)
+			if err = svc.TokenRepo.RevokeRefreshTokenFamily(token.Family, time.Now()); err != nil {
+				return model.Tokens{}, err
+			}
+			return model.Tokens{}, ErrInvalidRefreshToken
+		}
+		return model.Tokens{}, err
+	}
+	// auth_time is not carried over, refresh token does not prove the user is still present
+	accessToken, err := svc.newAccessToken(token.UserID, role, time.Time{})
+	if err != nil {
+		return model.Tokens{}, err
+	}
+	return model.Tokens{AccessToken: accessToken, RefreshToken: newRefreshToken}, nil
+}
+
+func (svc AuthServiceImpl) Logout(c echo.Context, refreshToken string) error {
+	claims, err := getClaims(c)
+	if err != nil {
+		return err
+	}
+	if refreshToken != "" {
+		token, err := svc.TokenRepo.GetRefreshToken(hashSecret(refreshToken))
+		if err != nil {
+			if err == repository.ErrRecordNotFound {
+				return ErrInvalidRefreshToken
+			}
+			return err
+		}
+		if token.UserID != claims.UserID {
+			log.Warnf("user with ID %d tried to revoke refresh token of user with ID %d", claims.UserID, token.UserID)
+			return ErrInvalidRefreshToken
+		}
+		if err = svc.TokenRepo.RevokeRefreshTokenFamily(token.Family, time.Now()); err != nil {
+			return err
+		}
+	}
+	// tokens issued before jti was introduced cannot be revoked, they expire within accessTokenTTL
+	if claims.Id == "" {
+		return nil
+	}
+	return svc.TokenRepo.RevokeAccessToken(claims.Id, time.Unix(claims.ExpiresAt, 0))
+}
+
+func (svc AuthServiceImpl) IsTokenRevoked(claims *JwtCustomClaims) (bool, error) {
+	// tokens issued before iat was introduced are treated as the oldest ones
+	return svc.TokenRepo.IsAccessTokenRevoked(claims.Id, claims.UserID, time.Unix(claims.IssuedAt, 0))
+}
+
+// newAccessToken returns signed JWT token, auth_time claim is omitted when authTime is zero.
+func (svc AuthServiceImpl) newAccessToken(userID int, role model.Role, authTime time.Time) (string, error) {
+	jti, err := newTokenID()
+	if err != nil {
+		log.Errorf("error while generating token ID; error %v", err)
+		return "", err
+	}
+	now := time.Now()
 	claims := &JwtCustomClaims{
-		credentials.UserID,
-		jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(time.Hour).Unix(),
+		UserID: userID,
+		Role:   role,
+		StandardClaims: jwt.StandardClaims{
+			Id:        jti,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(accessTokenTTL).Unix(),
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
-	signedToken, err := token.SignedString(jwtTokenSign)
+	if !authTime.IsZero() {
+		claims.AuthTime = authTime.Unix()
+	}
+	signedToken, err := svc.Keys.Sign(claims)
 	if err != nil {
 		log.Errorf("error while signing token; error %v", err)
 		return "", err
@@ -91,11 +383,130 @@ func (svc AuthServiceImpl) Authenticate(login, password string) (string, error)
 	return signedToken, nil
 }
 
+// newMFAToken returns short-lived token proving that password of the user was verified.
+func (svc AuthServiceImpl) newMFAToken(credentials model.Credentials) (string, error) {
+	jti, err := newTokenID()
+	if err != nil {
+		log.Errorf("error while generating token ID; error %v", err)
+		return "", err
+	}
+	now := time.Now()
+	claims := &JwtCustomClaims{
+		UserID: credentials.UserID,
+		Role:   credentials.Role,
+		StandardClaims: jwt.StandardClaims{
+			Id:        jti,
+			Subject:   credentials.Login,
+			Audience:  mfaPendingAudience,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(mfaTokenTTL).Unix(),
+		},
+	}
+	signedToken, err := svc.Keys.Sign(claims)
+	if err != nil {
+		log.Errorf("error while signing MFA token; error %v", err)
+		return "", err
+	}
+	return signedToken, nil
+}
+
+// newRefreshToken returns refresh token for the client and the record, with hash of the token, to be saved in DB.
+func (svc AuthServiceImpl) newRefreshToken(userID int, family string) (string, model.RefreshTokenDB, error) {
+	token, hash, err := newSecretToken()
+	if err != nil {
+		log.Errorf("error while generating refresh token; error %v", err)
+		return "", model.RefreshTokenDB{}, err
+	}
+	ttl := svc.RefreshTokenTTL
+	if ttl <= 0 {
+		ttl = DefaultRefreshTokenTTL
+	}
+	now := time.Now()
+	return token, model.RefreshTokenDB{
+		TokenHash: hash,
+		Family:    family,
+		UserID:    userID,
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}, nil
+}
+
+// rehashPassword replaces legacy password hash after successful login. Login does not fail when the hash cannot be replaced.
+func (svc AuthServiceImpl) rehashPassword(credentials model.Credentials, password string) {
+	hash, err := HashPassword(password)
+	if err != nil {
+		log.Errorf("error while hashing password for login %s; error %v", credentials.Login, err)
+		return
+	}
+	if err = svc.CredentialsRepo.UpdatePassword(credentials.Login, credentials.Password, hash); err != nil {
+		log.Errorf("error while re-hashing password for login %s; error %v", credentials.Login, err)
+		return
+	}
+	log.Infof("password of login %s re-hashed", credentials.Login)
+}
+
 func (svc AuthServiceImpl) GetUserIDFromToken(c echo.Context) (int, error) {
-	userToken := c.Get("user").(*jwt.Token)
+	if apiKey, ok := getAPIKey(c); ok {
+		return apiKey.UserID, nil
+	}
+	claims, err := getClaims(c)
+	if err != nil {
+		return 0, err
+	}
+	return claims.UserID, nil
+}
+
+func (svc AuthServiceImpl) GetAuthTimeFromToken(c echo.Context) (time.Time, error) {
+	if apiKey, ok := getAPIKey(c); ok {
+		// the key cannot re-authenticate, its age does not matter
+		if apiKey.HasScope(model.ScopeTransactionsLarge) {
+			return time.Now(), nil
+		}
+		return time.Time{}, nil
+	}
+	claims, err := getClaims(c)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if claims.AuthTime == 0 {
+		return time.Time{}, nil
+	}
+	return time.Unix(claims.AuthTime, 0), nil
+}
+
+func (svc AuthServiceImpl) GetRoleFromToken(c echo.Context) (model.Role, error) {
+	if _, ok := getAPIKey(c); ok {
+		// API keys never grant privileges of support staff
+		return model.RoleUser, nil
+	}
+	claims, err := getClaims(c)
+	if err != nil {
+		return "", err
+	}
+	return claims.GetRole(), nil
+}
+
+func (svc AuthServiceImpl) GetLogin(c echo.Context) (string, error) {
+	claims, err := getClaims(c)
+	if err != nil {
+		return "", err
+	}
+	credentials, err := svc.CredentialsRepo.GetByUserID(claims.UserID)
+	if err != nil {
+		log.Errorf("error while retrieving credentials of user with ID %d; error %v", claims.UserID, err)
+		return "", err
+	}
+	return credentials.Login, nil
+}
+
+func getClaims(c echo.Context) (*JwtCustomClaims, error) {
+	userToken, ok := c.Get("user").(*jwt.Token)
+	if !ok {
+		return nil, errors.New("error while reading JWT token from context")
+	}
 	claims, ok := userToken.Claims.(*JwtCustomClaims)
 	if !ok {
-		return 0, errors.New("error while reading JWT custom claims")
+		return nil, errors.New("error while reading JWT custom claims")
 	}
-	return claims.UserID, nil
+	return claims, nil
 }
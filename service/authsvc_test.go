@@ -2,10 +2,14 @@ package service
 
 import (
 	"errors"
-	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/golang-jwt/jwt"
+	"github.com/labstack/echo/v4"
 	"zuzanna.com/walletapi/model"
 	"zuzanna.com/walletapi/repository"
 )
@@ -21,6 +25,8 @@ func newCredentialsRepoFake() CredentialsRepoFake {
 		db: map[string]model.Credentials{
 			"ala11": {ID: 1, Login: "ala11", Password: "aGFzbG8=", UserID: 1},
 			"ola22": {ID: 2, Login: "ola22", Password: "MXFhelhTV0A=", UserID: 2},
+			// password: "haslo"
+			"ela33": {ID: 3, Login: "ela33", Password: "bcrypt$$2a$04$07etRhWMrDoyJWUR/Yb03O5HPG7CgvZ8LXEwSz7WAJ5ssFS3buugm", UserID: 3, Role: model.RoleSupport},
 		},
 	}
 }
@@ -35,6 +41,106 @@ func (r CredentialsRepoFake) Get(login string) (model.Credentials, error) {
 	return model.Credentials{}, repository.ErrRecordNotFound
 }
 
+func (r CredentialsRepoFake) GetByUserID(userID int) (model.Credentials, error) {
+	for _, cred := range r.db {
+		if cred.UserID == userID {
+			return cred, nil
+		}
+	}
+	return model.Credentials{}, repository.ErrRecordNotFound
+}
+
+func (r CredentialsRepoFake) UpdatePassword(login, oldHash, newHash string) error {
+	cred, ok := r.db[login]
+	if !ok || cred.Password != oldHash {
+		return repository.ErrRecordNotFound
+	}
+	cred.Password = newHash
+	r.db[login] = cred
+	return nil
+}
+
+func (r CredentialsRepoFake) GetRole(userID int) (model.Role, error) {
+	for _, cred := range r.db {
+		if cred.UserID == userID {
+			if cred.Role == "" {
+				return model.RoleUser, nil
+			}
+			return cred.Role, nil
+		}
+	}
+	return "", repository.ErrRecordNotFound
+}
+
+type TokenRepoFake struct {
+	refreshTokens map[string]model.RefreshTokenDB
+	revoked       map[string]time.Time
+	revokedUsers  map[int]time.Time
+}
+
+func newTokenRepoFake() TokenRepoFake {
+	return TokenRepoFake{
+		refreshTokens: map[string]model.RefreshTokenDB{},
+		revoked:       map[string]time.Time{},
+		revokedUsers:  map[int]time.Time{},
+	}
+}
+
+func (r TokenRepoFake) CreateRefreshToken(t model.RefreshTokenDB) error {
+	r.refreshTokens[t.TokenHash] = t
+	return nil
+}
+
+func (r TokenRepoFake) GetRefreshToken(hash string) (model.RefreshTokenDB, error) {
+	t, ok := r.refreshTokens[hash]
+	if !ok {
+		return model.RefreshTokenDB{}, repository.ErrRecordNotFound
+	}
+	return t, nil
+}
+
+func (r TokenRepoFake) RotateRefreshToken(hash string, next model.RefreshTokenDB) error {
+	t := r.refreshTokens[hash]
+	if !t.RevokedAt.IsZero() {
+		return repository.ErrRefreshTokenRevoked
+	}
+	t.RevokedAt = next.CreatedAt
+	r.refreshTokens[hash] = t
+	r.refreshTokens[next.TokenHash] = next
+	return nil
+}
+
+func (r TokenRepoFake) RevokeRefreshTokenFamily(family string, revokedAt time.Time) error {
+	for hash, t := range r.refreshTokens {
+		if t.Family == family && t.RevokedAt.IsZero() {
+			t.RevokedAt = revokedAt
+			r.refreshTokens[hash] = t
+		}
+	}
+	return nil
+}
+
+func (r TokenRepoFake) RevokeAccessToken(jti string, expiresAt time.Time) error {
+	r.revoked[jti] = expiresAt
+	return nil
+}
+
+func (r TokenRepoFake) RevokeUserTokens(userID int, revokedAt time.Time) error {
+	for hash, t := range r.refreshTokens {
+		if t.UserID == userID && t.RevokedAt.IsZero() {
+			t.RevokedAt = revokedAt
+			r.refreshTokens[hash] = t
+		}
+	}
+	r.revokedUsers[userID] = revokedAt
+	return nil
+}
+
+func (r TokenRepoFake) IsAccessTokenRevoked(jti string, userID int, issuedAt time.Time) (bool, error) {
+	_, ok := r.revoked[jti]
+	return ok || r.revokedUsers[userID].After(issuedAt), nil
+}
+
 type testCase struct {
 	username    string
 	password    string
@@ -46,29 +152,32 @@ func TestAuthenticate(t *testing.T) {
 	cases := []testCase{
 		{"ala11", "haslo", nil},
 		{"ola22", "1qazXSW@", nil},
+		{"ela33", "haslo", nil},
+		{"ela33", "wrongPass", ErrUnauthorized},
 		{"nofound", "--", ErrUnauthorized},
 		{"ala11", "wrongPass", ErrUnauthorized},
 		{"err", "haslo", errExpected},
 	}
 
+	tokenRepo := newTokenRepoFake()
 	authSvc := AuthServiceImpl{
-		newCredentialsRepoFake(),
+		CredentialsRepo: newCredentialsRepoFake(),
+		TokenRepo:       tokenRepo,
+		Keys:            testJWTKeys,
 	}
 
 	for _, testCase := range cases {
-		tokenStr, err := authSvc.Authenticate(testCase.username, testCase.password)
+		tokens, err := authSvc.Authenticate(testCase.username, testCase.password)
 		if testCase.expectedErr == nil {
-			token, err := jwt.ParseWithClaims(tokenStr, &JwtCustomClaims{}, func(t *jwt.Token) (interface{}, error) {
-				if t.Method.Alg() != "HS256" {
-					return nil, fmt.Errorf("unexpected jwt signing method=%v", t.Header["alg"])
-				}
-				return jwtTokenSign, nil
-			})
-			if err != nil {
-				t.Errorf("error was not expected while parsing JWT token: %s", err)
+			token := mustParseToken(t, tokens.AccessToken)
+			if token.Claims.(*JwtCustomClaims).Id == "" {
+				t.Errorf("token ID (jti) got: empty; want: random ID")
 			}
-			if !token.Valid {
-				t.Errorf("token invalid got: %s (%+v); want: valid token", tokenStr, token)
+			if testCase.username == "ela33" && token.Claims.(*JwtCustomClaims).GetRole() != model.RoleSupport {
+				t.Errorf("token role got: %s; want: %s", token.Claims.(*JwtCustomClaims).GetRole(), model.RoleSupport)
+			}
+			if _, ok := tokenRepo.refreshTokens[hashSecret(tokens.RefreshToken)]; !ok {
+				t.Errorf("refresh token %s not saved; want: hash of the token saved", tokens.RefreshToken)
 			}
 		} else {
 			if err != testCase.expectedErr {
@@ -78,3 +187,268 @@ func TestAuthenticate(t *testing.T) {
 	}
 
 }
+
+func TestAuthenticateRehashesLegacyPassword(t *testing.T) {
+	repo := newCredentialsRepoFake()
+	authSvc := AuthServiceImpl{CredentialsRepo: repo, TokenRepo: newTokenRepoFake(), Keys: testJWTKeys}
+
+	if _, err := authSvc.Authenticate("ala11", "wrongPass"); err != ErrUnauthorized {
+		t.Errorf("unexpected err got: %v; want: %v", err, ErrUnauthorized)
+	}
+	if repo.db["ala11"].Password != "aGFzbG8=" {
+		t.Errorf("password must not be re-hashed after failed login, got: %s", repo.db["ala11"].Password)
+	}
+
+	if _, err := authSvc.Authenticate("ala11", "haslo"); err != nil {
+		t.Errorf("error was not expected while authenticating: %s", err)
+	}
+	rehashed := repo.db["ala11"].Password
+	if !strings.HasPrefix(rehashed, bcryptPrefix) {
+		t.Errorf("password after login got: %s; want: %s hash", rehashed, bcryptPrefix)
+	}
+
+	// user can still log in with re-hashed password
+	if _, err := authSvc.Authenticate("ala11", "haslo"); err != nil {
+		t.Errorf("error was not expected while authenticating with re-hashed password: %s", err)
+	}
+	if repo.db["ala11"].Password != rehashed {
+		t.Errorf("up to date hash must not be replaced, got: %s; want: %s", repo.db["ala11"].Password, rehashed)
+	}
+}
+
+var testJWTKeys, _ = NewEphemeralJWTKeySet()
+
+func mustParseToken(t *testing.T, tokenStr string) *jwt.Token {
+	token, err := jwt.ParseWithClaims(tokenStr, &JwtCustomClaims{}, testJWTKeys.Keyfunc)
+	if err != nil {
+		t.Errorf("error was not expected while parsing JWT token: %s", err)
+		return &jwt.Token{Claims: &JwtCustomClaims{}}
+	}
+	if !token.Valid {
+		t.Errorf("token invalid got: %s (%+v); want: valid token", tokenStr, token)
+	}
+	return token
+}
+
+func TestRefresh(t *testing.T) {
+	tokenRepo := newTokenRepoFake()
+	authSvc := AuthServiceImpl{CredentialsRepo: newCredentialsRepoFake(), TokenRepo: tokenRepo, Keys: testJWTKeys}
+
+	tokens, err := authSvc.Authenticate("ala11", "haslo")
+	if err != nil {
+		t.Fatalf("error was not expected while authenticating: %s", err)
+	}
+
+	refreshed, err := authSvc.Refresh(tokens.RefreshToken)
+	if err != nil {
+		t.Errorf("error was not expected while refreshing token: %s", err)
+	}
+	if refreshed.RefreshToken == tokens.RefreshToken {
+		t.Errorf("refresh token was not rotated, got: %s", refreshed.RefreshToken)
+	}
+	if claims := mustParseToken(t, refreshed.AccessToken).Claims.(*JwtCustomClaims); claims.UserID != 1 {
+		t.Errorf("user ID of refreshed token got: %d; want: 1", claims.UserID)
+	}
+
+	// rotated token is reused - whole family is revoked, including the latest token
+	if _, err = authSvc.Refresh(tokens.RefreshToken); err != ErrInvalidRefreshToken {
+		t.Errorf("error got: %v; want: %v", err, ErrInvalidRefreshToken)
+	}
+	if _, err = authSvc.Refresh(refreshed.RefreshToken); err != ErrInvalidRefreshToken {
+		t.Errorf("error got: %v; want: %v", err, ErrInvalidRefreshToken)
+	}
+
+	if _, err = authSvc.Refresh("unknown"); err != ErrInvalidRefreshToken {
+		t.Errorf("error got: %v; want: %v", err, ErrInvalidRefreshToken)
+	}
+
+	expired := model.RefreshTokenDB{TokenHash: hashSecret("expired"), Family: "f", UserID: 1, ExpiresAt: time.Now().Add(-time.Minute)}
+	tokenRepo.refreshTokens[expired.TokenHash] = expired
+	if _, err = authSvc.Refresh("expired"); err != ErrInvalidRefreshToken {
+		t.Errorf("error got: %v; want: %v", err, ErrInvalidRefreshToken)
+	}
+}
+
+func TestLogout(t *testing.T) {
+	tokenRepo := newTokenRepoFake()
+	authSvc := AuthServiceImpl{CredentialsRepo: newCredentialsRepoFake(), TokenRepo: tokenRepo, Keys: testJWTKeys}
+
+	tokens, err := authSvc.Authenticate("ala11", "haslo")
+	if err != nil {
+		t.Fatalf("error was not expected while authenticating: %s", err)
+	}
+	otherTokens, err := authSvc.Authenticate("ola22", "1qazXSW@")
+	if err != nil {
+		t.Fatalf("error was not expected while authenticating: %s", err)
+	}
+	token := mustParseToken(t, tokens.AccessToken)
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/logout", nil), httptest.NewRecorder())
+	c.Set("user", token)
+
+	// refresh token of other user cannot be revoked
+	if err = authSvc.Logout(c, otherTokens.RefreshToken); err != ErrInvalidRefreshToken {
+		t.Errorf("error got: %v; want: %v", err, ErrInvalidRefreshToken)
+	}
+
+	if err = authSvc.Logout(c, tokens.RefreshToken); err != nil {
+		t.Errorf("error was not expected while logging out: %s", err)
+	}
+	revoked, err := authSvc.IsTokenRevoked(token.Claims.(*JwtCustomClaims))
+	if err != nil || !revoked {
+		t.Errorf("access token revoked got: %t (%v); want: true", revoked, err)
+	}
+	if _, err = authSvc.Refresh(tokens.RefreshToken); err != ErrInvalidRefreshToken {
+		t.Errorf("error got: %v; want: %v", err, ErrInvalidRefreshToken)
+	}
+	if _, err = authSvc.Refresh(otherTokens.RefreshToken); err != nil {
+		t.Errorf("error was not expected while refreshing token of other user: %s", err)
+	}
+}
+
+func TestNewJWTMiddleware(t *testing.T) {
+	tokenRepo := newTokenRepoFake()
+	authSvc := AuthServiceImpl{CredentialsRepo: newCredentialsRepoFake(), TokenRepo: tokenRepo, Keys: testJWTKeys}
+	tokens, err := authSvc.Authenticate("ala11", "haslo")
+	if err != nil {
+		t.Fatalf("error was not expected while authenticating: %s", err)
+	}
+	handler := NewJWTMiddleware(authSvc, testJWTKeys)(func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+
+	for _, revoked := range []bool{false, true} {
+		if revoked {
+			tokenRepo.revoked[mustParseToken(t, tokens.AccessToken).Claims.(*JwtCustomClaims).Id] = time.Now().Add(time.Hour)
+		}
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/balances", nil)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+tokens.AccessToken)
+		rec := httptest.NewRecorder()
+		if err := handler(echo.New().NewContext(req, rec)); err != nil {
+			t.Errorf("error was not expected while handling request: %s", err)
+		}
+		want := http.StatusOK
+		if revoked {
+			want = http.StatusUnauthorized
+		}
+		if rec.Code != want {
+			t.Errorf("http code for revoked=%t got: %d; want: %d", revoked, rec.Code, want)
+		}
+	}
+}
+
+func TestLoginWithMFA(t *testing.T) {
+	tokenRepo := newTokenRepoFake()
+	mfaSvc := NewMFAService(newMFARepoFake())
+	authSvc := AuthServiceImpl{CredentialsRepo: newCredentialsRepoFake(), TokenRepo: tokenRepo, Keys: testJWTKeys, MFA: mfaSvc}
+	secret, _ := enrollMFA(t, mfaSvc, 1)
+
+	tokens, err := authSvc.Authenticate("ala11", "haslo")
+	if err != nil {
+		t.Fatalf("error was not expected while authenticating: %s", err)
+	}
+	if tokens.MFAToken == "" || tokens.AccessToken != "" || tokens.RefreshToken != "" {
+		t.Fatalf("tokens got: %+v; want only MFA token", tokens)
+	}
+	login, err := authSvc.ParseMFAToken(tokens.MFAToken)
+	if err != nil || login != "ala11" {
+		t.Errorf("login from MFA token got: %s (%v); want: ala11", login, err)
+	}
+
+	// MFA token cannot be used as access token
+	handler := NewJWTMiddleware(authSvc, testJWTKeys)(func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/balances", nil)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer "+tokens.MFAToken)
+	rec := httptest.NewRecorder()
+	if err = handler(echo.New().NewContext(req, rec)); err != nil {
+		t.Errorf("error was not expected while handling request: %s", err)
+	}
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("http code for MFA token got: %d; want: %d", rec.Code, http.StatusUnauthorized)
+	}
+
+	if _, err = authSvc.VerifyMFA(tokens.MFAToken, "000000"); err != ErrInvalidMFACode {
+		t.Errorf("error got: %v; want: %v", err, ErrInvalidMFACode)
+	}
+	verified, err := authSvc.VerifyMFA(tokens.MFAToken, currentTOTPCode(t, secret, 0))
+	if err != nil {
+		t.Fatalf("error was not expected while verifying one-time code: %s", err)
+	}
+	if claims := mustParseToken(t, verified.AccessToken).Claims.(*JwtCustomClaims); claims.UserID != 1 || claims.Audience != "" {
+		t.Errorf("claims of access token got: %+v; want user ID 1 without audience", claims)
+	}
+	if _, err = authSvc.Refresh(verified.RefreshToken); err != nil {
+		t.Errorf("error was not expected while refreshing token: %s", err)
+	}
+
+	// MFA token is exchanged only once
+	if _, err = authSvc.VerifyMFA(tokens.MFAToken, currentTOTPCode(t, secret, 1)); err != ErrInvalidMFAToken {
+		t.Errorf("error got: %v; want: %v", err, ErrInvalidMFAToken)
+	}
+	if _, err = authSvc.ParseMFAToken(verified.AccessToken); err != ErrInvalidMFAToken {
+		t.Errorf("error for access token got: %v; want: %v", err, ErrInvalidMFAToken)
+	}
+
+	// users without MFA get tokens right away
+	tokens, err = authSvc.Authenticate("ola22", "1qazXSW@")
+	if err != nil || tokens.AccessToken == "" || tokens.MFAToken != "" {
+		t.Errorf("tokens got: %+v (%v); want access token", tokens, err)
+	}
+}
+
+func TestReauthenticate(t *testing.T) {
+	mfaSvc := NewMFAService(newMFARepoFake())
+	authSvc := AuthServiceImpl{CredentialsRepo: newCredentialsRepoFake(), TokenRepo: newTokenRepoFake(), Keys: testJWTKeys}
+
+	tokens, err := authSvc.Authenticate("ala11", "haslo")
+	if err != nil {
+		t.Fatalf("error was not expected while authenticating: %s", err)
+	}
+	if claims := mustParseToken(t, tokens.AccessToken).Claims.(*JwtCustomClaims); time.Since(time.Unix(claims.AuthTime, 0)) > time.Minute {
+		t.Errorf("auth_time of access token got: %d; want: now", claims.AuthTime)
+	}
+	refreshed, err := authSvc.Refresh(tokens.RefreshToken)
+	if err != nil {
+		t.Fatalf("error was not expected while refreshing token: %s", err)
+	}
+
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/reauth", nil), httptest.NewRecorder())
+	c.Set("user", mustParseToken(t, refreshed.AccessToken))
+	if authTime, err := authSvc.GetAuthTimeFromToken(c); err != nil || !authTime.IsZero() {
+		t.Errorf("auth time of refreshed token got: %s (%v); want: zero time", authTime, err)
+	}
+	if login, err := authSvc.GetLogin(c); err != nil || login != "ala11" {
+		t.Errorf("login got: %s (%v); want: ala11", login, err)
+	}
+	if role, err := authSvc.GetRoleFromToken(c); err != nil || role != model.RoleUser {
+		t.Errorf("role got: %s (%v); want: %s", role, err, model.RoleUser)
+	}
+
+	if _, err = authSvc.Reauthenticate(c, "wrongPass", ""); err != ErrUnauthorized {
+		t.Errorf("error got: %v; want: %v", err, ErrUnauthorized)
+	}
+	if _, err = authSvc.Reauthenticate(c, "", "123456"); err != ErrInvalidMFACode {
+		t.Errorf("error for one-time code without MFA got: %v; want: %v", err, ErrInvalidMFACode)
+	}
+	reauthenticated, err := authSvc.Reauthenticate(c, "haslo", "")
+	if err != nil {
+		t.Fatalf("error was not expected while re-authenticating: %s", err)
+	}
+	if reauthenticated.RefreshToken != "" {
+		t.Errorf("refresh token got: %s; want: none", reauthenticated.RefreshToken)
+	}
+	c.Set("user", mustParseToken(t, reauthenticated.AccessToken))
+	if authTime, err := authSvc.GetAuthTimeFromToken(c); err != nil || time.Since(authTime) > time.Minute {
+		t.Errorf("auth time of re-authenticated token got: %s (%v); want: now", authTime, err)
+	}
+
+	authSvc.MFA = mfaSvc
+	secret, _ := enrollMFA(t, mfaSvc, 1)
+	if _, err = authSvc.Reauthenticate(c, "", "000000"); err != ErrInvalidMFACode {
+		t.Errorf("error got: %v; want: %v", err, ErrInvalidMFACode)
+	}
+	if _, err = authSvc.Reauthenticate(c, "", currentTOTPCode(t, secret, 0)); err != nil {
+		t.Errorf("error was not expected while re-authenticating with one-time code: %s", err)
+	}
+}
@@ -1,12 +1,20 @@
 package service
 
 import (
+	"errors"
+
+	"github.com/labstack/gommon/log"
 	"zuzanna.com/walletapi/model"
 	"zuzanna.com/walletapi/repository"
 )
 
+var ErrBalanceExists = errors.New("user already has balance in requested currency")
+
 type BalanceService interface {
 	GetByUserID(userID int) ([]model.Balance, error)
+	// Get returns balance of the user, ErrBalanceNotFound when the user has no such balance.
+	Get(userID, id int) (model.BalanceDetails, error)
+	Create(userID int, currency model.Currency) (model.Balance, error)
 }
 
 type BalanceServiceImpl struct {
@@ -27,3 +35,27 @@ func (svc BalanceServiceImpl) GetByUserID(userID int) ([]model.Balance, error) {
 	}
 	return model.ConvertListBalanceDB(balances), nil
 }
+
+func (svc BalanceServiceImpl) Get(userID, id int) (model.BalanceDetails, error) {
+	balance, lastTransactionAt, err := svc.repo.GetBalance(id, userID)
+	if err != nil {
+		if err == repository.ErrRecordNotFound {
+			return model.BalanceDetails{}, ErrBalanceNotFound
+		}
+		return model.BalanceDetails{}, err
+	}
+	return model.BalanceDetails{Balance: model.Balance(balance), LastTransactionAt: lastTransactionAt}, nil
+}
+
+// Create opens new empty balance for the user. User can hold only one balance per currency.
+func (svc BalanceServiceImpl) Create(userID int, currency model.Currency) (model.Balance, error) {
+	balance, err := svc.repo.CreateBalance(userID, currency)
+	if err != nil {
+		if err == repository.ErrBalanceExists {
+			return model.Balance{}, ErrBalanceExists
+		}
+		log.Errorf("#Create(...) error while creating %s balance for user with ID %d; error: %v", currency, userID, err)
+		return model.Balance{}, err
+	}
+	return model.Balance(balance), nil
+}
@@ -5,6 +5,7 @@ import (
 	"math/rand"
 	"reflect"
 	"testing"
+	"time"
 
 	"zuzanna.com/walletapi/model"
 	"zuzanna.com/walletapi/repository"
@@ -12,13 +13,15 @@ import (
 
 type BalanceRepoFake struct {
 	db map[int][]model.BalanceDB
+	// transactions of users, the newest first
+	transactions map[int][]model.TransactionDB
 }
 
 func newBalanceRepoFake() BalanceRepoFake {
 	return BalanceRepoFake{
 		db: map[int][]model.BalanceDB{
-			1: {{ID: 1, Currency: model.SGD, Balance: 1000, Locked: false, UserID: 1}},
-			2: {{ID: 2, Currency: model.SGD, Balance: 25.65, Locked: false, UserID: 2}, {ID: 3, Currency: model.SGD, Balance: 12759.77, Locked: false, UserID: 2}},
+			1: {{ID: 1, Currency: model.SGD, Balance: model.MustParseAmount("1000"), UserID: 1}},
+			2: {{ID: 2, Currency: model.SGD, Balance: model.MustParseAmount("25.65"), UserID: 2}, {ID: 3, Currency: model.SGD, Balance: model.MustParseAmount("12759.77"), UserID: 2}},
 			3: {},
 		},
 	}
@@ -34,6 +37,18 @@ func (r BalanceRepoFake) GetList(userID int) ([]model.BalanceDB, error) {
 	return nil, errors.New("user not found")
 }
 
+func (r BalanceRepoFake) CreateBalance(userID int, currency model.Currency) (model.BalanceDB, error) {
+	for _, b := range r.db[userID] {
+		if b.Currency == currency {
+			return model.BalanceDB{}, repository.ErrBalanceExists
+		}
+	}
+	if userID == 4 {
+		return model.BalanceDB{}, errExpected
+	}
+	return model.BalanceDB{ID: 99, Currency: currency, UserID: userID}, nil
+}
+
 func (r BalanceRepoFake) UpdateBalances(balanceIDs []int, updateFn func(b []model.BalanceDB) ([]model.BalanceDB, error)) error {
 	if balanceIDs[0] == -1 {
 		return repository.ErrBalancesNotFound
@@ -53,6 +68,9 @@ func (r BalanceRepoFake) UpdateBalances(balanceIDs []int, updateFn func(b []mode
 }
 
 func (r BalanceRepoFake) MakeTransaction(t model.TransactionDB, fn func(t model.TransactionDBFull) (model.TransactionDBFull, error)) (model.TransactionDB, error) {
+	if t.SenderBalanceID == -1 {
+		return model.TransactionDB{}, repository.ErrBalancesNotFound
+	}
 	transactionFullDB := makeTransactionDBFull(t)
 	transactionFullDB, err := fn(transactionFullDB)
 	if err != nil {
@@ -60,11 +78,87 @@ func (r BalanceRepoFake) MakeTransaction(t model.TransactionDB, fn func(t model.
 	}
 	t.ID = rand.Intn(9) + 101
 	t.Date = transactionFullDB.Date
+	t.Currency = transactionFullDB.Currency
+	t.ReceiverAmount = transactionFullDB.ReceiverAmount
+	t.ReceiverCurrency = transactionFullDB.ReceiverCurrency
+	t.Rate = transactionFullDB.Rate
+	t.RateDate = transactionFullDB.RateDate
 	return t, nil
 }
 
-func (r BalanceRepoFake) GetTransactions(userID int) ([]model.TransactionDB, error) {
-	return nil, nil
+func (r BalanceRepoFake) GetTransactions(userID int, filter model.TransactionFilter) ([]model.TransactionDB, error) {
+	if r.transactions == nil {
+		return nil, errExpected
+	}
+	transactions := r.transactions[userID]
+	if filter.Limit < len(transactions) {
+		transactions = transactions[:filter.Limit]
+	}
+	return transactions, nil
+}
+
+func (r BalanceRepoFake) GetBalance(id, userID int) (model.BalanceDB, time.Time, error) {
+	for _, b := range r.db[userID] {
+		if b.ID == id {
+			var lastTransactionAt time.Time
+			for _, t := range r.transactions[userID] {
+				if (t.SenderBalanceID == id || t.ReceiverBalanceID == id) && t.Date.After(lastTransactionAt) {
+					lastTransactionAt = t.Date
+				}
+			}
+			return b, lastTransactionAt, nil
+		}
+	}
+	return model.BalanceDB{}, time.Time{}, repository.ErrRecordNotFound
+}
+
+func (r BalanceRepoFake) GetTransaction(id, userID int) (model.TransactionDB, error) {
+	for _, t := range r.transactions[userID] {
+		if t.ID == id {
+			return t, nil
+		}
+	}
+	return model.TransactionDB{}, repository.ErrRecordNotFound
+}
+
+// RefundTransaction looks for the refunded transaction among transactions of all users and for its balances in balances.
+func (r BalanceRepoFake) RefundTransaction(id int, fn func(refunded model.TransactionDB, refund model.TransactionDBFull) (model.TransactionDBFull, error)) (model.TransactionDB, error) {
+	var refunded *model.TransactionDB
+	for _, transactions := range r.transactions {
+		for i := range transactions {
+			if transactions[i].ID == id {
+				refunded = &transactions[i]
+			}
+		}
+	}
+	if refunded == nil {
+		return model.TransactionDB{}, repository.ErrRecordNotFound
+	}
+	refund := model.TransactionDBFull{}
+	for _, b := range balances {
+		if b.ID == refunded.ReceiverBalanceID {
+			refund.SenderBalance = b
+		}
+		if b.ID == refunded.SenderBalanceID {
+			refund.ReceiverBalance = b
+		}
+	}
+	refund, err := fn(*refunded, refund)
+	if err != nil {
+		return model.TransactionDB{}, err
+	}
+	return model.TransactionDB{
+		ID:                 rand.Intn(9) + 201,
+		SenderBalanceID:    refund.SenderBalance.ID,
+		ReceiverBalanceID:  refund.ReceiverBalance.ID,
+		Amount:             refund.Amount,
+		Currency:           refund.Currency,
+		Date:               refund.Date,
+		ReceiverAmount:     refund.ReceiverAmount,
+		ReceiverCurrency:   refund.ReceiverCurrency,
+		TransactionDetails: refund.TransactionDetails,
+		RefundOf:           id,
+	}, nil
 }
 
 func makeTransactionDBFull(t model.TransactionDB) model.TransactionDBFull {
@@ -72,7 +166,7 @@ func makeTransactionDBFull(t model.TransactionDB) model.TransactionDBFull {
 		SenderBalance:   transactionTestCases[t.ID].senderBalance,
 		ReceiverBalance: transactionTestCases[t.ID].receiverBalance,
 		Amount:          t.Amount,
-		Currency:        model.SGD,
+		Currency:        t.Currency,
 	}
 }
 
@@ -87,10 +181,10 @@ func TestGetByUserID(t *testing.T) {
 
 	testCases := []balanceTestCase{
 		{userID: 1,
-			expectedBalances: []model.Balance{{ID: 1, Currency: model.SGD, Balance: 1000, Locked: false, UserID: 1}},
+			expectedBalances: []model.Balance{{ID: 1, Currency: model.SGD, Balance: model.MustParseAmount("1000"), UserID: 1}},
 			expectedErr:      nil},
 		{userID: 2,
-			expectedBalances: []model.Balance{{ID: 2, Currency: model.SGD, Balance: 25.65, Locked: false, UserID: 2}, {ID: 3, Currency: model.SGD, Balance: 12759.77, Locked: false, UserID: 2}},
+			expectedBalances: []model.Balance{{ID: 2, Currency: model.SGD, Balance: model.MustParseAmount("25.65"), UserID: 2}, {ID: 3, Currency: model.SGD, Balance: model.MustParseAmount("12759.77"), UserID: 2}},
 			expectedErr:      nil},
 		{userID: 3,
 			expectedBalances: []model.Balance{},
@@ -112,3 +206,47 @@ func TestGetByUserID(t *testing.T) {
 		}
 	}
 }
+
+func TestCreate(t *testing.T) {
+	svc := BalanceServiceImpl{repo: newBalanceRepoFake()}
+
+	testCases := []struct {
+		userID          int
+		currency        model.Currency
+		expectedBalance model.Balance
+		expectedErr     error
+	}{
+		{userID: 1, currency: model.USD, expectedBalance: model.Balance{ID: 99, Currency: model.USD, UserID: 1}, expectedErr: nil},
+		{userID: 1, currency: model.SGD, expectedBalance: model.Balance{}, expectedErr: ErrBalanceExists},
+		{userID: 4, currency: model.SGD, expectedBalance: model.Balance{}, expectedErr: errExpected},
+	}
+
+	for _, testCase := range testCases {
+		balance, err := svc.Create(testCase.userID, testCase.currency)
+		if testCase.expectedErr != err {
+			t.Errorf("error got: %v; want: %v", err, testCase.expectedErr)
+		}
+		if balance != testCase.expectedBalance {
+			t.Errorf("wrong balance got: %+v; want: %+v", balance, testCase.expectedBalance)
+		}
+	}
+}
+
+func TestGet(t *testing.T) {
+	repo := newBalanceRepoFake()
+	lastTransactionAt := time.Now()
+	repo.transactions = map[int][]model.TransactionDB{2: {{ID: 1, SenderBalanceID: 2, ReceiverBalanceID: 1, Date: lastTransactionAt}}}
+	svc := NewBalanceService(repo)
+
+	got, err := svc.Get(2, 2)
+	if err != nil || got.ID != 2 || !got.LastTransactionAt.Equal(lastTransactionAt) {
+		t.Errorf("balance got: %+v (%v); want balance 2 with last transaction at %s", got, err, lastTransactionAt)
+	}
+	if got, err = svc.Get(2, 3); err != nil || !got.LastTransactionAt.IsZero() {
+		t.Errorf("balance got: %+v (%v); want balance 3 without transactions", got, err)
+	}
+	// balance of other user
+	if _, err = svc.Get(2, 1); err != ErrBalanceNotFound {
+		t.Errorf("error got: %v; want: %v", err, ErrBalanceNotFound)
+	}
+}
@@ -0,0 +1,242 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/labstack/gommon/log"
+	"zuzanna.com/walletapi/model"
+	"zuzanna.com/walletapi/repository"
+)
+
+var ErrBatchNotFound = errors.New("batch not found")
+var ErrInvalidBatchAmount = fmt.Errorf("amount of every batch item must be greater than 0 and at most %s", model.MaxAmount)
+
+// MaxSyncBatchItems is the largest batch processed during the request, larger batches are processed by BatchProcessor.
+const MaxSyncBatchItems = 20
+
+// batchClaimTTL is how long the instance has to process claimed batch, after that its pending items can be processed again.
+const batchClaimTTL = 5 * time.Minute
+
+// batchesPerRun limits number of batches processed by one run of BatchProcessor.
+const batchesPerRun = 10
+
+type BatchService interface {
+	// Submit checks the batch of the user authenticated at authTime - total amount of its items must be available on the sender
+	// balance and it is compared with step-up threshold. Batch with at most MaxSyncBatchItems items is processed at once,
+	// larger batch is returned pending and processed by BatchProcessor.
+	Submit(userID int, b model.Batch, authTime time.Time) (model.Batch, error)
+	// Get returns batch of the user with results of its items, ErrBatchNotFound for other batches.
+	Get(userID, id int) (model.Batch, error)
+	// Process transfers pending items of claimed batch and saves its results. Items of all-or-nothing batch are transferred
+	// in one DB transaction, the item which failed it is failed and the others are skipped. Items of best-effort batch are
+	// transferred one by one.
+	Process(b model.Batch) (model.Batch, error)
+}
+
+type BatchServiceImpl struct {
+	repo     repository.BatchRepo
+	balances repository.BalanceRepo
+	// transactions checks and makes transfers of items like TransactionService.Execute.
+	transactions TransactionServiceImpl
+}
+
+func NewBatchService(r repository.BatchRepo, balances repository.BalanceRepo, fx FXRateProvider, stepUp *StepUpPolicy) BatchService {
+	if r == nil || balances == nil {
+		panic("repo cannot be nil!")
+	}
+	return BatchServiceImpl{repo: r, balances: balances, transactions: NewTransactionService(balances, fx, stepUp)}
+}
+
+func (svc BatchServiceImpl) Submit(userID int, b model.Batch, authTime time.Time) (model.Batch, error) {
+	now := time.Now()
+	sender, _, err := svc.balances.GetBalance(b.SenderBalanceID, userID)
+	if err != nil {
+		if err == repository.ErrRecordNotFound {
+			return model.Batch{}, ErrBalanceNotFound
+		}
+		return model.Batch{}, err
+	}
+	if b.Currency != "" && b.Currency != sender.Currency {
+		return model.Batch{}, ErrCurrencyMismatch
+	}
+	b.Currency = sender.Currency
+	for _, item := range b.Items {
+		// amounts above model.MaxAmount could overflow the total and skip the checks below
+		if item.Amount <= 0 || item.Amount > model.MaxAmount {
+			return model.Batch{}, ErrInvalidBatchAmount
+		}
+		if !b.Currency.AllowsAmount(item.Amount) {
+			return model.Batch{}, ErrAmountNotAllowed
+		}
+	}
+	// funds are checked up front with the same rule as every transfer, so best-effort batch is not transferred
+	// only partially because of them
+	total := b.Total()
+	if balance := model.Balance(sender); total >= balance.Available() {
+		log.Warnf("#Submit(...) failed while submitting batch, error: %v", ErrInsufficientBalance)
+		return model.Batch{}, ErrInsufficientBalance
+	}
+	if svc.transactions.requiresStepUp(total, b.Currency) && !svc.transactions.stepUp.IsFresh(authTime, now) {
+		log.Warnf("#Submit(...) failed while submitting batch, error: %v", ErrStepUpRequired)
+		return model.Batch{}, ErrStepUpRequired
+	}
+
+	b.UserID = userID
+	b.Status = model.BatchPending
+	b.CreatedAt = now
+	// batch processed now is claimed, so BatchProcessor does not process it at the same time
+	var claimedUntil time.Time
+	if len(b.Items) <= MaxSyncBatchItems {
+		claimedUntil = now.Add(batchClaimTTL)
+	}
+	b.ID, err = svc.repo.CreateBatch(model.ConvertBatch(b), claimedUntil)
+	if err != nil {
+		return model.Batch{}, err
+	}
+	if claimedUntil.IsZero() {
+		log.Infof("batch %d with %d items will be processed in background", b.ID, len(b.Items))
+		return b, nil
+	}
+	return svc.Process(b)
+}
+
+func (svc BatchServiceImpl) Get(userID, id int) (model.Batch, error) {
+	b, err := svc.repo.GetBatch(id, userID)
+	if err != nil {
+		if err == repository.ErrRecordNotFound {
+			return model.Batch{}, ErrBatchNotFound
+		}
+		return model.Batch{}, err
+	}
+	return model.ConvertBatchDB(b), nil
+}
+
+func (svc BatchServiceImpl) Process(b model.Batch) (model.Batch, error) {
+	now := time.Now()
+	// step-up authentication was required when the batch was submitted
+	fn := func(item model.BatchItemDB, t model.TransactionDBFull) (model.TransactionDBFull, error) {
+		return svc.transactions.transfer(b.UserID, b.Transaction(model.BatchItem(item)), now)(t)
+	}
+	pending := []model.BatchItemDB{}
+	for _, item := range b.Items {
+		if item.Status == model.BatchItemPending {
+			pending = append(pending, model.BatchItemDB(item))
+		}
+	}
+
+	if b.Mode == model.BatchAllOrNothing {
+		made, err := svc.repo.MakeBatchTransactions(model.ConvertBatch(b), pending, fn)
+		if err != nil && !isBatchItemError(err) && err != repository.ErrBalancesNotFound {
+			log.Errorf("#Process(...) error while processing batch %d; error: %v", b.ID, err)
+			return b, err
+		}
+		b.Items = batchResults(b.Items, pending, made, err)
+	} else {
+		for _, item := range pending {
+			attempted := []model.BatchItemDB{item}
+			made, err := svc.repo.MakeBatchTransactions(model.ConvertBatch(b), attempted, fn)
+			b.Items = batchResults(b.Items, attempted, made, err)
+		}
+	}
+
+	b.Finish(now)
+	if err := svc.repo.SaveBatch(model.ConvertBatch(b)); err != nil {
+		return b, err
+	}
+	log.Infof("processed batch %d; status: %s", b.ID, b.Status)
+	return b, nil
+}
+
+func isBatchItemError(err error) bool {
+	var itemErr repository.BatchItemError
+	return errors.As(err, &itemErr)
+}
+
+// batchResults sets results of transfers of attempted items - made items are completed. Item which failed with err is failed
+// and the other attempted items are skipped, all of them fail when err does not belong to an item.
+func batchResults(items []model.BatchItem, attempted, made []model.BatchItemDB, err error) []model.BatchItem {
+	for _, m := range made {
+		items[m.Index] = model.BatchItem(m)
+	}
+	if err == nil {
+		return items
+	}
+
+	failed := -1
+	var itemErr repository.BatchItemError
+	if errors.As(err, &itemErr) {
+		failed, err = itemErr.Index, itemErr.Err
+	}
+	if err == repository.ErrBalancesNotFound {
+		err = ErrBalanceNotFound
+	}
+	for _, item := range attempted {
+		if failed == -1 || failed == item.Index {
+			items[item.Index].Status = model.BatchItemFailed
+			items[item.Index].Error = transferErrorMessage(err)
+		} else {
+			items[item.Index].Status = model.BatchItemSkipped
+			items[item.Index].Error = fmt.Sprintf("not transferred because item %d failed", failed)
+		}
+	}
+	return items
+}
+
+// BatchProcessor processes batches which are too large to be processed during the request.
+type BatchProcessor struct {
+	repo    repository.BatchRepo
+	batches BatchService
+}
+
+func NewBatchProcessor(r repository.BatchRepo, batches BatchService) BatchProcessor {
+	if r == nil {
+		panic("repo cannot be nil!")
+	}
+	if batches == nil {
+		panic("batch service cannot be nil!")
+	}
+	return BatchProcessor{repo: r, batches: batches}
+}
+
+// Run processes pending batches every interval until ctx is done. It is meant to be started in separate goroutine.
+func (svc BatchProcessor) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := svc.RunPending(); err != nil {
+				log.Errorf("#Run(...) error while processing batches; error: %v", err)
+			}
+		}
+	}
+}
+
+// RunPending processes pending batches and returns them with results of their items. Batch which cannot be processed
+// does not stop the others, its claim expires and it is processed again - the first such error is returned.
+func (svc BatchProcessor) RunPending() ([]model.Batch, error) {
+	now := time.Now()
+	claimed, err := svc.repo.ClaimPendingBatches(now, now.Add(batchClaimTTL), batchesPerRun)
+	if err != nil {
+		return nil, err
+	}
+	processed := []model.Batch{}
+	var processErr error
+	for _, b := range claimed {
+		batch, err := svc.batches.Process(model.ConvertBatchDB(b))
+		if err != nil {
+			log.Errorf("#RunPending(...) error while processing batch %d; error: %v", b.ID, err)
+			if processErr == nil {
+				processErr = err
+			}
+			continue
+		}
+		processed = append(processed, batch)
+	}
+	return processed, processErr
+}
@@ -0,0 +1,269 @@
+package service
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"zuzanna.com/walletapi/model"
+	"zuzanna.com/walletapi/repository"
+)
+
+// BatchRepoFake transfers items between balances, which are changed only when all items of the call are transferred.
+// Saving of the batches in failSave fails.
+type BatchRepoFake struct {
+	balances     map[int]model.BalanceDB
+	batches      map[int]model.BatchDB
+	claimed      map[int]bool
+	transactions *int
+	failSave     map[int]bool
+}
+
+func newBatchRepoFake() BatchRepoFake {
+	return BatchRepoFake{
+		balances: map[int]model.BalanceDB{
+			1: {ID: 1, Currency: model.SGD, Balance: model.MustParseAmount("1000"), UserID: 1},
+			2: {ID: 2, Currency: model.SGD, Balance: model.MustParseAmount("25.65"), UserID: 2},
+			3: {ID: 3, Currency: model.SGD, Balance: model.MustParseAmount("12759.77"), UserID: 2},
+			4: {ID: 4, Currency: model.USD, UserID: 3},
+		},
+		batches:      map[int]model.BatchDB{},
+		claimed:      map[int]bool{},
+		transactions: new(int),
+		failSave:     map[int]bool{},
+	}
+}
+
+func (r BatchRepoFake) CreateBatch(b model.BatchDB, claimedUntil time.Time) (int, error) {
+	b.ID = len(r.batches) + 1
+	r.batches[b.ID] = b
+	r.claimed[b.ID] = !claimedUntil.IsZero()
+	return b.ID, nil
+}
+
+func (r BatchRepoFake) GetBatch(id, userID int) (model.BatchDB, error) {
+	b, ok := r.batches[id]
+	if !ok || b.UserID != userID {
+		return model.BatchDB{}, repository.ErrRecordNotFound
+	}
+	return b, nil
+}
+
+func (r BatchRepoFake) ClaimPendingBatches(now, claimedUntil time.Time, limit int) ([]model.BatchDB, error) {
+	batches := []model.BatchDB{}
+	for id := 1; id <= len(r.batches) && len(batches) < limit; id++ {
+		if r.batches[id].Status == model.BatchPending && !r.claimed[id] {
+			r.claimed[id] = true
+			batches = append(batches, r.batches[id])
+		}
+	}
+	return batches, nil
+}
+
+func (r BatchRepoFake) MakeBatchTransactions(b model.BatchDB, items []model.BatchItemDB,
+	fn func(item model.BatchItemDB, t model.TransactionDBFull) (model.TransactionDBFull, error)) ([]model.BatchItemDB, error) {
+	balances := map[int]model.BalanceDB{}
+	for id, balance := range r.balances {
+		balances[id] = balance
+	}
+	made := []model.BatchItemDB{}
+	for _, item := range items {
+		receiver, ok := balances[item.ReceiverBalanceID]
+		if !ok {
+			return nil, repository.BatchItemError{Index: item.Index, Err: repository.ErrBalancesNotFound}
+		}
+		t, err := fn(item, model.TransactionDBFull{SenderBalance: balances[b.SenderBalanceID], ReceiverBalance: receiver, Amount: item.Amount,
+			Currency: b.Currency})
+		if err != nil {
+			return nil, repository.BatchItemError{Index: item.Index, Err: err}
+		}
+		balances[t.SenderBalance.ID] = t.SenderBalance
+		balances[t.ReceiverBalance.ID] = t.ReceiverBalance
+		*r.transactions++
+		item.Status = model.BatchItemCompleted
+		item.TransactionID = *r.transactions
+		made = append(made, item)
+	}
+	for id, balance := range balances {
+		r.balances[id] = balance
+	}
+	return made, nil
+}
+
+func (r BatchRepoFake) SaveBatch(b model.BatchDB) error {
+	if r.failSave[b.ID] {
+		return errExpected
+	}
+	r.batches[b.ID] = b
+	r.claimed[b.ID] = false
+	return nil
+}
+
+func newTestBatch(mode model.BatchMode, receivers ...int) model.Batch {
+	b := model.Batch{SenderBalanceID: 1, Mode: mode}
+	for i, receiver := range receivers {
+		b.Items = append(b.Items, model.BatchItem{Index: i, ReceiverBalanceID: receiver, Amount: model.MustParseAmount("10"), Status: model.BatchItemPending})
+	}
+	return b
+}
+
+func newTestBatchService(repo BatchRepoFake, stepUp *StepUpPolicy) BatchService {
+	return NewBatchService(repo, newBalanceRepoFake(), nil, stepUp)
+}
+
+func TestSubmitBatchValidation(t *testing.T) {
+	svc := newTestBatchService(newBatchRepoFake(), &StepUpPolicy{Threshold: model.MustParseAmount("500"), Currency: model.SGD, MaxAge: 5 * time.Minute})
+
+	tooMuch := newTestBatch(model.BatchBestEffort, 2, 3)
+	tooMuch.Items[1].Amount = model.MustParseAmount("991")
+	aboveStepUp := newTestBatch(model.BatchBestEffort, 2, 3)
+	aboveStepUp.Items[1].Amount = model.MustParseAmount("491")
+	wholeBalance := newTestBatch(model.BatchBestEffort, 2, 3)
+	wholeBalance.Items[1].Amount = model.MustParseAmount("990")
+	overflow := newTestBatch(model.BatchBestEffort, 2, 3, 2)
+	overflow.Items[0].Amount = math.MaxInt64 / 2
+	overflow.Items[1].Amount = math.MaxInt64 / 2
+	otherCurrency := newTestBatch(model.BatchBestEffort, 2)
+	otherCurrency.Currency = model.USD
+	cases := []struct {
+		name   string
+		userID int
+		batch  model.Batch
+		want   error
+	}{
+		{"other user's balance", 2, newTestBatch(model.BatchBestEffort, 2), ErrBalanceNotFound},
+		{"other currency", 1, otherCurrency, ErrCurrencyMismatch},
+		{"total above available balance", 1, tooMuch, ErrInsufficientBalance},
+		// transfer of the last item would fail, sender balance must stay above its amount
+		{"total equal to available balance", 1, wholeBalance, ErrInsufficientBalance},
+		{"total overflowing amount", 1, overflow, ErrInvalidBatchAmount},
+		{"total above step-up threshold", 1, aboveStepUp, ErrStepUpRequired},
+	}
+	for _, c := range cases {
+		if _, err := svc.Submit(c.userID, c.batch, time.Time{}); err != c.want {
+			t.Errorf("%s: error got: %v; want: %v", c.name, err, c.want)
+		}
+	}
+	if _, err := svc.Submit(1, aboveStepUp, time.Now()); err != nil {
+		t.Errorf("error was not expected while submitting batch after step-up: %s", err)
+	}
+}
+
+func TestSubmitBatchAllOrNothing(t *testing.T) {
+	repo := newBatchRepoFake()
+	svc := newTestBatchService(repo, nil)
+
+	b, err := svc.Submit(1, newTestBatch(model.BatchAllOrNothing, 2, 3), time.Time{})
+	if err != nil {
+		t.Fatalf("error was not expected while submitting batch: %s", err)
+	}
+	if b.Status != model.BatchCompleted || b.Items[0].TransactionID != 1 || b.Items[1].TransactionID != 2 || b.CompletedAt.IsZero() {
+		t.Errorf("batch got: %+v; want completed", b)
+	}
+	if repo.balances[1].Balance != model.MustParseAmount("980") {
+		t.Errorf("sender balance got: %s; want: 980", repo.balances[1].Balance)
+	}
+
+	// the second item is in other currency, nothing is transferred
+	b, err = svc.Submit(1, newTestBatch(model.BatchAllOrNothing, 2, 4, 3), time.Time{})
+	if err != nil {
+		t.Fatalf("error was not expected while submitting batch: %s", err)
+	}
+	want := []model.BatchItemStatus{model.BatchItemSkipped, model.BatchItemFailed, model.BatchItemSkipped}
+	for i, item := range b.Items {
+		if item.Status != want[i] || item.TransactionID != 0 {
+			t.Errorf("item %d got: %+v; want: %s", i, item, want[i])
+		}
+	}
+	if b.Status != model.BatchFailed || b.Items[1].Error != ErrCurrencyMismatch.Error() {
+		t.Errorf("batch got: %+v; want failed because of currency mismatch", b)
+	}
+	if repo.balances[1].Balance != model.MustParseAmount("980") {
+		t.Errorf("sender balance got: %s; want: 980", repo.balances[1].Balance)
+	}
+	if saved, _ := svc.Get(1, b.ID); saved.Status != model.BatchFailed {
+		t.Errorf("saved batch got: %+v; want failed", saved)
+	}
+	if _, err = svc.Get(2, b.ID); err != ErrBatchNotFound {
+		t.Errorf("batch of other user error got: %v; want: %v", err, ErrBatchNotFound)
+	}
+}
+
+func TestSubmitBatchBestEffort(t *testing.T) {
+	repo := newBatchRepoFake()
+	svc := newTestBatchService(repo, nil)
+
+	b, err := svc.Submit(1, newTestBatch(model.BatchBestEffort, 2, 4, 99, 3), time.Time{})
+	if err != nil {
+		t.Fatalf("error was not expected while submitting batch: %s", err)
+	}
+	if b.Status != model.BatchPartiallyCompleted {
+		t.Errorf("batch status got: %s; want: %s", b.Status, model.BatchPartiallyCompleted)
+	}
+	want := []struct {
+		status model.BatchItemStatus
+		err    error
+	}{{model.BatchItemCompleted, nil}, {model.BatchItemFailed, ErrCurrencyMismatch}, {model.BatchItemFailed, ErrBalanceNotFound}, {model.BatchItemCompleted, nil}}
+	for i, item := range b.Items {
+		if item.Status != want[i].status || (want[i].err != nil && item.Error != want[i].err.Error()) {
+			t.Errorf("item %d got: %+v; want: %s (%v)", i, item, want[i].status, want[i].err)
+		}
+	}
+	if repo.balances[1].Balance != model.MustParseAmount("980") {
+		t.Errorf("sender balance got: %s; want: 980", repo.balances[1].Balance)
+	}
+}
+
+func TestBatchProcessor(t *testing.T) {
+	repo := newBatchRepoFake()
+	svc := newTestBatchService(repo, nil)
+	receivers := []int{}
+	for i := 0; i <= MaxSyncBatchItems; i++ {
+		receivers = append(receivers, 2+i%2)
+	}
+
+	b, err := svc.Submit(1, newTestBatch(model.BatchBestEffort, receivers...), time.Time{})
+	if err != nil {
+		t.Fatalf("error was not expected while submitting batch: %s", err)
+	}
+	if b.Status != model.BatchPending || *repo.transactions != 0 {
+		t.Fatalf("large batch got: %s with %d transfers; want pending without transfers", b.Status, *repo.transactions)
+	}
+
+	processed, err := NewBatchProcessor(repo, svc).RunPending()
+	if err != nil {
+		t.Errorf("error was not expected while processing batches: %s", err)
+	}
+	if len(processed) != 1 || processed[0].Status != model.BatchCompleted || *repo.transactions != len(receivers) {
+		t.Errorf("processed batches got: %+v; want one completed batch", processed)
+	}
+	if saved, _ := svc.Get(1, b.ID); saved.Status != model.BatchCompleted {
+		t.Errorf("saved batch got: %s; want: %s", saved.Status, model.BatchCompleted)
+	}
+}
+
+func TestBatchProcessorError(t *testing.T) {
+	repo := newBatchRepoFake()
+	svc := newTestBatchService(repo, nil)
+	receivers := []int{}
+	for i := 0; i <= MaxSyncBatchItems; i++ {
+		receivers = append(receivers, 2)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := svc.Submit(1, newTestBatch(model.BatchBestEffort, receivers...), time.Time{}); err != nil {
+			t.Fatalf("error was not expected while submitting batch: %s", err)
+		}
+	}
+	repo.failSave[1] = true
+
+	processed, err := NewBatchProcessor(repo, svc).RunPending()
+	if err != errExpected {
+		t.Errorf("error got: %v; want: %v", err, errExpected)
+	}
+	if len(processed) != 1 || processed[0].ID != 2 || processed[0].Status != model.BatchCompleted {
+		t.Errorf("processed batches got: %+v; want completed batch 2", processed)
+	}
+	if saved, _ := svc.Get(1, 1); saved.Status != model.BatchPending {
+		t.Errorf("batch which was not saved got: %s; want: %s", saved.Status, model.BatchPending)
+	}
+}
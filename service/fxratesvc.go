@@ -0,0 +1,78 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/labstack/gommon/log"
+	"zuzanna.com/walletapi/model"
+)
+
+var ErrRateNotFound = errors.New("exchange rate for requested currency pair not found")
+
+// FXRateProvider provides exchange rates used for transfers between balances in different currencies.
+type FXRateProvider interface {
+	GetRate(from, to model.Currency) (model.FXRate, error)
+}
+
+// StaticFXRateProvider serves rates from fixed in-memory table. Only pairs present in the table are supported, inverse rate is not calculated.
+type StaticFXRateProvider struct {
+	rates map[currencyPair]model.FXRate
+}
+
+type currencyPair struct {
+	from model.Currency
+	to   model.Currency
+}
+
+func NewStaticFXRateProvider(rates []model.FXRate) StaticFXRateProvider {
+	table := make(map[currencyPair]model.FXRate, len(rates))
+	for _, r := range rates {
+		table[currencyPair{from: r.From, to: r.To}] = r
+	}
+	return StaticFXRateProvider{rates: table}
+}
+
+func (p StaticFXRateProvider) GetRate(from, to model.Currency) (model.FXRate, error) {
+	rate, ok := p.rates[currencyPair{from: from, to: to}]
+	if !ok {
+		return model.FXRate{}, ErrRateNotFound
+	}
+	return rate, nil
+}
+
+type fxRateFileEntry struct {
+	From model.Currency `json:"from"`
+	To   model.Currency `json:"to"`
+	Rate model.Rate     `json:"rate"`
+	Date time.Time      `json:"date"`
+}
+
+// NewFileFXRateProvider loads rates from JSON file, e.g.:
+// [{"from": "USD", "to": "SGD", "rate": "1.352", "date": "2021-12-20T10:00:00Z"}]
+func NewFileFXRateProvider(path string) (StaticFXRateProvider, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return StaticFXRateProvider{}, fmt.Errorf("unable to read exchange rates file %s; error: %w", path, err)
+	}
+	entries := []fxRateFileEntry{}
+	if err = json.Unmarshal(b, &entries); err != nil {
+		return StaticFXRateProvider{}, fmt.Errorf("unable to parse exchange rates file %s; error: %w", path, err)
+	}
+
+	rates := make([]model.FXRate, len(entries))
+	for i, e := range entries {
+		if !e.From.IsValid() || !e.To.IsValid() || e.From == e.To {
+			return StaticFXRateProvider{}, fmt.Errorf("invalid currency pair %s/%s in exchange rates file %s", e.From, e.To, path)
+		}
+		if e.Rate <= 0 {
+			return StaticFXRateProvider{}, fmt.Errorf("missing rate for currency pair %s/%s in exchange rates file %s", e.From, e.To, path)
+		}
+		rates[i] = model.FXRate{From: e.From, To: e.To, Rate: e.Rate, Date: e.Date}
+	}
+	log.Infof("loaded %d exchange rate(s) from %s", len(rates), path)
+	return NewStaticFXRateProvider(rates), nil
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"zuzanna.com/walletapi/model"
+)
+
+func TestStaticFXRateProvider(t *testing.T) {
+	rateDate := time.Now()
+	provider := NewStaticFXRateProvider([]model.FXRate{
+		{From: model.USD, To: model.SGD, Rate: model.MustParseRate("1.3654"), Date: rateDate},
+	})
+
+	rate, err := provider.GetRate(model.USD, model.SGD)
+	if err != nil {
+		t.Errorf("error was not expected while getting rate: %s", err)
+	}
+	if rate.Rate != model.MustParseRate("1.3654") || !rate.Date.Equal(rateDate) {
+		t.Errorf("rate got: %+v; want: 1.365400 at %s", rate, rateDate)
+	}
+
+	_, err = provider.GetRate(model.SGD, model.USD)
+	if err != ErrRateNotFound {
+		t.Errorf("error got: %v; want: %v", err, ErrRateNotFound)
+	}
+}
+
+func TestFileFXRateProvider(t *testing.T) {
+	dir := t.TempDir()
+
+	valid := filepath.Join(dir, "valid.json")
+	os.WriteFile(valid, []byte(`[{"from": "USD", "to": "SGD", "rate": "1.3654", "date": "2021-12-20T08:00:00Z"}]`), 0600)
+	provider, err := NewFileFXRateProvider(valid)
+	if err != nil {
+		t.Errorf("error was not expected while loading rates: %s", err)
+	}
+	rate, err := provider.GetRate(model.USD, model.SGD)
+	if err != nil {
+		t.Errorf("error was not expected while getting rate: %s", err)
+	}
+	if rate.Rate != model.MustParseRate("1.3654") {
+		t.Errorf("rate got: %s; want: 1.365400", rate.Rate)
+	}
+
+	testCases := map[string]string{
+		"unknown_currency.json": `[{"from": "USD", "to": "XXX", "rate": "1.3654"}]`,
+		"same_currency.json":    `[{"from": "USD", "to": "USD", "rate": "1"}]`,
+		"negative_rate.json":    `[{"from": "USD", "to": "SGD", "rate": "-1.3654"}]`,
+		"missing_rate.json":     `[{"from": "USD", "to": "SGD"}]`,
+		"not_json.json":         `USD,SGD,1.3654`,
+	}
+	for name, content := range testCases {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, []byte(content), 0600)
+		if _, err := NewFileFXRateProvider(path); err == nil {
+			t.Errorf("error expected while loading %s", name)
+		}
+	}
+
+	if _, err := NewFileFXRateProvider(filepath.Join(dir, "missing.json")); err == nil {
+		t.Errorf("error expected while loading missing file")
+	}
+}
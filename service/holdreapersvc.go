@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/labstack/gommon/log"
+	"zuzanna.com/walletapi/model"
+	"zuzanna.com/walletapi/repository"
+)
+
+// HoldExpiredEvent is the operational log event written for every hold expired by HoldReaper.
+const HoldExpiredEvent = "HOLD_EXPIRED"
+
+// HoldReaper expires active holds after their expiry, so held money is available again for the sender.
+type HoldReaper struct {
+	repo  repository.HoldRepo
+	opLog OperationalLogService
+}
+
+type expiredHoldDetails struct {
+	HoldID            int       `json:"holdId"`
+	SenderBalanceID   int       `json:"senderBalanceId"`
+	ReceiverBalanceID int       `json:"receiverBalanceId"`
+	Amount            string    `json:"amount"`
+	Currency          string    `json:"currency"`
+	ExpiresAt         time.Time `json:"expiresAt"`
+}
+
+func NewHoldReaper(r repository.HoldRepo, opLog OperationalLogService) HoldReaper {
+	if r == nil {
+		panic("repo cannot be nil!")
+	}
+	if opLog == nil {
+		panic("operational log service cannot be nil!")
+	}
+	return HoldReaper{repo: r, opLog: opLog}
+}
+
+// Run expires holds every interval until ctx is done. It is meant to be started in separate goroutine.
+func (svc HoldReaper) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := svc.ExpireHolds(); err != nil {
+				log.Errorf("#Run(...) error while expiring holds; error: %v", err)
+			}
+		}
+	}
+}
+
+// ExpireHolds releases active holds after their expiry and writes every expired hold to the operational log.
+// Holds captured or voided in the meantime are skipped.
+func (svc HoldReaper) ExpireHolds() ([]model.Hold, error) {
+	now := time.Now()
+	holds, err := svc.repo.GetExpiredHolds(now)
+	if err != nil {
+		return nil, err
+	}
+	expired := []model.Hold{}
+	for _, h := range holds {
+		var senderUserID int
+		updated, err := svc.repo.UpdateHold(h.ID, func(hold model.HoldDBFull) (model.HoldDBFull, *model.TransactionDBFull, error) {
+			if hold.Status != model.HoldActive || now.Before(hold.ExpiresAt) {
+				return model.HoldDBFull{}, nil, ErrHoldNotActive
+			}
+			sender := model.Balance(hold.SenderBalance)
+			sender.Release(hold.Amount)
+			hold.SenderBalance = model.BalanceDB(sender)
+			hold.Status = model.HoldExpired
+			senderUserID = sender.UserID
+			return hold, nil, nil
+		})
+		if err != nil {
+			if err != ErrHoldNotActive {
+				return expired, err
+			}
+			continue
+		}
+		svc.opLog.CreateEventLog(model.Info, HoldExpiredEvent, senderUserID, expiredHoldDetails{HoldID: updated.ID, SenderBalanceID: updated.SenderBalanceID,
+			ReceiverBalanceID: updated.ReceiverBalanceID, Amount: updated.Amount.String(), Currency: string(updated.Currency), ExpiresAt: updated.ExpiresAt})
+		expired = append(expired, model.Hold(updated))
+	}
+	if len(expired) > 0 {
+		log.Infof("expired %d hold(s)", len(expired))
+	}
+	return expired, nil
+}
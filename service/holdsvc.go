@@ -0,0 +1,196 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/labstack/gommon/log"
+	"zuzanna.com/walletapi/model"
+	"zuzanna.com/walletapi/repository"
+)
+
+var ErrHoldNotFound = errors.New("hold not found")
+var ErrHoldNotAllowed = errors.New("only receiver of the hold can capture or void it")
+var ErrHoldNotActive = errors.New("hold was already captured or voided, or it expired")
+var ErrCaptureExceedsHold = errors.New("captured amount exceeds amount of the hold")
+var ErrInvalidHoldExpiry = errors.New("hold must expire in the future, at most in 30 days")
+
+// DefaultHoldTTL is used when expiry of new hold is not set, holds cannot be active longer than maxHoldTTL.
+const DefaultHoldTTL = 7 * 24 * time.Hour
+const maxHoldTTL = 30 * 24 * time.Hour
+
+type HoldService interface {
+	// Create reserves amount of the sender balance of the user authenticated at authTime for the receiver balance.
+	// Hold goes through the same checks as transfers, including step-up authentication, but only in one currency.
+	Create(userID int, h model.Hold, authTime time.Time) (model.Hold, error)
+	// Get returns hold from or to a balance of the user, ErrHoldNotFound for other holds.
+	Get(userID, id int) (model.Hold, error)
+	// Capture transfers amount of the active hold to its receiver balance, whole hold when amount is zero, the rest
+	// of the hold is released. Only the receiver can capture the hold, other users get ErrHoldNotFound unless they made it.
+	Capture(userID, id int, amount model.Amount) (model.Hold, error)
+	// Void releases whole active hold, only the receiver can void it like in Capture.
+	Void(userID, id int) (model.Hold, error)
+}
+
+type HoldServiceImpl struct {
+	repo repository.HoldRepo
+	// fx is optional, it is used only to compare amount with step-up threshold in other currency.
+	fx FXRateProvider
+	// stepUp is optional, without it holds of any amount are allowed regardless of authentication time.
+	stepUp *StepUpPolicy
+}
+
+func NewHoldService(r repository.HoldRepo, fx FXRateProvider, stepUp *StepUpPolicy) HoldService {
+	if r == nil {
+		panic("repo cannot be nil!")
+	}
+	return HoldServiceImpl{repo: r, fx: fx, stepUp: stepUp}
+}
+
+func (svc HoldServiceImpl) Create(userID int, h model.Hold, authTime time.Time) (model.Hold, error) {
+	now := time.Now()
+	if h.ExpiresAt.IsZero() {
+		h.ExpiresAt = now.Add(DefaultHoldTTL)
+	}
+	if !h.ExpiresAt.After(now) || h.ExpiresAt.After(now.Add(maxHoldTTL)) {
+		return model.Hold{}, ErrInvalidHoldExpiry
+	}
+	h.CreatedAt = now
+	h.Status = model.HoldActive
+
+	created, err := svc.repo.CreateHold(model.HoldDB(h), func(hold model.HoldDBFull) (model.HoldDBFull, error) {
+		sender := model.Balance(hold.SenderBalance)
+		receiver := model.Balance(hold.ReceiverBalance)
+		if userID != sender.UserID {
+			log.Warnf("#Create(...) failed while creating hold, error: %v", ErrUnauthorizedTransaction)
+			return model.HoldDBFull{}, ErrUnauthorizedTransaction
+		}
+		if (h.Currency != "" && h.Currency != sender.Currency) || sender.Currency != receiver.Currency {
+			log.Warnf("#Create(...) failed while creating hold, error: %v", ErrCurrencyMismatch)
+			return model.HoldDBFull{}, ErrCurrencyMismatch
+		}
+		if !sender.Currency.AllowsAmount(h.Amount) {
+			log.Warnf("#Create(...) failed while creating hold, error: %v", ErrAmountNotAllowed)
+			return model.HoldDBFull{}, ErrAmountNotAllowed
+		}
+		if svc.stepUp.Requires(h.Amount, sender.Currency, svc.fx) && !svc.stepUp.IsFresh(authTime, now) {
+			log.Warnf("#Create(...) failed while creating hold, error: %v", ErrStepUpRequired)
+			return model.HoldDBFull{}, ErrStepUpRequired
+		}
+		// hold is valid when the same transfer would be
+		transactionFull := model.TransactionFull{SenderBalance: &sender, ReceiverBalance: &receiver, Amount: h.Amount, Currency: sender.Currency}
+		if !transactionFull.IsValid() {
+			log.Warnf("#Create(...) failed while creating hold, error: %v", ErrInsufficientBalance)
+			return model.HoldDBFull{}, ErrInsufficientBalance
+		}
+
+		sender.Hold(h.Amount)
+		hold.Currency = sender.Currency
+		hold.SenderBalance = model.BalanceDB(sender)
+		return hold, nil
+	})
+	if err != nil {
+		if err == repository.ErrBalancesNotFound {
+			return model.Hold{}, ErrBalanceNotFound
+		}
+		log.Errorf("#Create(...) error create hold %+v; error: %v", h, err)
+		return model.Hold{}, err
+	}
+	return model.Hold(created), nil
+}
+
+func (svc HoldServiceImpl) Get(userID, id int) (model.Hold, error) {
+	h, err := svc.repo.GetHold(id, userID)
+	if err != nil {
+		if err == repository.ErrRecordNotFound {
+			return model.Hold{}, ErrHoldNotFound
+		}
+		return model.Hold{}, err
+	}
+	return model.Hold(h), nil
+}
+
+func (svc HoldServiceImpl) Capture(userID, id int, amount model.Amount) (model.Hold, error) {
+	return svc.update(userID, id, func(h model.Hold, sender, receiver *model.Balance) (*model.TransactionFull, error) {
+		if amount == 0 {
+			amount = h.Amount
+		}
+		if amount < 0 || amount > h.Amount {
+			return nil, ErrCaptureExceedsHold
+		}
+		if !h.Currency.AllowsAmount(amount) {
+			return nil, ErrAmountNotAllowed
+		}
+
+		sender.Release(h.Amount)
+		transactionFull := model.TransactionFull{SenderBalance: sender, ReceiverBalance: receiver, Amount: amount, Currency: h.Currency}
+		if !transactionFull.IsValid() {
+			return nil, ErrInsufficientBalance
+		}
+		transactionFull.Make()
+		return &transactionFull, nil
+	})
+}
+
+func (svc HoldServiceImpl) Void(userID, id int) (model.Hold, error) {
+	return svc.update(userID, id, func(h model.Hold, sender, receiver *model.Balance) (*model.TransactionFull, error) {
+		sender.Release(h.Amount)
+		return nil, nil
+	})
+}
+
+// update captures (fn returns transaction) or voids active hold of the receiver user.
+func (svc HoldServiceImpl) update(userID, id int, fn func(h model.Hold, sender, receiver *model.Balance) (*model.TransactionFull, error)) (model.Hold, error) {
+	updated, err := svc.repo.UpdateHold(id, func(hold model.HoldDBFull) (model.HoldDBFull, *model.TransactionDBFull, error) {
+		h := model.Hold(hold.HoldDB)
+		sender := model.Balance(hold.SenderBalance)
+		receiver := model.Balance(hold.ReceiverBalance)
+		if receiver.UserID != userID {
+			if sender.UserID == userID {
+				log.Warnf("#update(...) failed while updating hold %d, error: %v", h.ID, ErrHoldNotAllowed)
+				return model.HoldDBFull{}, nil, ErrHoldNotAllowed
+			}
+			return model.HoldDBFull{}, nil, ErrHoldNotFound
+		}
+		if !h.IsActive(time.Now()) {
+			log.Warnf("#update(...) failed while updating hold %d, error: %v", h.ID, ErrHoldNotActive)
+			return model.HoldDBFull{}, nil, ErrHoldNotActive
+		}
+
+		transactionFull, err := fn(h, &sender, &receiver)
+		if err != nil {
+			log.Warnf("#update(...) failed while updating hold %d, error: %v", h.ID, err)
+			return model.HoldDBFull{}, nil, err
+		}
+
+		hold.SenderBalance = model.BalanceDB(sender)
+		hold.ReceiverBalance = model.BalanceDB(receiver)
+		if transactionFull == nil {
+			hold.Status = model.HoldVoided
+			return hold, nil, nil
+		}
+		hold.Status = model.HoldCaptured
+		hold.CapturedAmount = transactionFull.Amount
+		return hold, &model.TransactionDBFull{
+			SenderBalance:      hold.SenderBalance,
+			ReceiverBalance:    hold.ReceiverBalance,
+			Amount:             transactionFull.Amount,
+			Currency:           transactionFull.Currency,
+			Date:               transactionFull.Date,
+			ReceiverAmount:     transactionFull.ReceiverAmount,
+			ReceiverCurrency:   transactionFull.ReceiverCurrency,
+			TransactionDetails: h.TransactionDetails,
+		}, nil
+	})
+	if err != nil {
+		switch err {
+		case repository.ErrRecordNotFound:
+			return model.Hold{}, ErrHoldNotFound
+		case repository.ErrBalancesNotFound:
+			return model.Hold{}, ErrBalanceNotFound
+		}
+		log.Errorf("#update(...) error update hold %d; error: %v", id, err)
+		return model.Hold{}, err
+	}
+	return model.Hold(updated), nil
+}
@@ -0,0 +1,251 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"zuzanna.com/walletapi/model"
+	"zuzanna.com/walletapi/repository"
+)
+
+// HoldRepoFake keeps balances and holds in maps, so changes saved by one call are seen by the next one.
+type HoldRepoFake struct {
+	balances map[int]model.BalanceDB
+	holds    map[int]model.HoldDB
+	// transactions made by captures
+	transactions *[]model.TransactionDBFull
+}
+
+func newHoldRepoFake() HoldRepoFake {
+	return HoldRepoFake{
+		balances: map[int]model.BalanceDB{
+			1: {ID: 1, Currency: model.SGD, Balance: model.MustParseAmount("100"), UserID: 1},
+			2: {ID: 2, Currency: model.SGD, Balance: model.MustParseAmount("50"), UserID: 2},
+			3: {ID: 3, Currency: model.USD, Balance: model.MustParseAmount("50"), UserID: 2},
+		},
+		holds:        map[int]model.HoldDB{},
+		transactions: &[]model.TransactionDBFull{},
+	}
+}
+
+func (r HoldRepoFake) CreateHold(h model.HoldDB, fn func(h model.HoldDBFull) (model.HoldDBFull, error)) (model.HoldDB, error) {
+	sender, ok := r.balances[h.SenderBalanceID]
+	receiver, ok2 := r.balances[h.ReceiverBalanceID]
+	if !ok || !ok2 {
+		return model.HoldDB{}, repository.ErrBalancesNotFound
+	}
+	hold, err := fn(model.HoldDBFull{HoldDB: h, SenderBalance: sender, ReceiverBalance: receiver})
+	if err != nil {
+		return model.HoldDB{}, err
+	}
+	hold.ID = len(r.holds) + 1
+	r.holds[hold.ID] = hold.HoldDB
+	r.balances[sender.ID], r.balances[receiver.ID] = hold.SenderBalance, hold.ReceiverBalance
+	return hold.HoldDB, nil
+}
+
+func (r HoldRepoFake) GetHold(id, userID int) (model.HoldDB, error) {
+	h, ok := r.holds[id]
+	if !ok || (r.balances[h.SenderBalanceID].UserID != userID && r.balances[h.ReceiverBalanceID].UserID != userID) {
+		return model.HoldDB{}, repository.ErrRecordNotFound
+	}
+	return h, nil
+}
+
+func (r HoldRepoFake) UpdateHold(id int, fn func(h model.HoldDBFull) (model.HoldDBFull, *model.TransactionDBFull, error)) (model.HoldDB, error) {
+	h, ok := r.holds[id]
+	if !ok {
+		return model.HoldDB{}, repository.ErrRecordNotFound
+	}
+	hold, transaction, err := fn(model.HoldDBFull{HoldDB: h, SenderBalance: r.balances[h.SenderBalanceID], ReceiverBalance: r.balances[h.ReceiverBalanceID]})
+	if err != nil {
+		return model.HoldDB{}, err
+	}
+	if transaction != nil {
+		*r.transactions = append(*r.transactions, *transaction)
+		hold.TransactionID = len(*r.transactions)
+	}
+	r.holds[id] = hold.HoldDB
+	r.balances[h.SenderBalanceID], r.balances[h.ReceiverBalanceID] = hold.SenderBalance, hold.ReceiverBalance
+	return hold.HoldDB, nil
+}
+
+func (r HoldRepoFake) GetExpiredHolds(now time.Time) ([]model.HoldDB, error) {
+	holds := []model.HoldDB{}
+	for id := 1; id <= len(r.holds); id++ {
+		if h := r.holds[id]; h.Status == model.HoldActive && !now.Before(h.ExpiresAt) {
+			holds = append(holds, h)
+		}
+	}
+	return holds, nil
+}
+
+func TestCreateHold(t *testing.T) {
+	repo := newHoldRepoFake()
+	svc := NewHoldService(repo, nil, &StepUpPolicy{Threshold: model.MustParseAmount("80"), Currency: model.SGD, MaxAge: 5 * time.Minute})
+
+	h, err := svc.Create(1, model.Hold{SenderBalanceID: 1, ReceiverBalanceID: 2, Amount: model.MustParseAmount("60"),
+		TransactionDetails: model.TransactionDetails{Memo: "Order 42"}}, time.Time{})
+	if err != nil {
+		t.Fatalf("error was not expected while creating a hold: %s", err)
+	}
+	if h.ID != 1 || h.Status != model.HoldActive || h.Currency != model.SGD || h.Memo != "Order 42" {
+		t.Errorf("hold got: %+v", h)
+	}
+	if ttl := h.ExpiresAt.Sub(h.CreatedAt); ttl != DefaultHoldTTL {
+		t.Errorf("hold expires in: %s; want: %s", ttl, DefaultHoldTTL)
+	}
+	if b := model.Balance(repo.balances[1]); b.Balance != model.MustParseAmount("100") || b.Available() != model.MustParseAmount("40") {
+		t.Errorf("sender balance got: %+v; want balance 100 with 40 available", b)
+	}
+
+	cases := []struct {
+		name   string
+		userID int
+		hold   model.Hold
+		want   error
+	}{
+		{"held money is not available", 1, model.Hold{SenderBalanceID: 1, ReceiverBalanceID: 2, Amount: model.MustParseAmount("40")}, ErrInsufficientBalance},
+		{"other user's balance", 2, model.Hold{SenderBalanceID: 1, ReceiverBalanceID: 2, Amount: model.MustParseAmount("1")}, ErrUnauthorizedTransaction},
+		{"different currencies", 2, model.Hold{SenderBalanceID: 2, ReceiverBalanceID: 3, Amount: model.MustParseAmount("1")}, ErrCurrencyMismatch},
+		{"missing balance", 1, model.Hold{SenderBalanceID: 1, ReceiverBalanceID: 9, Amount: model.MustParseAmount("1")}, ErrBalanceNotFound},
+		{"expired", 1, model.Hold{SenderBalanceID: 1, ReceiverBalanceID: 2, Amount: model.MustParseAmount("1"), ExpiresAt: time.Now().Add(-time.Second)}, ErrInvalidHoldExpiry},
+		{"expires too late", 1, model.Hold{SenderBalanceID: 1, ReceiverBalanceID: 2, Amount: model.MustParseAmount("1"), ExpiresAt: time.Now().Add(31 * 24 * time.Hour)}, ErrInvalidHoldExpiry},
+	}
+	for _, c := range cases {
+		if _, err := svc.Create(c.userID, c.hold, time.Time{}); err != c.want {
+			t.Errorf("%s: error got: %v; want: %v", c.name, err, c.want)
+		}
+	}
+
+	repo.balances[1] = model.BalanceDB{ID: 1, Currency: model.SGD, Balance: model.MustParseAmount("1000"), UserID: 1}
+	if _, err = svc.Create(1, model.Hold{SenderBalanceID: 1, ReceiverBalanceID: 2, Amount: model.MustParseAmount("90")}, time.Time{}); err != ErrStepUpRequired {
+		t.Errorf("error got: %v; want: %v", err, ErrStepUpRequired)
+	}
+	if _, err = svc.Create(1, model.Hold{SenderBalanceID: 1, ReceiverBalanceID: 2, Amount: model.MustParseAmount("90")}, time.Now()); err != nil {
+		t.Errorf("error was not expected while creating a hold after step-up: %s", err)
+	}
+}
+
+func TestCaptureHold(t *testing.T) {
+	repo := newHoldRepoFake()
+	svc := NewHoldService(repo, nil, nil)
+	h, err := svc.Create(1, model.Hold{SenderBalanceID: 1, ReceiverBalanceID: 2, Amount: model.MustParseAmount("60"),
+		TransactionDetails: model.TransactionDetails{Reference: "ORDER-42"}}, time.Time{})
+	if err != nil {
+		t.Fatalf("error was not expected while creating a hold: %s", err)
+	}
+
+	if _, err = svc.Capture(1, h.ID, 0); err != ErrHoldNotAllowed {
+		t.Errorf("capture by sender error got: %v; want: %v", err, ErrHoldNotAllowed)
+	}
+	if _, err = svc.Capture(3, h.ID, 0); err != ErrHoldNotFound {
+		t.Errorf("capture by other user error got: %v; want: %v", err, ErrHoldNotFound)
+	}
+	if _, err = svc.Capture(2, h.ID, model.MustParseAmount("60.01")); err != ErrCaptureExceedsHold {
+		t.Errorf("error got: %v; want: %v", err, ErrCaptureExceedsHold)
+	}
+
+	captured, err := svc.Capture(2, h.ID, model.MustParseAmount("45"))
+	if err != nil {
+		t.Fatalf("error was not expected while capturing a hold: %s", err)
+	}
+	if captured.Status != model.HoldCaptured || captured.CapturedAmount != model.MustParseAmount("45") || captured.TransactionID != 1 {
+		t.Errorf("captured hold got: %+v", captured)
+	}
+	if b := repo.balances[1]; b.Balance != model.MustParseAmount("55") || b.Held != 0 {
+		t.Errorf("sender balance got: %+v; want balance 55 with nothing held", b)
+	}
+	if b := repo.balances[2]; b.Balance != model.MustParseAmount("95") {
+		t.Errorf("receiver balance got: %+v; want balance 95", b)
+	}
+	if tr := (*repo.transactions)[0]; tr.Amount != model.MustParseAmount("45") || tr.ReceiverAmount != tr.Amount || tr.Reference != "ORDER-42" {
+		t.Errorf("captured transaction got: %+v", tr)
+	}
+
+	if _, err = svc.Capture(2, h.ID, 0); err != ErrHoldNotActive {
+		t.Errorf("second capture error got: %v; want: %v", err, ErrHoldNotActive)
+	}
+	if _, err = svc.Void(2, h.ID); err != ErrHoldNotActive {
+		t.Errorf("void of captured hold error got: %v; want: %v", err, ErrHoldNotActive)
+	}
+	if _, err = svc.Capture(2, 99, 0); err != ErrHoldNotFound {
+		t.Errorf("error got: %v; want: %v", err, ErrHoldNotFound)
+	}
+}
+
+func TestVoidHold(t *testing.T) {
+	repo := newHoldRepoFake()
+	svc := NewHoldService(repo, nil, nil)
+	h, err := svc.Create(1, model.Hold{SenderBalanceID: 1, ReceiverBalanceID: 2, Amount: model.MustParseAmount("60")}, time.Time{})
+	if err != nil {
+		t.Fatalf("error was not expected while creating a hold: %s", err)
+	}
+
+	voided, err := svc.Void(2, h.ID)
+	if err != nil {
+		t.Fatalf("error was not expected while voiding a hold: %s", err)
+	}
+	if voided.Status != model.HoldVoided || voided.CapturedAmount != 0 || len(*repo.transactions) != 0 {
+		t.Errorf("voided hold got: %+v", voided)
+	}
+	if b := repo.balances[1]; b.Balance != model.MustParseAmount("100") || b.Held != 0 {
+		t.Errorf("sender balance got: %+v; want balance 100 with nothing held", b)
+	}
+
+	got, err := svc.Get(1, h.ID)
+	if err != nil || got.Status != model.HoldVoided {
+		t.Errorf("hold got: %+v (%v); want voided hold", got, err)
+	}
+	if _, err = svc.Get(3, h.ID); err != ErrHoldNotFound {
+		t.Errorf("error got: %v; want: %v", err, ErrHoldNotFound)
+	}
+}
+
+func TestExpireHolds(t *testing.T) {
+	repo := newHoldRepoFake()
+	svc := NewHoldService(repo, nil, nil)
+	for _, expiresAt := range []time.Time{time.Now().Add(time.Hour), time.Now().Add(2 * time.Hour)} {
+		if _, err := svc.Create(1, model.Hold{SenderBalanceID: 1, ReceiverBalanceID: 2, Amount: model.MustParseAmount("20"), ExpiresAt: expiresAt}, time.Time{}); err != nil {
+			t.Fatalf("error was not expected while creating a hold: %s", err)
+		}
+	}
+	// the first hold expired in the meantime
+	h := repo.holds[1]
+	h.ExpiresAt = time.Now().Add(-time.Minute)
+	repo.holds[1] = h
+
+	if _, err := svc.Capture(2, 1, 0); err != ErrHoldNotActive {
+		t.Errorf("capture of expired hold error got: %v; want: %v", err, ErrHoldNotActive)
+	}
+
+	events := []eventLog{}
+	reaper := NewHoldReaper(repo, OperationalLogServiceFake{events: &events})
+	expired, err := reaper.ExpireHolds()
+	if err != nil {
+		t.Errorf("error was not expected while expiring holds: %s", err)
+	}
+	if len(expired) != 1 || expired[0].ID != 1 || expired[0].Status != model.HoldExpired {
+		t.Errorf("expired holds got: %+v; want hold with ID 1", expired)
+	}
+	if b := repo.balances[1]; b.Held != model.MustParseAmount("20") {
+		t.Errorf("sender balance got: %+v; want 20 held by the second hold", b)
+	}
+	if len(events) != 1 || events[0].event != HoldExpiredEvent || events[0].userID != 1 {
+		t.Fatalf("operational log events got: %+v; want one %s event for user 1", events, HoldExpiredEvent)
+	}
+	details, _ := json.Marshal(events[0].details)
+	want := `{"holdId":1,"senderBalanceId":1,"receiverBalanceId":2,"amount":"20.00","currency":"SGD","expiresAt":` + mustMarshal(t, h.ExpiresAt) + `}`
+	if string(details) != want {
+		t.Errorf("operational log details got: %s; want: %s", details, want)
+	}
+}
+
+func mustMarshal(t *testing.T, v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("cannot marshal %v: %s", v, err)
+	}
+	return string(b)
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/labstack/gommon/log"
+	"zuzanna.com/walletapi/model"
+	"zuzanna.com/walletapi/repository"
+)
+
+var ErrInvalidIdempotencyKey = errors.New("idempotency key is too long")
+var ErrIdempotencyKeyMismatch = errors.New("idempotency key was already used for a different request")
+var ErrIdempotencyKeyInProgress = errors.New("request with the same idempotency key is still in progress")
+
+const maxIdempotencyKeyLength = 255
+
+type IdempotencyService interface {
+	// Start reserves key for the request. If the key was already used for the same request, returned key holds saved response.
+	Start(userID int, key string, request []byte) (model.IdempotencyKey, error)
+	// Finish saves response for the key, requests retried with the key get the same response.
+	Finish(userID int, key string, code int, response []byte) error
+	// Release removes the key, e.g. when request failed and can be safely retried.
+	Release(userID int, key string) error
+}
+
+type IdempotencyServiceImpl struct {
+	repo repository.IdempotencyRepo
+	// ttl is how long the key is remembered, after that the same key can be used for a new request.
+	ttl time.Duration
+}
+
+func NewIdempotencyService(r repository.IdempotencyRepo, ttl time.Duration) IdempotencyServiceImpl {
+	if r == nil {
+		panic("repo cannot be nil!")
+	}
+	return IdempotencyServiceImpl{repo: r, ttl: ttl}
+}
+
+func (svc IdempotencyServiceImpl) Start(userID int, key string, request []byte) (model.IdempotencyKey, error) {
+	if len(key) > maxIdempotencyKeyLength {
+		return model.IdempotencyKey{}, ErrInvalidIdempotencyKey
+	}
+	now := time.Now()
+	newKey := model.IdempotencyKeyDB{UserID: userID, Key: key, Fingerprint: fingerprint(request), CreatedAt: now}
+
+	reserved, err := svc.repo.Reserve(newKey, now.Add(-svc.ttl))
+	if err != nil {
+		return model.IdempotencyKey{}, err
+	}
+	if reserved {
+		return model.IdempotencyKey(newKey), nil
+	}
+
+	existingKey, err := svc.repo.Get(userID, key)
+	if err != nil {
+		if err == repository.ErrRecordNotFound {
+			// key was released by concurrent request in the meantime
+			return model.IdempotencyKey{}, ErrIdempotencyKeyInProgress
+		}
+		return model.IdempotencyKey{}, err
+	}
+	if existingKey.Fingerprint != newKey.Fingerprint {
+		log.Warnf("#Start(...) idempotency key %s of user with ID %d reused for different request", key, userID)
+		return model.IdempotencyKey{}, ErrIdempotencyKeyMismatch
+	}
+	if existingKey.ResponseCode == 0 {
+		return model.IdempotencyKey{}, ErrIdempotencyKeyInProgress
+	}
+	return model.IdempotencyKey(existingKey), nil
+}
+
+func (svc IdempotencyServiceImpl) Finish(userID int, key string, code int, response []byte) error {
+	return svc.repo.SaveResponse(userID, key, code, response)
+}
+
+func (svc IdempotencyServiceImpl) Release(userID int, key string) error {
+	return svc.repo.Delete(userID, key)
+}
+
+func fingerprint(request []byte) string {
+	sum := sha256.Sum256(request)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"zuzanna.com/walletapi/model"
+	"zuzanna.com/walletapi/repository"
+)
+
+type IdempotencyRepoFake struct {
+	db map[string]model.IdempotencyKeyDB
+}
+
+func newIdempotencyRepoFake() IdempotencyRepoFake {
+	return IdempotencyRepoFake{db: map[string]model.IdempotencyKeyDB{}}
+}
+
+func (r IdempotencyRepoFake) Reserve(k model.IdempotencyKeyDB, expiredBefore time.Time) (bool, error) {
+	if k.Key == "err" {
+		return false, errExpected
+	}
+	existing, ok := r.db[k.Key]
+	if ok && !existing.CreatedAt.Before(expiredBefore) {
+		return false, nil
+	}
+	r.db[k.Key] = k
+	return true, nil
+}
+
+func (r IdempotencyRepoFake) Get(userID int, key string) (model.IdempotencyKeyDB, error) {
+	k, ok := r.db[key]
+	if !ok {
+		return model.IdempotencyKeyDB{}, repository.ErrRecordNotFound
+	}
+	return k, nil
+}
+
+func (r IdempotencyRepoFake) SaveResponse(userID int, key string, code int, body []byte) error {
+	k := r.db[key]
+	k.ResponseCode = code
+	k.ResponseBody = body
+	r.db[key] = k
+	return nil
+}
+
+func (r IdempotencyRepoFake) Delete(userID int, key string) error {
+	delete(r.db, key)
+	return nil
+}
+
+func TestIdempotencyStart(t *testing.T) {
+	repo := newIdempotencyRepoFake()
+	svc := NewIdempotencyService(repo, time.Hour)
+	request := []byte(`{"amount":10}`)
+
+	k, err := svc.Start(1, "key1", request)
+	if err != nil {
+		t.Errorf("error was not expected while starting request: %s", err)
+	}
+	if k.IsCompleted() {
+		t.Errorf("new key must not be completed, got: %+v", k)
+	}
+
+	_, err = svc.Start(1, "key1", request)
+	if err != ErrIdempotencyKeyInProgress {
+		t.Errorf("error got: %v; want: %v", err, ErrIdempotencyKeyInProgress)
+	}
+
+	err = svc.Finish(1, "key1", 201, []byte(`{"id":1}`))
+	if err != nil {
+		t.Errorf("error was not expected while finishing request: %s", err)
+	}
+	k, err = svc.Start(1, "key1", request)
+	if err != nil {
+		t.Errorf("error was not expected while replaying request: %s", err)
+	}
+	if !k.IsCompleted() || k.ResponseCode != 201 || string(k.ResponseBody) != `{"id":1}` {
+		t.Errorf("replayed key got: %+v; want saved response", k)
+	}
+
+	_, err = svc.Start(1, "key1", []byte(`{"amount":11}`))
+	if err != ErrIdempotencyKeyMismatch {
+		t.Errorf("error got: %v; want: %v", err, ErrIdempotencyKeyMismatch)
+	}
+
+	_, err = svc.Start(1, "err", request)
+	if err != errExpected {
+		t.Errorf("error got: %v; want: %v", err, errExpected)
+	}
+
+	longKey := make([]byte, maxIdempotencyKeyLength+1)
+	for i := range longKey {
+		longKey[i] = 'a'
+	}
+	_, err = svc.Start(1, string(longKey), request)
+	if err != ErrInvalidIdempotencyKey {
+		t.Errorf("error got: %v; want: %v", err, ErrInvalidIdempotencyKey)
+	}
+}
+
+func TestIdempotencyKeyExpiry(t *testing.T) {
+	repo := newIdempotencyRepoFake()
+	repo.db["old"] = model.IdempotencyKeyDB{UserID: 1, Key: "old", Fingerprint: fingerprint([]byte("a")), ResponseCode: 201, CreatedAt: time.Now().Add(-2 * time.Hour)}
+	svc := NewIdempotencyService(repo, time.Hour)
+
+	k, err := svc.Start(1, "old", []byte("b"))
+	if err != nil {
+		t.Errorf("error was not expected while reusing expired key: %s", err)
+	}
+	if k.IsCompleted() {
+		t.Errorf("expired key must be reserved again, got: %+v", k)
+	}
+}
+
+func TestIdempotencyRelease(t *testing.T) {
+	repo := newIdempotencyRepoFake()
+	svc := NewIdempotencyService(repo, time.Hour)
+
+	if _, err := svc.Start(1, "key1", []byte("a")); err != nil {
+		t.Errorf("error was not expected while starting request: %s", err)
+	}
+	if err := svc.Release(1, "key1"); err != nil {
+		t.Errorf("error was not expected while releasing key: %s", err)
+	}
+	if _, err := svc.Start(1, "key1", []byte("b")); err != nil {
+		t.Errorf("error was not expected while reusing released key: %s", err)
+	}
+}
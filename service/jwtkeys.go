@@ -0,0 +1,235 @@
+package service
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/labstack/gommon/log"
+	"zuzanna.com/walletapi/model"
+)
+
+var ErrNoSigningKey = errors.New("no private key found to sign JWT tokens")
+var ErrUnknownKeyID = errors.New("unknown JWT key ID (kid)")
+
+// keyFileExt is extension of key files, name of the file without extension is key ID (kid).
+const keyFileExt = ".pem"
+
+type JWTKeyProvider interface {
+	// Sign signs claims with the current signing key and sets its ID in kid header.
+	Sign(claims jwt.Claims) (string, error)
+	// Keyfunc returns public key used to verify the token, based on kid header of the token.
+	Keyfunc(token *jwt.Token) (interface{}, error)
+	// PublicKeys returns all keys active for verification in JWK format.
+	PublicKeys() []model.JWK
+}
+
+type jwtKey struct {
+	id      string
+	method  jwt.SigningMethod
+	private crypto.PrivateKey
+	public  crypto.PublicKey
+}
+
+// JWTKeySet holds RS256/EdDSA keys loaded from a directory. Every "<kid>.pem" file holds a private key (PKCS#1 or PKCS#8) or
+// a public key (PKIX). All keys are used for verification, tokens are signed with the private key which ID sorts last,
+// so keys named e.g. "2026-10-18.pem" rotate by adding a newer file. Public key files allow to verify tokens signed with
+// retired key until they expire.
+type JWTKeySet struct {
+	dir     string
+	mu      sync.RWMutex
+	signing *jwtKey
+	keys    map[string]*jwtKey
+}
+
+// NewJWTKeySet loads keys from dir, the directory must contain at least one private key.
+func NewJWTKeySet(dir string) (*JWTKeySet, error) {
+	ks := &JWTKeySet{dir: dir}
+	if err := ks.Reload(); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+// NewEphemeralJWTKeySet generates single Ed25519 key kept only in memory. Tokens signed with it are not valid after restart.
+func NewEphemeralJWTKeySet() (*JWTKeySet, error) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	id, err := newTokenID()
+	if err != nil {
+		return nil, err
+	}
+	key := &jwtKey{id: id, method: jwt.SigningMethodEdDSA, private: private, public: public}
+	return &JWTKeySet{signing: key, keys: map[string]*jwtKey{id: key}}, nil
+}
+
+// Reload reads keys from the directory again. Current keys are kept when the directory cannot be read.
+func (ks *JWTKeySet) Reload() error {
+	if ks.dir == "" {
+		return nil
+	}
+	keys, signing, err := loadJWTKeys(ks.dir)
+	if err != nil {
+		return err
+	}
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if ks.signing == nil || ks.signing.id != signing.id {
+		log.Infof("JWT tokens are signed with key %s (%s)", signing.id, signing.method.Alg())
+	}
+	ks.keys = keys
+	ks.signing = signing
+	return nil
+}
+
+// Run reloads keys every interval until ctx is done.
+func (ks *JWTKeySet) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := ks.Reload(); err != nil {
+				log.Errorf("#Run(...) error while reloading JWT keys from %s, previous keys are kept; error %v", ks.dir, err)
+			}
+		}
+	}
+}
+
+func (ks *JWTKeySet) Sign(claims jwt.Claims) (string, error) {
+	ks.mu.RLock()
+	key := ks.signing
+	ks.mu.RUnlock()
+
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.id
+	return token.SignedString(key.private)
+}
+
+func (ks *JWTKeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	ks.mu.RLock()
+	key, ok := ks.keys[kid]
+	ks.mu.RUnlock()
+	if !ok {
+		return nil, ErrUnknownKeyID
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected jwt signing method=%v for key %s", token.Header["alg"], kid)
+	}
+	return key.public, nil
+}
+
+func (ks *JWTKeySet) PublicKeys() []model.JWK {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	jwks := make([]model.JWK, 0, len(ks.keys))
+	for _, key := range ks.keys {
+		jwks = append(jwks, key.jwk())
+	}
+	sort.Slice(jwks, func(i, j int) bool {
+		return jwks[i].Kid < jwks[j].Kid
+	})
+	return jwks
+}
+
+func (key *jwtKey) jwk() model.JWK {
+	jwk := model.JWK{Kid: key.id, Use: "sig", Alg: key.method.Alg()}
+	switch public := key.public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	}
+	return jwk
+}
+
+func loadJWTKeys(dir string) (map[string]*jwtKey, *jwtKey, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*"+keyFileExt))
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.Strings(files)
+
+	keys := make(map[string]*jwtKey, len(files))
+	var signing *jwtKey
+	for _, file := range files {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, nil, err
+		}
+		key, err := parseJWTKey(strings.TrimSuffix(filepath.Base(file), keyFileExt), b)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid key file %s: %w", file, err)
+		}
+		keys[key.id] = key
+		if key.private != nil {
+			signing = key
+		}
+	}
+	if signing == nil {
+		return nil, nil, ErrNoSigningKey
+	}
+	return keys, signing, nil
+}
+
+func parseJWTKey(id string, b []byte) (*jwtKey, error) {
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %s", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &jwtKey{id: id}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.method, key.private, key.public = jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.method, key.public = jwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		key.method, key.private, key.public = jwt.SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.method, key.public = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, fmt.Errorf("unsupported key type %T, only RSA and Ed25519 keys are supported", parsed)
+	}
+	return key, nil
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang-jwt/jwt"
+)
+
+func writeKeyFile(t *testing.T, dir, kid, blockType string, der []byte) {
+	b := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := ioutil.WriteFile(filepath.Join(dir, kid+keyFileExt), b, 0600); err != nil {
+		t.Fatalf("error was not expected while writing key file: %s", err)
+	}
+}
+
+func writeEd25519Key(t *testing.T, dir, kid string) ed25519.PublicKey {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("error was not expected while generating key: %s", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatalf("error was not expected while marshalling key: %s", err)
+	}
+	writeKeyFile(t, dir, kid, "PRIVATE KEY", der)
+	return public
+}
+
+func signTestToken(t *testing.T, ks *JWTKeySet) string {
+	signed, err := ks.Sign(&JwtCustomClaims{UserID: 1})
+	if err != nil {
+		t.Fatalf("error was not expected while signing token: %s", err)
+	}
+	return signed
+}
+
+func TestJWTKeySet(t *testing.T) {
+	dir := t.TempDir()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("error was not expected while generating key: %s", err)
+	}
+	writeKeyFile(t, dir, "2026-01-01", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))
+
+	ks, err := NewJWTKeySet(dir)
+	if err != nil {
+		t.Fatalf("error was not expected while loading keys: %s", err)
+	}
+	oldToken := signTestToken(t, ks)
+	token, err := jwt.ParseWithClaims(oldToken, &JwtCustomClaims{}, ks.Keyfunc)
+	if err != nil || token.Header["kid"] != "2026-01-01" || token.Method.Alg() != "RS256" {
+		t.Errorf("token got: kid %v, alg %s (%v); want: kid 2026-01-01, alg RS256", token.Header["kid"], token.Method.Alg(), err)
+	}
+
+	// newer key is added - new tokens are signed with it, tokens signed with the old key are still valid
+	writeEd25519Key(t, dir, "2026-02-01")
+	if err = ks.Reload(); err != nil {
+		t.Fatalf("error was not expected while reloading keys: %s", err)
+	}
+	newToken := signTestToken(t, ks)
+	token, err = jwt.ParseWithClaims(newToken, &JwtCustomClaims{}, ks.Keyfunc)
+	if err != nil || token.Header["kid"] != "2026-02-01" || token.Method.Alg() != "EdDSA" {
+		t.Errorf("token got: kid %v, alg %s (%v); want: kid 2026-02-01, alg EdDSA", token.Header["kid"], token.Method.Alg(), err)
+	}
+	if _, err = jwt.ParseWithClaims(oldToken, &JwtCustomClaims{}, ks.Keyfunc); err != nil {
+		t.Errorf("error was not expected while parsing token signed with old key: %s", err)
+	}
+
+	jwks := ks.PublicKeys()
+	if len(jwks) != 2 || jwks[0].Kty != "RSA" || jwks[0].N == "" || jwks[0].E != "AQAB" || jwks[1].Kty != "OKP" || jwks[1].Crv != "Ed25519" || jwks[1].X == "" {
+		t.Errorf("public keys got: %+v; want: RSA and Ed25519 keys", jwks)
+	}
+
+	// old private key is retired, its public part is kept for verification
+	der, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatalf("error was not expected while marshalling key: %s", err)
+	}
+	writeKeyFile(t, dir, "2026-01-01", "PUBLIC KEY", der)
+	if err = ks.Reload(); err != nil {
+		t.Fatalf("error was not expected while reloading keys: %s", err)
+	}
+	if _, err = jwt.ParseWithClaims(oldToken, &JwtCustomClaims{}, ks.Keyfunc); err != nil {
+		t.Errorf("error was not expected while parsing token signed with retired key: %s", err)
+	}
+
+	// old key is removed
+	if err = os.Remove(filepath.Join(dir, "2026-01-01"+keyFileExt)); err != nil {
+		t.Fatalf("error was not expected while removing key file: %s", err)
+	}
+	if err = ks.Reload(); err != nil {
+		t.Fatalf("error was not expected while reloading keys: %s", err)
+	}
+	if _, err = jwt.ParseWithClaims(oldToken, &JwtCustomClaims{}, ks.Keyfunc); err == nil {
+		t.Errorf("token signed with removed key got: valid; want: error")
+	}
+
+	// invalid key file - current keys are kept
+	if err = ioutil.WriteFile(filepath.Join(dir, "2026-03-01"+keyFileExt), []byte("not a key"), 0600); err != nil {
+		t.Fatalf("error was not expected while writing key file: %s", err)
+	}
+	if err = ks.Reload(); err == nil {
+		t.Errorf("reload with invalid key file got: nil error; want: error")
+	}
+	if _, err = jwt.ParseWithClaims(newToken, &JwtCustomClaims{}, ks.Keyfunc); err != nil {
+		t.Errorf("error was not expected while parsing token after failed reload: %s", err)
+	}
+}
+
+func TestNewJWTKeySetWithoutPrivateKey(t *testing.T) {
+	if _, err := NewJWTKeySet(t.TempDir()); err != ErrNoSigningKey {
+		t.Errorf("error got: %v; want: %v", err, ErrNoSigningKey)
+	}
+}
+
+func TestJWTKeySetRejectsTokenWithoutKid(t *testing.T) {
+	ks, err := NewEphemeralJWTKeySet()
+	if err != nil {
+		t.Fatalf("error was not expected while generating keys: %s", err)
+	}
+	// token signed with the old HMAC secret
+	hmacToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &JwtCustomClaims{UserID: 1}).SignedString([]byte("mySecret"))
+	if err != nil {
+		t.Fatalf("error was not expected while signing token: %s", err)
+	}
+	if _, err = jwt.ParseWithClaims(hmacToken, &JwtCustomClaims{}, ks.Keyfunc); err == nil {
+		t.Errorf("token without kid got: valid; want: error")
+	}
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/labstack/gommon/log"
+	"zuzanna.com/walletapi/model"
+	"zuzanna.com/walletapi/repository"
+)
+
+var ErrLoginLocked = errors.New("too many failed login attempts")
+
+// LoginThrottle counts every attempt as failed before credentials are verified, so parallel guesses cannot all pass
+// the check before the first of them is registered.
+type LoginThrottle interface {
+	// Attempt counts failed attempt of the login from the client IP. It returns ErrLoginLocked and time after which login
+	// can be retried when the login or the IP is locked, then the attempt is not counted and must not be made.
+	Attempt(login, ip string) (time.Duration, error)
+	// RegisterSuccess resets failures of the login. Failures of the IP are kept, so one valid account cannot be used
+	// to reset the counter of IP guessing passwords of other accounts - only the successful attempt is not counted.
+	RegisterSuccess(login, ip string) error
+	// Cancel does not count the attempt which did not fail, e.g. password was verified and login continues with one-time
+	// code. Failures of the login are kept.
+	Cancel(login, ip string) error
+	Unlock(login string) error
+}
+
+// LoginThrottlePolicy defines when login is locked. After every failure the login (or IP) is locked for Backoff doubled
+// with every next failure, after MaxFailures it is locked for Lockout. Failures older than Lockout are forgotten.
+type LoginThrottlePolicy struct {
+	MaxFailures   int
+	MaxIPFailures int
+	Backoff       time.Duration
+	Lockout       time.Duration
+}
+
+var DefaultLoginThrottlePolicy = LoginThrottlePolicy{
+	MaxFailures:   5,
+	MaxIPFailures: 20,
+	Backoff:       time.Second,
+	Lockout:       15 * time.Minute,
+}
+
+type LoginThrottleImpl struct {
+	repo   repository.LoginAttemptRepo
+	policy LoginThrottlePolicy
+}
+
+func NewLoginThrottle(r repository.LoginAttemptRepo, policy LoginThrottlePolicy) LoginThrottleImpl {
+	if r == nil {
+		panic("repo cannot be nil!")
+	}
+	return LoginThrottleImpl{repo: r, policy: policy}
+}
+
+func loginKey(login string) string {
+	return "login:" + login
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+func (svc LoginThrottleImpl) Attempt(login, ip string) (time.Duration, error) {
+	retryAfter, err := svc.attempt(loginKey(login), svc.policy.MaxFailures)
+	if err != nil || retryAfter > 0 {
+		return retryAfter, err
+	}
+	retryAfter, err = svc.attempt(ipKey(ip), svc.policy.MaxIPFailures)
+	if err != nil || retryAfter > 0 {
+		// the login was counted already
+		if cancelErr := svc.cancel(loginKey(login), svc.policy.MaxFailures); cancelErr != nil && err == nil {
+			err = cancelErr
+		}
+	}
+	return retryAfter, err
+}
+
+// attempt counts failed attempt of the key unless it is locked, then it returns ErrLoginLocked with time after which
+// the key is unlocked.
+func (svc LoginThrottleImpl) attempt(key string, maxFailures int) (time.Duration, error) {
+	now := time.Now()
+	var retryAfter time.Duration
+	a, err := svc.repo.Update(key, func(a *model.LoginAttemptDB) {
+		if retryAfter = model.LoginAttempt(*a).RetryAfter(now); retryAfter > 0 {
+			return
+		}
+		if now.Sub(a.LastFailureAt) > svc.policy.Lockout {
+			a.Failures = 0
+		}
+		a.Failures++
+		a.LastFailureAt = now
+		a.LockedUntil = now.Add(svc.policy.lockFor(a.Failures, maxFailures))
+	})
+	if err != nil {
+		log.Errorf("#Attempt(...) error while registering login attempt of %s; error: %v", key, err)
+		return 0, err
+	}
+	if retryAfter > 0 {
+		return retryAfter, ErrLoginLocked
+	}
+	if a.Failures == maxFailures {
+		log.Warnf("%s locked until %s after %d failed login attempts", key, a.LockedUntil.Format(time.RFC3339), a.Failures)
+	}
+	return 0, nil
+}
+
+// cancel does not count the last attempt of the key. The key was not locked before the attempt was allowed, so lock
+// set by the attempt is lifted unless failures of the key still reach maxFailures.
+func (svc LoginThrottleImpl) cancel(key string, maxFailures int) error {
+	_, err := svc.repo.Update(key, func(a *model.LoginAttemptDB) {
+		if a.Failures > 0 {
+			a.Failures--
+		}
+		if a.Failures < maxFailures {
+			a.LockedUntil = time.Time{}
+		}
+	})
+	if err != nil {
+		log.Errorf("#Cancel(...) error while cancelling login attempt of %s; error: %v", key, err)
+		return err
+	}
+	return nil
+}
+
+// lockFor returns how long key is locked after given number of failures.
+func (p LoginThrottlePolicy) lockFor(failures, maxFailures int) time.Duration {
+	if failures >= maxFailures {
+		return p.Lockout
+	}
+	d := p.Backoff
+	for i := 1; i < failures && d < p.Lockout; i++ {
+		d *= 2
+	}
+	if d > p.Lockout {
+		return p.Lockout
+	}
+	return d
+}
+
+func (svc LoginThrottleImpl) RegisterSuccess(login, ip string) error {
+	if err := svc.repo.Reset(loginKey(login)); err != nil {
+		return err
+	}
+	return svc.cancel(ipKey(ip), svc.policy.MaxIPFailures)
+}
+
+func (svc LoginThrottleImpl) Cancel(login, ip string) error {
+	if err := svc.cancel(loginKey(login), svc.policy.MaxFailures); err != nil {
+		return err
+	}
+	return svc.cancel(ipKey(ip), svc.policy.MaxIPFailures)
+}
+
+func (svc LoginThrottleImpl) Unlock(login string) error {
+	if err := svc.repo.Reset(loginKey(login)); err != nil {
+		log.Errorf("#Unlock(...) error while unlocking login %s; error: %v", login, err)
+		return err
+	}
+	return nil
+}
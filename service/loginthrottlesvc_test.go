@@ -0,0 +1,124 @@
+package service
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"zuzanna.com/walletapi/repository"
+)
+
+func TestLoginThrottlePolicyLockFor(t *testing.T) {
+	policy := LoginThrottlePolicy{MaxFailures: 5, Backoff: time.Second, Lockout: 15 * time.Minute}
+	cases := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{failures: 1, want: time.Second},
+		{failures: 2, want: 2 * time.Second},
+		{failures: 4, want: 8 * time.Second},
+		{failures: 5, want: 15 * time.Minute},
+		{failures: 50, want: 15 * time.Minute},
+	}
+	for _, testCase := range cases {
+		if got := policy.lockFor(testCase.failures, policy.MaxFailures); got != testCase.want {
+			t.Errorf("lockFor(%d) got: %s; want: %s", testCase.failures, got, testCase.want)
+		}
+	}
+
+	// backoff never exceeds lockout
+	policy = LoginThrottlePolicy{MaxFailures: 100, Backoff: time.Minute, Lockout: 5 * time.Minute}
+	if got := policy.lockFor(90, policy.MaxFailures); got != 5*time.Minute {
+		t.Errorf("lockFor(90) got: %s; want: %s", got, 5*time.Minute)
+	}
+}
+
+func TestLoginThrottle(t *testing.T) {
+	svc := NewLoginThrottle(repository.NewMemoryLoginAttemptRepo(), LoginThrottlePolicy{
+		MaxFailures:   2,
+		MaxIPFailures: 3,
+		Lockout:       time.Hour,
+	})
+
+	for i := 0; i < 2; i++ {
+		if _, err := svc.Attempt("ala11", "10.0.0.1"); err != nil {
+			t.Errorf("error was not expected while counting attempt: %s", err)
+		}
+	}
+	retryAfter, err := svc.Attempt("ala11", "10.0.0.2")
+	if err != ErrLoginLocked || retryAfter <= 59*time.Minute {
+		t.Errorf("attempt of locked login got: %v, retry after %s; want: %v, retry after 1h", err, retryAfter, ErrLoginLocked)
+	}
+
+	// third attempt from the IP locks it for every login, attempts from locked IP are not counted for the login
+	if _, err = svc.Attempt("ola22", "10.0.0.1"); err != nil {
+		t.Errorf("error was not expected while counting attempt: %s", err)
+	}
+	if _, err = svc.Attempt("ela33", "10.0.0.1"); err != ErrLoginLocked {
+		t.Errorf("attempt from locked IP got: %v; want: %v", err, ErrLoginLocked)
+	}
+	if _, err = svc.Attempt("ela33", "10.0.0.2"); err != nil {
+		t.Errorf("error was not expected while counting attempt: %s", err)
+	}
+	if _, err = svc.Attempt("ela33", "10.0.0.2"); err != nil {
+		t.Errorf("attempt of login after attempt from locked IP got: %v; want it counted once", err)
+	}
+
+	if err = svc.Unlock("ala11"); err != nil {
+		t.Errorf("error was not expected while unlocking login: %s", err)
+	}
+	if _, err = svc.Attempt("ala11", "10.0.0.2"); err != nil {
+		t.Errorf("error was not expected while counting attempt of unlocked login: %s", err)
+	}
+
+	// successful login resets failures of the login and does not count its attempt from the IP
+	for i := 0; i < 2; i++ {
+		if _, err = svc.Attempt("ola22", "10.0.0.3"); err != nil {
+			t.Errorf("error was not expected while counting attempt: %s", err)
+		}
+		if err = svc.RegisterSuccess("ola22", "10.0.0.3"); err != nil {
+			t.Errorf("error was not expected while registering success: %s", err)
+		}
+	}
+	if _, err = svc.Attempt("ola22", "10.0.0.3"); err != nil {
+		t.Errorf("error was not expected while counting attempt after success: %s", err)
+	}
+	if _, err = svc.Attempt("ela33", "10.0.0.1"); err != ErrLoginLocked {
+		t.Errorf("attempt from locked IP after success of other login got: %v; want: %v", err, ErrLoginLocked)
+	}
+
+	// cancelled attempt is not counted
+	if err = svc.Cancel("ola22", "10.0.0.3"); err != nil {
+		t.Errorf("error was not expected while cancelling attempt: %s", err)
+	}
+	if _, err = svc.Attempt("ola22", "10.0.0.3"); err != nil {
+		t.Errorf("error was not expected while counting attempt after cancelled one: %s", err)
+	}
+}
+
+func TestLoginThrottleParallelAttempts(t *testing.T) {
+	svc := NewLoginThrottle(repository.NewMemoryLoginAttemptRepo(), LoginThrottlePolicy{
+		MaxFailures:   3,
+		MaxIPFailures: 100,
+		Lockout:       time.Hour,
+	})
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	allowed := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := svc.Attempt("ala11", "10.0.0.1"); err == nil {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if allowed != 3 {
+		t.Errorf("allowed parallel attempts got: %d; want: 3", allowed)
+	}
+}
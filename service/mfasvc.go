@@ -0,0 +1,173 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/labstack/gommon/log"
+	"zuzanna.com/walletapi/model"
+	"zuzanna.com/walletapi/repository"
+)
+
+var ErrMFAAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+var ErrMFANotEnrolled = errors.New("TOTP secret was not enrolled")
+var ErrInvalidMFACode = errors.New("invalid one-time code")
+
+const recoveryCodesCount = 10
+
+// recoveryCodeBytes gives 16 base32 characters, enough entropy to store codes hashed with SHA-256.
+const recoveryCodeBytes = 10
+
+type MFAService interface {
+	// Enroll generates new TOTP secret. Secret is used for login only after it is confirmed.
+	Enroll(userID int) (model.TOTPEnrollment, error)
+	// Confirm enables two-factor authentication when code matches enrolled secret and returns new recovery codes.
+	Confirm(userID int, code string) ([]string, error)
+	IsEnabled(userID int) (bool, error)
+	// Verify checks TOTP code or unused recovery code, every code is accepted only once.
+	Verify(userID int, code string) error
+}
+
+type MFAServiceImpl struct {
+	repo repository.MFARepo
+}
+
+func NewMFAService(r repository.MFARepo) MFAServiceImpl {
+	if r == nil {
+		panic("repo cannot be nil!")
+	}
+	return MFAServiceImpl{repo: r}
+}
+
+func (svc MFAServiceImpl) Enroll(userID int) (model.TOTPEnrollment, error) {
+	secret, err := newTOTPSecret()
+	if err != nil {
+		log.Errorf("#Enroll(...) error while generating TOTP secret; error: %v", err)
+		return model.TOTPEnrollment{}, err
+	}
+	login, err := svc.repo.SaveSecret(userID, secret)
+	if err != nil {
+		if err == repository.ErrMFAConfirmed {
+			return model.TOTPEnrollment{}, ErrMFAAlreadyEnabled
+		}
+		return model.TOTPEnrollment{}, err
+	}
+	return model.TOTPEnrollment{Secret: secret, URI: totpURI(login, secret)}, nil
+}
+
+func (svc MFAServiceImpl) Confirm(userID int, code string) ([]string, error) {
+	m, err := svc.get(userID)
+	if err != nil {
+		if err == repository.ErrRecordNotFound {
+			return nil, ErrMFANotEnrolled
+		}
+		return nil, err
+	}
+	if m.IsConfirmed() {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	now := time.Now()
+	step, ok := matchTOTP(m.Secret, strings.TrimSpace(code), now)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes := make([]string, recoveryCodesCount)
+	hashes := make([]string, recoveryCodesCount)
+	for i := range codes {
+		if codes[i], err = newRecoveryCode(); err != nil {
+			log.Errorf("#Confirm(...) error while generating recovery code; error: %v", err)
+			return nil, err
+		}
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+	if err = svc.repo.Confirm(userID, step, now, hashes); err != nil {
+		if err == repository.ErrMFAConfirmed {
+			return nil, ErrMFAAlreadyEnabled
+		}
+		return nil, err
+	}
+	log.Infof("two-factor authentication enabled for user with ID %d", userID)
+	return codes, nil
+}
+
+func (svc MFAServiceImpl) IsEnabled(userID int) (bool, error) {
+	m, err := svc.get(userID)
+	if err != nil {
+		if err == repository.ErrRecordNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	return m.IsConfirmed(), nil
+}
+
+func (svc MFAServiceImpl) Verify(userID int, code string) error {
+	m, err := svc.get(userID)
+	if err != nil {
+		if err == repository.ErrRecordNotFound {
+			return ErrInvalidMFACode
+		}
+		return err
+	}
+	if !m.IsConfirmed() {
+		return ErrInvalidMFACode
+	}
+
+	code = strings.TrimSpace(code)
+	if step, ok := matchTOTP(m.Secret, code, time.Now()); ok {
+		// code could be intercepted, so it cannot be replayed within its validity window
+		used, err := svc.repo.UseStep(userID, step)
+		if err != nil {
+			return err
+		}
+		if !used {
+			log.Warnf("#Verify(...) TOTP code of user with ID %d reused", userID)
+			return ErrInvalidMFACode
+		}
+		return nil
+	}
+
+	used, err := svc.repo.UseRecoveryCode(userID, hashRecoveryCode(code), time.Now())
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidMFACode
+	}
+	log.Infof("recovery code of user with ID %d used", userID)
+	return nil
+}
+
+func (svc MFAServiceImpl) get(userID int) (model.MFA, error) {
+	m, err := svc.repo.Get(userID)
+	if err != nil {
+		return model.MFA{}, err
+	}
+	return model.MFA(m), nil
+}
+
+// newRecoveryCode returns code formatted in groups of 4 characters, e.g. ABCD-EFGH-IJKL-MNOP.
+func newRecoveryCode() (string, error) {
+	b := make([]byte, recoveryCodeBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	code := totpEncoding.EncodeToString(b)
+	groups := []string{}
+	for i := 0; i < len(code); i += 4 {
+		groups = append(groups, code[i:i+4])
+	}
+	return strings.Join(groups, "-"), nil
+}
+
+// hashRecoveryCode hashes normalized code, so it can be typed in lowercase and without dashes.
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
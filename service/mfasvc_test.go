@@ -0,0 +1,172 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"zuzanna.com/walletapi/model"
+	"zuzanna.com/walletapi/repository"
+)
+
+type MFARepoFake struct {
+	db            map[int]model.MFADB
+	recoveryCodes map[int]map[string]bool
+}
+
+func newMFARepoFake() MFARepoFake {
+	return MFARepoFake{
+		db:            map[int]model.MFADB{},
+		recoveryCodes: map[int]map[string]bool{},
+	}
+}
+
+func (r MFARepoFake) SaveSecret(userID int, secret string) (string, error) {
+	if m, ok := r.db[userID]; ok && !m.ConfirmedAt.IsZero() {
+		return "", repository.ErrMFAConfirmed
+	}
+	r.db[userID] = model.MFADB{UserID: userID, Secret: secret}
+	return "ala11", nil
+}
+
+func (r MFARepoFake) Get(userID int) (model.MFADB, error) {
+	m, ok := r.db[userID]
+	if !ok {
+		return model.MFADB{}, repository.ErrRecordNotFound
+	}
+	return m, nil
+}
+
+func (r MFARepoFake) Confirm(userID int, step int64, confirmedAt time.Time, codeHashes []string) error {
+	m := r.db[userID]
+	if !m.ConfirmedAt.IsZero() {
+		return repository.ErrMFAConfirmed
+	}
+	m.ConfirmedAt = confirmedAt
+	m.LastUsedStep = step
+	r.db[userID] = m
+	r.recoveryCodes[userID] = map[string]bool{}
+	for _, hash := range codeHashes {
+		r.recoveryCodes[userID][hash] = false
+	}
+	return nil
+}
+
+func (r MFARepoFake) UseStep(userID int, step int64) (bool, error) {
+	m := r.db[userID]
+	if m.ConfirmedAt.IsZero() || m.LastUsedStep >= step {
+		return false, nil
+	}
+	m.LastUsedStep = step
+	r.db[userID] = m
+	return true, nil
+}
+
+func (r MFARepoFake) UseRecoveryCode(userID int, codeHash string, usedAt time.Time) (bool, error) {
+	used, ok := r.recoveryCodes[userID][codeHash]
+	if !ok || used {
+		return false, nil
+	}
+	r.recoveryCodes[userID][codeHash] = true
+	return true, nil
+}
+
+// currentTOTPCode returns code an authenticator app would show for the secret, shifted by given number of steps.
+func currentTOTPCode(t *testing.T, secret string, steps int64) string {
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("error was not expected while decoding secret: %s", err)
+	}
+	return totpCode(key, totpStep(time.Now())+steps)
+}
+
+// enrollMFA enables two-factor authentication for the user and returns the secret and recovery codes.
+func enrollMFA(t *testing.T, svc MFAServiceImpl, userID int) (string, []string) {
+	enrollment, err := svc.Enroll(userID)
+	if err != nil {
+		t.Fatalf("error was not expected while enrolling TOTP secret: %s", err)
+	}
+	codes, err := svc.Confirm(userID, currentTOTPCode(t, enrollment.Secret, -1))
+	if err != nil {
+		t.Fatalf("error was not expected while confirming TOTP secret: %s", err)
+	}
+	return enrollment.Secret, codes
+}
+
+func TestMFAEnrollAndConfirm(t *testing.T) {
+	svc := NewMFAService(newMFARepoFake())
+
+	if _, err := svc.Confirm(1, "123456"); err != ErrMFANotEnrolled {
+		t.Errorf("error got: %v; want: %v", err, ErrMFANotEnrolled)
+	}
+
+	enrollment, err := svc.Enroll(1)
+	if err != nil {
+		t.Fatalf("error was not expected while enrolling TOTP secret: %s", err)
+	}
+	if !strings.HasPrefix(enrollment.URI, "otpauth://totp/walletApi:ala11?") || !strings.Contains(enrollment.URI, "secret="+enrollment.Secret) {
+		t.Errorf("provisioning URI got: %s; want URI with account ala11 and secret %s", enrollment.URI, enrollment.Secret)
+	}
+	if enabled, _ := svc.IsEnabled(1); enabled {
+		t.Errorf("two-factor authentication enabled before confirmation")
+	}
+
+	if _, err = svc.Confirm(1, "abcdef"); err != ErrInvalidMFACode {
+		t.Errorf("error got: %v; want: %v", err, ErrInvalidMFACode)
+	}
+	codes, err := svc.Confirm(1, currentTOTPCode(t, enrollment.Secret, 0))
+	if err != nil {
+		t.Fatalf("error was not expected while confirming TOTP secret: %s", err)
+	}
+	if len(codes) != recoveryCodesCount {
+		t.Errorf("number of recovery codes got: %d; want: %d", len(codes), recoveryCodesCount)
+	}
+	if enabled, err := svc.IsEnabled(1); err != nil || !enabled {
+		t.Errorf("two-factor authentication enabled got: %t (%v); want: true", enabled, err)
+	}
+
+	if _, err = svc.Enroll(1); err != ErrMFAAlreadyEnabled {
+		t.Errorf("error got: %v; want: %v", err, ErrMFAAlreadyEnabled)
+	}
+}
+
+func TestMFAVerify(t *testing.T) {
+	repo := newMFARepoFake()
+	svc := NewMFAService(repo)
+	secret, codes := enrollMFA(t, svc, 1)
+
+	if err := svc.Verify(2, currentTOTPCode(t, secret, 0)); err != ErrInvalidMFACode {
+		t.Errorf("error for user without MFA got: %v; want: %v", err, ErrInvalidMFACode)
+	}
+
+	code := currentTOTPCode(t, secret, 0)
+	if err := svc.Verify(1, code); err != nil {
+		t.Errorf("error was not expected while verifying TOTP code: %s", err)
+	}
+	// the same code cannot be replayed, nor the one of the previous step
+	if err := svc.Verify(1, code); err != ErrInvalidMFACode {
+		t.Errorf("error for reused code got: %v; want: %v", err, ErrInvalidMFACode)
+	}
+	if err := svc.Verify(1, currentTOTPCode(t, secret, -1)); err != ErrInvalidMFACode {
+		t.Errorf("error for code of previous step got: %v; want: %v", err, ErrInvalidMFACode)
+	}
+
+	// recovery code is accepted in lowercase and without dashes, but only once
+	recoveryCode := strings.ToLower(strings.ReplaceAll(codes[0], "-", ""))
+	if err := svc.Verify(1, recoveryCode); err != nil {
+		t.Errorf("error was not expected while verifying recovery code: %s", err)
+	}
+	if err := svc.Verify(1, codes[0]); err != ErrInvalidMFACode {
+		t.Errorf("error for used recovery code got: %v; want: %v", err, ErrInvalidMFACode)
+	}
+	if err := svc.Verify(1, codes[1]); err != nil {
+		t.Errorf("error was not expected while verifying recovery code: %s", err)
+	}
+	for hash := range repo.recoveryCodes[1] {
+		for _, c := range codes {
+			if hash == c {
+				t.Errorf("recovery code %s stored in plain text", c)
+			}
+		}
+	}
+}
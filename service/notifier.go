@@ -0,0 +1,41 @@
+package service
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+
+	"zuzanna.com/walletapi/model"
+)
+
+// Notifier delivers notifications to users, e.g. by e-mail or SMS.
+type Notifier interface {
+	Notify(n model.Notification) error
+}
+
+// FileNotifier appends notifications as JSON lines to a file. It is meant for development, when there is no real
+// delivery channel, so notifications can be read from the file.
+type FileNotifier struct {
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+func NewFileNotifier(path string) (FileNotifier, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return FileNotifier{}, err
+	}
+	return FileNotifier{mu: &sync.Mutex{}, w: f}, nil
+}
+
+func (n FileNotifier) Notify(notification model.Notification) error {
+	b, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	_, err = n.w.Write(append(b, '\n'))
+	return err
+}
@@ -16,9 +16,47 @@ import (
 
 var ErrNoUSerInContext = errors.New("could not retrieve userID from context")
 
+// sensitiveRequestFields and sensitiveResponseFields list JSON body fields hidden in operational log per route, see logRoute.
+var sensitiveRequestFields = map[string][]string{
+	"/v1/users":                {"password"},
+	"/api/v1/mfa/totp/confirm": {"code"},
+	"/api/v1/me/password":      {"currentPassword", "newPassword"},
+	"/password/reset/confirm":  {"token", "newPassword"},
+}
+
+var sensitiveResponseFields = map[string][]string{
+	"/login":                           {"token", "refreshToken", "mfaToken"},
+	"/login/mfa":                       {"token", "refreshToken"},
+	"/token/refresh":                   {"token", "refreshToken"},
+	"/reauth":                          {"token"},
+	"/api/v1/mfa/totp":                 {"secret", "uri"},
+	"/api/v1/mfa/totp/confirm":         {"recoveryCodes"},
+	"/api/v1/api-keys":                 {"key"},
+	"/api/admin/v1/users/:id/api-keys": {"key"},
+}
+
+// sensitiveFormFields are hidden in operational log of all requests.
+var sensitiveFormFields = map[string]bool{
+	"password":      true,
+	"refresh_token": true,
+	"mfa_token":     true,
+	"code":          true,
+}
+
+// unauthenticatedURIs are requested without JWT token, so there is no user ID to log.
+var unauthenticatedURIs = map[string]bool{
+	"/login":                  true,
+	"/login/mfa":              true,
+	"/token/refresh":          true,
+	"/password/reset":         true,
+	"/password/reset/confirm": true,
+}
+
 type OperationalLogService interface {
 	CreateLog(c echo.Context, reqBody, resBody []byte)
 	LogSkipper(c echo.Context) bool
+	// CreateEventLog logs event with its details, e.g. access to admin endpoint, which is not described by plain request log.
+	CreateEventLog(level model.LogLevel, event string, userID int, details interface{})
 }
 
 type JSONOperationalLogService struct {
@@ -49,6 +87,26 @@ func (svc JSONOperationalLogService) CreateLog(c echo.Context, reqBody, resBody
 	fmt.Println(svc.ToJSON(createLog(c, reqBody, resBody)))
 }
 
+func (svc JSONOperationalLogService) CreateEventLog(level model.LogLevel, event string, userID int, details interface{}) {
+	fmt.Println(svc.ToJSON(createEventLog(level, event, userID, details)))
+}
+
+func createEventLog(level model.LogLevel, event string, userID int, details interface{}) model.OperationalLog {
+	opLog := initOperationalLog()
+	opLog.Protocol = ""
+	opLog.Level = level
+	opLog.Event = event
+	opLog.UserID = userID
+	b, err := json.Marshal(details)
+	if err != nil {
+		log.Errorf("could not marshal details (%+v) of %s event to JSON, err: %s", details, event, err)
+		opLog.Err = "error while marshal details to JSON"
+		return opLog
+	}
+	opLog.Details = b
+	return opLog
+}
+
 func createLog(c echo.Context, reqBody, resBody []byte) model.OperationalLog {
 	opLog := initOperationalLog()
 	req := c.Request()
@@ -58,17 +116,23 @@ func createLog(c echo.Context, reqBody, resBody []byte) model.OperationalLog {
 	opLog.Method = req.Method
 	if req.Header.Get("content-type") == echo.MIMEApplicationForm {
 		opLog.Request = model.Request{Form: byteFormToMap(req)}
+	} else if fields, ok := sensitiveRequestFields[logRoute(c)]; ok {
+		body, err := hideSensitiveData(reqBody, fields...)
+		opLog.Request = model.Request{Body: body}
+		opLog.Err = wrapErr(opLog.Err, err)
 	} else {
 		body, err := createRawMessageWithCheck(reqBody)
 		opLog.Request = model.Request{Body: body}
 		opLog.Err = wrapErr(opLog.Err, err)
 	}
-	if req.RequestURI == "/login" {
-		body, err := hideSensitiveData(resBody)
+	if fields, ok := sensitiveResponseFields[logRoute(c)]; ok {
+		body, err := hideSensitiveData(resBody, fields...)
 		opLog.Response = model.Response{Body: body, Code: resp.Status}
 		opLog.Err = wrapErr(opLog.Err, err)
 	} else {
 		opLog.Response = model.Response{Body: createRawMessage(resBody), Code: resp.Status}
+	}
+	if !unauthenticatedURIs[logRoute(c)] {
 		id, err := getUserIDFromToken(c)
 		opLog.UserID = id
 		opLog.Err = wrapErr(opLog.Err, err)
@@ -76,6 +140,15 @@ func createLog(c echo.Context, reqBody, resBody []byte) model.OperationalLog {
 	return opLog
 }
 
+// logRoute returns route of the request with path parameters like :id, so sensitive fields are found for any ID
+// and regardless of query string. Request path is returned when the request was not routed.
+func logRoute(c echo.Context) string {
+	if c.Path() != "" {
+		return c.Path()
+	}
+	return c.Request().URL.Path
+}
+
 func wrapErr(initial string, wrapped error) string {
 	if wrapped == nil {
 		return initial
@@ -91,6 +164,9 @@ func initOperationalLog() model.OperationalLog {
 }
 
 func getUserIDFromToken(c echo.Context) (int, error) {
+	if apiKey, ok := getAPIKey(c); ok {
+		return apiKey.UserID, nil
+	}
 	contextUser := c.Get("user")
 	if contextUser == nil {
 		log.Infof("could not retrieve user from context for operational log")
@@ -130,8 +206,7 @@ func byteFormToMap(req *http.Request) map[string]interface{} {
 	req.ParseForm()
 	values := req.Form
 	for k, v := range values {
-		// hide sensitive data
-		if k == "password" {
+		if sensitiveFormFields[k] {
 			m[k] = "***"
 			continue
 		}
@@ -140,7 +215,8 @@ func byteFormToMap(req *http.Request) map[string]interface{} {
 	return m
 }
 
-func hideSensitiveData(body []byte) ([]byte, error) {
+// hideSensitiveData replaces values of given fields of JSON body with "***".
+func hideSensitiveData(body []byte, fields ...string) ([]byte, error) {
 	if len(body) == 0 {
 		return nil, nil
 	}
@@ -151,8 +227,10 @@ func hideSensitiveData(body []byte) ([]byte, error) {
 		return nil, errors.New("error while unmarshal body to map")
 	}
 	// hide sensitive data
-	if _, ok := m["token"]; ok {
-		m["token"] = "***"
+	for _, f := range fields {
+		if _, ok := m[f]; ok {
+			m[f] = "***"
+		}
 	}
 	b, err := json.Marshal(m)
 	if err != nil {
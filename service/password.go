@@ -0,0 +1,89 @@
+package service
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"unicode"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ErrUnknownPasswordHash = errors.New("unknown password hash algorithm")
+var ErrWeakPassword = errors.New("password must have from 8 to 72 characters and contain at least one lowercase letter, one uppercase letter and one digit")
+
+// minPasswordLength and maxPasswordLength define password policy, bcrypt uses only first 72 bytes of password.
+const minPasswordLength = 8
+const maxPasswordLength = 72
+
+// bcryptPrefix marks password hashed with bcrypt. Every stored hash starts with algorithm prefix, so the algorithm can be changed later
+// without breaking existing passwords. Passwords without known prefix are legacy base64 encoded plain text.
+const bcryptPrefix = "bcrypt$"
+
+// passwordHashCost is bcrypt cost used for new hashes, passwords hashed with lower cost are re-hashed on successful login.
+var passwordHashCost = bcrypt.DefaultCost
+
+// dummyPasswordHash is compared when login is not found, so response time does not reveal which logins exist.
+var dummyPasswordHash, _ = HashPassword("dummy password")
+
+// HashPassword hashes password with bcrypt, salt is generated for every password.
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), passwordHashCost)
+	if err != nil {
+		return "", err
+	}
+	return bcryptPrefix + string(hash), nil
+}
+
+// VerifyPassword compares password with the stored hash in constant time.
+// Second value is true when the hash should be replaced with a new one, e.g. it uses legacy scheme.
+func VerifyPassword(storedHash, password string) (bool, bool, error) {
+	if strings.HasPrefix(storedHash, bcryptPrefix) {
+		hash := []byte(strings.TrimPrefix(storedHash, bcryptPrefix))
+		err := bcrypt.CompareHashAndPassword(hash, []byte(password))
+		if err == bcrypt.ErrMismatchedHashAndPassword {
+			return false, false, nil
+		}
+		if err != nil {
+			return false, false, err
+		}
+		cost, err := bcrypt.Cost(hash)
+		return true, err == nil && cost < passwordHashCost, nil
+	}
+	if strings.Contains(storedHash, "$") {
+		return false, false, ErrUnknownPasswordHash
+	}
+
+	// legacy: base64 encoded plain text password
+	decoded, err := base64.StdEncoding.DecodeString(storedHash)
+	if err != nil {
+		return false, false, err
+	}
+	if subtle.ConstantTimeCompare(decoded, []byte(password)) != 1 {
+		return false, false, nil
+	}
+	return true, true, nil
+}
+
+// CheckPasswordPolicy checks if password is strong enough to be set.
+func CheckPasswordPolicy(password string) error {
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return ErrWeakPassword
+	}
+	var lower, upper, digit bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		}
+	}
+	if !lower || !upper || !digit {
+		return ErrWeakPassword
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHashPassword(t *testing.T) {
+	hash, err := HashPassword("1qazXSW@")
+	if err != nil {
+		t.Fatalf("error was not expected while hashing password: %s", err)
+	}
+	if !strings.HasPrefix(hash, bcryptPrefix) {
+		t.Errorf("hash got: %s; want prefix: %s", hash, bcryptPrefix)
+	}
+	other, _ := HashPassword("1qazXSW@")
+	if hash == other {
+		t.Errorf("hashes of the same password must differ (salt), got: %s twice", hash)
+	}
+}
+
+func TestVerifyPassword(t *testing.T) {
+	hash, _ := HashPassword("haslo")
+	cases := []struct {
+		storedHash string
+		password   string
+		ok         bool
+		rehash     bool
+		err        error
+	}{
+		{storedHash: hash, password: "haslo", ok: true, rehash: false},
+		{storedHash: hash, password: "Haslo", ok: false, rehash: false},
+		// cost lower than current one
+		{storedHash: "bcrypt$$2a$04$07etRhWMrDoyJWUR/Yb03O5HPG7CgvZ8LXEwSz7WAJ5ssFS3buugm", password: "haslo", ok: true, rehash: true},
+		// legacy base64 password
+		{storedHash: "aGFzbG8=", password: "haslo", ok: true, rehash: true},
+		{storedHash: "aGFzbG8=", password: "hasl", ok: false, rehash: false},
+		{storedHash: "md5$5f4dcc3b5aa765d61d8327deb882cf99", password: "haslo", ok: false, rehash: false, err: ErrUnknownPasswordHash},
+	}
+
+	for _, c := range cases {
+		ok, rehash, err := VerifyPassword(c.storedHash, c.password)
+		if err != c.err {
+			t.Errorf("VerifyPassword(%q, %q) error got: %v; want: %v", c.storedHash, c.password, err, c.err)
+		}
+		if ok != c.ok || rehash != c.rehash {
+			t.Errorf("VerifyPassword(%q, %q) got: %t, %t; want: %t, %t", c.storedHash, c.password, ok, rehash, c.ok, c.rehash)
+		}
+	}
+}
+
+func TestCheckPasswordPolicy(t *testing.T) {
+	cases := map[string]error{
+		"1qazXSW@":                nil,
+		"Haslo123":                nil,
+		"Hasl123":                 ErrWeakPassword,
+		"haslo123":                ErrWeakPassword,
+		"HASLO123":                ErrWeakPassword,
+		"HasloHaslo":              ErrWeakPassword,
+		strings.Repeat("aA1", 25): ErrWeakPassword,
+		strings.Repeat("aA1", 24): nil,
+	}
+	for password, want := range cases {
+		if got := CheckPasswordPolicy(password); got != want {
+			t.Errorf("CheckPasswordPolicy(%q) got: %v; want: %v", password, got, want)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/labstack/gommon/log"
+	"zuzanna.com/walletapi/model"
+	"zuzanna.com/walletapi/repository"
+)
+
+var ErrWrongPassword = errors.New("current password does not match")
+var ErrInvalidResetToken = errors.New("invalid, expired or used password reset token")
+
+// DefaultPasswordResetTTL is how long password reset token can be used when not configured otherwise.
+const DefaultPasswordResetTTL = 30 * time.Minute
+
+type PasswordService interface {
+	// Change replaces password of the user when the current password matches. All sessions and API keys of the user are revoked.
+	Change(userID int, currentPassword, newPassword string) error
+	// RequestReset sends single-use reset token to the user. It does not fail when login does not exist,
+	// so the response does not reveal which logins exist.
+	RequestReset(login string) error
+	// Reset replaces password of the user the token was sent to. All sessions and API keys of the user are revoked.
+	Reset(token, newPassword string) error
+}
+
+type PasswordServiceImpl struct {
+	credentials repository.CredentialsRepo
+	resets      repository.PasswordResetRepo
+	tokens      repository.TokenRepo
+	apiKeys     repository.APIKeyRepo
+	notifier    Notifier
+	resetTTL    time.Duration
+}
+
+func NewPasswordService(credentials repository.CredentialsRepo, resets repository.PasswordResetRepo, tokens repository.TokenRepo,
+	apiKeys repository.APIKeyRepo, notifier Notifier, resetTTL time.Duration) PasswordServiceImpl {
+	if credentials == nil || resets == nil || tokens == nil || apiKeys == nil {
+		panic("repo cannot be nil!")
+	}
+	if notifier == nil {
+		panic("notifier cannot be nil!")
+	}
+	if resetTTL <= 0 {
+		resetTTL = DefaultPasswordResetTTL
+	}
+	return PasswordServiceImpl{credentials: credentials, resets: resets, tokens: tokens, apiKeys: apiKeys, notifier: notifier, resetTTL: resetTTL}
+}
+
+func (svc PasswordServiceImpl) Change(userID int, currentPassword, newPassword string) error {
+	credentials, err := svc.credentials.GetByUserID(userID)
+	if err != nil {
+		log.Errorf("#Change(...) error while retrieving credentials of user with ID %d; error: %v", userID, err)
+		return err
+	}
+	ok, _, err := VerifyPassword(credentials.Password, currentPassword)
+	if err != nil {
+		log.Errorf("#Change(...) error while verifying password of user with ID %d; error: %v", userID, err)
+		return err
+	}
+	if !ok {
+		return ErrWrongPassword
+	}
+	if err = CheckPasswordPolicy(newPassword); err != nil {
+		return err
+	}
+	if err = svc.setPassword(credentials, newPassword); err != nil {
+		if err == repository.ErrRecordNotFound {
+			// password was changed in the meantime
+			return ErrWrongPassword
+		}
+		return err
+	}
+	return nil
+}
+
+func (svc PasswordServiceImpl) RequestReset(login string) error {
+	credentials, err := svc.credentials.Get(login)
+	if err != nil {
+		if err == repository.ErrRecordNotFound {
+			log.Infof("password reset requested for unknown login %s", login)
+			return nil
+		}
+		log.Errorf("#RequestReset(...) error while retrieving credentials for login %s; error: %v", login, err)
+		return err
+	}
+
+	token, hash, err := newSecretToken()
+	if err != nil {
+		log.Errorf("#RequestReset(...) error while generating password reset token; error: %v", err)
+		return err
+	}
+	now := time.Now()
+	reset := model.PasswordResetDB{TokenHash: hash, UserID: credentials.UserID, ExpiresAt: now.Add(svc.resetTTL), CreatedAt: now}
+	if err = svc.resets.Create(reset); err != nil {
+		return err
+	}
+
+	err = svc.notifier.Notify(model.Notification{
+		UserID:  credentials.UserID,
+		Login:   credentials.Login,
+		Subject: "Password reset",
+		Message: fmt.Sprintf("Use token %s to set new password. The token is valid until %s.", token, reset.ExpiresAt.Format(time.RFC3339)),
+		Time:    now,
+	})
+	if err != nil {
+		log.Errorf("#RequestReset(...) error while sending password reset token to user with ID %d; error: %v", credentials.UserID, err)
+		return err
+	}
+	return nil
+}
+
+func (svc PasswordServiceImpl) Reset(token, newPassword string) error {
+	// the policy is checked first, so the token is not used up by a request with weak password
+	if err := CheckPasswordPolicy(newPassword); err != nil {
+		return err
+	}
+	userID, err := svc.resets.Use(hashSecret(token), time.Now())
+	if err != nil {
+		if err == repository.ErrRecordNotFound {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+	credentials, err := svc.credentials.GetByUserID(userID)
+	if err != nil {
+		log.Errorf("#Reset(...) error while retrieving credentials of user with ID %d; error: %v", userID, err)
+		return err
+	}
+	if err = svc.setPassword(credentials, newPassword); err != nil {
+		if err == repository.ErrRecordNotFound {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+	return nil
+}
+
+// setPassword replaces password hash and ends all sessions of the user, including the ones which could be opened
+// by someone who knew the previous password, revokes API keys which could be created in them and invalidates
+// the other reset tokens.
+func (svc PasswordServiceImpl) setPassword(credentials model.Credentials, password string) error {
+	hash, err := HashPassword(password)
+	if err != nil {
+		log.Errorf("#setPassword(...) error while hashing password of user with ID %d; error: %v", credentials.UserID, err)
+		return err
+	}
+	if err = svc.credentials.UpdatePassword(credentials.Login, credentials.Password, hash); err != nil {
+		if err != repository.ErrRecordNotFound {
+			log.Errorf("#setPassword(...) error while updating password of user with ID %d; error: %v", credentials.UserID, err)
+		}
+		return err
+	}
+	// access tokens keep issue time with precision of seconds, tokens issued in the same second are not revoked
+	now := time.Now().Truncate(time.Second)
+	if err = svc.tokens.RevokeUserTokens(credentials.UserID, now); err != nil {
+		log.Errorf("#setPassword(...) error while revoking sessions of user with ID %d; error: %v", credentials.UserID, err)
+		return err
+	}
+	if err = svc.apiKeys.RevokeUserKeys(credentials.UserID, now); err != nil {
+		log.Errorf("#setPassword(...) error while revoking API keys of user with ID %d; error: %v", credentials.UserID, err)
+		return err
+	}
+	if err = svc.resets.InvalidateAll(credentials.UserID, now); err != nil {
+		log.Errorf("#setPassword(...) error while invalidating password reset tokens of user with ID %d; error: %v", credentials.UserID, err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"zuzanna.com/walletapi/model"
+	"zuzanna.com/walletapi/repository"
+)
+
+type PasswordResetRepoFake struct {
+	db map[string]model.PasswordResetDB
+}
+
+func newPasswordResetRepoFake() PasswordResetRepoFake {
+	return PasswordResetRepoFake{db: map[string]model.PasswordResetDB{}}
+}
+
+func (r PasswordResetRepoFake) Create(reset model.PasswordResetDB) error {
+	r.db[reset.TokenHash] = reset
+	return nil
+}
+
+func (r PasswordResetRepoFake) Use(hash string, usedAt time.Time) (int, error) {
+	reset, ok := r.db[hash]
+	if !ok || !reset.UsedAt.IsZero() || !reset.ExpiresAt.After(usedAt) {
+		return 0, repository.ErrRecordNotFound
+	}
+	reset.UsedAt = usedAt
+	r.db[hash] = reset
+	return reset.UserID, nil
+}
+
+func (r PasswordResetRepoFake) InvalidateAll(userID int, usedAt time.Time) error {
+	for hash, reset := range r.db {
+		if reset.UserID == userID && reset.UsedAt.IsZero() {
+			reset.UsedAt = usedAt
+			r.db[hash] = reset
+		}
+	}
+	return nil
+}
+
+type NotifierFake struct {
+	sent *[]model.Notification
+}
+
+func (n NotifierFake) Notify(notification model.Notification) error {
+	*n.sent = append(*n.sent, notification)
+	return nil
+}
+
+// resetTokenFromNotification returns token from message of the last notification.
+func resetTokenFromNotification(t *testing.T, sent []model.Notification) string {
+	if len(sent) == 0 {
+		t.Fatalf("password reset token was not sent")
+	}
+	fields := strings.Fields(sent[len(sent)-1].Message)
+	if len(fields) < 3 {
+		t.Fatalf("unexpected notification message: %s", sent[len(sent)-1].Message)
+	}
+	return fields[2]
+}
+
+func TestChangePassword(t *testing.T) {
+	credentialsRepo := newCredentialsRepoFake()
+	tokenRepo := newTokenRepoFake()
+	authSvc := AuthServiceImpl{CredentialsRepo: credentialsRepo, TokenRepo: tokenRepo, Keys: testJWTKeys}
+	apiKeyRepo := newAPIKeyRepoFake()
+	svc := NewPasswordService(credentialsRepo, newPasswordResetRepoFake(), tokenRepo, apiKeyRepo, NotifierFake{sent: &[]model.Notification{}}, 0)
+
+	tokens, err := authSvc.Authenticate("ala11", "haslo")
+	if err != nil {
+		t.Fatalf("error was not expected while authenticating: %s", err)
+	}
+	apiKeyRepo.Create(model.APIKeyDB{KeyHash: "hash1", UserID: 1, ExpiresAt: time.Now().Add(time.Hour)})
+	apiKeyRepo.Create(model.APIKeyDB{KeyHash: "hash2", UserID: 2, ExpiresAt: time.Now().Add(time.Hour)})
+
+	if err = svc.Change(1, "wrong", "Secret123"); err != ErrWrongPassword {
+		t.Errorf("error for wrong current password got: %v; want: %v", err, ErrWrongPassword)
+	}
+	if err = svc.Change(1, "haslo", "secret"); err != ErrWeakPassword {
+		t.Errorf("error for weak password got: %v; want: %v", err, ErrWeakPassword)
+	}
+	if err = svc.Change(1, "haslo", "Secret123"); err != nil {
+		t.Fatalf("error was not expected while changing password: %s", err)
+	}
+
+	if _, err = authSvc.Authenticate("ala11", "haslo"); err != ErrUnauthorized {
+		t.Errorf("error for old password got: %v; want: %v", err, ErrUnauthorized)
+	}
+	if _, err = authSvc.Authenticate("ala11", "Secret123"); err != nil {
+		t.Errorf("error was not expected while authenticating with new password: %s", err)
+	}
+	if _, err = authSvc.Refresh(tokens.RefreshToken); err != ErrInvalidRefreshToken {
+		t.Errorf("error for refresh token issued before password change got: %v; want: %v", err, ErrInvalidRefreshToken)
+	}
+	if revoked, _ := tokenRepo.IsAccessTokenRevoked("", 1, time.Now().Add(-time.Minute)); !revoked {
+		t.Errorf("access token issued before password change is not revoked")
+	}
+	if revoked, _ := tokenRepo.IsAccessTokenRevoked("", 2, time.Now().Add(-time.Minute)); revoked {
+		t.Errorf("access token of other user is revoked")
+	}
+	if apiKeyRepo.db["hash1"].RevokedAt.IsZero() {
+		t.Errorf("API key created before password change is not revoked")
+	}
+	if !apiKeyRepo.db["hash2"].RevokedAt.IsZero() {
+		t.Errorf("API key of other user is revoked")
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	credentialsRepo := newCredentialsRepoFake()
+	tokenRepo := newTokenRepoFake()
+	sent := []model.Notification{}
+	svc := NewPasswordService(credentialsRepo, newPasswordResetRepoFake(), tokenRepo, newAPIKeyRepoFake(), NotifierFake{sent: &sent}, time.Hour)
+
+	if err := svc.RequestReset("nobody"); err != nil {
+		t.Errorf("error was not expected for unknown login: %s", err)
+	}
+	if len(sent) != 0 {
+		t.Errorf("notification sent for unknown login: %+v", sent)
+	}
+
+	if err := svc.RequestReset("ola22"); err != nil {
+		t.Fatalf("error was not expected while requesting password reset: %s", err)
+	}
+	first := resetTokenFromNotification(t, sent)
+	if err := svc.RequestReset("ola22"); err != nil {
+		t.Fatalf("error was not expected while requesting password reset: %s", err)
+	}
+	second := resetTokenFromNotification(t, sent)
+	if sent[1].UserID != 2 || sent[1].Login != "ola22" {
+		t.Errorf("notification got: %+v; want notification for user 2", sent[1])
+	}
+
+	if err := svc.Reset("invalid", "Secret123"); err != ErrInvalidResetToken {
+		t.Errorf("error for invalid token got: %v; want: %v", err, ErrInvalidResetToken)
+	}
+	// weak password does not use up the token
+	if err := svc.Reset(second, "secret"); err != ErrWeakPassword {
+		t.Errorf("error for weak password got: %v; want: %v", err, ErrWeakPassword)
+	}
+	if err := svc.Reset(second, "Secret123"); err != nil {
+		t.Fatalf("error was not expected while resetting password: %s", err)
+	}
+	if err := svc.Reset(second, "Secret456"); err != ErrInvalidResetToken {
+		t.Errorf("error for used token got: %v; want: %v", err, ErrInvalidResetToken)
+	}
+	if err := svc.Reset(first, "Secret456"); err != ErrInvalidResetToken {
+		t.Errorf("error for token requested before reset got: %v; want: %v", err, ErrInvalidResetToken)
+	}
+
+	cred, _ := credentialsRepo.Get("ola22")
+	if ok, _, _ := VerifyPassword(cred.Password, "Secret123"); !ok {
+		t.Errorf("password was not reset")
+	}
+	if _, ok := tokenRepo.revokedUsers[2]; !ok {
+		t.Errorf("sessions were not revoked after password reset")
+	}
+}
+
+func TestResetPasswordExpiredToken(t *testing.T) {
+	resetRepo := newPasswordResetRepoFake()
+	svc := NewPasswordService(newCredentialsRepoFake(), resetRepo, newTokenRepoFake(), newAPIKeyRepoFake(), NotifierFake{sent: &[]model.Notification{}}, 0)
+
+	resetRepo.Create(model.PasswordResetDB{TokenHash: hashSecret("expired"), UserID: 1, ExpiresAt: time.Now().Add(-time.Second)})
+	if err := svc.Reset("expired", "Secret123"); err != ErrInvalidResetToken {
+		t.Errorf("error for expired token got: %v; want: %v", err, ErrInvalidResetToken)
+	}
+}
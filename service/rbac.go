@@ -0,0 +1,71 @@
+package service
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"zuzanna.com/walletapi/model"
+)
+
+// AdminAccessEvent is the operational log event written for every request to admin endpoints, including denied ones.
+const AdminAccessEvent = "ADMIN_ACCESS"
+
+type adminAccessDetails struct {
+	Role         model.Role `json:"role"`
+	Method       string     `json:"method"`
+	Path         string     `json:"path"`
+	TargetUserID int        `json:"targetUserId,omitempty"`
+	Code         int        `json:"code"`
+}
+
+// RequireRole allows request only when role from JWT token includes required role. It must be used after JWT middleware.
+func RequireRole(required model.Role) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			claims, err := getClaims(c)
+			if err != nil {
+				return c.JSON(http.StatusUnauthorized, model.NewErrResponse(http.StatusUnauthorized, "Invalid JWT token."))
+			}
+			if !claims.GetRole().Includes(required) {
+				log.Warnf("user with ID %d and role %s denied access to %s %s", claims.UserID, claims.GetRole(), c.Request().Method, c.Request().RequestURI)
+				return c.JSON(http.StatusForbidden, model.NewErrResponse(http.StatusForbidden, "Insufficient role."))
+			}
+			return next(c)
+		}
+	}
+}
+
+// AuditAdminAccess writes every request to the operational log as AdminAccessEvent. It must be used after JWT middleware
+// and before RequireRole, so denied requests are logged as well.
+func AuditAdminAccess(opLog OperationalLogService) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			err := next(c)
+			if err != nil {
+				c.Error(err)
+			}
+
+			details := adminAccessDetails{
+				Method: c.Request().Method,
+				Path:   c.Request().RequestURI,
+				Code:   c.Response().Status,
+			}
+			userID := 0
+			if claims, claimsErr := getClaims(c); claimsErr == nil {
+				userID = claims.UserID
+				details.Role = claims.GetRole()
+			}
+			if id, convErr := strconv.Atoi(c.Param("id")); convErr == nil {
+				details.TargetUserID = id
+			}
+			level := model.Info
+			if details.Code >= http.StatusBadRequest {
+				level = model.Warn
+			}
+			opLog.CreateEventLog(level, AdminAccessEvent, userID, details)
+			return nil
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/labstack/echo/v4"
+	"zuzanna.com/walletapi/model"
+)
+
+func TestAdminAccess(t *testing.T) {
+	cases := []struct {
+		role         model.Role
+		expectedCode int
+		expectedLvl  model.LogLevel
+	}{
+		{role: model.RoleSupport, expectedCode: http.StatusOK, expectedLvl: model.Info},
+		{role: model.RoleAdmin, expectedCode: http.StatusOK, expectedLvl: model.Info},
+		{role: model.RoleUser, expectedCode: http.StatusForbidden, expectedLvl: model.Warn},
+		// token issued before roles were introduced
+		{role: "", expectedCode: http.StatusForbidden, expectedLvl: model.Warn},
+	}
+
+	for _, testCase := range cases {
+		events := []eventLog{}
+		e := echo.New()
+		admin := e.Group("/api/admin", func(next echo.HandlerFunc) echo.HandlerFunc {
+			return func(c echo.Context) error {
+				c.Set("user", &jwt.Token{Claims: &JwtCustomClaims{UserID: 3, Role: testCase.role}})
+				return next(c)
+			}
+		}, AuditAdminAccess(OperationalLogServiceFake{events: &events}), RequireRole(model.RoleSupport))
+		admin.GET("/v1/users/:id/balances", func(c echo.Context) error {
+			return c.NoContent(http.StatusOK)
+		})
+
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/admin/v1/users/7/balances", nil))
+		if rec.Code != testCase.expectedCode {
+			t.Errorf("http code for role %q got: %d; want: %d", testCase.role, rec.Code, testCase.expectedCode)
+		}
+		if len(events) != 1 {
+			t.Fatalf("number of operational log events got: %d; want: 1", len(events))
+		}
+		want := eventLog{
+			level:  testCase.expectedLvl,
+			event:  AdminAccessEvent,
+			userID: 3,
+			details: adminAccessDetails{
+				Role:         (&JwtCustomClaims{Role: testCase.role}).GetRole(),
+				Method:       http.MethodGet,
+				Path:         "/api/admin/v1/users/7/balances",
+				TargetUserID: 7,
+				Code:         testCase.expectedCode,
+			},
+		}
+		if events[0] != want {
+			t.Errorf("operational log event got: %+v; want: %+v", events[0], want)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"errors"
+	"strings"
+
+	"zuzanna.com/walletapi/model"
+	"zuzanna.com/walletapi/repository"
+)
+
+var ErrRecipientNotFound = errors.New("no user with such login, e-mail or phone alias")
+var ErrRecipientNoBalance = errors.New("recipient has no balance in the transfer currency")
+var ErrSameBalance = errors.New("sender and receiver balances cannot be the same")
+
+// RecipientService finds receivers of transfers given by login, e-mail or phone alias instead of balance ID.
+type RecipientService interface {
+	// Lookup returns user with the login or alias. When currency is set the user must have balance in this currency.
+	Lookup(receiver string, currency model.Currency) (model.User, error)
+	// ResolveBalance returns ID of the receiver's balance in currency of the sender balance of the user.
+	ResolveBalance(userID, senderBalanceID int, receiver string) (int, error)
+}
+
+type RecipientServiceImpl struct {
+	users    repository.UserRepo
+	balances repository.BalanceRepo
+}
+
+func NewRecipientService(users repository.UserRepo, balances repository.BalanceRepo) RecipientServiceImpl {
+	if users == nil || balances == nil {
+		panic("repo cannot be nil!")
+	}
+	return RecipientServiceImpl{users: users, balances: balances}
+}
+
+func (svc RecipientServiceImpl) Lookup(receiver string, currency model.Currency) (model.User, error) {
+	userID, err := svc.getUserID(receiver)
+	if err != nil {
+		return model.User{}, err
+	}
+	if currency != "" {
+		if _, err = svc.getBalanceID(userID, currency); err != nil {
+			return model.User{}, err
+		}
+	}
+	user, err := svc.users.Get(userID)
+	if err != nil {
+		if err == repository.ErrRecordNotFound {
+			return model.User{}, ErrRecipientNotFound
+		}
+		return model.User{}, err
+	}
+	return model.User(user), nil
+}
+
+func (svc RecipientServiceImpl) ResolveBalance(userID, senderBalanceID int, receiver string) (int, error) {
+	balances, err := svc.balances.GetList(userID)
+	if err != nil {
+		return 0, err
+	}
+	var currency model.Currency
+	for _, b := range balances {
+		if b.ID == senderBalanceID {
+			currency = b.Currency
+		}
+	}
+	if currency == "" {
+		return 0, ErrBalanceNotFound
+	}
+
+	receiverID, err := svc.getUserID(receiver)
+	if err != nil {
+		return 0, err
+	}
+	balanceID, err := svc.getBalanceID(receiverID, currency)
+	if err != nil {
+		return 0, err
+	}
+	if balanceID == senderBalanceID {
+		return 0, ErrSameBalance
+	}
+	return balanceID, nil
+}
+
+func (svc RecipientServiceImpl) getUserID(receiver string) (int, error) {
+	userID, err := svc.users.GetIDByAlias(strings.TrimSpace(receiver), model.NormalizeAlias(receiver))
+	if err != nil {
+		if err == repository.ErrRecordNotFound {
+			return 0, ErrRecipientNotFound
+		}
+		return 0, err
+	}
+	return userID, nil
+}
+
+// getBalanceID returns the user's balance in the currency, users have at most one balance per currency.
+func (svc RecipientServiceImpl) getBalanceID(userID int, currency model.Currency) (int, error) {
+	balances, err := svc.balances.GetList(userID)
+	if err != nil {
+		return 0, err
+	}
+	for _, b := range balances {
+		if b.Currency == currency {
+			return b.ID, nil
+		}
+	}
+	return 0, ErrRecipientNoBalance
+}
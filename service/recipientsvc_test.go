@@ -0,0 +1,67 @@
+package service
+
+import (
+	"testing"
+
+	"zuzanna.com/walletapi/model"
+)
+
+func newRecipientService() RecipientServiceImpl {
+	users := newUserRepoFake()
+	users.credentials["ola22"] = model.Credentials{Login: "ola22", UserID: 2}
+	users.users[2] = model.UserDB{ID: 2, FirstName: "Ola", LastName: "Nowak", Age: 30}
+	balances := BalanceRepoFake{db: map[int][]model.BalanceDB{
+		1: {{ID: 1, Currency: model.SGD, UserID: 1}, {ID: 2, Currency: model.USD, UserID: 1}},
+		2: {{ID: 3, Currency: model.SGD, UserID: 2}},
+	}}
+	return NewRecipientService(users, balances)
+}
+
+func TestLookupRecipient(t *testing.T) {
+	svc := newRecipientService()
+
+	cases := []struct {
+		receiver string
+		currency model.Currency
+		userID   int
+		err      error
+	}{
+		{receiver: "ala11", userID: 1},
+		{receiver: " ALA@example.com ", currency: model.USD, userID: 1},
+		{receiver: "+65 9123-4567", userID: 1},
+		{receiver: "ola22", currency: model.USD, err: ErrRecipientNoBalance},
+		{receiver: "ela33", err: ErrRecipientNotFound},
+	}
+	for _, testCase := range cases {
+		user, err := svc.Lookup(testCase.receiver, testCase.currency)
+		if err != testCase.err || user.ID != testCase.userID {
+			t.Errorf("recipient %s in %s got: %+v (%v); want user %d (%v)", testCase.receiver, testCase.currency, user, err, testCase.userID, testCase.err)
+		}
+	}
+}
+
+func TestResolveRecipientBalance(t *testing.T) {
+	svc := newRecipientService()
+
+	cases := []struct {
+		userID          int
+		senderBalanceID int
+		receiver        string
+		balanceID       int
+		err             error
+	}{
+		{userID: 2, senderBalanceID: 3, receiver: "ala@example.com", balanceID: 1},
+		{userID: 1, senderBalanceID: 1, receiver: "ola22", balanceID: 3},
+		{userID: 1, senderBalanceID: 2, receiver: "ola22", err: ErrRecipientNoBalance},
+		// balance of other user
+		{userID: 2, senderBalanceID: 1, receiver: "ala11", err: ErrBalanceNotFound},
+		{userID: 1, senderBalanceID: 1, receiver: "ala11", err: ErrSameBalance},
+		{userID: 1, senderBalanceID: 1, receiver: "nobody", err: ErrRecipientNotFound},
+	}
+	for _, testCase := range cases {
+		balanceID, err := svc.ResolveBalance(testCase.userID, testCase.senderBalanceID, testCase.receiver)
+		if err != testCase.err || balanceID != testCase.balanceID {
+			t.Errorf("balance of %s for transfer from balance %d got: %d (%v); want: %d (%v)", testCase.receiver, testCase.senderBalanceID, balanceID, err, testCase.balanceID, testCase.err)
+		}
+	}
+}
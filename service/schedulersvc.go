@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/labstack/gommon/log"
+	"zuzanna.com/walletapi/model"
+	"zuzanna.com/walletapi/repository"
+)
+
+// scheduleClaimTTL is how long the scheduler instance has to execute claimed transfers, after that they can be claimed again.
+const scheduleClaimTTL = 5 * time.Minute
+
+// scheduleBatchSize limits number of transfers executed by one run of the scheduler.
+const scheduleBatchSize = 100
+
+// ScheduleRetryPolicy retries transfer which can succeed later (see retryable) MaxAttempts times, the first retry is
+// after Backoff, which is doubled with every failed attempt.
+type ScheduleRetryPolicy struct {
+	MaxAttempts int
+	Backoff     time.Duration
+}
+
+var DefaultScheduleRetryPolicy = ScheduleRetryPolicy{MaxAttempts: 5, Backoff: time.Minute}
+
+// Delay returns time to wait after failed attempt, attempts are counted from 1.
+func (p ScheduleRetryPolicy) Delay(attempt int) time.Duration {
+	return p.Backoff * time.Duration(1<<uint(attempt-1))
+}
+
+// TransferScheduler executes due scheduled transfers with the same checks as TransactionService.Execute.
+type TransferScheduler struct {
+	repo         repository.ScheduleRepo
+	transactions TransactionService
+	retry        ScheduleRetryPolicy
+}
+
+// NewTransferScheduler returns scheduler which executes transfers with transactions. Step-up authentication was required
+// when the schedules were created, so it is not required again.
+func NewTransferScheduler(r repository.ScheduleRepo, transactions TransactionService, retry ScheduleRetryPolicy) TransferScheduler {
+	if r == nil {
+		panic("repo cannot be nil!")
+	}
+	if transactions == nil {
+		panic("transaction service cannot be nil!")
+	}
+	return TransferScheduler{repo: r, transactions: transactions, retry: retry}
+}
+
+// Run executes due transfers every interval until ctx is done. It is meant to be started in separate goroutine.
+func (svc TransferScheduler) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := svc.RunDue(); err != nil {
+				log.Errorf("#Run(...) error while executing scheduled transfers; error: %v", err)
+			}
+		}
+	}
+}
+
+// RunDue executes due transfers and returns their schedules with results of the runs. Schedule which run cannot be saved
+// does not stop the others, its claim expires and the run is retried - the first such error is returned.
+func (svc TransferScheduler) RunDue() ([]model.Schedule, error) {
+	now := time.Now()
+	claimed, err := svc.repo.ClaimDue(now, now.Add(scheduleClaimTTL), scheduleBatchSize)
+	if err != nil {
+		return nil, err
+	}
+	executed := []model.Schedule{}
+	var runErr error
+	for _, s := range claimed {
+		schedule, err := svc.run(model.Schedule(s), now)
+		if err == repository.ErrScheduleRunSaved {
+			log.Warnf("#RunDue(...) scheduled transfer %d was executed by other instance", s.ID)
+			continue
+		}
+		if err != nil {
+			log.Errorf("#RunDue(...) error while executing scheduled transfer %d; error: %v", s.ID, err)
+			if runErr == nil {
+				runErr = err
+			}
+			continue
+		}
+		executed = append(executed, schedule)
+	}
+	if len(executed) > 0 {
+		log.Infof("executed %d scheduled transfer(s)", len(executed))
+	}
+	return executed, runErr
+}
+
+// run executes transfer of the schedule. Successful transfer moves the schedule to its next run, the schedule without
+// recurrence is completed - it is saved together with the transfer, so the transfer is not made again when the run
+// is not saved. Transfer which can succeed later is retried by the retry policy, other failures fail the schedule.
+func (svc TransferScheduler) run(s model.Schedule, now time.Time) (model.Schedule, error) {
+	done := s
+	done.LastRunAt = now
+	done.Attempts, done.LastError, done.RetryAt = 0, "", time.Time{}
+	if next := s.NextRun(now); !next.IsZero() {
+		done.NextRunAt = next
+	} else {
+		done.Status = model.ScheduleCompleted
+	}
+	var saved model.ScheduleDB
+	// step-up authentication was required when the schedule was created
+	_, err := svc.transactions.ExecuteWith(s.UserID, s.Transaction(), now,
+		func(t model.TransactionDB, fn func(t model.TransactionDBFull) (model.TransactionDBFull, error)) (model.TransactionDB, error) {
+			var err error
+			saved, err = svc.repo.MakeScheduledTransaction(model.ScheduleDB(s), model.ScheduleDB(done), fn)
+			return model.TransactionDB{ID: saved.LastTransactionID}, err
+		})
+	if err == nil {
+		return model.Schedule(saved), nil
+	}
+	if err == repository.ErrScheduleRunSaved {
+		return model.Schedule{}, err
+	}
+
+	log.Warnf("#run(...) scheduled transfer %d failed; error: %v", s.ID, err)
+	s.LastRunAt = now
+	s.Attempts++
+	s.LastError = transferErrorMessage(err)
+	if retryable(err) && s.Attempts < svc.retry.MaxAttempts {
+		s.RetryAt = now.Add(svc.retry.Delay(s.Attempts))
+	} else {
+		s.RetryAt = time.Time{}
+		s.Status = model.ScheduleFailed
+	}
+	if err = svc.repo.SaveRun(model.ScheduleDB(s)); err != nil {
+		return model.Schedule{}, err
+	}
+	return s, nil
+}
+
+// retryable checks if failed transfer can succeed later - the sender can get money and internal errors (e.g. of DB)
+// can be transient. Other transfer errors do not change without the user.
+func retryable(err error) bool {
+	return err == ErrInsufficientBalance || !isTransferError(err)
+}
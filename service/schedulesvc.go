@@ -0,0 +1,131 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/labstack/gommon/log"
+	"zuzanna.com/walletapi/model"
+	"zuzanna.com/walletapi/repository"
+)
+
+var ErrScheduleNotFound = errors.New("schedule not found")
+var ErrScheduleStatus = errors.New("schedule status does not allow the change")
+var ErrInvalidScheduleStart = errors.New("schedule must start in the future, at most in a year")
+
+// maxScheduleStart limits how far in the future the first scheduled transfer can be.
+const maxScheduleStart = 365 * 24 * time.Hour
+
+type ScheduleService interface {
+	// Create schedules transfer from the balance of the user authenticated at authTime. Step-up authentication is required
+	// when the schedule is created, scheduled transfers are executed without it, see TransferScheduler.
+	Create(userID int, s model.Schedule, authTime time.Time) (model.Schedule, error)
+	// Get returns schedule of the user, ErrScheduleNotFound for other schedules.
+	Get(userID, id int) (model.Schedule, error)
+	// List returns all schedules of the user, the newest first.
+	List(userID int) ([]model.Schedule, error)
+	// Pause stops executing active schedule until it is resumed.
+	Pause(userID, id int) (model.Schedule, error)
+	// Resume continues executing paused schedule. Transfer missed while the schedule was paused is executed at once,
+	// the next ones as scheduled.
+	Resume(userID, id int) (model.Schedule, error)
+	// Cancel stops executing active or paused schedule for good.
+	Cancel(userID, id int) (model.Schedule, error)
+}
+
+type ScheduleServiceImpl struct {
+	repo     repository.ScheduleRepo
+	balances repository.BalanceRepo
+	// fx is optional, it is used only to compare amount with step-up threshold in other currency.
+	fx FXRateProvider
+	// stepUp is optional, without it transfers of any amount can be scheduled regardless of authentication time.
+	stepUp *StepUpPolicy
+}
+
+func NewScheduleService(r repository.ScheduleRepo, balances repository.BalanceRepo, fx FXRateProvider, stepUp *StepUpPolicy) ScheduleService {
+	if r == nil || balances == nil {
+		panic("repo cannot be nil!")
+	}
+	return ScheduleServiceImpl{repo: r, balances: balances, fx: fx, stepUp: stepUp}
+}
+
+func (svc ScheduleServiceImpl) Create(userID int, s model.Schedule, authTime time.Time) (model.Schedule, error) {
+	now := time.Now()
+	if !s.NextRunAt.After(now) || s.NextRunAt.After(now.Add(maxScheduleStart)) {
+		return model.Schedule{}, ErrInvalidScheduleStart
+	}
+	sender, _, err := svc.balances.GetBalance(s.SenderBalanceID, userID)
+	if err != nil {
+		if err == repository.ErrRecordNotFound {
+			return model.Schedule{}, ErrBalanceNotFound
+		}
+		return model.Schedule{}, err
+	}
+	if s.Currency != "" && s.Currency != sender.Currency {
+		return model.Schedule{}, ErrCurrencyMismatch
+	}
+	if !sender.Currency.AllowsAmount(s.Amount) {
+		return model.Schedule{}, ErrAmountNotAllowed
+	}
+	if svc.stepUp.Requires(s.Amount, sender.Currency, svc.fx) && !svc.stepUp.IsFresh(authTime, now) {
+		log.Warnf("#Create(...) failed while creating schedule, error: %v", ErrStepUpRequired)
+		return model.Schedule{}, ErrStepUpRequired
+	}
+
+	if s.Recurrence == model.RecurrenceMonthly && s.DayOfMonth == 0 {
+		s.DayOfMonth = s.NextRunAt.Day()
+	}
+	s.UserID = userID
+	s.Status = model.ScheduleActive
+	s.CreatedAt = now
+	s.ID, err = svc.repo.Create(model.ScheduleDB(s))
+	if err != nil {
+		return model.Schedule{}, err
+	}
+	return s, nil
+}
+
+func (svc ScheduleServiceImpl) Get(userID, id int) (model.Schedule, error) {
+	s, err := svc.repo.Get(id, userID)
+	if err != nil {
+		if err == repository.ErrRecordNotFound {
+			return model.Schedule{}, ErrScheduleNotFound
+		}
+		return model.Schedule{}, err
+	}
+	return model.Schedule(s), nil
+}
+
+func (svc ScheduleServiceImpl) List(userID int) ([]model.Schedule, error) {
+	schedules, err := svc.repo.GetByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	return model.ConvertListScheduleDB(schedules), nil
+}
+
+func (svc ScheduleServiceImpl) Pause(userID, id int) (model.Schedule, error) {
+	return svc.updateStatus(userID, id, []model.ScheduleStatus{model.ScheduleActive}, model.SchedulePaused)
+}
+
+func (svc ScheduleServiceImpl) Resume(userID, id int) (model.Schedule, error) {
+	return svc.updateStatus(userID, id, []model.ScheduleStatus{model.SchedulePaused}, model.ScheduleActive)
+}
+
+func (svc ScheduleServiceImpl) Cancel(userID, id int) (model.Schedule, error) {
+	return svc.updateStatus(userID, id, []model.ScheduleStatus{model.ScheduleActive, model.SchedulePaused}, model.ScheduleCancelled)
+}
+
+// updateStatus returns ErrScheduleStatus when the schedule of the user exists, but it has none of from statuses.
+func (svc ScheduleServiceImpl) updateStatus(userID, id int, from []model.ScheduleStatus, to model.ScheduleStatus) (model.Schedule, error) {
+	s, err := svc.repo.UpdateStatus(id, userID, from, to)
+	if err == repository.ErrRecordNotFound {
+		if _, err = svc.Get(userID, id); err == nil {
+			return model.Schedule{}, ErrScheduleStatus
+		}
+	}
+	if err != nil {
+		return model.Schedule{}, err
+	}
+	return model.Schedule(s), nil
+}
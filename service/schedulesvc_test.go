@@ -0,0 +1,325 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"zuzanna.com/walletapi/model"
+	"zuzanna.com/walletapi/repository"
+)
+
+// ScheduleRepoFake makes scheduled transfers between balances, saving run of the schedules in failRun fails and transfers
+// of the schedules in failTransfer fail before the balances are checked.
+type ScheduleRepoFake struct {
+	schedules    map[int]model.ScheduleDB
+	balances     map[int]model.BalanceDB
+	transactions *int
+	failRun      map[int]bool
+	failTransfer map[int]bool
+}
+
+func newScheduleRepoFake() ScheduleRepoFake {
+	return ScheduleRepoFake{
+		schedules: map[int]model.ScheduleDB{},
+		balances: map[int]model.BalanceDB{
+			1: {ID: 1, Currency: model.SGD, Balance: model.MustParseAmount("1000"), UserID: 1},
+			2: {ID: 2, Currency: model.SGD, Balance: model.MustParseAmount("25.65"), UserID: 2},
+			3: {ID: 3, Currency: model.SGD, Balance: model.MustParseAmount("12759.77"), UserID: 2},
+			4: {ID: 4, Currency: model.SGD, Balance: model.MustParseAmount("20"), UserID: 1},
+			5: {ID: 5, Currency: model.SGD, UserID: 1},
+		},
+		transactions: new(int),
+		failRun:      map[int]bool{},
+		failTransfer: map[int]bool{},
+	}
+}
+
+func (r ScheduleRepoFake) Create(s model.ScheduleDB) (int, error) {
+	s.ID = len(r.schedules) + 1
+	r.schedules[s.ID] = s
+	return s.ID, nil
+}
+
+func (r ScheduleRepoFake) Get(id, userID int) (model.ScheduleDB, error) {
+	s, ok := r.schedules[id]
+	if !ok || s.UserID != userID {
+		return model.ScheduleDB{}, repository.ErrRecordNotFound
+	}
+	return s, nil
+}
+
+func (r ScheduleRepoFake) GetByUserID(userID int) ([]model.ScheduleDB, error) {
+	schedules := []model.ScheduleDB{}
+	for id := len(r.schedules); id > 0; id-- {
+		if r.schedules[id].UserID == userID {
+			schedules = append(schedules, r.schedules[id])
+		}
+	}
+	return schedules, nil
+}
+
+func (r ScheduleRepoFake) UpdateStatus(id, userID int, from []model.ScheduleStatus, to model.ScheduleStatus) (model.ScheduleDB, error) {
+	s, err := r.Get(id, userID)
+	if err != nil {
+		return model.ScheduleDB{}, err
+	}
+	for _, status := range from {
+		if s.Status == status {
+			s.Status = to
+			r.schedules[id] = s
+			return s, nil
+		}
+	}
+	return model.ScheduleDB{}, repository.ErrRecordNotFound
+}
+
+func (r ScheduleRepoFake) ClaimDue(now, claimedUntil time.Time, limit int) ([]model.ScheduleDB, error) {
+	schedules := []model.ScheduleDB{}
+	for id := 1; id <= len(r.schedules) && len(schedules) < limit; id++ {
+		s := r.schedules[id]
+		due := s.NextRunAt
+		if !s.RetryAt.IsZero() {
+			due = s.RetryAt
+		}
+		if s.Status == model.ScheduleActive && !due.After(now) {
+			schedules = append(schedules, s)
+		}
+	}
+	return schedules, nil
+}
+
+func (r ScheduleRepoFake) SaveRun(s model.ScheduleDB) error {
+	if r.failRun[s.ID] {
+		return errExpected
+	}
+	if r.schedules[s.ID].Status != model.ScheduleActive {
+		s.Status = r.schedules[s.ID].Status
+	}
+	r.schedules[s.ID] = s
+	return nil
+}
+
+func (r ScheduleRepoFake) MakeScheduledTransaction(claimed, run model.ScheduleDB, fn func(t model.TransactionDBFull) (model.TransactionDBFull, error)) (model.ScheduleDB, error) {
+	sender, okSender := r.balances[claimed.SenderBalanceID]
+	receiver, okReceiver := r.balances[claimed.ReceiverBalanceID]
+	if !okSender || !okReceiver {
+		return model.ScheduleDB{}, repository.ErrBalancesNotFound
+	}
+	if r.failRun[claimed.ID] || r.failTransfer[claimed.ID] {
+		return model.ScheduleDB{}, errExpected
+	}
+	t, err := fn(model.TransactionDBFull{SenderBalance: sender, ReceiverBalance: receiver, Amount: claimed.Amount, Currency: claimed.Currency,
+		TransactionDetails: claimed.TransactionDetails})
+	if err != nil {
+		return model.ScheduleDB{}, err
+	}
+	r.balances[sender.ID], r.balances[receiver.ID] = t.SenderBalance, t.ReceiverBalance
+	*r.transactions++
+	run.LastTransactionID = *r.transactions
+	if err = r.SaveRun(run); err != nil {
+		return model.ScheduleDB{}, err
+	}
+	return r.schedules[run.ID], nil
+}
+
+func TestCreateSchedule(t *testing.T) {
+	repo := newScheduleRepoFake()
+	svc := NewScheduleService(repo, newBalanceRepoFake(), nil, &StepUpPolicy{Threshold: model.MustParseAmount("500"), Currency: model.SGD, MaxAge: 5 * time.Minute})
+	startAt := time.Now().Add(time.Hour)
+
+	s, err := svc.Create(1, model.Schedule{SenderBalanceID: 1, ReceiverBalanceID: 2, Amount: model.MustParseAmount("400"), Recurrence: model.RecurrenceMonthly,
+		NextRunAt: startAt, TransactionDetails: model.TransactionDetails{Memo: "Rent"}}, time.Time{})
+	if err != nil {
+		t.Fatalf("error was not expected while creating schedule: %s", err)
+	}
+	if s.ID != 1 || s.UserID != 1 || s.Status != model.ScheduleActive || s.DayOfMonth != startAt.Day() || !s.NextRunAt.Equal(startAt) {
+		t.Errorf("schedule got: %+v", s)
+	}
+
+	cases := []struct {
+		name     string
+		userID   int
+		schedule model.Schedule
+		want     error
+	}{
+		{"other user's balance", 2, model.Schedule{SenderBalanceID: 1, ReceiverBalanceID: 2, Amount: model.MustParseAmount("1"), NextRunAt: startAt}, ErrBalanceNotFound},
+		{"in the past", 1, model.Schedule{SenderBalanceID: 1, ReceiverBalanceID: 2, Amount: model.MustParseAmount("1"), NextRunAt: time.Now().Add(-time.Minute)}, ErrInvalidScheduleStart},
+		{"too late", 1, model.Schedule{SenderBalanceID: 1, ReceiverBalanceID: 2, Amount: model.MustParseAmount("1"), NextRunAt: time.Now().AddDate(1, 1, 0)}, ErrInvalidScheduleStart},
+		{"other currency", 1, model.Schedule{SenderBalanceID: 1, ReceiverBalanceID: 2, Amount: model.MustParseAmount("1"), Currency: model.USD, NextRunAt: startAt}, ErrCurrencyMismatch},
+		{"above step-up threshold", 1, model.Schedule{SenderBalanceID: 1, ReceiverBalanceID: 2, Amount: model.MustParseAmount("501"), NextRunAt: startAt}, ErrStepUpRequired},
+	}
+	for _, c := range cases {
+		if _, err := svc.Create(c.userID, c.schedule, time.Time{}); err != c.want {
+			t.Errorf("%s: error got: %v; want: %v", c.name, err, c.want)
+		}
+	}
+	if _, err = svc.Create(1, model.Schedule{SenderBalanceID: 1, ReceiverBalanceID: 2, Amount: model.MustParseAmount("501"), NextRunAt: startAt}, time.Now()); err != nil {
+		t.Errorf("error was not expected while creating schedule after step-up: %s", err)
+	}
+}
+
+func TestScheduleStatus(t *testing.T) {
+	repo := newScheduleRepoFake()
+	svc := NewScheduleService(repo, newBalanceRepoFake(), nil, nil)
+	s, err := svc.Create(1, model.Schedule{SenderBalanceID: 1, ReceiverBalanceID: 2, Amount: model.MustParseAmount("10"), Recurrence: model.RecurrenceDaily,
+		NextRunAt: time.Now().Add(time.Hour)}, time.Time{})
+	if err != nil {
+		t.Fatalf("error was not expected while creating schedule: %s", err)
+	}
+
+	if s, err = svc.Pause(1, s.ID); err != nil || s.Status != model.SchedulePaused {
+		t.Errorf("paused schedule got: %+v (%v)", s, err)
+	}
+	if _, err = svc.Pause(1, s.ID); err != ErrScheduleStatus {
+		t.Errorf("pause of paused schedule error got: %v; want: %v", err, ErrScheduleStatus)
+	}
+	if s, err = svc.Resume(1, s.ID); err != nil || s.Status != model.ScheduleActive {
+		t.Errorf("resumed schedule got: %+v (%v)", s, err)
+	}
+	if s, err = svc.Cancel(1, s.ID); err != nil || s.Status != model.ScheduleCancelled {
+		t.Errorf("cancelled schedule got: %+v (%v)", s, err)
+	}
+	if _, err = svc.Resume(1, s.ID); err != ErrScheduleStatus {
+		t.Errorf("resume of cancelled schedule error got: %v; want: %v", err, ErrScheduleStatus)
+	}
+	if _, err = svc.Cancel(2, s.ID); err != ErrScheduleNotFound {
+		t.Errorf("cancel of other user's schedule error got: %v; want: %v", err, ErrScheduleNotFound)
+	}
+
+	schedules, err := svc.List(1)
+	if err != nil || len(schedules) != 1 || schedules[0].ID != s.ID {
+		t.Errorf("schedules got: %+v (%v)", schedules, err)
+	}
+}
+
+func TestRunDue(t *testing.T) {
+	now := time.Now()
+	repo := newScheduleRepoFake()
+	schedules := []model.ScheduleDB{
+		{UserID: 1, SenderBalanceID: 1, ReceiverBalanceID: 2, Amount: model.MustParseAmount("10"), Status: model.ScheduleActive, NextRunAt: now.Add(-time.Minute)},
+		{UserID: 1, SenderBalanceID: 1, ReceiverBalanceID: 3, Amount: model.MustParseAmount("20"), Recurrence: model.RecurrenceWeekly, Status: model.ScheduleActive,
+			NextRunAt: now.Add(-time.Minute)},
+		// the sender balance stays too low
+		{UserID: 1, SenderBalanceID: 4, ReceiverBalanceID: 2, Amount: model.MustParseAmount("30"), Status: model.ScheduleActive, NextRunAt: now.Add(-time.Minute)},
+		{UserID: 1, SenderBalanceID: 5, ReceiverBalanceID: 2, Amount: model.MustParseAmount("40"), Status: model.ScheduleActive, NextRunAt: now.Add(-time.Minute)},
+		{UserID: 1, SenderBalanceID: 1, ReceiverBalanceID: 2, Amount: model.MustParseAmount("50"), Status: model.ScheduleActive, NextRunAt: now.Add(time.Hour)},
+		// the receiver balance does not exist
+		{UserID: 1, SenderBalanceID: 1, ReceiverBalanceID: 9, Amount: model.MustParseAmount("60"), Status: model.ScheduleActive, NextRunAt: now.Add(-time.Minute)},
+	}
+	for _, s := range schedules {
+		if _, err := repo.Create(s); err != nil {
+			t.Fatalf("error was not expected while creating schedule: %s", err)
+		}
+	}
+	svc := NewTransferScheduler(repo, NewTransactionService(newBalanceRepoFake(), nil, nil), ScheduleRetryPolicy{MaxAttempts: 2, Backoff: time.Minute})
+
+	ran, err := svc.RunDue()
+	if err != nil {
+		t.Errorf("error was not expected while executing scheduled transfers: %s", err)
+	}
+	if len(ran) != 5 || *repo.transactions != 2 {
+		t.Fatalf("executed schedules got: %d with %d transfers; want: 5 with 2 transfers", len(ran), *repo.transactions)
+	}
+	if b := repo.balances[1]; b.Balance != model.MustParseAmount("970") {
+		t.Errorf("sender balance got: %s; want: 970", b.Balance)
+	}
+	if s := repo.schedules[1]; s.Status != model.ScheduleCompleted || s.LastTransactionID != 1 {
+		t.Errorf("one-time schedule got: %+v; want completed with transaction 1", s)
+	}
+	if s := repo.schedules[2]; s.Status != model.ScheduleActive || s.LastTransactionID != 2 || !s.NextRunAt.Equal(now.Add(-time.Minute).AddDate(0, 0, 7)) {
+		t.Errorf("weekly schedule got: %+v; want active with next run in a week", s)
+	}
+	if s := repo.schedules[3]; s.Status != model.ScheduleActive || s.Attempts != 1 || !s.RetryAt.After(now) || s.LastError != ErrInsufficientBalance.Error() {
+		t.Errorf("schedule of low balance got: %+v; want active with retry", s)
+	}
+	if s := repo.schedules[4]; s.Status != model.ScheduleActive || s.Attempts != 1 || !s.RetryAt.After(now) || s.LastError != ErrInsufficientBalance.Error() {
+		t.Errorf("schedule of insufficient balance got: %+v; want active with retry", s)
+	}
+	if s := repo.schedules[6]; s.Status != model.ScheduleFailed || s.Attempts != 1 || s.LastError != ErrBalanceNotFound.Error() {
+		t.Errorf("schedule of missing balance got: %+v; want failed", s)
+	}
+
+	// retries are due after backoff, the last attempt fails the schedule and the sender got money for the other one
+	for _, id := range []int{3, 4} {
+		s := repo.schedules[id]
+		s.RetryAt = now.Add(-time.Second)
+		repo.schedules[id] = s
+	}
+	b := repo.balances[5]
+	b.Balance = model.MustParseAmount("100")
+	repo.balances[5] = b
+	if ran, err = svc.RunDue(); err != nil || len(ran) != 2 {
+		t.Errorf("retried schedules got: %+v (%v); want two", ran, err)
+	}
+	if s := repo.schedules[3]; s.Status != model.ScheduleFailed || s.Attempts != 2 || !s.RetryAt.IsZero() {
+		t.Errorf("schedule of low balance got: %+v; want failed after 2 attempts", s)
+	}
+	if s := repo.schedules[4]; s.Status != model.ScheduleCompleted || s.Attempts != 0 || s.LastError != "" || s.LastTransactionID != 3 {
+		t.Errorf("schedule of insufficient balance got: %+v; want completed with transaction 3", s)
+	}
+}
+
+func TestRunDueInternalError(t *testing.T) {
+	now := time.Now()
+	repo := newScheduleRepoFake()
+	if _, err := repo.Create(model.ScheduleDB{UserID: 1, SenderBalanceID: 1, ReceiverBalanceID: 2, Amount: model.MustParseAmount("10"),
+		Status: model.ScheduleActive, NextRunAt: now.Add(-time.Minute)}); err != nil {
+		t.Fatalf("error was not expected while creating schedule: %s", err)
+	}
+	repo.failTransfer[1] = true
+	svc := NewTransferScheduler(repo, NewTransactionService(newBalanceRepoFake(), nil, nil), ScheduleRetryPolicy{MaxAttempts: 2, Backoff: time.Minute})
+
+	if ran, err := svc.RunDue(); err != nil || len(ran) != 1 || *repo.transactions != 0 {
+		t.Errorf("executed schedules got: %+v (%v) with %d transfers; want one without transfer", ran, err, *repo.transactions)
+	}
+	s := repo.schedules[1]
+	if s.Status != model.ScheduleActive || s.Attempts != 1 || !s.RetryAt.After(now) || s.LastError != transferErrorMessage(errExpected) {
+		t.Errorf("schedule of failed transfer got: %+v; want active with retry", s)
+	}
+
+	// the database is available again
+	repo.failTransfer[1] = false
+	s.RetryAt = now.Add(-time.Second)
+	repo.schedules[1] = s
+	if ran, err := svc.RunDue(); err != nil || len(ran) != 1 || *repo.transactions != 1 {
+		t.Errorf("retried schedules got: %+v (%v) with %d transfers; want one with transfer", ran, err, *repo.transactions)
+	}
+	if s := repo.schedules[1]; s.Status != model.ScheduleCompleted || s.LastTransactionID != 1 {
+		t.Errorf("retried schedule got: %+v; want completed with transaction 1", s)
+	}
+}
+
+func TestRunDueSaveRunError(t *testing.T) {
+	now := time.Now()
+	repo := newScheduleRepoFake()
+	for _, s := range []model.ScheduleDB{
+		{UserID: 1, SenderBalanceID: 1, ReceiverBalanceID: 2, Amount: model.MustParseAmount("10"), Status: model.ScheduleActive, NextRunAt: now.Add(-time.Minute)},
+		{UserID: 1, SenderBalanceID: 1, ReceiverBalanceID: 3, Amount: model.MustParseAmount("20"), Status: model.ScheduleActive, NextRunAt: now.Add(-time.Minute)},
+	} {
+		if _, err := repo.Create(s); err != nil {
+			t.Fatalf("error was not expected while creating schedule: %s", err)
+		}
+	}
+	repo.failRun[1] = true
+	svc := NewTransferScheduler(repo, NewTransactionService(newBalanceRepoFake(), nil, nil), ScheduleRetryPolicy{MaxAttempts: 2, Backoff: time.Minute})
+
+	ran, err := svc.RunDue()
+	if err != errExpected {
+		t.Errorf("error got: %v; want: %v", err, errExpected)
+	}
+	if len(ran) != 1 || ran[0].ID != 2 || *repo.transactions != 1 {
+		t.Errorf("executed schedules got: %+v with %d transfers; want schedule 2 with 1 transfer", ran, *repo.transactions)
+	}
+	if s := repo.schedules[1]; s.Status != model.ScheduleActive || !s.LastRunAt.IsZero() {
+		t.Errorf("schedule which run was not saved got: %+v; want unchanged", s)
+	}
+}
+
+func TestScheduleRetryDelay(t *testing.T) {
+	p := ScheduleRetryPolicy{MaxAttempts: 5, Backoff: time.Minute}
+	for attempt, want := range map[int]time.Duration{1: time.Minute, 2: 2 * time.Minute, 4: 8 * time.Minute} {
+		if got := p.Delay(attempt); got != want {
+			t.Errorf("delay after attempt %d got: %s; want: %s", attempt, got, want)
+		}
+	}
+}
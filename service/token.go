@@ -0,0 +1,40 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// secretTokenBytes is number of random bytes of secret tokens - refresh tokens, password reset tokens and API keys.
+// Tokens have enough entropy, so fast SHA-256 is enough to hash them.
+const secretTokenBytes = 32
+
+// tokenIDBytes is number of random bytes of access token ID (jti) and refresh token family.
+const tokenIDBytes = 16
+
+// newTokenID returns random hex encoded identifier.
+func newTokenID() (string, error) {
+	b := make([]byte, tokenIDBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// newSecretToken returns random token sent to the client and its hash stored in DB.
+func newSecretToken() (token, hash string, err error) {
+	b := make([]byte, secretTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, hashSecret(token), nil
+}
+
+// hashSecret returns hash of the token stored in DB instead of the token, see newSecretToken.
+func hashSecret(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
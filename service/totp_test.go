@@ -0,0 +1,71 @@
+package service
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is base32 of SHA1 test key "12345678901234567890" from RFC 6238.
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode(t *testing.T) {
+	// RFC 6238 test vectors truncated to 6 digits
+	cases := []struct {
+		unix int64
+		code string
+	}{
+		{unix: 59, code: "287082"},
+		{unix: 1111111109, code: "081804"},
+		{unix: 1234567890, code: "005924"},
+		{unix: 20000000000, code: "353130"},
+	}
+	key, err := totpEncoding.DecodeString(rfc6238Secret)
+	if err != nil {
+		t.Fatalf("error was not expected while decoding secret: %s", err)
+	}
+	for _, testCase := range cases {
+		if code := totpCode(key, totpStep(time.Unix(testCase.unix, 0))); code != testCase.code {
+			t.Errorf("code at %d got: %s; want: %s", testCase.unix, code, testCase.code)
+		}
+	}
+}
+
+func TestMatchTOTP(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	cases := []struct {
+		code string
+		at   time.Time
+		ok   bool
+	}{
+		{code: "081804", at: now, ok: true},
+		// clock drift of one step is tolerated
+		{code: "081804", at: now.Add(totpPeriod), ok: true},
+		{code: "081804", at: now.Add(-totpPeriod), ok: true},
+		{code: "081804", at: now.Add(3 * totpPeriod), ok: false},
+		{code: "000000", at: now, ok: false},
+		{code: "81804", at: now, ok: false},
+	}
+	for _, testCase := range cases {
+		step, ok := matchTOTP(rfc6238Secret, testCase.code, testCase.at)
+		if ok != testCase.ok {
+			t.Errorf("match of %s at %s got: %t; want: %t", testCase.code, testCase.at, ok, testCase.ok)
+		}
+		if ok && step != totpStep(now) {
+			t.Errorf("step got: %d; want: %d", step, totpStep(now))
+		}
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri, err := url.Parse(totpURI("ala 11", rfc6238Secret))
+	if err != nil {
+		t.Fatalf("error was not expected while parsing URI: %s", err)
+	}
+	if uri.Scheme != "otpauth" || uri.Host != "totp" || uri.Path != "/walletApi:ala 11" {
+		t.Errorf("URI got: %s; want: otpauth://totp/walletApi:ala%%2011", uri)
+	}
+	if q := uri.Query(); q.Get("secret") != rfc6238Secret || q.Get("issuer") != totpIssuer {
+		t.Errorf("URI query got: %s; want secret %s and issuer %s", uri.RawQuery, rfc6238Secret, totpIssuer)
+	}
+}
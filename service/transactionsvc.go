@@ -2,6 +2,7 @@ package service
 
 import (
 	"errors"
+	"time"
 
 	"github.com/labstack/gommon/log"
 	"zuzanna.com/walletapi/model"
@@ -9,59 +10,160 @@ import (
 )
 
 var ErrBalanceNotFound = errors.New("sender or receiver balances not found")
-var ErrBalancesLocked = errors.New("sender or receiver balances are locked, new transaction is not allowed")
-var ErrBalanceUnlocked = errors.New("sender or receiver balances are unlocked, inconsistent state")
-var ErrInsufficientBalance = errors.New("sender/receiver unlocked or insufficient balance of a sender")
+var ErrInsufficientBalance = errors.New("insufficient balance of a sender")
 var ErrUnauthorizedTransaction = errors.New("userID from JWT token differ from balance's userID of sender for transaction")
+var ErrCurrencyMismatch = errors.New("transaction currency differs from sender or receiver balance currency")
+var ErrConversionNotSupported = errors.New("currency conversion between balances is not supported")
+var ErrConversionRateNotFound = errors.New("no exchange rate for sender and receiver balance currencies")
+var ErrAmountNotAllowed = errors.New("amount has more decimal places than the currency allows")
+var ErrTransactionNotFound = errors.New("transaction not found")
+var ErrStepUpRequired = errors.New("transfer amount requires recent re-authentication")
+var ErrRefundNotAllowed = errors.New("only receiver of the transaction or admin can refund it")
+var ErrRefundExceedsAmount = errors.New("refund amount exceeds not refunded amount of the transaction")
+var ErrRefundOfRefund = errors.New("refund cannot be refunded")
+
+// transferErrors can be shown to the user as the reason of transfer failed in background, other errors are internal.
+var transferErrors = []error{ErrBalanceNotFound, ErrInsufficientBalance, ErrUnauthorizedTransaction, ErrCurrencyMismatch,
+	ErrConversionNotSupported, ErrConversionRateNotFound, ErrAmountNotAllowed}
+
+// transferErrorMessage returns reason of failed transfer to be shown to the user, see transferErrors.
+func transferErrorMessage(err error) string {
+	if isTransferError(err) {
+		return err.Error()
+	}
+	return "transfer failed because of internal error"
+}
+
+// isTransferError checks if err is one of transferErrors.
+func isTransferError(err error) bool {
+	for _, transferErr := range transferErrors {
+		if err == transferErr {
+			return true
+		}
+	}
+	return false
+}
 
 type TransactionService interface {
-	Execute(userID int, t model.Transaction) (model.Transaction, error)
-	Retrieve(userID int) ([]model.Transaction, error)
+	// Execute makes transfer of the user authenticated at authTime, see StepUpPolicy.
+	Execute(userID int, t model.Transaction, authTime time.Time) (model.Transaction, error)
+	// ExecuteWith makes transfer like Execute, but saves it with makeTransaction instead of the repo of the service,
+	// so the caller can save other changes in the same DB transaction.
+	ExecuteWith(userID int, t model.Transaction, authTime time.Time, makeTransaction MakeTransactionFunc) (model.Transaction, error)
+	// Retrieve returns page of the user's history matching the filter, Limit of the filter must be set.
+	Retrieve(userID int, filter model.TransactionFilter) (model.TransactionPage, error)
+	// Get returns transaction from or to a balance of the user, ErrTransactionNotFound for other transactions.
+	Get(userID, id int) (model.Transaction, error)
+	// Refund returns money of the transaction to its sender as a new transaction from its receiver balance. Only the receiver
+	// and admins can refund it, other users get ErrTransactionNotFound unless they sent it. Refund goes through the same checks
+	// as transfers, including step-up authentication.
+	Refund(userID int, role model.Role, r model.Refund, authTime time.Time) (model.Transaction, error)
 }
 
+// MakeTransactionFunc makes transfer t between balances locked for fn, see repository.BalanceRepo.MakeTransaction.
+type MakeTransactionFunc func(t model.TransactionDB, fn func(t model.TransactionDBFull) (model.TransactionDBFull, error)) (model.TransactionDB, error)
+
 type TransactionServiceImpl struct {
 	repo repository.BalanceRepo
+	// fx is optional, without it transfers between different currencies are not supported.
+	fx FXRateProvider
+	// stepUp is optional, without it transfers of any amount are allowed regardless of authentication time.
+	stepUp *StepUpPolicy
+}
+
+// StepUpPolicy requires the user to authenticate with password or one-time code within MaxAge before transfer
+// of amount above Threshold. Amounts in other currencies are converted to Currency of the threshold.
+type StepUpPolicy struct {
+	Threshold model.Amount
+	Currency  model.Currency
+	MaxAge    time.Duration
+}
+
+// Requires checks if amount is above Threshold, it is false for nil policy. When the amount cannot be converted by fx
+// to the currency of the threshold re-authentication is required.
+func (p *StepUpPolicy) Requires(amount model.Amount, currency model.Currency, fx FXRateProvider) bool {
+	if p == nil {
+		return false
+	}
+	if currency == p.Currency {
+		return amount > p.Threshold
+	}
+	if fx == nil {
+		return true
+	}
+	rate, err := fx.GetRate(currency, p.Currency)
+	if err != nil {
+		log.Warnf("#Requires(...) cannot compare %s %s with step-up threshold; error: %v", amount, currency, err)
+		return true
+	}
+	return rate.Rate.Convert(amount, p.Currency) > p.Threshold
+}
+
+// IsFresh checks if authentication at authTime is recent enough, zero time is never fresh.
+func (p StepUpPolicy) IsFresh(authTime, now time.Time) bool {
+	return !authTime.IsZero() && now.Sub(authTime) <= p.MaxAge
 }
 
-func NewTransactionService(r repository.BalanceRepo) TransactionService {
+func NewTransactionService(r repository.BalanceRepo, fx FXRateProvider, stepUp *StepUpPolicy) TransactionServiceImpl {
 	if r == nil {
 		panic("repo cannot be nil!")
 	}
-	return TransactionServiceImpl{repo: r}
+	return TransactionServiceImpl{repo: r, fx: fx, stepUp: stepUp}
 }
 
-func (svc TransactionServiceImpl) Retrieve(userID int) ([]model.Transaction, error) {
-	transactions, err := svc.repo.GetTransactions(userID)
+func (svc TransactionServiceImpl) Retrieve(userID int, filter model.TransactionFilter) (model.TransactionPage, error) {
+	limit := filter.Limit
+	// one more transaction is read to know if there is the next page
+	filter.Limit++
+	transactions, err := svc.repo.GetTransactions(userID, filter)
 	if err != nil {
-		return nil, err
+		return model.TransactionPage{}, err
 	}
-	return model.ConvertListTransactionDB(transactions), nil
+	page := model.TransactionPage{Transactions: model.ConvertListTransactionDB(transactions)}
+	if len(page.Transactions) > limit {
+		page.Transactions = page.Transactions[:limit]
+		last := page.Transactions[limit-1]
+		page.Next = &model.TransactionCursor{Date: last.Date, ID: last.ID}
+	}
+	return page, nil
 }
 
-// Execute executes transaction that is send specific amount of money from sender balance to receiver balance.
-func (svc TransactionServiceImpl) Execute(userID int, t model.Transaction) (model.Transaction, error) {
-
-	err := svc.lockBalances(t.SenderBalanceID, t.ReceiverBalanceID)
+func (svc TransactionServiceImpl) Get(userID, id int) (model.Transaction, error) {
+	transaction, err := svc.repo.GetTransaction(id, userID)
 	if err != nil {
+		if err == repository.ErrRecordNotFound {
+			return model.Transaction{}, ErrTransactionNotFound
+		}
 		return model.Transaction{}, err
 	}
+	return model.ConvertTransactionDB(transaction), nil
+}
 
-	newTransaction, err := svc.makeTransaction(userID, t)
-	if err != nil {
-		log.Errorf("#Execute(...) error make transaction %+v; error: %v", t, err)
+// Execute executes transaction that is send specific amount of money from sender balance to receiver balance.
+// Whole transfer is done in one DB transaction, concurrent transfers on the same balances are executed one after another.
+func (svc TransactionServiceImpl) Execute(userID int, t model.Transaction, authTime time.Time) (model.Transaction, error) {
+	return svc.ExecuteWith(userID, t, authTime, svc.repo.MakeTransaction)
+}
 
-		errFromUpdate := svc.unlockBalances(t.SenderBalanceID, t.ReceiverBalanceID)
-		if errFromUpdate != nil {
-			return model.Transaction{}, errFromUpdate
+func (svc TransactionServiceImpl) ExecuteWith(userID int, t model.Transaction, authTime time.Time, makeTransaction MakeTransactionFunc) (model.Transaction, error) {
+	newTransaction, err := svc.makeTransaction(makeTransaction, userID, t, authTime)
+	if err != nil {
+		if err == repository.ErrBalancesNotFound {
+			return model.Transaction{}, ErrBalanceNotFound
 		}
-
+		log.Errorf("#Execute(...) error make transaction %+v; error: %v", t, err)
 		return model.Transaction{}, err
 	}
-	return model.Transaction(newTransaction), nil
+	return model.ConvertTransactionDB(newTransaction), nil
+}
+
+func (svc TransactionServiceImpl) makeTransaction(makeTransaction MakeTransactionFunc, userID int, transaction model.Transaction, authTime time.Time) (model.TransactionDB, error) {
+	return makeTransaction(model.ConvertTransaction(transaction), svc.transfer(userID, transaction, authTime))
 }
 
-func (svc TransactionServiceImpl) makeTransaction(userID int, transaction model.Transaction) (model.TransactionDB, error) {
-	return svc.repo.MakeTransaction(model.TransactionDB(transaction), func(t model.TransactionDBFull) (model.TransactionDBFull, error) {
+// transfer returns fn which checks transaction of the user authenticated at authTime and makes it between locked balances.
+func (svc TransactionServiceImpl) transfer(userID int, transaction model.Transaction, authTime time.Time) func(t model.TransactionDBFull) (model.TransactionDBFull, error) {
+	return func(t model.TransactionDBFull) (model.TransactionDBFull, error) {
 		sender := model.Balance(t.SenderBalance)
 		receiver := model.Balance(t.ReceiverBalance)
 		transactionFull := model.TransactionFull{
@@ -77,6 +179,26 @@ func (svc TransactionServiceImpl) makeTransaction(userID int, transaction model.
 			return model.TransactionDBFull{}, ErrUnauthorizedTransaction
 		}
 
+		if transaction.Currency != "" && transaction.Currency != sender.Currency {
+			log.Warnf("#Execute(...) failed while making transaction, error: %v", ErrCurrencyMismatch)
+			return model.TransactionDBFull{}, ErrCurrencyMismatch
+		}
+		transactionFull.Currency = sender.Currency
+		if !transactionFull.Currency.AllowsAmount(transactionFull.Amount) {
+			log.Warnf("#Execute(...) failed while making transaction, error: %v", ErrAmountNotAllowed)
+			return model.TransactionDBFull{}, ErrAmountNotAllowed
+		}
+		if svc.requiresStepUp(transactionFull.Amount, transactionFull.Currency) && !svc.stepUp.IsFresh(authTime, time.Now()) {
+			log.Warnf("#Execute(...) failed while making transaction, error: %v", ErrStepUpRequired)
+			return model.TransactionDBFull{}, ErrStepUpRequired
+		}
+		if !transactionFull.SameCurrency() {
+			if err := svc.convert(&transactionFull, transaction.Convert); err != nil {
+				log.Warnf("#Execute(...) failed while making transaction, error: %v", err)
+				return model.TransactionDBFull{}, err
+			}
+		}
+
 		if !transactionFull.IsValid() {
 			log.Warnf("#Execute(...) failed while making transaction, error: %v", ErrInsufficientBalance)
 			return model.TransactionDBFull{}, ErrInsufficientBalance
@@ -84,58 +206,128 @@ func (svc TransactionServiceImpl) makeTransaction(userID int, transaction model.
 
 		transactionFull.Make()
 
-		transactionFull.SenderBalance.Unlock()
-		transactionFull.ReceiverBalance.Unlock()
-
 		return model.TransactionDBFull{
-			ID:              transactionFull.ID,
-			SenderBalance:   model.BalanceDB(*transactionFull.SenderBalance),
-			ReceiverBalance: model.BalanceDB(*transactionFull.ReceiverBalance),
-			Amount:          transactionFull.Amount,
-			Currency:        transactionFull.Currency,
-			Date:            transactionFull.Date,
+			ID:                 transactionFull.ID,
+			SenderBalance:      model.BalanceDB(*transactionFull.SenderBalance),
+			ReceiverBalance:    model.BalanceDB(*transactionFull.ReceiverBalance),
+			Amount:             transactionFull.Amount,
+			Currency:           transactionFull.Currency,
+			Date:               transactionFull.Date,
+			ReceiverAmount:     transactionFull.ReceiverAmount,
+			ReceiverCurrency:   transactionFull.ReceiverCurrency,
+			Rate:               transactionFull.Rate,
+			RateDate:           transactionFull.RateDate,
+			TransactionDetails: t.TransactionDetails,
 		}, nil
-	})
+	}
 }
 
-func (svc TransactionServiceImpl) lockBalances(senderID, balanceID int) error {
-	err := svc.repo.UpdateBalances([]int{senderID, balanceID}, func(bs []model.BalanceDB) ([]model.BalanceDB, error) {
-		balances := model.ConvertListBalanceDB(bs)
-		if balances[0].IsLocked() || balances[1].IsLocked() {
-			return nil, ErrBalancesLocked
-		}
-
-		balances[0].Lock()
-		balances[1].Lock()
-
-		return model.ConvertListBalance(balances), nil
-	})
+func (svc TransactionServiceImpl) Refund(userID int, role model.Role, r model.Refund, authTime time.Time) (model.Transaction, error) {
+	refund, err := svc.refundTransaction(userID, role.Includes(model.RoleAdmin), r, authTime)
 	if err != nil {
-		if err == repository.ErrBalancesNotFound {
-			return ErrBalanceNotFound
+		switch err {
+		case repository.ErrRecordNotFound:
+			return model.Transaction{}, ErrTransactionNotFound
+		case repository.ErrBalancesNotFound:
+			return model.Transaction{}, ErrBalanceNotFound
 		}
-		log.Errorf("#Execute(...) error cannot acquire lock for sender/receiver balance; error: %v", err)
-		return err
+		log.Errorf("#Refund(...) error refund transaction %d; error: %v", r.TransactionID, err)
+		return model.Transaction{}, err
 	}
-	return nil
+	return model.ConvertTransactionDB(refund), nil
 }
 
-func (svc TransactionServiceImpl) unlockBalances(senderID, balanceID int) error {
-	errFromUpdate := svc.repo.UpdateBalances([]int{senderID, balanceID}, func(bs []model.BalanceDB) ([]model.BalanceDB, error) {
-		balances := model.ConvertListBalanceDB(bs)
-		if !balances[0].IsLocked() || !balances[1].IsLocked() {
-			log.Error(ErrBalanceUnlocked.Error())
-			return nil, ErrBalanceUnlocked
+func (svc TransactionServiceImpl) refundTransaction(userID int, admin bool, r model.Refund, authTime time.Time) (model.TransactionDB, error) {
+	return svc.repo.RefundTransaction(r.TransactionID, func(refunded model.TransactionDB, t model.TransactionDBFull) (model.TransactionDBFull, error) {
+		original := model.ConvertTransactionDB(refunded)
+		sender := model.Balance(t.SenderBalance)
+		receiver := model.Balance(t.ReceiverBalance)
+		if !admin && sender.UserID != userID {
+			if receiver.UserID == userID {
+				log.Warnf("#Refund(...) failed while refunding transaction %d, error: %v", original.ID, ErrRefundNotAllowed)
+				return model.TransactionDBFull{}, ErrRefundNotAllowed
+			}
+			return model.TransactionDBFull{}, ErrTransactionNotFound
+		}
+		if original.RefundOf != 0 {
+			log.Warnf("#Refund(...) failed while refunding transaction %d, error: %v", original.ID, ErrRefundOfRefund)
+			return model.TransactionDBFull{}, ErrRefundOfRefund
+		}
+		amount := r.Amount
+		if amount == 0 {
+			amount = original.RefundableAmount()
+		}
+		if amount <= 0 || amount > original.RefundableAmount() {
+			log.Warnf("#Refund(...) failed while refunding transaction %d, error: %v", original.ID, ErrRefundExceedsAmount)
+			return model.TransactionDBFull{}, ErrRefundExceedsAmount
+		}
+		if !sender.Currency.AllowsAmount(amount) {
+			log.Warnf("#Refund(...) failed while refunding transaction %d, error: %v", original.ID, ErrAmountNotAllowed)
+			return model.TransactionDBFull{}, ErrAmountNotAllowed
+		}
+		if svc.requiresStepUp(amount, sender.Currency) && !svc.stepUp.IsFresh(authTime, time.Now()) {
+			log.Warnf("#Refund(...) failed while refunding transaction %d, error: %v", original.ID, ErrStepUpRequired)
+			return model.TransactionDBFull{}, ErrStepUpRequired
+		}
+
+		transactionFull := model.TransactionFull{
+			SenderBalance:   &sender,
+			ReceiverBalance: &receiver,
+			Amount:          amount,
+			Currency:        sender.Currency,
+		}
+		if !transactionFull.SameCurrency() {
+			// sender of the refunded transaction gets back the same part of what was sent, no exchange rate is applied
+			transactionFull.ReceiverAmount = original.SenderRefund(amount)
+			transactionFull.ReceiverCurrency = receiver.Currency
+			if transactionFull.ReceiverAmount <= 0 {
+				log.Warnf("#Refund(...) failed while refunding transaction %d, error: %v", original.ID, ErrAmountNotAllowed)
+				return model.TransactionDBFull{}, ErrAmountNotAllowed
+			}
+		}
+		if !transactionFull.IsValid() {
+			log.Warnf("#Refund(...) failed while refunding transaction %d, error: %v", original.ID, ErrInsufficientBalance)
+			return model.TransactionDBFull{}, ErrInsufficientBalance
 		}
 
-		balances[0].Unlock()
-		balances[1].Unlock()
+		transactionFull.Make()
 
-		return model.ConvertListBalance(balances), nil
+		return model.TransactionDBFull{
+			SenderBalance:      model.BalanceDB(*transactionFull.SenderBalance),
+			ReceiverBalance:    model.BalanceDB(*transactionFull.ReceiverBalance),
+			Amount:             transactionFull.Amount,
+			Currency:           transactionFull.Currency,
+			Date:               transactionFull.Date,
+			ReceiverAmount:     transactionFull.ReceiverAmount,
+			ReceiverCurrency:   transactionFull.ReceiverCurrency,
+			TransactionDetails: r.TransactionDetails,
+		}, nil
 	})
-	if errFromUpdate != nil {
-		log.Errorf("#UpdateBalances(...) error cannot remove lock for sender/receiver balance; error: %v", errFromUpdate)
-		return errFromUpdate
+}
+
+// requiresStepUp checks if amount is above step-up threshold, see StepUpPolicy.Requires.
+func (svc TransactionServiceImpl) requiresStepUp(amount model.Amount, currency model.Currency) bool {
+	return svc.stepUp.Requires(amount, currency, svc.fx)
+}
+
+// convert applies exchange rate from sender to receiver balance currency. Conversion must be explicitly requested.
+func (svc TransactionServiceImpl) convert(t *model.TransactionFull, requested bool) error {
+	if !requested {
+		return ErrCurrencyMismatch
+	}
+	if svc.fx == nil {
+		return ErrConversionNotSupported
+	}
+	rate, err := svc.fx.GetRate(t.SenderBalance.Currency, t.ReceiverBalance.Currency)
+	if err != nil {
+		if err == ErrRateNotFound {
+			return ErrConversionRateNotFound
+		}
+		return err
+	}
+	t.ApplyRate(rate)
+	if t.ReceiverAmount <= 0 {
+		return ErrAmountNotAllowed
 	}
 	return nil
 }
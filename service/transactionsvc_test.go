@@ -1,7 +1,9 @@
 package service
 
 import (
+	"reflect"
 	"testing"
+	"time"
 
 	"zuzanna.com/walletapi/model"
 )
@@ -14,55 +16,61 @@ type transactionTestCase struct {
 	expectedErr     error
 }
 
-type lockBalanceTestCase struct {
-	b1          model.BalanceDB
-	b2          model.BalanceDB
-	expectedErr error
-}
-
 var balances = []model.BalanceDB{
 	{
 		ID:       1,
 		Currency: model.SGD,
-		Balance:  100.77,
-		Locked:   true,
+		Balance:  model.MustParseAmount("100.77"),
 		UserID:   1,
 	},
 	{
 		ID:       2,
 		Currency: model.SGD,
-		Balance:  100.77,
-		Locked:   true,
+		Balance:  model.MustParseAmount("100.77"),
 		UserID:   11,
 	},
 	{
 		ID:       22,
 		Currency: model.SGD,
-		Balance:  100.77,
-		Locked:   true,
+		Balance:  model.MustParseAmount("100.77"),
 		UserID:   2,
 	},
 	{
 		ID:       3,
 		Currency: model.SGD,
-		Balance:  59.45,
-		Locked:   true,
+		Balance:  model.MustParseAmount("59.45"),
 		UserID:   5,
 	},
 	{
 		ID:       23,
 		Currency: model.SGD,
-		Balance:  100.77,
-		Locked:   true,
+		Balance:  model.MustParseAmount("100.77"),
 		UserID:   2,
 	},
 	{
 		ID:       45,
 		Currency: model.SGD,
-		Balance:  55.87,
-		Locked:   true,
+		Balance:  model.MustParseAmount("55.87"),
 		UserID:   5,
 	},
+	{
+		ID:       46,
+		Currency: model.USD,
+		Balance:  model.MustParseAmount("10.00"),
+		UserID:   6,
+	},
+	{
+		ID:       47,
+		Currency: model.JPY,
+		Balance:  model.MustParseAmount("1000"),
+		UserID:   6,
+	},
+	{
+		ID:       48,
+		Currency: model.JPY,
+		Balance:  model.MustParseAmount("100"),
+		UserID:   7,
+	},
 }
 
 // ID of transaction holds the index in slice - for BalanceRepoFake logic
@@ -74,7 +82,7 @@ var transactionTestCases = []transactionTestCase{
 			ID:                0, // index 0
 			SenderBalanceID:   balances[0].ID,
 			ReceiverBalanceID: balances[1].ID,
-			Amount:            10.34,
+			Amount:            model.MustParseAmount("10.34"),
 			Currency:          model.SGD,
 		},
 		expectedErr: nil},
@@ -85,7 +93,7 @@ var transactionTestCases = []transactionTestCase{
 			ID:                1, // index 1
 			SenderBalanceID:   balances[2].ID,
 			ReceiverBalanceID: balances[3].ID,
-			Amount:            10.34,
+			Amount:            model.MustParseAmount("10.34"),
 			Currency:          model.SGD,
 		},
 		expectedErr: ErrUnauthorizedTransaction},
@@ -96,21 +104,63 @@ var transactionTestCases = []transactionTestCase{
 			ID:                2, // index 2
 			SenderBalanceID:   balances[4].ID,
 			ReceiverBalanceID: balances[5].ID,
-			Amount:            1000.85,
+			Amount:            model.MustParseAmount("1000.85"),
 			Currency:          model.SGD,
 		},
 		expectedErr: ErrInsufficientBalance},
+	{userID: 1,
+		senderBalance:   balances[0],
+		receiverBalance: balances[6],
+		transaction: model.Transaction{
+			ID:                3, // index 3
+			SenderBalanceID:   balances[0].ID,
+			ReceiverBalanceID: balances[6].ID,
+			Amount:            model.MustParseAmount("1.00"),
+		},
+		expectedErr: ErrCurrencyMismatch},
+	{userID: 1,
+		senderBalance:   balances[0],
+		receiverBalance: balances[6],
+		transaction: model.Transaction{
+			ID:                4, // index 4
+			SenderBalanceID:   balances[0].ID,
+			ReceiverBalanceID: balances[6].ID,
+			Amount:            model.MustParseAmount("1.00"),
+			Convert:           true,
+		},
+		expectedErr: ErrConversionNotSupported},
+	{userID: 1,
+		senderBalance:   balances[0],
+		receiverBalance: balances[1],
+		transaction: model.Transaction{
+			ID:                5, // index 5
+			SenderBalanceID:   balances[0].ID,
+			ReceiverBalanceID: balances[1].ID,
+			Amount:            model.MustParseAmount("1.00"),
+			Currency:          model.USD,
+		},
+		expectedErr: ErrCurrencyMismatch},
+	{userID: 6,
+		senderBalance:   balances[7],
+		receiverBalance: balances[8],
+		transaction: model.Transaction{
+			ID:                6, // index 6
+			SenderBalanceID:   balances[7].ID,
+			ReceiverBalanceID: balances[8].ID,
+			Amount:            model.MustParseAmount("10.50"),
+		},
+		expectedErr: ErrAmountNotAllowed},
 }
 
 func TestMakeTransaction(t *testing.T) {
-	svc := TransactionServiceImpl{newBalanceRepoFake()}
+	svc := TransactionServiceImpl{repo: newBalanceRepoFake()}
 
 	for _, test := range transactionTestCases {
-		newTransaction, err := svc.makeTransaction(test.userID, test.transaction)
+		newTransaction, err := svc.makeTransaction(svc.repo.MakeTransaction, test.userID, test.transaction, time.Time{})
 		if err != test.expectedErr {
 			t.Errorf("error got: %s; want: %v", err, test.expectedErr)
 		}
-		if err != nil && (model.TransactionDB{}) != newTransaction {
+		if err != nil && !reflect.DeepEqual(model.TransactionDB{}, newTransaction) {
 			t.Errorf("new transaction wrong, got: %+v; want: empty struct", newTransaction)
 		}
 		if err == nil {
@@ -124,7 +174,7 @@ func TestMakeTransaction(t *testing.T) {
 				t.Errorf("new transaction ReceiverBalanceID wrong, got: %d; want: %d", newTransaction.ReceiverBalanceID, test.transaction.ReceiverBalanceID)
 			}
 			if newTransaction.Amount != test.transaction.Amount {
-				t.Errorf("new transaction Amount wrong, got: %f; want: %f", newTransaction.Amount, test.transaction.Amount)
+				t.Errorf("new transaction Amount wrong, got: %s; want: %s", newTransaction.Amount, test.transaction.Amount)
 			}
 			if newTransaction.Currency != test.transaction.Currency {
 				t.Errorf("new transaction Currency wrong, got: %s; want: %s", newTransaction.Currency, test.transaction.Currency)
@@ -133,69 +183,216 @@ func TestMakeTransaction(t *testing.T) {
 	}
 }
 
-func TestLockBalances(t *testing.T) {
-	b1 := &balances[0]
-	b1.Locked = false
-	b2 := &balances[1]
-	b2.Locked = false
+func TestMakeTransactionWithConversion(t *testing.T) {
+	rateDate := time.Date(2021, 12, 20, 8, 0, 0, 0, time.UTC)
+	svc := TransactionServiceImpl{
+		repo: newBalanceRepoFake(),
+		fx: NewStaticFXRateProvider([]model.FXRate{
+			{From: model.SGD, To: model.USD, Rate: model.MustParseRate("0.7324"), Date: rateDate},
+		}),
+	}
 
-	b3 := &balances[2]
-	b3.Locked = true
-	b4 := &balances[3]
-	b4.Locked = false
+	// index 4 - SGD to USD with conversion requested
+	test := transactionTestCases[4]
+	newTransaction, err := svc.makeTransaction(svc.repo.MakeTransaction, test.userID, test.transaction, time.Time{})
+	if err != nil {
+		t.Errorf("error was not expected while making transaction: %s", err)
+	}
+	if newTransaction.Amount != model.MustParseAmount("1.00") || newTransaction.Currency != model.SGD {
+		t.Errorf("new transaction source amount wrong, got: %s %s; want: 1.00 SGD", newTransaction.Amount, newTransaction.Currency)
+	}
+	if newTransaction.ReceiverAmount != model.MustParseAmount("0.73") || newTransaction.ReceiverCurrency != model.USD {
+		t.Errorf("new transaction destination amount wrong, got: %s %s; want: 0.73 USD", newTransaction.ReceiverAmount, newTransaction.ReceiverCurrency)
+	}
+	if newTransaction.Rate != model.MustParseRate("0.7324") || !newTransaction.RateDate.Equal(rateDate) {
+		t.Errorf("new transaction rate wrong, got: %s at %s; want: 0.732400 at %s", newTransaction.Rate, newTransaction.RateDate, rateDate)
+	}
 
-	b5 := &balances[2]
-	b5.Locked = false
-	b6 := &balances[3]
-	b6.Locked = true
-	var lockBalanceTestCases = []lockBalanceTestCase{
-		{b1: *b1, b2: *b2, expectedErr: nil},
-		{b1: *b3, b2: *b4, expectedErr: ErrBalancesLocked},
-		{b1: *b5, b2: *b6, expectedErr: ErrBalancesLocked},
+	// index 3 - conversion not requested
+	test = transactionTestCases[3]
+	_, err = svc.makeTransaction(svc.repo.MakeTransaction, test.userID, test.transaction, time.Time{})
+	if err != ErrCurrencyMismatch {
+		t.Errorf("error got: %v; want: %v", err, ErrCurrencyMismatch)
 	}
 
-	svc := TransactionServiceImpl{newBalanceRepoFake()}
+	// no USD to SGD rate
+	svc.fx = NewStaticFXRateProvider(nil)
+	test = transactionTestCases[4]
+	_, err = svc.makeTransaction(svc.repo.MakeTransaction, test.userID, test.transaction, time.Time{})
+	if err != ErrConversionRateNotFound {
+		t.Errorf("error got: %v; want: %v", err, ErrConversionRateNotFound)
+	}
+}
 
-	for _, test := range lockBalanceTestCases {
-		err := svc.lockBalances(test.b1.ID, test.b2.ID)
-		if err != test.expectedErr {
-			t.Errorf("error got: %s; want: %v", err, test.expectedErr)
-		}
+func TestExecute(t *testing.T) {
+	svc := TransactionServiceImpl{repo: newBalanceRepoFake()}
+
+	test := transactionTestCases[0]
+	newTransaction, err := svc.Execute(test.userID, test.transaction, time.Time{})
+	if err != nil {
+		t.Errorf("error was not expected while executing transaction: %s", err)
+	}
+	if newTransaction.ReceiverAmount != test.transaction.Amount || newTransaction.ReceiverCurrency != model.SGD {
+		t.Errorf("new transaction destination amount wrong, got: %s %s; want: %s SGD", newTransaction.ReceiverAmount, newTransaction.ReceiverCurrency, test.transaction.Amount)
 	}
 
-	err := svc.lockBalances(-1, 0)
+	_, err = svc.Execute(1, model.Transaction{SenderBalanceID: -1, ReceiverBalanceID: 2, Amount: model.MustParseAmount("1")}, time.Time{})
 	if err != ErrBalanceNotFound {
-		t.Errorf("error got: %s; want: %v", err, ErrBalanceNotFound)
+		t.Errorf("error got: %v; want: %v", err, ErrBalanceNotFound)
 	}
 }
 
-func TestUnlockBalances(t *testing.T) {
-	b1 := &balances[0]
-	b1.Locked = true
-	b2 := &balances[1]
-	b2.Locked = true
+func TestExecuteWithStepUp(t *testing.T) {
+	policy := StepUpPolicy{Threshold: model.MustParseAmount("10.00"), Currency: model.SGD, MaxAge: 5 * time.Minute}
+	svc := TransactionServiceImpl{repo: newBalanceRepoFake(), stepUp: &policy}
+	now := time.Now()
+
+	// index 0 - 10.34 SGD is above the threshold
+	test := transactionTestCases[0]
+	cases := []struct {
+		authTime    time.Time
+		expectedErr error
+	}{
+		{authTime: time.Time{}, expectedErr: ErrStepUpRequired},
+		{authTime: now.Add(-10 * time.Minute), expectedErr: ErrStepUpRequired},
+		{authTime: now.Add(-time.Minute), expectedErr: nil},
+	}
+	for _, testCase := range cases {
+		if _, err := svc.Execute(test.userID, test.transaction, testCase.authTime); err != testCase.expectedErr {
+			t.Errorf("error for auth time %s got: %v; want: %v", testCase.authTime, err, testCase.expectedErr)
+		}
+	}
+
+	// amount below the threshold does not require re-authentication
+	belowThreshold := test.transaction
+	belowThreshold.Amount = model.MustParseAmount("10.00")
+	if _, err := svc.Execute(test.userID, belowThreshold, time.Time{}); err != nil {
+		t.Errorf("error was not expected while executing transaction below threshold: %s", err)
+	}
+
+	// index 4 - 1.00 SGD compared with threshold in USD
+	test = transactionTestCases[4]
+	svc.fx = NewStaticFXRateProvider([]model.FXRate{
+		{From: model.SGD, To: model.USD, Rate: model.MustParseRate("0.7324"), Date: now},
+	})
+	svc.stepUp = &StepUpPolicy{Threshold: model.MustParseAmount("0.70"), Currency: model.USD, MaxAge: 5 * time.Minute}
+	if _, err := svc.Execute(test.userID, test.transaction, time.Time{}); err != ErrStepUpRequired {
+		t.Errorf("error got: %v; want: %v", err, ErrStepUpRequired)
+	}
+	svc.stepUp.Threshold = model.MustParseAmount("0.80")
+	if _, err := svc.Execute(test.userID, test.transaction, time.Time{}); err != nil {
+		t.Errorf("error was not expected while executing transaction below converted threshold: %s", err)
+	}
+	// amount which cannot be converted is treated as above the threshold
+	svc.stepUp.Currency = model.JPY
+	if _, err := svc.Execute(test.userID, test.transaction, time.Time{}); err != ErrStepUpRequired {
+		t.Errorf("error got: %v; want: %v", err, ErrStepUpRequired)
+	}
+}
 
-	b3 := &balances[2]
-	b3.Locked = true
-	b4 := &balances[3]
-	b4.Locked = false
+func TestRetrieve(t *testing.T) {
+	repo := newBalanceRepoFake()
+	now := time.Now()
+	repo.transactions = map[int][]model.TransactionDB{1: {
+		{ID: 3, SenderBalanceID: 1, ReceiverBalanceID: 2, Currency: model.SGD, Date: now},
+		{ID: 2, SenderBalanceID: 2, ReceiverBalanceID: 1, Currency: model.SGD, Date: now.Add(-time.Hour)},
+		{ID: 1, SenderBalanceID: 1, ReceiverBalanceID: 2, Currency: model.SGD, Date: now.Add(-2 * time.Hour)},
+	}}
+	svc := TransactionServiceImpl{repo: repo}
 
-	b5 := &balances[2]
-	b5.Locked = false
-	b6 := &balances[3]
-	b6.Locked = true
-	var lockBalanceTestCases = []lockBalanceTestCase{
-		{b1: *b1, b2: *b2, expectedErr: nil},
-		{b1: *b3, b2: *b4, expectedErr: ErrBalanceUnlocked},
-		{b1: *b5, b2: *b6, expectedErr: ErrBalanceUnlocked},
+	page, err := svc.Retrieve(1, model.TransactionFilter{Limit: 2})
+	if err != nil {
+		t.Fatalf("error was not expected while retrieving transactions: %s", err)
+	}
+	if len(page.Transactions) != 2 || page.Next == nil || page.Next.ID != 2 || !page.Next.Date.Equal(now.Add(-time.Hour)) {
+		t.Errorf("page got: %+v; want 2 transactions and cursor of transaction 2", page)
 	}
 
-	svc := TransactionServiceImpl{newBalanceRepoFake()}
+	page, err = svc.Retrieve(1, model.TransactionFilter{Limit: 3})
+	if err != nil || len(page.Transactions) != 3 || page.Next != nil {
+		t.Errorf("last page got: %+v (%v); want 3 transactions without next cursor", page, err)
+	}
 
-	for _, test := range lockBalanceTestCases {
-		err := svc.unlockBalances(test.b1.ID, test.b2.ID)
-		if err != test.expectedErr {
-			t.Errorf("error got: %s; want: %v", err, test.expectedErr)
+	svc = TransactionServiceImpl{repo: newBalanceRepoFake()}
+	if _, err = svc.Retrieve(1, model.TransactionFilter{Limit: 2}); err != errExpected {
+		t.Errorf("error got: %v; want: %v", err, errExpected)
+	}
+}
+
+func TestGetTransaction(t *testing.T) {
+	repo := newBalanceRepoFake()
+	repo.transactions = map[int][]model.TransactionDB{1: {{ID: 3, SenderBalanceID: 1, ReceiverBalanceID: 2, Currency: model.SGD}}}
+	svc := TransactionServiceImpl{repo: repo}
+
+	if got, err := svc.Get(1, 3); err != nil || got.ID != 3 {
+		t.Errorf("transaction got: %+v (%v); want transaction 3", got, err)
+	}
+	if _, err := svc.Get(2, 3); err != ErrTransactionNotFound {
+		t.Errorf("error got: %v; want: %v", err, ErrTransactionNotFound)
+	}
+}
+
+func TestRefund(t *testing.T) {
+	repo := newBalanceRepoFake()
+	repo.transactions = map[int][]model.TransactionDB{
+		11: {
+			{ID: 31, SenderBalanceID: 2, ReceiverBalanceID: 1, Currency: model.SGD, Amount: model.MustParseAmount("5"),
+				ReceiverCurrency: model.SGD, ReceiverAmount: model.MustParseAmount("5"), RefundOf: 30},
+			{ID: 30, SenderBalanceID: 1, ReceiverBalanceID: 2, Currency: model.SGD, Amount: model.MustParseAmount("20"),
+				ReceiverCurrency: model.SGD, ReceiverAmount: model.MustParseAmount("20"), RefundedAmount: model.MustParseAmount("5")},
+		},
+		7: {
+			{ID: 32, SenderBalanceID: 46, ReceiverBalanceID: 48, Currency: model.USD, Amount: model.MustParseAmount("1.50"),
+				ReceiverCurrency: model.JPY, ReceiverAmount: model.MustParseAmount("225"), Rate: model.MustParseRate("150")},
+		},
+	}
+	svc := TransactionServiceImpl{repo: repo}
+
+	cases := []struct {
+		userID         int
+		role           model.Role
+		refund         model.Refund
+		expectedAmount model.Amount
+		// expectedReceiverAmount is returned to the sender of the refunded transaction
+		expectedReceiverAmount model.Amount
+		expectedErr            error
+	}{
+		{userID: 11, role: model.RoleUser, refund: model.Refund{TransactionID: 30},
+			expectedAmount: model.MustParseAmount("15"), expectedReceiverAmount: model.MustParseAmount("15")},
+		{userID: 11, role: model.RoleUser, refund: model.Refund{TransactionID: 30, Amount: model.MustParseAmount("15.01")},
+			expectedErr: ErrRefundExceedsAmount},
+		{userID: 1, role: model.RoleUser, refund: model.Refund{TransactionID: 30}, expectedErr: ErrRefundNotAllowed},
+		{userID: 5, role: model.RoleSupport, refund: model.Refund{TransactionID: 30}, expectedErr: ErrTransactionNotFound},
+		{userID: 99, role: model.RoleAdmin, refund: model.Refund{TransactionID: 30, Amount: model.MustParseAmount("2.50")},
+			expectedAmount: model.MustParseAmount("2.50"), expectedReceiverAmount: model.MustParseAmount("2.50")},
+		{userID: 11, role: model.RoleUser, refund: model.Refund{TransactionID: 31}, expectedErr: ErrRefundNotAllowed},
+		{userID: 1, role: model.RoleUser, refund: model.Refund{TransactionID: 31}, expectedErr: ErrRefundOfRefund},
+		{userID: 11, role: model.RoleUser, refund: model.Refund{TransactionID: 99}, expectedErr: ErrTransactionNotFound},
+		// converted transfer - sender gets back the same part of what was sent
+		{userID: 7, role: model.RoleUser, refund: model.Refund{TransactionID: 32, Amount: model.MustParseAmount("75")},
+			expectedAmount: model.MustParseAmount("75"), expectedReceiverAmount: model.MustParseAmount("0.50")},
+		{userID: 7, role: model.RoleUser, refund: model.Refund{TransactionID: 32, Amount: model.MustParseAmount("0.50")},
+			expectedErr: ErrAmountNotAllowed},
+		{userID: 7, role: model.RoleUser, refund: model.Refund{TransactionID: 32}, expectedErr: ErrInsufficientBalance},
+	}
+	for _, testCase := range cases {
+		refund, err := svc.Refund(testCase.userID, testCase.role, testCase.refund, time.Time{})
+		if err != testCase.expectedErr {
+			t.Errorf("error for refund %+v of user %d got: %v; want: %v", testCase.refund, testCase.userID, err, testCase.expectedErr)
+			continue
 		}
+		if err == nil && (refund.RefundOf != testCase.refund.TransactionID || refund.Amount != testCase.expectedAmount ||
+			refund.ReceiverAmount != testCase.expectedReceiverAmount) {
+			t.Errorf("refund got: %+v; want %s refunded and %s returned", refund, testCase.expectedAmount, testCase.expectedReceiverAmount)
+		}
+	}
+
+	policy := StepUpPolicy{Threshold: model.MustParseAmount("10.00"), Currency: model.SGD, MaxAge: 5 * time.Minute}
+	svc.stepUp = &policy
+	if _, err := svc.Refund(11, model.RoleUser, model.Refund{TransactionID: 30}, time.Time{}); err != ErrStepUpRequired {
+		t.Errorf("error got: %v; want: %v", err, ErrStepUpRequired)
+	}
+	if _, err := svc.Refund(11, model.RoleUser, model.Refund{TransactionID: 30}, time.Now()); err != nil {
+		t.Errorf("error was not expected while refunding after re-authentication: %s", err)
 	}
 }
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/labstack/gommon/log"
+	"zuzanna.com/walletapi/model"
+	"zuzanna.com/walletapi/repository"
+)
+
+var ErrLoginExists = errors.New("login is already taken")
+var ErrUserNotFound = errors.New("user not found")
+
+// defaultCurrency is currency of initial balance of new user when not requested otherwise.
+const defaultCurrency = model.SGD
+
+type UserService interface {
+	Register(u model.User, login, password string, currency model.Currency) (model.User, error)
+	Get(userID int) (model.User, error)
+	// Update changes profile fields set in the patch, the patch must be validated by the caller.
+	Update(userID int, patch model.UserPatch) (model.User, error)
+	// GetBalanceOwners returns owners of the balances by balance ID, e.g. to show counterparty names.
+	GetBalanceOwners(balanceIDs []int) (map[int]model.User, error)
+}
+
+type UserServiceImpl struct {
+	repo repository.UserRepo
+}
+
+func NewUserService(r repository.UserRepo) UserServiceImpl {
+	if r == nil {
+		panic("repo cannot be nil!")
+	}
+	return UserServiceImpl{repo: r}
+}
+
+// Register creates new user with credentials and initial empty balance.
+func (svc UserServiceImpl) Register(u model.User, login, password string, currency model.Currency) (model.User, error) {
+	if err := CheckPasswordPolicy(password); err != nil {
+		return model.User{}, err
+	}
+	if currency == "" {
+		currency = defaultCurrency
+	}
+	hash, err := HashPassword(password)
+	if err != nil {
+		log.Errorf("#Register(...) error while hashing password for login %s; error: %v", login, err)
+		return model.User{}, err
+	}
+
+	user, err := svc.repo.Create(model.UserDB(u), model.Credentials{Login: login, Password: hash}, currency)
+	if err != nil {
+		if err == repository.ErrLoginExists {
+			return model.User{}, ErrLoginExists
+		}
+		log.Errorf("#Register(...) error while creating user with login %s; error: %v", login, err)
+		return model.User{}, err
+	}
+	return model.User(user), nil
+}
+
+func (svc UserServiceImpl) Get(userID int) (model.User, error) {
+	user, err := svc.repo.Get(userID)
+	if err != nil {
+		if err == repository.ErrRecordNotFound {
+			return model.User{}, ErrUserNotFound
+		}
+		return model.User{}, err
+	}
+	return model.User(user), nil
+}
+
+func (svc UserServiceImpl) Update(userID int, patch model.UserPatch) (model.User, error) {
+	user, err := svc.repo.Update(userID, patch)
+	if err != nil {
+		if err == repository.ErrRecordNotFound {
+			return model.User{}, ErrUserNotFound
+		}
+		return model.User{}, err
+	}
+	return model.User(user), nil
+}
+
+func (svc UserServiceImpl) GetBalanceOwners(balanceIDs []int) (map[int]model.User, error) {
+	owners := map[int]model.User{}
+	if len(balanceIDs) == 0 {
+		return owners, nil
+	}
+	users, err := svc.repo.GetByBalanceIDs(balanceIDs)
+	if err != nil {
+		return nil, err
+	}
+	for balanceID, u := range users {
+		owners[balanceID] = model.User(u)
+	}
+	return owners, nil
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"testing"
+
+	"zuzanna.com/walletapi/model"
+	"zuzanna.com/walletapi/repository"
+)
+
+type UserRepoFake struct {
+	credentials map[string]model.Credentials
+	currencies  map[int]model.Currency
+	users       map[int]model.UserDB
+	aliases     map[string]int
+}
+
+func newUserRepoFake() UserRepoFake {
+	return UserRepoFake{
+		credentials: map[string]model.Credentials{"ala11": {ID: 1, Login: "ala11", Password: "aGFzbG8=", UserID: 1}},
+		currencies:  map[int]model.Currency{},
+		users:       map[int]model.UserDB{1: {ID: 1, FirstName: "Ala", LastName: "Kot", Age: 25}},
+		aliases:     map[string]int{"ala@example.com": 1, "+6591234567": 1},
+	}
+}
+
+func (r UserRepoFake) Create(u model.UserDB, c model.Credentials, currency model.Currency) (model.UserDB, error) {
+	if _, ok := r.credentials[c.Login]; ok {
+		return model.UserDB{}, repository.ErrLoginExists
+	}
+	if c.Login == "err" {
+		return model.UserDB{}, errExpected
+	}
+	u.ID = len(r.credentials) + 1
+	c.UserID = u.ID
+	r.credentials[c.Login] = c
+	r.currencies[u.ID] = currency
+	r.users[u.ID] = u
+	return u, nil
+}
+
+func (r UserRepoFake) Get(userID int) (model.UserDB, error) {
+	u, ok := r.users[userID]
+	if !ok {
+		return model.UserDB{}, repository.ErrRecordNotFound
+	}
+	return u, nil
+}
+
+func (r UserRepoFake) Update(userID int, patch model.UserPatch) (model.UserDB, error) {
+	u, ok := r.users[userID]
+	if !ok {
+		return model.UserDB{}, repository.ErrRecordNotFound
+	}
+	if patch.FirstName != nil {
+		u.FirstName = *patch.FirstName
+	}
+	if patch.LastName != nil {
+		u.LastName = *patch.LastName
+	}
+	if patch.Age != nil {
+		u.Age = *patch.Age
+	}
+	r.users[userID] = u
+	return u, nil
+}
+
+func (r UserRepoFake) GetIDByAlias(login, alias string) (int, error) {
+	if c, ok := r.credentials[login]; ok {
+		return c.UserID, nil
+	}
+	if userID, ok := r.aliases[alias]; ok {
+		return userID, nil
+	}
+	return 0, repository.ErrRecordNotFound
+}
+
+// GetByBalanceIDs treats balance ID as ID of the user, every fake user has one balance.
+func (r UserRepoFake) GetByBalanceIDs(balanceIDs []int) (map[int]model.UserDB, error) {
+	owners := map[int]model.UserDB{}
+	for _, id := range balanceIDs {
+		if u, ok := r.users[id]; ok {
+			owners[id] = u
+		}
+	}
+	return owners, nil
+}
+
+func TestRegister(t *testing.T) {
+	repo := newUserRepoFake()
+	svc := NewUserService(repo)
+	user := model.User{FirstName: "Ola", LastName: "Kot", Age: 30}
+
+	cases := []struct {
+		login       string
+		password    string
+		currency    model.Currency
+		expectedErr error
+	}{
+		{login: "ola22", password: "1qazXSW@", expectedErr: nil},
+		{login: "ela33", password: "1qazXSW@", currency: model.USD, expectedErr: nil},
+		{login: "ala11", password: "1qazXSW@", expectedErr: ErrLoginExists},
+		{login: "ula44", password: "haslo", expectedErr: ErrWeakPassword},
+		{login: "err", password: "1qazXSW@", expectedErr: errExpected},
+	}
+
+	for _, testCase := range cases {
+		got, err := svc.Register(user, testCase.login, testCase.password, testCase.currency)
+		if err != testCase.expectedErr {
+			t.Errorf("error got: %v; want: %v", err, testCase.expectedErr)
+		}
+		if err != nil {
+			continue
+		}
+		if got.ID == 0 || got.FirstName != user.FirstName || got.LastName != user.LastName || got.Age != user.Age {
+			t.Errorf("registered user got: %+v; want: %+v with ID", got, user)
+		}
+		stored := repo.credentials[testCase.login]
+		if ok, _, _ := VerifyPassword(stored.Password, testCase.password); !ok || stored.Password == testCase.password {
+			t.Errorf("stored password must be hashed, got: %s", stored.Password)
+		}
+		wantCurrency := testCase.currency
+		if wantCurrency == "" {
+			wantCurrency = model.SGD
+		}
+		if repo.currencies[got.ID] != wantCurrency {
+			t.Errorf("initial balance currency got: %s; want: %s", repo.currencies[got.ID], wantCurrency)
+		}
+	}
+}
+
+func TestUpdateProfile(t *testing.T) {
+	svc := NewUserService(newUserRepoFake())
+
+	age := 26
+	got, err := svc.Update(1, model.UserPatch{Age: &age})
+	want := model.User{ID: 1, FirstName: "Ala", LastName: "Kot", Age: 26}
+	if err != nil || got != want {
+		t.Errorf("user got: %+v (%v); want: %+v", got, err, want)
+	}
+	if got, _ = svc.Get(1); got != want {
+		t.Errorf("user got: %+v; want: %+v", got, want)
+	}
+	if _, err = svc.Update(9, model.UserPatch{Age: &age}); err != ErrUserNotFound {
+		t.Errorf("error got: %v; want: %v", err, ErrUserNotFound)
+	}
+	if _, err = svc.Get(9); err != ErrUserNotFound {
+		t.Errorf("error got: %v; want: %v", err, ErrUserNotFound)
+	}
+}